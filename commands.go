@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	intconfig "backend/internal/config"
+	"backend/internal/db/migrations"
+	"backend/internal/services"
+)
+
+// runCommand menjalankan subcommand CLI (mis. `go run . migrate up`).
+// Mengembalikan false bila argumen bukan subcommand sehingga server dijalankan seperti biasa.
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "migrate":
+		runMigrate(args[1:])
+		return true
+	case "files":
+		runFiles(args[1:])
+		return true
+	case "consistency":
+		runConsistency(args[1:])
+		return true
+	default:
+		return false
+	}
+}
+
+// go run . migrate up|down [steps]|status
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal("pemakaian: migrate up|down [steps]|status")
+	}
+
+	cfg, err := intconfig.LoadDBConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db := intconfig.ConnectDB(cfg)
+	defer intconfig.CloseDB()
+
+	runner, err := migrations.NewRunner(db)
+	if err != nil {
+		log.Fatalf("gagal memuat migration: %v", err)
+	}
+	runner.Logf = log.Printf
+
+	switch args[0] {
+	case "up":
+		done, err := runner.Up()
+		if err != nil {
+			log.Fatalf("migrate up: %v", err)
+		}
+		log.Printf("%d migration diterapkan", len(done))
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				log.Fatalf("jumlah steps tidak valid: %q", args[1])
+			}
+			steps = n
+		}
+		done, err := runner.Down(steps)
+		if err != nil {
+			log.Fatalf("migrate down: %v", err)
+		}
+		log.Printf("%d migration di-rollback", len(done))
+
+	case "status":
+		list, err := runner.Status()
+		if err != nil {
+			log.Fatalf("migrate status: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, st := range list {
+			status, appliedAt := "pending", "-"
+			if st.Applied {
+				status = "applied"
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if st.ChecksumMismatch {
+				status = "CHECKSUM MISMATCH"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, status, appliedAt)
+		}
+		_ = w.Flush()
+
+	default:
+		log.Fatalf("subcommand migrate tidak dikenal: %q (pakai up|down|status)", args[0])
+	}
+}
+
+// go run . files migrate-base64 [--dry-run]
+// Memindahkan bukti bayar & surat jalan base64 lama ke FileStore (FILE_STORE) dan
+// mengganti nilai kolom dengan referensi "file:<key>".
+func runFiles(args []string) {
+	if len(args) == 0 || args[0] != "migrate-base64" {
+		log.Fatal("pemakaian: files migrate-base64 [--dry-run]")
+	}
+	dryRun := false
+	for _, a := range args[1:] {
+		switch a {
+		case "--dry-run":
+			dryRun = true
+		default:
+			log.Fatalf("argumen tidak dikenal: %q", a)
+		}
+	}
+
+	env := intconfig.LoadEnv()
+	db := intconfig.ConnectDB(env.DB)
+	defer intconfig.CloseDB()
+
+	m := services.FileBlobMigration{
+		// blob lama bisa lebih besar dari batas upload baru; tetap dipindahkan
+		Files:  services.FileService{Store: services.NewFileStore(env), MaxBytes: 64 << 20},
+		DB:     db,
+		DryRun: dryRun,
+		Logf:   log.Printf,
+	}
+	reports, err := m.Run()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tCOLUMN\tSCANNED\tMIGRATED\tSKIPPED\tFAILED")
+	for _, r := range reports {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\n", r.Table, r.Column, r.Scanned, r.Migrated, r.Skipped, r.Failed)
+	}
+	_ = w.Flush()
+	if err != nil {
+		log.Fatalf("files migrate-base64: %v", err)
+	}
+	if dryRun {
+		log.Println("dry run: tidak ada data yang diubah")
+	}
+}
+
+// go run . consistency check|repair [--kinds=paid_without_settings,...]
+// Melaporkan (check) atau memperbaiki (repair) booking lunas, settings, penumpang dan
+// trip_information yang tidak sinkron. Repair aman dijalankan berulang.
+func runConsistency(args []string) {
+	if len(args) == 0 || (args[0] != "check" && args[0] != "repair") {
+		log.Fatal("pemakaian: consistency check|repair [--kinds=jenis1,jenis2]")
+	}
+	rawKinds := ""
+	for _, a := range args[1:] {
+		switch {
+		case strings.HasPrefix(a, "--kinds="):
+			rawKinds = strings.TrimPrefix(a, "--kinds=")
+		default:
+			log.Fatalf("argumen tidak dikenal: %q", a)
+		}
+	}
+	kinds, err := services.ParseConsistencyKinds(rawKinds)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	env := intconfig.LoadEnv()
+	intconfig.ConnectDB(env.DB)
+	defer intconfig.CloseDB()
+
+	svc := services.ConsistencyService{RequestID: "cli-consistency"}
+	var rep services.ConsistencyReport
+	if args[0] == "repair" {
+		rep, err = svc.Repair(kinds)
+	} else {
+		rep, err = svc.Check(kinds)
+	}
+	if err != nil {
+		log.Fatalf("consistency %s: %v", args[0], err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tTABLE\tID\tBOOKING\tSTATUS\tDETAIL")
+	for _, i := range rep.Issues {
+		status := "found"
+		switch {
+		case i.RepairError != "":
+			status = "failed: " + i.RepairError
+		case i.Repaired:
+			status = "repaired"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", i.Kind, i.Table, i.RowID, i.BookingID, status, i.Detail)
+	}
+	_ = w.Flush()
+	log.Printf("%d temuan, %d diperbaiki, %d gagal", len(rep.Issues), rep.Repaired, rep.Failed)
+	if rep.Failed > 0 {
+		os.Exit(1)
+	}
+}
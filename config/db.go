@@ -2,14 +2,11 @@
 package config
 
 import (
-	"context"
 	"database/sql"
-	"fmt"
 	"log"
 	"sync"
-	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	intconfig "backend/internal/config"
 )
 
 var (
@@ -17,43 +14,25 @@ var (
 	dbMu sync.Mutex
 )
 
+// ConnectDB memakai koneksi yang sama dengan internal/config (DB_* dari environment),
+// supaya handler lama tidak punya DSN sendiri.
 func ConnectDB() {
 	dbMu.Lock()
 	defer dbMu.Unlock()
+	connectLocked()
+}
 
+func connectLocked() {
 	// kalau sudah ada DB aktif, jangan paksa reconnect di sini
 	if DB != nil {
 		return
 	}
 
-	// ✅ Tambah parameter timeout + loc + charset supaya stabil
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true&loc=Local&charset=utf8mb4&timeout=5s&readTimeout=30s&writeTimeout=30s",
-		"root",           // username
-		"",               // password
-		"127.0.0.1:3306", // host:port
-		"travel_app",     // nama database
-	)
-
-	var err error
-	DB, err = sql.Open("mysql", dsn)
+	cfg, err := intconfig.LoadDBConfig()
 	if err != nil {
-		log.Fatalf("Gagal open DB: %v", err)
-	}
-
-	// ✅ Pool setting (penting untuk hindari invalid connection / stale conn)
-	DB.SetMaxOpenConns(25)
-	DB.SetMaxIdleConns(25)
-	DB.SetConnMaxLifetime(10 * time.Minute)
-	DB.SetConnMaxIdleTime(5 * time.Minute)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	if err = DB.PingContext(ctx); err != nil {
-		log.Fatalf("Gagal ping DB: %v", err)
+		log.Fatal(err)
 	}
-
-	log.Println("Berhasil konek ke database MySQL")
+	DB = intconfig.ConnectDB(cfg)
 }
 
 // EnsureDB: dipanggil sebelum transaksi dimulai.
@@ -63,17 +42,10 @@ func EnsureDB() error {
 	defer dbMu.Unlock()
 
 	if DB == nil {
-		ConnectDB()
+		connectLocked()
 		return nil
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-
-	if err := DB.PingContext(ctx); err != nil {
-		return err
-	}
-	return nil
+	return intconfig.EnsureDB()
 }
 
 func CloseDB() {
@@ -81,7 +53,7 @@ func CloseDB() {
 	defer dbMu.Unlock()
 
 	if DB != nil {
-		_ = DB.Close()
+		intconfig.CloseDB()
 		DB = nil
 	}
 }
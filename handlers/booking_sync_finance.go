@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"backend/config"
+	"backend/internal/services"
 	"database/sql"
 	"fmt"
 	"log"
@@ -96,99 +97,9 @@ func parseDayMonthYear(dateStr string) (int, int, int) {
 	return d.Day(), int(d.Month()), d.Year()
 }
 
-// routeFareIDR: daftar ongkos otomatis (bisa kamu perluas).
-func routeFareIDR(from, to string) int64 {
-	f := strings.ToLower(strings.TrimSpace(from))
-	t := strings.ToLower(strings.TrimSpace(to))
-	if f == "" || t == "" {
-		return 0
-	}
-
-	// kelompok Pasirpengaraian & sekitarnya
-	group := map[string]bool{
-		"skpd":            true,
-		"simpang d":       true,
-		"skpc":            true,
-		"simpang kumu":    true,
-		"muara rumbai":    true,
-		"surau tinggi":    true,
-		"pasirpengaraian": true,
-	}
-
-	isGroup := func(x string) bool { return group[x] }
-
-	// helper bidirectional fare
-	match := func(a, b string) bool {
-		return (f == a && t == b) || (f == b && t == a)
-	}
-
-	// 1) group <-> pekanbaru 150k
-	if (isGroup(f) && t == "pekanbaru") || (isGroup(t) && f == "pekanbaru") {
-		return 150_000
-	}
-
-	// 2) group <-> kabun 120k
-	if (isGroup(f) && t == "kabun") || (isGroup(t) && f == "kabun") {
-		return 120_000
-	}
-
-	// 4) group <-> tandun 100k
-	if (isGroup(f) && t == "tandun") || (isGroup(t) && f == "tandun") {
-		return 100_000
-	}
-
-	// 5) group <-> petapahan 130k
-	if (isGroup(f) && t == "petapahan") || (isGroup(t) && f == "petapahan") {
-		return 130_000
-	}
-
-	// 6) group <-> suram 120k
-	if (isGroup(f) && t == "suram") || (isGroup(t) && f == "suram") {
-		return 120_000
-	}
-
-	// 7) group <-> aliantan 120k
-	if (isGroup(f) && t == "aliantan") || (isGroup(t) && f == "aliantan") {
-		return 120_000
-	}
-
-	// 8) group <-> bangkinang 130k
-	if (isGroup(f) && t == "bangkinang") || (isGroup(t) && f == "bangkinang") {
-		return 130_000
-	}
-
-	// 9) bangkinang <-> pekanbaru 100k
-	if match("bangkinang", "pekanbaru") {
-		return 100_000
-	}
-
-	// 10) ujung batu <-> pekanbaru 130k
-	if match("ujung batu", "pekanbaru") {
-		return 130_000
-	}
-
-	// 11) suram <-> pekanbaru 120k
-	if match("suram", "pekanbaru") {
-		return 120_000
-	}
-
-	// 12) petapahan <-> pekanbaru 100k (catatan: kamu tulis nomor 7 lagi)
-	if match("petapahan", "pekanbaru") {
-		return 100_000
-	}
-
-	return 0
-}
-
-// regulerFareFromBooking: samakan tarif dengan aturan di reguler_handler.go (regulerFarePerSeat).
-// Jika tidak cocok, fallback ke routeFareIDR (legacy mapping).
+// regulerFareFromBooking: tarif per seat dari tabel fares (sumber yang sama dengan quote reguler).
 func regulerFareFromBooking(from, to string) int64 {
-	fk := normalizeKey(from)
-	tk := normalizeKey(to)
-	if fare := regulerFarePerSeat(fk, tk); fare > 0 {
-		return fare
-	}
-	return routeFareIDR(from, to)
+	return services.FareService{}.PricePerSeat(from, to, "", "", 0)
 }
 
 // loadDriverVehicleType: ambil vehicle_type dari akun driver (driver_accounts/drivers) berdasarkan driver name
@@ -3,6 +3,7 @@ package handlers
 
 import (
 	"backend/config"
+	"backend/internal/services"
 	"database/sql"
 	"errors"
 	"net/http"
@@ -44,120 +45,22 @@ type StopItem struct {
 	Display string `json:"display"`
 }
 
-// ====== util normalisasi ======
-
-func normalizeKey(s string) string {
-	s = strings.ToLower(strings.TrimSpace(s))
-	s = strings.ReplaceAll(s, " ", "")
-	s = strings.ReplaceAll(s, "-", "")
-	s = strings.ReplaceAll(s, ".", "")
-	return s
-}
+// ====== katalog stop & tarif (delegasi ke internal FareService) ======
 
 // canonical display name (konsisten untuk DB & unique index booking_seats)
 func canonicalStopDisplay(s string) (display string, key string, ok bool) {
-	k := normalizeKey(s)
-
-	switch k {
-	case "skpd":
-		return "SKPD", "skpd", true
-	case "simpangd":
-		return "Simpang D", "simpangd", true
-	case "skpc":
-		return "SKPC", "skpc", true
-	case "simpangkumu":
-		return "Simpang Kumu", "simpangkumu", true
-	case "muararumbai":
-		return "Muara Rumbai", "muararumbai", true
-	case "surautinggi":
-		return "Surau Tinggi", "surautinggi", true
-	case "pasirpengaraian", "pasipengaraian":
-		return "Pasir Pengaraian", "pasirpengaraian", true
-	case "ub", "ujungbatu":
-		return "Ujung Batu", "ujungbatu", true
-	case "tandun":
-		return "Tandun", "tandun", true
-	case "silam":
-		return "Silam", "silam", true
-	case "petapahan":
-		return "Petapahan", "petapahan", true
-	case "suram":
-		return "Suram", "suram", true
-	case "aliantan":
-		return "Aliantan", "aliantan", true
-	case "kuok":
-		return "Kuok", "kuok", true
-	case "bangkinang":
-		return "Bangkinang", "bangkinang", true
-	case "kabun":
-		return "Kabun", "kabun", true
-	case "pku", "pekanbaru":
-		return "Pekanbaru", "pekanbaru", true
-	default:
+	st, err := services.FareService{}.ResolveStop(s)
+	if err != nil {
 		return "", "", false
 	}
-}
-
-// ====== pricing rules (bidirectional) sesuai ongkos ======
-
-var groupA = map[string]bool{
-	"skpd":            true,
-	"simpangd":        true,
-	"skpc":            true,
-	"simpangkumu":     true,
-	"muararumbai":     true,
-	"surautinggi":     true,
-	"pasirpengaraian": true,
-}
-
-func isPair(a, b, x, y string) bool {
-	return (a == x && b == y) || (a == y && b == x)
+	return st.DisplayName, st.Key, true
 }
 
 func regulerFarePerSeat(fromKey, toKey string) int64 {
 	if fromKey == "" || toKey == "" || fromKey == toKey {
 		return 0
 	}
-
-	// khusus
-	if isPair(fromKey, toKey, "bangkinang", "pekanbaru") {
-		return 100000
-	}
-	if isPair(fromKey, toKey, "ujungbatu", "pekanbaru") {
-		return 130000
-	}
-	if isPair(fromKey, toKey, "suram", "pekanbaru") {
-		return 120000
-	}
-	if isPair(fromKey, toKey, "petapahan", "pekanbaru") {
-		return 100000
-	}
-
-	// Group A <-> tujuan tertentu
-	if groupA[fromKey] || groupA[toKey] {
-		other := toKey
-		if groupA[toKey] {
-			other = fromKey
-		}
-		switch other {
-		case "pekanbaru":
-			return 150000
-		case "kabun":
-			return 120000
-		case "tandun":
-			return 100000
-		case "petapahan":
-			return 130000
-		case "suram":
-			return 120000
-		case "aliantan":
-			return 120000
-		case "bangkinang":
-			return 130000
-		}
-	}
-
-	return 0
+	return services.FareService{}.PricePerSeat(fromKey, toKey, "", "", 0)
 }
 
 // ====== util validation ======
@@ -226,24 +129,14 @@ func bestOrderBy(db queryRower, table string) string {
 // ✅ 1) GET /api/reguler/stops
 // ======================================================
 func GetRegulerStops(c *gin.Context) {
-	stops := []StopItem{
-		{Key: "skpd", Display: "SKPD"},
-		{Key: "simpangd", Display: "Simpang D"},
-		{Key: "skpc", Display: "SKPC"},
-		{Key: "simpangkumu", Display: "Simpang Kumu"},
-		{Key: "muararumbai", Display: "Muara Rumbai"},
-		{Key: "surautinggi", Display: "Surau Tinggi"},
-		{Key: "pasirpengaraian", Display: "Pasir Pengaraian"},
-		{Key: "ujungbatu", Display: "Ujung Batu"},
-		{Key: "tandun", Display: "Tandun"},
-		{Key: "silam", Display: "Silam"},
-		{Key: "petapahan", Display: "Petapahan"},
-		{Key: "suram", Display: "Suram"},
-		{Key: "aliantan", Display: "Aliantan"},
-		{Key: "kuok", Display: "Kuok"},
-		{Key: "bangkinang", Display: "Bangkinang"},
-		{Key: "kabun", Display: "Kabun"},
-		{Key: "pekanbaru", Display: "Pekanbaru"},
+	list, err := services.FareService{}.ListStops(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal memuat daftar stop"})
+		return
+	}
+	stops := make([]StopItem, 0, len(list))
+	for _, st := range list {
+		stops = append(stops, StopItem{Key: st.Key, Display: st.DisplayName})
 	}
 	c.JSON(http.StatusOK, gin.H{"stops": stops})
 }
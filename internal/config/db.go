@@ -3,7 +3,6 @@ package config
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -12,45 +11,75 @@ import (
 )
 
 var (
-	DB   *sql.DB
-	dbMu sync.Mutex
+	DB     *sql.DB
+	ReadDB *sql.DB // read-replica opsional; nil bila DB_READ_DSN kosong
+	dbMu   sync.Mutex
+	dbCfg  DBConfig
 )
 
 // ConnectDB initializes the shared DB connection (idempotent).
-func ConnectDB() *sql.DB {
+// Bila cfg.ReadDSN diisi, koneksi read-replica juga dibuka ke ReadDB.
+func ConnectDB(cfg DBConfig) *sql.DB {
 	dbMu.Lock()
 	defer dbMu.Unlock()
+	return connectLocked(cfg)
+}
 
+func connectLocked(cfg DBConfig) *sql.DB {
 	if DB != nil {
 		return DB
 	}
+	dbCfg = cfg
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true&loc=Local&charset=utf8mb4&timeout=5s&readTimeout=30s&writeTimeout=30s",
-		"root",
-		"",
-		"127.0.0.1:3306",
-		"travel_app",
-	)
+	db, err := openPool(cfg.DSN(), cfg)
+	if err != nil {
+		log.Fatalf("Gagal konek DB %s: %v", cfg.Redacted(), err)
+	}
+	DB = db
+	log.Printf("Berhasil konek ke database MySQL (%s)", cfg.Redacted())
+
+	if cfg.ReadDSN != "" {
+		dsn, err := cfg.ReplicaDSN()
+		if err != nil {
+			log.Fatalf("DB_READ_DSN tidak valid: %v", err)
+		}
+		replica, err := openPool(dsn, cfg)
+		if err != nil {
+			log.Fatalf("Gagal konek read-replica: %v", err)
+		}
+		ReadDB = replica
+		log.Println("Read-replica aktif untuk laporan & list")
+	}
+	return DB
+}
 
+func openPool(dsn string, cfg DBConfig) (*sql.DB, error) {
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		log.Fatalf("Gagal open DB: %v", err)
+		return nil, err
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(10*time.Minute)
-	db.SetConnMaxIdleTime(5*time.Minute)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	if err := db.PingContext(ctx); err != nil {
-		log.Fatalf("Gagal ping DB: %v", err)
+		_ = db.Close()
+		return nil, err
 	}
+	return db, nil
+}
 
-	DB = db
-	log.Println("Berhasil konek ke database MySQL")
+// ReadOnlyDB returns the read replica when configured, otherwise the primary.
+// Hanya untuk query baca yang boleh sedikit tertinggal (replication lag).
+func ReadOnlyDB() *sql.DB {
+	if ReadDB != nil {
+		return ReadDB
+	}
 	return DB
 }
 
@@ -59,7 +88,7 @@ func EnsureDB() error {
 	defer dbMu.Unlock()
 
 	if DB == nil {
-		ConnectDB()
+		connectLocked(dbCfg)
 		return nil
 	}
 
@@ -76,6 +105,10 @@ func CloseDB() {
 	dbMu.Lock()
 	defer dbMu.Unlock()
 
+	if ReadDB != nil {
+		_ = ReadDB.Close()
+		ReadDB = nil
+	}
 	if DB != nil {
 		_ = DB.Close()
 		DB = nil
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DBConfig berisi semua setting koneksi MySQL yang dibaca dari environment.
+type DBConfig struct {
+	Host string
+	Port int
+	User string
+	Pass string
+	Name string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// TLS mengikuti parameter tls go-sql-driver: "", true, false, skip-verify, preferred.
+	TLS      string
+	Timezone string
+
+	// ReadDSN opsional: DSN read-replica untuk laporan & endpoint list.
+	ReadDSN string
+}
+
+// ConfigError mengumpulkan semua masalah konfigurasi supaya bisa diperbaiki sekaligus.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e ConfigError) Error() string {
+	return "konfigurasi database tidak valid:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// LoadDBConfig membaca DB_* dari environment. DB_HOST, DB_USER dan DB_NAME wajib diisi.
+func LoadDBConfig() (DBConfig, error) {
+	var problems []string
+
+	required := func(key string) string {
+		v := strings.TrimSpace(os.Getenv(key))
+		if v == "" {
+			problems = append(problems, key+" belum diatur")
+		}
+		return v
+	}
+	intVal := func(key string, def int) int {
+		v := strings.TrimSpace(os.Getenv(key))
+		if v == "" {
+			return def
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			problems = append(problems, fmt.Sprintf("%s harus angka >= 0, didapat %q", key, v))
+			return def
+		}
+		return n
+	}
+	durVal := func(key string, def time.Duration) time.Duration {
+		v := strings.TrimSpace(os.Getenv(key))
+		if v == "" {
+			return def
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			problems = append(problems, fmt.Sprintf("%s harus durasi (contoh 30s, 5m), didapat %q", key, v))
+			return def
+		}
+		return d
+	}
+
+	cfg := DBConfig{
+		Host: required("DB_HOST"),
+		User: required("DB_USER"),
+		Pass: os.Getenv("DB_PASS"),
+		Name: required("DB_NAME"),
+		Port: intVal("DB_PORT", 3306),
+
+		MaxOpenConns:    intVal("DB_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:    intVal("DB_MAX_IDLE_CONNS", 25),
+		ConnMaxLifetime: durVal("DB_CONN_MAX_LIFETIME", 10*time.Minute),
+		ConnMaxIdleTime: durVal("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+
+		DialTimeout:  durVal("DB_TIMEOUT", 5*time.Second),
+		ReadTimeout:  durVal("DB_READ_TIMEOUT", 30*time.Second),
+		WriteTimeout: durVal("DB_WRITE_TIMEOUT", 30*time.Second),
+
+		TLS:      strings.TrimSpace(os.Getenv("DB_TLS")),
+		Timezone: strings.TrimSpace(os.Getenv("DB_TIMEZONE")),
+		ReadDSN:  strings.TrimSpace(os.Getenv("DB_READ_DSN")),
+	}
+	if cfg.Timezone == "" {
+		cfg.Timezone = "Local"
+	}
+
+	if cfg.Port <= 0 || cfg.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("DB_PORT di luar rentang: %d", cfg.Port))
+	}
+	switch strings.ToLower(cfg.TLS) {
+	case "", "true", "false", "skip-verify", "preferred":
+	default:
+		problems = append(problems, fmt.Sprintf("DB_TLS harus true|false|skip-verify|preferred, didapat %q", cfg.TLS))
+	}
+	if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+		problems = append(problems, fmt.Sprintf("DB_TIMEZONE tidak dikenal: %q", cfg.Timezone))
+	}
+	if cfg.ReadDSN != "" {
+		if _, err := mysql.ParseDSN(cfg.ReadDSN); err != nil {
+			problems = append(problems, "DB_READ_DSN tidak valid: "+err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return cfg, ConfigError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+func (c DBConfig) location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// DSN membangun DSN primary. Password tidak pernah dicetak ke log; pakai Redacted untuk logging.
+func (c DBConfig) DSN() string {
+	m := mysql.NewConfig()
+	m.User = c.User
+	m.Passwd = c.Pass
+	m.Net = "tcp"
+	m.Addr = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	m.DBName = c.Name
+	m.ParseTime = true
+	m.Loc = c.location()
+	m.Timeout = c.DialTimeout
+	m.ReadTimeout = c.ReadTimeout
+	m.WriteTimeout = c.WriteTimeout
+	m.TLSConfig = strings.ToLower(c.TLS)
+	m.Params = map[string]string{"charset": "utf8mb4"}
+	return m.FormatDSN()
+}
+
+// ReplicaDSN menormalkan DB_READ_DSN supaya parseTime/loc sama dengan primary.
+func (c DBConfig) ReplicaDSN() (string, error) {
+	if c.ReadDSN == "" {
+		return "", nil
+	}
+	m, err := mysql.ParseDSN(c.ReadDSN)
+	if err != nil {
+		return "", err
+	}
+	m.ParseTime = true
+	m.Loc = c.location()
+	return m.FormatDSN(), nil
+}
+
+// Redacted mengembalikan ringkasan koneksi yang aman untuk log.
+func (c DBConfig) Redacted() string {
+	return fmt.Sprintf("%s@%s:%d/%s", c.User, c.Host, c.Port, c.Name)
+}
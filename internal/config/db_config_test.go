@@ -0,0 +1,64 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestLoadDBConfigListsAllMissingKeys(t *testing.T) {
+	t.Setenv("DB_HOST", "")
+	t.Setenv("DB_USER", "")
+	t.Setenv("DB_NAME", "")
+	t.Setenv("DB_PORT", "abc")
+	t.Setenv("DB_TLS", "")
+	t.Setenv("DB_TIMEZONE", "")
+	t.Setenv("DB_READ_DSN", "")
+
+	_, err := LoadDBConfig()
+	if err == nil {
+		t.Fatalf("expected error for missing DB settings")
+	}
+	msg := err.Error()
+	for _, key := range []string{"DB_HOST", "DB_USER", "DB_NAME", "DB_PORT"} {
+		if !strings.Contains(msg, key) {
+			t.Fatalf("error should mention %s, got:\n%s", key, msg)
+		}
+	}
+}
+
+func TestDBConfigDSN(t *testing.T) {
+	t.Setenv("DB_HOST", "db.internal")
+	t.Setenv("DB_PORT", "3307")
+	t.Setenv("DB_USER", "app")
+	t.Setenv("DB_PASS", "p@ss:word")
+	t.Setenv("DB_NAME", "travel_app")
+	t.Setenv("DB_TLS", "skip-verify")
+	t.Setenv("DB_TIMEZONE", "Asia/Jakarta")
+	t.Setenv("DB_READ_DSN", "ro:secret@tcp(replica:3306)/travel_app")
+
+	cfg, err := LoadDBConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := mysql.ParseDSN(cfg.DSN())
+	if err != nil {
+		t.Fatalf("generated DSN does not parse: %v", err)
+	}
+	if parsed.Addr != "db.internal:3307" || parsed.Passwd != "p@ss:word" || parsed.DBName != "travel_app" {
+		t.Fatalf("unexpected DSN fields: %+v", parsed)
+	}
+	if !parsed.ParseTime || parsed.Loc.String() != "Asia/Jakarta" || parsed.TLSConfig != "skip-verify" {
+		t.Fatalf("DSN options not applied: parseTime=%v loc=%v tls=%q", parsed.ParseTime, parsed.Loc, parsed.TLSConfig)
+	}
+
+	replica, err := cfg.ReplicaDSN()
+	if err != nil {
+		t.Fatalf("replica dsn error: %v", err)
+	}
+	if rp, _ := mysql.ParseDSN(replica); rp == nil || !rp.ParseTime || rp.Addr != "replica:3306" {
+		t.Fatalf("replica DSN should keep address and force parseTime, got %q", replica)
+	}
+}
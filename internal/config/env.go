@@ -1,13 +1,66 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// legacyJWTSecret hanya dipakai di mode development bila JWT_SECRET belum diatur.
+const legacyJWTSecret = "super-secret-key-change-me"
+
 type Env struct {
 	AppAddr string
 	GinMode string
+
+	DB DBConfig
+
+	JWTSecret       string
+	JWTTTL          time.Duration
+	RefreshTokenTTL time.Duration
+
+	SchemaCacheTTL time.Duration
+
+	SeatHoldTTL           time.Duration
+	SeatHoldSweepInterval time.Duration
+
+	ScheduleHorizonDays      int
+	ScheduleGenerateInterval time.Duration
+
+	CancelFreeHours  int
+	CancelFeePercent int
+
+	PaymentDeadline       time.Duration
+	PaymentCutoffHours    int
+	BookingExpiryInterval time.Duration
+
+	PaymentProvider   string
+	MidtransServerKey string
+	MidtransBaseURL   string
+	FakePaymentSecret string
+	PaymentChargeTTL  time.Duration
+
+	FileStore     string
+	FileStoreDir  string
+	PublicBaseURL string
+	FileURLSecret string
+	FileURLTTL    time.Duration
+	FileMaxBytes  int64
+	S3Endpoint    string
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+	S3PathStyle   bool
+
+	TripDuration    time.Duration
+	RestAfterReturn time.Duration
+
+	OutboxInterval    time.Duration
+	OutboxMaxAttempts int
+	OutboxBackoff     time.Duration
 }
 
 func LoadEnv() Env {
@@ -18,8 +71,303 @@ func LoadEnv() Env {
 
 	ginMode := strings.TrimSpace(os.Getenv("GIN_MODE"))
 
+	dbCfg, err := LoadDBConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	jwtSecret := strings.TrimSpace(os.Getenv("JWT_SECRET"))
+	if jwtSecret == "" {
+		if ginMode == "release" {
+			log.Fatal("JWT_SECRET wajib diatur saat GIN_MODE=release")
+		}
+		log.Println("warning: JWT_SECRET kosong, memakai secret default (hanya untuk development)")
+		jwtSecret = legacyJWTSecret
+	}
+
+	jwtTTL := 24 * time.Hour
+	if v := strings.TrimSpace(os.Getenv("JWT_TTL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("JWT_TTL tidak valid (contoh: 15m, 24h): %q", v)
+		}
+		jwtTTL = d
+	}
+
+	refreshTTL := 30 * 24 * time.Hour
+	if v := strings.TrimSpace(os.Getenv("REFRESH_TOKEN_TTL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("REFRESH_TOKEN_TTL tidak valid (contoh: 168h, 720h): %q", v)
+		}
+		refreshTTL = d
+	}
+
+	schemaTTL := 10 * time.Minute
+	if v := strings.TrimSpace(os.Getenv("SCHEMA_CACHE_TTL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("SCHEMA_CACHE_TTL tidak valid (contoh: 5m, 1h): %q", v)
+		}
+		schemaTTL = d
+	}
+
+	seatHoldTTL := 10 * time.Minute
+	if v := strings.TrimSpace(os.Getenv("SEAT_HOLD_TTL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("SEAT_HOLD_TTL tidak valid (contoh: 5m, 15m): %q", v)
+		}
+		seatHoldTTL = d
+	}
+
+	seatHoldSweep := time.Minute
+	if v := strings.TrimSpace(os.Getenv("SEAT_HOLD_SWEEP_INTERVAL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("SEAT_HOLD_SWEEP_INTERVAL tidak valid (contoh: 30s, 1m): %q", v)
+		}
+		seatHoldSweep = d
+	}
+
+	scheduleHorizon := 14
+	if v := strings.TrimSpace(os.Getenv("SCHEDULE_HORIZON_DAYS")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 366 {
+			log.Fatalf("SCHEDULE_HORIZON_DAYS tidak valid (1-366): %q", v)
+		}
+		scheduleHorizon = n
+	}
+
+	scheduleInterval := time.Hour
+	if v := strings.TrimSpace(os.Getenv("SCHEDULE_GENERATE_INTERVAL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("SCHEDULE_GENERATE_INTERVAL tidak valid (contoh: 30m, 6h): %q", v)
+		}
+		scheduleInterval = d
+	}
+
+	cancelFreeHours := 24
+	if v := strings.TrimSpace(os.Getenv("CANCEL_FREE_HOURS")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatalf("CANCEL_FREE_HOURS tidak valid (jam, >= 0): %q", v)
+		}
+		cancelFreeHours = n
+	}
+
+	cancelFeePercent := 25
+	if v := strings.TrimSpace(os.Getenv("CANCEL_FEE_PERCENT")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 100 {
+			log.Fatalf("CANCEL_FEE_PERCENT tidak valid (0-100): %q", v)
+		}
+		cancelFeePercent = n
+	}
+
+	paymentDeadline := 2 * time.Hour
+	if v := strings.TrimSpace(os.Getenv("PAYMENT_DEADLINE")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("PAYMENT_DEADLINE tidak valid (contoh: 30m, 2h; 0 = nonaktif): %q", v)
+		}
+		paymentDeadline = d
+	}
+
+	paymentCutoff := 3
+	if v := strings.TrimSpace(os.Getenv("PAYMENT_CUTOFF_HOURS")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatalf("PAYMENT_CUTOFF_HOURS tidak valid (jam, >= 0): %q", v)
+		}
+		paymentCutoff = n
+	}
+
+	expiryInterval := time.Minute
+	if v := strings.TrimSpace(os.Getenv("BOOKING_EXPIRY_INTERVAL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("BOOKING_EXPIRY_INTERVAL tidak valid (contoh: 30s, 1m): %q", v)
+		}
+		expiryInterval = d
+	}
+
+	paymentProvider := strings.ToLower(strings.TrimSpace(os.Getenv("PAYMENT_PROVIDER")))
+	switch paymentProvider {
+	case "", "fake", "midtrans":
+	default:
+		log.Fatalf("PAYMENT_PROVIDER tidak valid (kosong, fake, midtrans): %q", paymentProvider)
+	}
+	if paymentProvider == "fake" && ginMode == "release" {
+		log.Fatal("PAYMENT_PROVIDER=fake tidak boleh dipakai saat GIN_MODE=release")
+	}
+
+	midtransKey := strings.TrimSpace(os.Getenv("MIDTRANS_SERVER_KEY"))
+	if paymentProvider == "midtrans" && midtransKey == "" {
+		log.Fatal("MIDTRANS_SERVER_KEY wajib diatur saat PAYMENT_PROVIDER=midtrans")
+	}
+	midtransBaseURL := strings.TrimSpace(os.Getenv("MIDTRANS_BASE_URL"))
+	if midtransBaseURL == "" {
+		midtransBaseURL = "https://api.sandbox.midtrans.com"
+	}
+
+	fakeSecret := strings.TrimSpace(os.Getenv("FAKE_PAYMENT_SECRET"))
+	if paymentProvider == "fake" && fakeSecret == "" {
+		log.Println("warning: FAKE_PAYMENT_SECRET kosong, memakai secret default (hanya untuk development)")
+		fakeSecret = "fake-payment-secret"
+	}
+
+	chargeTTL := time.Hour
+	if v := strings.TrimSpace(os.Getenv("PAYMENT_CHARGE_TTL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("PAYMENT_CHARGE_TTL tidak valid (contoh: 30m, 1h): %q", v)
+		}
+		chargeTTL = d
+	}
+
+	fileStore := strings.ToLower(strings.TrimSpace(os.Getenv("FILE_STORE")))
+	if fileStore == "" {
+		fileStore = "local"
+	}
+	if fileStore != "local" && fileStore != "s3" {
+		log.Fatalf("FILE_STORE tidak valid (local, s3): %q", fileStore)
+	}
+	fileStoreDir := strings.TrimSpace(os.Getenv("FILE_STORE_DIR"))
+	if fileStoreDir == "" {
+		fileStoreDir = "storage"
+	}
+	fileURLSecret := strings.TrimSpace(os.Getenv("FILE_URL_SECRET"))
+	if fileURLSecret == "" {
+		fileURLSecret = jwtSecret
+	}
+
+	fileURLTTL := 15 * time.Minute
+	if v := strings.TrimSpace(os.Getenv("FILE_URL_TTL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("FILE_URL_TTL tidak valid (contoh: 15m, 1h): %q", v)
+		}
+		fileURLTTL = d
+	}
+
+	fileMaxBytes := int64(5 << 20)
+	if v := strings.TrimSpace(os.Getenv("FILE_MAX_BYTES")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			log.Fatalf("FILE_MAX_BYTES tidak valid (byte, > 0): %q", v)
+		}
+		fileMaxBytes = n
+	}
+
+	s3PathStyle := false
+	if v := strings.TrimSpace(os.Getenv("S3_PATH_STYLE")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("S3_PATH_STYLE tidak valid (true/false): %q", v)
+		}
+		s3PathStyle = b
+	}
+	s3Endpoint := strings.TrimSpace(os.Getenv("S3_ENDPOINT"))
+	s3Bucket := strings.TrimSpace(os.Getenv("S3_BUCKET"))
+	s3AccessKey := strings.TrimSpace(os.Getenv("S3_ACCESS_KEY"))
+	s3SecretKey := strings.TrimSpace(os.Getenv("S3_SECRET_KEY"))
+	if fileStore == "s3" && (s3Endpoint == "" || s3Bucket == "" || s3AccessKey == "" || s3SecretKey == "") {
+		log.Fatal("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY dan S3_SECRET_KEY wajib diatur saat FILE_STORE=s3")
+	}
+
+	tripDuration := 5 * time.Hour
+	if v := strings.TrimSpace(os.Getenv("TRIP_DURATION")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("TRIP_DURATION tidak valid (contoh: 4h, 5h30m): %q", v)
+		}
+		tripDuration = d
+	}
+
+	restAfterReturn := 8 * time.Hour
+	if v := strings.TrimSpace(os.Getenv("DRIVER_REST_AFTER_RETURN")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("DRIVER_REST_AFTER_RETURN tidak valid (contoh: 8h; 0 = tanpa jeda): %q", v)
+		}
+		restAfterReturn = d
+	}
+
+	outboxInterval := 5 * time.Second
+	if v := strings.TrimSpace(os.Getenv("OUTBOX_INTERVAL")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("OUTBOX_INTERVAL tidak valid (contoh: 5s, 1m): %q", v)
+		}
+		outboxInterval = d
+	}
+
+	outboxMaxAttempts := 8
+	if v := strings.TrimSpace(os.Getenv("OUTBOX_MAX_ATTEMPTS")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("OUTBOX_MAX_ATTEMPTS tidak valid (angka > 0): %q", v)
+		}
+		outboxMaxAttempts = n
+	}
+
+	outboxBackoff := 30 * time.Second
+	if v := strings.TrimSpace(os.Getenv("OUTBOX_BACKOFF")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("OUTBOX_BACKOFF tidak valid (contoh: 30s, 1m): %q", v)
+		}
+		outboxBackoff = d
+	}
+
 	return Env{
-		AppAddr: appAddr,
-		GinMode: ginMode,
+		AppAddr:         appAddr,
+		GinMode:         ginMode,
+		DB:              dbCfg,
+		JWTSecret:       jwtSecret,
+		JWTTTL:          jwtTTL,
+		RefreshTokenTTL: refreshTTL,
+		SchemaCacheTTL:  schemaTTL,
+
+		SeatHoldTTL:           seatHoldTTL,
+		SeatHoldSweepInterval: seatHoldSweep,
+
+		ScheduleHorizonDays:      scheduleHorizon,
+		ScheduleGenerateInterval: scheduleInterval,
+
+		CancelFreeHours:  cancelFreeHours,
+		CancelFeePercent: cancelFeePercent,
+
+		PaymentDeadline:       paymentDeadline,
+		PaymentCutoffHours:    paymentCutoff,
+		BookingExpiryInterval: expiryInterval,
+
+		PaymentProvider:   paymentProvider,
+		MidtransServerKey: midtransKey,
+		MidtransBaseURL:   midtransBaseURL,
+		FakePaymentSecret: fakeSecret,
+		PaymentChargeTTL:  chargeTTL,
+
+		FileStore:     fileStore,
+		FileStoreDir:  fileStoreDir,
+		PublicBaseURL: strings.TrimRight(strings.TrimSpace(os.Getenv("PUBLIC_BASE_URL")), "/"),
+		FileURLSecret: fileURLSecret,
+		FileURLTTL:    fileURLTTL,
+		FileMaxBytes:  fileMaxBytes,
+		S3Endpoint:    s3Endpoint,
+		S3Region:      strings.TrimSpace(os.Getenv("S3_REGION")),
+		S3Bucket:      s3Bucket,
+		S3AccessKey:   s3AccessKey,
+		S3SecretKey:   s3SecretKey,
+		S3PathStyle:   s3PathStyle,
+
+		TripDuration:    tripDuration,
+		RestAfterReturn: restAfterReturn,
+
+		OutboxInterval:    outboxInterval,
+		OutboxMaxAttempts: outboxMaxAttempts,
+		OutboxBackoff:     outboxBackoff,
 	}
 }
@@ -4,13 +4,20 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
-	"sync"
 )
 
 type QueryRower interface {
 	QueryRow(query string, args ...any) *sql.Row
 }
 
+// Queryer dipenuhi *sql.DB maupun *sql.Tx; repository/service yang bisa berjalan di dalam
+// transaksi pemanggil atau langsung di db menerima Queryer.
+type Queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 // NullIfEmpty helps store optional strings without wiping existing data.
 func NullIfEmpty(s string) any {
 	if s == "" {
@@ -19,19 +26,18 @@ func NullIfEmpty(s string) any {
 	return s
 }
 
-var (
-	tableCache  sync.Map // map[string]bool
-	columnCache sync.Map // map[string]bool, key: "table|column"
-)
-
+// HasTable reports whether the table exists, answered from the shared SchemaCache.
 func HasTable(q QueryRower, table string) bool {
-	if table == "" {
-		return false
-	}
-	if v, ok := tableCache.Load(table); ok {
-		return v.(bool)
-	}
+	return Schema.HasTable(q, table)
+}
 
+// HasColumn reports whether table.column exists, answered from the shared SchemaCache.
+func HasColumn(q QueryRower, table, column string) bool {
+	return Schema.HasColumn(q, table, column)
+}
+
+// probeTable queries information_schema directly; fallback when no snapshot is available.
+func probeTable(q QueryRower, table string) bool {
 	var name sql.NullString
 	err := q.QueryRow(`
 		SELECT table_name
@@ -45,20 +51,11 @@ func HasTable(q QueryRower, table string) bool {
 	if err != nil && errors.Is(err, driver.ErrBadConn) {
 		ok = false
 	}
-
-	tableCache.Store(table, ok)
 	return ok
 }
 
-func HasColumn(q QueryRower, table, column string) bool {
-	if table == "" || column == "" {
-		return false
-	}
-	key := table + "|" + column
-	if v, ok := columnCache.Load(key); ok {
-		return v.(bool)
-	}
-
+// probeColumn queries information_schema directly; fallback when no snapshot is available.
+func probeColumn(q QueryRower, table, column string) bool {
 	var name sql.NullString
 	err := q.QueryRow(`
 		SELECT column_name
@@ -73,8 +70,6 @@ func HasColumn(q QueryRower, table, column string) bool {
 	if err != nil && errors.Is(err, driver.ErrBadConn) {
 		ok = false
 	}
-
-	columnCache.Store(key, ok)
 	return ok
 }
 
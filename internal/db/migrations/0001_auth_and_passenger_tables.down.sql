@@ -0,0 +1,2 @@
+-- booking_passengers sudah ada sebelum migration diperkenalkan (dibuat runtime), jadi tidak di-drop.
+DROP TABLE IF EXISTS user_sessions;
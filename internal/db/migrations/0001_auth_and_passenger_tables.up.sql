@@ -0,0 +1,33 @@
+-- Tabel yang sebelumnya dibuat saat runtime (ensureBookingPassengerTable, SessionRepository).
+
+CREATE TABLE IF NOT EXISTS booking_passengers (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	booking_id BIGINT NOT NULL,
+	seat_code VARCHAR(50) NOT NULL,
+	passenger_name VARCHAR(255) NOT NULL,
+	passenger_phone VARCHAR(100) NOT NULL,
+	paid_price BIGINT NULL DEFAULT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_booking_seat (booking_id, seat_code),
+	KEY idx_booking (booking_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	family_id VARCHAR(64) NOT NULL,
+	token_hash CHAR(64) NOT NULL,
+	device_name VARCHAR(255) NOT NULL DEFAULT '',
+	user_agent VARCHAR(512) NOT NULL DEFAULT '',
+	ip_address VARCHAR(64) NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	last_used_at DATETIME NULL DEFAULT NULL,
+	rotated_at DATETIME NULL DEFAULT NULL,
+	replaced_by BIGINT NULL DEFAULT NULL,
+	revoked_at DATETIME NULL DEFAULT NULL,
+	revoke_reason VARCHAR(100) NOT NULL DEFAULT '',
+	UNIQUE KEY uniq_token_hash (token_hash),
+	KEY idx_user (user_id),
+	KEY idx_family (family_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,75 @@
+-- Menyeragamkan nama kolom alias lama ke nama kanonik yang dipakai kode baru.
+-- Untuk setiap pasangan (alias -> kanonik):
+--   * kanonik belum ada  => alias di-rename menjadi kanonik
+--   * keduanya ada       => kanonik yang kosong diisi dari alias (alias tidak dihapus)
+-- Migration ini tidak bisa di-rollback (tidak ada file .down.sql).
+
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_normalize_column(IN p_table VARCHAR(64), IN p_alias VARCHAR(64), IN p_canonical VARCHAR(64))
+BEGIN
+	DECLARE has_alias INT DEFAULT 0;
+	DECLARE has_canonical INT DEFAULT 0;
+
+	SELECT COUNT(*) INTO has_alias FROM information_schema.columns
+	WHERE table_schema = DATABASE() AND table_name = p_table AND column_name = p_alias;
+	SELECT COUNT(*) INTO has_canonical FROM information_schema.columns
+	WHERE table_schema = DATABASE() AND table_name = p_table AND column_name = p_canonical;
+
+	IF has_alias > 0 AND has_canonical = 0 THEN
+		SET @migrate_sql = CONCAT('ALTER TABLE `', p_table, '` RENAME COLUMN `', p_alias, '` TO `', p_canonical, '`');
+		PREPARE migrate_stmt FROM @migrate_sql;
+		EXECUTE migrate_stmt;
+		DEALLOCATE PREPARE migrate_stmt;
+	ELSEIF has_alias > 0 AND has_canonical > 0 THEN
+		SET @migrate_sql = CONCAT(
+			'UPDATE `', p_table, '` SET `', p_canonical, '` = `', p_alias, '` ',
+			'WHERE (`', p_canonical, '` IS NULL OR CAST(`', p_canonical, '` AS CHAR) = '''') ',
+			'AND `', p_alias, '` IS NOT NULL');
+		PREPARE migrate_stmt FROM @migrate_sql;
+		EXECUTE migrate_stmt;
+		DEALLOCATE PREPARE migrate_stmt;
+	END IF;
+END
+-- +migrate StatementEnd
+
+-- bookings
+CALL migrate_normalize_column('bookings', 'service_type', 'category');
+CALL migrate_normalize_column('bookings', 'serviceType', 'category');
+CALL migrate_normalize_column('bookings', 'layanan', 'category');
+CALL migrate_normalize_column('bookings', 'origin', 'route_from');
+CALL migrate_normalize_column('bookings', 'from_city', 'route_from');
+CALL migrate_normalize_column('bookings', 'routeFrom', 'route_from');
+CALL migrate_normalize_column('bookings', 'destination', 'route_to');
+CALL migrate_normalize_column('bookings', 'to_city', 'route_to');
+CALL migrate_normalize_column('bookings', 'routeTo', 'route_to');
+CALL migrate_normalize_column('bookings', 'departure_date', 'trip_date');
+CALL migrate_normalize_column('bookings', 'departureDate', 'trip_date');
+CALL migrate_normalize_column('bookings', 'departure_time', 'trip_time');
+CALL migrate_normalize_column('bookings', 'departureTime', 'trip_time');
+CALL migrate_normalize_column('bookings', 'booking_name', 'passenger_name');
+CALL migrate_normalize_column('bookings', 'bookingName', 'passenger_name');
+CALL migrate_normalize_column('bookings', 'customer_name', 'passenger_name');
+CALL migrate_normalize_column('bookings', 'nama_pemesan', 'passenger_name');
+CALL migrate_normalize_column('bookings', 'customer_phone', 'passenger_phone');
+CALL migrate_normalize_column('bookings', 'no_hp', 'passenger_phone');
+CALL migrate_normalize_column('bookings', 'passengerCount', 'passenger_count');
+CALL migrate_normalize_column('bookings', 'jumlah_penumpang', 'passenger_count');
+CALL migrate_normalize_column('bookings', 'pickup_address', 'pickup_location');
+CALL migrate_normalize_column('bookings', 'pickupAddress', 'pickup_location');
+CALL migrate_normalize_column('bookings', 'alamat_jemput', 'pickup_location');
+CALL migrate_normalize_column('bookings', 'dropoff_address', 'dropoff_location');
+CALL migrate_normalize_column('bookings', 'dropoffAddress', 'dropoff_location');
+CALL migrate_normalize_column('bookings', 'alamat_turun', 'dropoff_location');
+CALL migrate_normalize_column('bookings', 'total_amount', 'total');
+CALL migrate_normalize_column('bookings', 'grand_total', 'total');
+CALL migrate_normalize_column('bookings', 'totalHarga', 'total');
+CALL migrate_normalize_column('bookings', 'total_harga', 'total');
+CALL migrate_normalize_column('bookings', 'role_trip', 'trip_role');
+CALL migrate_normalize_column('bookings', 'tripRole', 'trip_role');
+
+-- departure_settings
+CALL migrate_normalize_column('departure_settings', 'driver', 'driver_name');
+CALL migrate_normalize_column('departure_settings', 'car_code', 'vehicle_code');
+CALL migrate_normalize_column('departure_settings', 'reguler_booking_id', 'booking_id');
+
+DROP PROCEDURE IF EXISTS migrate_normalize_column;
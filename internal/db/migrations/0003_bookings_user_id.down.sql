@@ -0,0 +1,21 @@
+-- Mengikuti guard di .up.sql: hanya dijalankan bila kolom/index-nya ada.
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_drop_bookings_user_id()
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = 'bookings' AND index_name = 'idx_bookings_user'
+	) THEN
+		ALTER TABLE bookings DROP KEY idx_bookings_user;
+	END IF;
+	IF EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'bookings' AND column_name = 'user_id'
+	) THEN
+		ALTER TABLE bookings DROP COLUMN user_id;
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_drop_bookings_user_id();
+DROP PROCEDURE IF EXISTS migrate_drop_bookings_user_id;
@@ -0,0 +1,15 @@
+-- Pemilik booking untuk pembatasan akses customer (lihat requireBookingAccess).
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_bookings_user_id()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'bookings' AND column_name = 'user_id'
+	) THEN
+		ALTER TABLE bookings ADD COLUMN user_id BIGINT NULL DEFAULT NULL, ADD KEY idx_bookings_user (user_id);
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_bookings_user_id();
+DROP PROCEDURE IF EXISTS migrate_add_bookings_user_id;
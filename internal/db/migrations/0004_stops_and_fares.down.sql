@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS fares;
+DROP TABLE IF EXISTS stops;
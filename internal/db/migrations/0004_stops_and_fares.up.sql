@@ -0,0 +1,72 @@
+-- Katalog halte dan matriks tarif (sebelumnya hard-coded di canonicalStopDisplay,
+-- regulerFarePerSeat, utils.ComputeFare dan routeFareIDR).
+
+CREATE TABLE IF NOT EXISTS stops (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	stop_key VARCHAR(64) NOT NULL,
+	display_name VARCHAR(100) NOT NULL,
+	aliases VARCHAR(500) NOT NULL DEFAULT '',
+	cluster VARCHAR(64) NULL DEFAULT NULL,
+	sort_order INT NOT NULL DEFAULT 0,
+	is_active TINYINT(1) NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_stop_key (stop_key),
+	KEY idx_cluster (cluster)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- origin/destination diisi salah satu: *_key (halte) atau *_cluster (kelompok halte).
+-- category NULL = berlaku untuk semua kategori; valid_from/valid_to NULL = tanpa batas.
+CREATE TABLE IF NOT EXISTS fares (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	origin_key VARCHAR(64) NULL DEFAULT NULL,
+	origin_cluster VARCHAR(64) NULL DEFAULT NULL,
+	destination_key VARCHAR(64) NULL DEFAULT NULL,
+	destination_cluster VARCHAR(64) NULL DEFAULT NULL,
+	category VARCHAR(50) NULL DEFAULT NULL,
+	price BIGINT NOT NULL,
+	bidirectional TINYINT(1) NOT NULL DEFAULT 1,
+	valid_from DATE NULL DEFAULT NULL,
+	valid_to DATE NULL DEFAULT NULL,
+	is_active TINYINT(1) NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	KEY idx_origin (origin_key, origin_cluster),
+	KEY idx_destination (destination_key, destination_cluster)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT IGNORE INTO stops (stop_key, display_name, aliases, cluster, sort_order) VALUES
+	('skpd', 'SKPD', '', 'rohul', 1),
+	('simpangd', 'Simpang D', '', 'rohul', 2),
+	('skpc', 'SKPC', '', 'rohul', 3),
+	('simpangkumu', 'Simpang Kumu', '', 'rohul', 4),
+	('muararumbai', 'Muara Rumbai', '', 'rohul', 5),
+	('surautinggi', 'Surau Tinggi', '', 'rohul', 6),
+	('pasirpengaraian', 'Pasir Pengaraian', 'pasipengaraian', 'rohul', 7),
+	('ujungbatu', 'Ujung Batu', 'ub', NULL, 8),
+	('tandun', 'Tandun', '', NULL, 9),
+	('silam', 'Silam', '', NULL, 10),
+	('petapahan', 'Petapahan', '', NULL, 11),
+	('suram', 'Suram', '', NULL, 12),
+	('aliantan', 'Aliantan', '', NULL, 13),
+	('kuok', 'Kuok', '', NULL, 14),
+	('bangkinang', 'Bangkinang', '', NULL, 15),
+	('kabun', 'Kabun', '', NULL, 16),
+	('pekanbaru', 'Pekanbaru', 'pku', NULL, 17);
+
+-- Tarif halte <-> halte (lebih spesifik, menang atas tarif cluster)
+INSERT INTO fares (origin_key, destination_key, price) VALUES
+	('bangkinang', 'pekanbaru', 100000),
+	('ujungbatu', 'pekanbaru', 130000),
+	('suram', 'pekanbaru', 120000),
+	('petapahan', 'pekanbaru', 100000);
+
+-- Tarif cluster Rokan Hulu (SKPD s/d Pasir Pengaraian) <-> halte tujuan
+INSERT INTO fares (origin_cluster, destination_key, price) VALUES
+	('rohul', 'pekanbaru', 150000),
+	('rohul', 'kabun', 120000),
+	('rohul', 'tandun', 100000),
+	('rohul', 'petapahan', 130000),
+	('rohul', 'suram', 120000),
+	('rohul', 'aliantan', 120000),
+	('rohul', 'bangkinang', 130000);
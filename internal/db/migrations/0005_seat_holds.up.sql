@@ -0,0 +1,17 @@
+-- Penahanan seat sementara selama checkout. Satu seat per slot hanya boleh
+-- punya satu hold; hold kedaluwarsa dibersihkan sweeper (dan sebelum hold baru dibuat).
+CREATE TABLE IF NOT EXISTS seat_holds (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	hold_token VARCHAR(64) NOT NULL,
+	user_id BIGINT NULL DEFAULT NULL,
+	route_from VARCHAR(100) NOT NULL,
+	route_to VARCHAR(100) NOT NULL,
+	trip_date DATE NOT NULL,
+	trip_time VARCHAR(10) NOT NULL,
+	seat_code VARCHAR(10) NOT NULL,
+	expires_at DATETIME NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_hold_seat (route_from, route_to, trip_date, trip_time, seat_code),
+	KEY idx_hold_token (hold_token),
+	KEY idx_hold_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
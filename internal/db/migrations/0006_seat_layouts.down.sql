@@ -0,0 +1,2 @@
+ALTER TABLE vehicles DROP KEY idx_vehicles_vehicle_type, DROP COLUMN vehicle_type;
+DROP TABLE IF EXISTS seat_layouts;
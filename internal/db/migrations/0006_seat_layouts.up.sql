@@ -0,0 +1,43 @@
+-- Denah kursi per tipe kendaraan. seat_map berisi JSON [{"code":"1A","row":1,"col":1}, ...];
+-- driver_row/driver_col posisi sopir pada grid; disabled_seats dipisah koma.
+CREATE TABLE IF NOT EXISTS seat_layouts (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	vehicle_type VARCHAR(50) NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	seat_rows INT NOT NULL,
+	seat_cols INT NOT NULL,
+	seat_map TEXT NOT NULL,
+	driver_row INT NOT NULL DEFAULT 1,
+	driver_col INT NOT NULL DEFAULT 1,
+	disabled_seats VARCHAR(255) NOT NULL DEFAULT '',
+	capacity INT NOT NULL,
+	is_default TINYINT(1) NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_layout_vehicle_type (vehicle_type)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Denah default reguler (6 penumpang, setir kanan):
+--   1A  .   SOPIR
+--   2A  .   2B
+--   3A  3B  3C
+INSERT IGNORE INTO seat_layouts (vehicle_type, name, seat_rows, seat_cols, seat_map, driver_row, driver_col, capacity, is_default) VALUES
+	('reguler', 'Reguler 6 kursi', 3, 3,
+	 '[{"code":"1A","row":1,"col":1},{"code":"2A","row":2,"col":1},{"code":"2B","row":2,"col":3},{"code":"3A","row":3,"col":1},{"code":"3B","row":3,"col":2},{"code":"3C","row":3,"col":3}]',
+	 1, 3, 6, 1);
+
+-- Kendaraan menunjuk denah lewat vehicle_type.
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_vehicles_vehicle_type()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'vehicles' AND column_name = 'vehicle_type'
+	) THEN
+		ALTER TABLE vehicles ADD COLUMN vehicle_type VARCHAR(50) NULL DEFAULT NULL, ADD KEY idx_vehicles_vehicle_type (vehicle_type);
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_vehicles_vehicle_type();
+DROP PROCEDURE IF EXISTS migrate_add_vehicles_vehicle_type;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS route_stops;
+DROP TABLE IF EXISTS routes;
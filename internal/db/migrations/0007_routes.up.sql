@@ -0,0 +1,35 @@
+-- Definisi jalur reguler: urutan halte per jalur. Satu mobil pada tanggal+jam yang sama
+-- melewati semua halte jalur, jadi seat yang dibooking untuk satu segmen ikut terpakai
+-- di setiap segmen lain yang overlap. Jalur berlaku dua arah (urutan dibalik untuk arah pulang).
+CREATE TABLE IF NOT EXISTS routes (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	code VARCHAR(64) NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	is_active TINYINT(1) NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_route_code (code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS route_stops (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	route_id BIGINT NOT NULL,
+	stop_key VARCHAR(64) NOT NULL,
+	seq INT NOT NULL,
+	UNIQUE KEY uniq_route_seq (route_id, seq),
+	UNIQUE KEY uniq_route_stop (route_id, stop_key),
+	KEY idx_route_stops_stop (stop_key),
+	CONSTRAINT fk_route_stops_route FOREIGN KEY (route_id) REFERENCES routes (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Jalur utama Rohul -> Pekanbaru mengikuti urutan katalog stop.
+INSERT IGNORE INTO routes (code, name) VALUES ('rohul-pekanbaru', 'Rohul - Pekanbaru');
+
+INSERT IGNORE INTO route_stops (route_id, stop_key, seq)
+SELECT r.id, s.stop_key, s.sort_order
+FROM routes r
+JOIN stops s ON s.stop_key IN (
+	'skpd', 'simpangd', 'skpc', 'simpangkumu', 'muararumbai', 'surautinggi', 'pasirpengaraian',
+	'ujungbatu', 'tandun', 'silam', 'petapahan', 'suram', 'aliantan', 'kuok', 'bangkinang', 'kabun', 'pekanbaru'
+)
+WHERE r.code = 'rohul-pekanbaru';
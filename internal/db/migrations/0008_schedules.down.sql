@@ -0,0 +1,4 @@
+ALTER TABLE bookings DROP KEY idx_bookings_trip_slot, DROP COLUMN trip_slot_id;
+DROP TABLE IF EXISTS trip_slots;
+DROP TABLE IF EXISTS schedule_blackouts;
+DROP TABLE IF EXISTS schedules;
@@ -0,0 +1,64 @@
+-- Jadwal keberangkatan reguler. schedules = timetable berulang per jalur (hari + jam),
+-- trip_slots = keberangkatan konkret hasil generate, schedule_blackouts = tanggal libur.
+-- direction: 'forward' mengikuti urutan route_stops, 'reverse' arah sebaliknya.
+-- days_of_week: ISO 1=Senin .. 7=Minggu, dipisah koma.
+CREATE TABLE IF NOT EXISTS schedules (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	route_id BIGINT NOT NULL,
+	direction VARCHAR(10) NOT NULL DEFAULT 'forward',
+	days_of_week VARCHAR(20) NOT NULL DEFAULT '1,2,3,4,5,6,7',
+	departure_time VARCHAR(5) NOT NULL,
+	vehicle_type VARCHAR(50) NULL DEFAULT NULL,
+	capacity INT NOT NULL,
+	valid_from DATE NULL DEFAULT NULL,
+	valid_to DATE NULL DEFAULT NULL,
+	is_active TINYINT(1) NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_schedule_departure (route_id, direction, departure_time),
+	CONSTRAINT fk_schedules_route FOREIGN KEY (route_id) REFERENCES routes (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- route_id NULL = libur untuk semua jalur.
+CREATE TABLE IF NOT EXISTS schedule_blackouts (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	blackout_date DATE NOT NULL,
+	route_id BIGINT NULL DEFAULT NULL,
+	reason VARCHAR(255) NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_blackout (blackout_date, route_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- status: open (bisa dibooking), closed (ditutup admin/blackout).
+CREATE TABLE IF NOT EXISTS trip_slots (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	schedule_id BIGINT NULL DEFAULT NULL,
+	route_id BIGINT NOT NULL,
+	direction VARCHAR(10) NOT NULL DEFAULT 'forward',
+	trip_date DATE NOT NULL,
+	trip_time VARCHAR(5) NOT NULL,
+	vehicle_type VARCHAR(50) NULL DEFAULT NULL,
+	capacity INT NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'open',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY uniq_trip_slot (route_id, direction, trip_date, trip_time),
+	KEY idx_trip_slots_date (trip_date),
+	CONSTRAINT fk_trip_slots_route FOREIGN KEY (route_id) REFERENCES routes (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Booking menunjuk trip slot yang dipesan.
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_bookings_trip_slot_id()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'bookings' AND column_name = 'trip_slot_id'
+	) THEN
+		ALTER TABLE bookings ADD COLUMN trip_slot_id BIGINT NULL DEFAULT NULL, ADD KEY idx_bookings_trip_slot (trip_slot_id);
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_bookings_trip_slot_id();
+DROP PROCEDURE IF EXISTS migrate_add_bookings_trip_slot_id;
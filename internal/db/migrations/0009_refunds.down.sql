@@ -0,0 +1,2 @@
+ALTER TABLE bookings DROP COLUMN cancel_reason, DROP COLUMN cancelled_at;
+DROP TABLE IF EXISTS refunds;
@@ -0,0 +1,35 @@
+-- Pembatalan booking reguler. refunds mencatat dana yang harus dikembalikan ke customer
+-- (amount = dibayar - fee pembatalan). status: pending -> processed / rejected oleh admin.
+CREATE TABLE IF NOT EXISTS refunds (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	booking_id BIGINT NOT NULL,
+	paid_amount BIGINT NOT NULL DEFAULT 0,
+	fee BIGINT NOT NULL DEFAULT 0,
+	amount BIGINT NOT NULL DEFAULT 0,
+	method VARCHAR(50) NOT NULL DEFAULT '',
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	reason VARCHAR(255) NOT NULL DEFAULT '',
+	note VARCHAR(255) NOT NULL DEFAULT '',
+	requested_by BIGINT NULL DEFAULT NULL,
+	processed_at DATETIME NULL DEFAULT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	KEY idx_refunds_booking (booking_id),
+	KEY idx_refunds_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Waktu dan alasan pembatalan di booking.
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_bookings_cancelled_at()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'bookings' AND column_name = 'cancelled_at'
+	) THEN
+		ALTER TABLE bookings ADD COLUMN cancelled_at DATETIME NULL DEFAULT NULL, ADD COLUMN cancel_reason VARCHAR(255) NULL DEFAULT NULL;
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_bookings_cancelled_at();
+DROP PROCEDURE IF EXISTS migrate_add_bookings_cancelled_at;
@@ -0,0 +1,29 @@
+-- Riwayat reschedule booking reguler beserta selisih tarif.
+-- adjustment: none (tarif sama / belum bayar), supplement (customer kurang bayar), credit (kelebihan bayar).
+-- adjustment_status: none, pending -> settled / waived oleh admin.
+CREATE TABLE IF NOT EXISTS booking_reschedules (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	booking_id BIGINT NOT NULL,
+	old_route_from VARCHAR(100) NOT NULL,
+	old_route_to VARCHAR(100) NOT NULL,
+	old_trip_date DATE NOT NULL,
+	old_trip_time VARCHAR(10) NOT NULL,
+	old_seats VARCHAR(255) NOT NULL DEFAULT '',
+	old_total BIGINT NOT NULL DEFAULT 0,
+	new_route_from VARCHAR(100) NOT NULL,
+	new_route_to VARCHAR(100) NOT NULL,
+	new_trip_date DATE NOT NULL,
+	new_trip_time VARCHAR(10) NOT NULL,
+	new_seats VARCHAR(255) NOT NULL DEFAULT '',
+	new_total BIGINT NOT NULL DEFAULT 0,
+	adjustment VARCHAR(20) NOT NULL DEFAULT 'none',
+	adjustment_amount BIGINT NOT NULL DEFAULT 0,
+	adjustment_status VARCHAR(20) NOT NULL DEFAULT 'none',
+	reason VARCHAR(255) NOT NULL DEFAULT '',
+	note VARCHAR(255) NOT NULL DEFAULT '',
+	requested_by BIGINT NULL DEFAULT NULL,
+	settled_at DATETIME NULL DEFAULT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	KEY idx_reschedules_booking (booking_id),
+	KEY idx_reschedules_adjustment (adjustment_status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
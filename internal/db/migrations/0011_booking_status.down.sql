@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS booking_status_history;
+ALTER TABLE bookings DROP COLUMN booking_status;
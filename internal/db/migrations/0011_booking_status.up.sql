@@ -0,0 +1,30 @@
+-- Status siklus hidup booking (draft -> awaiting_payment -> awaiting_validation -> paid -> departed -> completed,
+-- atau cancelled/rejected). Baris lama dengan booking_status NULL diturunkan dari payment_status.
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_bookings_booking_status()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'bookings' AND column_name = 'booking_status'
+	) THEN
+		ALTER TABLE bookings ADD COLUMN booking_status VARCHAR(30) NULL DEFAULT NULL;
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_bookings_booking_status();
+DROP PROCEDURE IF EXISTS migrate_add_bookings_booking_status;
+
+-- Riwayat setiap perpindahan status booking beserta pelaku dan request ID.
+CREATE TABLE IF NOT EXISTS booking_status_history (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	booking_id BIGINT NOT NULL,
+	from_status VARCHAR(30) NOT NULL DEFAULT '',
+	to_status VARCHAR(30) NOT NULL,
+	actor_id BIGINT NULL DEFAULT NULL,
+	actor_role VARCHAR(20) NOT NULL DEFAULT '',
+	request_id VARCHAR(64) NOT NULL DEFAULT '',
+	note VARCHAR(255) NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	KEY idx_booking_status_history_booking (booking_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
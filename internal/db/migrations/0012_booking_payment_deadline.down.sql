@@ -0,0 +1 @@
+ALTER TABLE bookings DROP KEY idx_bookings_payment_deadline, DROP COLUMN payment_deadline;
@@ -0,0 +1,16 @@
+-- Batas waktu upload bukti bayar. Booking belum lunas yang lewat batas ini tanpa bukti
+-- dibuat expired oleh job server dan seat-nya dilepas. NULL = tidak pernah expired (booking lama).
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_bookings_payment_deadline()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'bookings' AND column_name = 'payment_deadline'
+	) THEN
+		ALTER TABLE bookings ADD COLUMN payment_deadline DATETIME NULL DEFAULT NULL, ADD KEY idx_bookings_payment_deadline (payment_deadline);
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_bookings_payment_deadline();
+DROP PROCEDURE IF EXISTS migrate_add_bookings_payment_deadline;
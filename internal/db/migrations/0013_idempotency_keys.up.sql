@@ -0,0 +1,16 @@
+-- Idempotency-Key untuk POST yang rawan di-retry app (buat booking, kirim bukti bayar).
+-- scope = pemilik key (user), request_hash = sha256 method+path+body, response disimpan untuk replay.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	scope VARCHAR(64) NOT NULL,
+	idem_key VARCHAR(128) NOT NULL,
+	request_hash CHAR(64) NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'processing',
+	response_status INT NOT NULL DEFAULT 0,
+	content_type VARCHAR(100) NOT NULL DEFAULT '',
+	response_body MEDIUMBLOB NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at DATETIME NOT NULL,
+	UNIQUE KEY uq_idempotency_keys_scope_key (scope, idem_key),
+	KEY idx_idempotency_keys_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,21 @@
+-- Tagihan payment gateway (QRIS / virtual account) per booking. reference = order id yang dikirim
+-- ke provider dan dipakai mencocokkan webhook. status: pending -> paid / expired / failed.
+CREATE TABLE IF NOT EXISTS payment_charges (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	booking_id BIGINT NOT NULL,
+	provider VARCHAR(30) NOT NULL,
+	reference VARCHAR(64) NOT NULL,
+	provider_ref VARCHAR(100) NOT NULL DEFAULT '',
+	method VARCHAR(20) NOT NULL,
+	bank VARCHAR(20) NOT NULL DEFAULT '',
+	amount BIGINT NOT NULL DEFAULT 0,
+	qr_string TEXT NULL,
+	va_number VARCHAR(50) NOT NULL DEFAULT '',
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	expires_at DATETIME NULL DEFAULT NULL,
+	paid_at DATETIME NULL DEFAULT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY uq_payment_charges_reference (reference),
+	KEY idx_payment_charges_booking (booking_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
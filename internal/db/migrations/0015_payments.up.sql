@@ -0,0 +1,18 @@
+-- Ledger pembayaran per booking: DP, pelunasan cash, transfer, payment gateway.
+-- status: pending (bukti menunggu validasi) -> approved / rejected; cancelled bila booking dibatalkan.
+-- Booking lunas bila total approved >= total booking.
+CREATE TABLE IF NOT EXISTS payments (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	booking_id BIGINT NOT NULL,
+	amount BIGINT NOT NULL,
+	method VARCHAR(30) NOT NULL DEFAULT '',
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	received_by BIGINT NULL DEFAULT NULL,
+	validation_id BIGINT NULL DEFAULT NULL,
+	reference VARCHAR(100) NOT NULL DEFAULT '',
+	note VARCHAR(255) NOT NULL DEFAULT '',
+	validated_at DATETIME NULL DEFAULT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	KEY idx_payments_booking (booking_id),
+	KEY idx_payments_validation (validation_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,21 @@
+-- Mutasi kredit hasil impor rekening koran (CSV/MT940) untuk mencocokkan bukti transfer otomatis.
+-- fingerprint mencegah mutasi yang sama tercatat dua kali bila file diimpor ulang.
+-- status: matched (validasi disetujui otomatis/manual), review (ambigu), unmatched, ignored.
+CREATE TABLE IF NOT EXISTS bank_mutations (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	fingerprint CHAR(64) NOT NULL,
+	source VARCHAR(10) NOT NULL,
+	posted_at DATE NOT NULL,
+	amount BIGINT NOT NULL,
+	description VARCHAR(255) NOT NULL DEFAULT '',
+	reference VARCHAR(100) NOT NULL DEFAULT '',
+	status VARCHAR(20) NOT NULL DEFAULT 'unmatched',
+	validation_id BIGINT NULL DEFAULT NULL,
+	candidate_ids VARCHAR(255) NOT NULL DEFAULT '',
+	note VARCHAR(255) NOT NULL DEFAULT '',
+	imported_by BIGINT NULL DEFAULT NULL,
+	resolved_at DATETIME NULL DEFAULT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY uq_bank_mutations_fingerprint (fingerprint),
+	KEY idx_bank_mutations_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
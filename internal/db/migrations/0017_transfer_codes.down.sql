@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS transfer_codes;
+ALTER TABLE bookings DROP COLUMN transfer_code;
@@ -0,0 +1,27 @@
+-- Kode unik transfer (1-999) yang ditambahkan ke nominal transfer booking supaya mutasi bank bisa
+-- dicocokkan ke satu booking. bookings.total tetap tarif (pendapatan); kode unik disimpan terpisah
+-- di bookings.transfer_code. transfer_codes berisi kode yang sedang dipakai: amount = nominal yang
+-- harus ditransfer (PK, jadi tidak ada dua booking aktif dengan nominal transfer sama) dan baris
+-- dihapus saat booking lunas, expired, atau dibatalkan.
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_bookings_transfer_code()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'bookings' AND column_name = 'transfer_code'
+	) THEN
+		ALTER TABLE bookings ADD COLUMN transfer_code SMALLINT UNSIGNED NULL DEFAULT NULL;
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_bookings_transfer_code();
+DROP PROCEDURE IF EXISTS migrate_add_bookings_transfer_code;
+
+CREATE TABLE IF NOT EXISTS transfer_codes (
+	amount BIGINT NOT NULL PRIMARY KEY,
+	booking_id BIGINT NOT NULL,
+	code SMALLINT UNSIGNED NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY uq_transfer_codes_booking (booking_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
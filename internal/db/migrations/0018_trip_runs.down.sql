@@ -0,0 +1,2 @@
+ALTER TABLE bookings DROP KEY idx_bookings_trip_run, DROP COLUMN trip_run_id;
+DROP TABLE IF EXISTS trip_runs;
@@ -0,0 +1,37 @@
+-- Satu perjalanan armada (run): jadwal + jalur + mobil + sopir, berisi beberapa booking.
+-- Driver/kendaraan di-assign sekali di run lalu disalin ke departure_settings / return_settings
+-- tiap booking. status: planned -> departed (Berangkat) -> returned (Pulang); cancelled = dibatalkan.
+CREATE TABLE IF NOT EXISTS trip_runs (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	trip_slot_id BIGINT NULL DEFAULT NULL,
+	route_from VARCHAR(100) NOT NULL DEFAULT '',
+	route_to VARCHAR(100) NOT NULL DEFAULT '',
+	trip_date DATE NOT NULL,
+	trip_time VARCHAR(5) NOT NULL DEFAULT '',
+	trip_number VARCHAR(50) NOT NULL DEFAULT '',
+	driver_name VARCHAR(100) NOT NULL DEFAULT '',
+	vehicle_code VARCHAR(50) NOT NULL DEFAULT '',
+	vehicle_type VARCHAR(50) NOT NULL DEFAULT '',
+	status VARCHAR(20) NOT NULL DEFAULT 'planned',
+	note VARCHAR(255) NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	KEY idx_trip_runs_date (trip_date, trip_time),
+	KEY idx_trip_runs_slot (trip_slot_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Booking menunjuk run tempat ia diangkut (satu booking = satu run).
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_bookings_trip_run_id()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'bookings' AND column_name = 'trip_run_id'
+	) THEN
+		ALTER TABLE bookings ADD COLUMN trip_run_id BIGINT NULL DEFAULT NULL, ADD KEY idx_bookings_trip_run (trip_run_id);
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_bookings_trip_run_id();
+DROP PROCEDURE IF EXISTS migrate_add_bookings_trip_run_id;
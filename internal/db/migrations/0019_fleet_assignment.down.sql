@@ -0,0 +1,2 @@
+ALTER TABLE drivers DROP COLUMN home_base;
+ALTER TABLE vehicles DROP COLUMN home_base, DROP COLUMN service_status;
@@ -0,0 +1,29 @@
+-- Data armada untuk saran penugasan sopir/kendaraan per slot keberangkatan.
+-- vehicles.service_status: active (siap jalan), service (di bengkel), inactive (tidak dipakai).
+-- home_base: nama halte/pool asal kendaraan dan sopir (dibandingkan dengan halte awal slot).
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_fleet_assignment_columns()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'vehicles' AND column_name = 'service_status'
+	) THEN
+		ALTER TABLE vehicles ADD COLUMN service_status VARCHAR(20) NOT NULL DEFAULT 'active';
+	END IF;
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'vehicles' AND column_name = 'home_base'
+	) THEN
+		ALTER TABLE vehicles ADD COLUMN home_base VARCHAR(100) NULL DEFAULT NULL;
+	END IF;
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'drivers' AND column_name = 'home_base'
+	) THEN
+		ALTER TABLE drivers ADD COLUMN home_base VARCHAR(100) NULL DEFAULT NULL;
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_fleet_assignment_columns();
+DROP PROCEDURE IF EXISTS migrate_add_fleet_assignment_columns;
@@ -0,0 +1 @@
+ALTER TABLE route_stops DROP COLUMN minutes_from_start;
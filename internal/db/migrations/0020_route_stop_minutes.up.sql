@@ -0,0 +1,16 @@
+-- Estimasi waktu tempuh: menit sejak halte pertama jalur (arah forward). Lama perjalanan
+-- antar dua halte = selisih menitnya; NULL = belum diisi (estimasi proporsional TRIP_DURATION).
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_route_stops_minutes()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'route_stops' AND column_name = 'minutes_from_start'
+	) THEN
+		ALTER TABLE route_stops ADD COLUMN minutes_from_start INT NULL DEFAULT NULL;
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_route_stops_minutes();
+DROP PROCEDURE IF EXISTS migrate_add_route_stops_minutes;
@@ -0,0 +1,20 @@
+-- Outbox: event sinkronisasi (settings, penumpang, trip_information) ditulis di transaksi yang sama
+-- dengan perubahan status booking, lalu diproses worker dengan retry + backoff.
+-- status: pending -> processing -> done; gagal sampai batas percobaan -> dead (bisa di-replay admin).
+CREATE TABLE IF NOT EXISTS outbox_events (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	event_type VARCHAR(50) NOT NULL,
+	aggregate_id BIGINT NOT NULL DEFAULT 0,
+	payload TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	attempts INT NOT NULL DEFAULT 0,
+	last_error VARCHAR(500) NOT NULL DEFAULT '',
+	request_id VARCHAR(64) NOT NULL DEFAULT '',
+	available_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	locked_at DATETIME NULL DEFAULT NULL,
+	processed_at DATETIME NULL DEFAULT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	KEY idx_outbox_due (status, available_at),
+	KEY idx_outbox_aggregate (event_type, aggregate_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
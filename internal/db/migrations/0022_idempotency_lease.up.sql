@@ -0,0 +1,3 @@
+-- Lease untuk Idempotency-Key yang sedang diproses: key processing yang locked_until-nya sudah
+-- lewat (proses mati/panic sebelum respons disimpan atau key dilepas) dianggap bebas lagi.
+ALTER TABLE idempotency_keys ADD COLUMN locked_until DATETIME NULL DEFAULT NULL AFTER status;
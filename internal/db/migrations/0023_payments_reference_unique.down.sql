@@ -0,0 +1 @@
+ALTER TABLE payments DROP INDEX uq_payments_booking_reference, DROP COLUMN reference_key;
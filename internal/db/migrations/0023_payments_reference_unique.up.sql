@@ -0,0 +1,6 @@
+-- Satu reference (mis. order id payment gateway) hanya boleh tercatat sekali per booking, supaya
+-- webhook yang dikirim bersamaan tidak mencatat pembayaran dua kali. Entri tanpa reference ('')
+-- disimpan NULL di reference_key sehingga tidak ikut unik. Duplikat lama harus dibereskan dulu.
+ALTER TABLE payments
+	ADD COLUMN reference_key VARCHAR(100) AS (NULLIF(reference, '')) STORED,
+	ADD UNIQUE KEY uq_payments_booking_reference (booking_id, reference_key);
@@ -0,0 +1,2 @@
+-- Sengaja tidak menghapus paid_price: kolom ini juga bagian dari tabel booking_passengers versi 0001,
+-- sehingga skema sesudah rollback ke 0023 tetap memilikinya.
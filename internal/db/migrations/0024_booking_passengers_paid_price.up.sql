@@ -0,0 +1,16 @@
+-- booking_passengers lama (dibuat runtime sebelum 0001) belum punya paid_price; sebelumnya kolom ini
+-- ditambahkan saat request oleh BookingService. Rollback tidak menghapusnya (lihat .down.sql).
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_booking_passengers_paid_price()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'booking_passengers' AND column_name = 'paid_price'
+	) THEN
+		ALTER TABLE booking_passengers ADD COLUMN paid_price BIGINT NULL DEFAULT NULL;
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_booking_passengers_paid_price();
+DROP PROCEDURE IF EXISTS migrate_add_booking_passengers_paid_price;
@@ -0,0 +1,14 @@
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_drop_departure_driver_user_id()
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'departure_settings' AND column_name = 'driver_user_id'
+	) THEN
+		ALTER TABLE departure_settings DROP KEY idx_departure_settings_driver_user, DROP COLUMN driver_user_id;
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_drop_departure_driver_user_id();
+DROP PROCEDURE IF EXISTS migrate_drop_departure_driver_user_id;
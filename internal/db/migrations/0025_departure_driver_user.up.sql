@@ -0,0 +1,28 @@
+-- Sopir yang ditugaskan disimpan sebagai user id (role driver) supaya akses driver ke
+-- keberangkatan tidak bergantung pada kecocokan nama. Baris lama diisi dari nama sopir
+-- bila namanya cocok dengan tepat satu user driver.
+-- +migrate StatementBegin
+CREATE PROCEDURE migrate_add_departure_driver_user_id()
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = 'departure_settings' AND column_name = 'driver_user_id'
+	) THEN
+		ALTER TABLE departure_settings ADD COLUMN driver_user_id BIGINT NULL DEFAULT NULL, ADD KEY idx_departure_settings_driver_user (driver_user_id);
+	END IF;
+END
+-- +migrate StatementEnd
+
+CALL migrate_add_departure_driver_user_id();
+DROP PROCEDURE IF EXISTS migrate_add_departure_driver_user_id;
+
+UPDATE departure_settings d
+JOIN (
+	SELECT LOWER(TRIM(name)) AS driver_name, MIN(id) AS user_id
+	FROM users
+	WHERE LOWER(TRIM(role)) = 'driver' AND TRIM(COALESCE(name, '')) <> ''
+	GROUP BY LOWER(TRIM(name))
+	HAVING COUNT(*) = 1
+) u ON u.driver_name = LOWER(TRIM(d.driver_name))
+SET d.driver_user_id = u.user_id
+WHERE d.driver_user_id IS NULL;
@@ -0,0 +1,169 @@
+// Package migrations berisi file SQL skema yang di-embed ke binary beserta runner-nya.
+//
+// Penamaan file: NNNN_nama.up.sql dan (opsional) NNNN_nama.down.sql.
+// Migration tanpa file .down.sql dianggap tidak bisa di-rollback.
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Migration adalah satu versi skema.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Reversible reports whether the migration ships a down script.
+func (m Migration) Reversible() bool {
+	return strings.TrimSpace(m.Down) != ""
+}
+
+// Load membaca semua migration yang di-embed, terurut berdasarkan versi.
+func Load() ([]Migration, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		fileName := e.Name()
+		version, name, direction, ok := parseFileName(fileName)
+		if !ok {
+			return nil, fmt.Errorf("nama file migration tidak valid: %s", fileName)
+		}
+		raw, err := fs.ReadFile(fsys, fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("versi %d dipakai dua nama berbeda: %s dan %s", version, m.Name, name)
+		}
+		switch direction {
+		case "up":
+			m.Up = string(raw)
+		case "down":
+			m.Down = string(raw)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %04d_%s tidak punya file .up.sql", m.Version, m.Name)
+		}
+		m.Checksum = checksum(m.Up)
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// parseFileName memecah "0002_normalize_legacy_columns.up.sql".
+func parseFileName(fileName string) (version int64, name, direction string, ok bool) {
+	if path.Ext(fileName) != ".sql" {
+		return 0, "", "", false
+	}
+	base := strings.TrimSuffix(fileName, ".sql")
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", false
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+
+	idx := strings.Index(base, "_")
+	if idx <= 0 || idx == len(base)-1 {
+		return 0, "", "", false
+	}
+	v, err := strconv.ParseInt(base[:idx], 10, 64)
+	if err != nil || v <= 0 {
+		return 0, "", "", false
+	}
+	return v, base[idx+1:], direction, true
+}
+
+func checksum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// splitStatements memecah script menjadi statement tunggal (driver MySQL tidak
+// menerima multi statement). Statement berakhir di baris yang diakhiri ';'.
+// Blok "-- +migrate StatementBegin" ... "-- +migrate StatementEnd" dikirim utuh,
+// dipakai untuk CREATE PROCEDURE yang berisi ';' di dalamnya.
+func splitStatements(script string) []string {
+	var (
+		out     []string
+		buf     strings.Builder
+		inBlock bool
+	)
+	flush := func() {
+		stmt := strings.TrimSpace(buf.String())
+		stmt = strings.TrimSpace(strings.TrimSuffix(stmt, ";"))
+		if stmt != "" {
+			out = append(out, stmt)
+		}
+		buf.Reset()
+	}
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.EqualFold(trimmed, "-- +migrate StatementBegin"):
+			flush()
+			inBlock = true
+			continue
+		case strings.EqualFold(trimmed, "-- +migrate StatementEnd"):
+			flush()
+			inBlock = false
+			continue
+		}
+		if inBlock {
+			buf.WriteString(line)
+			buf.WriteString("\n")
+			continue
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+	flush()
+	return out
+}
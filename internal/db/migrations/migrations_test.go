@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoadEmbeddedMigrationsOrdered(t *testing.T) {
+	list, err := Load()
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if len(list) == 0 {
+		t.Fatalf("expected embedded migrations")
+	}
+	for i, m := range list {
+		if i > 0 && m.Version <= list[i-1].Version {
+			t.Fatalf("migrations not strictly ordered at %d", m.Version)
+		}
+		if len(m.Checksum) != 64 {
+			t.Fatalf("migration %d has no checksum", m.Version)
+		}
+		if len(splitStatements(m.Up)) == 0 {
+			t.Fatalf("migration %d has no statements", m.Version)
+		}
+	}
+}
+
+func TestSplitStatementsKeepsProcedureBlock(t *testing.T) {
+	script := `-- komentar
+CREATE TABLE a (id INT);
+-- +migrate StatementBegin
+CREATE PROCEDURE p()
+BEGIN
+	SELECT 1;
+	SELECT 2;
+END
+-- +migrate StatementEnd
+CALL p();
+`
+	got := splitStatements(script)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 statements, got %d: %q", len(got), got)
+	}
+	if !strings.Contains(got[1], "SELECT 1;") || !strings.HasSuffix(got[1], "END") {
+		t.Fatalf("procedure body should be kept intact, got %q", got[1])
+	}
+	if got[2] != "CALL p()" {
+		t.Fatalf("unexpected last statement %q", got[2])
+	}
+}
+
+func TestRunnerUpRejectsChangedMigration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+
+	r := Runner{DB: db, Migrations: []Migration{{Version: 1, Name: "init", Up: "SELECT 1;", Checksum: checksum("SELECT 1;")}}}
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}).
+			AddRow(int64(1), "init", checksum("SELECT 2;"), time.Now()))
+
+	if _, err := r.Up(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Runner menerapkan migration ke database dan mencatatnya di schema_migrations.
+//
+// Catatan: DDL MySQL tidak transaksional. Bila sebuah statement gagal di tengah
+// migration, versi tersebut tidak dicatat dan perlu diperbaiki manual sebelum
+// menjalankan ulang.
+type Runner struct {
+	DB         *sql.DB
+	Migrations []Migration
+	Logf       func(format string, args ...any)
+	Now        func() time.Time
+}
+
+// Status adalah kondisi satu migration terhadap database.
+type Status struct {
+	Version          int64
+	Name             string
+	Applied          bool
+	AppliedAt        time.Time
+	ChecksumMismatch bool
+}
+
+type appliedRow struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// NewRunner membuat runner dengan migration yang di-embed.
+func NewRunner(db *sql.DB) (Runner, error) {
+	list, err := Load()
+	if err != nil {
+		return Runner{}, err
+	}
+	return Runner{DB: db, Migrations: list}, nil
+}
+
+func (r Runner) logf(format string, args ...any) {
+	if r.Logf != nil {
+		r.Logf(format, args...)
+	}
+}
+
+func (r Runner) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+func (r Runner) ensureTable() error {
+	if r.DB == nil {
+		return fmt.Errorf("db tidak tersedia")
+	}
+	_, err := r.DB.Exec(`
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	checksum CHAR(64) NOT NULL,
+	applied_at DATETIME NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func (r Runner) applied() (map[int64]appliedRow, error) {
+	if err := r.ensureTable(); err != nil {
+		return nil, err
+	}
+	rows, err := r.DB.Query(`SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int64]appliedRow{}
+	for rows.Next() {
+		var (
+			v   int64
+			row appliedRow
+		)
+		if err := rows.Scan(&v, &row.name, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		out[v] = row
+	}
+	return out, rows.Err()
+}
+
+// verify memastikan file migration yang sudah diterapkan tidak diubah setelahnya.
+func (r Runner) verify(applied map[int64]appliedRow) error {
+	known := map[int64]bool{}
+	for _, m := range r.Migrations {
+		known[m.Version] = true
+		row, ok := applied[m.Version]
+		if !ok {
+			continue
+		}
+		if row.checksum != m.Checksum {
+			return fmt.Errorf("checksum migration %04d_%s berbeda dengan yang sudah diterapkan; jangan ubah migration lama, buat versi baru", m.Version, m.Name)
+		}
+	}
+	for v, row := range applied {
+		if !known[v] {
+			return fmt.Errorf("migration %04d_%s tercatat di database tetapi tidak ada di binary ini", v, row.name)
+		}
+	}
+	return nil
+}
+
+// Up menerapkan semua migration yang belum diterapkan. Mengembalikan migration yang dijalankan.
+func (r Runner) Up() ([]Migration, error) {
+	applied, err := r.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.verify(applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range r.Migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		r.logf("migrate up %04d_%s", m.Version, m.Name)
+		if err := r.exec(m.Up); err != nil {
+			return done, fmt.Errorf("migration %04d_%s gagal: %w", m.Version, m.Name, err)
+		}
+		if _, err := r.DB.Exec(
+			`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+			m.Version, m.Name, m.Checksum, r.now(),
+		); err != nil {
+			return done, fmt.Errorf("catat migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Down me-rollback sejumlah steps migration terakhir yang sudah diterapkan.
+func (r Runner) Down(steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	applied, err := r.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.verify(applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(r.Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := r.Migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if !m.Reversible() {
+			return done, fmt.Errorf("migration %04d_%s tidak bisa di-rollback (tanpa .down.sql)", m.Version, m.Name)
+		}
+		r.logf("migrate down %04d_%s", m.Version, m.Name)
+		if err := r.exec(m.Down); err != nil {
+			return done, fmt.Errorf("rollback %04d_%s gagal: %w", m.Version, m.Name, err)
+		}
+		if _, err := r.DB.Exec(`DELETE FROM schema_migrations WHERE version=?`, m.Version); err != nil {
+			return done, fmt.Errorf("hapus catatan migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Status mengembalikan kondisi semua migration. Checksum yang berbeda ditandai, bukan error.
+func (r Runner) Status() ([]Status, error) {
+	applied, err := r.applied()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(r.Migrations))
+	for _, m := range r.Migrations {
+		st := Status{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = row.appliedAt
+			st.ChecksumMismatch = row.checksum != m.Checksum
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+func (r Runner) exec(script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := r.DB.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
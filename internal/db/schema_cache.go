@@ -0,0 +1,221 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSchemaTTL dipakai bila SchemaCache.TTL kosong.
+const DefaultSchemaTTL = 10 * time.Minute
+
+// schemaRetryDelay menahan reload ulang setelah gagal supaya information_schema tidak dibanjiri.
+const schemaRetryDelay = 30 * time.Second
+
+// RowsQueryer is satisfied by *sql.DB and *sql.Tx.
+type RowsQueryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// SchemaCache menyimpan snapshot seluruh tabel/kolom database aktif (satu query ke
+// information_schema), sehingga HasTable/HasColumn tidak lagi query per-request.
+// Snapshot di-refresh otomatis setelah TTL atau manual lewat Refresh/Invalidate.
+type SchemaCache struct {
+	TTL time.Duration
+
+	mu          sync.RWMutex
+	tables      map[string]map[string]struct{} // lower(table) -> set lower(column)
+	loadedAt    time.Time
+	lastErr     string
+	lastErrAt   time.Time
+	refreshLock sync.Mutex
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	refreshes     atomic.Uint64
+	refreshErrors atomic.Uint64
+}
+
+// SchemaStats dipakai endpoint admin untuk memantau efektivitas cache.
+type SchemaStats struct {
+	Tables        int       `json:"tables"`
+	Columns       int       `json:"columns"`
+	LoadedAt      time.Time `json:"loadedAt"`
+	TTLSeconds    float64   `json:"ttlSeconds"`
+	Hits          uint64    `json:"hits"`
+	Misses        uint64    `json:"misses"`
+	HitRate       float64   `json:"hitRate"`
+	Refreshes     uint64    `json:"refreshes"`
+	RefreshErrors uint64    `json:"refreshErrors"`
+	LastError     string    `json:"lastError,omitempty"`
+}
+
+// Schema adalah cache bersama yang dipakai HasTable/HasColumn.
+var Schema = &SchemaCache{TTL: DefaultSchemaTTL}
+
+func (c *SchemaCache) ttl() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return DefaultSchemaTTL
+}
+
+// HasTable reports whether the table exists in the current database.
+func (c *SchemaCache) HasTable(q QueryRower, table string) bool {
+	if table == "" {
+		return false
+	}
+	tables, ok := c.snapshot(q)
+	if !ok {
+		return probeTable(q, table)
+	}
+	_, exists := tables[strings.ToLower(table)]
+	return exists
+}
+
+// HasColumn reports whether table.column exists in the current database.
+func (c *SchemaCache) HasColumn(q QueryRower, table, column string) bool {
+	if table == "" || column == "" {
+		return false
+	}
+	tables, ok := c.snapshot(q)
+	if !ok {
+		return probeColumn(q, table, column)
+	}
+	cols, exists := tables[strings.ToLower(table)]
+	if !exists {
+		return false
+	}
+	_, exists = cols[strings.ToLower(column)]
+	return exists
+}
+
+// Refresh memuat ulang snapshot dari information_schema.
+func (c *SchemaCache) Refresh(q RowsQueryer) error {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+	return c.refreshLocked(q)
+}
+
+// Invalidate membuang snapshot; lookup berikutnya memuat ulang. Panggil setelah DDL runtime.
+func (c *SchemaCache) Invalidate() {
+	c.mu.Lock()
+	c.tables = nil
+	c.loadedAt = time.Time{}
+	c.lastErrAt = time.Time{}
+	c.mu.Unlock()
+}
+
+// Stats mengembalikan ukuran snapshot dan metrik hit/miss.
+func (c *SchemaCache) Stats() SchemaStats {
+	c.mu.RLock()
+	st := SchemaStats{
+		Tables:     len(c.tables),
+		LoadedAt:   c.loadedAt,
+		TTLSeconds: c.ttl().Seconds(),
+		LastError:  c.lastErr,
+	}
+	for _, cols := range c.tables {
+		st.Columns += len(cols)
+	}
+	c.mu.RUnlock()
+
+	st.Hits = c.hits.Load()
+	st.Misses = c.misses.Load()
+	st.Refreshes = c.refreshes.Load()
+	st.RefreshErrors = c.refreshErrors.Load()
+	if total := st.Hits + st.Misses; total > 0 {
+		st.HitRate = float64(st.Hits) / float64(total)
+	}
+	return st
+}
+
+// snapshot returns a fresh snapshot, loading it when missing or expired.
+// ok=false means no snapshot is available and the caller must probe directly.
+func (c *SchemaCache) snapshot(q QueryRower) (map[string]map[string]struct{}, bool) {
+	if tables, fresh := c.current(); fresh {
+		c.hits.Add(1)
+		return tables, true
+	}
+	c.misses.Add(1)
+
+	rq, canLoad := q.(RowsQueryer)
+	if !canLoad {
+		tables, _ := c.current()
+		return tables, tables != nil
+	}
+
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+	// goroutine lain mungkin sudah me-refresh selama menunggu lock
+	if tables, fresh := c.current(); fresh {
+		return tables, true
+	}
+	c.mu.RLock()
+	backoff := !c.lastErrAt.IsZero() && time.Since(c.lastErrAt) < schemaRetryDelay
+	c.mu.RUnlock()
+	if !backoff {
+		_ = c.refreshLocked(rq)
+	}
+	// snapshot lama tetap dipakai bila refresh gagal
+	tables, _ := c.current()
+	return tables, tables != nil
+}
+
+func (c *SchemaCache) current() (map[string]map[string]struct{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.tables == nil {
+		return nil, false
+	}
+	return c.tables, time.Since(c.loadedAt) < c.ttl()
+}
+
+func (c *SchemaCache) refreshLocked(q RowsQueryer) error {
+	tables, err := loadSchema(q)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.refreshErrors.Add(1)
+		c.lastErr = err.Error()
+		c.lastErrAt = time.Now()
+		return err
+	}
+	c.refreshes.Add(1)
+	c.tables = tables
+	c.loadedAt = time.Now()
+	c.lastErr = ""
+	c.lastErrAt = time.Time{}
+	return nil
+}
+
+func loadSchema(q RowsQueryer) (map[string]map[string]struct{}, error) {
+	rows, err := q.Query(`
+		SELECT table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema = DATABASE()
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]map[string]struct{}{}
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, err
+		}
+		t := strings.ToLower(table)
+		if out[t] == nil {
+			out[t] = map[string]struct{}{}
+		}
+		out[t][strings.ToLower(column)] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSchemaCacheLoadsOnceAndCountsHits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).
+			AddRow("bookings", "id").
+			AddRow("bookings", "Trip_Date").
+			AddRow("users", "id"))
+
+	cache := &SchemaCache{TTL: time.Hour}
+	if !cache.HasTable(db, "bookings") {
+		t.Fatalf("bookings should exist")
+	}
+	if !cache.HasColumn(db, "bookings", "trip_date") {
+		t.Fatalf("column lookup should be case-insensitive")
+	}
+	if cache.HasColumn(db, "bookings", "departure_date") || cache.HasTable(db, "payments") {
+		t.Fatalf("missing table/column reported as present")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("schema should be loaded with a single query: %v", err)
+	}
+
+	st := cache.Stats()
+	if st.Tables != 2 || st.Columns != 3 {
+		t.Fatalf("unexpected snapshot size: %+v", st)
+	}
+	if st.Misses != 1 || st.Hits != 3 || st.Refreshes != 1 {
+		t.Fatalf("unexpected metrics: %+v", st)
+	}
+}
+
+func TestSchemaCacheReloadsAfterInvalidate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).AddRow("bookings", "id"))
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).
+			AddRow("bookings", "id").
+			AddRow("user_sessions", "id"))
+
+	cache := &SchemaCache{TTL: time.Hour}
+	if cache.HasTable(db, "user_sessions") {
+		t.Fatalf("user_sessions should not exist before DDL")
+	}
+	cache.Invalidate()
+	if !cache.HasTable(db, "user_sessions") {
+		t.Fatalf("user_sessions should be visible after invalidate")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
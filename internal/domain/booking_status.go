@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BookingStatus adalah status siklus hidup booking (bookings.booking_status).
+type BookingStatus string
+
+const (
+	BookingDraft              BookingStatus = "draft"
+	BookingAwaitingPayment    BookingStatus = "awaiting_payment"
+	BookingAwaitingValidation BookingStatus = "awaiting_validation"
+	BookingPartiallyPaid      BookingStatus = "partially_paid"
+	BookingPaid               BookingStatus = "paid"
+	BookingDeparted           BookingStatus = "departed"
+	BookingCompleted          BookingStatus = "completed"
+	BookingCancelled          BookingStatus = "cancelled"
+	BookingRejected           BookingStatus = "rejected"
+	BookingExpired            BookingStatus = "expired"
+)
+
+// bookingTransitions memetakan status asal ke status tujuan yang diizinkan.
+// Pembayaran yang ditolak boleh dikirim ulang, divalidasi langsung oleh admin, dibatalkan,
+// atau expired bila tidak ada bukti baru sampai batas waktu; booking yang belum dibayar melewati batas waktu menjadi expired. Booking DP (partially_paid)
+// tidak expired karena sudah ada uang masuk; lunas setelah sisa tagihan nol. Booking lunas kembali
+// ke partially_paid bila totalnya naik (reschedule ke tarif lebih mahal).
+var bookingTransitions = map[BookingStatus][]BookingStatus{
+	BookingDraft:              {BookingAwaitingPayment, BookingAwaitingValidation, BookingPartiallyPaid, BookingPaid, BookingCancelled, BookingExpired},
+	BookingAwaitingPayment:    {BookingAwaitingValidation, BookingPartiallyPaid, BookingPaid, BookingCancelled, BookingExpired},
+	BookingAwaitingValidation: {BookingPartiallyPaid, BookingPaid, BookingRejected, BookingCancelled, BookingExpired},
+	BookingPartiallyPaid:      {BookingAwaitingValidation, BookingPaid, BookingCancelled},
+	BookingRejected:           {BookingAwaitingValidation, BookingPartiallyPaid, BookingPaid, BookingCancelled, BookingExpired},
+	BookingPaid:               {BookingPartiallyPaid, BookingDeparted, BookingCancelled},
+	BookingDeparted:           {BookingCompleted},
+}
+
+// Valid melaporkan apakah s adalah status booking yang dikenal.
+func (s BookingStatus) Valid() bool {
+	switch s {
+	case BookingDraft, BookingAwaitingPayment, BookingAwaitingValidation, BookingPartiallyPaid, BookingPaid,
+		BookingDeparted, BookingCompleted, BookingCancelled, BookingRejected, BookingExpired:
+		return true
+	}
+	return false
+}
+
+// CanTransition melaporkan apakah booking boleh berpindah dari s ke to.
+func (s BookingStatus) CanTransition(to BookingStatus) bool {
+	for _, next := range bookingTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateBookingTransition mengembalikan ConflictError bila perpindahan status tidak diizinkan.
+// Perpindahan ke status yang sama dianggap no-op dan selalu valid.
+func ValidateBookingTransition(from, to BookingStatus) error {
+	if !to.Valid() {
+		return ValidationError{Field: "status", Msg: fmt.Sprintf("status booking %q tidak dikenal", to)}
+	}
+	if from == to || from.CanTransition(to) {
+		return nil
+	}
+	return ConflictError{Resource: "booking", Msg: fmt.Sprintf("Status booking %s tidak bisa diubah ke %s", from, to)}
+}
+
+// BookingStatusFromPayment menerjemahkan label payment_status lama (Lunas, Menunggu Validasi, ...)
+// ke BookingStatus. Label kosong/tidak dikenal dianggap belum dibayar.
+func BookingStatusFromPayment(label string) BookingStatus {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "menunggu validasi", "awaiting_validation":
+		return BookingAwaitingValidation
+	case "dp", "partially_paid", "sebagian":
+		return BookingPartiallyPaid
+	case "lunas", "paid", "payment paid", "settlement", "success", "sukses", "approve", "approved", "pembayaran sukses":
+		return BookingPaid
+	case "ditolak", "rejected", "reject":
+		return BookingRejected
+	case "dibatalkan", "cancelled", "canceled":
+		return BookingCancelled
+	case "berangkat", "departed":
+		return BookingDeparted
+	case "selesai", "completed":
+		return BookingCompleted
+	case "kedaluwarsa", "expired":
+		return BookingExpired
+	default:
+		return BookingAwaitingPayment
+	}
+}
+
+// PaymentLabel adalah nilai payment_status yang ditulis untuk status ini.
+// Kosong berarti payment_status tidak berubah (departed/completed tetap Lunas).
+func (s BookingStatus) PaymentLabel() string {
+	switch s {
+	case BookingAwaitingPayment:
+		return "Belum Lunas"
+	case BookingAwaitingValidation:
+		return "Menunggu Validasi"
+	case BookingPartiallyPaid:
+		return "DP"
+	case BookingPaid:
+		return "Lunas"
+	case BookingRejected:
+		return "Ditolak"
+	case BookingCancelled:
+		return "Dibatalkan"
+	case BookingExpired:
+		return "Kedaluwarsa"
+	}
+	return ""
+}
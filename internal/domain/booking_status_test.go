@@ -0,0 +1,67 @@
+package domain
+
+import "testing"
+
+func TestValidateBookingTransition(t *testing.T) {
+	cases := []struct {
+		from, to BookingStatus
+		ok       bool
+	}{
+		{BookingDraft, BookingAwaitingPayment, true},
+		{BookingAwaitingPayment, BookingAwaitingValidation, true},
+		{BookingAwaitingValidation, BookingPaid, true},
+		{BookingAwaitingValidation, BookingRejected, true},
+		{BookingRejected, BookingAwaitingValidation, true},
+		{BookingRejected, BookingCancelled, true},
+		{BookingRejected, BookingExpired, true},
+		{BookingPaid, BookingDeparted, true},
+		{BookingDeparted, BookingCompleted, true},
+		{BookingPaid, BookingPaid, true},
+		{BookingAwaitingPayment, BookingDeparted, false},
+		{BookingDeparted, BookingCancelled, false},
+		{BookingCompleted, BookingPaid, false},
+		{BookingCancelled, BookingPaid, false},
+		{BookingAwaitingValidation, BookingExpired, true},
+		{BookingExpired, BookingAwaitingValidation, false},
+		{BookingPaid, BookingExpired, false},
+		{BookingAwaitingValidation, BookingPartiallyPaid, true},
+		{BookingPartiallyPaid, BookingPaid, true},
+		{BookingPartiallyPaid, BookingExpired, false},
+		{BookingPaid, BookingPartiallyPaid, true},
+		{BookingDeparted, BookingPartiallyPaid, false},
+	}
+	for _, tc := range cases {
+		err := ValidateBookingTransition(tc.from, tc.to)
+		if tc.ok && err != nil {
+			t.Errorf("%s -> %s: unexpected error %v", tc.from, tc.to, err)
+		}
+		if !tc.ok && !IsConflict(err) {
+			t.Errorf("%s -> %s: expected conflict, got %v", tc.from, tc.to, err)
+		}
+	}
+	if err := ValidateBookingTransition(BookingPaid, "unknown"); !IsValidation(err) {
+		t.Fatalf("expected validation error for unknown status, got %v", err)
+	}
+}
+
+func TestBookingStatusFromPayment(t *testing.T) {
+	cases := map[string]BookingStatus{
+		"":                  BookingAwaitingPayment,
+		"Belum Lunas":       BookingAwaitingPayment,
+		"Menunggu Validasi": BookingAwaitingValidation,
+		"DP":                BookingPartiallyPaid,
+		"Lunas":             BookingPaid,
+		" sukses ":          BookingPaid,
+		"Ditolak":           BookingRejected,
+		"Dibatalkan":        BookingCancelled,
+		"Kedaluwarsa":       BookingExpired,
+	}
+	for label, want := range cases {
+		if got := BookingStatusFromPayment(label); got != want {
+			t.Errorf("%q: got %s want %s", label, got, want)
+		}
+		if lbl := want.PaymentLabel(); BookingStatusFromPayment(lbl) != want {
+			t.Errorf("label %q of %s does not round-trip", lbl, want)
+		}
+	}
+}
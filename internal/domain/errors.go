@@ -95,6 +95,34 @@ func (e InternalError) Error() string {
 
 func (e InternalError) Unwrap() error { return e.Err }
 
+type UnauthorizedError struct {
+	Msg string
+	Err error
+}
+
+func (e UnauthorizedError) Error() string {
+	if e.Msg != "" {
+		return e.Msg
+	}
+	return "unauthorized"
+}
+
+func (e UnauthorizedError) Unwrap() error { return e.Err }
+
+type ForbiddenError struct {
+	Msg string
+	Err error
+}
+
+func (e ForbiddenError) Error() string {
+	if e.Msg != "" {
+		return e.Msg
+	}
+	return "forbidden"
+}
+
+func (e ForbiddenError) Unwrap() error { return e.Err }
+
 func IsNotFound(err error) bool {
 	var target NotFoundError
 	return errors.As(err, &target)
@@ -114,3 +142,13 @@ func IsInternal(err error) bool {
 	var target InternalError
 	return errors.As(err, &target)
 }
+
+func IsUnauthorized(err error) bool {
+	var target UnauthorizedError
+	return errors.As(err, &target)
+}
+
+func IsForbidden(err error) bool {
+	var target ForbiddenError
+	return errors.As(err, &target)
+}
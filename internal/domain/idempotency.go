@@ -0,0 +1,9 @@
+package domain
+
+// IdempotentResponse adalah respons HTTP yang disimpan untuk satu Idempotency-Key
+// dan dikirim ulang apa adanya saat request yang sama di-retry.
+type IdempotentResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
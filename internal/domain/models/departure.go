@@ -13,6 +13,7 @@ type DepartureSetting struct {
 	PassengerCount     string `json:"passenger_count"`
 	ServiceType        string `json:"service_type"`
 	DriverName         string `json:"driver_name"`
+	DriverUserID       int64  `json:"driver_user_id"`
 	VehicleCode        string `json:"vehicle_code"`
 	VehicleType        string `json:"vehicle_type"`
 	SuratJalanFile     string `json:"surat_jalan_file"`
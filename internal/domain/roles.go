@@ -0,0 +1,23 @@
+package domain
+
+import "strings"
+
+// Role values stored in users.role.
+const (
+	RoleAdmin    = "admin"
+	RoleDriver   = "driver"
+	RoleCustomer = "customer"
+)
+
+// NormalizeRole maps legacy role strings onto the canonical roles.
+// Akun hasil /auth/register tersimpan sebagai "user", diperlakukan sama dengan customer.
+func NormalizeRole(role string) string {
+	switch strings.ToLower(strings.TrimSpace(role)) {
+	case "admin", "administrator", "superadmin", "super_admin", "owner":
+		return RoleAdmin
+	case "driver", "sopir", "supir":
+		return RoleDriver
+	default:
+		return RoleCustomer
+	}
+}
@@ -28,6 +28,13 @@ type Filter struct {
 
 // RequestContext carries authenticated user info when available.
 type RequestContext struct {
-	UserID ID     `json:"userId"`
-	Role   string `json:"role"`
+	UserID    ID     `json:"userId"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	SessionID string `json:"-"`
+}
+
+// IsAdmin reports whether the authenticated user has admin privileges.
+func (rc RequestContext) IsAdmin() bool {
+	return rc.Role == RoleAdmin
 }
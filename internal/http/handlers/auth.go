@@ -3,16 +3,44 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
-	"time"
+	"strings"
+	"sync"
 
 	intconfig "backend/internal/config"
+	"backend/internal/http/middleware"
+	"backend/internal/services"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret = []byte("super-secret-key-change-me")
+var (
+	authMu  sync.RWMutex
+	authSvc services.AuthService
+)
+
+// SetAuthService stores the token/session service shared with the auth middleware.
+func SetAuthService(svc services.AuthService) {
+	authMu.Lock()
+	defer authMu.Unlock()
+	authSvc = svc
+}
+
+func authService(c *gin.Context) services.AuthService {
+	authMu.RLock()
+	defer authMu.RUnlock()
+	svc := authSvc
+	svc.RequestID = middleware.GetRequestID(c)
+	return svc
+}
+
+func deviceInfo(c *gin.Context, name string) services.DeviceInfo {
+	return services.DeviceInfo{
+		Name:      strings.TrimSpace(name),
+		UserAgent: c.GetHeader("User-Agent"),
+		IP:        c.ClientIP(),
+	}
+}
 
 // AuthUser mirrors legacy auth response user payload.
 type AuthUser struct {
@@ -26,8 +54,9 @@ type AuthUser struct {
 }
 
 type loginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"deviceName"`
 }
 
 // POST /api/auth/login
@@ -72,24 +101,74 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-	})
+	if !services.IsActiveUserStatus(user.Status) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "akun tidak aktif"})
+		return
+	}
 
-	tokenString, err := token.SignedString(jwtSecret)
+	tokens, err := authService(c).StartSession(user.ID, user.Name, user.Role, deviceInfo(c, req.DeviceName))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal membuat token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token": tokenString,
-		"user":  user,
+		"token":            tokens.AccessToken,
+		"expiresAt":        tokens.ExpiresAt,
+		"refreshToken":     tokens.RefreshToken,
+		"refreshExpiresAt": tokens.RefreshExpiresAt,
+		"user":             user,
 	})
 }
 
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+	DeviceName   string `json:"deviceName"`
+}
+
+// POST /api/auth/refresh
+func RefreshToken(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "payload tidak valid", err)
+		return
+	}
+
+	tokens, err := authService(c).Refresh(req.RefreshToken, deviceInfo(c, req.DeviceName))
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+	All          bool   `json:"all"`
+}
+
+// POST /api/auth/logout
+// Body opsional: {"refreshToken": "...", "all": true} untuk keluar dari semua perangkat.
+func Logout(c *gin.Context) {
+	rc, ok := requestUser(c)
+	if !ok {
+		return
+	}
+	var req logoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, "payload tidak valid", err)
+			return
+		}
+	}
+
+	if err := authService(c).Logout(rc, req.RefreshToken, req.All); err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logout berhasil"})
+}
+
 type registerRequest struct {
 	Name     string `json:"name"`
 	Username string `json:"username"`
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+	"backend/internal/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestUser returns the authenticated caller or responds 401.
+func requestUser(c *gin.Context) (domain.RequestContext, bool) {
+	rc, ok := middleware.GetRequestContext(c)
+	if !ok {
+		respondError(c, http.StatusUnauthorized, "unauthorized", "login diperlukan", nil)
+		return rc, false
+	}
+	return rc, true
+}
+
+// requireBookingAccess memastikan customer hanya mengakses booking miliknya sendiri.
+// Booking yang pemiliknya tidak diketahui (user_id kosong atau kolom belum dimigrasi) hanya
+// bisa diakses admin.
+func requireBookingAccess(c *gin.Context, bookingID int64) bool {
+	rc, ok := requestUser(c)
+	if !ok {
+		return false
+	}
+	if rc.Role != domain.RoleCustomer {
+		return true
+	}
+
+	ownerID, err := bookingOwnerID(intconfig.DB, bookingID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "booking_not_found", "booking tidak ditemukan", nil)
+			return false
+		}
+		respondError(c, http.StatusInternalServerError, "db_error", "gagal memeriksa pemilik booking", err)
+		return false
+	}
+	if ownerID <= 0 || domain.ID(ownerID) != rc.UserID {
+		respondError(c, http.StatusForbidden, "forbidden", "booking ini bukan milik anda", nil)
+		return false
+	}
+	return true
+}
+
+// requirePassengerAccess resolves passengers.booking_id before applying requireBookingAccess.
+func requirePassengerAccess(c *gin.Context, passengerID int64) bool {
+	rc, ok := requestUser(c)
+	if !ok {
+		return false
+	}
+	if rc.Role != domain.RoleCustomer {
+		return true
+	}
+
+	db := intconfig.DB
+	if !intdb.HasTable(db, "passengers") || !intdb.HasColumn(db, "passengers", "booking_id") {
+		respondError(c, http.StatusForbidden, "forbidden", "akses ditolak", nil)
+		return false
+	}
+	var bookingID int64
+	if err := db.QueryRow(`SELECT COALESCE(booking_id,0) FROM passengers WHERE id=? LIMIT 1`, passengerID).Scan(&bookingID); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "passenger_not_found", "data passenger tidak ditemukan", nil)
+			return false
+		}
+		respondError(c, http.StatusInternalServerError, "db_error", "gagal membaca passenger", err)
+		return false
+	}
+	if bookingID <= 0 {
+		respondError(c, http.StatusForbidden, "forbidden", "akses ditolak", nil)
+		return false
+	}
+	return requireBookingAccess(c, bookingID)
+}
+
+// isOwnDeparture dipakai untuk membatasi driver hanya ke keberangkatan yang ditugaskan padanya,
+// dicocokkan lewat departure_settings.driver_user_id (bukan nama, yang bisa kembar atau diganti).
+func isOwnDeparture(rc domain.RequestContext, driverUserID int64) bool {
+	if rc.Role != domain.RoleDriver {
+		return true
+	}
+	return driverUserID > 0 && domain.ID(driverUserID) == rc.UserID
+}
+
+func bookingOwnerID(q intdb.QueryRower, bookingID int64) (int64, error) {
+	var owner int64
+	err := q.QueryRow(`SELECT COALESCE(user_id,0) FROM bookings WHERE id=? LIMIT 1`, bookingID).Scan(&owner)
+	return owner, err
+}
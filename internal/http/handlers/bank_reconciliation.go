@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"backend/internal/http/middleware"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBankStatementBytes membatasi ukuran file rekening koran yang diimpor.
+const maxBankStatementBytes = 10 << 20
+
+// bankReconciliationService menyetujui bukti transfer lewat applyPaymentValidation, alur yang sama
+// dengan POST /api/payment-validations/:id/approve. tripRole dipakai untuk validasi yang role-nya kosong.
+func bankReconciliationService(c *gin.Context, tripRole string) services.BankReconciliationService {
+	return services.BankReconciliationService{
+		RequestID: middleware.GetRequestID(c),
+		Approve: func(validationID int64) error {
+			_, err := applyPaymentValidation(c, validationID, true, tripRole)
+			return err
+		},
+	}
+}
+
+// ======================================================
+// POST /api/admin/bank-statements/import
+// multipart: file (CSV / MT940), tripRole opsional (Keberangkatan/Kepulangan)
+// ======================================================
+func AdminImportBankStatement(c *gin.Context) {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "validation_error", "file wajib diisi", nil)
+		return
+	}
+	if fh.Size > maxBankStatementBytes {
+		respondError(c, http.StatusRequestEntityTooLarge, "validation_error", "ukuran file melebihi batas", nil)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "validation_error", "gagal membaca file", nil)
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxBankStatementBytes))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "validation_error", "gagal membaca file", nil)
+		return
+	}
+
+	tripRole := normalizeTripRolePV(c.PostForm("tripRole"))
+	if tripRole != "" && tripRole != "Keberangkatan" && tripRole != "Kepulangan" {
+		respondError(c, http.StatusBadRequest, "validation_error", "tripRole harus Keberangkatan atau Kepulangan", nil)
+		return
+	}
+
+	res, err := bankReconciliationService(c, tripRole).Import(fh.Filename, data, statusActor(c).UserID)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GET /api/admin/bank-mutations?status=review|matched|unmatched|ignored
+func AdminListBankMutations(c *gin.Context) {
+	list, err := bankReconciliationService(c, "").List(strings.TrimSpace(c.Query("status")))
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"mutations": list})
+}
+
+type resolveBankMutationRequest struct {
+	ValidationID int64  `json:"validationId"`
+	TripRole     string `json:"tripRole"`
+}
+
+// POST /api/admin/bank-mutations/:id/resolve
+func AdminResolveBankMutation(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req resolveBankMutationRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	m, err := bankReconciliationService(c, normalizeTripRolePV(req.TripRole)).Resolve(id, req.ValidationID)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+type ignoreBankMutationRequest struct {
+	Note string `json:"note"`
+}
+
+// POST /api/admin/bank-mutations/:id/ignore
+func AdminIgnoreBankMutation(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req ignoreBankMutationRequest
+	if c.Request.ContentLength != 0 && !BindJSONOrError(c, &req) {
+		return
+	}
+	m, err := bankReconciliationService(c, "").Ignore(id, req.Note)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
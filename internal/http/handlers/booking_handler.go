@@ -83,6 +83,9 @@ func SaveBookingPassengers(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "invalid_booking_id", "id booking tidak valid", nil)
 		return
 	}
+	if !requireBookingAccess(c, bookingID) {
+		return
+	}
 
 	raw, err := io.ReadAll(c.Request.Body)
 	if err != nil {
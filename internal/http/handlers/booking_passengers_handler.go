@@ -8,7 +8,8 @@ import (
 
 	intconfig "backend/internal/config"
 	intdb "backend/internal/db"
-	"backend/internal/utils"
+	"backend/internal/http/middleware"
+	"backend/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,6 +29,9 @@ func GetBookingPassengers(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "invalid_booking_id", "id booking tidak valid", nil)
 		return
 	}
+	if !requireBookingAccess(c, bookingID) {
+		return
+	}
 
 	db := intconfig.DB
 	var (
@@ -76,18 +80,13 @@ func GetBookingPassengers(c *gin.Context) {
 	// map per-seat dari booking_passengers
 	passengerMap := map[string]bookingPassengerResponse{}
 	withPassengerPhone := intdb.HasColumn(db, "booking_passengers", "passenger_phone")
-	withPaidPrice := intdb.HasColumn(db, "booking_passengers", "paid_price")
 
 	if intdb.HasTable(db, "booking_passengers") {
 		phoneSel := "''"
 		if withPassengerPhone {
 			phoneSel = "COALESCE(passenger_phone,'')"
 		}
-		priceSel := "0"
-		if withPaidPrice {
-			priceSel = "COALESCE(paid_price,0)"
-		}
-		query := `SELECT seat_code, COALESCE(passenger_name,''), ` + phoneSel + `, ` + priceSel + ` FROM booking_passengers WHERE booking_id=?`
+		query := `SELECT seat_code, COALESCE(passenger_name,''), ` + phoneSel + `, COALESCE(paid_price,0) FROM booking_passengers WHERE booking_id=?`
 		pRows, err := db.Query(query, bookingID)
 		if err == nil {
 			defer pRows.Close()
@@ -119,11 +118,12 @@ func GetBookingPassengers(c *gin.Context) {
 
 	resp := []bookingPassengerResponse{}
 	baseFare := pricePerSeat.Int64
+	fares := services.FareService{RequestID: middleware.GetRequestID(c)}
 	for _, seat := range seatList {
 		item := passengerMap[seat]
 		item.SeatCode = seat
 		if item.PaidPrice == 0 {
-			item.PaidPrice = utils.ComputeFare(routeFrom.String, routeTo.String, baseFare)
+			item.PaidPrice = fares.PricePerSeat(routeFrom.String, routeTo.String, "", tripDate.String, baseFare)
 		}
 		resp = append(resp, item)
 	}
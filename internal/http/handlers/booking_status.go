@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"backend/internal/domain"
+	"backend/internal/http/middleware"
+	"backend/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusActor mengisi pelaku perubahan status booking dari user yang login.
+func statusActor(c *gin.Context) repositories.StatusActor {
+	actor := repositories.StatusActor{RequestID: middleware.GetRequestID(c)}
+	if rc, ok := middleware.GetRequestContext(c); ok {
+		actor.UserID = int64(rc.UserID)
+		actor.Role = rc.Role
+	}
+	return actor
+}
+
+// transitionBookingTx memindahkan status booking di dalam transaksi handler.
+// false berarti respons error ({"message": ...}) sudah dikirim.
+func transitionBookingTx(c *gin.Context, tx *sql.Tx, bookingID int64, to domain.BookingStatus, note string) bool {
+	_, err := repositories.BookingRepository{}.TransitionTx(tx, bookingID, to, statusActor(c), note)
+	if err == nil {
+		return true
+	}
+	var ce domain.ConflictError
+	if errors.As(err, &ce) {
+		c.JSON(http.StatusConflict, gin.H{"message": ce.Msg})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal update status booking: " + err.Error()})
+	return false
+}
+
+// GET /api/bookings/:id/history
+func GetBookingStatusHistory(c *gin.Context) {
+	bookingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || bookingID <= 0 {
+		respondError(c, http.StatusBadRequest, "invalid_booking_id", "id booking tidak valid", nil)
+		return
+	}
+	if !requireBookingAccess(c, bookingID) {
+		return
+	}
+
+	repo := repositories.BookingRepository{}
+	status, err := repo.CurrentStatus(bookingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, "booking_not_found", "booking tidak ditemukan", nil)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "db_error", "gagal membaca booking", err)
+		return
+	}
+	history, err := repo.StatusHistory(bookingID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "db_error", "gagal memuat riwayat status", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bookingId": bookingID,
+		"status":    status,
+		"history":   history,
+	})
+}
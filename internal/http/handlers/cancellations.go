@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"backend/internal/domain"
+	"backend/internal/http/middleware"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	cancelPolicyMu sync.RWMutex
+	cancelPolicy   services.CancellationPolicy
+)
+
+// SetCancellationPolicy stores the cancellation policy (from env) used by the cancel endpoint.
+func SetCancellationPolicy(p services.CancellationPolicy) {
+	cancelPolicyMu.Lock()
+	defer cancelPolicyMu.Unlock()
+	cancelPolicy = p
+}
+
+func cancellationService(c *gin.Context) services.CancellationService {
+	cancelPolicyMu.RLock()
+	defer cancelPolicyMu.RUnlock()
+	return services.CancellationService{
+		Policy:    cancelPolicy,
+		Actor:     statusActor(c),
+		RequestID: middleware.GetRequestID(c),
+	}
+}
+
+// respondBookingChangeError memetakan error pembatalan/reschedule ke respons reguler ({"message": ...}).
+func respondBookingChangeError(c *gin.Context, err error, fallback string) {
+	var (
+		ve domain.ValidationError
+		ce domain.ConflictError
+	)
+	switch {
+	case errors.As(err, &ve):
+		c.JSON(http.StatusBadRequest, gin.H{"message": ve.Msg})
+	case errors.As(err, &ce):
+		c.JSON(http.StatusConflict, gin.H{"message": ce.Msg})
+	case domain.IsNotFound(err):
+		c.JSON(http.StatusNotFound, gin.H{"message": "booking tidak ditemukan"})
+	case domain.IsForbidden(err):
+		c.JSON(http.StatusForbidden, gin.H{"message": "hold bukan milik anda"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": fallback})
+	}
+}
+
+type CancelBookingRequest struct {
+	Reason       string `json:"reason"`
+	RefundMethod string `json:"refundMethod"` // kosong = sama dengan metode bayar
+}
+
+// ======================================================
+// POST /api/reguler/bookings/:id/cancel
+// ======================================================
+func CancelRegulerBooking(c *gin.Context) {
+	bookingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || bookingID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "id tidak valid"})
+		return
+	}
+	if !requireBookingAccess(c, bookingID) {
+		return
+	}
+
+	var req CancelBookingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "payload tidak valid"})
+			return
+		}
+	}
+
+	var userID int64
+	if rc, ok := middleware.GetRequestContext(c); ok {
+		userID = int64(rc.UserID)
+	}
+
+	res, err := cancellationService(c).Cancel(bookingID, userID, req.Reason, req.RefundMethod)
+	if err != nil {
+		respondBookingChangeError(c, err, "Gagal membatalkan booking")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Booking dibatalkan",
+		"cancellation": res,
+	})
+}
+
+// ===== admin refund (/api/admin/refunds) =====
+
+// GET /api/admin/refunds?status=pending
+func AdminListRefunds(c *gin.Context) {
+	list, err := cancellationService(c).ListRefunds(c.Query("status"))
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"refunds": list})
+}
+
+type refundUpdateRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// PUT /api/admin/refunds/:id
+func AdminUpdateRefund(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req refundUpdateRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	rf, err := cancellationService(c).UpdateRefund(id, req.Status, req.Note)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rf)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/internal/http/middleware"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func consistencyService(c *gin.Context) services.ConsistencyService {
+	return services.ConsistencyService{RequestID: middleware.GetRequestID(c)}
+}
+
+// GET /api/admin/consistency?kinds=paid_without_settings,orphan_trip_information — laporan data tidak sinkron
+func AdminConsistencyReport(c *gin.Context) {
+	kinds, err := services.ParseConsistencyKinds(c.Query("kinds"))
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	rep, err := consistencyService(c).Check(kinds)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
+
+// POST /api/admin/consistency/repair?kinds=... — perbaiki temuan lewat jalur sinkron yang sama (aman diulang)
+func AdminConsistencyRepair(c *gin.Context) {
+	kinds, err := services.ParseConsistencyKinds(c.Query("kinds"))
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	rep, err := consistencyService(c).Repair(kinds)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
@@ -41,7 +41,7 @@ func ListVehicleCosts(c *gin.Context) {
 		return
 	}
 
-	rows, err := intconfig.DB.Query(`
+	rows, err := intconfig.ReadOnlyDB().Query(`
 		SELECT id, car_code, driver_name, year, month, maintenance_fee, insurance_fee, installment_fee
 		FROM vehicle_costs_monthly
 		WHERE car_code=? AND year=?
@@ -132,7 +132,7 @@ func ListCompanyExpenses(c *gin.Context) {
 		return
 	}
 
-	rows, err := intconfig.DB.Query(`
+	rows, err := intconfig.ReadOnlyDB().Query(`
 		SELECT id, year, month, staff_fee, office_fee, internet_fee, promo_fee, flyer_fee, legal_fee
 		FROM company_expenses_monthly
 		WHERE year=?
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"backend/internal/http/middleware"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	assignmentPolicyMu sync.RWMutex
+	assignmentPolicy   services.AssignmentPolicy
+)
+
+// SetAssignmentPolicy stores the trip duration / driver rest policy (from env) used for suggestions.
+func SetAssignmentPolicy(p services.AssignmentPolicy) {
+	assignmentPolicyMu.Lock()
+	defer assignmentPolicyMu.Unlock()
+	assignmentPolicy = p
+}
+
+func currentAssignmentPolicy() services.AssignmentPolicy {
+	assignmentPolicyMu.RLock()
+	defer assignmentPolicyMu.RUnlock()
+	return assignmentPolicy
+}
+
+func assignmentService(c *gin.Context) services.AssignmentService {
+	return services.AssignmentService{
+		Routes:    routeService(c),
+		Runs:      tripRunService(c),
+		Guard:     assignmentGuard(c),
+		Policy:    currentAssignmentPolicy(),
+		RequestID: middleware.GetRequestID(c),
+	}
+}
+
+// assignmentGuard dipakai saat sopir/kendaraan/jadwal settings atau trip run diubah.
+func assignmentGuard(c *gin.Context) services.AssignmentGuard {
+	return services.AssignmentGuard{
+		Routes:    routeService(c),
+		Policy:    currentAssignmentPolicy(),
+		RequestID: middleware.GetRequestID(c),
+		Actor:     statusActor(c),
+	}
+}
+
+type applySuggestionsRequest struct {
+	Date      string  `json:"date"`
+	SlotIDs   []int64 `json:"slotIds"`
+	Overwrite bool    `json:"overwrite"`
+}
+
+// GET /api/departures/slots/:slot/suggestions — saran sopir/kendaraan untuk satu trip slot
+func GetDepartureSlotSuggestions(c *gin.Context) {
+	slotID, err := strconv.ParseInt(c.Param("slot"), 10, 64)
+	if err != nil || slotID <= 0 {
+		respondError(c, http.StatusBadRequest, "validation_error", "slot tidak valid", nil)
+		return
+	}
+	sug, err := assignmentService(c).Suggest(slotID)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sug)
+}
+
+// POST /api/departures/suggestions/apply — terapkan saran ke slotIds atau semua slot pada date
+func ApplyDepartureSuggestions(c *gin.Context) {
+	var req applySuggestionsRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	results, err := assignmentService(c).Apply(services.AssignmentApplyInput{
+		Date:      req.Date,
+		SlotIDs:   req.SlotIDs,
+		Overwrite: req.Overwrite,
+	})
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
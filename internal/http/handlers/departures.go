@@ -12,6 +12,7 @@ import (
 
 	intconfig "backend/internal/config"
 	intdb "backend/internal/db"
+	"backend/internal/domain"
 	"backend/internal/domain/models"
 	"backend/internal/http/middleware"
 	"backend/internal/repositories"
@@ -31,12 +32,14 @@ type DepartureSettingDTO struct {
 	ServiceType        string `json:"serviceType"`
 	DriverName         string `json:"driverName"`
 	DriverNameSnake    string `json:"driver_name"`
+	DriverUserID       int64  `json:"driverUserId"`
 	VehicleCode        string `json:"vehicleCode"`
 	VehicleCodeSnake   string `json:"vehicle_code"`
 	VehicleType        string `json:"vehicleType"`
 	VehicleTypeSnake   string `json:"vehicle_type"`
 	SuratJalanFile     string `json:"suratJalanFile"`
 	SuratJalanFileName string `json:"suratJalanFileName"`
+	SuratJalanFileURL  string `json:"suratJalanFileUrl,omitempty"`
 	DepartureStatus    string `json:"departureStatus"`
 	DepartureTime      string `json:"departureTime"`
 	RouteFrom          string `json:"routeFrom"`
@@ -58,12 +61,14 @@ func toDepartureDTO(dep models.DepartureSetting) DepartureSettingDTO {
 		ServiceType:        strings.TrimSpace(dep.ServiceType),
 		DriverName:         strings.TrimSpace(dep.DriverName),
 		DriverNameSnake:    strings.TrimSpace(dep.DriverName),
+		DriverUserID:       dep.DriverUserID,
 		VehicleCode:        strings.TrimSpace(dep.VehicleCode),
 		VehicleCodeSnake:   strings.TrimSpace(dep.VehicleCode),
 		VehicleType:        strings.TrimSpace(dep.VehicleType),
 		VehicleTypeSnake:   strings.TrimSpace(dep.VehicleType),
 		SuratJalanFile:     strings.TrimSpace(dep.SuratJalanFile),
 		SuratJalanFileName: strings.TrimSpace(dep.SuratJalanFileName),
+		SuratJalanFileURL:  fileURL(dep.SuratJalanFile),
 		DepartureStatus:    strings.TrimSpace(dep.DepartureStatus),
 		DepartureTime:      strings.TrimSpace(dep.DepartureTime),
 		RouteFrom:          strings.TrimSpace(dep.RouteFrom),
@@ -85,6 +90,7 @@ func GetDepartureSettings(c *gin.Context) {
 		return
 	}
 
+	rc, _ := middleware.GetRequestContext(c)
 	driverVehicleMap := loadDriverVehicleTypes()
 
 	tripNoSel := "''"
@@ -112,7 +118,7 @@ func GetDepartureSettings(c *gin.Context) {
 		vehicleTypeSel = "COALESCE(vehicle_type,'')"
 	}
 
-	rows, err := intconfig.DB.Query(fmt.Sprintf(`
+	rows, err := intconfig.ReadOnlyDB().Query(fmt.Sprintf(`
 		SELECT
 			id,
 			COALESCE(booking_name,''),
@@ -123,6 +129,7 @@ func GetDepartureSettings(c *gin.Context) {
 			COALESCE(passenger_count, 0),
 			COALESCE(service_type,''),
 			COALESCE(driver_name,''),
+			COALESCE(driver_user_id,0),
 			COALESCE(vehicle_code,''),
 			COALESCE(surat_jalan_file,''),
 			COALESCE(surat_jalan_file_name,''),
@@ -158,6 +165,7 @@ func GetDepartureSettings(c *gin.Context) {
 			&countInt,
 			&d.ServiceType,
 			&d.DriverName,
+			&d.DriverUserID,
 			&d.VehicleCode,
 			&d.SuratJalanFile,
 			&d.SuratJalanFileName,
@@ -175,6 +183,10 @@ func GetDepartureSettings(c *gin.Context) {
 			return
 		}
 
+		if !isOwnDeparture(rc, d.DriverUserID) {
+			continue
+		}
+
 		d.PassengerCount = strconv.Itoa(countInt)
 		applyDepartureFallbacks(&d, driverVehicleMap)
 		syncDepartureDTOAliases(&d)
@@ -237,6 +249,7 @@ func GetDepartureSettingByID(c *gin.Context) {
 			COALESCE(passenger_count, 0),
 			COALESCE(service_type,''),
 			COALESCE(driver_name,''),
+			COALESCE(driver_user_id,0),
 			COALESCE(vehicle_code,''),
 			COALESCE(surat_jalan_file,''),
 			COALESCE(surat_jalan_file_name,''),
@@ -265,6 +278,7 @@ func GetDepartureSettingByID(c *gin.Context) {
 		&countInt,
 		&d.ServiceType,
 		&d.DriverName,
+		&d.DriverUserID,
 		&d.VehicleCode,
 		&d.SuratJalanFile,
 		&d.SuratJalanFileName,
@@ -286,6 +300,11 @@ func GetDepartureSettingByID(c *gin.Context) {
 		return
 	}
 
+	if rc, _ := middleware.GetRequestContext(c); !isOwnDeparture(rc, d.DriverUserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "keberangkatan ini tidak ditugaskan kepada anda"})
+		return
+	}
+
 	d.PassengerCount = strconv.Itoa(countInt)
 	applyDepartureFallbacks(&d, driverVehicleMap)
 	syncDepartureDTOAliases(&d)
@@ -312,6 +331,13 @@ func CreateDepartureSetting(c *gin.Context) {
 		input.VehicleType = lookupDriverVehicleType(input.DriverName)
 	}
 
+	suratJalan, err := fileService().StoreInline(services.FileKindSuratJalan, input.SuratJalanFile)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	input.SuratJalanFile = suratJalan
+
 	count, _ := strconv.Atoi(input.PassengerCount)
 
 	cols := []string{}
@@ -335,6 +361,11 @@ func CreateDepartureSetting(c *gin.Context) {
 	}
 	add("service_type", input.ServiceType)
 	add("driver_name", input.DriverName)
+	if input.DriverUserID <= 0 {
+		input.DriverUserID = repositories.DriverUserIDByName(intconfig.DB, input.DriverName)
+	}
+	cols = append(cols, "driver_user_id")
+	vals = append(vals, input.DriverUserID)
 	add("vehicle_code", input.VehicleCode)
 	add("vehicle_type", input.VehicleType)
 	add("surat_jalan_file", input.SuratJalanFile)
@@ -361,7 +392,7 @@ func CreateDepartureSetting(c *gin.Context) {
 	ph := make([]string, len(cols))
 	for i := range ph {
 		ph[i] = "?"
-		if cols[i] == "booking_id" {
+		if cols[i] == "booking_id" || cols[i] == "driver_user_id" {
 			ph[i] = "NULLIF(?,0)"
 		}
 	}
@@ -408,13 +439,36 @@ func UpdateDepartureSetting(c *gin.Context) {
 		return
 	}
 
+	if raw, err = storeInlineFilesInJSON(raw, services.FileKindSuratJalan, "suratJalanFile", "surat_jalan_file"); err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+
+	if rc, _ := middleware.GetRequestContext(c); rc.Role == domain.RoleDriver {
+		existing, err := repositories.DepartureRepository{}.GetByID(id)
+		if err != nil {
+			RespondError(c, http.StatusNotFound, "data tidak ditemukan", err)
+			return
+		}
+		if !isOwnDeparture(rc, existing.DriverUserID) {
+			RespondError(c, http.StatusForbidden, "keberangkatan ini tidak ditugaskan kepada anda", nil)
+			return
+		}
+	}
+
 	svc := services.DepartureService{
 		Repo:        repositories.DepartureRepository{DB: nil},
 		BookingRepo: repositories.BookingRepository{},
 		SeatRepo:    repositories.BookingSeatRepository{},
+		Guard:       assignmentGuard(c),
 		RequestID:   middleware.GetRequestID(c),
+		Actor:       statusActor(c),
 	}
 	dep, err := svc.MarkBerangkat(id, raw)
+	if domain.IsConflict(err) {
+		RespondDomainError(c, err)
+		return
+	}
 	if err != nil {
 		RespondError(c, http.StatusInternalServerError, "gagal memperbarui keberangkatan", err)
 		return
@@ -465,6 +519,7 @@ func applyDepartureFallbacks(d *DepartureSettingDTO, driverVehicleMap map[string
 			d.SuratJalanFile = buildSuratJalanAPI(d.BookingID)
 		}
 	}
+	d.SuratJalanFileURL = fileURL(d.SuratJalanFile)
 
 	if strings.TrimSpace(d.VehicleType) == "" && strings.TrimSpace(d.DriverName) != "" {
 		if vt := driverVehicleMap[strings.ToLower(strings.TrimSpace(d.DriverName))]; vt != "" {
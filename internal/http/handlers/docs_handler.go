@@ -22,6 +22,9 @@ func GetPassengerETicketPDF(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "invalid_passenger_id", "id passenger tidak valid", err)
 		return
 	}
+	if !requirePassengerAccess(c, pid) {
+		return
+	}
 
 	paid, payErr := isPaymentLunas(pid)
 	if payErr != nil {
@@ -57,6 +60,9 @@ func GetPassengerInvoicePDF(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "invalid_passenger_id", "id passenger tidak valid", err)
 		return
 	}
+	if !requirePassengerAccess(c, pid) {
+		return
+	}
 
 	paid, payErr := isPaymentLunas(pid)
 	if payErr != nil {
@@ -41,7 +41,7 @@ func GetDriverAccounts(c *gin.Context) {
 		vtSel = "COALESCE(vehicle_type,'')"
 	}
 
-	rows, err := intconfig.DB.Query(`
+	rows, err := intconfig.ReadOnlyDB().Query(`
 		SELECT
 			id,
 			COALESCE(driver_name,''),
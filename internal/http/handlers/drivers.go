@@ -19,11 +19,12 @@ type Driver struct {
 	VehicleAssigned string `json:"vehicleAssigned"`
 	CreatedAt      string `json:"createdAt"`
 	Photo          string `json:"photo"`
+	HomeBase       string `json:"homeBase"` // pool asal sopir, dipakai saran assignment
 }
 
 // GET /api/drivers
 func GetDrivers(c *gin.Context) {
-	rows, err := intconfig.DB.Query(`
+	rows, err := intconfig.ReadOnlyDB().Query(`
 		SELECT
 			id,
 			COALESCE(name, ''),
@@ -32,7 +33,8 @@ func GetDrivers(c *gin.Context) {
 			COALESCE(vehicle_type, ''),
 			COALESCE(vehicle_assigned, ''),
 			COALESCE(DATE_FORMAT(created_at, '%Y-%m-%d %H:%i:%s'), ''),
-			COALESCE(photo, '')
+			COALESCE(photo, ''),
+			COALESCE(home_base, '')
 		FROM drivers
 		ORDER BY id DESC
 	`)
@@ -55,6 +57,7 @@ func GetDrivers(c *gin.Context) {
 			&d.VehicleAssigned,
 			&d.CreatedAt,
 			&d.Photo,
+			&d.HomeBase,
 		); err != nil {
 			log.Println("GetDrivers scan error:", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal membaca data driver: " + err.Error()})
@@ -100,6 +103,7 @@ func CreateDriver(c *gin.Context) {
 
 	id, _ := res.LastInsertId()
 	input.ID = int(id)
+	saveDriverHomeBase(id, input.HomeBase)
 
 	_ = intconfig.DB.QueryRow(`
 		SELECT
@@ -153,6 +157,7 @@ func UpdateDriver(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal mengupdate driver: " + err.Error()})
 		return
 	}
+	saveDriverHomeBase(int64(id), input.HomeBase)
 
 	var out Driver
 	err = intconfig.DB.QueryRow(`
@@ -164,7 +169,8 @@ func UpdateDriver(c *gin.Context) {
 			COALESCE(vehicle_type, ''),
 			COALESCE(vehicle_assigned, ''),
 			COALESCE(DATE_FORMAT(created_at, '%Y-%m-%d %H:%i:%s'), ''),
-			COALESCE(photo, '')
+			COALESCE(photo, ''),
+			COALESCE(home_base, '')
 		FROM drivers
 		WHERE id = ?
 	`, id).Scan(
@@ -176,6 +182,7 @@ func UpdateDriver(c *gin.Context) {
 		&out.VehicleAssigned,
 		&out.CreatedAt,
 		&out.Photo,
+		&out.HomeBase,
 	)
 	if err != nil {
 		log.Println("UpdateDriver readback error:", err)
@@ -211,3 +218,9 @@ func DeleteDriver(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "driver berhasil dihapus"})
 }
+
+func saveDriverHomeBase(id int64, homeBase string) {
+	if _, err := intconfig.DB.Exec(`UPDATE drivers SET home_base = ? WHERE id = ?`, vehicleNullIfEmpty(homeBase), id); err != nil {
+		log.Println("saveDriverHomeBase error:", err)
+	}
+}
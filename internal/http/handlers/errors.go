@@ -48,6 +48,10 @@ func RespondDomainError(c *gin.Context, err error) {
 		respondError(c, http.StatusNotFound, "not_found", err.Error(), nil)
 	case domain.IsConflict(err):
 		respondError(c, http.StatusConflict, "conflict", err.Error(), nil)
+	case domain.IsUnauthorized(err):
+		respondError(c, http.StatusUnauthorized, "unauthorized", err.Error(), nil)
+	case domain.IsForbidden(err):
+		respondError(c, http.StatusForbidden, "forbidden", err.Error(), nil)
 	default:
 		respondError(c, http.StatusInternalServerError, "internal_error", "terjadi kesalahan", nil)
 	}
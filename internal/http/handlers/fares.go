@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===== admin katalog stop & matriks tarif (/api/admin/stops, /api/admin/fares) =====
+
+type stopRequest struct {
+	Key         string   `json:"key"`
+	DisplayName string   `json:"displayName"`
+	Aliases     []string `json:"aliases"`
+	Cluster     string   `json:"cluster"`
+	SortOrder   int      `json:"sortOrder"`
+	IsActive    *bool    `json:"isActive"`
+}
+
+func (r stopRequest) toStop() repositories.Stop {
+	return repositories.Stop{
+		Key:         r.Key,
+		DisplayName: r.DisplayName,
+		Aliases:     r.Aliases,
+		Cluster:     r.Cluster,
+		SortOrder:   r.SortOrder,
+		IsActive:    r.IsActive == nil || *r.IsActive,
+	}
+}
+
+type fareRequest struct {
+	OriginKey          string `json:"originKey"`
+	OriginCluster      string `json:"originCluster"`
+	DestinationKey     string `json:"destinationKey"`
+	DestinationCluster string `json:"destinationCluster"`
+	Category           string `json:"category"`
+	Price              int64  `json:"price"`
+	Bidirectional      *bool  `json:"bidirectional"`
+	ValidFrom          string `json:"validFrom"`
+	ValidTo            string `json:"validTo"`
+	IsActive           *bool  `json:"isActive"`
+}
+
+func (r fareRequest) toFare() repositories.Fare {
+	return repositories.Fare{
+		OriginKey:          r.OriginKey,
+		OriginCluster:      r.OriginCluster,
+		DestinationKey:     r.DestinationKey,
+		DestinationCluster: r.DestinationCluster,
+		Category:           r.Category,
+		Price:              r.Price,
+		Bidirectional:      r.Bidirectional == nil || *r.Bidirectional,
+		ValidFrom:          r.ValidFrom,
+		ValidTo:            r.ValidTo,
+		IsActive:           r.IsActive == nil || *r.IsActive,
+	}
+}
+
+func adminIDParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondError(c, http.StatusBadRequest, "validation_error", "id tidak valid", nil)
+		return 0, false
+	}
+	return id, true
+}
+
+func AdminListStops(c *gin.Context) {
+	stops, err := fareService(c).ListStops(false)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stops": stops})
+}
+
+func AdminCreateStop(c *gin.Context) {
+	var req stopRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	stop, err := fareService(c).CreateStop(req.toStop())
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, stop)
+}
+
+func AdminUpdateStop(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req stopRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	stop, err := fareService(c).UpdateStop(id, req.toStop())
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stop)
+}
+
+func AdminDeleteStop(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	if err := fareService(c).DeleteStop(id); err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func AdminListFares(c *gin.Context) {
+	fares, err := fareService(c).ListFares()
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"fares": fares})
+}
+
+func AdminCreateFare(c *gin.Context) {
+	var req fareRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	fare, err := fareService(c).CreateFare(req.toFare())
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, fare)
+}
+
+func AdminUpdateFare(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req fareRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	fare, err := fareService(c).UpdateFare(id, req.toFare())
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, fare)
+}
+
+func AdminDeleteFare(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	if err := fareService(c).DeleteFare(id); err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
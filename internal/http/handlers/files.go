@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	fileSvcMu sync.RWMutex
+	fileSvc   services.FileService
+)
+
+// SetFileService stores the file service (from env) used for uploads and signed download URLs.
+func SetFileService(s services.FileService) {
+	fileSvcMu.Lock()
+	defer fileSvcMu.Unlock()
+	fileSvc = s
+}
+
+func fileService() services.FileService {
+	fileSvcMu.RLock()
+	defer fileSvcMu.RUnlock()
+	return fileSvc
+}
+
+// storeInlineFile memindahkan base64/data URL dari payload JSON lama ke FileStore.
+func storeInlineFile(c *gin.Context, kind, value string) (string, bool) {
+	ref, err := fileService().StoreInline(kind, value)
+	if err != nil {
+		respondFileError(c, err)
+		return "", false
+	}
+	return ref, true
+}
+
+// storeInlineFilesInJSON mengganti field base64 pada payload JSON parsial dengan referensi FileStore.
+func storeInlineFilesInJSON(raw []byte, kind string, keys ...string) ([]byte, error) {
+	var m map[string]any
+	if json.Unmarshal(raw, &m) != nil {
+		return raw, nil
+	}
+	changed := false
+	for _, k := range keys {
+		v, ok := m[k].(string)
+		if !ok {
+			continue
+		}
+		ref, err := fileService().StoreInline(kind, v)
+		if err != nil {
+			return nil, err
+		}
+		if ref != v {
+			m[k] = ref
+			changed = true
+		}
+	}
+	if !changed {
+		return raw, nil
+	}
+	return json.Marshal(m)
+}
+
+// fileURL mengembalikan signed URL untuk nilai kolom yang berupa referensi FileStore.
+func fileURL(value string) string {
+	return fileService().URL(value)
+}
+
+func isMultipart(c *gin.Context) bool {
+	return strings.HasPrefix(strings.ToLower(c.ContentType()), "multipart/form-data")
+}
+
+// saveUploadedFile menyimpan file multipart pada field ke FileStore.
+func saveUploadedFile(c *gin.Context, field, kind string) (services.StoredFile, bool) {
+	svc := fileService()
+	if svc.Store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "penyimpanan file belum dikonfigurasi"})
+		return services.StoredFile{}, false
+	}
+	fh, err := c.FormFile(field)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": field + " wajib diisi"})
+		return services.StoredFile{}, false
+	}
+	if fh.Size > svc.MaxBytes && svc.MaxBytes > 0 {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "ukuran file melebihi batas"})
+		return services.StoredFile{}, false
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "gagal membaca file"})
+		return services.StoredFile{}, false
+	}
+	defer f.Close()
+
+	stored, err := svc.Save(kind, f)
+	if err != nil {
+		respondFileError(c, err)
+		return services.StoredFile{}, false
+	}
+	return stored, true
+}
+
+func respondFileError(c *gin.Context, err error) {
+	respondBookingChangeError(c, err, "Gagal menyimpan file")
+}
+
+// ======================================================
+// POST /api/files (multipart: file, kind=surat-jalan|payment-proof)
+// ======================================================
+func UploadFile(c *gin.Context) {
+	kind := strings.TrimSpace(c.PostForm("kind"))
+	switch kind {
+	case "", services.FileKindSuratJalan:
+		kind = services.FileKindSuratJalan
+	case services.FileKindPaymentProof:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"message": "kind harus surat-jalan atau payment-proofs"})
+		return
+	}
+
+	stored, ok := saveUploadedFile(c, "file", kind)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "File tersimpan",
+		"file":    stored,
+		"url":     fileURL(stored.Ref),
+	})
+}
+
+// ======================================================
+// GET /api/files/*key?expires=&signature= (publik, URL bertanda tangan)
+// Hanya untuk FILE_STORE=local; S3 memakai presigned URL langsung ke bucket.
+// ======================================================
+func DownloadFile(c *gin.Context) {
+	store, ok := fileService().Store.(services.LocalFileStore)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"message": "file tidak ditemukan"})
+		return
+	}
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if !store.VerifySignedURL(key, c.Query("expires"), c.Query("signature")) {
+		c.JSON(http.StatusForbidden, gin.H{"message": "link file tidak valid atau sudah kedaluwarsa"})
+		return
+	}
+
+	f, err := store.Open(key)
+	if err != nil {
+		if errors.Is(err, services.ErrFileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "file tidak ditemukan"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal membaca file"})
+		return
+	}
+	defer f.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", contentType)
+	_, _ = io.Copy(c.Writer, f)
+}
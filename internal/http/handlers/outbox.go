@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"backend/internal/http/middleware"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func outboxService(c *gin.Context) services.OutboxService {
+	return services.OutboxService{RequestID: middleware.GetRequestID(c)}
+}
+
+// GET /api/admin/outbox?status=pending|processing|done|dead
+func AdminListOutboxEvents(c *gin.Context) {
+	list, err := outboxService(c).List(strings.TrimSpace(c.Query("status")))
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": list})
+}
+
+// GET /api/admin/outbox/:id
+func AdminGetOutboxEvent(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	e, err := outboxService(c).Get(id)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, e)
+}
+
+// POST /api/admin/outbox/:id/replay — antrekan ulang event dead untuk segera diproses worker
+func AdminReplayOutboxEvent(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	e, err := outboxService(c).Replay(id)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, e)
+}
@@ -870,8 +870,6 @@ func findDriverVehicleForBooking(bookingID int64) (string, string) {
 	switch {
 	case intdb.HasColumn(db, "departure_settings", "booking_id"):
 		_ = db.QueryRow(`SELECT id FROM departure_settings WHERE booking_id=? LIMIT 1`, bookingID).Scan(&dsID)
-	case intdb.HasColumn(db, "departure_settings", "reguler_booking_id"):
-		_ = db.QueryRow(`SELECT id FROM departure_settings WHERE reguler_booking_id=? LIMIT 1`, bookingID).Scan(&dsID)
 	default:
 		return "", ""
 	}
@@ -884,8 +882,6 @@ func findDriverVehicleForBooking(bookingID int64) (string, string) {
 	switch {
 	case intdb.HasColumn(db, "departure_settings", "driver_name"):
 		_ = db.QueryRow(`SELECT COALESCE(driver_name,'') FROM departure_settings WHERE id=?`, dsID).Scan(&driver)
-	case intdb.HasColumn(db, "departure_settings", "driver"):
-		_ = db.QueryRow(`SELECT COALESCE(driver,'') FROM departure_settings WHERE id=?`, dsID).Scan(&driver)
 	default:
 		driver = sql.NullString{String: "", Valid: true}
 	}
@@ -901,8 +897,6 @@ func findDriverVehicleForBooking(bookingID int64) (string, string) {
 		_ = db.QueryRow(`SELECT COALESCE(vehicle,'') FROM departure_settings WHERE id=?`, dsID).Scan(&vehicle)
 	case intdb.HasColumn(db, "departure_settings", "vehicle_code"):
 		_ = db.QueryRow(`SELECT COALESCE(vehicle_code,'') FROM departure_settings WHERE id=?`, dsID).Scan(&vehicle)
-	case intdb.HasColumn(db, "departure_settings", "car_code"):
-		_ = db.QueryRow(`SELECT COALESCE(car_code,'') FROM departure_settings WHERE id=?`, dsID).Scan(&vehicle)
 	default:
 		vehicle = sql.NullString{String: "", Valid: true}
 	}
@@ -951,8 +945,6 @@ func findDriverVehicleByTrip(dateStr, timeStr, from, to string) (string, string)
 	driverSel := "''"
 	if intdb.HasColumn(db, "departure_settings", "driver_name") {
 		driverSel = "COALESCE(driver_name,'')"
-	} else if intdb.HasColumn(db, "departure_settings", "driver") {
-		driverSel = "COALESCE(driver,'')"
 	}
 
 	vehicleSel := "''"
@@ -965,8 +957,6 @@ func findDriverVehicleByTrip(dateStr, timeStr, from, to string) (string, string)
 		vehicleSel = "COALESCE(vehicle,'')"
 	case intdb.HasColumn(db, "departure_settings", "vehicle_code"):
 		vehicleSel = "COALESCE(vehicle_code,'')"
-	case intdb.HasColumn(db, "departure_settings", "car_code"):
-		vehicleSel = "COALESCE(car_code,'')"
 	}
 
 	q := fmt.Sprintf(
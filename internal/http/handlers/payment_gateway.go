@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/domain"
+	"backend/internal/http/middleware"
+	"backend/internal/repositories"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	gatewayMu        sync.RWMutex
+	gatewayProvider  services.PaymentProvider
+	gatewayChargeTTL time.Duration
+)
+
+// SetPaymentGateway stores the payment provider (from env); nil disables charge & webhook endpoints.
+func SetPaymentGateway(p services.PaymentProvider, chargeTTL time.Duration) {
+	gatewayMu.Lock()
+	defer gatewayMu.Unlock()
+	gatewayProvider = p
+	gatewayChargeTTL = chargeTTL
+}
+
+func paymentGatewayService(c *gin.Context) services.PaymentGatewayService {
+	gatewayMu.RLock()
+	defer gatewayMu.RUnlock()
+	reqID := middleware.GetRequestID(c)
+	return services.PaymentGatewayService{
+		Provider:  gatewayProvider,
+		ChargeTTL: gatewayChargeTTL,
+		RequestID: reqID,
+		Ledger:    services.PaymentLedgerService{RequestID: reqID},
+		Payments: services.PaymentService{
+			PaymentRepo:     repositories.PaymentRepository{},
+			BookingRepo:     repositories.BookingRepository{},
+			BookingSeatRepo: repositories.BookingSeatRepository{},
+			RequestID:       reqID,
+			DepartureSvc:    services.DepartureService{Repo: repositories.DepartureRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}, RequestID: reqID},
+			ReturnSvc:       services.ReturnService{Repo: repositories.ReturnRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}, RequestID: reqID},
+			PassengerSvc:    services.PassengerService{PassengerRepo: repositories.PassengerRepository{}, BookingRepo: repositories.BookingRepository{}, BookingSeatRepo: repositories.BookingSeatRepository{}},
+		},
+	}
+}
+
+type CreateChargeRequest struct {
+	Method string `json:"method"` // "qris" | "va"
+	Bank   string `json:"bank"`   // bca | bni | bri | permata | cimb (untuk va)
+}
+
+// ======================================================
+// POST /api/reguler/bookings/:id/charge
+// ======================================================
+func CreateRegulerCharge(c *gin.Context) {
+	bookingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || bookingID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "id tidak valid"})
+		return
+	}
+	if !requireBookingAccess(c, bookingID) {
+		return
+	}
+
+	var req CreateChargeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "payload tidak valid"})
+			return
+		}
+	}
+
+	svc := paymentGatewayService(c)
+	if svc.Provider == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "payment gateway belum diaktifkan"})
+		return
+	}
+	charge, err := svc.CreateCharge(bookingID, req.Method, req.Bank)
+	if err != nil {
+		respondBookingChangeError(c, err, "Gagal membuat tagihan pembayaran")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tagihan pembayaran dibuat",
+		"charge":  charge,
+	})
+}
+
+// ======================================================
+// POST /api/payment-gateway/webhook (publik, diverifikasi signature)
+// ======================================================
+func PaymentWebhook(c *gin.Context) {
+	svc := paymentGatewayService(c)
+	if svc.Provider == nil {
+		respondError(c, http.StatusServiceUnavailable, "gateway_disabled", "payment gateway belum diaktifkan", nil)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_body", "gagal membaca payload", nil)
+		return
+	}
+	charge, err := svc.HandleWebhook(c.Request.Header, body)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reference": charge.Reference, "status": charge.Status})
+}
+
+type simulatePaymentRequest struct {
+	Status string `json:"status"` // paid (default) | expired | failed
+}
+
+// ======================================================
+// POST /api/payment-gateway/fake/:reference
+// Mensimulasikan webhook provider fake (hanya bila PAYMENT_PROVIDER=fake).
+// ======================================================
+func SimulateFakePayment(c *gin.Context) {
+	svc := paymentGatewayService(c)
+	fake, ok := svc.Provider.(services.FakePaymentProvider)
+	if !ok {
+		respondError(c, http.StatusNotFound, "not_found", "provider fake tidak aktif", nil)
+		return
+	}
+
+	var req simulatePaymentRequest
+	if c.Request.ContentLength > 0 && !BindJSONOrError(c, &req) {
+		return
+	}
+	status := strings.ToLower(strings.TrimSpace(req.Status))
+	if status == "" {
+		status = repositories.ChargePaid
+	}
+	switch status {
+	case repositories.ChargePaid, repositories.ChargeExpired, repositories.ChargeFailed:
+	default:
+		respondError(c, http.StatusBadRequest, "validation_error", "status harus paid, expired, atau failed", nil)
+		return
+	}
+
+	charge, err := repositories.PaymentChargeRepository{}.GetByReference(c.Param("reference"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			RespondDomainError(c, domain.NotFoundError{Resource: "tagihan"})
+			return
+		}
+		RespondDomainError(c, domain.InternalError{Msg: "gagal memuat tagihan", Err: err})
+		return
+	}
+	if !requireBookingAccess(c, charge.BookingID) {
+		return
+	}
+
+	body, sig, err := fake.SignedNotification(charge.Reference, status, charge.Amount, time.Now())
+	if err != nil {
+		RespondDomainError(c, domain.InternalError{Msg: "gagal membuat notifikasi", Err: err})
+		return
+	}
+	header := http.Header{}
+	header.Set(services.FakePaymentSignatureHeader, sig)
+
+	charge, err = svc.HandleWebhook(header, body)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"charge": charge})
+}
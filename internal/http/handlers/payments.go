@@ -3,7 +3,7 @@ package handlers
 import (
 	"bytes"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -13,6 +13,7 @@ import (
 
 	intconfig "backend/internal/config"
 	intdb "backend/internal/db"
+	"backend/internal/domain"
 	"backend/internal/http/middleware"
 	"backend/internal/repositories"
 	"backend/internal/services"
@@ -35,8 +36,9 @@ type PaymentValidation struct {
 	PaymentStatus string `json:"paymentStatus"`
 	TripRole      string `json:"tripRole"`
 
-	ProofFile     string `json:"proofFile"`
+	ProofFile     string `json:"proofFile"` // referensi FileStore ("file:...") atau base64 lama
 	ProofFileName string `json:"proofFileName"`
+	ProofFileURL  string `json:"proofFileUrl,omitempty"`
 
 	CreatedAt string `json:"createdAt"`
 }
@@ -62,7 +64,7 @@ func normalizeTripRolePV(role string) string {
 }
 
 func GetPaymentValidations(c *gin.Context) {
-	rows, err := intconfig.DB.Query(`
+	rows, err := intconfig.ReadOnlyDB().Query(`
 		SELECT 
 			id,
 			COALESCE(customer_name,''),
@@ -109,6 +111,7 @@ func GetPaymentValidations(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal membaca data: " + err.Error()})
 			return
 		}
+		pv.ProofFileURL = fileURL(pv.ProofFile)
 		list = append(list, pv)
 	}
 
@@ -116,9 +119,6 @@ func GetPaymentValidations(c *gin.Context) {
 }
 
 func CreatePaymentValidation(c *gin.Context) {
-	raw, _ := io.ReadAll(c.Request.Body)
-	c.Request.Body = io.NopCloser(bytes.NewBuffer(raw))
-
 	var input PaymentValidation
 	if err := c.ShouldBindJSON(&input); err != nil {
 		log.Println("CreatePaymentValidation bind error:", err)
@@ -137,6 +137,14 @@ func CreatePaymentValidation(c *gin.Context) {
 	input.TripRole = strings.TrimSpace(input.TripRole)
 	input.ProofFileName = strings.TrimSpace(input.ProofFileName)
 
+	// bukti base64 dipindah ke FileStore; kolom hanya menyimpan referensinya
+	ref, err := fileService().StoreInline(services.FileKindPaymentProof, input.ProofFile)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	input.ProofFile = ref
+
 	if input.PaymentStatus == "" {
 		input.PaymentStatus = "Menunggu Validasi"
 	}
@@ -198,8 +206,9 @@ func CreatePaymentValidation(c *gin.Context) {
 	input.ID = id
 	_ = intconfig.DB.QueryRow(`SELECT COALESCE(created_at,'') FROM payment_validations WHERE id=?`, id).Scan(&input.CreatedAt)
 
+	input.ProofFileURL = fileURL(input.ProofFile)
+
 	c.JSON(http.StatusCreated, input)
-	triggerValidatePayment(c, raw)
 }
 
 func DeletePaymentValidation(c *gin.Context) {
@@ -302,6 +311,14 @@ func UpdatePaymentValidation(c *gin.Context) {
 	input.TripRole = normalizeTripRolePV(input.TripRole)
 	input.ProofFileName = strings.TrimSpace(input.ProofFileName)
 
+	// bukti base64 dipindah ke FileStore; kolom hanya menyimpan referensinya
+	ref, err := fileService().StoreInline(services.FileKindPaymentProof, input.ProofFile)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	input.ProofFile = ref
+
 	if input.PaymentStatus == "" {
 		input.PaymentStatus = "Menunggu Validasi"
 	}
@@ -320,6 +337,7 @@ func UpdatePaymentValidation(c *gin.Context) {
 
 	var bookingID int64
 	var existingPayMethod sql.NullString
+	syncAfterCommit := false
 
 	if intdb.HasColumn(tx, "payment_validations", "booking_id") {
 		if err := tx.QueryRow(`SELECT COALESCE(booking_id,0), COALESCE(payment_method,'') FROM payment_validations WHERE id=? LIMIT 1`, id64).
@@ -410,18 +428,19 @@ func UpdatePaymentValidation(c *gin.Context) {
 			}
 		}
 
+		// status lunas + event booking_paid tersimpan di transaksi yang sama dengan edit validasi
 		if autoSyncOnManualPaidEdit && isValidationPaid(input.PaymentStatus) && strings.TrimSpace(input.TripRole) != "" {
-			reqID := middleware.GetRequestID(c)
-			svc := services.PaymentService{
-				PaymentRepo:     repositories.PaymentRepository{},
-				BookingRepo:     repositories.BookingRepository{},
-				BookingSeatRepo: repositories.BookingSeatRepository{},
-				RequestID:       reqID,
-				DepartureSvc:    services.DepartureService{Repo: repositories.DepartureRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}, RequestID: reqID},
-				ReturnSvc:       services.ReturnService{Repo: repositories.ReturnRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}, RequestID: reqID},
-				PassengerSvc:    services.PassengerService{PassengerRepo: repositories.PassengerRepository{}, BookingRepo: repositories.BookingRepository{}, BookingSeatRepo: repositories.BookingSeatRepository{}},
+			queued, err := paymentService(c).MarkPaidTx(tx, bookingID, raw)
+			switch {
+			case domain.IsConflict(err):
+				log.Println("UpdatePaymentValidation mark paid skipped:", err)
+			case err != nil:
+				log.Println("UpdatePaymentValidation mark paid error:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal menandai booking lunas: " + err.Error()})
+				return
+			default:
+				syncAfterCommit = !queued
 			}
-			_ = svc.ValidatePayment(bookingID, raw)
 		}
 	}
 
@@ -431,9 +450,15 @@ func UpdatePaymentValidation(c *gin.Context) {
 	}
 	committed = true
 
+	// tanpa tabel outbox (migration belum dijalankan) sinkron langsung seperti sebelumnya
+	if syncAfterCommit {
+		if err := paymentService(c).SyncSettings(bookingID, input.TripRole); err != nil {
+			log.Printf("[PAYMENT] sync after validation edit booking_id=%d error: %v", bookingID, err)
+		}
+	}
+
 	input.ID = id64
 	c.JSON(http.StatusOK, input)
-	triggerValidatePayment(c, raw)
 }
 
 func ApprovePaymentValidation(c *gin.Context) {
@@ -451,11 +476,60 @@ func updatePaymentValidationStatus(c *gin.Context, approved bool) {
 		return
 	}
 
-	tx, err := intconfig.DB.Begin()
+	res, err := applyPaymentValidation(c, validationID, approved, "")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal mulai transaksi: " + err.Error()})
+		respondPaymentValidationError(c, err)
 		return
 	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Status validasi diperbarui",
+		"validationId":  validationID,
+		"bookingId":     res.BookingID,
+		"paymentStatus": res.PaymentStatus,
+		"balance":       res.Balance,
+		"refund":        res.Refund,
+	})
+}
+
+// paymentValidationResult adalah hasil approve/reject satu payment_validations.
+type paymentValidationResult struct {
+	BookingID     int64
+	PaymentStatus string
+	Balance       *services.BookingBalance
+	// Refund terisi bila bukti disetujui setelah booking dibatalkan.
+	Refund *repositories.Refund
+}
+
+// respondPaymentValidationError mengirim error applyPaymentValidation sebagai {"message": ...}.
+func respondPaymentValidationError(c *gin.Context, err error) {
+	var (
+		ve domain.ValidationError
+		ce domain.ConflictError
+		ie domain.InternalError
+	)
+	switch {
+	case errors.As(err, &ve):
+		c.JSON(http.StatusBadRequest, gin.H{"message": ve.Msg})
+	case errors.As(err, &ce):
+		c.JSON(http.StatusConflict, gin.H{"message": ce.Msg})
+	case errors.As(err, &ie) && ie.Err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": ie.Msg + ": " + ie.Err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+// applyPaymentValidation menyetujui/menolak payment_validations: update validasi & ledger, pindahkan
+// status booking, lalu sinkron departure/return bila booking lunas. Dipakai endpoint approve/reject
+// dan pencocokan mutasi bank. tripRole kosong = pakai role yang tersimpan di validasi.
+func applyPaymentValidation(c *gin.Context, validationID int64, approved bool, tripRole string) (paymentValidationResult, error) {
+	var res paymentValidationResult
+
+	tx, err := intconfig.DB.Begin()
+	if err != nil {
+		return res, domain.InternalError{Msg: "gagal mulai transaksi", Err: err}
+	}
 	committed := false
 	defer func() {
 		if !committed {
@@ -465,43 +539,69 @@ func updatePaymentValidationStatus(c *gin.Context, approved bool) {
 
 	var bookingID int64
 	var payMethod sql.NullString
-	var tripRole string
+	var storedRole string
 
 	if intdb.HasColumn(tx, "payment_validations", "booking_id") {
 		if err := tx.QueryRow(
 			`SELECT COALESCE(booking_id,0), COALESCE(payment_method,''), COALESCE(trip_role,'') 
 			 FROM payment_validations WHERE id=? LIMIT 1`, validationID).
-			Scan(&bookingID, &payMethod, &tripRole); err != nil && err != sql.ErrNoRows {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal baca validasi: " + err.Error()})
-			return
+			Scan(&bookingID, &payMethod, &storedRole); err != nil && err != sql.ErrNoRows {
+			return res, domain.InternalError{Msg: "gagal baca validasi", Err: err}
 		}
 	} else {
 		if err := tx.QueryRow(
 			`SELECT COALESCE(payment_method,''), COALESCE(trip_role,'') 
 			 FROM payment_validations WHERE id=? LIMIT 1`, validationID).
-			Scan(&payMethod, &tripRole); err != nil && err != sql.ErrNoRows {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal baca validasi: " + err.Error()})
-			return
+			Scan(&payMethod, &storedRole); err != nil && err != sql.ErrNoRows {
+			return res, domain.InternalError{Msg: "gagal baca validasi", Err: err}
 		}
 		if intdb.HasColumn(tx, "bookings", "payment_validation_id") {
 			if err := tx.QueryRow(`SELECT COALESCE(id,0) FROM bookings WHERE payment_validation_id=? LIMIT 1`, validationID).
 				Scan(&bookingID); err != nil && err != sql.ErrNoRows {
-				c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal cari booking: " + err.Error()})
-				return
+				return res, domain.InternalError{Msg: "gagal cari booking", Err: err}
 			}
 		}
 	}
 
 	if bookingID <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "booking_id tidak ditemukan untuk validasi ini"})
-		return
+		return res, domain.ValidationError{Field: "booking_id", Msg: "booking_id tidak ditemukan untuk validasi ini"}
 	}
+	res.BookingID = bookingID
 
+	// booking dibatalkan saat buktinya masih menunggu: validasi hanya menentukan refund,
+	// status booking tetap dibatalkan dan tidak ada sinkronisasi perjalanan
+	status, err := (repositories.BookingRepository{}).StatusTx(tx, bookingID)
+	if err != nil {
+		return res, domain.InternalError{Msg: "gagal membaca status booking", Err: err}
+	}
+	if status == domain.BookingCancelled {
+		pvStatus := "Ditolak"
+		if approved {
+			pvStatus = "Sukses"
+		}
+		if _, err := tx.Exec(`UPDATE payment_validations SET payment_status = ? WHERE id = ?`, pvStatus, validationID); err != nil {
+			return res, domain.InternalError{Msg: "gagal update validasi", Err: err}
+		}
+		rf, err := cancellationService(c).SettleLateProofTx(tx, bookingID, validationID, approved, strings.TrimSpace(payMethod.String), statusActor(c).UserID)
+		if err != nil {
+			return res, err
+		}
+		if err := tx.Commit(); err != nil {
+			return res, domain.InternalError{Msg: "gagal commit transaksi", Err: err}
+		}
+		committed = true
+		res.PaymentStatus = domain.BookingCancelled.PaymentLabel()
+		res.Refund = rf
+		return res, nil
+	}
+
+	if strings.TrimSpace(tripRole) == "" {
+		tripRole = storedRole
+	}
 	tripRole = normalizeTripRolePV(tripRole)
 
 	if approved && strings.TrimSpace(tripRole) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Role trip belum diisi. Mohon edit validasi pembayaran dan pilih Keberangkatan/Kepulangan terlebih dahulu."})
-		return
+		return res, domain.ValidationError{Field: "trip_role", Msg: "Role trip belum diisi. Mohon edit validasi pembayaran dan pilih Keberangkatan/Kepulangan terlebih dahulu."}
 	}
 
 	newPVStatus := "Ditolak"
@@ -528,17 +628,34 @@ func updatePaymentValidationStatus(c *gin.Context, approved bool) {
 		"UPDATE payment_validations SET "+strings.Join(setParts, ", ")+" WHERE id = ?",
 		args...,
 	); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal update validasi: " + err.Error()})
-		return
+		return res, domain.InternalError{Msg: "gagal update validasi", Err: err}
+	}
+
+	// ledger payments menentukan hasil akhirnya: lunas hanya bila sisa tagihan nol,
+	// selain itu booking tetap/menjadi DP selama sudah ada pembayaran yang disetujui
+	target := domain.BookingStatusFromPayment(newBookingStatus)
+	if ledger := paymentLedgerService(c); ledger.Available() {
+		bal, err := ledger.SettleValidationTx(tx, bookingID, validationID, approved, strings.TrimSpace(payMethod.String), statusActor(c).UserID)
+		if err != nil {
+			return res, domain.InternalError{Msg: "gagal memperbarui ledger pembayaran", Err: err}
+		}
+		res.Balance = &bal
+		if st := bal.Status(); st == domain.BookingPartiallyPaid {
+			target = st
+			newBookingStatus = st.PaymentLabel()
+		}
+	}
+
+	if _, err := (repositories.BookingRepository{}).TransitionTx(tx, bookingID, target, statusActor(c), "payment_validation id="+strconv.FormatInt(validationID, 10)); err != nil {
+		if domain.IsConflict(err) {
+			return res, err
+		}
+		return res, domain.InternalError{Msg: "gagal update status booking", Err: err}
 	}
 
 	bSet := []string{}
 	bArgs := []any{}
 
-	if intdb.HasColumn(tx, "bookings", "payment_status") {
-		bSet = append(bSet, "payment_status = ?")
-		bArgs = append(bArgs, newBookingStatus)
-	}
 	if intdb.HasColumn(tx, "bookings", "payment_method") && payMethod.Valid && strings.TrimSpace(payMethod.String) != "" {
 		bSet = append(bSet, "payment_method = ?")
 		bArgs = append(bArgs, strings.TrimSpace(payMethod.String))
@@ -558,76 +675,48 @@ func updatePaymentValidationStatus(c *gin.Context, approved bool) {
 			"UPDATE bookings SET "+strings.Join(bSet, ", ")+" WHERE id = ?",
 			bArgs...,
 		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal update booking: " + err.Error()})
-			return
+			return res, domain.InternalError{Msg: "gagal update booking", Err: err}
 		}
 	}
 
+	// booking lunas: pembuatan departure/return settings & sinkron penumpang dicatat ke outbox
+	// di transaksi yang sama, lalu dikerjakan worker (dengan retry)
+	outbox := repositories.OutboxRepository{}
+	queued := false
+	if target == domain.BookingPaid && outbox.Available() {
+		payload := repositories.BookingPaidPayload{BookingID: bookingID, TripRole: tripRole}
+		if _, err := outbox.EnqueueTx(tx, repositories.EventBookingPaid, bookingID, payload, middleware.GetRequestID(c)); err != nil {
+			return res, domain.InternalError{Msg: "gagal mencatat event sinkronisasi", Err: err}
+		}
+		queued = true
+	}
+
 	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal commit transaksi: " + err.Error()})
-		return
+		return res, domain.InternalError{Msg: "gagal commit transaksi", Err: err}
 	}
 	committed = true
+	res.PaymentStatus = newBookingStatus
 
-	if approved {
-		reqID := middleware.GetRequestID(c)
-		svc := services.PaymentService{
-			PaymentRepo:     repositories.PaymentRepository{},
-			BookingRepo:     repositories.BookingRepository{},
-			BookingSeatRepo: repositories.BookingSeatRepository{},
-			RequestID:       reqID,
-			DepartureSvc:    services.DepartureService{Repo: repositories.DepartureRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}, RequestID: reqID},
-			ReturnSvc:       services.ReturnService{Repo: repositories.ReturnRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}, RequestID: reqID},
-			PassengerSvc:    services.PassengerService{PassengerRepo: repositories.PassengerRepository{}, BookingRepo: repositories.BookingRepository{}, BookingSeatRepo: repositories.BookingSeatRepository{}},
+	// tanpa tabel outbox (migration belum dijalankan) sinkron langsung seperti sebelumnya
+	if target == domain.BookingPaid && !queued {
+		if err := paymentService(c).SyncSettings(bookingID, tripRole); err != nil {
+			log.Printf("[PAYMENT] sync after validation booking_id=%d error: %v", bookingID, err)
 		}
-		_ = svc.ValidatePayment(bookingID, nil)
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":       "Status validasi diperbarui",
-		"validationId":  validationID,
-		"bookingId":     bookingID,
-		"paymentStatus": newBookingStatus,
-	})
+	return res, nil
 }
 
-func triggerValidatePayment(c *gin.Context, raw []byte) {
-	var payload map[string]any
-	_ = json.Unmarshal(raw, &payload)
-
-	var bookingID int64
-	for _, k := range []string{"booking_id", "bookingId"} {
-		if v, ok := payload[k]; ok {
-			switch val := v.(type) {
-			case float64:
-				bookingID = int64(val)
-			case int64:
-				bookingID = val
-			case int:
-				bookingID = int64(val)
-			case string:
-				if n, err := strconv.ParseInt(val, 10, 64); err == nil {
-					bookingID = n
-				}
-			}
-		}
-	}
-
-	if bookingID <= 0 {
-		return
-	}
-
+// paymentService menyiapkan PaymentService (validasi lunas + sinkron departure/return) untuk request c.
+func paymentService(c *gin.Context) services.PaymentService {
 	reqID := middleware.GetRequestID(c)
-	svc := services.PaymentService{
+	return services.PaymentService{
 		PaymentRepo:     repositories.PaymentRepository{},
 		BookingRepo:     repositories.BookingRepository{},
 		BookingSeatRepo: repositories.BookingSeatRepository{},
 		RequestID:       reqID,
+		Actor:           statusActor(c),
 		DepartureSvc:    services.DepartureService{Repo: repositories.DepartureRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}, RequestID: reqID},
 		ReturnSvc:       services.ReturnService{Repo: repositories.ReturnRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}, RequestID: reqID},
 		PassengerSvc:    services.PassengerService{PassengerRepo: repositories.PassengerRepository{}, BookingRepo: repositories.BookingRepository{}, BookingSeatRepo: repositories.BookingSeatRepository{}},
 	}
-	if err := svc.ValidatePayment(bookingID, raw); err != nil {
-		RespondError(c, http.StatusInternalServerError, "gagal memvalidasi pembayaran", err)
-	}
 }
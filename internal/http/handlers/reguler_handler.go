@@ -10,6 +10,10 @@ import (
 	"time"
 
 	intconfig "backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/http/middleware"
+	"backend/internal/repositories"
+	"backend/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-sql-driver/mysql"
@@ -29,6 +33,8 @@ type RegulerQuoteRequest struct {
 	Time           string   `json:"time"`
 	SelectedSeats  []string `json:"selectedSeats"`
 	PassengerCount int      `json:"passengerCount"`
+	HoldToken      string   `json:"holdToken,omitempty"`
+	SlotID         int64    `json:"slotId,omitempty"` // trip slot dari GET /slots (opsional, dicocokkan dgn date/time)
 }
 
 type RegulerQuoteResponse struct {
@@ -44,122 +50,25 @@ type StopItem struct {
 	Display string `json:"display"`
 }
 
-// ====== util normalisasi ======
+// ====== katalog stop & tarif (tabel stops/fares via FareService) ======
 
-func normalizeKey(s string) string {
-	s = strings.ToLower(strings.TrimSpace(s))
-	s = strings.ReplaceAll(s, " ", "")
-	s = strings.ReplaceAll(s, "-", "")
-	s = strings.ReplaceAll(s, ".", "")
-	return s
+func fareService(c *gin.Context) services.FareService {
+	return services.FareService{RequestID: middleware.GetRequestID(c)}
 }
 
-// canonical display name (konsisten untuk DB & unique index booking_seats)
-func canonicalStopDisplay(s string) (display string, key string, ok bool) {
-	k := normalizeKey(s)
-
-	switch k {
-	case "skpd":
-		return "SKPD", "skpd", true
-	case "simpangd":
-		return "Simpang D", "simpangd", true
-	case "skpc":
-		return "SKPC", "skpc", true
-	case "simpangkumu":
-		return "Simpang Kumu", "simpangkumu", true
-	case "muararumbai":
-		return "Muara Rumbai", "muararumbai", true
-	case "surautinggi":
-		return "Surau Tinggi", "surautinggi", true
-	case "pasirpengaraian", "pasipengaraian":
-		return "Pasir Pengaraian", "pasirpengaraian", true
-	case "ub", "ujungbatu":
-		return "Ujung Batu", "ujungbatu", true
-	case "tandun":
-		return "Tandun", "tandun", true
-	case "silam":
-		return "Silam", "silam", true
-	case "petapahan":
-		return "Petapahan", "petapahan", true
-	case "suram":
-		return "Suram", "suram", true
-	case "aliantan":
-		return "Aliantan", "aliantan", true
-	case "kuok":
-		return "Kuok", "kuok", true
-	case "bangkinang":
-		return "Bangkinang", "bangkinang", true
-	case "kabun":
-		return "Kabun", "kabun", true
-	case "pku", "pekanbaru":
-		return "Pekanbaru", "pekanbaru", true
+// respondRegulerError memetakan error katalog stop/tarif/denah ke response reguler (format {"message": ...}).
+func respondRegulerError(c *gin.Context, err error) {
+	var ve domain.ValidationError
+	switch {
+	case errors.As(err, &ve):
+		c.JSON(http.StatusBadRequest, gin.H{"message": ve.Msg})
+	case domain.IsNotFound(err):
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Tarif rute ini belum tersedia. Pilih rute lain."})
 	default:
-		return "", "", false
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal memuat katalog stop/tarif"})
 	}
 }
 
-// ====== pricing rules (bidirectional) sesuai ongkos ======
-
-var groupA = map[string]bool{
-	"skpd":            true,
-	"simpangd":        true,
-	"skpc":            true,
-	"simpangkumu":     true,
-	"muararumbai":     true,
-	"surautinggi":     true,
-	"pasirpengaraian": true,
-}
-
-func isPair(a, b, x, y string) bool {
-	return (a == x && b == y) || (a == y && b == x)
-}
-
-func regulerFarePerSeat(fromKey, toKey string) int64 {
-	if fromKey == "" || toKey == "" || fromKey == toKey {
-		return 0
-	}
-
-	// khusus
-	if isPair(fromKey, toKey, "bangkinang", "pekanbaru") {
-		return 100000
-	}
-	if isPair(fromKey, toKey, "ujungbatu", "pekanbaru") {
-		return 130000
-	}
-	if isPair(fromKey, toKey, "suram", "pekanbaru") {
-		return 120000
-	}
-	if isPair(fromKey, toKey, "petapahan", "pekanbaru") {
-		return 100000
-	}
-
-	// Group A <-> tujuan tertentu
-	if groupA[fromKey] || groupA[toKey] {
-		other := toKey
-		if groupA[toKey] {
-			other = fromKey
-		}
-		switch other {
-		case "pekanbaru":
-			return 150000
-		case "kabun":
-			return 120000
-		case "tandun":
-			return 100000
-		case "petapahan":
-			return 130000
-		case "suram":
-			return 120000
-		case "aliantan":
-			return 120000
-		case "bangkinang":
-			return 130000
-		}
-	}
-
-	return 0
-}
-
 // ====== util validation ======
 
 func normalizeTimeStr(t string) (string, error) {
@@ -212,6 +121,19 @@ func normalizeSeats(arr []string) []string {
 	return out
 }
 
+func containsAnySeat(list, seats []string) bool {
+	set := map[string]bool{}
+	for _, s := range list {
+		set[strings.ToUpper(strings.TrimSpace(s))] = true
+	}
+	for _, s := range seats {
+		if set[s] {
+			return true
+		}
+	}
+	return false
+}
+
 func bestOrderBy(db queryRower, table string) string {
 	if hasColumn(db, table, "created_at") {
 		return "created_at ASC"
@@ -226,93 +148,56 @@ func bestOrderBy(db queryRower, table string) string {
 // ✅ 1) GET /api/reguler/stops
 // ======================================================
 func GetRegulerStops(c *gin.Context) {
-	stops := []StopItem{
-		{Key: "skpd", Display: "SKPD"},
-		{Key: "simpangd", Display: "Simpang D"},
-		{Key: "skpc", Display: "SKPC"},
-		{Key: "simpangkumu", Display: "Simpang Kumu"},
-		{Key: "muararumbai", Display: "Muara Rumbai"},
-		{Key: "surautinggi", Display: "Surau Tinggi"},
-		{Key: "pasirpengaraian", Display: "Pasir Pengaraian"},
-		{Key: "ujungbatu", Display: "Ujung Batu"},
-		{Key: "tandun", Display: "Tandun"},
-		{Key: "silam", Display: "Silam"},
-		{Key: "petapahan", Display: "Petapahan"},
-		{Key: "suram", Display: "Suram"},
-		{Key: "aliantan", Display: "Aliantan"},
-		{Key: "kuok", Display: "Kuok"},
-		{Key: "bangkinang", Display: "Bangkinang"},
-		{Key: "kabun", Display: "Kabun"},
-		{Key: "pekanbaru", Display: "Pekanbaru"},
+	list, err := fareService(c).ListStops(true)
+	if err != nil {
+		respondRegulerError(c, err)
+		return
+	}
+	stops := make([]StopItem, 0, len(list))
+	for _, st := range list {
+		stops = append(stops, StopItem{Key: st.Key, Display: st.DisplayName})
 	}
 	c.JSON(http.StatusOK, gin.H{"stops": stops})
 }
 
 // ======================================================
-// ✅ 2) GET /api/reguler/seats?from=&to=&date=&time=
+// ✅ 2) GET /api/reguler/seats?from=&to=&date=&time=[&slotId=]
 // ======================================================
 func GetRegulerSeats(c *gin.Context) {
-	from := strings.TrimSpace(c.Query("from"))
-	to := strings.TrimSpace(c.Query("to"))
-	date := strings.TrimSpace(c.Query("date"))
-	tm := strings.TrimSpace(c.Query("time"))
-
-	if from == "" || to == "" || date == "" || tm == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "from, to, date, time wajib"})
-		return
-	}
-
-	fromDisplay, _, ok := canonicalStopDisplay(from)
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Origin tidak didukung"})
-		return
-	}
-	toDisplay, _, ok := canonicalStopDisplay(to)
+	slot, ok := resolveRegulerSlot(c, c.Query("from"), c.Query("to"), c.Query("date"), c.Query("time"))
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Destination tidak didukung"})
 		return
 	}
-
-	if _, err := time.Parse("2006-01-02", date); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Format date tidak valid (YYYY-MM-DD)"})
+	slotID, _ := strconv.ParseInt(strings.TrimSpace(c.Query("slotId")), 10, 64)
+	if _, ok := resolveTripSlot(c, &slot, slotID); !ok {
 		return
 	}
 
-	hhmm, err := normalizeTimeStr(tm)
+	// holdToken (opsional) = hold milik pemanggil, dilaporkan terpisah supaya tetap bisa dipilih
+	state, err := seatHoldService(c).SlotSeats(intconfig.DB, slot, strings.TrimSpace(c.Query("holdToken")))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal mengambil seat"})
 		return
 	}
 
-	rows, err := intconfig.DB.Query(`
-		SELECT seat_code
-		FROM booking_seats
-		WHERE route_from = ?
-		  AND route_to = ?
-		  AND trip_date = ?
-		  AND trip_time = ?
-	`, fromDisplay, toDisplay, date, hhmm)
+	// denah kendaraan slot + status tiap kursi (null bila belum ada denah)
+	var seatMap *services.SeatMap
+	layout, ok, err := seatLayoutService(c).LayoutForSlot(slot)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal mengambil seat"})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal memuat denah kursi"})
 		return
 	}
-	defer rows.Close()
-
-	booked := []string{}
-	for rows.Next() {
-		var seat string
-		if err := rows.Scan(&seat); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal membaca seat"})
-			return
-		}
-		booked = append(booked, strings.ToUpper(strings.TrimSpace(seat)))
-	}
-	if err := rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal membaca hasil seat"})
-		return
+	if ok {
+		m := services.BuildSeatMap(layout, state)
+		seatMap = &m
 	}
 
-	c.JSON(http.StatusOK, gin.H{"bookedSeats": booked})
+	c.JSON(http.StatusOK, gin.H{
+		"bookedSeats": state.Booked,
+		"heldSeats":   state.Held,
+		"myHeldSeats": state.OwnHeld,
+		"layout":      seatMap,
+	})
 }
 
 // ======================================================
@@ -330,20 +215,12 @@ func GetRegulerQuote(c *gin.Context) {
 	req.Date = strings.TrimSpace(req.Date)
 	req.Time = strings.TrimSpace(req.Time)
 
-	fromDisplay, fromKey, ok := canonicalStopDisplay(req.From)
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Origin tidak didukung"})
-		return
-	}
-	toDisplay, toKey, ok := canonicalStopDisplay(req.To)
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Destination tidak didukung"})
-		return
-	}
-	if fromKey == toKey {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Origin dan Destination tidak boleh sama"})
+	fare, err := fareService(c).Quote(req.From, req.To, req.Category, req.Date)
+	if err != nil {
+		respondRegulerError(c, err)
 		return
 	}
+	fromDisplay, toDisplay := fare.From.DisplayName, fare.To.DisplayName
 
 	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Format date tidak valid (YYYY-MM-DD)"})
@@ -378,49 +255,38 @@ func GetRegulerQuote(c *gin.Context) {
 		return
 	}
 
-	pricePerSeat := regulerFarePerSeat(fromKey, toKey)
-	if pricePerSeat <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Tarif rute ini belum tersedia. Pilih rute lain."})
-		return
-	}
-
+	pricePerSeat := fare.PricePerSeat
 	total := int64(pax) * pricePerSeat
 	if total > RegulerMaxTotal {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Total melebihi batas maksimal (900.000)"})
 		return
 	}
 
-	// cek conflict seat sebelum user klik Next
-	placeholders := make([]string, 0, len(seats))
-	args := make([]any, 0, 4+len(seats))
-	for range seats {
-		placeholders = append(placeholders, "?")
+	// cek conflict seat (booked / ditahan customer lain) sebelum user klik Next
+	slot, ok := withSlotLegs(c, repositories.SeatSlot{From: fromDisplay, To: toDisplay, Date: req.Date, Time: hhmm})
+	if !ok {
+		return
 	}
-	args = append(args, fromDisplay, toDisplay, req.Date, hhmm)
-	for _, s := range seats {
-		args = append(args, s)
-	}
-
-	q := `
-		SELECT seat_code
-		FROM booking_seats
-		WHERE route_from = ?
-		  AND route_to = ?
-		  AND trip_date = ?
-		  AND trip_time = ?
-		  AND seat_code IN (` + strings.Join(placeholders, ",") + `)
-		LIMIT 1
-	`
-	var taken string
-	err = intconfig.DB.QueryRow(q, args...).Scan(&taken)
-	if err == nil && strings.TrimSpace(taken) != "" {
-		c.JSON(http.StatusConflict, gin.H{"message": "Ada seat yang sudah dibooking. Silakan pilih seat lain."})
+	tripSlot, ok := resolveTripSlot(c, &slot, req.SlotID)
+	if !ok || !validateSlotSeats(c, slot, seats) {
 		return
 	}
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+	state, err := seatHoldService(c).SlotSeats(intconfig.DB, slot, strings.TrimSpace(req.HoldToken))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal cek seat availability"})
 		return
 	}
+	if containsAnySeat(state.Booked, seats) {
+		c.JSON(http.StatusConflict, gin.H{"message": "Ada seat yang sudah dibooking. Silakan pilih seat lain."})
+		return
+	}
+	if containsAnySeat(state.Held, seats) {
+		c.JSON(http.StatusConflict, gin.H{"message": "Ada seat yang sedang ditahan pelanggan lain. Silakan pilih seat lain."})
+		return
+	}
+	if !checkSlotCapacity(c, tripSlot, state, len(seats)) {
+		return
+	}
 
 	c.JSON(http.StatusOK, RegulerQuoteResponse{
 		PricePerSeat: int(pricePerSeat),
@@ -454,26 +320,13 @@ func CreateRegulerBooking(c *gin.Context) {
 		req.Category = "Reguler"
 	}
 
-	fromDisplay, fromKey, ok := canonicalStopDisplay(req.From)
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Origin tidak didukung"})
-		return
-	}
-	toDisplay, toKey, ok := canonicalStopDisplay(req.To)
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Destination tidak didukung"})
-		return
-	}
-	if fromKey == toKey {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Origin dan Destination tidak boleh sama"})
-		return
-	}
-
-	pricePerSeat := regulerFarePerSeat(fromKey, toKey)
-	if pricePerSeat <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Tarif rute ini belum tersedia. Pilih rute lain."})
+	fare, err := fareService(c).Quote(req.From, req.To, req.Category, req.Date)
+	if err != nil {
+		respondRegulerError(c, err)
 		return
 	}
+	fromDisplay, toDisplay := fare.From.DisplayName, fare.To.DisplayName
+	pricePerSeat := fare.PricePerSeat
 
 	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Format date tidak valid (YYYY-MM-DD)"})
@@ -519,6 +372,16 @@ func CreateRegulerBooking(c *gin.Context) {
 
 	req.BookingFor = normalizeBookingFor(req.BookingFor)
 
+	slot, ok := withSlotLegs(c, repositories.SeatSlot{From: fromDisplay, To: toDisplay, Date: req.Date, Time: hhmm})
+	if !ok {
+		return
+	}
+	// perjalanan terjadwal wajib menunjuk trip slot open (slotId atau date+time yang cocok)
+	tripSlot, ok := resolveTripSlot(c, &slot, req.SlotID)
+	if !ok || !validateSlotSeats(c, slot, req.SelectedSeats) {
+		return
+	}
+
 	total := int64(req.PassengerCount) * pricePerSeat
 	if total > RegulerMaxTotal {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Total melebihi batas maksimal (900.000)"})
@@ -530,10 +393,11 @@ func CreateRegulerBooking(c *gin.Context) {
 	if paymentMethod != "cash" && paymentMethod != "transfer" && paymentMethod != "qris" {
 		paymentMethod = "" // biar tidak nyimpan value aneh
 	}
+	// cash belum lunas sampai admin mencatat uang yang diterima lewat confirm-cash (ledger)
 	var paymentStatus string
 	switch paymentMethod {
 	case "cash":
-		paymentStatus = "Lunas"
+		paymentStatus = domain.BookingAwaitingPayment.PaymentLabel()
 	case "transfer", "qris":
 		paymentStatus = "Menunggu Validasi"
 	}
@@ -576,10 +440,19 @@ func CreateRegulerBooking(c *gin.Context) {
 		cols = append(cols, "booking_for")
 		args = append(args, req.BookingFor)
 	}
+	if tripSlot.ID > 0 {
+		cols = append(cols, "trip_slot_id")
+		args = append(args, tripSlot.ID)
+	}
 	if hasColumn(tx, "bookings", "passenger_phone") {
 		cols = append(cols, "passenger_phone")
 		args = append(args, req.PassengerPhone)
 	}
+	// pemilik booking (dipakai untuk membatasi akses customer ke booking miliknya)
+	if rc, ok := middleware.GetRequestContext(c); ok {
+		cols = append(cols, "user_id")
+		args = append(args, int64(rc.UserID))
+	}
 
 	// payment columns (opsional, tergantung schema)
 	if hasColumn(tx, "bookings", "payment_method") && paymentMethod != "" {
@@ -606,6 +479,68 @@ func CreateRegulerBooking(c *gin.Context) {
 		return
 	}
 	bookingID, _ := bookingRes.LastInsertId()
+	if err := (repositories.BookingRepository{}).StartStatusTx(tx, bookingID, domain.BookingStatusFromPayment(paymentStatus), statusActor(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal menyimpan status booking"})
+		return
+	}
+	// transfer dapat kode unik supaya mutasi bank bisa dicocokkan ke booking ini
+	var transferCode, transferAmount int64
+	if paymentMethod == "transfer" {
+		code, err := transferCodeService(c).AssignTx(tx, bookingID, total)
+		if err != nil {
+			RespondDomainError(c, err)
+			return
+		}
+		if code > 0 {
+			transferCode, transferAmount = code, total+code
+		}
+	}
+	// booking transfer/QRIS belum lunas wajib mengirim bukti bayar sebelum batas waktu, kalau tidak di-expire;
+	// cash dibayar ke sopir/admin sehingga tidak punya batas upload bukti
+	var paymentDeadline *time.Time
+	if policy := bookingExpiryPolicy(); policy.Enabled() && paymentStatus != "Lunas" && paymentMethod != "cash" {
+		if dl, err := policy.Deadline(time.Now(), req.Date, hhmm); err == nil {
+			if err := (repositories.BookingRepository{}).SetPaymentDeadlineTx(tx, bookingID, dl); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal menyimpan batas pembayaran"})
+				return
+			}
+			paymentDeadline = &dl
+		}
+	}
+
+	// seat yang sudah dibooking di segmen overlap (naik/turun beda halte) tidak tertangkap unique key
+	holds := seatHoldService(c)
+	if err := holds.EnsureSeatsFreeTx(tx, slot, req.SelectedSeats); err != nil {
+		if domain.IsConflict(err) {
+			c.JSON(http.StatusConflict, gin.H{"message": "Seat sudah dibooking orang lain, silakan pilih seat lain"})
+			return
+		}
+		RespondDomainError(c, err)
+		return
+	}
+
+	if tripSlot.ID > 0 {
+		err := scheduleService(c).CheckCapacityTx(tx, tripSlot, slot, req.HoldToken, req.SelectedSeats)
+		var ce domain.ConflictError
+		if errors.As(err, &ce) {
+			c.JSON(http.StatusConflict, gin.H{"message": ce.Msg})
+			return
+		}
+		if err != nil {
+			RespondDomainError(c, err)
+			return
+		}
+	}
+
+	// seat yang ditahan customer lain ditolak; hold milik request ini dilepas
+	var holderID int64
+	if rc, ok := middleware.GetRequestContext(c); ok {
+		holderID = int64(rc.UserID)
+	}
+	if err := holds.ConsumeTx(tx, holderID, req.HoldToken, slot, req.SelectedSeats); err != nil {
+		RespondDomainError(c, err)
+		return
+	}
 
 	for _, seat := range req.SelectedSeats {
 		_, err := tx.Exec(`
@@ -697,8 +632,12 @@ func CreateRegulerBooking(c *gin.Context) {
 		PricePerSeat: pricePerSeat,
 		TotalAmount:  total,
 
-		PaymentMethod: paymentMethod,
-		PaymentStatus: paymentStatus,
+		TransferCode:   transferCode,
+		TransferAmount: transferAmount,
+
+		PaymentMethod:   paymentMethod,
+		PaymentStatus:   paymentStatus,
+		PaymentDeadline: paymentDeadline,
 	})
 }
 
@@ -747,6 +686,9 @@ func GetRegulerSuratJalan(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "id tidak valid"})
 		return
 	}
+	if !requireBookingAccess(c, id64) {
+		return
+	}
 
 	scope := strings.ToLower(strings.TrimSpace(c.Query("scope"))) // "" | "trip"
 
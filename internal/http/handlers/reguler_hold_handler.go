@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	intconfig "backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/http/middleware"
+	"backend/internal/repositories"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	seatHoldMu  sync.RWMutex
+	seatHoldSvc services.SeatHoldService
+)
+
+// SetSeatHoldService stores the seat hold service (TTL from env) used by reguler handlers.
+func SetSeatHoldService(svc services.SeatHoldService) {
+	seatHoldMu.Lock()
+	defer seatHoldMu.Unlock()
+	seatHoldSvc = svc
+}
+
+func seatHoldService(c *gin.Context) services.SeatHoldService {
+	seatHoldMu.RLock()
+	defer seatHoldMu.RUnlock()
+	svc := seatHoldSvc
+	svc.RequestID = middleware.GetRequestID(c)
+	return svc
+}
+
+type RegulerHoldRequest struct {
+	From          string   `json:"from"`
+	To            string   `json:"to"`
+	Date          string   `json:"date"`
+	Time          string   `json:"time"`
+	SelectedSeats []string `json:"selectedSeats"`
+	HoldToken     string   `json:"holdToken,omitempty"` // isi untuk mengganti pilihan seat pada hold yang sama
+	SlotID        int64    `json:"slotId,omitempty"`
+}
+
+// resolveRegulerSlot memvalidasi from/to/date/time dan mengembalikan slot kanonik
+// beserta segmen overlap di jalurnya. Menulis response 400 ({"message": ...}) bila tidak valid.
+func resolveRegulerSlot(c *gin.Context, from, to, date, tm string) (repositories.SeatSlot, bool) {
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	date, tm = strings.TrimSpace(date), strings.TrimSpace(tm)
+	if from == "" || to == "" || date == "" || tm == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "from, to, date, time wajib"})
+		return repositories.SeatSlot{}, false
+	}
+
+	fares := fareService(c)
+	fromStop, err := fares.ResolveStop(from)
+	if err != nil {
+		respondRegulerError(c, domain.ValidationError{Field: "from", Msg: "Origin tidak didukung", Err: err})
+		return repositories.SeatSlot{}, false
+	}
+	toStop, err := fares.ResolveStop(to)
+	if err != nil {
+		respondRegulerError(c, domain.ValidationError{Field: "to", Msg: "Destination tidak didukung", Err: err})
+		return repositories.SeatSlot{}, false
+	}
+
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Format date tidak valid (YYYY-MM-DD)"})
+		return repositories.SeatSlot{}, false
+	}
+	hhmm, err := normalizeTimeStr(tm)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return repositories.SeatSlot{}, false
+	}
+	return withSlotLegs(c, repositories.SeatSlot{From: fromStop.DisplayName, To: toStop.DisplayName, Date: date, Time: hhmm})
+}
+
+// ======================================================
+// POST /api/reguler/holds
+// ======================================================
+func CreateRegulerHold(c *gin.Context) {
+	var req RegulerHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "JSON tidak valid"})
+		return
+	}
+	slot, ok := resolveRegulerSlot(c, req.From, req.To, req.Date, req.Time)
+	if !ok {
+		return
+	}
+
+	seats := normalizeSeats(req.SelectedSeats)
+	if len(seats) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "selectedSeats wajib"})
+		return
+	}
+	if hasDuplicates(seats) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Seat tidak boleh duplikat"})
+		return
+	}
+	if len(seats) > RegulerMaxPax {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Maksimal 6 seat per hold"})
+		return
+	}
+	tripSlot, ok := resolveTripSlot(c, &slot, req.SlotID)
+	if !ok || !validateSlotSeats(c, slot, seats) {
+		return
+	}
+	if tripSlot.ID > 0 {
+		// hold lama dengan token yang sama akan diganti, jadi tidak ikut dihitung
+		state, err := seatHoldService(c).SlotSeats(intconfig.DB, slot, strings.TrimSpace(req.HoldToken))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal cek seat availability"})
+			return
+		}
+		if !checkSlotCapacity(c, tripSlot, state, len(seats)) {
+			return
+		}
+	}
+
+	var userID int64
+	if rc, ok := middleware.GetRequestContext(c); ok {
+		userID = int64(rc.UserID)
+	}
+	hold, err := seatHoldService(c).Hold(userID, slot, seats, req.HoldToken)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, hold)
+}
+
+// ======================================================
+// DELETE /api/reguler/holds/:token
+// ======================================================
+func ReleaseRegulerHold(c *gin.Context) {
+	token := strings.TrimSpace(c.Param("token"))
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "token wajib"})
+		return
+	}
+	var userID int64
+	if rc, ok := middleware.GetRequestContext(c); ok {
+		userID = int64(rc.UserID)
+	}
+	if err := seatHoldService(c).Release(userID, token); err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -1,5 +1,7 @@
 package handlers
 
+import "time"
+
 // Max jumlah penumpang untuk reguler (sesuaikan jika kursi berubah)
 const RegulerMaxPax = 6
 
@@ -26,6 +28,12 @@ type RegulerBookingRequest struct {
 
 	SelectedSeats []string `json:"selectedSeats"`
 
+	// HoldToken dari POST /holds; seat yang ditahan dilepas saat booking tersimpan
+	HoldToken string `json:"holdToken,omitempty"`
+
+	// SlotID trip slot dari GET /slots; wajib cocok dengan date/time bila rute sudah terjadwal
+	SlotID int64 `json:"slotId,omitempty"`
+
 	// バ. Tambahan agar cocok dengan reguler_handler.go (tidak error lagi)
 	BookingFor     string          `json:"bookingFor,omitempty"`     // "self" / "other" (opsional)
 	PassengerCount int             `json:"passengerCount,omitempty"` // opsional; default = len(selectedSeats)
@@ -73,9 +81,16 @@ type RegulerBookingResponse struct {
 	PricePerSeat int64 `json:"pricePerSeat,omitempty"`
 	TotalAmount  int64 `json:"totalAmount"`
 
+	// kode unik transfer (metode transfer); nominal yang ditransfer = TotalAmount + TransferCode
+	TransferCode   int64 `json:"transferCode,omitempty"`
+	TransferAmount int64 `json:"transferAmount,omitempty"`
+
 	PaymentMethod string `json:"paymentMethod"`
 	PaymentStatus string `json:"paymentStatus"`
 
+	// batas upload bukti bayar (booking belum lunas); lewat batas ini booking di-expire
+	PaymentDeadline *time.Time `json:"paymentDeadline,omitempty"`
+
 	// OPTIONAL: relasi ke tabel payment_validations jika disimpan di booking
 	PaymentValidationID *int64 `json:"paymentValidationId,omitempty"`
 }
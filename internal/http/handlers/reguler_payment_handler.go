@@ -7,9 +7,11 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	intconfig "backend/internal/config"
+	"backend/internal/domain"
 	"backend/internal/http/middleware"
 	"backend/internal/repositories"
 	"backend/internal/services"
@@ -17,6 +19,32 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+var (
+	expiryPolicyMu sync.RWMutex
+	expiryPolicy   services.BookingExpiryPolicy
+)
+
+// SetBookingExpiryPolicy stores the unpaid-booking deadline policy (from env) used when creating bookings.
+func SetBookingExpiryPolicy(p services.BookingExpiryPolicy) {
+	expiryPolicyMu.Lock()
+	defer expiryPolicyMu.Unlock()
+	expiryPolicy = p
+}
+
+func bookingExpiryPolicy() services.BookingExpiryPolicy {
+	expiryPolicyMu.RLock()
+	defer expiryPolicyMu.RUnlock()
+	return expiryPolicy
+}
+
+func paymentLedgerService(c *gin.Context) services.PaymentLedgerService {
+	return services.PaymentLedgerService{RequestID: middleware.GetRequestID(c)}
+}
+
+func transferCodeService(c *gin.Context) services.TransferCodeService {
+	return services.TransferCodeService{RequestID: middleware.GetRequestID(c)}
+}
+
 // ===============================
 // REQUEST DTO
 // ===============================
@@ -25,21 +53,23 @@ type SubmitPaymentRequest struct {
 	PaymentMethod string `json:"paymentMethod"` // "transfer" | "qris"
 	ProofFile     string `json:"proofFile"`     // base64/data-url string
 	ProofFileName string `json:"proofFileName"` // nama file bukti
+	Amount        int64  `json:"amount"`        // nominal transfer; 0 = sisa tagihan
+}
+
+type ConfirmCashRequest struct {
+	Amount int64  `json:"amount"` // nominal diterima; 0 = sisa tagihan
+	Note   string `json:"note"`
 }
 
 // ===============================
-// Helpers: ambil kolom yang EXIST di DB
+// Helpers: kolom opsional di DB
 // ===============================
 
-func firstExistingCol(table string, candidates ...string) string {
-	for _, c := range candidates {
-		c = strings.TrimSpace(c)
-		if c == "" {
-			continue
-		}
-		if hasColumn(intconfig.DB, table, c) {
-			return c
-		}
+// optionalCol mengembalikan col bila ada di table, kosong bila tidak. Alias kolom lama sudah
+// diseragamkan migration 0002, jadi hanya nama kanonik yang dicek.
+func optionalCol(table, col string) string {
+	if hasColumn(intconfig.DB, table, col) {
+		return col
 	}
 	return ""
 }
@@ -70,31 +100,22 @@ func GetRegulerBookingDetail(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "id tidak valid"})
 		return
 	}
-
-	// Booking columns (dinamis, karena nama kolom tiap project beda)
-	colCategory := firstExistingCol("bookings", "category", "service_type", "serviceType", "layanan")
-	colFrom := firstExistingCol("bookings", "route_from", "origin", "from_city", "from", "routeFrom")
-	colTo := firstExistingCol("bookings", "route_to", "destination", "to_city", "to", "routeTo")
-	colTripDate := firstExistingCol("bookings", "trip_date", "departure_date", "departureDate", "booking_date", "tanggal_pemesanan", "created_at")
-	colTripTime := firstExistingCol("bookings", "trip_time", "departure_time", "departureTime")
-	colPassengerName := firstExistingCol("bookings", "passenger_name", "booking_name", "bookingName", "customer_name", "nama_pemesan")
-	colPassengerCount := firstExistingCol("bookings", "passenger_count", "passengerCount", "jumlah_penumpang")
-	colPickup := firstExistingCol("bookings", "pickup_location", "pickup_address", "pickupAddress", "alamat_jemput")
-	colDropoff := firstExistingCol("bookings", "dropoff_location", "dropoff_address", "dropoffAddress", "alamat_turun")
-	colTotal := firstExistingCol("bookings", "total", "total_amount", "grand_total", "totalHarga", "total_harga")
+	if !requireBookingAccess(c, bookingID) {
+		return
+	}
 
 	cols := []string{
 		"id",
-		strExpr(colCategory),
-		strExpr(colFrom),
-		strExpr(colTo),
-		strExpr(colTripDate),
-		strExpr(colTripTime),
-		strExpr(colPassengerName),
-		intExpr(colPassengerCount),
-		strExpr(colPickup),
-		strExpr(colDropoff),
-		intExpr(colTotal),
+		strExpr("category"),
+		strExpr("route_from"),
+		strExpr("route_to"),
+		strExpr("trip_date"),
+		strExpr("trip_time"),
+		strExpr("passenger_name"),
+		intExpr("passenger_count"),
+		strExpr("pickup_location"),
+		strExpr("dropoff_location"),
+		intExpr("total"),
 	}
 
 	hasPayMethod := hasColumn(intconfig.DB, "bookings", "payment_method")
@@ -106,6 +127,7 @@ func GetRegulerBookingDetail(c *gin.Context) {
 	if hasPayStatus {
 		cols = append(cols, "COALESCE(payment_status,'')")
 	}
+	cols = append(cols, "payment_deadline")
 
 	query := "SELECT " + strings.Join(cols, ", ") + " FROM bookings WHERE id = ? LIMIT 1"
 
@@ -122,8 +144,9 @@ func GetRegulerBookingDetail(c *gin.Context) {
 		dropoff        string
 		total          int64
 
-		paymentMethod string
-		paymentStatus string
+		paymentMethod   string
+		paymentStatus   string
+		paymentDeadline sql.NullTime
 	)
 
 	args := []any{
@@ -136,6 +159,7 @@ func GetRegulerBookingDetail(c *gin.Context) {
 	if hasPayStatus {
 		args = append(args, &paymentStatus)
 	}
+	args = append(args, &paymentDeadline)
 
 	if err := intconfig.DB.QueryRow(query, bookingID).Scan(args...); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"message": "booking tidak ditemukan"})
@@ -149,6 +173,38 @@ func GetRegulerBookingDetail(c *gin.Context) {
 		paymentStatus = "Belum Bayar"
 	}
 
+	// batas upload bukti bayar untuk countdown di app; null bila sudah dibayar/DP/tanpa batas
+	var deadline *time.Time
+	switch domain.BookingStatusFromPayment(paymentStatus) {
+	case domain.BookingPaid, domain.BookingPartiallyPaid:
+	default:
+		if paymentDeadline.Valid {
+			deadline = &paymentDeadline.Time
+		}
+	}
+
+	// ringkasan DP/pelunasan dari ledger payments (null sebelum migration)
+	var balance *services.BookingBalance
+	if ledger := paymentLedgerService(c); ledger.Available() {
+		bal, err := ledger.Balance(bookingID)
+		if err != nil {
+			respondBookingChangeError(c, err, "gagal membaca riwayat pembayaran")
+			return
+		}
+		balance = &bal
+	}
+
+	// kode unik transfer: nominal transfer = sisa tagihan + kode (tidak dihitung sebagai pendapatan tarif)
+	var transferCode, transferAmount int64
+	if balance != nil {
+		transferCode, transferAmount = balance.TransferCode, balance.TransferAmount
+	} else if code, err := (repositories.BookingRepository{}).TransferCode(bookingID); err == nil && code > 0 {
+		transferCode = code
+		if domain.BookingStatusFromPayment(paymentStatus) != domain.BookingPaid {
+			transferAmount = total + code
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"id":              id,
 		"category":        category,
@@ -163,6 +219,10 @@ func GetRegulerBookingDetail(c *gin.Context) {
 		"total":           total,
 		"paymentMethod":   paymentMethod,
 		"paymentStatus":   paymentStatus,
+		"paymentDeadline": deadline,
+		"transferCode":    transferCode,
+		"transferAmount":  transferAmount,
+		"balance":         balance,
 	})
 }
 
@@ -177,9 +237,25 @@ func SubmitRegulerPaymentProof(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "id tidak valid"})
 		return
 	}
+	if !requireBookingAccess(c, bookingID) {
+		return
+	}
 
+	// multipart (file langsung) atau JSON lama (base64/data URL); keduanya disimpan ke FileStore
 	var req SubmitPaymentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	multipart := isMultipart(c)
+	if multipart {
+		req.PaymentMethod = c.PostForm("paymentMethod")
+		req.ProofFileName = c.PostForm("proofFileName")
+		if v := strings.TrimSpace(c.PostForm("amount")); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": "amount tidak valid"})
+				return
+			}
+			req.Amount = n
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "payload tidak valid"})
 		return
 	}
@@ -192,13 +268,38 @@ func SubmitRegulerPaymentProof(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "paymentMethod harus 'transfer' atau 'qris'"})
 		return
 	}
-	if req.ProofFile == "" {
+	if !multipart && req.ProofFile == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "proofFile wajib diisi"})
 		return
 	}
 	if req.ProofFileName == "" {
 		req.ProofFileName = "bukti-pembayaran"
 	}
+	if req.Amount < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "amount tidak valid"})
+		return
+	}
+
+	if multipart {
+		stored, ok := saveUploadedFile(c, "proofFile", services.FileKindPaymentProof)
+		if !ok {
+			return
+		}
+		req.ProofFile = stored.Ref
+	} else {
+		ref, ok := storeInlineFile(c, services.FileKindPaymentProof, req.ProofFile)
+		if !ok {
+			return
+		}
+		req.ProofFile = ref
+	}
+	// file yang sudah tersimpan dihapus lagi bila booking gagal diupdate
+	committed := false
+	defer func() {
+		if key, ok := services.FileKeyFromRef(req.ProofFile); ok && !committed {
+			_ = fileService().Store.Delete(key)
+		}
+	}()
 
 	// ✅ Ambil info booking ringkas untuk di-copy ke payment_validations (DINAMIS & error tidak dibuang)
 	var (
@@ -208,30 +309,20 @@ func SubmitRegulerPaymentProof(c *gin.Context) {
 		bookingDate   string
 		routeFrom     string
 		routeTo       string
-		tripRole      string
 	)
 
-	colCustomerName := firstExistingCol("bookings", "passenger_name", "booking_name", "bookingName", "customer_name", "nama_pemesan")
-	colCustomerPhone := firstExistingCol("bookings", "passenger_phone", "phone", "customer_phone", "no_hp", "hp")
-	colPickup := firstExistingCol("bookings", "pickup_location", "pickup_address", "pickupAddress", "alamat_jemput")
-	colBookingDate := firstExistingCol("bookings", "trip_date", "departure_date", "departureDate", "booking_date", "tanggal_pemesanan", "created_at")
-	colFrom := firstExistingCol("bookings", "route_from", "origin", "from", "routeFrom")
-	colTo := firstExistingCol("bookings", "route_to", "destination", "to", "routeTo")
-	colTripRole := firstExistingCol("bookings", "trip_role", "role_trip", "tripRole")
-
 	readSQL := fmt.Sprintf(
-		`SELECT %s,%s,%s,%s,%s,%s,%s FROM bookings WHERE id=? LIMIT 1`,
-		strExpr(colCustomerName),
-		strExpr(colCustomerPhone),
-		strExpr(colPickup),
-		strExpr(colBookingDate),
-		strExpr(colFrom),
-		strExpr(colTo),
-		strExpr(colTripRole),
+		`SELECT %s,%s,%s,%s,%s,%s FROM bookings WHERE id=? LIMIT 1`,
+		strExpr("passenger_name"),
+		strExpr(optionalCol("bookings", "passenger_phone")),
+		strExpr("pickup_location"),
+		strExpr("trip_date"),
+		strExpr("route_from"),
+		strExpr("route_to"),
 	)
 
 	if err := intconfig.DB.QueryRow(readSQL, bookingID).Scan(
-		&customerName, &customerPhone, &pickup, &bookingDate, &routeFrom, &routeTo, &tripRole,
+		&customerName, &customerPhone, &pickup, &bookingDate, &routeFrom, &routeTo,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{"message": "booking tidak ditemukan untuk submit pembayaran"})
@@ -241,9 +332,6 @@ func SubmitRegulerPaymentProof(c *gin.Context) {
 		return
 	}
 
-	// ✅ trip_role harus kosong saat Menunggu Validasi (nunggu admin edit manual)
-	tripRole = ""
-
 	tx, err := intconfig.DB.Begin()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal mulai transaksi"})
@@ -286,6 +374,7 @@ func SubmitRegulerPaymentProof(c *gin.Context) {
 		args = append(args, routeTo)
 	}
 
+	// ✅ trip_role harus kosong saat Menunggu Validasi (nunggu admin edit manual)
 	if hasColumn(tx, "payment_validations", "trip_role") {
 		cols = append(cols, "trip_role")
 		vals = append(vals, "?")
@@ -313,7 +402,44 @@ func SubmitRegulerPaymentProof(c *gin.Context) {
 
 	validationID, _ := res.LastInsertId()
 
+	// nominal bukti dicatat pending di ledger; lunas/DP ditentukan saat admin memvalidasi
+	var balance *services.BookingBalance
+	if ledger := paymentLedgerService(c); ledger.Available() {
+		bal, err := ledger.BalanceTx(tx, bookingID)
+		if err != nil {
+			respondBookingChangeError(c, err, "gagal membaca riwayat pembayaran")
+			return
+		}
+		amount := req.Amount
+		if amount == 0 {
+			amount = bal.Outstanding
+		}
+		if req.PaymentMethod == "transfer" {
+			// kode unik bukan bagian tagihan, yang dicatat hanya porsi tarifnya
+			amount = bal.TransferPortion(amount)
+		}
+		if err := bal.CheckAmount(amount); err != nil {
+			respondBookingChangeError(c, err, "gagal mencatat pembayaran")
+			return
+		}
+		bal, err = ledger.RecordTx(tx, repositories.LedgerPayment{
+			BookingID:    bookingID,
+			Amount:       amount,
+			Method:       req.PaymentMethod,
+			Status:       repositories.LedgerPending,
+			ValidationID: validationID,
+		})
+		if err != nil {
+			respondBookingChangeError(c, err, "gagal mencatat pembayaran")
+			return
+		}
+		balance = &bal
+	}
+
 	// 2) update bookings => menunggu validasi + link validation id (jika kolom ada)
+	if !transitionBookingTx(c, tx, bookingID, domain.BookingAwaitingValidation, "bukti pembayaran "+req.PaymentMethod) {
+		return
+	}
 	updates := []string{}
 	uargs := []any{}
 
@@ -321,10 +447,6 @@ func SubmitRegulerPaymentProof(c *gin.Context) {
 		updates = append(updates, "payment_method = ?")
 		uargs = append(uargs, req.PaymentMethod)
 	}
-	if hasColumn(tx, "bookings", "payment_status") {
-		updates = append(updates, "payment_status = ?")
-		uargs = append(uargs, "Menunggu Validasi")
-	}
 	if hasColumn(tx, "bookings", "payment_validation_id") {
 		updates = append(updates, "payment_validation_id = ?")
 		uargs = append(uargs, validationID)
@@ -351,18 +473,22 @@ func SubmitRegulerPaymentProof(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal commit transaksi: " + err.Error()})
 		return
 	}
+	committed = true
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "Bukti pembayaran terkirim. Menunggu validasi admin.",
 		"bookingId":     bookingID,
 		"validationId":  validationID,
 		"paymentStatus": "Menunggu Validasi",
+		"proofFileUrl":  fileURL(req.ProofFile),
+		"balance":       balance,
 	})
 }
 
 // ===============================
 // POST /api/reguler/bookings/:id/confirm-cash
-// Cash: langsung Lunas => trigger SyncConfirmedRegulerBookingTx(tx, bookingID)
+// Cash: body opsional {"amount"} (default sisa tagihan) dicatat di ledger payments.
+// Lunas => trigger SyncConfirmedRegulerBookingTx(tx, bookingID); masih ada sisa => DP.
 // ===============================
 
 func ConfirmRegulerCash(c *gin.Context) {
@@ -371,6 +497,27 @@ func ConfirmRegulerCash(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "id tidak valid"})
 		return
 	}
+	// hanya admin yang menerima uang tunai boleh mencatatnya (route juga dibatasi adminOnly)
+	rc, ok := requestUser(c)
+	if !ok {
+		return
+	}
+	if rc.Role != domain.RoleAdmin {
+		respondError(c, http.StatusForbidden, "forbidden", "konfirmasi cash hanya untuk admin", nil)
+		return
+	}
+
+	var req ConfirmCashRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "payload tidak valid"})
+			return
+		}
+	}
+	if req.Amount < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "amount tidak valid"})
+		return
+	}
 
 	if err := intconfig.DB.Ping(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "DB ping gagal: " + err.Error()})
@@ -384,6 +531,42 @@ func ConfirmRegulerCash(c *gin.Context) {
 	}
 	defer func() { _ = tx.Rollback() }()
 
+	target := domain.BookingPaid
+	var balance *services.BookingBalance
+	if ledger := paymentLedgerService(c); ledger.Available() {
+		bal, err := ledger.BalanceTx(tx, bookingID)
+		if err != nil {
+			respondBookingChangeError(c, err, "gagal membaca riwayat pembayaran")
+			return
+		}
+		amount := req.Amount
+		if amount == 0 {
+			amount = bal.Outstanding
+		}
+		if err := bal.CheckAmount(amount); err != nil {
+			respondBookingChangeError(c, err, "gagal mencatat pembayaran")
+			return
+		}
+		bal, err = ledger.RecordTx(tx, repositories.LedgerPayment{
+			BookingID:  bookingID,
+			Amount:     amount,
+			Method:     "cash",
+			Status:     repositories.LedgerApproved,
+			ReceivedBy: statusActor(c).UserID,
+			Note:       req.Note,
+		})
+		if err != nil {
+			respondBookingChangeError(c, err, "gagal mencatat pembayaran")
+			return
+		}
+		balance = &bal
+		target = bal.Status()
+	}
+
+	if !transitionBookingTx(c, tx, bookingID, target, "konfirmasi cash") {
+		return
+	}
+
 	updates := []string{}
 	args := []any{}
 
@@ -391,10 +574,6 @@ func ConfirmRegulerCash(c *gin.Context) {
 		updates = append(updates, "payment_method = ?")
 		args = append(args, "cash")
 	}
-	if hasColumn(tx, "bookings", "payment_status") {
-		updates = append(updates, "payment_status = ?")
-		args = append(args, "Lunas")
-	}
 	if hasColumn(tx, "bookings", "updated_at") {
 		updates = append(updates, "updated_at = ?")
 		args = append(args, time.Now())
@@ -408,42 +587,53 @@ func ConfirmRegulerCash(c *gin.Context) {
 		}
 	}
 
-	// Trigger auto-sync modul-modul setelah Lunas
-	tripRole := ""
-	if hasColumn(tx, "bookings", "trip_role") {
-		_ = tx.QueryRow(`SELECT COALESCE(trip_role,'') FROM bookings WHERE id=? LIMIT 1`, bookingID).Scan(&tripRole)
+	// booking lunas: event sinkronisasi booking_paid dicatat di transaksi yang sama dengan
+	// pembayaran cash, lalu dikerjakan worker outbox
+	queued := false
+	if target == domain.BookingPaid {
+		tripRole := ""
+		if hasColumn(tx, "bookings", "trip_role") {
+			_ = tx.QueryRow(`SELECT COALESCE(trip_role,'') FROM bookings WHERE id=? LIMIT 1`, bookingID).Scan(&tripRole)
+		}
+		var raw json.RawMessage
+		if strings.TrimSpace(tripRole) != "" {
+			if b, err := json.Marshal(map[string]string{"trip_role": tripRole}); err == nil {
+				raw = b
+			}
+		}
+		if queued, err = paymentService(c).MarkPaidTx(tx, bookingID, raw); err != nil {
+			respondBookingChangeError(c, err, "gagal menandai booking lunas")
+			return
+		}
 	}
 	if err := tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal commit transaksi"})
 		return
 	}
 
-	reqID := middleware.GetRequestID(c)
-	svc := services.PaymentService{
-		PaymentRepo:     repositories.PaymentRepository{},
-		BookingRepo:     repositories.BookingRepository{},
-		BookingSeatRepo: repositories.BookingSeatRepository{},
-		RequestID:       reqID,
-		DepartureSvc:    services.DepartureService{Repo: repositories.DepartureRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}, RequestID: reqID},
-		ReturnSvc:       services.ReturnService{Repo: repositories.ReturnRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}, RequestID: reqID},
-		PassengerSvc:    services.PassengerService{PassengerRepo: repositories.PassengerRepository{}, BookingRepo: repositories.BookingRepository{}, BookingSeatRepo: repositories.BookingSeatRepository{}},
+	if target != domain.BookingPaid {
+		c.JSON(http.StatusOK, gin.H{
+			"message":       "Pembayaran cash dicatat sebagai DP. Sisa tagihan dibayar saat pelunasan.",
+			"bookingId":     bookingID,
+			"paymentStatus": target.PaymentLabel(),
+			"balance":       balance,
+		})
+		return
 	}
 
-	var raw json.RawMessage
-	if strings.TrimSpace(tripRole) != "" {
-		if b, err := json.Marshal(map[string]string{"trip_role": tripRole}); err == nil {
-			raw = b
+	// tanpa tabel outbox (migration belum dijalankan) sinkron langsung seperti sebelumnya
+	if !queued {
+		if err := paymentService(c).SyncSettings(bookingID, ""); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal sync data perjalanan: " + err.Error()})
+			return
 		}
 	}
-	if err := svc.ValidatePayment(bookingID, raw); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "gagal sync data perjalanan: " + err.Error()})
-		return
-	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "Pembayaran cash dikonfirmasi. E-ticket & invoice siap ditampilkan.",
 		"bookingId":     bookingID,
 		"paymentStatus": "Lunas",
+		"balance":       balance,
 	})
 }
 
@@ -93,7 +93,7 @@ func ReportVehicle(c *gin.Context) {
 		}
 	}
 
-	rows, qerr := intconfig.DB.Query(`
+	rows, qerr := intconfig.ReadOnlyDB().Query(`
 		SELECT
 			month,
 			dept_category, dept_passenger_fare, dept_package_fare, dept_admin_percent_override,
@@ -174,7 +174,7 @@ func ReportVehicle(c *gin.Context) {
 		return
 	}
 
-	crows, qerr2 := intconfig.DB.Query(`
+	crows, qerr2 := intconfig.ReadOnlyDB().Query(`
 		SELECT
 			month,
 			COALESCE(driver_name, ''),
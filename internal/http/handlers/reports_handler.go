@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	intconfig "backend/internal/config"
 	"backend/internal/repositories"
 	"backend/internal/services"
 
@@ -17,7 +18,7 @@ func GetFinanceReport(c *gin.Context) {
 	end := strings.TrimSpace(c.Query("end_date"))
 
 	svc := services.ReportsService{
-		TripsRepo: repositories.TripsRepository{},
+		TripsRepo: repositories.TripsRepository{DB: intconfig.ReadOnlyDB()},
 	}
 	report, err := svc.GetFinanceReport(services.FinanceReportFilter{
 		TripRole:  role,
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/http/middleware"
+	"backend/internal/repositories"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func rescheduleService(c *gin.Context) services.RescheduleService {
+	return services.RescheduleService{
+		Holds:     seatHoldService(c),
+		Schedules: scheduleService(c),
+		Actor:     statusActor(c),
+		RequestID: middleware.GetRequestID(c),
+	}
+}
+
+type RescheduleBookingRequest struct {
+	From          string   `json:"from"` // kosong = rute booking saat ini
+	To            string   `json:"to"`
+	Date          string   `json:"date"`
+	Time          string   `json:"time"`
+	SlotID        int64    `json:"slotId,omitempty"`
+	SelectedSeats []string `json:"selectedSeats"`
+	HoldToken     string   `json:"holdToken,omitempty"`
+	Reason        string   `json:"reason"`
+}
+
+// ======================================================
+// POST /api/reguler/bookings/:id/reschedule
+// ======================================================
+func RescheduleRegulerBooking(c *gin.Context) {
+	bookingID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || bookingID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "id tidak valid"})
+		return
+	}
+	if !requireBookingAccess(c, bookingID) {
+		return
+	}
+
+	var req RescheduleBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "JSON tidak valid"})
+		return
+	}
+
+	booking, err := repositories.BookingRepository{}.GetByID(bookingID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "booking tidak ditemukan"})
+		return
+	}
+	category := strings.TrimSpace(booking.Category)
+	if category == "" {
+		category = "Reguler"
+	}
+	from := strings.TrimSpace(req.From)
+	if from == "" {
+		from = booking.RouteFrom
+	}
+	to := strings.TrimSpace(req.To)
+	if to == "" {
+		to = booking.RouteTo
+	}
+	date := strings.TrimSpace(req.Date)
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Format date tidak valid (YYYY-MM-DD)"})
+		return
+	}
+	hhmm, err := normalizeTimeStr(req.Time)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	seats := normalizeSeats(req.SelectedSeats)
+	if len(seats) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "selectedSeats wajib"})
+		return
+	}
+	if hasDuplicates(seats) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Seat tidak boleh duplikat"})
+		return
+	}
+
+	// tarif dihitung ulang dengan aturan yang sama seperti GetRegulerQuote
+	fare, err := fareService(c).Quote(from, to, category, date)
+	if err != nil {
+		respondRegulerError(c, err)
+		return
+	}
+	if int64(len(seats))*fare.PricePerSeat > RegulerMaxTotal {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Total melebihi batas maksimal (900.000)"})
+		return
+	}
+
+	slot, ok := withSlotLegs(c, repositories.SeatSlot{From: fare.From.DisplayName, To: fare.To.DisplayName, Date: date, Time: hhmm})
+	if !ok {
+		return
+	}
+	tripSlot, ok := resolveTripSlot(c, &slot, req.SlotID)
+	if !ok || !validateSlotSeats(c, slot, seats) {
+		return
+	}
+
+	var userID int64
+	if rc, ok := middleware.GetRequestContext(c); ok {
+		userID = int64(rc.UserID)
+	}
+
+	rs, err := rescheduleService(c).Reschedule(services.RescheduleInput{
+		BookingID:    bookingID,
+		UserID:       userID,
+		Slot:         slot,
+		TripSlot:     tripSlot,
+		Seats:        seats,
+		PricePerSeat: fare.PricePerSeat,
+		HoldToken:    strings.TrimSpace(req.HoldToken),
+		Reason:       req.Reason,
+	})
+	if err != nil {
+		respondBookingChangeError(c, err, "Gagal reschedule booking")
+		return
+	}
+
+	resp := gin.H{
+		"message":    "Booking berhasil di-reschedule",
+		"reschedule": rs,
+	}
+	// saldo terbaru: tarif naik pada booking lunas menyisakan tagihan (status DP)
+	if ledger := paymentLedgerService(c); ledger.Available() {
+		if bal, err := ledger.Balance(bookingID); err == nil {
+			resp["balance"] = bal
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ===== admin penyesuaian tarif reschedule (/api/admin/reschedules) =====
+
+// GET /api/admin/reschedules?status=pending
+func AdminListReschedules(c *gin.Context) {
+	list, err := rescheduleService(c).List(c.Query("status"))
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reschedules": list})
+}
+
+type rescheduleSettleRequest struct {
+	Status string `json:"status"`
+	Method string `json:"method"` // metode bayar tambahan / refund kredit; kosong = default
+	Note   string `json:"note"`
+}
+
+// PUT /api/admin/reschedules/:id
+func AdminSettleReschedule(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req rescheduleSettleRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	rs, err := rescheduleService(c).SettleAdjustment(id, req.Status, req.Method, req.Note)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rs)
+}
@@ -11,6 +11,7 @@ import (
 
 	intconfig "backend/internal/config"
 	intdb "backend/internal/db"
+	"backend/internal/domain"
 	"backend/internal/http/middleware"
 	"backend/internal/repositories"
 	"backend/internal/services"
@@ -56,7 +57,7 @@ func GetReturnSettings(c *gin.Context) {
 		vehicleTypeSel = "COALESCE(vehicle_type,'')"
 	}
 
-	rows, err := intconfig.DB.Query(fmt.Sprintf(`
+	rows, err := intconfig.ReadOnlyDB().Query(fmt.Sprintf(`
 		SELECT
 			id,
 			COALESCE(booking_name,''),
@@ -256,6 +257,13 @@ func CreateReturnSetting(c *gin.Context) {
 		input.VehicleType = lookupDriverVehicleType(input.DriverName)
 	}
 
+	suratJalan, err := fileService().StoreInline(services.FileKindSuratJalan, input.SuratJalanFile)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	input.SuratJalanFile = suratJalan
+
 	count, _ := strconv.Atoi(input.PassengerCount)
 
 	cols := []string{}
@@ -350,13 +358,24 @@ func UpdateReturnSetting(c *gin.Context) {
 		return
 	}
 
+	if raw, err = storeInlineFilesInJSON(raw, services.FileKindSuratJalan, "suratJalanFile", "surat_jalan_file"); err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+
 	svc := services.ReturnService{
 		Repo:        repositories.ReturnRepository{DB: intconfig.DB},
 		BookingRepo: repositories.BookingRepository{DB: intconfig.DB},
 		SeatRepo:    repositories.BookingSeatRepository{DB: intconfig.DB},
+		Guard:       assignmentGuard(c),
 		RequestID:   middleware.GetRequestID(c),
+		Actor:       statusActor(c),
 	}
 	ret, err := svc.MarkPulang(id, raw)
+	if domain.IsConflict(err) {
+		RespondDomainError(c, err)
+		return
+	}
 	if err != nil {
 		RespondError(c, http.StatusInternalServerError, "gagal memperbarui kepulangan", err)
 		return
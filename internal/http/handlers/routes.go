@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/internal/http/middleware"
+	"backend/internal/repositories"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func routeService(c *gin.Context) services.RouteService {
+	return services.RouteService{Fares: fareService(c), RequestID: middleware.GetRequestID(c)}
+}
+
+// withSlotLegs melengkapi slot dengan segmen overlap di jalurnya supaya seat yang terpakai
+// di segmen lain ikut dihitung. Menulis response 500 ({"message": ...}) bila gagal.
+func withSlotLegs(c *gin.Context, slot repositories.SeatSlot) (repositories.SeatSlot, bool) {
+	slot, err := routeService(c).SlotLegs(slot)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal memuat jalur rute"})
+		return slot, false
+	}
+	return slot, true
+}
+
+// ===== admin jalur reguler (/api/admin/routes) =====
+
+type routeRequest struct {
+	Code        string   `json:"code"`
+	Name        string   `json:"name"`
+	Stops       []string `json:"stops"`
+	StopMinutes []int    `json:"stopMinutes"`
+	IsActive    *bool    `json:"isActive"`
+}
+
+func (r routeRequest) toRoute() repositories.Route {
+	return repositories.Route{
+		Code:        r.Code,
+		Name:        r.Name,
+		Stops:       r.Stops,
+		StopMinutes: r.StopMinutes,
+		IsActive:    r.IsActive == nil || *r.IsActive,
+	}
+}
+
+func AdminListRoutes(c *gin.Context) {
+	list, err := routeService(c).List()
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"routes": list})
+}
+
+func AdminGetRoute(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	rt, err := routeService(c).Get(id)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rt)
+}
+
+func AdminCreateRoute(c *gin.Context) {
+	var req routeRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	rt, err := routeService(c).Create(req.toRoute())
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, rt)
+}
+
+func AdminUpdateRoute(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req routeRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	rt, err := routeService(c).Update(id, req.toRoute())
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rt)
+}
+
+func AdminDeleteRoute(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	if err := routeService(c).Delete(id); err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/http/middleware"
+	"backend/internal/repositories"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func scheduleService(c *gin.Context) services.ScheduleService {
+	return services.ScheduleService{
+		Routes:    routeService(c),
+		Holds:     seatHoldService(c),
+		RequestID: middleware.GetRequestID(c),
+	}
+}
+
+// resolveTripSlot memastikan slot reguler menunjuk trip slot terjadwal yang masih open.
+// ts.ID == 0 berarti perjalanan belum terjadwal (date/time bebas seperti sebelumnya).
+// Tipe kendaraan slot dipakai untuk memilih denah kursi.
+func resolveTripSlot(c *gin.Context, slot *repositories.SeatSlot, slotID int64) (repositories.TripSlot, bool) {
+	ts, scheduled, err := scheduleService(c).ResolveSlot(*slot, slotID)
+	if err != nil {
+		respondRegulerError(c, err)
+		return ts, false
+	}
+	if scheduled {
+		slot.VehicleType = ts.VehicleType
+	}
+	return ts, true
+}
+
+// checkSlotCapacity menulis 409 ({"message": ...}) bila seat yang diminta melebihi sisa kapasitas.
+func checkSlotCapacity(c *gin.Context, ts repositories.TripSlot, state services.SlotSeatState, requested int) bool {
+	if ts.ID == 0 {
+		return true
+	}
+	if err := services.CheckCapacity(ts, state, requested); err != nil {
+		var ce domain.ConflictError
+		if errors.As(err, &ce) {
+			c.JSON(http.StatusConflict, gin.H{"message": ce.Msg})
+			return false
+		}
+		RespondDomainError(c, err)
+		return false
+	}
+	return true
+}
+
+// ======================================================
+// GET /api/reguler/slots?from=&to=&date=
+// ======================================================
+func GetRegulerSlots(c *gin.Context) {
+	from, to := strings.TrimSpace(c.Query("from")), strings.TrimSpace(c.Query("to"))
+	date := strings.TrimSpace(c.Query("date"))
+	if from == "" || to == "" || date == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "from, to, date wajib"})
+		return
+	}
+	fares := fareService(c)
+	fromStop, err := fares.ResolveStop(from)
+	if err != nil {
+		respondRegulerError(c, domain.ValidationError{Field: "from", Msg: "Origin tidak didukung", Err: err})
+		return
+	}
+	toStop, err := fares.ResolveStop(to)
+	if err != nil {
+		respondRegulerError(c, domain.ValidationError{Field: "to", Msg: "Destination tidak didukung", Err: err})
+		return
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Format date tidak valid (YYYY-MM-DD)"})
+		return
+	}
+
+	slot, ok := withSlotLegs(c, repositories.SeatSlot{From: fromStop.DisplayName, To: toStop.DisplayName, Date: date})
+	if !ok {
+		return
+	}
+	slots, scheduled, err := scheduleService(c).AvailableSlots(intconfig.DB, slot)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal memuat jadwal keberangkatan"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"scheduled": scheduled, "slots": slots})
+}
+
+// ===== admin jadwal (/api/admin/schedules, /blackouts, /trip-slots) =====
+
+type scheduleRequest struct {
+	RouteID       int64  `json:"routeId"`
+	Direction     string `json:"direction"`
+	DaysOfWeek    []int  `json:"daysOfWeek"`
+	DepartureTime string `json:"departureTime"`
+	VehicleType   string `json:"vehicleType"`
+	Capacity      int    `json:"capacity"`
+	ValidFrom     string `json:"validFrom"`
+	ValidTo       string `json:"validTo"`
+	IsActive      *bool  `json:"isActive"`
+}
+
+func (r scheduleRequest) toSchedule() repositories.Schedule {
+	return repositories.Schedule{
+		RouteID:       r.RouteID,
+		Direction:     r.Direction,
+		DaysOfWeek:    r.DaysOfWeek,
+		DepartureTime: r.DepartureTime,
+		VehicleType:   r.VehicleType,
+		Capacity:      r.Capacity,
+		ValidFrom:     r.ValidFrom,
+		ValidTo:       r.ValidTo,
+		IsActive:      r.IsActive == nil || *r.IsActive,
+	}
+}
+
+func AdminListSchedules(c *gin.Context) {
+	list, err := scheduleService(c).ListSchedules()
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": list})
+}
+
+func AdminGetSchedule(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	sc, err := scheduleService(c).GetSchedule(id)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sc)
+}
+
+func AdminCreateSchedule(c *gin.Context) {
+	var req scheduleRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	sc, err := scheduleService(c).CreateSchedule(req.toSchedule())
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, sc)
+}
+
+func AdminUpdateSchedule(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req scheduleRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	sc, err := scheduleService(c).UpdateSchedule(id, req.toSchedule())
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sc)
+}
+
+func AdminDeleteSchedule(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	if err := scheduleService(c).DeleteSchedule(id); err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+type generateSlotsRequest struct {
+	From string `json:"from"` // YYYY-MM-DD, default hari ini
+	Days int    `json:"days"`
+}
+
+// POST /api/admin/schedules/generate
+func AdminGenerateTripSlots(c *gin.Context) {
+	var req generateSlotsRequest
+	if c.Request.ContentLength > 0 && !BindJSONOrError(c, &req) {
+		return
+	}
+	from := time.Now()
+	if strings.TrimSpace(req.From) != "" {
+		d, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(req.From), time.Local)
+		if err != nil {
+			RespondDomainError(c, domain.ValidationError{Field: "from", Msg: "format YYYY-MM-DD"})
+			return
+		}
+		from = d
+	}
+	if req.Days > 366 {
+		RespondDomainError(c, domain.ValidationError{Field: "days", Msg: "maksimal 366"})
+		return
+	}
+	created, err := scheduleService(c).Generate(from, req.Days)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"created": created})
+}
+
+type blackoutRequest struct {
+	Date    string `json:"date"`
+	RouteID int64  `json:"routeId"`
+	Reason  string `json:"reason"`
+}
+
+func AdminListBlackouts(c *gin.Context) {
+	list, err := scheduleService(c).ListBlackouts(c.Query("from"))
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"blackouts": list})
+}
+
+func AdminCreateBlackout(c *gin.Context) {
+	var req blackoutRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	b, err := scheduleService(c).CreateBlackout(repositories.Blackout{Date: req.Date, RouteID: req.RouteID, Reason: req.Reason})
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, b)
+}
+
+func AdminDeleteBlackout(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	if err := scheduleService(c).DeleteBlackout(id); err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// GET /api/admin/trip-slots?date=YYYY-MM-DD
+func AdminListTripSlots(c *gin.Context) {
+	list, err := scheduleService(c).ListSlots(c.Query("date"))
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"slots": list})
+}
+
+type tripSlotRequest struct {
+	Status      string  `json:"status"`
+	Capacity    int     `json:"capacity"`
+	VehicleType *string `json:"vehicleType"`
+}
+
+// PUT /api/admin/trip-slots/:id
+func AdminUpdateTripSlot(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req tripSlotRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	ts, err := scheduleService(c).UpdateSlot(id, req.Status, req.Capacity, req.VehicleType)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ts)
+}
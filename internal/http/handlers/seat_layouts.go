@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend/internal/http/middleware"
+	"backend/internal/repositories"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func seatLayoutService(c *gin.Context) services.SeatLayoutService {
+	return services.SeatLayoutService{RequestID: middleware.GetRequestID(c)}
+}
+
+// validateSlotSeats menolak seat yang tidak ada di denah kendaraan slot.
+// Menulis response ({"message": ...}) dan mengembalikan false bila tidak valid.
+func validateSlotSeats(c *gin.Context, slot repositories.SeatSlot, seats []string) bool {
+	layout, ok, err := seatLayoutService(c).LayoutForSlot(slot)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Gagal memuat denah kursi"})
+		return false
+	}
+	if !ok {
+		// belum ada denah (migration belum jalan): perilaku lama, seat bebas
+		return true
+	}
+	if err := services.ValidateSeatCodes(layout, seats); err != nil {
+		respondRegulerError(c, err)
+		return false
+	}
+	return true
+}
+
+// ===== admin denah kursi (/api/admin/seat-layouts) =====
+
+type seatLayoutRequest struct {
+	VehicleType   string                  `json:"vehicleType"`
+	Name          string                  `json:"name"`
+	Rows          int                     `json:"rows"`
+	Cols          int                     `json:"cols"`
+	Seats         []repositories.SeatCell `json:"seats"`
+	DriverRow     int                     `json:"driverRow"`
+	DriverCol     int                     `json:"driverCol"`
+	DisabledSeats []string                `json:"disabledSeats"`
+	Capacity      int                     `json:"capacity"`
+	IsDefault     bool                    `json:"isDefault"`
+}
+
+func (r seatLayoutRequest) toLayout() repositories.SeatLayout {
+	return repositories.SeatLayout{
+		VehicleType:   r.VehicleType,
+		Name:          r.Name,
+		Rows:          r.Rows,
+		Cols:          r.Cols,
+		Seats:         r.Seats,
+		DriverRow:     r.DriverRow,
+		DriverCol:     r.DriverCol,
+		DisabledSeats: r.DisabledSeats,
+		Capacity:      r.Capacity,
+		IsDefault:     r.IsDefault,
+	}
+}
+
+func AdminListSeatLayouts(c *gin.Context) {
+	list, err := seatLayoutService(c).List()
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"layouts": list})
+}
+
+func AdminGetSeatLayout(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	layout, err := seatLayoutService(c).Get(id)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, layout)
+}
+
+func AdminCreateSeatLayout(c *gin.Context) {
+	var req seatLayoutRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	layout, err := seatLayoutService(c).Create(req.toLayout())
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, layout)
+}
+
+func AdminUpdateSeatLayout(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req seatLayoutRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	layout, err := seatLayoutService(c).Update(id, req.toLayout())
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, layout)
+}
+
+func AdminDeleteSeatLayout(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	if err := seatLayoutService(c).Delete(id); err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
 
 	"github.com/gin-gonic/gin"
 )
@@ -59,3 +60,22 @@ func Routes(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"routes": out})
 }
+
+// GET /api/admin/schema-cache
+func GetSchemaCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, intdb.Schema.Stats())
+}
+
+// POST /api/admin/schema-cache/refresh
+// Dipakai setelah migrasi/ALTER TABLE manual supaya perubahan skema langsung terbaca.
+func RefreshSchemaCache(c *gin.Context) {
+	if intconfig.DB == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database belum terhubung"})
+		return
+	}
+	if err := intdb.Schema.Refresh(intconfig.DB); err != nil {
+		RespondError(c, http.StatusInternalServerError, "gagal memuat ulang skema", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "schema cache diperbarui", "stats": intdb.Schema.Stats()})
+}
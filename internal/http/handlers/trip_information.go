@@ -13,19 +13,21 @@ import (
 
 	intconfig "backend/internal/config"
 	intdb "backend/internal/db"
+	"backend/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type TripInformation struct {
-	ID            uint   `json:"id"`
-	TripNumber    string `json:"tripNumber"`
-	DepartureDate string `json:"departureDate"`
-	DepartureTime string `json:"departureTime"`
-	DriverName    string `json:"driverName"`
-	VehicleCode   string `json:"vehicleCode"`
-	LicensePlate  string `json:"licensePlate"`
-	ESuratJalan   string `json:"eSuratJalan"`
+	ID             uint   `json:"id"`
+	TripNumber     string `json:"tripNumber"`
+	DepartureDate  string `json:"departureDate"`
+	DepartureTime  string `json:"departureTime"`
+	DriverName     string `json:"driverName"`
+	VehicleCode    string `json:"vehicleCode"`
+	LicensePlate   string `json:"licensePlate"`
+	ESuratJalan    string `json:"eSuratJalan"`
+	ESuratJalanURL string `json:"eSuratJalanUrl,omitempty"`
 }
 
 type TripInformationList = []TripInformation
@@ -91,7 +93,7 @@ func GetTripInformation(c *gin.Context) {
 		ORDER BY ti.departure_date DESC, ti.id DESC
 	`
 
-	rows, err := intconfig.DB.Query(query)
+	rows, err := intconfig.ReadOnlyDB().Query(query)
 	if err != nil {
 		log.Printf("GetTripInformation - query error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -150,6 +152,7 @@ func GetTripInformation(c *gin.Context) {
 		}
 		if eSurat.Valid {
 			t.ESuratJalan = eSurat.String
+			t.ESuratJalanURL = fileURL(eSurat.String)
 		}
 
 		if strings.TrimSpace(t.DriverName) == "" || strings.TrimSpace(t.VehicleCode) == "" {
@@ -216,16 +219,12 @@ func findDepartureDriverVehicleBySchedule(dateStr, timeStr, from, to string) (st
 	driverSel := "''"
 	if intdb.HasColumn(intconfig.DB, "departure_settings", "driver_name") {
 		driverSel = "COALESCE(driver_name,'')"
-	} else if intdb.HasColumn(intconfig.DB, "departure_settings", "driver") {
-		driverSel = "COALESCE(driver,'')"
 	}
 
 	vehicleSel := "''"
 	switch {
 	case intdb.HasColumn(intconfig.DB, "departure_settings", "vehicle_code"):
 		vehicleSel = "COALESCE(vehicle_code,'')"
-	case intdb.HasColumn(intconfig.DB, "departure_settings", "car_code"):
-		vehicleSel = "COALESCE(car_code,'')"
 	case intdb.HasColumn(intconfig.DB, "departure_settings", "vehicle_type"):
 		vehicleSel = "COALESCE(vehicle_type,'')"
 	case intdb.HasColumn(intconfig.DB, "departure_settings", "vehicle_name"):
@@ -272,6 +271,13 @@ func CreateTripInformation(c *gin.Context) {
 	input.DepartureDate = normalizeDateOnly(input.DepartureDate)
 	input.DepartureTime = normalizeTripTime(input.DepartureTime)
 
+	eSurat, err := fileService().StoreInline(services.FileKindSuratJalan, input.ESuratJalan)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	input.ESuratJalan = eSurat
+
 	tx, err := intconfig.DB.Begin()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal mulai transaksi: " + err.Error()})
@@ -415,6 +421,13 @@ func UpdateTripInformation(c *gin.Context) {
 	input.DepartureDate = normalizeDateOnly(input.DepartureDate)
 	input.DepartureTime = normalizeTripTime(input.DepartureTime)
 
+	eSurat, err := fileService().StoreInline(services.FileKindSuratJalan, input.ESuratJalan)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	input.ESuratJalan = eSurat
+
 	sets := []string{}
 	args := []any{}
 
@@ -531,6 +544,26 @@ func GetTripSuratJalan(c *gin.Context) {
 		return
 	}
 
+	// file di FileStore: redirect ke signed URL bila klien minta file, selain itu kirim URL-nya
+	if key, ok := services.FileKeyFromRef(s); ok {
+		u := fileURL(s)
+		if u == "" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "penyimpanan file belum dikonfigurasi"})
+			return
+		}
+		accept := strings.ToLower(c.GetHeader("Accept"))
+		if strings.Contains(accept, "image/") || strings.Contains(accept, "application/pdf") {
+			c.Redirect(http.StatusFound, u)
+			return
+		}
+		kind := "file"
+		if !strings.HasSuffix(key, ".pdf") {
+			kind = "image"
+		}
+		c.JSON(http.StatusOK, gin.H{"__type": kind, "src": u})
+		return
+	}
+
 	accept := strings.ToLower(strings.TrimSpace(c.GetHeader("Accept")))
 
 	if strings.HasPrefix(strings.ToLower(s), "data:") {
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	intconfig "backend/internal/config"
+	"backend/internal/http/middleware"
+	"backend/internal/repositories"
+	"backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func tripRunService(c *gin.Context) services.TripRunService {
+	reqID := middleware.GetRequestID(c)
+	actor := statusActor(c)
+	guard := assignmentGuard(c)
+	return services.TripRunService{
+		Departures: services.DepartureService{
+			BookingRepo: repositories.BookingRepository{},
+			SeatRepo:    repositories.BookingSeatRepository{},
+			Guard:       guard,
+			RequestID:   reqID,
+			Actor:       actor,
+		},
+		Returns: services.ReturnService{
+			Repo:        repositories.ReturnRepository{DB: intconfig.DB},
+			BookingRepo: repositories.BookingRepository{DB: intconfig.DB},
+			SeatRepo:    repositories.BookingSeatRepository{DB: intconfig.DB},
+			Guard:       guard,
+			RequestID:   reqID,
+			Actor:       actor,
+		},
+		RequestID: reqID,
+	}
+}
+
+type tripRunRequest struct {
+	TripSlotID  int64   `json:"tripSlotId"`
+	RouteFrom   string  `json:"routeFrom"`
+	RouteTo     string  `json:"routeTo"`
+	TripDate    string  `json:"tripDate"`
+	TripTime    string  `json:"tripTime"`
+	TripNumber  string  `json:"tripNumber"`
+	DriverName  string  `json:"driverName"`
+	VehicleCode string  `json:"vehicleCode"`
+	VehicleType string  `json:"vehicleType"`
+	Note        string  `json:"note"`
+	BookingIDs  []int64 `json:"bookingIds"`
+}
+
+type tripRunAssignmentRequest struct {
+	TripDate    *string `json:"tripDate"`
+	TripTime    *string `json:"tripTime"`
+	TripNumber  *string `json:"tripNumber"`
+	DriverName  *string `json:"driverName"`
+	VehicleCode *string `json:"vehicleCode"`
+	VehicleType *string `json:"vehicleType"`
+	Note        *string `json:"note"`
+	Force       bool    `json:"force"`
+}
+
+type tripRunBookingsRequest struct {
+	BookingIDs []int64 `json:"bookingIds"`
+}
+
+// GET /api/trip-runs?date=&from=&to=&status=
+func ListTripRuns(c *gin.Context) {
+	list, err := tripRunService(c).List(repositories.TripRunFilter{
+		Date:   strings.TrimSpace(c.Query("date")),
+		From:   strings.TrimSpace(c.Query("from")),
+		To:     strings.TrimSpace(c.Query("to")),
+		Status: strings.TrimSpace(c.Query("status")),
+	})
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": list})
+}
+
+// GET /api/trip-runs/:id
+func GetTripRun(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	run, err := tripRunService(c).Get(id)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// POST /api/trip-runs
+func CreateTripRun(c *gin.Context) {
+	var req tripRunRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	run, err := tripRunService(c).Create(services.TripRunInput{
+		TripSlotID:  req.TripSlotID,
+		RouteFrom:   req.RouteFrom,
+		RouteTo:     req.RouteTo,
+		TripDate:    req.TripDate,
+		TripTime:    req.TripTime,
+		TripNumber:  req.TripNumber,
+		DriverName:  req.DriverName,
+		VehicleCode: req.VehicleCode,
+		VehicleType: req.VehicleType,
+		Note:        req.Note,
+		BookingIDs:  req.BookingIDs,
+	})
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, run)
+}
+
+// PUT /api/trip-runs/:id — assign sopir/kendaraan/jadwal sekali untuk semua booking run
+func UpdateTripRun(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req tripRunAssignmentRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	run, err := tripRunService(c).Assign(id, services.TripRunAssignment{
+		TripDate:    req.TripDate,
+		TripTime:    req.TripTime,
+		TripNumber:  req.TripNumber,
+		DriverName:  req.DriverName,
+		VehicleCode: req.VehicleCode,
+		VehicleType: req.VehicleType,
+		Note:        req.Note,
+		Force:       req.Force,
+	})
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// POST /api/trip-runs/:id/bookings — pindahkan booking ke run ini
+func AddTripRunBookings(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	var req tripRunBookingsRequest
+	if !BindJSONOrError(c, &req) {
+		return
+	}
+	run, err := tripRunService(c).AddBookings(id, req.BookingIDs)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// DELETE /api/trip-runs/:id/bookings/:bookingId
+func RemoveTripRunBooking(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	bookingID, err := strconv.ParseInt(c.Param("bookingId"), 10, 64)
+	if err != nil || bookingID <= 0 {
+		respondError(c, http.StatusBadRequest, "validation_error", "bookingId tidak valid", nil)
+		return
+	}
+	run, err := tripRunService(c).RemoveBooking(id, bookingID)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// tripRunPayload membaca payload tambahan (opsional) untuk departure/return_settings, mis. surat jalan.
+func tripRunPayload(c *gin.Context) ([]byte, bool) {
+	raw, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "validation_error", "gagal membaca payload", nil)
+		return nil, false
+	}
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		return nil, true
+	}
+	raw, err = storeInlineFilesInJSON(raw, services.FileKindSuratJalan, "suratJalanFile", "surat_jalan_file")
+	if err != nil {
+		RespondDomainError(c, err)
+		return nil, false
+	}
+	return raw, true
+}
+
+// POST /api/trip-runs/:id/berangkat — semua booking run Berangkat
+func MarkTripRunBerangkat(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	extra, ok := tripRunPayload(c)
+	if !ok {
+		return
+	}
+	run, err := tripRunService(c).MarkBerangkat(id, extra)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// POST /api/trip-runs/:id/pulang — semua booking run selesai (kepulangan)
+func MarkTripRunPulang(c *gin.Context) {
+	id, ok := adminIDParam(c)
+	if !ok {
+		return
+	}
+	extra, ok := tripRunPayload(c)
+	if !ok {
+		return
+	}
+	run, err := tripRunService(c).MarkPulang(id, extra)
+	if err != nil {
+		RespondDomainError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
@@ -114,7 +114,7 @@ func nullFloatValue(p *float64) any {
 
 // GET /api/trips
 func GetTrips(c *gin.Context) {
-	rows, err := intconfig.DB.Query(`
+	rows, err := intconfig.ReadOnlyDB().Query(`
 		SELECT id, day, month, year,
 		       car_code, vehicle_name, driver_name, order_no,
 		       dept_origin, dept_dest, dept_category, dept_passenger_count, dept_passenger_fare, dept_package_count, dept_package_fare,
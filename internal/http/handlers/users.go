@@ -7,6 +7,7 @@ import (
 	"time"
 
 	intconfig "backend/internal/config"
+	"backend/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -26,7 +27,7 @@ type User struct {
 
 // GET /api/users
 func GetUsers(c *gin.Context) {
-	rows, err := intconfig.DB.Query(`
+	rows, err := intconfig.ReadOnlyDB().Query(`
 		SELECT id, name, username, email, phone, role, status, created_at
 		FROM users
 		ORDER BY created_at ASC
@@ -263,6 +264,24 @@ func UpdateUser(c *gin.Context) {
 		}
 	}
 
+	// akun dinonaktifkan / password atau role diganti => semua perangkat wajib login ulang
+	// (role ikut tertanam di access token)
+	revokeReason := ""
+	switch {
+	case !services.IsActiveUserStatus(status):
+		revokeReason = services.RevokeReasonUserInactive
+	case input.Password != "":
+		revokeReason = services.RevokeReasonPasswordChange
+	case role != existing.Role:
+		revokeReason = services.RevokeReasonRoleChange
+	}
+	if revokeReason != "" {
+		if err := authService(c).RevokeUserSessions(id, revokeReason); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "user diupdate tetapi gagal mencabut session: " + err.Error()})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "user berhasil diupdate"})
 }
 
@@ -279,6 +298,10 @@ func DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal menghapus user: " + err.Error()})
 		return
 	}
+	if err := authService(c).RevokeUserSessions(id, services.RevokeReasonUserDeleted); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user dihapus tetapi gagal mencabut session: " + err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "user berhasil dihapus"})
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	intconfig "backend/internal/config"
+	"backend/internal/repositories"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-sql-driver/mysql"
@@ -20,14 +21,21 @@ type vehicle struct {
 	Color       string `json:"color,omitempty"`
 	Kilometers  *int   `json:"kilometers,omitempty"`
 	LastService string `json:"lastService,omitempty"`
+	VehicleType string `json:"vehicleType,omitempty"` // menentukan denah kursi (seat_layouts)
+	// ServiceStatus: active | service | inactive; hanya kendaraan active yang disarankan untuk slot.
+	ServiceStatus string `json:"serviceStatus,omitempty"`
+	HomeBase      string `json:"homeBase,omitempty"`
 }
 
 type vehiclePayload struct {
-	VehicleCode string `json:"vehicleCode" binding:"required"`
-	PlateNumber string `json:"plateNumber" binding:"required"`
-	Color       string `json:"color"`
-	Kilometers  *int   `json:"kilometers"`
-	LastService string `json:"lastService"`
+	VehicleCode   string `json:"vehicleCode" binding:"required"`
+	PlateNumber   string `json:"plateNumber" binding:"required"`
+	Color         string `json:"color"`
+	Kilometers    *int   `json:"kilometers"`
+	LastService   string `json:"lastService"`
+	VehicleType   string `json:"vehicleType"`
+	ServiceStatus string `json:"serviceStatus"`
+	HomeBase      string `json:"homeBase"`
 }
 
 // GET /api/vehicles?q=LK&page=1&limit=50
@@ -51,7 +59,10 @@ func GetVehicles(c *gin.Context) {
 			CASE 
 				WHEN last_service IS NULL THEN NULL
 				ELSE DATE_FORMAT(last_service, '%Y-%m-%d')
-			END AS last_service
+			END AS last_service,
+			COALESCE(vehicle_type,'') AS vehicle_type,
+			COALESCE(service_status,'active') AS service_status,
+			COALESCE(home_base,'') AS home_base
 		FROM vehicles
 	`
 
@@ -80,10 +91,10 @@ func GetVehicles(c *gin.Context) {
 		offset := (page - 1) * limit
 		query := baseSelect + where + order + " LIMIT ? OFFSET ?"
 		args = append(args, limit, offset)
-		rows, err = intconfig.DB.Query(query, args...)
+		rows, err = intconfig.ReadOnlyDB().Query(query, args...)
 	} else {
 		query := baseSelect + where + order
-		rows, err = intconfig.DB.Query(query, args...)
+		rows, err = intconfig.ReadOnlyDB().Query(query, args...)
 	}
 
 	if err != nil {
@@ -105,6 +116,9 @@ func GetVehicles(c *gin.Context) {
 			&color,
 			&km,
 			&last,
+			&v.VehicleType,
+			&v.ServiceStatus,
+			&v.HomeBase,
 		); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "gagal scan data kendaraan: " + err.Error()})
 			return
@@ -156,10 +170,17 @@ func CreateVehicle(c *gin.Context) {
 		lastService = payload.LastService
 	}
 
+	serviceStatus, ok := vehicleServiceStatus(payload.ServiceStatus)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "serviceStatus harus active, service atau inactive"})
+		return
+	}
+
 	res, err := intconfig.DB.Exec(`
-		INSERT INTO vehicles (vehicle_code, plate_number, color, kilometers, last_service)
-		VALUES (?, ?, ?, ?, ?)
-	`, vehicleCode, plateNumber, vehicleNullIfEmpty(payload.Color), payload.Kilometers, lastService)
+		INSERT INTO vehicles (vehicle_code, plate_number, color, kilometers, last_service, vehicle_type, service_status, home_base)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		vehicleCode, plateNumber, vehicleNullIfEmpty(payload.Color), payload.Kilometers, lastService,
+		vehicleNullIfEmpty(strings.ToLower(payload.VehicleType)), serviceStatus, vehicleNullIfEmpty(payload.HomeBase))
 	if err != nil {
 		if me, ok := err.(*mysql.MySQLError); ok && me.Number == 1062 {
 			c.JSON(http.StatusConflict, gin.H{"error": "Kode Mobil atau Plat Mobil sudah terdaftar (duplikat)."})
@@ -204,11 +225,18 @@ func UpdateVehicle(c *gin.Context) {
 		lastService = payload.LastService
 	}
 
+	serviceStatus, ok := vehicleServiceStatus(payload.ServiceStatus)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "serviceStatus harus active, service atau inactive"})
+		return
+	}
+
 	res, err := intconfig.DB.Exec(`
-		UPDATE vehicles
-		SET vehicle_code = ?, plate_number = ?, color = ?, kilometers = ?, last_service = ?
-		WHERE id = ?
-	`, vehicleCode, plateNumber, vehicleNullIfEmpty(payload.Color), payload.Kilometers, lastService, id)
+		UPDATE vehicles SET vehicle_code = ?, plate_number = ?, color = ?, kilometers = ?, last_service = ?,
+			vehicle_type = ?, service_status = ?, home_base = ?
+		WHERE id = ?`,
+		vehicleCode, plateNumber, vehicleNullIfEmpty(payload.Color), payload.Kilometers, lastService,
+		vehicleNullIfEmpty(strings.ToLower(payload.VehicleType)), serviceStatus, vehicleNullIfEmpty(payload.HomeBase), id)
 	if err != nil {
 		if me, ok := err.(*mysql.MySQLError); ok && me.Number == 1062 {
 			c.JSON(http.StatusConflict, gin.H{"error": "Kode Mobil atau Plat Mobil sudah terdaftar (duplikat)."})
@@ -258,3 +286,14 @@ func vehicleNullIfEmpty(s string) any {
 	}
 	return s
 }
+
+// vehicleServiceStatus menormalkan serviceStatus payload; kosong = active.
+func vehicleServiceStatus(v string) (string, bool) {
+	switch v = strings.ToLower(strings.TrimSpace(v)); v {
+	case "":
+		return repositories.VehicleActive, true
+	case repositories.VehicleActive, repositories.VehicleService, repositories.VehicleInactive:
+		return v, true
+	}
+	return "", false
+}
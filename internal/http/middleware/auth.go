@@ -1,10 +1,100 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"net/http"
+	"strings"
 
-// AuthOptional is a placeholder for future auth middleware. It currently passes through.
-func AuthOptional() gin.HandlerFunc {
+	"backend/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+const authContextKey = "auth"
+
+// Authenticator resolves a bearer token into the caller identity.
+// services.TokenService only checks the signature; services.AuthService also checks the session.
+type Authenticator interface {
+	Authenticate(token string) (domain.RequestContext, error)
+}
+
+// Authenticate validates the Bearer token issued by /api/auth/login and stores the
+// caller identity in the gin context. Requests without a valid token are rejected.
+func Authenticate(tokens Authenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		rc, err := tokens.Authenticate(bearerToken(c))
+		if err != nil {
+			if !domain.IsUnauthorized(err) {
+				abortAuth(c, http.StatusInternalServerError, "internal_error", "gagal memverifikasi session")
+				return
+			}
+			abortAuth(c, http.StatusUnauthorized, "unauthorized", err.Error())
+			return
+		}
+		c.Set(authContextKey, rc)
 		c.Next()
 	}
 }
+
+// AuthOptional loads the caller identity when a valid token is present but never rejects.
+func AuthOptional(tokens Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if raw := bearerToken(c); raw != "" {
+			if rc, err := tokens.Authenticate(raw); err == nil {
+				c.Set(authContextKey, rc)
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireRoles only lets through callers whose role is in the allowed list.
+// Must be mounted after Authenticate.
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	allowed := map[string]bool{}
+	for _, r := range roles {
+		allowed[domain.NormalizeRole(r)] = true
+	}
+	return func(c *gin.Context) {
+		rc, ok := GetRequestContext(c)
+		if !ok {
+			abortAuth(c, http.StatusUnauthorized, "unauthorized", "login diperlukan")
+			return
+		}
+		if !allowed[rc.Role] {
+			abortAuth(c, http.StatusForbidden, "forbidden", "akses ditolak untuk role "+rc.Role)
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetRequestContext returns the authenticated user for this request, if any.
+func GetRequestContext(c *gin.Context) (domain.RequestContext, bool) {
+	if c == nil {
+		return domain.RequestContext{}, false
+	}
+	if v, ok := c.Get(authContextKey); ok {
+		if rc, ok := v.(domain.RequestContext); ok {
+			return rc, true
+		}
+	}
+	return domain.RequestContext{}, false
+}
+
+func bearerToken(c *gin.Context) string {
+	h := strings.TrimSpace(c.GetHeader("Authorization"))
+	if len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+// abortAuth mirrors the handlers error payload; middleware cannot import handlers.
+func abortAuth(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"error":      message,
+		"code":       code,
+		"message":    message,
+		"request_id": GetRequestID(c),
+	})
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader dikirim app untuk request yang aman di-retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLen = 128
+
+// DefaultIdempotencyMaxBody dipakai bila batas body tidak diisi; body dibaca utuh untuk hash.
+const DefaultIdempotencyMaxBody = 8 << 20
+
+// IdempotencyStore menyimpan respons per key; diimplementasikan oleh services.IdempotencyService.
+type IdempotencyStore interface {
+	Reserve(scope, key, hash string) (*domain.IdempotentResponse, error)
+	Complete(scope, key string, resp domain.IdempotentResponse) error
+	Release(scope, key string) error
+}
+
+// Idempotency memutar ulang respons asli bila request dengan Idempotency-Key yang sama
+// di-retry, dan menolak (409) key yang dipakai ulang untuk body berbeda.
+// Request tanpa header diproses seperti biasa. Dipasang setelah Authenticate.
+// Body lebih dari maxBody byte ditolak 413 sebelum dibaca seluruhnya ke memori.
+func Idempotency(store IdempotencyStore, maxBody int64) gin.HandlerFunc {
+	if maxBody <= 0 {
+		maxBody = DefaultIdempotencyMaxBody
+	}
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			abortAuth(c, http.StatusBadRequest, "invalid_idempotency_key", "Idempotency-Key maksimal 128 karakter")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				abortAuth(c, http.StatusRequestEntityTooLarge, "payload_too_large", "ukuran payload melebihi batas")
+				return
+			}
+			abortAuth(c, http.StatusBadRequest, "invalid_body", "gagal membaca payload")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := "anon"
+		if rc, ok := GetRequestContext(c); ok {
+			scope = "user:" + strconv.FormatInt(int64(rc.UserID), 10)
+		}
+		sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.Path+"\n"), body...))
+		hash := hex.EncodeToString(sum[:])
+
+		replay, err := store.Reserve(scope, key, hash)
+		if err != nil {
+			var ce domain.ConflictError
+			if errors.As(err, &ce) {
+				abortAuth(c, http.StatusConflict, "idempotency_conflict", ce.Msg)
+				return
+			}
+			abortAuth(c, http.StatusInternalServerError, "internal_error", "gagal memeriksa Idempotency-Key")
+			return
+		}
+		if replay != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(replay.Status, replay.ContentType, replay.Body)
+			c.Abort()
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		defer func() {
+			// handler panic: key dilepas sebelum panic diteruskan ke Recovery supaya retry diproses ulang
+			if p := recover(); p != nil {
+				_ = store.Release(scope, key)
+				panic(p)
+			}
+		}()
+		c.Next()
+
+		status := rec.Status()
+		if status >= http.StatusInternalServerError {
+			_ = store.Release(scope, key)
+			return
+		}
+		_ = store.Complete(scope, key, domain.IdempotentResponse{
+			Status:      status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		})
+	}
+}
+
+// responseRecorder menyalin body respons supaya bisa disimpan untuk replay.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
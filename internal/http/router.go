@@ -7,16 +7,17 @@ import (
 	"time"
 
 	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+	"backend/internal/domain"
 	h "backend/internal/http/handlers"
 	"backend/internal/http/middleware"
+	"backend/internal/services"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func NewRouter(env intconfig.Env) *gin.Engine {
-	_ = env
-
 	r := gin.New()
 
 	// Middleware dasar
@@ -54,10 +55,12 @@ func NewRouter(env intconfig.Env) *gin.Engine {
 			"Authorization",
 			"Accept",
 			"X-Requested-With",
+			"Idempotency-Key",
 		},
 		ExposeHeaders: []string{
 			"Content-Length",
 			"Content-Disposition", // penting untuk download pdf/file
+			"Idempotent-Replayed",
 		},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -83,62 +86,149 @@ func NewRouter(env intconfig.Env) *gin.Engine {
 		})
 	})
 
+	intdb.Schema.TTL = env.SchemaCacheTTL
+
+	authSvc := services.AuthService{
+		Tokens:     services.TokenService{Secret: []byte(env.JWTSecret), TTL: env.JWTTTL},
+		RefreshTTL: env.RefreshTokenTTL,
+	}
+	h.SetAuthService(authSvc)
+	h.SetSeatHoldService(services.SeatHoldService{TTL: env.SeatHoldTTL})
+	h.SetCancellationPolicy(services.CancellationPolicy{FreeHours: env.CancelFreeHours, FeePercent: env.CancelFeePercent})
+	h.SetBookingExpiryPolicy(services.BookingExpiryPolicy{Window: env.PaymentDeadline, CutoffHours: env.PaymentCutoffHours})
+	h.SetPaymentGateway(services.NewPaymentProvider(env), env.PaymentChargeTTL)
+	h.SetAssignmentPolicy(services.AssignmentPolicy{TripDuration: env.TripDuration, RestAfterReturn: env.RestAfterReturn})
+	h.SetFileService(services.FileService{Store: services.NewFileStore(env), MaxBytes: env.FileMaxBytes, URLTTL: env.FileURLTTL})
+
+	authn := middleware.Authenticate(authSvc)
+	adminOnly := middleware.RequireRoles(domain.RoleAdmin)
+	adminOrDriver := middleware.RequireRoles(domain.RoleAdmin, domain.RoleDriver)
+	adminOrCustomer := middleware.RequireRoles(domain.RoleAdmin, domain.RoleCustomer)
+
 	api := r.Group("/api")
 	{
+		// Public
 		api.GET("/health", h.Health)
-		api.GET("/db-check", h.DBCheck)
-		api.GET("/routes", h.Routes)
-
-		// Bookings common
-		bookings := api.Group("/bookings")
-		bookings.POST("/:id/passengers", h.SaveBookingPassengers)
-		bookings.GET("/:id/passengers", h.GetBookingPassengers)
 
 		// Auth
 		auth := api.Group("/auth")
 		auth.POST("/login", h.Login)
 		auth.POST("/register", h.Register)
+		auth.POST("/refresh", h.RefreshToken)
+		auth.POST("/logout", authn, h.Logout)
+
+		// app Flutter me-retry POST saat koneksi putus; Idempotency-Key mencegah booking/bukti ganda.
+		// Batas body mengikuti bukti bayar terbesar (base64 JSON lebih besar ~4/3 dari file-nya).
+		idem := middleware.Idempotency(services.IdempotencyService{}, env.FileMaxBytes*4/3+1<<20)
+
+		// Reguler (katalog publik + booking milik customer)
+		reguler := api.Group("/bookings/reguler")
+		mountReguler(reguler, authn, idem)
+		// legacy path
+		legacyReguler := api.Group("/reguler")
+		mountReguler(legacyReguler, authn, idem)
+
+		// Webhook payment gateway (tanpa login, diverifikasi lewat signature)
+		api.POST("/payment-gateway/webhook", h.PaymentWebhook)
+		// Download file lokal (akses lewat signed URL, tanpa login)
+		api.GET("/files/*key", h.DownloadFile)
+
+		// Semua route di bawah ini wajib login
+		secured := api.Group("", authn)
+
+		secured.GET("/db-check", adminOnly, h.DBCheck)
+		secured.GET("/routes", adminOnly, h.Routes)
+		secured.POST("/files", adminOrDriver, h.UploadFile)
+
+		admin := secured.Group("/admin", adminOnly)
+		admin.GET("/schema-cache", h.GetSchemaCacheStats)
+		admin.POST("/schema-cache/refresh", h.RefreshSchemaCache)
+		admin.GET("/stops", h.AdminListStops)
+		admin.POST("/stops", h.AdminCreateStop)
+		admin.PUT("/stops/:id", h.AdminUpdateStop)
+		admin.DELETE("/stops/:id", h.AdminDeleteStop)
+		admin.GET("/fares", h.AdminListFares)
+		admin.POST("/fares", h.AdminCreateFare)
+		admin.PUT("/fares/:id", h.AdminUpdateFare)
+		admin.DELETE("/fares/:id", h.AdminDeleteFare)
+		admin.GET("/routes", h.AdminListRoutes)
+		admin.GET("/routes/:id", h.AdminGetRoute)
+		admin.POST("/routes", h.AdminCreateRoute)
+		admin.PUT("/routes/:id", h.AdminUpdateRoute)
+		admin.DELETE("/routes/:id", h.AdminDeleteRoute)
+		admin.GET("/schedules", h.AdminListSchedules)
+		admin.POST("/schedules/generate", h.AdminGenerateTripSlots)
+		admin.GET("/schedules/:id", h.AdminGetSchedule)
+		admin.POST("/schedules", h.AdminCreateSchedule)
+		admin.PUT("/schedules/:id", h.AdminUpdateSchedule)
+		admin.DELETE("/schedules/:id", h.AdminDeleteSchedule)
+		admin.GET("/blackouts", h.AdminListBlackouts)
+		admin.POST("/blackouts", h.AdminCreateBlackout)
+		admin.DELETE("/blackouts/:id", h.AdminDeleteBlackout)
+		admin.GET("/trip-slots", h.AdminListTripSlots)
+		admin.PUT("/trip-slots/:id", h.AdminUpdateTripSlot)
+		admin.GET("/seat-layouts", h.AdminListSeatLayouts)
+		admin.GET("/seat-layouts/:id", h.AdminGetSeatLayout)
+		admin.POST("/seat-layouts", h.AdminCreateSeatLayout)
+		admin.PUT("/seat-layouts/:id", h.AdminUpdateSeatLayout)
+		admin.DELETE("/seat-layouts/:id", h.AdminDeleteSeatLayout)
+		admin.GET("/refunds", h.AdminListRefunds)
+		admin.PUT("/refunds/:id", h.AdminUpdateRefund)
+		admin.GET("/reschedules", h.AdminListReschedules)
+		admin.PUT("/reschedules/:id", h.AdminSettleReschedule)
+		admin.POST("/bank-statements/import", h.AdminImportBankStatement)
+		admin.GET("/bank-mutations", h.AdminListBankMutations)
+		admin.POST("/bank-mutations/:id/resolve", h.AdminResolveBankMutation)
+		admin.POST("/bank-mutations/:id/ignore", h.AdminIgnoreBankMutation)
+		admin.GET("/outbox", h.AdminListOutboxEvents)
+		admin.GET("/outbox/:id", h.AdminGetOutboxEvent)
+		admin.POST("/outbox/:id/replay", h.AdminReplayOutboxEvent)
+		admin.GET("/consistency", h.AdminConsistencyReport)
+		admin.POST("/consistency/repair", h.AdminConsistencyRepair)
+
+		// Bookings common (customer hanya booking miliknya, dicek di handler)
+		bookings := secured.Group("/bookings", adminOrCustomer)
+		bookings.POST("/:id/passengers", h.SaveBookingPassengers)
+		bookings.GET("/:id/passengers", h.GetBookingPassengers)
+		bookings.GET("/:id/history", h.GetBookingStatusHistory)
 
 		// Users
-		users := api.Group("/users")
+		users := secured.Group("/users", adminOnly)
 		users.GET("", h.GetUsers)
 		users.GET("/:id", h.GetUserByID)
 		users.POST("", h.CreateUser)
 		users.PUT("/:id", h.UpdateUser)
 		users.DELETE("/:id", h.DeleteUser)
 
-		// Bookings (reguler)
-		reguler := bookings.Group("/reguler")
-		mountReguler(reguler)
-		// legacy path
-		legacyReguler := api.Group("/reguler")
-		mountReguler(legacyReguler)
-
 		// Payments
-		payments := api.Group("/payments")
+		payments := secured.Group("/payments", adminOnly)
 		mountPaymentValidations(payments)
 		// legacy payment validations
-		paymentValidations := api.Group("/payment-validations")
+		paymentValidations := secured.Group("/payment-validations", adminOnly)
 		mountPaymentValidations(paymentValidations)
+		// simulasi pembayaran provider fake (PAYMENT_PROVIDER=fake)
+		secured.POST("/payment-gateway/fake/:reference", adminOrCustomer, h.SimulateFakePayment)
 
-		// Departures
-		departures := api.Group("/departures")
-		mountDepartureSettings(departures)
-		legacyDepartures := api.Group("/departure-settings")
-		legacyDepartures.GET("", h.GetDepartureSettings)
-		legacyDepartures.GET("/:id", h.GetDepartureSettingByID)
-		legacyDepartures.POST("", h.CreateDepartureSetting)
-		legacyDepartures.PUT("/:id", h.UpdateDepartureSetting)
-		legacyDepartures.DELETE("/:id", h.DeleteDepartureSetting)
+		// Departures (driver hanya keberangkatan yang ditugaskan padanya, dicek di handler)
+		departures := secured.Group("/departures")
+		mountDepartureSettings(departures, adminOnly, adminOrDriver)
+		departures.GET("/slots/:slot/suggestions", adminOnly, h.GetDepartureSlotSuggestions)
+		departures.POST("/suggestions/apply", adminOnly, h.ApplyDepartureSuggestions)
+		legacyDepartures := secured.Group("/departure-settings")
+		legacyDepartures.GET("", adminOrDriver, h.GetDepartureSettings)
+		legacyDepartures.GET("/:id", adminOrDriver, h.GetDepartureSettingByID)
+		legacyDepartures.POST("", adminOnly, h.CreateDepartureSetting)
+		legacyDepartures.PUT("/:id", adminOrDriver, h.UpdateDepartureSetting)
+		legacyDepartures.DELETE("/:id", adminOnly, h.DeleteDepartureSetting)
 
 		// Return settings
-		returns := api.Group("/returns")
+		returns := secured.Group("/returns", adminOnly)
 		returns.GET("/settings", h.GetReturnSettings)
 		returns.GET("/settings/:id", h.GetReturnSettingByID)
 		returns.POST("/settings", h.CreateReturnSetting)
 		returns.PUT("/settings/:id", h.UpdateReturnSetting)
 		returns.DELETE("/settings/:id", h.DeleteReturnSetting)
-		legacyReturns := api.Group("/return-settings")
+		legacyReturns := secured.Group("/return-settings", adminOnly)
 		legacyReturns.GET("", h.GetReturnSettings)
 		legacyReturns.GET("/:id", h.GetReturnSettingByID)
 		legacyReturns.POST("", h.CreateReturnSetting)
@@ -146,16 +236,27 @@ func NewRouter(env intconfig.Env) *gin.Engine {
 		legacyReturns.DELETE("/:id", h.DeleteReturnSetting)
 
 		// Passengers
-		passengers := api.Group("/passengers")
-		passengers.GET("", h.GetPassengers)
-		passengers.POST("", h.CreatePassenger)
-		passengers.PUT("/:id", h.UpdatePassenger)
-		passengers.DELETE("/:id", h.DeletePassenger)
-		passengers.GET("/:id/e-ticket", h.GetPassengerETicketPDF)
-		passengers.GET("/:id/invoice", h.GetPassengerInvoicePDF)
+		passengers := secured.Group("/passengers")
+		passengers.GET("", adminOnly, h.GetPassengers)
+		passengers.POST("", adminOnly, h.CreatePassenger)
+		passengers.PUT("/:id", adminOnly, h.UpdatePassenger)
+		passengers.DELETE("/:id", adminOnly, h.DeletePassenger)
+		passengers.GET("/:id/e-ticket", adminOrCustomer, h.GetPassengerETicketPDF)
+		passengers.GET("/:id/invoice", adminOrCustomer, h.GetPassengerInvoicePDF)
+
+		// Trip runs (satu mobil + sopir untuk beberapa booking)
+		tripRuns := secured.Group("/trip-runs", adminOnly)
+		tripRuns.GET("", h.ListTripRuns)
+		tripRuns.POST("", h.CreateTripRun)
+		tripRuns.GET("/:id", h.GetTripRun)
+		tripRuns.PUT("/:id", h.UpdateTripRun)
+		tripRuns.POST("/:id/bookings", h.AddTripRunBookings)
+		tripRuns.DELETE("/:id/bookings/:bookingId", h.RemoveTripRunBooking)
+		tripRuns.POST("/:id/berangkat", h.MarkTripRunBerangkat)
+		tripRuns.POST("/:id/pulang", h.MarkTripRunPulang)
 
 		// Trip Information
-		tripInfo := api.Group("/trip-information")
+		tripInfo := secured.Group("/trip-information", adminOnly)
 		tripInfo.GET("", h.GetTripInformation)
 		tripInfo.POST("", h.CreateTripInformation)
 		tripInfo.PUT("/:id", h.UpdateTripInformation)
@@ -163,58 +264,77 @@ func NewRouter(env intconfig.Env) *gin.Engine {
 		tripInfo.GET("/:id/surat-jalan", h.GetTripSuratJalan)
 
 		// Trips (financial)
-		trips := api.Group("/trips")
+		trips := secured.Group("/trips", adminOnly)
 		trips.GET("", h.GetTrips)
 		trips.POST("", h.CreateTrip)
 		trips.PUT("/:id", h.UpdateTrip)
 		trips.DELETE("/:id", h.DeleteTrip)
 
 		// Reports
-		reports := api.Group("/reports")
+		reports := secured.Group("/reports", adminOnly)
 		reports.GET("/vehicle", h.ReportVehicle)
 		reports.GET("/finance", h.GetFinanceReport)
 
 		// Drivers & driver accounts
-		drivers := api.Group("/drivers")
+		drivers := secured.Group("/drivers", adminOnly)
 		drivers.GET("", h.GetDrivers)
 		drivers.POST("", h.CreateDriver)
 		drivers.PUT("/:id", h.UpdateDriver)
 		drivers.DELETE("/:id", h.DeleteDriver)
-		driverAccounts := api.Group("/driver-accounts")
+		driverAccounts := secured.Group("/driver-accounts", adminOnly)
 		driverAccounts.GET("", h.GetDriverAccounts)
 		driverAccounts.POST("", h.CreateDriverAccount)
 		driverAccounts.PUT("/:id", h.UpdateDriverAccount)
 		driverAccounts.DELETE("/:id", h.DeleteDriverAccount)
 
 		// Vehicles
-		vehicles := api.Group("/vehicles")
+		vehicles := secured.Group("/vehicles", adminOnly)
 		vehicles.GET("", h.GetVehicles)
 		vehicles.POST("", h.CreateVehicle)
 		vehicles.PUT("/:id", h.UpdateVehicle)
 		vehicles.DELETE("/:id", h.DeleteVehicle)
 
 		// Costs & expenses
-		api.GET("/vehicle-costs", h.ListVehicleCosts)
-		api.POST("/vehicle-costs", h.UpsertVehicleCost)
-		api.DELETE("/vehicle-costs/:id", h.DeleteVehicleCost)
+		costs := secured.Group("", adminOnly)
+		costs.GET("/vehicle-costs", h.ListVehicleCosts)
+		costs.POST("/vehicle-costs", h.UpsertVehicleCost)
+		costs.DELETE("/vehicle-costs/:id", h.DeleteVehicleCost)
 
-		api.GET("/company-expenses", h.ListCompanyExpenses)
-		api.POST("/company-expenses", h.UpsertCompanyExpense)
-		api.DELETE("/company-expenses/:id", h.DeleteCompanyExpense)
+		costs.GET("/company-expenses", h.ListCompanyExpenses)
+		costs.POST("/company-expenses", h.UpsertCompanyExpense)
+		costs.DELETE("/company-expenses/:id", h.DeleteCompanyExpense)
 	}
 
 	return r
 }
 
-func mountReguler(g *gin.RouterGroup) {
+func mountReguler(g *gin.RouterGroup, authn, idem gin.HandlerFunc) {
+	adminOnly := middleware.RequireRoles(domain.RoleAdmin)
+	adminOrCustomer := middleware.RequireRoles(domain.RoleAdmin, domain.RoleCustomer)
+
 	g.GET("/stops", h.GetRegulerStops)
+	g.GET("/slots", h.GetRegulerSlots)
 	g.GET("/seats", h.GetRegulerSeats)
 	g.POST("/quote", h.GetRegulerQuote)
-	g.POST("/bookings", h.CreateRegulerBooking)
-	g.GET("/bookings/:id/surat-jalan", h.GetRegulerSuratJalan)
-	g.GET("/bookings/:id", h.GetRegulerBookingDetail)
-	g.POST("/bookings/:id/submit-payment", h.SubmitRegulerPaymentProof)
-	g.POST("/bookings/:id/confirm-cash", h.ConfirmRegulerCash)
+
+	// surat jalan juga dibuka untuk driver
+	g.GET("/bookings/:id/surat-jalan", authn, h.GetRegulerSuratJalan)
+
+	// hold seat selama checkout
+	holds := g.Group("/holds", authn, adminOrCustomer)
+	holds.POST("", h.CreateRegulerHold)
+	holds.DELETE("/:token", h.ReleaseRegulerHold)
+
+	bookings := g.Group("/bookings", authn, adminOrCustomer)
+	bookings.POST("", idem, h.CreateRegulerBooking)
+	bookings.GET("/:id", h.GetRegulerBookingDetail)
+	bookings.POST("/:id/submit-payment", idem, h.SubmitRegulerPaymentProof)
+	bookings.POST("/:id/charge", idem, h.CreateRegulerCharge)
+	bookings.POST("/:id/cancel", h.CancelRegulerBooking)
+	bookings.POST("/:id/reschedule", h.RescheduleRegulerBooking)
+
+	// cash hanya dicatat oleh admin yang menerima uangnya, bukan self-service customer
+	g.POST("/bookings/:id/confirm-cash", authn, adminOnly, h.ConfirmRegulerCash)
 }
 
 func mountPaymentValidations(g *gin.RouterGroup) {
@@ -226,10 +346,10 @@ func mountPaymentValidations(g *gin.RouterGroup) {
 	g.PUT("/:id/reject", h.RejectPaymentValidation)
 }
 
-func mountDepartureSettings(g *gin.RouterGroup) {
-	g.GET("/settings", h.GetDepartureSettings)
-	g.GET("/settings/:id", h.GetDepartureSettingByID)
-	g.POST("/settings", h.CreateDepartureSetting)
-	g.PUT("/settings/:id", h.UpdateDepartureSetting)
-	g.DELETE("/settings/:id", h.DeleteDepartureSetting)
+func mountDepartureSettings(g *gin.RouterGroup, adminOnly, adminOrDriver gin.HandlerFunc) {
+	g.GET("/settings", adminOrDriver, h.GetDepartureSettings)
+	g.GET("/settings/:id", adminOrDriver, h.GetDepartureSettingByID)
+	g.POST("/settings", adminOnly, h.CreateDepartureSetting)
+	g.PUT("/settings/:id", adminOrDriver, h.UpdateDepartureSetting)
+	g.DELETE("/settings/:id", adminOnly, h.DeleteDepartureSetting)
 }
@@ -0,0 +1,235 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Status mutasi bank hasil impor rekening koran.
+const (
+	MutationMatched   = "matched"
+	MutationReview    = "review"
+	MutationUnmatched = "unmatched"
+	MutationIgnored   = "ignored"
+)
+
+// BankMutation adalah satu mutasi kredit rekening yang sudah diimpor.
+type BankMutation struct {
+	ID           int64      `json:"id"`
+	Source       string     `json:"source"`
+	PostedAt     time.Time  `json:"postedAt"`
+	Amount       int64      `json:"amount"`
+	Description  string     `json:"description"`
+	Reference    string     `json:"reference,omitempty"`
+	Status       string     `json:"status"`
+	ValidationID int64      `json:"validationId,omitempty"`
+	CandidateIDs []int64    `json:"candidateIds"`
+	Note         string     `json:"note,omitempty"`
+	ImportedBy   int64      `json:"importedBy,omitempty"`
+	ResolvedAt   *time.Time `json:"resolvedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+// PendingTransfer adalah bukti transfer yang masih menunggu validasi admin.
+type PendingTransfer struct {
+	ValidationID int64
+	BookingID    int64
+	Amount       int64 // nominal bukti di ledger payments, atau total booking
+	UniqueCode   int64 // kode unik transfer booking (0 bila tidak ada)
+	SubmittedAt  string
+}
+
+type BankMutationRepository struct {
+	DB *sql.DB
+}
+
+func (r BankMutationRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r BankMutationRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "bank_mutations") {
+		return nil, fmt.Errorf("tabel bank_mutations belum tersedia, jalankan `migrate up`")
+	}
+	return db, nil
+}
+
+// Available melaporkan apakah migration bank_mutations sudah dijalankan.
+func (r BankMutationRepository) Available() bool {
+	_, err := r.ready()
+	return err == nil
+}
+
+const bankMutationColumns = `id, source, posted_at, amount, description, reference, status,
+	COALESCE(validation_id, 0), candidate_ids, note, COALESCE(imported_by, 0), resolved_at, created_at`
+
+func scanBankMutation(sc interface{ Scan(...any) error }) (BankMutation, error) {
+	var (
+		m          BankMutation
+		candidates string
+		resolved   sql.NullTime
+	)
+	if err := sc.Scan(&m.ID, &m.Source, &m.PostedAt, &m.Amount, &m.Description, &m.Reference, &m.Status,
+		&m.ValidationID, &candidates, &m.Note, &m.ImportedBy, &resolved, &m.CreatedAt); err != nil {
+		return BankMutation{}, err
+	}
+	m.CandidateIDs = []int64{}
+	for _, s := range strings.Split(candidates, ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil && id > 0 {
+			m.CandidateIDs = append(m.CandidateIDs, id)
+		}
+	}
+	if resolved.Valid {
+		t := resolved.Time
+		m.ResolvedAt = &t
+	}
+	return m, nil
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Insert mencatat mutasi baru; duplicate=true bila fingerprint sudah pernah diimpor.
+func (r BankMutationRepository) Insert(m BankMutation, fingerprint string) (id int64, duplicate bool, err error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, false, err
+	}
+	res, err := db.Exec(`
+		INSERT INTO bank_mutations (fingerprint, source, posted_at, amount, description, reference, status, imported_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0))`,
+		fingerprint, m.Source, m.PostedAt.Format("2006-01-02"), m.Amount, truncate(strings.TrimSpace(m.Description), 255), truncate(strings.TrimSpace(m.Reference), 100),
+		MutationUnmatched, m.ImportedBy)
+	if err != nil {
+		var me *mysql.MySQLError
+		if errors.As(err, &me) && me.Number == 1062 {
+			return 0, true, nil
+		}
+		return 0, false, err
+	}
+	id, err = res.LastInsertId()
+	return id, false, err
+}
+
+// UpdateMatch menyimpan hasil pencocokan mutasi.
+func (r BankMutationRepository) UpdateMatch(id int64, status string, validationID int64, candidates []int64, note string, resolvedAt *time.Time) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		UPDATE bank_mutations SET status=?, validation_id=NULLIF(?, 0), candidate_ids=?, note=?, resolved_at=?
+		WHERE id=?`, status, validationID, joinIDs(candidates), truncate(strings.TrimSpace(note), 255), resolvedAt, id)
+	return err
+}
+
+func (r BankMutationRepository) GetByID(id int64) (BankMutation, error) {
+	db, err := r.ready()
+	if err != nil {
+		return BankMutation{}, err
+	}
+	return scanBankMutation(db.QueryRow(`SELECT `+bankMutationColumns+` FROM bank_mutations WHERE id = ?`, id))
+}
+
+// List mengembalikan mutasi terbaru lebih dulu; status kosong = semua.
+func (r BankMutationRepository) List(status string, limit int) ([]BankMutation, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 200
+	}
+	query := `SELECT ` + bankMutationColumns + ` FROM bank_mutations`
+	args := []any{}
+	if s := strings.TrimSpace(status); s != "" {
+		query += ` WHERE status = ?`
+		args = append(args, s)
+	}
+	args = append(args, limit)
+	rows, err := db.Query(query+` ORDER BY posted_at DESC, id DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []BankMutation{}
+	for rows.Next() {
+		m, err := scanBankMutation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// PendingTransfers mengembalikan bukti transfer berstatus Menunggu Validasi beserta nominal yang
+// diharapkan masuk. Kosong bila payment_validations belum punya kolom booking_id.
+func (r BankMutationRepository) PendingTransfers() ([]PendingTransfer, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasColumn(db, "payment_validations", "booking_id") {
+		return []PendingTransfer{}, nil
+	}
+
+	amount := "0"
+	if intdb.HasColumn(db, "bookings", "total") {
+		amount = "COALESCE(b.total,0)"
+	}
+	if intdb.HasTable(db, "payments") {
+		amount = "COALESCE((SELECT SUM(p.amount) FROM payments p WHERE p.validation_id = pv.id AND p.status = '" + LedgerPending + "'), " + amount + ")"
+	}
+	code := "0"
+	if intdb.HasTable(db, "transfer_codes") {
+		// kode dari reservasi aktif: kode yang tidak lagi dipesan tidak menjamin nominalnya unik
+		code = "COALESCE((SELECT tc.code FROM transfer_codes tc WHERE tc.booking_id = b.id), 0)"
+	}
+	submitted := "''"
+	if intdb.HasColumn(db, "payment_validations", "created_at") {
+		submitted = "COALESCE(CAST(pv.created_at AS CHAR),'')"
+	}
+
+	rows, err := db.Query(`
+		SELECT pv.id, pv.booking_id, ` + amount + `, ` + code + `, ` + submitted + `
+		FROM payment_validations pv
+		JOIN bookings b ON b.id = pv.booking_id
+		WHERE pv.payment_status = 'Menunggu Validasi'
+		  AND LOWER(COALESCE(pv.payment_method,'')) = 'transfer'
+		ORDER BY pv.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PendingTransfer{}
+	for rows.Next() {
+		var p PendingTransfer
+		if err := rows.Scan(&p.ValidationID, &p.BookingID, &p.Amount, &p.UniqueCode, &p.SubmittedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+)
+
+// SetPaymentDeadlineTx menyimpan batas upload bukti bayar booking.
+func (r BookingRepository) SetPaymentDeadlineTx(tx *sql.Tx, id int64, deadline time.Time) error {
+	_, err := tx.Exec(`UPDATE bookings SET payment_deadline=? WHERE id=?`, deadline, id)
+	return err
+}
+
+// ListPaymentOverdue mengembalikan id booking non-cash belum lunas yang melewati payment_deadline
+// dan tidak punya bukti pembayaran selain yang ditolak, paling lama lebih dulu.
+func (r BookingRepository) ListPaymentOverdue(now time.Time, limit int) ([]int64, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasColumn(db, "bookings", "payment_status") {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	where := []string{
+		"b.payment_deadline IS NOT NULL",
+		"b.payment_deadline <= ?",
+		"COALESCE(b.payment_status,'') IN ('', 'Belum Lunas', 'Menunggu Validasi', 'Ditolak')",
+	}
+	args := []any{now}
+	if intdb.HasColumn(db, "bookings", "payment_method") {
+		where = append(where, "COALESCE(b.payment_method,'') <> 'cash'")
+	}
+	where = append(where, "(b.booking_status IS NULL OR b.booking_status IN (?, ?, ?, ?))")
+	args = append(args, string(domain.BookingDraft), string(domain.BookingAwaitingPayment), string(domain.BookingAwaitingValidation), string(domain.BookingRejected))
+	if intdb.HasColumn(db, "bookings", "payment_validation_id") {
+		where = append(where, "(COALESCE(b.payment_validation_id,0) = 0 OR b.payment_status = 'Ditolak')")
+	}
+	if intdb.HasColumn(db, "payment_validations", "booking_id") {
+		where = append(where, "NOT EXISTS (SELECT 1 FROM payment_validations pv WHERE pv.booking_id = b.id"+proofPendingCond(db)+")")
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(`SELECT b.id FROM bookings b WHERE `+strings.Join(where, " AND ")+` ORDER BY b.payment_deadline LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// HasPaymentProofTx melaporkan apakah booking punya payment_validations (bukti transfer/QRIS)
+// yang belum ditolak; booking yang buktinya ditolak tetap bisa expired.
+func (r BookingRepository) HasPaymentProofTx(tx *sql.Tx, id int64) (bool, error) {
+	if intdb.HasColumn(tx, "bookings", "payment_validation_id") {
+		var pvID int64
+		var status string
+		if err := tx.QueryRow(`SELECT COALESCE(payment_validation_id,0), COALESCE(payment_status,'') FROM bookings WHERE id=?`, id).Scan(&pvID, &status); err != nil {
+			return false, err
+		}
+		if pvID > 0 && status != "Ditolak" {
+			return true, nil
+		}
+	}
+	if !intdb.HasColumn(tx, "payment_validations", "booking_id") {
+		return false, nil
+	}
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM payment_validations pv WHERE pv.booking_id=?`+proofPendingCond(tx), id).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// proofPendingCond menyaring bukti bayar yang sudah ditolak admin (payment_validations pv).
+func proofPendingCond(q intdb.QueryRower) string {
+	if !intdb.HasColumn(q, "payment_validations", "payment_status") {
+		return ""
+	}
+	return " AND COALESCE(pv.payment_status,'') <> 'Ditolak'"
+}
+
+// PaymentDeadline mengembalikan batas pembayaran booking; nil bila tidak ada.
+func (r BookingRepository) PaymentDeadline(id int64) (*time.Time, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	var deadline sql.NullTime
+	if err := db.QueryRow(`SELECT payment_deadline FROM bookings WHERE id=?`, id).Scan(&deadline); err != nil {
+		return nil, err
+	}
+	if !deadline.Valid {
+		return nil, nil
+	}
+	t := deadline.Time
+	return &t, nil
+}
@@ -4,9 +4,11 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	intconfig "backend/internal/config"
 	intdb "backend/internal/db"
+	"backend/internal/domain"
 )
 
 // Booking represents minimal booking data we need for departure creation.
@@ -120,26 +122,116 @@ func (r BookingRepository) GetByID(id int64) (Booking, error) {
 	return b, nil
 }
 
-// UpdatePaymentStatus sets payment_status (and optionally payment_method) on bookings.
-func (r BookingRepository) UpdatePaymentStatus(id int64, status, method string) error {
+// UpdatePaymentStatus memindahkan status booking sesuai label payment_status (mis. "Lunas")
+// lewat state machine BookingStatus, dan opsional menyimpan payment_method.
+// Transisi yang tidak diizinkan dikembalikan sebagai domain.ConflictError.
+func (r BookingRepository) UpdatePaymentStatus(id int64, status, method string, actor StatusActor) error {
 	if id <= 0 {
 		return fmt.Errorf("id tidak valid")
 	}
 	table := "bookings"
-	if !intdb.HasTable(intconfig.DB, table) || !intdb.HasColumn(intconfig.DB, table, "payment_status") {
+	db := r.db()
+	if !intdb.HasTable(db, table) || !intdb.HasColumn(db, table, "payment_status") {
 		return nil
 	}
 
-	sets := []string{"payment_status=?"}
-	args := []any{strings.TrimSpace(status)}
-	if method != "" && intdb.HasColumn(intconfig.DB, table, "payment_method") {
-		sets = append(sets, "payment_method=?")
-		args = append(args, method)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
 	}
-	if intdb.HasColumn(intconfig.DB, table, "updated_at") {
-		sets = append(sets, "updated_at=NOW()")
+	defer func() { _ = tx.Rollback() }()
+
+	if err := r.UpdatePaymentStatusTx(tx, id, status, method, actor); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdatePaymentStatusTx sama dengan UpdatePaymentStatus di dalam transaksi pemanggil.
+func (r BookingRepository) UpdatePaymentStatusTx(tx *sql.Tx, id int64, status, method string, actor StatusActor) error {
+	table := "bookings"
+	if _, err := r.TransitionTx(tx, id, domain.BookingStatusFromPayment(status), actor, ""); err != nil {
+		return err
+	}
+	if method != "" && intdb.HasColumn(tx, table, "payment_method") {
+		if _, err := tx.Exec(`UPDATE `+table+` SET payment_method=? WHERE id=?`, method, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LockForUpdate membaca booking (untuk pembatalan/reschedule) dan mengunci barisnya sampai tx selesai.
+// TripDate dikembalikan sebagai YYYY-MM-DD.
+func (r BookingRepository) LockForUpdate(tx *sql.Tx, id int64) (Booking, error) {
+	table := "bookings"
+	if !intdb.HasColumn(tx, table, "payment_status") {
+		return Booking{}, fmt.Errorf("schema bookings belum siap: kolom payment_status tidak ditemukan")
+	}
+	method := "''"
+	if intdb.HasColumn(tx, table, "payment_method") {
+		method = "COALESCE(payment_method,'')"
+	}
+	var b Booking
+	err := tx.QueryRow(`
+		SELECT id, COALESCE(category,''), COALESCE(route_from,''), COALESCE(route_to,''),
+		       COALESCE(DATE_FORMAT(trip_date, '%Y-%m-%d'),''), COALESCE(trip_time,''),
+		       COALESCE(passenger_count,0), COALESCE(price_per_seat,0), COALESCE(total,0),
+		       COALESCE(payment_status,''), `+method+`
+		FROM `+table+` WHERE id=? LIMIT 1 FOR UPDATE`, id).
+		Scan(&b.ID, &b.Category, &b.RouteFrom, &b.RouteTo, &b.TripDate, &b.TripTime,
+			&b.PassengerCount, &b.PricePerSeat, &b.Total, &b.PaymentStatus, &b.PaymentMethod)
+	return b, err
+}
+
+// MarkCancelledTx menandai booking batal (payment_status, cancelled_at, cancel_reason).
+func (r BookingRepository) MarkCancelledTx(tx *sql.Tx, id int64, status, reason string, at time.Time) error {
+	table := "bookings"
+	sets := []string{"payment_status=?", "cancelled_at=?", "cancel_reason=?"}
+	args := []any{status, at, strings.TrimSpace(reason)}
+	if intdb.HasColumn(tx, table, "updated_at") {
+		sets = append(sets, "updated_at=?")
+		args = append(args, at)
+	}
+	args = append(args, id)
+	_, err := tx.Exec(`UPDATE `+table+` SET `+strings.Join(sets, ",")+` WHERE id=?`, args...)
+	return err
+}
+
+// CancelledAtTx mengembalikan waktu pembatalan booking; ok=false bila booking belum dibatalkan.
+func (r BookingRepository) CancelledAtTx(tx *sql.Tx, id int64) (time.Time, bool, error) {
+	var at sql.NullTime
+	if err := tx.QueryRow(`SELECT cancelled_at FROM bookings WHERE id=? LIMIT 1`, id).Scan(&at); err != nil {
+		return time.Time{}, false, err
+	}
+	return at.Time, at.Valid, nil
+}
+
+// RescheduleTx memindahkan booking ke slot baru dan menyimpan tarif hasil hitung ulang.
+func (r BookingRepository) RescheduleTx(tx *sql.Tx, id int64, slot SeatSlot, tripSlotID, pricePerSeat, total int64, at time.Time) error {
+	table := "bookings"
+	sets := []string{"route_from=?", "route_to=?", "trip_date=?", "trip_time=?", "price_per_seat=?", "total=?", "trip_slot_id=NULLIF(?,0)"}
+	args := []any{slot.From, slot.To, slot.Date, slot.Time, pricePerSeat, total, tripSlotID}
+	if intdb.HasColumn(tx, table, "updated_at") {
+		sets = append(sets, "updated_at=?")
+		args = append(args, at)
+	}
+	args = append(args, id)
+	_, err := tx.Exec(`UPDATE `+table+` SET `+strings.Join(sets, ",")+` WHERE id=?`, args...)
+	return err
+}
+
+// ReduceTotalTx mengurangi total booking sebesar amount (tidak di bawah 0), mis. tagihan tambahan
+// reschedule yang dibebaskan admin.
+func (r BookingRepository) ReduceTotalTx(tx *sql.Tx, id, amount int64, at time.Time) error {
+	table := "bookings"
+	sets := []string{"total=GREATEST(COALESCE(total,0)-?,0)"}
+	args := []any{amount}
+	if intdb.HasColumn(tx, table, "updated_at") {
+		sets = append(sets, "updated_at=?")
+		args = append(args, at)
 	}
 	args = append(args, id)
-	_, err := intconfig.DB.Exec(`UPDATE `+table+` SET `+strings.Join(sets, ",")+` WHERE id=?`, args...)
+	_, err := tx.Exec(`UPDATE `+table+` SET `+strings.Join(sets, ",")+` WHERE id=?`, args...)
 	return err
 }
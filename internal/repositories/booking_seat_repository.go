@@ -77,3 +77,47 @@ func (r BookingSeatRepository) GetSeats(bookingID int64) ([]BookingSeat, error)
 	}
 	return out, rows.Err()
 }
+
+// DeleteByBookingTx melepas semua seat milik booking (dipakai saat pembatalan).
+func (r BookingSeatRepository) DeleteByBookingTx(tx *sql.Tx, bookingID int64) (int64, error) {
+	if !intdb.HasTable(tx, "booking_seats") {
+		return 0, nil
+	}
+	res, err := tx.Exec(`DELETE FROM booking_seats WHERE booking_id=?`, bookingID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ListByBookingTx mengembalikan kode seat booking sesuai urutan input, dikunci sampai tx selesai.
+func (r BookingSeatRepository) ListByBookingTx(tx *sql.Tx, bookingID int64) ([]string, error) {
+	rows, err := tx.Query(`SELECT seat_code FROM booking_seats WHERE booking_id=? ORDER BY id ASC FOR UPDATE`, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []string{}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		out = append(out, strings.ToUpper(strings.TrimSpace(code)))
+	}
+	return out, rows.Err()
+}
+
+// InsertSeatsTx mencatat seat booking pada slot; duplikat (unique key) dikembalikan apa adanya.
+func (r BookingSeatRepository) InsertSeatsTx(tx *sql.Tx, bookingID int64, slot SeatSlot, seats []string) error {
+	for _, seat := range seats {
+		if _, err := tx.Exec(`
+			INSERT INTO booking_seats
+			(booking_id, route_from, route_to, trip_date, trip_time, seat_code, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, NOW())`,
+			bookingID, slot.From, slot.To, slot.Date, slot.Time, seat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+)
+
+// StatusActor mencatat siapa yang memicu perubahan status booking.
+type StatusActor struct {
+	UserID    int64
+	Role      string
+	RequestID string
+}
+
+// BookingStatusChange adalah satu baris booking_status_history.
+type BookingStatusChange struct {
+	ID        int64                `json:"id"`
+	BookingID int64                `json:"bookingId"`
+	From      domain.BookingStatus `json:"from"`
+	To        domain.BookingStatus `json:"to"`
+	ActorID   int64                `json:"actorId,omitempty"`
+	ActorRole string               `json:"actorRole"`
+	RequestID string               `json:"requestId"`
+	Note      string               `json:"note"`
+	CreatedAt time.Time            `json:"createdAt"`
+}
+
+func (r BookingRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+// statusOf membaca status booking (dikunci bila lock). Booking lama dengan booking_status kosong
+// diturunkan dari payment_status.
+func (r BookingRepository) statusOf(q intdb.Queryer, id int64, lock bool) (domain.BookingStatus, error) {
+	query := `SELECT COALESCE(payment_status,''), COALESCE(booking_status,'') FROM bookings WHERE id=? LIMIT 1`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	var payment, status string
+	if err := q.QueryRow(query, id).Scan(&payment, &status); err != nil {
+		return "", err
+	}
+	if st := domain.BookingStatus(strings.TrimSpace(status)); st.Valid() {
+		return st, nil
+	}
+	return domain.BookingStatusFromPayment(payment), nil
+}
+
+// CurrentStatus mengembalikan status booking saat ini.
+func (r BookingRepository) CurrentStatus(id int64) (domain.BookingStatus, error) {
+	db := r.db()
+	if db == nil {
+		return "", fmt.Errorf("db tidak tersedia")
+	}
+	return r.statusOf(db, id, false)
+}
+
+// CheckTransition memvalidasi perpindahan status tanpa menulis apa pun.
+func (r BookingRepository) CheckTransition(id int64, to domain.BookingStatus) error {
+	from, err := r.CurrentStatus(id)
+	if err != nil {
+		return err
+	}
+	return domain.ValidateBookingTransition(from, to)
+}
+
+// TransitionTx memindahkan booking ke status to: mengunci baris booking, memvalidasi transisi,
+// menulis booking_status + label payment_status, lalu mencatat riwayatnya. Status yang sama = no-op.
+func (r BookingRepository) TransitionTx(q intdb.Queryer, id int64, to domain.BookingStatus, actor StatusActor, note string) (domain.BookingStatus, error) {
+	from, err := r.statusOf(q, id, true)
+	if err != nil {
+		return "", err
+	}
+	if from == to {
+		return from, nil
+	}
+	if err := domain.ValidateBookingTransition(from, to); err != nil {
+		return from, err
+	}
+
+	sets := []string{"booking_status=?"}
+	args := []any{string(to)}
+	if label := to.PaymentLabel(); label != "" && intdb.HasColumn(q, "bookings", "payment_status") {
+		sets = append(sets, "payment_status=?")
+		args = append(args, label)
+	}
+	if intdb.HasColumn(q, "bookings", "updated_at") {
+		sets = append(sets, "updated_at=NOW()")
+	}
+	args = append(args, id)
+	if _, err := q.Exec(`UPDATE bookings SET `+strings.Join(sets, ",")+` WHERE id=?`, args...); err != nil {
+		return from, err
+	}
+	if releasesTransferCode(to) {
+		if err := r.ReleaseTransferCodeTx(q, id); err != nil {
+			return from, err
+		}
+	}
+	return from, r.insertHistory(q, id, from, to, actor, note)
+}
+
+// Transition menjalankan TransitionTx dalam transaksi sendiri.
+func (r BookingRepository) Transition(id int64, to domain.BookingStatus, actor StatusActor, note string) (domain.BookingStatus, error) {
+	db := r.db()
+	if db == nil {
+		return "", fmt.Errorf("db tidak tersedia")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	from, err := r.TransitionTx(tx, id, to, actor, note)
+	if err != nil {
+		_ = tx.Rollback()
+		return from, err
+	}
+	return from, tx.Commit()
+}
+
+// StartStatusTx mencatat status awal booking yang baru dibuat (draft -> status).
+func (r BookingRepository) StartStatusTx(q intdb.Queryer, id int64, status domain.BookingStatus, actor StatusActor) error {
+	if err := domain.ValidateBookingTransition(domain.BookingDraft, status); err != nil {
+		return err
+	}
+	if _, err := q.Exec(`UPDATE bookings SET booking_status=? WHERE id=?`, string(status), id); err != nil {
+		return err
+	}
+	return r.insertHistory(q, id, domain.BookingDraft, status, actor, "booking dibuat")
+}
+
+// insertHistory dilewati bila migration booking_status_history belum dijalankan.
+func (r BookingRepository) insertHistory(q intdb.Queryer, id int64, from, to domain.BookingStatus, actor StatusActor, note string) error {
+	if !intdb.HasTable(q, "booking_status_history") {
+		return nil
+	}
+	_, err := q.Exec(`
+		INSERT INTO booking_status_history (booking_id, from_status, to_status, actor_id, actor_role, request_id, note)
+		VALUES (?, ?, ?, NULLIF(?, 0), ?, ?, ?)`,
+		id, string(from), string(to), actor.UserID, actor.Role, actor.RequestID, strings.TrimSpace(note))
+	return err
+}
+
+// StatusHistory mengembalikan riwayat status booking, terlama lebih dulu.
+func (r BookingRepository) StatusHistory(id int64) ([]BookingStatusChange, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "booking_status_history") {
+		return nil, fmt.Errorf("tabel booking_status_history belum tersedia, jalankan `migrate up`")
+	}
+	rows, err := db.Query(`
+		SELECT id, booking_id, from_status, to_status, COALESCE(actor_id, 0), actor_role, request_id, note, created_at
+		FROM booking_status_history WHERE booking_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []BookingStatusChange{}
+	for rows.Next() {
+		var (
+			h        BookingStatusChange
+			from, to string
+		)
+		if err := rows.Scan(&h.ID, &h.BookingID, &from, &to, &h.ActorID, &h.ActorRole, &h.RequestID, &h.Note, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		h.From = domain.BookingStatus(from)
+		h.To = domain.BookingStatus(to)
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// StatusTx membaca status booking di dalam transaksi tanpa mengunci baris.
+func (r BookingRepository) StatusTx(q intdb.Queryer, id int64) (domain.BookingStatus, error) {
+	return r.statusOf(q, id, false)
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// TransferCodesAvailable melaporkan apakah migration transfer_codes sudah dijalankan.
+func (r BookingRepository) TransferCodesAvailable() bool {
+	db := r.db()
+	return db != nil && intdb.HasTable(db, "transfer_codes")
+}
+
+// UsedTransferCodesTx mengembalikan kode unik yang sedang dipakai booking lain dengan nominal dasar
+// base, yaitu nominal transfer base+1 .. base+999 yang masih tercatat di transfer_codes.
+func (r BookingRepository) UsedTransferCodesTx(q intdb.Queryer, base int64) (map[int64]bool, error) {
+	used := map[int64]bool{}
+	rows, err := q.Query(`SELECT amount FROM transfer_codes WHERE amount BETWEEN ? AND ?`, base+1, base+999)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var amount int64
+		if err := rows.Scan(&amount); err != nil {
+			return nil, err
+		}
+		used[amount-base] = true
+	}
+	return used, rows.Err()
+}
+
+// ReserveTransferCodeTx memakai kode untuk booking (nominal transfer = base+code) dan menyimpannya di
+// bookings.transfer_code. taken=true bila nominal itu baru saja dipakai booking lain.
+func (r BookingRepository) ReserveTransferCodeTx(q intdb.Queryer, bookingID, base, code int64) (taken bool, err error) {
+	if _, err := q.Exec(`INSERT INTO transfer_codes (amount, booking_id, code) VALUES (?, ?, ?)`, base+code, bookingID, code); err != nil {
+		var me *mysql.MySQLError
+		if errors.As(err, &me) && me.Number == 1062 {
+			return true, nil
+		}
+		return false, err
+	}
+	_, err = q.Exec(`UPDATE bookings SET transfer_code=? WHERE id=?`, code, bookingID)
+	return false, err
+}
+
+// ReleaseTransferCodeTx melepas kode unik booking supaya bisa dipakai booking lain. Nilai
+// bookings.transfer_code tetap disimpan sebagai riwayat (invoice, rekonsiliasi).
+func (r BookingRepository) ReleaseTransferCodeTx(q intdb.Queryer, bookingID int64) error {
+	if !intdb.HasTable(q, "transfer_codes") {
+		return nil
+	}
+	_, err := q.Exec(`DELETE FROM transfer_codes WHERE booking_id=?`, bookingID)
+	return err
+}
+
+// ReservedTransferCodeTx membaca reservasi kode unik booking yang masih aktif di transfer_codes:
+// nominal transfer yang dipesan dan kodenya (0, 0 bila tidak ada / sebelum migration).
+func (r BookingRepository) ReservedTransferCodeTx(q intdb.Queryer, bookingID int64) (amount, code int64, err error) {
+	if !intdb.HasTable(q, "transfer_codes") {
+		return 0, 0, nil
+	}
+	err = q.QueryRow(`SELECT amount, code FROM transfer_codes WHERE booking_id=?`, bookingID).Scan(&amount, &code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, nil
+	}
+	return amount, code, err
+}
+
+// TransferCodeTx membaca kode unik transfer booking (0 bila tidak ada).
+func (r BookingRepository) TransferCodeTx(q intdb.Queryer, bookingID int64) (int64, error) {
+	var code int64
+	err := q.QueryRow(`SELECT COALESCE(transfer_code,0) FROM bookings WHERE id=?`, bookingID).Scan(&code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return code, err
+}
+
+// TransferCode menjalankan TransferCodeTx di luar transaksi.
+func (r BookingRepository) TransferCode(bookingID int64) (int64, error) {
+	db := r.db()
+	if db == nil {
+		return 0, fmt.Errorf("db tidak tersedia")
+	}
+	return r.TransferCodeTx(db, bookingID)
+}
+
+// releasesTransferCode: booking yang sudah lunas atau tidak aktif lagi tidak butuh kode unik.
+func releasesTransferCode(to domain.BookingStatus) bool {
+	switch to {
+	case domain.BookingPaid, domain.BookingExpired, domain.BookingCancelled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// SettingSeats adalah satu baris departure/return settings beserta kursi di booking_seats.
+type SettingSeats struct {
+	Table       string
+	ID          int64
+	BookingID   int64
+	SeatNumbers string
+	BookedSeats string // seat_code dari booking_seats, dipisah koma
+}
+
+// OrphanTripInfo adalah baris trip_information yang booking-nya sudah tidak ada.
+type OrphanTripInfo struct {
+	ID         int64
+	BookingID  int64
+	TripNumber string
+}
+
+// DepartedSetting adalah departure_settings berstatus Berangkat.
+type DepartedSetting struct {
+	ID        int64
+	BookingID int64
+}
+
+// ConsistencyRepository membaca data turunan booking (settings, penumpang, trip_information)
+// untuk mendeteksi baris yang tidak sinkron. Semua query adaptif terhadap schema lama.
+type ConsistencyRepository struct {
+	DB *sql.DB
+}
+
+func (r ConsistencyRepository) db() (*sql.DB, error) {
+	db := r.DB
+	if db == nil {
+		db = intconfig.DB
+	}
+	if db == nil || !intdb.HasTable(db, "bookings") {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	return db, nil
+}
+
+// paidFilter adalah kondisi booking lunas (termasuk yang sudah berangkat/selesai); booking lama
+// dengan booking_status kosong memakai payment_status Lunas.
+func paidFilter() string {
+	return "(b.booking_status IN ('paid', 'departed', 'completed') OR (COALESCE(b.booking_status, '') = '' AND COALESCE(b.payment_status, '') = 'Lunas'))"
+}
+
+// settingsTables mengembalikan tabel settings yang punya kolom booking_id.
+func settingsTables(db *sql.DB) []string {
+	out := []string{}
+	for _, t := range []string{"departure_settings", "return_settings"} {
+		if intdb.HasTable(db, t) && intdb.HasColumn(db, t, "booking_id") {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// hasSettingsExpr: EXISTS ke salah satu tabel settings untuk booking b.
+func hasSettingsExpr(tables []string) string {
+	parts := make([]string, len(tables))
+	for i, t := range tables {
+		parts[i] = "EXISTS (SELECT 1 FROM " + t + " s WHERE s.booking_id = b.id)"
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+func scanIDs(rows *sql.Rows) ([]int64, error) {
+	defer rows.Close()
+	out := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// PaidWithoutSettings mengembalikan booking lunas yang belum punya departure/return settings.
+func (r ConsistencyRepository) PaidWithoutSettings(limit int) ([]int64, error) {
+	db, err := r.db()
+	if err != nil {
+		return nil, err
+	}
+	tables := settingsTables(db)
+	if len(tables) == 0 {
+		return []int64{}, nil
+	}
+	rows, err := db.Query(`SELECT b.id FROM bookings b
+		WHERE `+paidFilter()+` AND NOT `+hasSettingsExpr(tables)+`
+		ORDER BY b.id ASC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+// PaidWithoutPassengers mengembalikan booking lunas yang sudah punya settings tetapi belum ada
+// baris passenger_seats (booking tanpa settings sudah dilaporkan PaidWithoutSettings).
+func (r ConsistencyRepository) PaidWithoutPassengers(limit int) ([]int64, error) {
+	db, err := r.db()
+	if err != nil {
+		return nil, err
+	}
+	tables := settingsTables(db)
+	if len(tables) == 0 || !intdb.HasTable(db, "passenger_seats") || !intdb.HasColumn(db, "passenger_seats", "booking_id") {
+		return []int64{}, nil
+	}
+	rows, err := db.Query(`SELECT b.id FROM bookings b
+		WHERE `+paidFilter()+` AND `+hasSettingsExpr(tables)+`
+			AND NOT EXISTS (SELECT 1 FROM passenger_seats p WHERE p.booking_id = b.id)
+		ORDER BY b.id ASC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+// SettingSeats mengembalikan settings (yang tidak batal) beserta kursi booking-nya untuk
+// dibandingkan dengan seat_numbers.
+func (r ConsistencyRepository) SettingSeats() ([]SettingSeats, error) {
+	db, err := r.db()
+	if err != nil {
+		return nil, err
+	}
+	out := []SettingSeats{}
+	if !intdb.HasTable(db, "booking_seats") || !intdb.HasColumn(db, "booking_seats", "seat_code") {
+		return out, nil
+	}
+	for _, table := range settingsTables(db) {
+		if !intdb.HasColumn(db, table, "seat_numbers") {
+			continue
+		}
+		active := ""
+		if intdb.HasColumn(db, table, "departure_status") {
+			active = " AND COALESCE(s.departure_status, '') <> 'Dibatalkan'"
+		}
+		rows, err := db.Query(`
+			SELECT s.id, s.booking_id, COALESCE(s.seat_numbers, ''),
+				GROUP_CONCAT(bs.seat_code ORDER BY bs.seat_code SEPARATOR ',')
+			FROM ` + table + ` s
+			JOIN booking_seats bs ON bs.booking_id = s.booking_id
+			WHERE s.booking_id > 0` + active + `
+			GROUP BY s.id, s.booking_id, s.seat_numbers
+			ORDER BY s.id ASC`)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			s := SettingSeats{Table: table}
+			if err := rows.Scan(&s.ID, &s.BookingID, &s.SeatNumbers, &s.BookedSeats); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			out = append(out, s)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// tripInfoReady memastikan trip_information punya kolom yang dipakai cek konsistensi.
+func tripInfoReady(db *sql.DB) bool {
+	if !intdb.HasTable(db, "trip_information") {
+		return false
+	}
+	for _, col := range []string{"id", "booking_id", "trip_number"} {
+		if !intdb.HasColumn(db, "trip_information", col) {
+			return false
+		}
+	}
+	return true
+}
+
+// OrphanTripInformation mengembalikan trip_information yang menunjuk booking yang sudah tidak ada.
+func (r ConsistencyRepository) OrphanTripInformation(limit int) ([]OrphanTripInfo, error) {
+	db, err := r.db()
+	if err != nil {
+		return nil, err
+	}
+	out := []OrphanTripInfo{}
+	if !tripInfoReady(db) {
+		return out, nil
+	}
+	rows, err := db.Query(`
+		SELECT t.id, t.booking_id, COALESCE(t.trip_number, '')
+		FROM trip_information t
+		LEFT JOIN bookings b ON b.id = t.booking_id
+		WHERE t.booking_id > 0 AND b.id IS NULL
+		ORDER BY t.id ASC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o OrphanTripInfo
+		if err := rows.Scan(&o.ID, &o.BookingID, &o.TripNumber); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// DepartedWithoutTripInformation mengembalikan departure_settings berstatus Berangkat yang belum
+// punya trip_information untuk booking-nya.
+func (r ConsistencyRepository) DepartedWithoutTripInformation(limit int) ([]DepartedSetting, error) {
+	db, err := r.db()
+	if err != nil {
+		return nil, err
+	}
+	out := []DepartedSetting{}
+	const table = "departure_settings"
+	if !tripInfoReady(db) || !intdb.HasTable(db, table) ||
+		!intdb.HasColumn(db, table, "booking_id") || !intdb.HasColumn(db, table, "departure_status") {
+		return out, nil
+	}
+	role := ""
+	if intdb.HasColumn(db, "trip_information", "trip_role") {
+		role = " AND (COALESCE(t.trip_role, '') = '' OR t.trip_role = 'berangkat')"
+	}
+	rows, err := db.Query(`
+		SELECT d.id, d.booking_id FROM `+table+` d
+		WHERE d.booking_id > 0 AND d.departure_status = 'Berangkat'
+			AND NOT EXISTS (SELECT 1 FROM trip_information t WHERE t.booking_id = d.booking_id`+role+`)
+		ORDER BY d.id ASC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d DepartedSetting
+		if err := rows.Scan(&d.ID, &d.BookingID); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// DeleteTripInformation menghapus satu baris trip_information yatim; booking_id ikut dicek agar
+// baris yang sudah diperbaiki di antara cek dan repair tidak ikut terhapus.
+func (r ConsistencyRepository) DeleteTripInformation(id int64) (bool, error) {
+	db, err := r.db()
+	if err != nil {
+		return false, err
+	}
+	res, err := db.Exec(`DELETE FROM trip_information
+		WHERE id = ? AND booking_id > 0 AND NOT EXISTS (SELECT 1 FROM bookings b WHERE b.id = trip_information.booking_id)`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UpdateSettingSeats menulis ulang seat_numbers (dan menaikkan passenger_count bila kurang) pada
+// satu baris departure/return settings tanpa menyentuh kolom lain seperti sopir atau status.
+func (r ConsistencyRepository) UpdateSettingSeats(table string, id int64, seats []string) error {
+	db, err := r.db()
+	if err != nil {
+		return err
+	}
+	if table != "departure_settings" && table != "return_settings" {
+		return fmt.Errorf("tabel %s bukan tabel settings", table)
+	}
+	if !intdb.HasColumn(db, table, "seat_numbers") {
+		return fmt.Errorf("schema %s belum siap: kolom seat_numbers tidak ditemukan", table)
+	}
+	sets := []string{"seat_numbers=?"}
+	args := []any{strings.Join(seats, ",")}
+	if intdb.HasColumn(db, table, "passenger_count") {
+		sets = append(sets, "passenger_count=GREATEST(COALESCE(passenger_count, 0), ?)")
+		args = append(args, len(seats))
+	}
+	if intdb.HasColumn(db, table, "updated_at") {
+		sets = append(sets, "updated_at=NOW()")
+	}
+	args = append(args, id)
+	_, err = db.Exec(`UPDATE `+table+` SET `+strings.Join(sets, ", ")+` WHERE id=?`, args...)
+	return err
+}
@@ -56,3 +56,23 @@ func TestBuildDeparturePatch_BookingIDZeroIgnored(t *testing.T) {
 		t.Fatalf("booking_id should stay the same, got %d", merged.BookingID)
 	}
 }
+
+func TestBuildDeparturePatch_DriverUserID(t *testing.T) {
+	existing := models.DepartureSetting{ID: 1, DriverName: "Budi", DriverUserID: 7, PassengerCount: "1"}
+
+	merged, presence, _, err := buildDeparturePatch(existing, []byte(`{"driverName":"Andi","driverUserId":9}`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !presence.DriverName || !presence.DriverUserID || merged.DriverUserID != 9 {
+		t.Fatalf("driver user id should follow payload, got presence=%v id=%d", presence.DriverUserID, merged.DriverUserID)
+	}
+
+	merged, presence, _, err = buildDeparturePatch(existing, []byte(`{"departure_status":"Berangkat"}`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if presence.DriverUserID || merged.DriverUserID != 7 {
+		t.Fatalf("driver user id should stay when key missing, got presence=%v id=%d", presence.DriverUserID, merged.DriverUserID)
+	}
+}
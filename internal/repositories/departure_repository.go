@@ -27,14 +27,30 @@ func (r DepartureRepository) db() *sql.DB {
 
 // GetByID loads the latest departure_settings row including optional columns.
 func (r DepartureRepository) GetByID(id int) (models.DepartureSetting, error) {
+	db := r.db()
+	if db == nil {
+		return models.DepartureSetting{}, sql.ErrNoRows
+	}
+	return r.getByID(db, id, false)
+}
+
+// GetForUpdateTx membaca departure_settings id sambil mengunci barisnya sampai tx selesai.
+func (r DepartureRepository) GetForUpdateTx(tx *sql.Tx, id int) (models.DepartureSetting, error) {
+	return r.getByID(tx, id, true)
+}
+
+func (r DepartureRepository) getByID(db intdb.Queryer, id int, lock bool) (models.DepartureSetting, error) {
 	if id <= 0 {
 		return models.DepartureSetting{}, sql.ErrNoRows
 	}
 	table := "departure_settings"
-	db := r.db()
-	if db == nil || !intdb.HasTable(db, table) {
+	if !intdb.HasTable(db, table) {
 		return models.DepartureSetting{}, sql.ErrNoRows
 	}
+	forUpdate := ""
+	if lock {
+		forUpdate = " FOR UPDATE"
+	}
 
 	var d models.DepartureSetting
 	var count int
@@ -59,8 +75,9 @@ func (r DepartureRepository) GetByID(id int) (models.DepartureSetting, error) {
 			COALESCE(trip_number,''),
 			COALESCE(booking_id,0),
 			COALESCE(departure_time,''), COALESCE(route_from,''), COALESCE(route_to,''), COALESCE(vehicle_type,''),
+			COALESCE(driver_user_id,0),
 			COALESCE(created_at,'')
-		FROM `+table+` WHERE id=? LIMIT 1`, id).Scan(
+		FROM `+table+` WHERE id=? LIMIT 1`+forUpdate, id).Scan(
 		&d.ID,
 		&d.BookingName,
 		&d.Phone,
@@ -77,6 +94,7 @@ func (r DepartureRepository) GetByID(id int) (models.DepartureSetting, error) {
 		&d.TripNumber,
 		&d.BookingID,
 		&depTime, &routeFrom, &routeTo, &vehicleType,
+		&d.DriverUserID,
 		&createdAt,
 	)
 	if err != nil {
@@ -148,6 +166,13 @@ func (r DepartureRepository) UpsertFromBooking(dep models.DepartureSetting) (mod
 	}
 	add("service_type", dep.ServiceType)
 	add("driver_name", dep.DriverName)
+	if intdb.HasColumn(db, table, "driver_user_id") {
+		if dep.DriverUserID <= 0 {
+			dep.DriverUserID = DriverUserIDByName(db, dep.DriverName)
+		}
+		cols = append(cols, "driver_user_id")
+		vals = append(vals, dep.DriverUserID)
+	}
 	add("vehicle_type", dep.VehicleType)
 	add("vehicle_code", dep.VehicleCode)
 	add("surat_jalan_file", dep.SuratJalanFile)
@@ -171,7 +196,7 @@ func (r DepartureRepository) UpsertFromBooking(dep models.DepartureSetting) (mod
 		}
 		placeholders := make([]string, len(cols))
 		for i := range placeholders {
-			if cols[i] == "booking_id" {
+			if cols[i] == "booking_id" || cols[i] == "driver_user_id" {
 				placeholders[i] = "NULLIF(?,0)"
 			} else {
 				placeholders[i] = "?"
@@ -189,7 +214,7 @@ func (r DepartureRepository) UpsertFromBooking(dep models.DepartureSetting) (mod
 		}
 		setParts := make([]string, len(cols))
 		for i, c := range cols {
-			if c == "booking_id" {
+			if c == "booking_id" || c == "driver_user_id" {
 				setParts[i] = c + "=NULLIF(?,0)"
 			} else {
 				setParts[i] = c + "=?"
@@ -209,11 +234,20 @@ func (r DepartureRepository) UpsertFromBooking(dep models.DepartureSetting) (mod
 
 // UpdatePartial applies only fields present in raw JSON (key presence), keeping existing data intact.
 func (r DepartureRepository) UpdatePartial(id int, rawJSON []byte) (models.DepartureSetting, error) {
+	db := r.db()
+	if db == nil {
+		return models.DepartureSetting{}, fmt.Errorf("tabel departure_settings tidak ditemukan")
+	}
+	return r.UpdatePartialTx(db, id, rawJSON)
+}
+
+// UpdatePartialTx sama dengan UpdatePartial di dalam transaksi pemanggil.
+func (r DepartureRepository) UpdatePartialTx(db intdb.Queryer, id int, rawJSON []byte) (models.DepartureSetting, error) {
 	if id <= 0 {
 		return models.DepartureSetting{}, sql.ErrNoRows
 	}
 
-	existing, err := r.GetByID(id)
+	existing, err := r.getByID(db, id, false)
 	if err != nil {
 		return models.DepartureSetting{}, err
 	}
@@ -224,8 +258,7 @@ func (r DepartureRepository) UpdatePartial(id int, rawJSON []byte) (models.Depar
 	}
 
 	table := "departure_settings"
-	db := r.db()
-	if db == nil || !intdb.HasTable(db, table) {
+	if !intdb.HasTable(db, table) {
 		return merged, fmt.Errorf("tabel departure_settings tidak ditemukan")
 	}
 
@@ -260,6 +293,14 @@ func (r DepartureRepository) UpdatePartial(id int, rawJSON []byte) (models.Depar
 	}
 	add(presence.ServiceType, "service_type", merged.ServiceType)
 	add(presence.DriverName, "driver_name", merged.DriverName)
+	if (presence.DriverName || presence.DriverUserID) && intdb.HasColumn(db, table, "driver_user_id") {
+		// Nama sopir berganti tanpa id eksplisit: id lama tidak lagi berlaku, cari ulang dari nama.
+		if !presence.DriverUserID {
+			merged.DriverUserID = DriverUserIDByName(db, merged.DriverName)
+		}
+		sets = append(sets, "driver_user_id=NULLIF(?,0)")
+		args = append(args, merged.DriverUserID)
+	}
 	add(presence.VehicleCode, "vehicle_code", merged.VehicleCode)
 	add(presence.VehicleType, "vehicle_type", merged.VehicleType)
 	add(presence.SuratJalanFile, "surat_jalan_file", merged.SuratJalanFile)
@@ -298,6 +339,47 @@ func (r DepartureRepository) UpdatePartial(id int, rawJSON []byte) (models.Depar
 	return merged, nil
 }
 
+// Preview menggabungkan payload ke baris id tanpa menyimpan. changed=true bila sopir, kendaraan,
+// tanggal, jam atau jalur hasil gabungan berbeda dari data tersimpan.
+func (r DepartureRepository) Preview(id int, rawJSON []byte) (models.DepartureSetting, bool, error) {
+	db := r.db()
+	if db == nil {
+		return models.DepartureSetting{}, false, sql.ErrNoRows
+	}
+	return r.PreviewTx(db, id, rawJSON)
+}
+
+// PreviewTx sama dengan Preview di dalam transaksi pemanggil (baris id biasanya sudah dikunci).
+func (r DepartureRepository) PreviewTx(db intdb.Queryer, id int, rawJSON []byte) (models.DepartureSetting, bool, error) {
+	existing, err := r.getByID(db, id, false)
+	if err != nil {
+		return models.DepartureSetting{}, false, err
+	}
+	merged, _, _, err := buildDeparturePatch(existing, rawJSON)
+	if err != nil {
+		return merged, false, err
+	}
+	merged.ID = id
+	return merged, assignmentChanged(existing, merged), nil
+}
+
+// assignmentChanged membandingkan field penugasan armada dua baris settings.
+func assignmentChanged(before, after models.DepartureSetting) bool {
+	clip := func(s string, n int) string {
+		s = strings.TrimSpace(s)
+		if len(s) > n {
+			return s[:n]
+		}
+		return s
+	}
+	return !strings.EqualFold(strings.TrimSpace(before.DriverName), strings.TrimSpace(after.DriverName)) ||
+		!strings.EqualFold(strings.TrimSpace(before.VehicleCode), strings.TrimSpace(after.VehicleCode)) ||
+		clip(before.DepartureDate, 10) != clip(after.DepartureDate, 10) ||
+		clip(before.DepartureTime, 5) != clip(after.DepartureTime, 5) ||
+		strings.TrimSpace(before.RouteFrom) != strings.TrimSpace(after.RouteFrom) ||
+		strings.TrimSpace(before.RouteTo) != strings.TrimSpace(after.RouteTo)
+}
+
 type departureFieldPresence struct {
 	BookingName        bool
 	Phone              bool
@@ -307,6 +389,7 @@ type departureFieldPresence struct {
 	PassengerCount     bool
 	ServiceType        bool
 	DriverName         bool
+	DriverUserID       bool
 	VehicleCode        bool
 	VehicleType        bool
 	SuratJalanFile     bool
@@ -391,6 +474,7 @@ func buildDeparturePatch(existing models.DepartureSetting, rawJSON []byte) (mode
 		PassengerCount:     hasField("passengercount", "passenger_count"),
 		ServiceType:        hasField("servicetype", "service_type"),
 		DriverName:         hasField("drivername", "driver_name"),
+		DriverUserID:       hasField("driveruserid", "driver_user_id"),
 		VehicleCode:        hasField("vehiclecode", "vehicle_code"),
 		VehicleType:        hasField("vehicletype", "vehicle_type"),
 		SuratJalanFile:     hasField("suratjalanfile", "surat_jalan_file"),
@@ -445,6 +529,12 @@ func buildDeparturePatch(existing models.DepartureSetting, rawJSON []byte) (mode
 			merged.DriverName = v
 		}
 	}
+	if presence.DriverUserID {
+		merged.DriverUserID = getInt64("driverUserId", "driver_user_id")
+		if merged.DriverUserID < 0 {
+			merged.DriverUserID = 0
+		}
+	}
 	if presence.VehicleCode {
 		if v := getString("vehicleCode", "vehicle_code"); v != "" {
 			merged.VehicleCode = v
@@ -510,10 +600,7 @@ func buildDeparturePatch(existing models.DepartureSetting, rawJSON []byte) (mode
 	return merged, presence, count, nil
 }
 
-func lookupVehicleTypeByDriver(db *sql.DB, driverName string) string {
-	if db == nil {
-		return ""
-	}
+func lookupVehicleTypeByDriver(db intdb.QueryRower, driverName string) string {
 	name := strings.TrimSpace(driverName)
 	if name == "" {
 		return ""
@@ -550,9 +637,62 @@ func lookupVehicleTypeByDriver(db *sql.DB, driverName string) string {
 	return ""
 }
 
+// DriverUserIDByName mencari user role driver dengan nama tersebut. Hasilnya 0 bila tidak ada
+// atau bila namanya dipakai lebih dari satu user driver, supaya otorisasi tidak salah orang.
+func DriverUserIDByName(db intdb.QueryRower, driverName string) int64 {
+	name := strings.ToLower(strings.TrimSpace(driverName))
+	if name == "" {
+		return 0
+	}
+	var id, matches int64
+	err := db.QueryRow(
+		`SELECT COALESCE(MIN(id),0), COUNT(*) FROM users WHERE LOWER(TRIM(role))='driver' AND LOWER(TRIM(name))=?`,
+		name,
+	).Scan(&id, &matches)
+	if err != nil || matches != 1 {
+		return 0
+	}
+	return id
+}
+
 func nullIfEmptyString(s string) any {
 	if strings.TrimSpace(s) == "" {
 		return nil
 	}
 	return s
 }
+
+// CancelByBookingTx mengosongkan seat di departure_settings milik booking dan menandai statusnya,
+// sehingga seat tersebut tidak lagi muncul di manifest keberangkatan.
+func (r DepartureRepository) CancelByBookingTx(tx *sql.Tx, bookingID int64, status string) error {
+	return cancelSettingsByBooking(tx, "departure_settings", bookingID, status)
+}
+
+// cancelSettingsByBooking dipakai bersama departure_settings dan return_settings.
+func cancelSettingsByBooking(tx *sql.Tx, table string, bookingID int64, status string) error {
+	if !intdb.HasTable(tx, table) || !intdb.HasColumn(tx, table, "booking_id") {
+		return nil
+	}
+	sets := []string{}
+	args := []any{}
+	for _, col := range []string{"seat_numbers", "passenger_count", "departure_status", "payment_status"} {
+		if !intdb.HasColumn(tx, table, col) {
+			continue
+		}
+		sets = append(sets, col+"=?")
+		switch col {
+		case "seat_numbers":
+			args = append(args, "")
+		case "passenger_count":
+			args = append(args, 0)
+		default:
+			args = append(args, status)
+		}
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	args = append(args, bookingID)
+	_, err := tx.Exec(`UPDATE `+table+` SET `+strings.Join(sets, ",")+` WHERE booking_id=?`, args...)
+	return err
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Fare adalah satu baris matriks tarif. Sisi origin/destination diisi Key (halte)
+// atau Cluster (kelompok halte). Category/ValidFrom/ValidTo kosong = berlaku umum.
+type Fare struct {
+	ID                 int64  `json:"id"`
+	OriginKey          string `json:"originKey"`
+	OriginCluster      string `json:"originCluster"`
+	DestinationKey     string `json:"destinationKey"`
+	DestinationCluster string `json:"destinationCluster"`
+	Category           string `json:"category"`
+	Price              int64  `json:"price"`
+	Bidirectional      bool   `json:"bidirectional"`
+	ValidFrom          string `json:"validFrom"` // YYYY-MM-DD
+	ValidTo            string `json:"validTo"`   // YYYY-MM-DD
+	IsActive           bool   `json:"isActive"`
+}
+
+type FareRepository struct {
+	DB *sql.DB
+}
+
+func (r FareRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r FareRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "fares") {
+		return nil, fmt.Errorf("tabel fares belum tersedia, jalankan `migrate up`")
+	}
+	return db, nil
+}
+
+const fareColumns = `id,
+	COALESCE(origin_key, ''), COALESCE(origin_cluster, ''),
+	COALESCE(destination_key, ''), COALESCE(destination_cluster, ''),
+	COALESCE(category, ''), price, bidirectional,
+	COALESCE(DATE_FORMAT(valid_from, '%Y-%m-%d'), ''), COALESCE(DATE_FORMAT(valid_to, '%Y-%m-%d'), ''),
+	is_active`
+
+func scanFare(sc interface{ Scan(...any) error }) (Fare, error) {
+	var f Fare
+	err := sc.Scan(&f.ID,
+		&f.OriginKey, &f.OriginCluster,
+		&f.DestinationKey, &f.DestinationCluster,
+		&f.Category, &f.Price, &f.Bidirectional,
+		&f.ValidFrom, &f.ValidTo,
+		&f.IsActive)
+	return f, err
+}
+
+// List mengembalikan seluruh tarif; activeOnly dipakai saat menghitung harga.
+func (r FareRepository) List(activeOnly bool) ([]Fare, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	q := `SELECT ` + fareColumns + ` FROM fares`
+	if activeOnly {
+		q += ` WHERE is_active = 1`
+	}
+	q += ` ORDER BY id ASC`
+
+	rows, err := db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Fare{}
+	for rows.Next() {
+		f, err := scanFare(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+func (r FareRepository) GetByID(id int64) (Fare, error) {
+	db, err := r.ready()
+	if err != nil {
+		return Fare{}, err
+	}
+	return scanFare(db.QueryRow(`SELECT `+fareColumns+` FROM fares WHERE id = ?`, id))
+}
+
+func (r FareRepository) Create(f Fare) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(`
+		INSERT INTO fares
+		(origin_key, origin_cluster, destination_key, destination_cluster, category, price, bidirectional, valid_from, valid_to, is_active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, fareArgs(f)...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r FareRepository) Update(f Fare) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	args := append(fareArgs(f), f.ID)
+	res, err := db.Exec(`
+		UPDATE fares
+		SET origin_key = ?, origin_cluster = ?, destination_key = ?, destination_cluster = ?,
+		    category = ?, price = ?, bidirectional = ?, valid_from = ?, valid_to = ?, is_active = ?
+		WHERE id = ?
+	`, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetByID(f.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r FareRepository) Delete(id int64) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`DELETE FROM fares WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CountByStopKey menghitung tarif yang masih merujuk stop_key (dipakai sebelum hapus stop).
+func (r FareRepository) CountByStopKey(key string) (int, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = db.QueryRow(`SELECT COUNT(*) FROM fares WHERE origin_key = ? OR destination_key = ?`, key, key).Scan(&n)
+	return n, err
+}
+
+func fareArgs(f Fare) []any {
+	return []any{
+		nullIfEmptyString(f.OriginKey), nullIfEmptyString(f.OriginCluster),
+		nullIfEmptyString(f.DestinationKey), nullIfEmptyString(f.DestinationCluster),
+		nullIfEmptyString(f.Category), f.Price, f.Bidirectional,
+		nullIfEmptyString(f.ValidFrom), nullIfEmptyString(f.ValidTo), f.IsActive,
+	}
+}
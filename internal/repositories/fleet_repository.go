@@ -0,0 +1,294 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Status servis kendaraan (vehicles.service_status).
+const (
+	VehicleActive   = "active"
+	VehicleService  = "service"
+	VehicleInactive = "inactive"
+)
+
+// Sumber penugasan armada yang sudah ada.
+const (
+	AssignmentDeparture = "departure"
+	AssignmentReturn    = "return"
+	AssignmentTripRun   = "trip_run"
+)
+
+// FleetVehicle adalah kendaraan yang bisa ditugaskan ke slot keberangkatan.
+type FleetVehicle struct {
+	VehicleCode   string `json:"vehicleCode"`
+	PlateNumber   string `json:"plateNumber"`
+	VehicleType   string `json:"vehicleType"`
+	ServiceStatus string `json:"serviceStatus"`
+	HomeBase      string `json:"homeBase"`
+}
+
+// FleetDriver adalah sopir yang bisa ditugaskan ke slot keberangkatan.
+type FleetDriver struct {
+	ID              int64  `json:"id"`
+	Name            string `json:"name"`
+	VehicleType     string `json:"vehicleType"`
+	VehicleAssigned string `json:"vehicleAssigned"`
+	HomeBase        string `json:"homeBase"`
+}
+
+// FleetAssignment adalah penugasan sopir/kendaraan yang sudah tercatat (departure_settings,
+// return_settings atau trip_runs) dan dipakai untuk cek bentrok jadwal.
+type FleetAssignment struct {
+	Source      string `json:"source"`
+	ID          int64  `json:"id"`
+	BookingID   int64  `json:"bookingId,omitempty"`
+	TripSlotID  int64  `json:"tripSlotId,omitempty"`
+	TripNumber  string `json:"tripNumber"`
+	DriverName  string `json:"driverName"`
+	VehicleCode string `json:"vehicleCode"`
+	Date        string `json:"date"` // YYYY-MM-DD
+	Time        string `json:"time"` // HH:MM
+	RouteFrom   string `json:"routeFrom"`
+	RouteTo     string `json:"routeTo"`
+}
+
+// SlotDeparture adalah baris departure_settings pada tanggal+jam slot.
+type SlotDeparture struct {
+	ID             int64  `json:"id"`
+	BookingID      int64  `json:"bookingId"`
+	RouteFrom      string `json:"routeFrom"`
+	RouteTo        string `json:"routeTo"`
+	PassengerCount int    `json:"passengerCount"`
+	SeatNumbers    string `json:"seatNumbers"`
+	DriverName     string `json:"driverName"`
+	VehicleCode    string `json:"vehicleCode"`
+	VehicleType    string `json:"vehicleType"`
+}
+
+// FleetRepository membaca data sopir, kendaraan dan penugasan untuk saran assignment.
+type FleetRepository struct {
+	DB *sql.DB
+}
+
+func (r FleetRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+// optionalColumn mengembalikan ekspresi kolom string, atau def bila kolom belum ada.
+func optionalColumn(db *sql.DB, table, col, def string) string {
+	if intdb.HasColumn(db, table, col) {
+		return "COALESCE(" + col + ", " + def + ")"
+	}
+	return def
+}
+
+// Vehicles mengembalikan semua kendaraan; service_status kosong dianggap active.
+func (r FleetRepository) Vehicles() ([]FleetVehicle, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "vehicles") {
+		return []FleetVehicle{}, nil
+	}
+	rows, err := db.Query(`
+		SELECT COALESCE(vehicle_code, ''), COALESCE(plate_number, ''),
+			` + optionalColumn(db, "vehicles", "vehicle_type", "''") + `,
+			` + optionalColumn(db, "vehicles", "service_status", "''") + `,
+			` + optionalColumn(db, "vehicles", "home_base", "''") + `
+		FROM vehicles
+		WHERE COALESCE(vehicle_code, '') <> ''
+		ORDER BY vehicle_code ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []FleetVehicle{}
+	for rows.Next() {
+		var v FleetVehicle
+		if err := rows.Scan(&v.VehicleCode, &v.PlateNumber, &v.VehicleType, &v.ServiceStatus, &v.HomeBase); err != nil {
+			return nil, err
+		}
+		v.ServiceStatus = strings.ToLower(strings.TrimSpace(v.ServiceStatus))
+		if v.ServiceStatus == "" {
+			v.ServiceStatus = VehicleActive
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// Drivers mengembalikan semua sopir yang punya nama.
+func (r FleetRepository) Drivers() ([]FleetDriver, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "drivers") {
+		return []FleetDriver{}, nil
+	}
+	rows, err := db.Query(`
+		SELECT id, COALESCE(name, ''), COALESCE(vehicle_type, ''), COALESCE(vehicle_assigned, ''),
+			` + optionalColumn(db, "drivers", "home_base", "''") + `
+		FROM drivers
+		WHERE COALESCE(name, '') <> ''
+		ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []FleetDriver{}
+	for rows.Next() {
+		var d FleetDriver
+		if err := rows.Scan(&d.ID, &d.Name, &d.VehicleType, &d.VehicleAssigned, &d.HomeBase); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// LockTx mengunci baris sopir (drivers.name) dan kendaraan (vehicles.vehicle_code) sampai tx
+// selesai, supaya cek bentrok + penulisan penugasan untuk sopir/kendaraan yang sama berjalan
+// bergantian. Nama kosong atau tabel yang belum ada dilewati.
+func (r FleetRepository) LockTx(q intdb.Queryer, driverName, vehicleCode string) error {
+	for _, target := range []struct{ table, col, val string }{
+		{"drivers", "name", driverName},
+		{"vehicles", "vehicle_code", vehicleCode},
+	} {
+		val := strings.TrimSpace(target.val)
+		if val == "" || !intdb.HasTable(q, target.table) {
+			continue
+		}
+		rows, err := q.Query(`SELECT id FROM `+target.table+` WHERE `+target.col+` = ? FOR UPDATE`, val)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settingsReady memastikan tabel settings punya kolom jadwal & jalur yang dibutuhkan.
+func settingsReady(db *sql.DB, table string) bool {
+	if !intdb.HasTable(db, table) {
+		return false
+	}
+	for _, col := range []string{"departure_date", "departure_time", "route_from", "route_to", "booking_id"} {
+		if !intdb.HasColumn(db, table, col) {
+			return false
+		}
+	}
+	return true
+}
+
+// Assignments mengembalikan penugasan sopir/kendaraan pada rentang tanggal [fromDate, toDate]
+// dari departure_settings, return_settings dan trip_runs (yang batal tidak dihitung).
+func (r FleetRepository) Assignments(fromDate, toDate string) ([]FleetAssignment, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	out := []FleetAssignment{}
+	for table, source := range map[string]string{"departure_settings": AssignmentDeparture, "return_settings": AssignmentReturn} {
+		if !settingsReady(db, table) {
+			continue
+		}
+		rows, err := db.Query(`
+			SELECT id, COALESCE(booking_id, 0), `+optionalColumn(db, table, "trip_number", "''")+`,
+				COALESCE(driver_name, ''), COALESCE(vehicle_code, ''),
+				LEFT(COALESCE(departure_date, ''), 10), LEFT(COALESCE(departure_time, ''), 5),
+				COALESCE(route_from, ''), COALESCE(route_to, '')
+			FROM `+table+`
+			WHERE departure_date BETWEEN ? AND ?
+				AND (COALESCE(driver_name, '') <> '' OR COALESCE(vehicle_code, '') <> '')
+				AND COALESCE(departure_status, '') <> 'Dibatalkan'`, fromDate, toDate)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			a := FleetAssignment{Source: source}
+			if err := rows.Scan(&a.ID, &a.BookingID, &a.TripNumber, &a.DriverName, &a.VehicleCode, &a.Date, &a.Time, &a.RouteFrom, &a.RouteTo); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			out = append(out, a)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !(TripRunRepository{DB: db}).Available() {
+		return out, nil
+	}
+	rows, err := db.Query(`
+		SELECT id, COALESCE(trip_slot_id, 0), trip_number, driver_name, vehicle_code, DATE_FORMAT(trip_date, '%Y-%m-%d'), trip_time, route_from, route_to
+		FROM trip_runs
+		WHERE trip_date BETWEEN ? AND ? AND status <> ? AND (driver_name <> '' OR vehicle_code <> '')`,
+		fromDate, toDate, TripRunCancelled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a := FleetAssignment{Source: AssignmentTripRun}
+		if err := rows.Scan(&a.ID, &a.TripSlotID, &a.TripNumber, &a.DriverName, &a.VehicleCode, &a.Date, &a.Time, &a.RouteFrom, &a.RouteTo); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// SlotDepartures mengembalikan departure_settings (yang tidak batal) pada tanggal + jam (HH:MM).
+func (r FleetRepository) SlotDepartures(date, clock string) ([]SlotDeparture, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	const table = "departure_settings"
+	if !settingsReady(db, table) {
+		return []SlotDeparture{}, nil
+	}
+	rows, err := db.Query(`
+		SELECT id, COALESCE(booking_id, 0), COALESCE(route_from, ''), COALESCE(route_to, ''),
+			COALESCE(passenger_count, 0), COALESCE(seat_numbers, ''), COALESCE(driver_name, ''), COALESCE(vehicle_code, ''),
+			`+optionalColumn(db, table, "vehicle_type", "''")+`
+		FROM `+table+`
+		WHERE departure_date = ? AND LEFT(departure_time, 5) = ? AND COALESCE(departure_status, '') <> 'Dibatalkan'
+		ORDER BY id ASC`, date, clock)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SlotDeparture{}
+	for rows.Next() {
+		var d SlotDeparture
+		if err := rows.Scan(&d.ID, &d.BookingID, &d.RouteFrom, &d.RouteTo, &d.PassengerCount, &d.SeatNumbers,
+			&d.DriverName, &d.VehicleCode, &d.VehicleType); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
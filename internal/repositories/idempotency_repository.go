@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Status baris idempotency_keys.
+const (
+	IdempotencyProcessing = "processing"
+	IdempotencyDone       = "done"
+)
+
+// IdempotencyKey adalah satu Idempotency-Key beserta respons yang disimpan untuk replay.
+type IdempotencyKey struct {
+	ID             int64
+	Scope          string
+	Key            string
+	RequestHash    string
+	Status         string
+	LockedUntil    time.Time // lease status processing; zero = tanpa lease (schema lama)
+	ResponseStatus int
+	ContentType    string
+	ResponseBody   []byte
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+}
+
+type IdempotencyRepository struct {
+	DB *sql.DB
+}
+
+func (r IdempotencyRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r IdempotencyRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "idempotency_keys") {
+		return nil, fmt.Errorf("tabel idempotency_keys belum tersedia, jalankan `migrate up`")
+	}
+	return db, nil
+}
+
+// Available melaporkan apakah migration idempotency_keys sudah dijalankan.
+func (r IdempotencyRepository) Available() bool {
+	_, err := r.ready()
+	return err == nil
+}
+
+// Insert mencadangkan key baru dengan status processing sampai k.LockedUntil; key yang sudah ada
+// menghasilkan error duplicate (1062).
+func (r IdempotencyRepository) Insert(k IdempotencyKey) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO idempotency_keys (scope, idem_key, request_hash, status, locked_until, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, k.Scope, k.Key, k.RequestHash, IdempotencyProcessing, k.LockedUntil, k.ExpiresAt)
+	return err
+}
+
+func (r IdempotencyRepository) Get(scope, key string) (IdempotencyKey, error) {
+	db, err := r.ready()
+	if err != nil {
+		return IdempotencyKey{}, err
+	}
+	var (
+		k      IdempotencyKey
+		locked sql.NullTime
+	)
+	err = db.QueryRow(`
+		SELECT id, scope, idem_key, request_hash, status, locked_until, response_status, content_type, response_body, created_at, expires_at
+		FROM idempotency_keys WHERE scope = ? AND idem_key = ? LIMIT 1`, scope, key).
+		Scan(&k.ID, &k.Scope, &k.Key, &k.RequestHash, &k.Status, &locked, &k.ResponseStatus, &k.ContentType, &k.ResponseBody, &k.CreatedAt, &k.ExpiresAt)
+	if locked.Valid {
+		k.LockedUntil = locked.Time
+	}
+	return k, err
+}
+
+// Complete menyimpan respons final untuk key yang sedang diproses.
+func (r IdempotencyRepository) Complete(scope, key string, status int, contentType string, body []byte) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		UPDATE idempotency_keys SET status = ?, response_status = ?, content_type = ?, response_body = ?
+		WHERE scope = ? AND idem_key = ?`, IdempotencyDone, status, contentType, body, scope, key)
+	return err
+}
+
+// Delete melepas key (mis. handler gagal 5xx) supaya retry berikutnya diproses ulang.
+func (r IdempotencyRepository) Delete(scope, key string) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM idempotency_keys WHERE scope = ? AND idem_key = ?`, scope, key)
+	return err
+}
+
+// DeleteStale melepas key processing yang lease-nya sudah habis per now. Key yang sudah selesai
+// atau baru dicadangkan ulang request lain (lease masih berlaku) tidak ikut terhapus.
+func (r IdempotencyRepository) DeleteStale(scope, key string, now time.Time) (bool, error) {
+	db, err := r.ready()
+	if err != nil {
+		return false, err
+	}
+	res, err := db.Exec(`
+		DELETE FROM idempotency_keys
+		WHERE scope = ? AND idem_key = ? AND status = ? AND locked_until IS NOT NULL AND locked_until <= ?`,
+		scope, key, IdempotencyProcessing, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteExpired membersihkan key yang sudah lewat masa berlakunya.
+func (r IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,260 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Status event outbox.
+const (
+	OutboxPending    = "pending"
+	OutboxProcessing = "processing"
+	OutboxDone       = "done"
+	OutboxDead       = "dead"
+)
+
+// Jenis event outbox.
+const (
+	// EventBookingPaid: buat/rapikan departure atau return settings dari booking lunas lalu sinkron penumpang.
+	EventBookingPaid = "booking_paid"
+	// EventPassengerSync: sinkron penumpang dari departure_settings yang berangkat.
+	EventPassengerSync = "passenger_sync"
+	// EventTripInfoUpsert: upsert trip_information dari departure_settings yang berangkat.
+	EventTripInfoUpsert = "trip_info_upsert"
+	// EventReturnSync: sinkron penumpang & trip_information dari return_settings yang pulang.
+	EventReturnSync = "return_sync"
+)
+
+// BookingPaidPayload adalah payload EventBookingPaid.
+type BookingPaidPayload struct {
+	BookingID int64  `json:"bookingId"`
+	TripRole  string `json:"tripRole"`
+}
+
+// DepartureSyncPayload adalah payload EventPassengerSync dan EventTripInfoUpsert.
+type DepartureSyncPayload struct {
+	DepartureID int `json:"departureId"`
+}
+
+// ReturnSyncPayload adalah payload EventReturnSync.
+type ReturnSyncPayload struct {
+	ReturnID int `json:"returnId"`
+}
+
+// OutboxEvent adalah satu event sinkronisasi yang menunggu / sudah diproses worker.
+type OutboxEvent struct {
+	ID          int64           `json:"id"`
+	EventType   string          `json:"eventType"`
+	AggregateID int64           `json:"aggregateId"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	LastError   string          `json:"lastError,omitempty"`
+	RequestID   string          `json:"requestId,omitempty"`
+	AvailableAt time.Time       `json:"availableAt"`
+	ProcessedAt *time.Time      `json:"processedAt,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+type OutboxRepository struct {
+	DB *sql.DB
+}
+
+func (r OutboxRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r OutboxRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "outbox_events") {
+		return nil, fmt.Errorf("tabel outbox_events belum tersedia, jalankan `migrate up`")
+	}
+	return db, nil
+}
+
+// Available melaporkan apakah migration outbox_events sudah dijalankan.
+func (r OutboxRepository) Available() bool {
+	_, err := r.ready()
+	return err == nil
+}
+
+// EnqueueTx mencatat event baru di transaksi pemanggil (atau langsung di db) supaya event hanya
+// ada bila perubahan statusnya ikut tersimpan.
+func (r OutboxRepository) EnqueueTx(q intdb.Queryer, eventType string, aggregateID int64, payload any, requestID string) (int64, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	res, err := q.Exec(`
+		INSERT INTO outbox_events (event_type, aggregate_id, payload, status, request_id, available_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		eventType, aggregateID, string(body), OutboxPending, truncate(requestID, 64), time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Enqueue sama dengan EnqueueTx tanpa transaksi.
+func (r OutboxRepository) Enqueue(eventType string, aggregateID int64, payload any, requestID string) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	return r.EnqueueTx(db, eventType, aggregateID, payload, requestID)
+}
+
+const outboxColumns = `id, event_type, aggregate_id, payload, status, attempts, last_error, request_id,
+	available_at, processed_at, created_at`
+
+func scanOutboxEvent(sc interface{ Scan(...any) error }) (OutboxEvent, error) {
+	var (
+		e         OutboxEvent
+		payload   string
+		processed sql.NullTime
+	)
+	if err := sc.Scan(&e.ID, &e.EventType, &e.AggregateID, &payload, &e.Status, &e.Attempts, &e.LastError, &e.RequestID,
+		&e.AvailableAt, &processed, &e.CreatedAt); err != nil {
+		return OutboxEvent{}, err
+	}
+	e.Payload = json.RawMessage(payload)
+	if processed.Valid {
+		t := processed.Time
+		e.ProcessedAt = &t
+	}
+	return e, nil
+}
+
+// Claim mengambil sampai batch event yang jatuh tempo (pending, atau processing yang lock-nya
+// lebih lama dari lease karena worker mati) lalu menandainya processing dan menambah attempts.
+func (r OutboxRepository) Claim(now time.Time, lease time.Duration, batch int) ([]OutboxEvent, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	if batch <= 0 {
+		batch = 20
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.Query(`SELECT `+outboxColumns+` FROM outbox_events
+		WHERE (status = ? AND available_at <= ?) OR (status = ? AND locked_at <= ?)
+		ORDER BY id ASC LIMIT ? FOR UPDATE`,
+		OutboxPending, now, OutboxProcessing, now.Add(-lease), batch)
+	if err != nil {
+		return nil, err
+	}
+	out := []OutboxEvent{}
+	for rows.Next() {
+		e, err := scanOutboxEvent(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		if _, err := tx.Exec(`UPDATE outbox_events SET status=?, attempts=attempts+1, locked_at=? WHERE id=?`,
+			OutboxProcessing, now, out[i].ID); err != nil {
+			return nil, err
+		}
+		out[i].Status = OutboxProcessing
+		out[i].Attempts++
+	}
+	return out, tx.Commit()
+}
+
+// MarkDone menandai event selesai diproses.
+func (r OutboxRepository) MarkDone(id int64, at time.Time) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE outbox_events SET status=?, last_error='', locked_at=NULL, processed_at=? WHERE id=?`,
+		OutboxDone, at, id)
+	return err
+}
+
+// MarkFailed mencatat error percobaan terakhir: status pending dengan available_at berikutnya
+// untuk retry, atau dead bila percobaan sudah habis.
+func (r OutboxRepository) MarkFailed(id int64, status string, lastErr string, availableAt time.Time) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE outbox_events SET status=?, last_error=?, available_at=?, locked_at=NULL WHERE id=?`,
+		status, truncate(strings.TrimSpace(lastErr), 500), availableAt, id)
+	return err
+}
+
+// Replay mengembalikan event ke antrean (pending, attempts 0) agar segera diproses ulang.
+func (r OutboxRepository) Replay(id int64, at time.Time) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE outbox_events SET status=?, attempts=0, available_at=?, locked_at=NULL WHERE id=?`,
+		OutboxPending, at, id)
+	return err
+}
+
+func (r OutboxRepository) GetByID(id int64) (OutboxEvent, error) {
+	db, err := r.ready()
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+	return scanOutboxEvent(db.QueryRow(`SELECT `+outboxColumns+` FROM outbox_events WHERE id=?`, id))
+}
+
+// List mengembalikan event terbaru, opsional disaring status.
+func (r OutboxRepository) List(status string, limit int) ([]OutboxEvent, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 200
+	}
+	query := `SELECT ` + outboxColumns + ` FROM outbox_events`
+	args := []any{}
+	if s := strings.TrimSpace(status); s != "" {
+		query += ` WHERE status = ?`
+		args = append(args, s)
+	}
+	args = append(args, limit)
+	rows, err := db.Query(query+` ORDER BY id DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []OutboxEvent{}
+	for rows.Next() {
+		e, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
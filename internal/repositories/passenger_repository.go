@@ -424,3 +424,51 @@ func nullableString(s string) any {
 	}
 	return strings.TrimSpace(s)
 }
+
+// DeleteByBookingTx menghapus baris penumpang per-seat milik booking dari passengers
+// dan passenger_seats (manifest), dipakai saat booking dibatalkan.
+func (r PassengerRepository) DeleteByBookingTx(tx *sql.Tx, bookingID int64) error {
+	for _, table := range []string{"passengers", "passenger_seats"} {
+		if !intdb.HasTable(tx, table) || !intdb.HasColumn(tx, table, "booking_id") {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE booking_id=?`, bookingID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemapSeatsTx memindahkan data penumpang booking dari seat lama ke seat baru (moves[lama] = baru)
+// di booking_passengers, passenger_seats dan passengers, sehingga baris yang sama dipakai ulang
+// setelah reschedule. Seat diganti lewat nama sementara agar pertukaran seat tidak bentrok unique key.
+func (r PassengerRepository) RemapSeatsTx(tx *sql.Tx, bookingID int64, moves map[string]string) error {
+	if len(moves) == 0 {
+		return nil
+	}
+	targets := [][2]string{
+		{"booking_passengers", "seat_code"},
+		{"passenger_seats", "seat_code"},
+		{"passenger_seats", "selected_seats"},
+		{"passengers", "selected_seats"},
+	}
+	done := map[string]bool{}
+	for _, t := range targets {
+		table, col := t[0], t[1]
+		if done[table] || !intdb.HasTable(tx, table) || !intdb.HasColumn(tx, table, "booking_id") || !intdb.HasColumn(tx, table, col) {
+			continue
+		}
+		done[table] = true
+		for from := range moves {
+			if _, err := tx.Exec(`UPDATE `+table+` SET `+col+`=CONCAT('~', `+col+`) WHERE booking_id=? AND `+col+`=?`, bookingID, from); err != nil {
+				return err
+			}
+		}
+		for from, to := range moves {
+			if _, err := tx.Exec(`UPDATE `+table+` SET `+col+`=? WHERE booking_id=? AND `+col+`=?`, to, bookingID, "~"+from); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Status tagihan payment gateway.
+const (
+	ChargePending = "pending"
+	ChargePaid    = "paid"
+	ChargeExpired = "expired"
+	ChargeFailed  = "failed"
+)
+
+// PaymentCharge adalah satu tagihan QRIS / virtual account di payment gateway.
+type PaymentCharge struct {
+	ID          int64      `json:"id"`
+	BookingID   int64      `json:"bookingId"`
+	Provider    string     `json:"provider"`
+	Reference   string     `json:"reference"`
+	ProviderRef string     `json:"providerRef,omitempty"`
+	Method      string     `json:"method"`
+	Bank        string     `json:"bank,omitempty"`
+	Amount      int64      `json:"amount"`
+	QRString    string     `json:"qrString,omitempty"`
+	VANumber    string     `json:"vaNumber,omitempty"`
+	Status      string     `json:"status"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	PaidAt      *time.Time `json:"paidAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type PaymentChargeRepository struct {
+	DB *sql.DB
+}
+
+func (r PaymentChargeRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r PaymentChargeRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "payment_charges") {
+		return nil, fmt.Errorf("tabel payment_charges belum tersedia, jalankan `migrate up`")
+	}
+	return db, nil
+}
+
+// Available melaporkan apakah migration payment_charges sudah dijalankan.
+func (r PaymentChargeRepository) Available() bool {
+	_, err := r.ready()
+	return err == nil
+}
+
+const paymentChargeColumns = `id, booking_id, provider, reference, provider_ref, method, bank, amount,
+	COALESCE(qr_string,''), va_number, status, expires_at, paid_at, created_at`
+
+func scanPaymentCharge(sc interface{ Scan(...any) error }) (PaymentCharge, error) {
+	var (
+		ch            PaymentCharge
+		expires, paid sql.NullTime
+	)
+	if err := sc.Scan(&ch.ID, &ch.BookingID, &ch.Provider, &ch.Reference, &ch.ProviderRef, &ch.Method, &ch.Bank, &ch.Amount,
+		&ch.QRString, &ch.VANumber, &ch.Status, &expires, &paid, &ch.CreatedAt); err != nil {
+		return PaymentCharge{}, err
+	}
+	if expires.Valid {
+		t := expires.Time
+		ch.ExpiresAt = &t
+	}
+	if paid.Valid {
+		t := paid.Time
+		ch.PaidAt = &t
+	}
+	return ch, nil
+}
+
+func (r PaymentChargeRepository) Insert(ch PaymentCharge) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(`
+		INSERT INTO payment_charges (booking_id, provider, reference, provider_ref, method, bank, amount, qr_string, va_number, status, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ch.BookingID, ch.Provider, ch.Reference, ch.ProviderRef, ch.Method, ch.Bank, ch.Amount, ch.QRString, ch.VANumber, ch.Status, ch.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r PaymentChargeRepository) GetByReference(reference string) (PaymentCharge, error) {
+	db, err := r.ready()
+	if err != nil {
+		return PaymentCharge{}, err
+	}
+	return scanPaymentCharge(db.QueryRow(`SELECT `+paymentChargeColumns+` FROM payment_charges WHERE reference = ? LIMIT 1`, reference))
+}
+
+// GetByReferenceForUpdateTx membaca tagihan dan menguncinya sampai transaksi selesai, supaya
+// webhook yang sama yang datang bersamaan diproses bergantian.
+func (r PaymentChargeRepository) GetByReferenceForUpdateTx(q intdb.Queryer, reference string) (PaymentCharge, error) {
+	return scanPaymentCharge(q.QueryRow(`SELECT `+paymentChargeColumns+` FROM payment_charges WHERE reference = ? LIMIT 1 FOR UPDATE`, reference))
+}
+
+// ActiveForBooking mengembalikan tagihan pending terbaru yang belum lewat expires_at.
+func (r PaymentChargeRepository) ActiveForBooking(bookingID int64, now time.Time) (PaymentCharge, error) {
+	db, err := r.ready()
+	if err != nil {
+		return PaymentCharge{}, err
+	}
+	return scanPaymentCharge(db.QueryRow(`
+		SELECT `+paymentChargeColumns+` FROM payment_charges
+		WHERE booking_id = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY id DESC LIMIT 1`, bookingID, ChargePending, now))
+}
+
+// UpdateStatus menandai tagihan dari webhook; paidAt hanya diisi untuk status paid.
+func (r PaymentChargeRepository) UpdateStatus(id int64, status string, paidAt *time.Time) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	return r.UpdateStatusTx(db, id, status, paidAt)
+}
+
+// UpdateStatusTx sama dengan UpdateStatus di dalam transaksi yang sedang berjalan.
+func (r PaymentChargeRepository) UpdateStatusTx(q intdb.Queryer, id int64, status string, paidAt *time.Time) error {
+	_, err := q.Exec(`UPDATE payment_charges SET status = ?, paid_at = COALESCE(?, paid_at) WHERE id = ?`, status, paidAt, id)
+	return err
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Status entri ledger pembayaran.
+const (
+	LedgerPending   = "pending"
+	LedgerApproved  = "approved"
+	LedgerRejected  = "rejected"
+	LedgerCancelled = "cancelled"
+)
+
+// LedgerPayment adalah satu pembayaran yang diterima untuk booking (DP, pelunasan, transfer, gateway).
+type LedgerPayment struct {
+	ID           int64      `json:"id"`
+	BookingID    int64      `json:"bookingId"`
+	Amount       int64      `json:"amount"`
+	Method       string     `json:"method"`
+	Status       string     `json:"status"`
+	ReceivedBy   int64      `json:"receivedBy,omitempty"`
+	ValidationID int64      `json:"validationId,omitempty"`
+	Reference    string     `json:"reference,omitempty"`
+	Note         string     `json:"note,omitempty"`
+	ValidatedAt  *time.Time `json:"validatedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+type PaymentLedgerRepository struct {
+	DB *sql.DB
+}
+
+func (r PaymentLedgerRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+// Available melaporkan apakah migration payments sudah dijalankan.
+func (r PaymentLedgerRepository) Available() bool {
+	db := r.db()
+	return db != nil && intdb.HasTable(db, "payments")
+}
+
+// InsertTx mencatat pembayaran baru dan mengembalikan id-nya.
+func (r PaymentLedgerRepository) InsertTx(q intdb.Queryer, p LedgerPayment) (int64, error) {
+	if !intdb.HasTable(q, "payments") {
+		return 0, fmt.Errorf("tabel payments belum tersedia, jalankan `migrate up`")
+	}
+	res, err := q.Exec(`
+		INSERT INTO payments (booking_id, amount, method, status, received_by, validation_id, reference, note, validated_at, created_at)
+		VALUES (?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), ?, ?, ?, ?)`,
+		p.BookingID, p.Amount, strings.TrimSpace(p.Method), p.Status, p.ReceivedBy, p.ValidationID,
+		strings.TrimSpace(p.Reference), strings.TrimSpace(p.Note), p.ValidatedAt, p.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ListTx mengembalikan ledger booking, terlama lebih dulu. Kosong bila tabel belum ada.
+func (r PaymentLedgerRepository) ListTx(q intdb.Queryer, bookingID int64) ([]LedgerPayment, error) {
+	if !intdb.HasTable(q, "payments") {
+		return nil, nil
+	}
+	rows, err := q.Query(`
+		SELECT id, booking_id, amount, method, status, COALESCE(received_by,0), COALESCE(validation_id,0),
+		       reference, note, validated_at, created_at
+		FROM payments WHERE booking_id=? ORDER BY id`, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []LedgerPayment{}
+	for rows.Next() {
+		var (
+			p         LedgerPayment
+			validated sql.NullTime
+		)
+		if err := rows.Scan(&p.ID, &p.BookingID, &p.Amount, &p.Method, &p.Status, &p.ReceivedBy, &p.ValidationID,
+			&p.Reference, &p.Note, &validated, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		if validated.Valid {
+			t := validated.Time
+			p.ValidatedAt = &t
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// List menjalankan ListTx di luar transaksi.
+func (r PaymentLedgerRepository) List(bookingID int64) ([]LedgerPayment, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	return r.ListTx(db, bookingID)
+}
+
+// SetValidationStatusTx memperbarui status entri pending yang berasal dari payment_validations id.
+// Mengembalikan jumlah entri yang berubah.
+func (r PaymentLedgerRepository) SetValidationStatusTx(q intdb.Queryer, validationID int64, status string, at time.Time) (int64, error) {
+	if !intdb.HasTable(q, "payments") {
+		return 0, nil
+	}
+	res, err := q.Exec(`UPDATE payments SET status=?, validated_at=? WHERE validation_id=? AND status=?`,
+		status, at, validationID, LedgerPending)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// BookingTotalTx membaca total tagihan booking.
+func (r PaymentLedgerRepository) BookingTotalTx(q intdb.Queryer, bookingID int64) (int64, error) {
+	if !intdb.HasColumn(q, "bookings", "total") {
+		return 0, nil
+	}
+	var total int64
+	err := q.QueryRow(`SELECT COALESCE(total,0) FROM bookings WHERE id=?`, bookingID).Scan(&total)
+	return total, err
+}
@@ -134,12 +134,20 @@ func (r PaymentRepository) GetByBookingID(bookingID int64) (models.PaymentValida
 
 // CreateOrUpdateValidation inserts/updates payment_validations by booking_id using key presence.
 func (r PaymentRepository) CreateOrUpdateValidation(bookingID int64, raw json.RawMessage) error {
+	db := r.db()
+	if db == nil {
+		return fmt.Errorf("tabel payment_validations tidak ditemukan")
+	}
+	return r.CreateOrUpdateValidationTx(db, bookingID, raw)
+}
+
+// CreateOrUpdateValidationTx sama dengan CreateOrUpdateValidation di dalam transaksi pemanggil.
+func (r PaymentRepository) CreateOrUpdateValidationTx(db intdb.Queryer, bookingID int64, raw json.RawMessage) error {
 	if bookingID <= 0 {
 		return fmt.Errorf("booking_id tidak valid")
 	}
-	db := r.db()
 	table := r.table()
-	if db == nil || !intdb.HasTable(db, table) {
+	if !intdb.HasTable(db, table) {
 		return fmt.Errorf("tabel payment_validations tidak ditemukan")
 	}
 
@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Status refund.
+const (
+	RefundPending   = "pending"
+	RefundProcessed = "processed"
+	RefundRejected  = "rejected"
+)
+
+// Refund adalah dana yang harus dikembalikan ke customer setelah booking dibatalkan.
+type Refund struct {
+	ID          int64      `json:"id"`
+	BookingID   int64      `json:"bookingId"`
+	PaidAmount  int64      `json:"paidAmount"`
+	Fee         int64      `json:"fee"`
+	Amount      int64      `json:"amount"`
+	Method      string     `json:"method"`
+	Status      string     `json:"status"`
+	Reason      string     `json:"reason"`
+	Note        string     `json:"note"`
+	RequestedBy int64      `json:"requestedBy,omitempty"`
+	ProcessedAt *time.Time `json:"processedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type RefundRepository struct {
+	DB *sql.DB
+}
+
+func (r RefundRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r RefundRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "refunds") {
+		return nil, fmt.Errorf("tabel refunds belum tersedia, jalankan `migrate up`")
+	}
+	return db, nil
+}
+
+// Available melaporkan apakah migration refunds sudah dijalankan.
+func (r RefundRepository) Available() bool {
+	_, err := r.ready()
+	return err == nil
+}
+
+const refundColumns = `id, booking_id, paid_amount, fee, amount, method, status, reason, note,
+	COALESCE(requested_by, 0), processed_at, created_at`
+
+func scanRefund(sc interface{ Scan(...any) error }) (Refund, error) {
+	var (
+		rf        Refund
+		processed sql.NullTime
+	)
+	if err := sc.Scan(&rf.ID, &rf.BookingID, &rf.PaidAmount, &rf.Fee, &rf.Amount, &rf.Method, &rf.Status,
+		&rf.Reason, &rf.Note, &rf.RequestedBy, &processed, &rf.CreatedAt); err != nil {
+		return Refund{}, err
+	}
+	if processed.Valid {
+		t := processed.Time
+		rf.ProcessedAt = &t
+	}
+	return rf, nil
+}
+
+// InsertTx mencatat refund di dalam transaksi pembatalan.
+func (r RefundRepository) InsertTx(tx *sql.Tx, rf Refund) (int64, error) {
+	if !intdb.HasTable(tx, "refunds") {
+		return 0, fmt.Errorf("tabel refunds belum tersedia, jalankan `migrate up`")
+	}
+	res, err := tx.Exec(`
+		INSERT INTO refunds (booking_id, paid_amount, fee, amount, method, status, reason, requested_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0))`,
+		rf.BookingID, rf.PaidAmount, rf.Fee, rf.Amount, rf.Method, rf.Status, rf.Reason, rf.RequestedBy)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// List mengembalikan refund terbaru lebih dulu; status kosong = semua.
+func (r RefundRepository) List(status string) ([]Refund, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + refundColumns + ` FROM refunds`
+	args := []any{}
+	if s := strings.TrimSpace(status); s != "" {
+		query += ` WHERE status = ?`
+		args = append(args, s)
+	}
+	rows, err := db.Query(query+` ORDER BY id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Refund{}
+	for rows.Next() {
+		rf, err := scanRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rf)
+	}
+	return out, rows.Err()
+}
+
+func (r RefundRepository) GetByID(id int64) (Refund, error) {
+	db, err := r.ready()
+	if err != nil {
+		return Refund{}, err
+	}
+	return scanRefund(db.QueryRow(`SELECT `+refundColumns+` FROM refunds WHERE id = ?`, id))
+}
+
+// ListByBooking mengembalikan refund milik satu booking (kosong bila tabel belum ada).
+func (r RefundRepository) ListByBooking(bookingID int64) ([]Refund, error) {
+	db, err := r.ready()
+	if err != nil {
+		return []Refund{}, nil
+	}
+	rows, err := db.Query(`SELECT `+refundColumns+` FROM refunds WHERE booking_id = ? ORDER BY id ASC`, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Refund{}
+	for rows.Next() {
+		rf, err := scanRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rf)
+	}
+	return out, rows.Err()
+}
+
+// UpdateStatus mengubah status refund; processed_at diisi saat status final.
+func (r RefundRepository) UpdateStatus(id int64, status, note string, at time.Time) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	var processed any
+	if status != RefundPending {
+		processed = at
+	}
+	res, err := db.Exec(`UPDATE refunds SET status = ?, note = ?, processed_at = ? WHERE id = ?`, status, note, processed, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Jenis dan status penyesuaian tarif reschedule.
+const (
+	AdjustmentNone       = "none"
+	AdjustmentSupplement = "supplement"
+	AdjustmentCredit     = "credit"
+
+	AdjustmentPending = "pending"
+	AdjustmentSettled = "settled"
+	AdjustmentWaived  = "waived"
+)
+
+// Reschedule adalah satu perpindahan booking ke slot/seat lain.
+type Reschedule struct {
+	ID               int64      `json:"id"`
+	BookingID        int64      `json:"bookingId"`
+	OldFrom          string     `json:"oldFrom"`
+	OldTo            string     `json:"oldTo"`
+	OldDate          string     `json:"oldDate"`
+	OldTime          string     `json:"oldTime"`
+	OldSeats         []string   `json:"oldSeats"`
+	OldTotal         int64      `json:"oldTotal"`
+	NewFrom          string     `json:"newFrom"`
+	NewTo            string     `json:"newTo"`
+	NewDate          string     `json:"newDate"`
+	NewTime          string     `json:"newTime"`
+	NewSeats         []string   `json:"newSeats"`
+	NewTotal         int64      `json:"newTotal"`
+	Adjustment       string     `json:"adjustment"`
+	AdjustmentAmount int64      `json:"adjustmentAmount"`
+	AdjustmentStatus string     `json:"adjustmentStatus"`
+	Reason           string     `json:"reason"`
+	Note             string     `json:"note"`
+	RequestedBy      int64      `json:"requestedBy,omitempty"`
+	SettledAt        *time.Time `json:"settledAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
+
+type RescheduleRepository struct {
+	DB *sql.DB
+}
+
+func (r RescheduleRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r RescheduleRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "booking_reschedules") {
+		return nil, fmt.Errorf("tabel booking_reschedules belum tersedia, jalankan `migrate up`")
+	}
+	return db, nil
+}
+
+// Available melaporkan apakah migration booking_reschedules sudah dijalankan.
+func (r RescheduleRepository) Available() bool {
+	_, err := r.ready()
+	return err == nil
+}
+
+const rescheduleColumns = `id, booking_id,
+	old_route_from, old_route_to, DATE_FORMAT(old_trip_date, '%Y-%m-%d'), old_trip_time, old_seats, old_total,
+	new_route_from, new_route_to, DATE_FORMAT(new_trip_date, '%Y-%m-%d'), new_trip_time, new_seats, new_total,
+	adjustment, adjustment_amount, adjustment_status, reason, note, COALESCE(requested_by, 0), settled_at, created_at`
+
+func scanReschedule(sc interface{ Scan(...any) error }) (Reschedule, error) {
+	var (
+		rs                 Reschedule
+		oldSeats, newSeats string
+		settled            sql.NullTime
+	)
+	if err := sc.Scan(&rs.ID, &rs.BookingID,
+		&rs.OldFrom, &rs.OldTo, &rs.OldDate, &rs.OldTime, &oldSeats, &rs.OldTotal,
+		&rs.NewFrom, &rs.NewTo, &rs.NewDate, &rs.NewTime, &newSeats, &rs.NewTotal,
+		&rs.Adjustment, &rs.AdjustmentAmount, &rs.AdjustmentStatus, &rs.Reason, &rs.Note, &rs.RequestedBy,
+		&settled, &rs.CreatedAt); err != nil {
+		return Reschedule{}, err
+	}
+	rs.OldSeats = splitAliases(oldSeats)
+	rs.NewSeats = splitAliases(newSeats)
+	if settled.Valid {
+		t := settled.Time
+		rs.SettledAt = &t
+	}
+	return rs, nil
+}
+
+// InsertTx mencatat reschedule di dalam transaksi pemindahan seat.
+func (r RescheduleRepository) InsertTx(tx *sql.Tx, rs Reschedule) (int64, error) {
+	if !intdb.HasTable(tx, "booking_reschedules") {
+		return 0, fmt.Errorf("tabel booking_reschedules belum tersedia, jalankan `migrate up`")
+	}
+	res, err := tx.Exec(`
+		INSERT INTO booking_reschedules (booking_id,
+			old_route_from, old_route_to, old_trip_date, old_trip_time, old_seats, old_total,
+			new_route_from, new_route_to, new_trip_date, new_trip_time, new_seats, new_total,
+			adjustment, adjustment_amount, adjustment_status, reason, requested_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0))`,
+		rs.BookingID,
+		rs.OldFrom, rs.OldTo, rs.OldDate, rs.OldTime, strings.Join(rs.OldSeats, ","), rs.OldTotal,
+		rs.NewFrom, rs.NewTo, rs.NewDate, rs.NewTime, strings.Join(rs.NewSeats, ","), rs.NewTotal,
+		rs.Adjustment, rs.AdjustmentAmount, rs.AdjustmentStatus, rs.Reason, rs.RequestedBy)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// List mengembalikan reschedule terbaru lebih dulu; adjustmentStatus kosong = semua.
+func (r RescheduleRepository) List(adjustmentStatus string) ([]Reschedule, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + rescheduleColumns + ` FROM booking_reschedules`
+	args := []any{}
+	if s := strings.TrimSpace(adjustmentStatus); s != "" {
+		query += ` WHERE adjustment_status = ?`
+		args = append(args, s)
+	}
+	rows, err := db.Query(query+` ORDER BY id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Reschedule{}
+	for rows.Next() {
+		rs, err := scanReschedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rs)
+	}
+	return out, rows.Err()
+}
+
+func (r RescheduleRepository) GetByID(id int64) (Reschedule, error) {
+	db, err := r.ready()
+	if err != nil {
+		return Reschedule{}, err
+	}
+	return scanReschedule(db.QueryRow(`SELECT `+rescheduleColumns+` FROM booking_reschedules WHERE id = ?`, id))
+}
+
+// GetForUpdateTx membaca reschedule sambil mengunci barisnya sampai transaksi selesai.
+func (r RescheduleRepository) GetForUpdateTx(tx *sql.Tx, id int64) (Reschedule, error) {
+	if !intdb.HasTable(tx, "booking_reschedules") {
+		return Reschedule{}, fmt.Errorf("tabel booking_reschedules belum tersedia, jalankan `migrate up`")
+	}
+	return scanReschedule(tx.QueryRow(`SELECT `+rescheduleColumns+` FROM booking_reschedules WHERE id = ? FOR UPDATE`, id))
+}
+
+// UpdateAdjustmentStatusTx menutup penyesuaian tarif (settled/waived) beserta catatan admin.
+func (r RescheduleRepository) UpdateAdjustmentStatusTx(tx *sql.Tx, id int64, status, note string, at time.Time) error {
+	_, err := tx.Exec(`UPDATE booking_reschedules SET adjustment_status = ?, note = ?, settled_at = ? WHERE id = ?`, status, note, at, id)
+	return err
+}
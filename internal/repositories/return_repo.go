@@ -26,7 +26,7 @@ func (r ReturnRepository) db() *sql.DB {
 }
 
 // helper SELECT expression (string)
-func (r ReturnRepository) selStr(db *sql.DB, table, col string) string {
+func (r ReturnRepository) selStr(db intdb.QueryRower, table, col string) string {
 	if intdb.HasColumn(db, table, col) {
 		return "COALESCE(`" + col + "`, '')"
 	}
@@ -34,7 +34,7 @@ func (r ReturnRepository) selStr(db *sql.DB, table, col string) string {
 }
 
 // helper SELECT expression (number)
-func (r ReturnRepository) selNum(db *sql.DB, table, col string) string {
+func (r ReturnRepository) selNum(db intdb.QueryRower, table, col string) string {
 	if intdb.HasColumn(db, table, col) {
 		return "COALESCE(`" + col + "`, 0)"
 	}
@@ -42,7 +42,7 @@ func (r ReturnRepository) selNum(db *sql.DB, table, col string) string {
 }
 
 // helper SELECT expression (datetime -> string)
-func (r ReturnRepository) selDateStr(db *sql.DB, table, col string) string {
+func (r ReturnRepository) selDateStr(db intdb.QueryRower, table, col string) string {
 	if intdb.HasColumn(db, table, col) {
 		// CAST supaya hasil selalu string dan aman saat COALESCE dengan ''
 		return "COALESCE(CAST(`" + col + "` AS CHAR), '')"
@@ -52,14 +52,30 @@ func (r ReturnRepository) selDateStr(db *sql.DB, table, col string) string {
 
 // GetByID loads return_settings row.
 func (r ReturnRepository) GetByID(id int) (models.ReturnSetting, error) {
+	db := r.db()
+	if db == nil {
+		return models.ReturnSetting{}, sql.ErrNoRows
+	}
+	return r.getByID(db, id, false)
+}
+
+// GetForUpdateTx membaca return_settings id sambil mengunci barisnya sampai tx selesai.
+func (r ReturnRepository) GetForUpdateTx(tx *sql.Tx, id int) (models.ReturnSetting, error) {
+	return r.getByID(tx, id, true)
+}
+
+func (r ReturnRepository) getByID(db intdb.Queryer, id int, lock bool) (models.ReturnSetting, error) {
 	if id <= 0 {
 		return models.ReturnSetting{}, sql.ErrNoRows
 	}
 	table := "return_settings"
-	db := r.db()
-	if db == nil || !intdb.HasTable(db, table) {
+	if !intdb.HasTable(db, table) {
 		return models.ReturnSetting{}, sql.ErrNoRows
 	}
+	forUpdate := ""
+	if lock {
+		forUpdate = " FOR UPDATE"
+	}
 
 	var d models.ReturnSetting
 	var count int
@@ -88,7 +104,7 @@ func (r ReturnRepository) GetByID(id int) (models.ReturnSetting, error) {
 			%s,
 			%s,
 			%s
-		FROM %s WHERE id=? LIMIT 1`,
+		FROM %s WHERE id=? LIMIT 1%s`,
 		r.selStr(db, table, "booking_name"),
 		r.selStr(db, table, "phone"),
 		r.selStr(db, table, "pickup_address"),
@@ -108,7 +124,7 @@ func (r ReturnRepository) GetByID(id int) (models.ReturnSetting, error) {
 		r.selStr(db, table, "route_to"),
 		r.selStr(db, table, "vehicle_type"),
 		r.selDateStr(db, table, "created_at"),
-		table,
+		table, forUpdate,
 	)
 
 	err := db.QueryRow(query, id).Scan(
@@ -148,12 +164,19 @@ func (r ReturnRepository) GetByID(id int) (models.ReturnSetting, error) {
 
 // GetByBookingID loads by booking_id if exists.
 func (r ReturnRepository) GetByBookingID(bookingID int64) (models.ReturnSetting, error) {
+	db := r.db()
+	if db == nil {
+		return models.ReturnSetting{}, sql.ErrNoRows
+	}
+	return r.getByBookingID(db, bookingID)
+}
+
+func (r ReturnRepository) getByBookingID(db intdb.Queryer, bookingID int64) (models.ReturnSetting, error) {
 	if bookingID <= 0 {
 		return models.ReturnSetting{}, sql.ErrNoRows
 	}
 	table := "return_settings"
-	db := r.db()
-	if db == nil || !intdb.HasTable(db, table) || !intdb.HasColumn(db, table, "booking_id") {
+	if !intdb.HasTable(db, table) || !intdb.HasColumn(db, table, "booking_id") {
 		return models.ReturnSetting{}, sql.ErrNoRows
 	}
 
@@ -161,88 +184,7 @@ func (r ReturnRepository) GetByBookingID(bookingID int64) (models.ReturnSetting,
 	if err := db.QueryRow(`SELECT id FROM `+table+` WHERE booking_id=? ORDER BY id DESC LIMIT 1`, bookingID).Scan(&id); err != nil {
 		return models.ReturnSetting{}, err
 	}
-
-	var d models.ReturnSetting
-	var count int
-	var depTime, routeFrom, routeTo, vehicleType, createdAt string
-
-	query := fmt.Sprintf(`
-		SELECT
-			id,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s,
-			%s
-		FROM %s WHERE id=? LIMIT 1`,
-		r.selStr(db, table, "booking_name"),
-		r.selStr(db, table, "phone"),
-		r.selStr(db, table, "pickup_address"),
-		r.selStr(db, table, "departure_date"),
-		r.selStr(db, table, "seat_numbers"),
-		r.selNum(db, table, "passenger_count"),
-		r.selStr(db, table, "service_type"),
-		r.selStr(db, table, "driver_name"),
-		r.selStr(db, table, "vehicle_code"),
-		r.selStr(db, table, "surat_jalan_file"),
-		r.selStr(db, table, "surat_jalan_file_name"),
-		r.selStr(db, table, "departure_status"),
-		r.selStr(db, table, "trip_number"),
-		r.selNum(db, table, "booking_id"),
-		r.selStr(db, table, "departure_time"),
-		r.selStr(db, table, "route_from"),
-		r.selStr(db, table, "route_to"),
-		r.selStr(db, table, "vehicle_type"),
-		r.selDateStr(db, table, "created_at"),
-		table,
-	)
-
-	if err := db.QueryRow(query, id).Scan(
-		&d.ID,
-		&d.BookingName,
-		&d.Phone,
-		&d.PickupAddress,
-		&d.DepartureDate,
-		&d.SeatNumbers,
-		&count,
-		&d.ServiceType,
-		&d.DriverName,
-		&d.VehicleCode,
-		&d.SuratJalanFile,
-		&d.SuratJalanFileName,
-		&d.DepartureStatus,
-		&d.TripNumber,
-		&d.BookingID,
-		&depTime,
-		&routeFrom,
-		&routeTo,
-		&vehicleType,
-		&createdAt,
-	); err != nil {
-		return models.ReturnSetting{}, err
-	}
-
-	d.PassengerCount = strconv.Itoa(count)
-	d.DepartureTime = strings.TrimSpace(depTime)
-	d.RouteFrom = strings.TrimSpace(routeFrom)
-	d.RouteTo = strings.TrimSpace(routeTo)
-	d.VehicleType = strings.TrimSpace(vehicleType)
-	d.CreatedAt = strings.TrimSpace(createdAt)
-	return d, nil
+	return r.getByID(db, id, false)
 }
 
 // CreateFromBooking upserts return_settings keyed by booking_id.
@@ -338,19 +280,27 @@ func (r ReturnRepository) CreateFromBooking(dep models.ReturnSetting) (models.Re
 
 // UpdatePartial applies only fields present in raw JSON (key presence).
 func (r ReturnRepository) UpdatePartial(id int, rawJSON []byte) (models.ReturnSetting, error) {
+	db := r.db()
+	if db == nil {
+		return models.ReturnSetting{}, fmt.Errorf("tabel return_settings tidak ditemukan")
+	}
+	return r.UpdatePartialTx(db, id, rawJSON)
+}
+
+// UpdatePartialTx sama dengan UpdatePartial di dalam transaksi pemanggil.
+func (r ReturnRepository) UpdatePartialTx(db intdb.Queryer, id int, rawJSON []byte) (models.ReturnSetting, error) {
 	if id <= 0 {
 		return models.ReturnSetting{}, sql.ErrNoRows
 	}
 	table := "return_settings"
-	db := r.db()
-	if db == nil || !intdb.HasTable(db, table) {
+	if !intdb.HasTable(db, table) {
 		return models.ReturnSetting{}, fmt.Errorf("tabel return_settings tidak ditemukan")
 	}
 
-	existing, err := r.GetByBookingIDFromPayload(rawJSON)
+	existing, err := r.getByBookingIDFromPayload(db, rawJSON)
 	if err != nil || existing.ID == 0 {
 		// fallback to load by id if booking_id not provided
-		if existing, err = r.GetByID(id); err != nil {
+		if existing, err = r.getByID(db, id, false); err != nil {
 			return models.ReturnSetting{}, err
 		}
 	}
@@ -417,14 +367,45 @@ func (r ReturnRepository) UpdatePartial(id int, rawJSON []byte) (models.ReturnSe
 	return merged, nil
 }
 
+// Preview menggabungkan payload ke baris id tanpa menyimpan; lihat DepartureRepository.Preview.
+func (r ReturnRepository) Preview(id int, rawJSON []byte) (models.ReturnSetting, bool, error) {
+	db := r.db()
+	if db == nil {
+		return models.ReturnSetting{}, false, sql.ErrNoRows
+	}
+	return r.PreviewTx(db, id, rawJSON)
+}
+
+// PreviewTx sama dengan Preview di dalam transaksi pemanggil (baris id biasanya sudah dikunci).
+func (r ReturnRepository) PreviewTx(db intdb.Queryer, id int, rawJSON []byte) (models.ReturnSetting, bool, error) {
+	existing, err := r.getByID(db, id, false)
+	if err != nil {
+		return models.ReturnSetting{}, false, err
+	}
+	merged, _, _, err := buildReturnPatch(existing, rawJSON)
+	if err != nil {
+		return merged, false, err
+	}
+	merged.ID = id
+	return merged, assignmentChanged(existing, merged), nil
+}
+
 // UpdateEnrich: update hasil fallback/enrichment tanpa tergantung payload key presence.
 func (r ReturnRepository) UpdateEnrich(id int, fields map[string]any) error {
+	db := r.db()
+	if db == nil {
+		return fmt.Errorf("tabel return_settings tidak ditemukan")
+	}
+	return r.UpdateEnrichTx(db, id, fields)
+}
+
+// UpdateEnrichTx sama dengan UpdateEnrich di dalam transaksi pemanggil.
+func (r ReturnRepository) UpdateEnrichTx(db intdb.Queryer, id int, fields map[string]any) error {
 	if id <= 0 {
 		return sql.ErrNoRows
 	}
 	table := "return_settings"
-	db := r.db()
-	if db == nil || !intdb.HasTable(db, table) {
+	if !intdb.HasTable(db, table) {
 		return fmt.Errorf("tabel return_settings tidak ditemukan")
 	}
 	if len(fields) == 0 {
@@ -489,12 +470,20 @@ type returnFieldPresence struct {
 
 // GetByBookingIDFromPayload tries to parse booking_id from raw JSON before loading.
 func (r ReturnRepository) GetByBookingIDFromPayload(rawJSON []byte) (models.ReturnSetting, error) {
+	db := r.db()
+	if db == nil {
+		return models.ReturnSetting{}, sql.ErrNoRows
+	}
+	return r.getByBookingIDFromPayload(db, rawJSON)
+}
+
+func (r ReturnRepository) getByBookingIDFromPayload(db intdb.Queryer, rawJSON []byte) (models.ReturnSetting, error) {
 	var input models.ReturnSetting
 	if err := json.Unmarshal(rawJSON, &input); err != nil {
 		return models.ReturnSetting{}, err
 	}
 	if input.BookingID > 0 {
-		return r.GetByBookingID(input.BookingID)
+		return r.getByBookingID(db, input.BookingID)
 	}
 	return models.ReturnSetting{}, sql.ErrNoRows
 }
@@ -623,3 +612,8 @@ func buildReturnPatch(existing models.ReturnSetting, rawJSON []byte) (models.Ret
 	count, _ := strconv.Atoi(strings.TrimSpace(merged.PassengerCount))
 	return merged, presence, count, nil
 }
+
+// CancelByBookingTx mengosongkan seat di return_settings milik booking dan menandai statusnya.
+func (r ReturnRepository) CancelByBookingTx(tx *sql.Tx, bookingID int64, status string) error {
+	return cancelSettingsByBooking(tx, "return_settings", bookingID, status)
+}
@@ -0,0 +1,242 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Route adalah satu jalur reguler; Stops berisi stop_key urut dari ujung awal ke ujung akhir.
+type Route struct {
+	ID       int64    `json:"id"`
+	Code     string   `json:"code"`
+	Name     string   `json:"name"`
+	IsActive bool     `json:"isActive"`
+	Stops    []string `json:"stops"`
+	// StopMinutes (opsional) sejajar Stops: menit sejak halte pertama, untuk estimasi waktu tempuh.
+	// Kosong bila belum diisi untuk semua halte.
+	StopMinutes []int `json:"stopMinutes,omitempty"`
+}
+
+type RouteRepository struct {
+	DB *sql.DB
+}
+
+func (r RouteRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r RouteRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "routes") || !intdb.HasTable(db, "route_stops") {
+		return nil, fmt.Errorf("tabel routes belum tersedia, jalankan `migrate up`")
+	}
+	return db, nil
+}
+
+// Available melaporkan apakah migration routes sudah dijalankan.
+func (r RouteRepository) Available() bool {
+	_, err := r.ready()
+	return err == nil
+}
+
+// List mengembalikan jalur beserta urutan haltenya.
+func (r RouteRepository) List(activeOnly bool) ([]Route, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	q := `SELECT id, code, name, is_active FROM routes`
+	if activeOnly {
+		q += ` WHERE is_active = 1`
+	}
+	q += ` ORDER BY id ASC`
+
+	rows, err := db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Route{}
+	index := map[int64]int{}
+	for rows.Next() {
+		var rt Route
+		if err := rows.Scan(&rt.ID, &rt.Code, &rt.Name, &rt.IsActive); err != nil {
+			return nil, err
+		}
+		rt.Stops = []string{}
+		index[rt.ID] = len(out)
+		out = append(out, rt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	stopRows, err := db.Query(`SELECT route_id, stop_key, minutes_from_start FROM route_stops ORDER BY route_id ASC, seq ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer stopRows.Close()
+	minutes := map[int64][]sql.NullInt64{}
+	for stopRows.Next() {
+		var (
+			routeID int64
+			key     string
+			m       sql.NullInt64
+		)
+		if err := stopRows.Scan(&routeID, &key, &m); err != nil {
+			return nil, err
+		}
+		if i, ok := index[routeID]; ok {
+			out[i].Stops = append(out[i].Stops, key)
+			minutes[routeID] = append(minutes[routeID], m)
+		}
+	}
+	if err := stopRows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].StopMinutes = completeMinutes(minutes[out[i].ID])
+	}
+	return out, nil
+}
+
+// completeMinutes mengembalikan menit per halte hanya bila semua halte terisi.
+func completeMinutes(list []sql.NullInt64) []int {
+	if len(list) == 0 {
+		return nil
+	}
+	out := make([]int, 0, len(list))
+	for _, m := range list {
+		if !m.Valid {
+			return nil
+		}
+		out = append(out, int(m.Int64))
+	}
+	return out
+}
+
+func (r RouteRepository) GetByID(id int64) (Route, error) {
+	db, err := r.ready()
+	if err != nil {
+		return Route{}, err
+	}
+	var rt Route
+	if err := db.QueryRow(`SELECT id, code, name, is_active FROM routes WHERE id = ?`, id).
+		Scan(&rt.ID, &rt.Code, &rt.Name, &rt.IsActive); err != nil {
+		return Route{}, err
+	}
+
+	rows, err := db.Query(`SELECT stop_key, minutes_from_start FROM route_stops WHERE route_id = ? ORDER BY seq ASC`, id)
+	if err != nil {
+		return Route{}, err
+	}
+	defer rows.Close()
+	rt.Stops = []string{}
+	minutes := []sql.NullInt64{}
+	for rows.Next() {
+		var (
+			key string
+			m   sql.NullInt64
+		)
+		if err := rows.Scan(&key, &m); err != nil {
+			return Route{}, err
+		}
+		rt.Stops = append(rt.Stops, key)
+		minutes = append(minutes, m)
+	}
+	rt.StopMinutes = completeMinutes(minutes)
+	return rt, rows.Err()
+}
+
+func (r RouteRepository) Create(rt Route) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO routes (code, name, is_active) VALUES (?, ?, ?)`, rt.Code, rt.Name, rt.IsActive)
+	if err != nil {
+		return 0, err
+	}
+	id, _ := res.LastInsertId()
+	if err := insertRouteStops(tx, id, rt.Stops, rt.StopMinutes); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// Update mengganti data jalur sekaligus seluruh urutan haltenya.
+func (r RouteRepository) Update(rt Route) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE routes SET code = ?, name = ?, is_active = ? WHERE id = ?`,
+		rt.Code, rt.Name, rt.IsActive, rt.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM route_stops WHERE route_id = ?`, rt.ID); err != nil {
+		return err
+	}
+	if err := insertRouteStops(tx, rt.ID, rt.Stops, rt.StopMinutes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r RouteRepository) Delete(id int64) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`DELETE FROM routes WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// insertRouteStops menyimpan urutan halte; minutes (opsional, sejajar stops) hanya ditulis
+// bila lengkap untuk semua halte.
+func insertRouteStops(tx *sql.Tx, routeID int64, stops []string, minutes []int) error {
+	withMinutes := len(minutes) == len(stops)
+	for i, key := range stops {
+		var err error
+		if withMinutes {
+			_, err = tx.Exec(`INSERT INTO route_stops (route_id, stop_key, seq, minutes_from_start) VALUES (?, ?, ?, ?)`, routeID, key, i+1, minutes[i])
+		} else {
+			_, err = tx.Exec(`INSERT INTO route_stops (route_id, stop_key, seq) VALUES (?, ?, ?)`, routeID, key, i+1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
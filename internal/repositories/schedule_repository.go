@@ -0,0 +1,408 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Arah perjalanan pada jalur (lihat route_stops).
+const (
+	DirectionForward = "forward"
+	DirectionReverse = "reverse"
+)
+
+// Status trip slot.
+const (
+	TripSlotOpen   = "open"
+	TripSlotClosed = "closed"
+)
+
+// Schedule adalah entri timetable berulang: jalur + arah + hari + jam berangkat.
+type Schedule struct {
+	ID            int64  `json:"id"`
+	RouteID       int64  `json:"routeId"`
+	Direction     string `json:"direction"`
+	DaysOfWeek    []int  `json:"daysOfWeek"` // ISO: 1=Senin .. 7=Minggu
+	DepartureTime string `json:"departureTime"`
+	VehicleType   string `json:"vehicleType"`
+	Capacity      int    `json:"capacity"`
+	ValidFrom     string `json:"validFrom"` // YYYY-MM-DD
+	ValidTo       string `json:"validTo"`   // YYYY-MM-DD
+	IsActive      bool   `json:"isActive"`
+}
+
+// Blackout menandai tanggal tanpa keberangkatan; RouteID 0 = semua jalur.
+type Blackout struct {
+	ID      int64  `json:"id"`
+	Date    string `json:"date"`
+	RouteID int64  `json:"routeId"`
+	Reason  string `json:"reason"`
+}
+
+// TripSlot adalah satu keberangkatan konkret yang bisa dibooking.
+type TripSlot struct {
+	ID          int64  `json:"id"`
+	ScheduleID  int64  `json:"scheduleId"`
+	RouteID     int64  `json:"routeId"`
+	Direction   string `json:"direction"`
+	Date        string `json:"date"`
+	Time        string `json:"time"`
+	VehicleType string `json:"vehicleType"`
+	Capacity    int    `json:"capacity"`
+	Status      string `json:"status"`
+}
+
+type ScheduleRepository struct {
+	DB *sql.DB
+}
+
+func (r ScheduleRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r ScheduleRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	for _, table := range []string{"schedules", "schedule_blackouts", "trip_slots"} {
+		if !intdb.HasTable(db, table) {
+			return nil, fmt.Errorf("tabel %s belum tersedia, jalankan `migrate up`", table)
+		}
+	}
+	return db, nil
+}
+
+// Available melaporkan apakah migration schedules sudah dijalankan.
+func (r ScheduleRepository) Available() bool {
+	_, err := r.ready()
+	return err == nil
+}
+
+// ===== schedules =====
+
+const scheduleColumns = `id, route_id, direction, days_of_week, departure_time, COALESCE(vehicle_type, ''), capacity,
+	COALESCE(DATE_FORMAT(valid_from, '%Y-%m-%d'), ''), COALESCE(DATE_FORMAT(valid_to, '%Y-%m-%d'), ''), is_active`
+
+func scanSchedule(sc interface{ Scan(...any) error }) (Schedule, error) {
+	var (
+		s    Schedule
+		days string
+	)
+	if err := sc.Scan(&s.ID, &s.RouteID, &s.Direction, &days, &s.DepartureTime, &s.VehicleType, &s.Capacity,
+		&s.ValidFrom, &s.ValidTo, &s.IsActive); err != nil {
+		return Schedule{}, err
+	}
+	s.DaysOfWeek = []int{}
+	for _, part := range splitAliases(days) {
+		if d, err := strconv.Atoi(part); err == nil {
+			s.DaysOfWeek = append(s.DaysOfWeek, d)
+		}
+	}
+	return s, nil
+}
+
+func joinDays(days []int) string {
+	parts := make([]string, 0, len(days))
+	for _, d := range days {
+		parts = append(parts, strconv.Itoa(d))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r ScheduleRepository) ListSchedules(activeOnly bool) ([]Schedule, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	q := `SELECT ` + scheduleColumns + ` FROM schedules`
+	if activeOnly {
+		q += ` WHERE is_active = 1`
+	}
+	q += ` ORDER BY route_id ASC, direction ASC, departure_time ASC`
+
+	rows, err := db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Schedule{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r ScheduleRepository) GetSchedule(id int64) (Schedule, error) {
+	db, err := r.ready()
+	if err != nil {
+		return Schedule{}, err
+	}
+	return scanSchedule(db.QueryRow(`SELECT `+scheduleColumns+` FROM schedules WHERE id = ?`, id))
+}
+
+// CountActiveSchedules menghitung timetable aktif pada jalur (semua arah).
+func (r ScheduleRepository) CountActiveSchedules(routeID int64) (int, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = db.QueryRow(`SELECT COUNT(*) FROM schedules WHERE route_id = ? AND is_active = 1`, routeID).Scan(&n)
+	return n, err
+}
+
+func (r ScheduleRepository) CreateSchedule(s Schedule) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(`
+		INSERT INTO schedules
+		(route_id, direction, days_of_week, departure_time, vehicle_type, capacity, valid_from, valid_to, is_active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.RouteID, s.Direction, joinDays(s.DaysOfWeek), s.DepartureTime, nullIfEmptyString(s.VehicleType), s.Capacity,
+		nullIfEmptyString(s.ValidFrom), nullIfEmptyString(s.ValidTo), s.IsActive)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r ScheduleRepository) UpdateSchedule(s Schedule) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		UPDATE schedules
+		SET route_id = ?, direction = ?, days_of_week = ?, departure_time = ?, vehicle_type = ?, capacity = ?,
+		    valid_from = ?, valid_to = ?, is_active = ?
+		WHERE id = ?
+	`, s.RouteID, s.Direction, joinDays(s.DaysOfWeek), s.DepartureTime, nullIfEmptyString(s.VehicleType), s.Capacity,
+		nullIfEmptyString(s.ValidFrom), nullIfEmptyString(s.ValidTo), s.IsActive, s.ID)
+	return err
+}
+
+func (r ScheduleRepository) DeleteSchedule(id int64) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`DELETE FROM schedules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ===== blackouts =====
+
+// ListBlackouts mengembalikan tanggal libur mulai fromDate (kosong = semua).
+func (r ScheduleRepository) ListBlackouts(fromDate string) ([]Blackout, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	q := `SELECT id, DATE_FORMAT(blackout_date, '%Y-%m-%d'), COALESCE(route_id, 0), reason FROM schedule_blackouts`
+	args := []any{}
+	if fromDate != "" {
+		q += ` WHERE blackout_date >= ?`
+		args = append(args, fromDate)
+	}
+	q += ` ORDER BY blackout_date ASC, id ASC`
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Blackout{}
+	for rows.Next() {
+		var b Blackout
+		if err := rows.Scan(&b.ID, &b.Date, &b.RouteID, &b.Reason); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+func (r ScheduleRepository) CreateBlackout(b Blackout) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	var routeID any
+	if b.RouteID > 0 {
+		routeID = b.RouteID
+	}
+	res, err := db.Exec(`INSERT INTO schedule_blackouts (blackout_date, route_id, reason) VALUES (?, ?, ?)`,
+		b.Date, routeID, b.Reason)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r ScheduleRepository) DeleteBlackout(id int64) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`DELETE FROM schedule_blackouts WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ===== trip slots =====
+
+const tripSlotColumns = `id, COALESCE(schedule_id, 0), route_id, direction, DATE_FORMAT(trip_date, '%Y-%m-%d'), trip_time,
+	COALESCE(vehicle_type, ''), capacity, status`
+
+func scanTripSlot(sc interface{ Scan(...any) error }) (TripSlot, error) {
+	var t TripSlot
+	err := sc.Scan(&t.ID, &t.ScheduleID, &t.RouteID, &t.Direction, &t.Date, &t.Time, &t.VehicleType, &t.Capacity, &t.Status)
+	return t, err
+}
+
+// InsertSlot membuat trip slot bila belum ada (slot yang sudah ada, termasuk yang ditutup, tidak diubah).
+func (r ScheduleRepository) InsertSlot(t TripSlot) (bool, error) {
+	db, err := r.ready()
+	if err != nil {
+		return false, err
+	}
+	var scheduleID any
+	if t.ScheduleID > 0 {
+		scheduleID = t.ScheduleID
+	}
+	res, err := db.Exec(`
+		INSERT IGNORE INTO trip_slots
+		(schedule_id, route_id, direction, trip_date, trip_time, vehicle_type, capacity, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, scheduleID, t.RouteID, t.Direction, t.Date, t.Time, nullIfEmptyString(t.VehicleType), t.Capacity, t.Status)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListSlots mengembalikan slot pada tanggal; routeID 0 / direction kosong = semua.
+func (r ScheduleRepository) ListSlots(date string, routeID int64, direction string, openOnly bool) ([]TripSlot, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	q := `SELECT ` + tripSlotColumns + ` FROM trip_slots WHERE trip_date = ?`
+	args := []any{date}
+	if routeID > 0 {
+		q += ` AND route_id = ?`
+		args = append(args, routeID)
+	}
+	if direction != "" {
+		q += ` AND direction = ?`
+		args = append(args, direction)
+	}
+	if openOnly {
+		q += ` AND status = ?`
+		args = append(args, TripSlotOpen)
+	}
+	q += ` ORDER BY trip_time ASC, route_id ASC`
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []TripSlot{}
+	for rows.Next() {
+		t, err := scanTripSlot(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (r ScheduleRepository) GetSlot(id int64) (TripSlot, error) {
+	db, err := r.ready()
+	if err != nil {
+		return TripSlot{}, err
+	}
+	return scanTripSlot(db.QueryRow(`SELECT `+tripSlotColumns+` FROM trip_slots WHERE id = ?`, id))
+}
+
+// FindSlot mencari slot berdasarkan jalur, arah, tanggal dan jam (HH:MM).
+func (r ScheduleRepository) FindSlot(routeID int64, direction, date, tm string) (TripSlot, error) {
+	db, err := r.ready()
+	if err != nil {
+		return TripSlot{}, err
+	}
+	return scanTripSlot(db.QueryRow(`
+		SELECT `+tripSlotColumns+` FROM trip_slots
+		WHERE route_id = ? AND direction = ? AND trip_date = ? AND trip_time = ?
+	`, routeID, direction, date, tm))
+}
+
+// UpdateSlot mengubah status/kapasitas satu slot (penutupan manual, ganti armada).
+func (r ScheduleRepository) UpdateSlot(t TripSlot) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`UPDATE trip_slots SET status = ?, capacity = ?, vehicle_type = ? WHERE id = ?`,
+		t.Status, t.Capacity, nullIfEmptyString(t.VehicleType), t.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetSlot(t.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CloseSlotsOn menutup slot open pada tanggal libur; routeID 0 = semua jalur.
+func (r ScheduleRepository) CloseSlotsOn(date string, routeID int64) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	q := `UPDATE trip_slots SET status = ? WHERE trip_date = ? AND status = ?`
+	args := []any{TripSlotClosed, date, TripSlotOpen}
+	if routeID > 0 {
+		q += ` AND route_id = ?`
+		args = append(args, routeID)
+	}
+	res, err := db.Exec(q, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
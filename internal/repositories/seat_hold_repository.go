@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// SeatSlot mengidentifikasi satu perjalanan reguler (nama stop sudah kanonik, time HH:MM).
+type SeatSlot struct {
+	From string
+	To   string
+	Date string // YYYY-MM-DD
+	Time string // HH:MM
+	// Legs adalah semua segmen (termasuk From/To sendiri) di mobil yang sama yang overlap
+	// dengan slot ini. Kosong = hanya From/To persis (jalur belum didefinisikan).
+	Legs []SeatLeg
+	// VehicleType dari trip slot terjadwal (kosong = ikut kendaraan di departure_settings).
+	VehicleType string
+}
+
+// SeatLeg adalah pasangan halte naik/turun (display name) pada satu jalur.
+type SeatLeg struct {
+	From string
+	To   string
+}
+
+// SeatHold adalah satu seat yang sedang ditahan selama checkout.
+type SeatHold struct {
+	ID        int64
+	Token     string
+	UserID    int64
+	Slot      SeatSlot
+	SeatCode  string
+	ExpiresAt time.Time
+}
+
+type SeatHoldRepository struct {
+	DB *sql.DB
+}
+
+func (r SeatHoldRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+// Ready memastikan tabel seat_holds sudah dibuat lewat migration.
+func (r SeatHoldRepository) Ready(q intdb.QueryRower) error {
+	if !intdb.HasTable(q, "seat_holds") {
+		return fmt.Errorf("tabel seat_holds belum tersedia, jalankan `migrate up`")
+	}
+	return nil
+}
+
+func (r SeatHoldRepository) Begin() (*sql.Tx, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if err := r.Ready(db); err != nil {
+		return nil, err
+	}
+	return db.Begin()
+}
+
+func seatPlaceholders(seats []string) (string, []any) {
+	ph := make([]string, 0, len(seats))
+	args := make([]any, 0, len(seats))
+	for _, s := range seats {
+		ph = append(ph, "?")
+		args = append(args, s)
+	}
+	return strings.Join(ph, ","), args
+}
+
+func slotArgs(slot SeatSlot) []any {
+	return []any{slot.From, slot.To, slot.Date, slot.Time}
+}
+
+const slotWhere = `route_from = ? AND route_to = ? AND trip_date = ? AND trip_time = ?`
+
+// occupancyWhere memfilter baris yang memakai seat di slot: From/To persis, atau
+// setiap segmen overlap bila slot.Legs terisi.
+func occupancyWhere(slot SeatSlot) (string, []any) {
+	if len(slot.Legs) == 0 {
+		return slotWhere, slotArgs(slot)
+	}
+	ph := make([]string, 0, len(slot.Legs))
+	args := make([]any, 0, len(slot.Legs)*2+2)
+	for _, leg := range slot.Legs {
+		ph = append(ph, "(?, ?)")
+		args = append(args, leg.From, leg.To)
+	}
+	args = append(args, slot.Date, slot.Time)
+	return `(route_from, route_to) IN (` + strings.Join(ph, ", ") + `) AND trip_date = ? AND trip_time = ?`, args
+}
+
+// DeleteExpiredForSlot membuang hold kedaluwarsa di slot supaya unique key tidak menghalangi hold baru.
+func (r SeatHoldRepository) DeleteExpiredForSlot(q intdb.Queryer, slot SeatSlot, now time.Time) error {
+	args := append(slotArgs(slot), now)
+	_, err := q.Exec(`DELETE FROM seat_holds WHERE `+slotWhere+` AND expires_at <= ?`, args...)
+	return err
+}
+
+// DeleteExpired dipakai sweeper; mengembalikan jumlah seat yang dilepas.
+func (r SeatHoldRepository) DeleteExpired(now time.Time) (int64, error) {
+	db := r.db()
+	if db == nil {
+		return 0, fmt.Errorf("db tidak tersedia")
+	}
+	if err := r.Ready(db); err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(`DELETE FROM seat_holds WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r SeatHoldRepository) DeleteByToken(q intdb.Queryer, token string) (int64, error) {
+	res, err := q.Exec(`DELETE FROM seat_holds WHERE hold_token = ?`, token)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r SeatHoldRepository) Insert(q intdb.Queryer, h SeatHold) error {
+	var userID any
+	if h.UserID > 0 {
+		userID = h.UserID
+	}
+	_, err := q.Exec(`
+		INSERT INTO seat_holds
+		(hold_token, user_id, route_from, route_to, trip_date, trip_time, seat_code, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`, h.Token, userID, h.Slot.From, h.Slot.To, h.Slot.Date, h.Slot.Time, h.SeatCode, h.ExpiresAt)
+	return err
+}
+
+// ListByToken mengembalikan semua seat milik satu hold token.
+func (r SeatHoldRepository) ListByToken(q intdb.Queryer, token string, forUpdate bool) ([]SeatHold, error) {
+	query := `
+		SELECT id, hold_token, COALESCE(user_id, 0), route_from, route_to,
+		       DATE_FORMAT(trip_date, '%Y-%m-%d'), trip_time, seat_code, expires_at
+		FROM seat_holds WHERE hold_token = ?`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	return scanSeatHolds(q.Query(query, token))
+}
+
+// ListForSlot mengembalikan hold di slot (termasuk segmen overlap); seats kosong = semua seat,
+// activeAt non-zero = hanya yang belum kedaluwarsa.
+func (r SeatHoldRepository) ListForSlot(q intdb.Queryer, slot SeatSlot, seats []string, activeAt time.Time, forUpdate bool) ([]SeatHold, error) {
+	where, args := occupancyWhere(slot)
+	query := `
+		SELECT id, hold_token, COALESCE(user_id, 0), route_from, route_to,
+		       DATE_FORMAT(trip_date, '%Y-%m-%d'), trip_time, seat_code, expires_at
+		FROM seat_holds WHERE ` + where
+	if len(seats) > 0 {
+		ph, seatArgs := seatPlaceholders(seats)
+		query += ` AND seat_code IN (` + ph + `)`
+		args = append(args, seatArgs...)
+	}
+	if !activeAt.IsZero() {
+		query += ` AND expires_at > ?`
+		args = append(args, activeAt)
+	}
+	query += ` ORDER BY seat_code ASC`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	return scanSeatHolds(q.Query(query, args...))
+}
+
+// BookedSeats mengembalikan seat di booking_seats yang terpakai di slot, termasuk segmen
+// overlap (seats kosong = semua). forUpdate mengunci baris selama transaksi booking.
+func (r SeatHoldRepository) BookedSeats(q intdb.Queryer, slot SeatSlot, seats []string, forUpdate bool) ([]string, error) {
+	where, args := occupancyWhere(slot)
+	query := `SELECT seat_code FROM booking_seats WHERE ` + where
+	if len(seats) > 0 {
+		ph, seatArgs := seatPlaceholders(seats)
+		query += ` AND seat_code IN (` + ph + `)`
+		args = append(args, seatArgs...)
+	}
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// seat yang sama bisa muncul di beberapa segmen overlap
+	out := []string{}
+	seen := map[string]bool{}
+	for rows.Next() {
+		var seat string
+		if err := rows.Scan(&seat); err != nil {
+			return nil, err
+		}
+		seat = strings.ToUpper(strings.TrimSpace(seat))
+		if !seen[seat] {
+			seen[seat] = true
+			out = append(out, seat)
+		}
+	}
+	return out, rows.Err()
+}
+
+func scanSeatHolds(rows *sql.Rows, err error) ([]SeatHold, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SeatHold{}
+	for rows.Next() {
+		var h SeatHold
+		if err := rows.Scan(&h.ID, &h.Token, &h.UserID, &h.Slot.From, &h.Slot.To,
+			&h.Slot.Date, &h.Slot.Time, &h.SeatCode, &h.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
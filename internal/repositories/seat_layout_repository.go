@@ -0,0 +1,248 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// SeatCell adalah satu kursi pada grid denah (row/col mulai dari 1).
+type SeatCell struct {
+	Code string `json:"code"`
+	Row  int    `json:"row"`
+	Col  int    `json:"col"`
+}
+
+// SeatLayout adalah denah kursi untuk satu tipe kendaraan.
+type SeatLayout struct {
+	ID            int64      `json:"id"`
+	VehicleType   string     `json:"vehicleType"`
+	Name          string     `json:"name"`
+	Rows          int        `json:"rows"`
+	Cols          int        `json:"cols"`
+	Seats         []SeatCell `json:"seats"`
+	DriverRow     int        `json:"driverRow"`
+	DriverCol     int        `json:"driverCol"`
+	DisabledSeats []string   `json:"disabledSeats"`
+	Capacity      int        `json:"capacity"`
+	IsDefault     bool       `json:"isDefault"`
+}
+
+type SeatLayoutRepository struct {
+	DB *sql.DB
+}
+
+func (r SeatLayoutRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r SeatLayoutRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "seat_layouts") {
+		return nil, fmt.Errorf("tabel seat_layouts belum tersedia, jalankan `migrate up`")
+	}
+	return db, nil
+}
+
+// Available melaporkan apakah migration seat_layouts sudah dijalankan.
+func (r SeatLayoutRepository) Available() bool {
+	_, err := r.ready()
+	return err == nil
+}
+
+const seatLayoutColumns = `id, vehicle_type, name, seat_rows, seat_cols, seat_map, driver_row, driver_col, disabled_seats, capacity, is_default`
+
+func scanSeatLayout(sc interface{ Scan(...any) error }) (SeatLayout, error) {
+	var (
+		l        SeatLayout
+		seatMap  string
+		disabled string
+	)
+	if err := sc.Scan(&l.ID, &l.VehicleType, &l.Name, &l.Rows, &l.Cols, &seatMap,
+		&l.DriverRow, &l.DriverCol, &disabled, &l.Capacity, &l.IsDefault); err != nil {
+		return SeatLayout{}, err
+	}
+	l.Seats = []SeatCell{}
+	if strings.TrimSpace(seatMap) != "" {
+		if err := json.Unmarshal([]byte(seatMap), &l.Seats); err != nil {
+			return SeatLayout{}, fmt.Errorf("seat_map layout %d tidak valid: %w", l.ID, err)
+		}
+	}
+	l.DisabledSeats = splitAliases(disabled)
+	return l, nil
+}
+
+func (r SeatLayoutRepository) List() ([]SeatLayout, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT ` + seatLayoutColumns + ` FROM seat_layouts ORDER BY is_default DESC, vehicle_type ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SeatLayout{}
+	for rows.Next() {
+		l, err := scanSeatLayout(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+func (r SeatLayoutRepository) GetByID(id int64) (SeatLayout, error) {
+	db, err := r.ready()
+	if err != nil {
+		return SeatLayout{}, err
+	}
+	return scanSeatLayout(db.QueryRow(`SELECT `+seatLayoutColumns+` FROM seat_layouts WHERE id = ?`, id))
+}
+
+// GetByVehicleType mencari denah untuk tipe kendaraan; vehicleType kosong = denah default.
+func (r SeatLayoutRepository) GetByVehicleType(vehicleType string) (SeatLayout, error) {
+	db, err := r.ready()
+	if err != nil {
+		return SeatLayout{}, err
+	}
+	if strings.TrimSpace(vehicleType) == "" {
+		return scanSeatLayout(db.QueryRow(`SELECT ` + seatLayoutColumns + ` FROM seat_layouts WHERE is_default = 1 ORDER BY id ASC LIMIT 1`))
+	}
+	return scanSeatLayout(db.QueryRow(`SELECT `+seatLayoutColumns+` FROM seat_layouts WHERE vehicle_type = ?`, vehicleType))
+}
+
+// SlotVehicleType mencari tipe kendaraan yang sudah ditugaskan ke slot lewat departure_settings
+// (kolom vehicle_type, atau vehicles.vehicle_type via vehicle_code). Kosong bila belum ada.
+func (r SeatLayoutRepository) SlotVehicleType(slot SeatSlot) (string, error) {
+	db := r.db()
+	if db == nil {
+		return "", fmt.Errorf("db tidak tersedia")
+	}
+	const table = "departure_settings"
+	for _, col := range []string{"departure_date", "departure_time", "route_from", "route_to"} {
+		if !intdb.HasColumn(db, table, col) {
+			return "", nil
+		}
+	}
+
+	sel := []string{}
+	if intdb.HasColumn(db, table, "vehicle_type") {
+		sel = append(sel, "NULLIF(TRIM(d.vehicle_type), '')")
+	}
+	join := ""
+	if intdb.HasColumn(db, table, "vehicle_code") {
+		join = ` LEFT JOIN vehicles v ON v.vehicle_code = d.vehicle_code`
+		sel = append(sel, "NULLIF(TRIM(v.vehicle_type), '')")
+	}
+	if len(sel) == 0 {
+		return "", nil
+	}
+	sel = append(sel, "''")
+
+	var vehicleType string
+	err := db.QueryRow(`
+		SELECT COALESCE(`+strings.Join(sel, ", ")+`) AS vt
+		FROM departure_settings d`+join+`
+		WHERE d.route_from = ? AND d.route_to = ? AND d.departure_date = ? AND LEFT(d.departure_time, 5) = ?
+		HAVING vt <> ''
+		ORDER BY d.id DESC
+		LIMIT 1
+	`, slot.From, slot.To, slot.Date, slot.Time).Scan(&vehicleType)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return vehicleType, err
+}
+
+func (r SeatLayoutRepository) Create(l SeatLayout) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	seatMap, err := json.Marshal(l.Seats)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if l.IsDefault {
+		if _, err := tx.Exec(`UPDATE seat_layouts SET is_default = 0`); err != nil {
+			return 0, err
+		}
+	}
+	res, err := tx.Exec(`
+		INSERT INTO seat_layouts
+		(vehicle_type, name, seat_rows, seat_cols, seat_map, driver_row, driver_col, disabled_seats, capacity, is_default)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, l.VehicleType, l.Name, l.Rows, l.Cols, string(seatMap), l.DriverRow, l.DriverCol,
+		strings.Join(l.DisabledSeats, ","), l.Capacity, l.IsDefault)
+	if err != nil {
+		return 0, err
+	}
+	id, _ := res.LastInsertId()
+	return id, tx.Commit()
+}
+
+func (r SeatLayoutRepository) Update(l SeatLayout) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	seatMap, err := json.Marshal(l.Seats)
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if l.IsDefault {
+		if _, err := tx.Exec(`UPDATE seat_layouts SET is_default = 0 WHERE id <> ?`, l.ID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`
+		UPDATE seat_layouts
+		SET vehicle_type = ?, name = ?, seat_rows = ?, seat_cols = ?, seat_map = ?,
+		    driver_row = ?, driver_col = ?, disabled_seats = ?, capacity = ?, is_default = ?
+		WHERE id = ?
+	`, l.VehicleType, l.Name, l.Rows, l.Cols, string(seatMap), l.DriverRow, l.DriverCol,
+		strings.Join(l.DisabledSeats, ","), l.Capacity, l.IsDefault, l.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r SeatLayoutRepository) Delete(id int64) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`DELETE FROM seat_layouts WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// UserSession is one refresh-token generation. A login starts a new family;
+// every refresh rotates the token into a new row of the same family.
+type UserSession struct {
+	ID           int64
+	UserID       int64
+	FamilyID     string
+	TokenHash    string
+	DeviceName   string
+	UserAgent    string
+	IPAddress    string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	LastUsedAt   sql.NullTime
+	RotatedAt    sql.NullTime
+	ReplacedBy   sql.NullInt64
+	RevokedAt    sql.NullTime
+	RevokeReason string
+}
+
+// Active reports whether the session can still be used at the given time.
+func (s UserSession) Active(now time.Time) bool {
+	return !s.RevokedAt.Valid && !s.RotatedAt.Valid && now.Before(s.ExpiresAt)
+}
+
+type SessionRepository struct {
+	DB *sql.DB
+}
+
+func (r SessionRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+// ready mengembalikan koneksi db; tabel user_sessions dibuat migration 0001, bukan saat request.
+func (r SessionRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	return db, nil
+}
+
+const sessionColumns = `id, user_id, family_id, token_hash, device_name, user_agent, ip_address,
+	created_at, expires_at, last_used_at, rotated_at, replaced_by, revoked_at, revoke_reason`
+
+func scanSession(row interface{ Scan(...any) error }) (UserSession, error) {
+	var s UserSession
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.FamilyID, &s.TokenHash, &s.DeviceName, &s.UserAgent, &s.IPAddress,
+		&s.CreatedAt, &s.ExpiresAt, &s.LastUsedAt, &s.RotatedAt, &s.ReplacedBy, &s.RevokedAt, &s.RevokeReason,
+	)
+	return s, err
+}
+
+// Create inserts a new session row and returns its id.
+func (r SessionRepository) Create(s UserSession) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	return insertSession(db, s)
+}
+
+func insertSession(db intdb.Queryer, s UserSession) (int64, error) {
+	res, err := db.Exec(`
+		INSERT INTO user_sessions
+		(user_id, family_id, token_hash, device_name, user_agent, ip_address, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.UserID,
+		s.FamilyID,
+		s.TokenHash,
+		truncate(strings.TrimSpace(s.DeviceName), 255),
+		truncate(strings.TrimSpace(s.UserAgent), 512),
+		truncate(strings.TrimSpace(s.IPAddress), 64),
+		s.CreatedAt,
+		s.ExpiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetByID loads a session by primary key.
+func (r SessionRepository) GetByID(id int64) (UserSession, error) {
+	db, err := r.ready()
+	if err != nil {
+		return UserSession{}, err
+	}
+	return scanSession(db.QueryRow(`SELECT `+sessionColumns+` FROM user_sessions WHERE id=? LIMIT 1`, id))
+}
+
+// GetByTokenHash loads a session by the sha256 hash of its refresh token.
+func (r SessionRepository) GetByTokenHash(hash string) (UserSession, error) {
+	db, err := r.ready()
+	if err != nil {
+		return UserSession{}, err
+	}
+	return scanSession(db.QueryRow(`SELECT `+sessionColumns+` FROM user_sessions WHERE token_hash=? LIMIT 1`, hash))
+}
+
+// Rotate atomically retires the current session and inserts its successor.
+// Returns sql.ErrNoRows when the current session was already rotated or revoked
+// (a concurrent refresh won the race).
+func (r SessionRepository) Rotate(currentID int64, next UserSession, now time.Time) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	nextID, err := insertSession(tx, next)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`
+		UPDATE user_sessions
+		SET rotated_at=?, replaced_by=?, last_used_at=?
+		WHERE id=? AND rotated_at IS NULL AND revoked_at IS NULL`,
+		now, nextID, now, currentID)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return nextID, nil
+}
+
+// Revoke marks a single session revoked.
+func (r SessionRepository) Revoke(id int64, reason string) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE user_sessions SET revoked_at=?, revoke_reason=? WHERE id=? AND revoked_at IS NULL`, time.Now(), reason, id)
+	return err
+}
+
+// RevokeFamily revokes every generation of a refresh-token family.
+func (r SessionRepository) RevokeFamily(familyID, reason string) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE user_sessions SET revoked_at=?, revoke_reason=? WHERE family_id=? AND revoked_at IS NULL`, time.Now(), reason, familyID)
+	return err
+}
+
+// RevokeAllForUser revokes every open session of a user.
+func (r SessionRepository) RevokeAllForUser(userID int64, reason string) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(`UPDATE user_sessions SET revoked_at=?, revoke_reason=? WHERE user_id=? AND revoked_at IS NULL`, time.Now(), reason, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// IsFamilyActive reports whether a family still has a usable (non-revoked) generation.
+// Dipakai middleware untuk memutus access token yang session-nya sudah dicabut.
+func (r SessionRepository) IsFamilyActive(familyID string) (bool, error) {
+	db, err := r.ready()
+	if err != nil {
+		return false, err
+	}
+	var n int
+	err = db.QueryRow(`SELECT COUNT(*) FROM user_sessions WHERE family_id=? AND revoked_at IS NULL`, familyID).Scan(&n)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Stop adalah satu halte/kota pada katalog reguler. Key dipakai untuk lookup,
+// DisplayName yang disimpan ke bookings/booking_seats.
+type Stop struct {
+	ID          int64    `json:"id"`
+	Key         string   `json:"key"`
+	DisplayName string   `json:"displayName"`
+	Aliases     []string `json:"aliases"`
+	Cluster     string   `json:"cluster"`
+	SortOrder   int      `json:"sortOrder"`
+	IsActive    bool     `json:"isActive"`
+}
+
+type StopRepository struct {
+	DB *sql.DB
+}
+
+func (r StopRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r StopRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "stops") {
+		return nil, fmt.Errorf("tabel stops belum tersedia, jalankan `migrate up`")
+	}
+	return db, nil
+}
+
+const stopColumns = `id, stop_key, display_name, COALESCE(aliases, ''), COALESCE(cluster, ''), sort_order, is_active`
+
+func scanStop(sc interface{ Scan(...any) error }) (Stop, error) {
+	var (
+		s       Stop
+		aliases string
+	)
+	if err := sc.Scan(&s.ID, &s.Key, &s.DisplayName, &aliases, &s.Cluster, &s.SortOrder, &s.IsActive); err != nil {
+		return Stop{}, err
+	}
+	s.Aliases = splitAliases(aliases)
+	return s, nil
+}
+
+func splitAliases(raw string) []string {
+	out := []string{}
+	for _, a := range strings.Split(raw, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// List mengembalikan katalog stop urut sort_order.
+func (r StopRepository) List(activeOnly bool) ([]Stop, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	q := `SELECT ` + stopColumns + ` FROM stops`
+	if activeOnly {
+		q += ` WHERE is_active = 1`
+	}
+	q += ` ORDER BY sort_order ASC, id ASC`
+
+	rows, err := db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Stop{}
+	for rows.Next() {
+		s, err := scanStop(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r StopRepository) GetByID(id int64) (Stop, error) {
+	db, err := r.ready()
+	if err != nil {
+		return Stop{}, err
+	}
+	return scanStop(db.QueryRow(`SELECT `+stopColumns+` FROM stops WHERE id = ?`, id))
+}
+
+func (r StopRepository) Create(s Stop) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.Exec(`
+		INSERT INTO stops (stop_key, display_name, aliases, cluster, sort_order, is_active)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, s.Key, s.DisplayName, strings.Join(s.Aliases, ","), nullIfEmptyString(s.Cluster), s.SortOrder, s.IsActive)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r StopRepository) Update(s Stop) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`
+		UPDATE stops
+		SET stop_key = ?, display_name = ?, aliases = ?, cluster = ?, sort_order = ?, is_active = ?
+		WHERE id = ?
+	`, s.Key, s.DisplayName, strings.Join(s.Aliases, ","), nullIfEmptyString(s.Cluster), s.SortOrder, s.IsActive, s.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// MySQL melaporkan 0 bila nilai tidak berubah; pastikan baris memang ada
+		if _, err := r.GetByID(s.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r StopRepository) Delete(id int64) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`DELETE FROM stops WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -270,3 +270,13 @@ func (r TripInformationRepository) UpsertTripInfo(info TripInformation) error {
 	_, err := db.Exec(`UPDATE `+table+` SET `+strings.Join(sets, ",")+` WHERE id=?`, args...)
 	return err
 }
+
+// DeleteByBookingTx menghapus trip_information milik booking (semua trip_role).
+func (r TripInformationRepository) DeleteByBookingTx(tx *sql.Tx, bookingID int64) error {
+	table := "trip_information"
+	if !intdb.HasTable(tx, table) || !intdb.HasColumn(tx, table, "booking_id") {
+		return nil
+	}
+	_, err := tx.Exec(`DELETE FROM `+table+` WHERE booking_id=?`, bookingID)
+	return err
+}
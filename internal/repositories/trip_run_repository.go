@@ -0,0 +1,243 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// Status trip run.
+const (
+	TripRunPlanned   = "planned"
+	TripRunDeparted  = "departed"
+	TripRunReturned  = "returned"
+	TripRunCancelled = "cancelled"
+)
+
+// TripRun adalah satu perjalanan armada (mobil + sopir) yang mengangkut beberapa booking.
+type TripRun struct {
+	ID           int64     `json:"id"`
+	TripSlotID   int64     `json:"tripSlotId,omitempty"`
+	RouteFrom    string    `json:"routeFrom"`
+	RouteTo      string    `json:"routeTo"`
+	TripDate     string    `json:"tripDate"` // YYYY-MM-DD
+	TripTime     string    `json:"tripTime"` // HH:MM
+	TripNumber   string    `json:"tripNumber"`
+	DriverName   string    `json:"driverName"`
+	VehicleCode  string    `json:"vehicleCode"`
+	VehicleType  string    `json:"vehicleType"`
+	Status       string    `json:"status"`
+	Note         string    `json:"note"`
+	BookingCount int       `json:"bookingCount"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// TripRunFilter menyaring daftar run; field kosong = semua.
+type TripRunFilter struct {
+	Date   string
+	From   string
+	To     string
+	Status string
+}
+
+type TripRunRepository struct {
+	DB *sql.DB
+}
+
+func (r TripRunRepository) db() *sql.DB {
+	if r.DB != nil {
+		return r.DB
+	}
+	return intconfig.DB
+}
+
+func (r TripRunRepository) ready() (*sql.DB, error) {
+	db := r.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	if !intdb.HasTable(db, "trip_runs") {
+		return nil, fmt.Errorf("tabel trip_runs belum tersedia, jalankan `migrate up`")
+	}
+	return db, nil
+}
+
+// Available melaporkan apakah migration trip_runs sudah dijalankan.
+func (r TripRunRepository) Available() bool {
+	_, err := r.ready()
+	return err == nil
+}
+
+const tripRunColumns = `id, COALESCE(trip_slot_id, 0), route_from, route_to, DATE_FORMAT(trip_date, '%Y-%m-%d'), trip_time,
+	trip_number, driver_name, vehicle_code, vehicle_type, status, note,
+	(SELECT COUNT(*) FROM bookings b WHERE b.trip_run_id = trip_runs.id), created_at, updated_at`
+
+func scanTripRun(sc interface{ Scan(...any) error }) (TripRun, error) {
+	var t TripRun
+	err := sc.Scan(&t.ID, &t.TripSlotID, &t.RouteFrom, &t.RouteTo, &t.TripDate, &t.TripTime,
+		&t.TripNumber, &t.DriverName, &t.VehicleCode, &t.VehicleType, &t.Status, &t.Note,
+		&t.BookingCount, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
+
+// List mengembalikan run urut tanggal & jam berangkat.
+func (r TripRunRepository) List(f TripRunFilter) ([]TripRun, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	where := []string{}
+	args := []any{}
+	if v := strings.TrimSpace(f.Date); v != "" {
+		where = append(where, "trip_date = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.From); v != "" {
+		where = append(where, "route_from = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.To); v != "" {
+		where = append(where, "route_to = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(f.Status); v != "" {
+		where = append(where, "status = ?")
+		args = append(args, v)
+	}
+	q := `SELECT ` + tripRunColumns + ` FROM trip_runs`
+	if len(where) > 0 {
+		q += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	rows, err := db.Query(q+` ORDER BY trip_date ASC, trip_time ASC, id ASC LIMIT 500`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TripRun{}
+	for rows.Next() {
+		t, err := scanTripRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (r TripRunRepository) GetByID(id int64) (TripRun, error) {
+	db, err := r.ready()
+	if err != nil {
+		return TripRun{}, err
+	}
+	return scanTripRun(db.QueryRow(`SELECT `+tripRunColumns+` FROM trip_runs WHERE id = ?`, id))
+}
+
+func (r TripRunRepository) Create(t TripRun) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	if t.Status == "" {
+		t.Status = TripRunPlanned
+	}
+	res, err := db.Exec(`
+		INSERT INTO trip_runs (trip_slot_id, route_from, route_to, trip_date, trip_time, trip_number,
+			driver_name, vehicle_code, vehicle_type, status, note)
+		VALUES (NULLIF(?, 0), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.TripSlotID, t.RouteFrom, t.RouteTo, t.TripDate, t.TripTime, t.TripNumber,
+		t.DriverName, t.VehicleCode, t.VehicleType, t.Status, t.Note)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Update menyimpan jadwal, assignment sopir/kendaraan, status dan catatan run.
+func (r TripRunRepository) Update(t TripRun) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	return r.UpdateTx(db, t)
+}
+
+// UpdateTx sama dengan Update di dalam transaksi pemanggil.
+func (r TripRunRepository) UpdateTx(q intdb.Queryer, t TripRun) error {
+	_, err := q.Exec(`
+		UPDATE trip_runs SET trip_date=?, trip_time=?, trip_number=?, driver_name=?, vehicle_code=?, vehicle_type=?,
+			status=?, note=?
+		WHERE id=?`,
+		t.TripDate, t.TripTime, t.TripNumber, t.DriverName, t.VehicleCode, t.VehicleType, t.Status, t.Note, t.ID)
+	return err
+}
+
+// AttachBookings memindahkan booking ke run (booking yang sudah ada di run lain ikut pindah).
+func (r TripRunRepository) AttachBookings(runID int64, bookingIDs []int64) error {
+	db, err := r.ready()
+	if err != nil {
+		return err
+	}
+	if len(bookingIDs) == 0 {
+		return nil
+	}
+	ph := make([]string, len(bookingIDs))
+	args := []any{runID}
+	for i, id := range bookingIDs {
+		ph[i] = "?"
+		args = append(args, id)
+	}
+	_, err = db.Exec(`UPDATE bookings SET trip_run_id=? WHERE id IN (`+strings.Join(ph, ",")+`)`, args...)
+	return err
+}
+
+// DetachBooking mengeluarkan booking dari run; false bila booking tidak ada di run tsb.
+func (r TripRunRepository) DetachBooking(runID, bookingID int64) (bool, error) {
+	db, err := r.ready()
+	if err != nil {
+		return false, err
+	}
+	res, err := db.Exec(`UPDATE bookings SET trip_run_id=NULL WHERE id=? AND trip_run_id=?`, bookingID, runID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// BookingIDs mengembalikan booking yang terpasang di run.
+func (r TripRunRepository) BookingIDs(runID int64) ([]int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT id FROM bookings WHERE trip_run_id = ? ORDER BY id`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// RunOfBooking mengembalikan id run booking (0 bila belum masuk run).
+func (r TripRunRepository) RunOfBooking(bookingID int64) (int64, error) {
+	db, err := r.ready()
+	if err != nil {
+		return 0, err
+	}
+	var runID int64
+	err = db.QueryRow(`SELECT COALESCE(trip_run_id, 0) FROM bookings WHERE id = ?`, bookingID).Scan(&runID)
+	return runID, err
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+	"backend/internal/domain/models"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+)
+
+// AssignmentGuard mencegah sopir/kendaraan yang sama ditugaskan ke dua perjalanan yang
+// waktunya bentrok (termasuk jeda istirahat sopir setelah leg pulang).
+type AssignmentGuard struct {
+	Fleet     repositories.FleetRepository
+	Routes    RouteService
+	Policy    AssignmentPolicy
+	RequestID string
+	Actor     repositories.StatusActor
+}
+
+// forceRequested membaca flag "force" dari payload mentah (true, "true" atau 1).
+func forceRequested(raw []byte) bool {
+	var payload struct {
+		Force any `json:"force"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return false
+	}
+	switch v := payload.Force.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(strings.TrimSpace(v), "true") || strings.TrimSpace(v) == "1"
+	case float64:
+		return v == 1
+	}
+	return false
+}
+
+// settingAssignment mengubah baris departure/return settings menjadi kandidat penugasan.
+func settingAssignment(source string, dep models.DepartureSetting) repositories.FleetAssignment {
+	clip := func(s string, n int) string {
+		s = strings.TrimSpace(s)
+		if len(s) > n {
+			return s[:n]
+		}
+		return s
+	}
+	return repositories.FleetAssignment{
+		Source:      source,
+		ID:          int64(dep.ID),
+		BookingID:   dep.BookingID,
+		TripNumber:  dep.TripNumber,
+		DriverName:  dep.DriverName,
+		VehicleCode: dep.VehicleCode,
+		Date:        clip(dep.DepartureDate, 10),
+		Time:        clip(dep.DepartureTime, 5),
+		RouteFrom:   dep.RouteFrom,
+		RouteTo:     dep.RouteTo,
+	}
+}
+
+// sameName membandingkan nama sopir / kode kendaraan tanpa memperhatikan huruf besar & spasi.
+func sameName(a, b string) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	return a != "" && strings.EqualFold(a, b)
+}
+
+// compatible: kosong di salah satu sisi dianggap cocok.
+func compatible(a, b string) bool {
+	return strings.TrimSpace(a) == "" || strings.TrimSpace(b) == "" || sameName(a, b)
+}
+
+// sameTrip: dua baris penugasan adalah satu perjalanan yang sama (mis. beberapa booking dalam
+// satu mobil) bila tanggal & jam sama, sopir & kendaraan tidak berbeda, dan no. trip atau arah
+// jalurnya sama.
+func sameTrip(a, b repositories.FleetAssignment, est RouteEstimator) bool {
+	if a.Date != b.Date || a.Time != b.Time {
+		return false
+	}
+	if !compatible(a.DriverName, b.DriverName) || !compatible(a.VehicleCode, b.VehicleCode) {
+		return false
+	}
+	if sameName(a.TripNumber, b.TripNumber) {
+		return true
+	}
+	ra, da, okA := est.Direction(a.RouteFrom, a.RouteTo)
+	rb, db, okB := est.Direction(b.RouteFrom, b.RouteTo)
+	if okA && okB {
+		return ra == rb && da == db
+	}
+	return NormalizeStopKey(a.RouteFrom) == NormalizeStopKey(b.RouteFrom) &&
+		NormalizeStopKey(a.RouteTo) == NormalizeStopKey(b.RouteTo)
+}
+
+// assignmentConflicts mengembalikan alasan bentrok kandidat dengan penugasan lain (sopir atau
+// kendaraan sama). Baris milik booking di skipBookings dan kandidat itu sendiri diabaikan.
+func assignmentConflicts(candidate repositories.FleetAssignment, others []repositories.FleetAssignment,
+	skipBookings map[int64]bool, est RouteEstimator, p AssignmentPolicy) []string {
+	start, err := time.ParseInLocation("2006-01-02 15:04", candidate.Date+" "+candidate.Time, time.Local)
+	if err != nil {
+		return nil
+	}
+	need := slotNeed{
+		Start:     start,
+		Duration:  est.Duration(candidate.RouteFrom, candidate.RouteTo),
+		ReturnLeg: candidate.Source == repositories.AssignmentReturn,
+	}
+	out := []string{}
+	for _, a := range others {
+		if a.Source == candidate.Source && a.ID == candidate.ID {
+			continue
+		}
+		if a.BookingID > 0 && skipBookings[a.BookingID] {
+			continue
+		}
+		if sameTrip(candidate, a, est) {
+			continue
+		}
+		if sameName(candidate.DriverName, a.DriverName) {
+			if reason := busyReason(need, a, p, true, est.Duration); reason != "" {
+				out = append(out, "sopir "+strings.TrimSpace(candidate.DriverName)+" "+reason)
+				continue
+			}
+		}
+		if sameName(candidate.VehicleCode, a.VehicleCode) {
+			if reason := busyReason(need, a, p, false, est.Duration); reason != "" {
+				out = append(out, "kendaraan "+strings.TrimSpace(candidate.VehicleCode)+" "+reason)
+			}
+		}
+	}
+	return out
+}
+
+// Check memvalidasi kandidat terhadap semua penugasan H-1 s/d H+1. Bentrok dikembalikan sebagai
+// domain.ConflictError berisi no. trip yang bentrok; force=true dari admin melewati cek dan dicatat di log.
+func (g AssignmentGuard) Check(candidate repositories.FleetAssignment, skipBookings []int64, force bool) error {
+	if strings.TrimSpace(candidate.DriverName) == "" && strings.TrimSpace(candidate.VehicleCode) == "" {
+		return nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", candidate.Date, time.Local)
+	if err != nil || len(candidate.Time) != 5 {
+		return nil
+	}
+	p := g.Policy.normalized()
+	est, err := g.Routes.Estimator(p.TripDuration)
+	if err != nil {
+		return err
+	}
+	others, err := g.Fleet.Assignments(day.AddDate(0, 0, -1).Format("2006-01-02"), day.AddDate(0, 0, 1).Format("2006-01-02"))
+	if err != nil {
+		return domain.InternalError{Msg: "gagal memuat penugasan armada", Err: err}
+	}
+	skip := map[int64]bool{}
+	for _, id := range skipBookings {
+		skip[id] = true
+	}
+	conflicts := assignmentConflicts(candidate, others, skip, est, p)
+	if len(conflicts) == 0 {
+		return nil
+	}
+	msg := strings.Join(conflicts, "; ")
+	if force && g.Actor.Role == domain.RoleAdmin {
+		utils.LogEvent(g.RequestID, "assignment", "force_override",
+			fmt.Sprintf("%s id=%d by user=%d role=%s: %s", candidate.Source, candidate.ID, g.Actor.UserID, g.Actor.Role, msg))
+		return nil
+	}
+	return domain.ConflictError{Resource: "assignment", Msg: "Jadwal bentrok: " + msg + ". Kirim force=true untuk tetap menyimpan"}
+}
+
+// CheckTx menjalankan Check di dalam transaksi yang akan menulis penugasan. Baris sopir &
+// kendaraan kandidat dikunci lebih dulu sehingga dua penugasan bersamaan untuk sopir/kendaraan
+// yang sama dicek bergantian, dan cek kedua melihat penugasan pertama yang sudah di-commit.
+func (g AssignmentGuard) CheckTx(q intdb.Queryer, candidate repositories.FleetAssignment, skipBookings []int64, force bool) error {
+	if err := g.Fleet.LockTx(q, candidate.DriverName, candidate.VehicleCode); err != nil {
+		return domain.InternalError{Msg: "gagal mengunci sopir/kendaraan", Err: err}
+	}
+	return g.Check(candidate, skipBookings, force)
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/repositories"
+)
+
+func TestAssignmentConflictsDriverAndVehicle(t *testing.T) {
+	est := testEstimator([]int{0, 60, 180, 240})
+	policy := AssignmentPolicy{TripDuration: 5 * time.Hour, RestAfterReturn: 8 * time.Hour}
+	candidate := repositories.FleetAssignment{Source: repositories.AssignmentDeparture, ID: 1, DriverName: "Andi", VehicleCode: "LK-01",
+		Date: "2026-10-20", Time: "08:00", RouteFrom: "SKPD", RouteTo: "Pekanbaru"}
+	others := []repositories.FleetAssignment{
+		// booking lain di mobil & jam yang sama: satu perjalanan, bukan bentrok
+		{Source: repositories.AssignmentDeparture, ID: 2, DriverName: "andi ", VehicleCode: "LK-01", Date: "2026-10-20", Time: "08:00", RouteFrom: "Ujung Batu", RouteTo: "Pekanbaru"},
+		// Andi sudah di trip lain jam 10:00 (SKPD-Pekanbaru 4 jam -> tiba 12:00)
+		{Source: repositories.AssignmentDeparture, ID: 3, TripNumber: "TR-07", DriverName: "Andi", VehicleCode: "LK-09", Date: "2026-10-20", Time: "10:00", RouteFrom: "SKPD", RouteTo: "Pekanbaru"},
+		// LK-01 dipakai sopir lain jam 11:00
+		{Source: repositories.AssignmentTripRun, ID: 4, TripNumber: "TR-08", DriverName: "Budi", VehicleCode: "LK-01", Date: "2026-10-20", Time: "11:00", RouteFrom: "Pekanbaru", RouteTo: "SKPD"},
+		// jam 12:00 sudah lewat (tiba 12:00): tidak bentrok
+		{Source: repositories.AssignmentDeparture, ID: 5, DriverName: "Budi", VehicleCode: "LK-01", Date: "2026-10-20", Time: "12:00", RouteFrom: "Pekanbaru", RouteTo: "SKPD"},
+	}
+	got := assignmentConflicts(candidate, others, nil, est, policy)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 conflicts, got %v", got)
+	}
+	if !strings.Contains(got[0], "sopir Andi") || !strings.Contains(got[0], "TR-07") {
+		t.Fatalf("expected driver conflict with TR-07, got %q", got[0])
+	}
+	if !strings.Contains(got[1], "kendaraan LK-01") || !strings.Contains(got[1], "TR-08") {
+		t.Fatalf("expected vehicle conflict with TR-08, got %q", got[1])
+	}
+
+	// baris milik booking yang di-skip (mis. anggota trip run) diabaikan
+	others[1].BookingID = 77
+	others[2].BookingID = 77
+	if got := assignmentConflicts(candidate, others, map[int64]bool{77: true}, est, policy); len(got) != 0 {
+		t.Fatalf("skipped bookings must not conflict, got %v", got)
+	}
+}
+
+func TestAssignmentConflictsRestAfterReturn(t *testing.T) {
+	est := testEstimator(nil)
+	policy := AssignmentPolicy{TripDuration: 6 * time.Hour, RestAfterReturn: 8 * time.Hour}
+	// Andi pulang 20:00 kemarin -> tiba 02:00, istirahat sampai 10:00
+	others := []repositories.FleetAssignment{
+		{Source: repositories.AssignmentReturn, ID: 9, DriverName: "Andi", VehicleCode: "LK-02", Date: "2026-10-19", Time: "20:00", RouteFrom: "Pekanbaru", RouteTo: "SKPD"},
+	}
+	early := repositories.FleetAssignment{Source: repositories.AssignmentDeparture, ID: 1, DriverName: "Andi", Date: "2026-10-20", Time: "07:00", RouteFrom: "SKPD", RouteTo: "Pekanbaru"}
+	got := assignmentConflicts(early, others, nil, est, policy)
+	if len(got) != 1 || !strings.Contains(got[0], "istirahat") || !strings.Contains(got[0], "return #9") {
+		t.Fatalf("expected rest conflict labelled by id, got %v", got)
+	}
+	early.Time = "10:00"
+	if got := assignmentConflicts(early, others, nil, est, policy); len(got) != 0 {
+		t.Fatalf("driver is rested at 10:00, got %v", got)
+	}
+	// kendaraan tidak butuh istirahat
+	vehicleOnly := repositories.FleetAssignment{Source: repositories.AssignmentDeparture, ID: 1, VehicleCode: "LK-02", Date: "2026-10-20", Time: "07:00", RouteFrom: "SKPD", RouteTo: "Pekanbaru"}
+	if got := assignmentConflicts(vehicleOnly, others, nil, est, policy); len(got) != 0 {
+		t.Fatalf("vehicle has no rest period, got %v", got)
+	}
+}
+
+func TestSameTripRequiresMatchingCrew(t *testing.T) {
+	est := testEstimator(nil)
+	a := repositories.FleetAssignment{DriverName: "Andi", VehicleCode: "LK-01", Date: "2026-10-20", Time: "08:00", RouteFrom: "SKPD", RouteTo: "Pekanbaru"}
+	b := a
+	b.VehicleCode = "LK-02"
+	if sameTrip(a, b, est) {
+		t.Fatalf("same driver in two vehicles is not one trip")
+	}
+	b = a
+	b.RouteFrom, b.RouteTo = "Pekanbaru", "SKPD"
+	if sameTrip(a, b, est) {
+		t.Fatalf("opposite direction is not one trip")
+	}
+	b.TripNumber, a.TripNumber = "TR-01", "tr-01"
+	if !sameTrip(a, b, est) {
+		t.Fatalf("same trip number at the same time is one trip")
+	}
+}
+
+func TestForceRequested(t *testing.T) {
+	for raw, want := range map[string]bool{
+		`{"force": true}`:   true,
+		`{"force": "true"}`: true,
+		`{"force": 1}`:      true,
+		`{"force": false}`:  false,
+		`{"driver_name":1}`: false,
+		`not json`:          false,
+	} {
+		if got := forceRequested([]byte(raw)); got != want {
+			t.Fatalf("forceRequested(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
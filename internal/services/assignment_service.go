@@ -0,0 +1,666 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+)
+
+// Default kebijakan assignment bila env tidak diatur.
+const (
+	DefaultTripDuration    = 5 * time.Hour
+	DefaultRestAfterReturn = 8 * time.Hour
+)
+
+// maxAssignmentAlternatives adalah jumlah alternatif yang ditampilkan selain saran utama.
+const maxAssignmentAlternatives = 4
+
+// AssignmentPolicy menentukan lama satu perjalanan (window bentrok sopir/kendaraan) dan
+// waktu istirahat minimal sopir setelah menyelesaikan leg pulang.
+type AssignmentPolicy struct {
+	TripDuration    time.Duration
+	RestAfterReturn time.Duration
+}
+
+func (p AssignmentPolicy) normalized() AssignmentPolicy {
+	if p.TripDuration <= 0 {
+		p.TripDuration = DefaultTripDuration
+	}
+	if p.RestAfterReturn < 0 {
+		p.RestAfterReturn = 0
+	}
+	return p
+}
+
+// AssignmentOption adalah satu pasangan sopir + kendaraan yang layak untuk slot, beserta skornya.
+type AssignmentOption struct {
+	DriverName  string   `json:"driverName"`
+	VehicleCode string   `json:"vehicleCode"`
+	VehicleType string   `json:"vehicleType"`
+	PlateNumber string   `json:"plateNumber"`
+	Capacity    int      `json:"capacity"`
+	Score       int      `json:"score"`
+	Reasons     []string `json:"reasons"`
+}
+
+// AssignmentRejection menjelaskan kenapa sopir/kendaraan tidak disarankan.
+type AssignmentRejection struct {
+	Kind   string `json:"kind"` // driver | vehicle
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// SlotSuggestion adalah saran penugasan untuk satu trip slot.
+type SlotSuggestion struct {
+	Slot           repositories.TripSlot        `json:"slot"`
+	RouteFrom      string                       `json:"routeFrom"`
+	RouteTo        string                       `json:"routeTo"`
+	Passengers     int                          `json:"passengers"`
+	Seats          []string                     `json:"seats"`
+	Departures     []repositories.SlotDeparture `json:"departures"`
+	CurrentDriver  string                       `json:"currentDriver"`
+	CurrentVehicle string                       `json:"currentVehicle"`
+	Suggestion     *AssignmentOption            `json:"suggestion"`
+	Alternatives   []AssignmentOption           `json:"alternatives"`
+	Rejected       []AssignmentRejection        `json:"rejected"`
+}
+
+// AssignmentApplyInput memilih slot yang sarannya diterapkan: SlotIDs, atau semua slot pada Date.
+// Overwrite=false hanya mengisi keberangkatan yang sopir & kendaraannya masih kosong.
+type AssignmentApplyInput struct {
+	Date      string
+	SlotIDs   []int64
+	Overwrite bool
+}
+
+// AppliedAssignment adalah hasil penerapan saran pada satu slot.
+type AppliedAssignment struct {
+	SlotID      int64  `json:"slotId"`
+	Date        string `json:"date"`
+	Time        string `json:"time"`
+	RouteFrom   string `json:"routeFrom"`
+	RouteTo     string `json:"routeTo"`
+	DriverName  string `json:"driverName,omitempty"`
+	VehicleCode string `json:"vehicleCode,omitempty"`
+	Departures  int    `json:"departures"`
+	Runs        int    `json:"runs"`
+	Skipped     string `json:"skipped,omitempty"`
+}
+
+// slotNeed adalah kebutuhan satu slot yang dicocokkan dengan armada.
+type slotNeed struct {
+	Origin         string
+	Start          time.Time
+	Duration       time.Duration // 0 = policy TripDuration
+	ReturnLeg      bool
+	Passengers     int
+	Seats          []string
+	VehicleType    string
+	CurrentDriver  string
+	CurrentVehicle string
+}
+
+// fleetSnapshot adalah data armada yang dipakai untuk menilai kandidat.
+type fleetSnapshot struct {
+	Drivers  []repositories.FleetDriver
+	Vehicles []repositories.FleetVehicle
+	Layouts  []repositories.SeatLayout
+	Busy     []repositories.FleetAssignment
+	// Estimate memperkirakan lama perjalanan penugasan lain; nil = policy TripDuration.
+	Estimate func(from, to string) time.Duration
+}
+
+// AssignmentService menyarankan sopir/kendaraan per trip slot berdasarkan kapasitas & denah
+// kursi, status servis kendaraan, jadwal sopir/kendaraan lain (termasuk istirahat setelah
+// leg pulang) dan home base, lalu bisa menerapkannya ke departure_settings & trip run.
+type AssignmentService struct {
+	Fleet      repositories.FleetRepository
+	Schedules  repositories.ScheduleRepository
+	Routes     RouteService
+	Layouts    repositories.SeatLayoutRepository
+	Departures repositories.DepartureRepository
+	Runs       TripRunService
+	Guard      AssignmentGuard
+	Policy     AssignmentPolicy
+	RequestID  string
+}
+
+func (s AssignmentService) db() *sql.DB {
+	if s.Departures.DB != nil {
+		return s.Departures.DB
+	}
+	return intconfig.DB
+}
+
+// sameBase membandingkan home base dengan nama halte (tanpa beda spasi/huruf besar).
+func sameBase(base, stop string) bool {
+	b := NormalizeStopKey(base)
+	return b != "" && b == NormalizeStopKey(stop)
+}
+
+// overlaps melaporkan apakah [s1,e1) dan [s2,e2) beririsan.
+func overlaps(s1, e1, s2, e2 time.Time) bool {
+	return s1.Before(e2) && s2.Before(e1)
+}
+
+// tripLabel adalah no. trip penugasan, atau sumber + id bila no. trip belum diisi.
+func tripLabel(a repositories.FleetAssignment) string {
+	if v := strings.TrimSpace(a.TripNumber); v != "" {
+		return v
+	}
+	return fmt.Sprintf("%s #%d", a.Source, a.ID)
+}
+
+func describeAssignment(a repositories.FleetAssignment) string {
+	label := map[string]string{
+		repositories.AssignmentDeparture: "keberangkatan",
+		repositories.AssignmentReturn:    "kepulangan",
+		repositories.AssignmentTripRun:   "trip run",
+	}[a.Source]
+	return fmt.Sprintf("%s %s (%s-%s %s %s)", label, tripLabel(a), a.RouteFrom, a.RouteTo, a.Date, a.Time)
+}
+
+// busyReason mengembalikan alasan bentrok penugasan a dengan slot; kosong bila tidak bentrok.
+// withRest: jeda istirahat setelah leg pulang ikut dihitung (hanya untuk sopir).
+func busyReason(need slotNeed, a repositories.FleetAssignment, p AssignmentPolicy, withRest bool, estimate func(from, to string) time.Duration) string {
+	start, err := time.ParseInLocation("2006-01-02 15:04", a.Date+" "+a.Time, need.Start.Location())
+	if err != nil {
+		return ""
+	}
+	slotDur, dur := need.Duration, p.TripDuration
+	if slotDur <= 0 {
+		slotDur = p.TripDuration
+	}
+	if estimate != nil {
+		dur = estimate(a.RouteFrom, a.RouteTo)
+	}
+	slotEnd, end := need.Start.Add(slotDur), start.Add(dur)
+	if overlaps(need.Start, slotEnd, start, end) {
+		return "bentrok dengan " + describeAssignment(a)
+	}
+	if !withRest || p.RestAfterReturn == 0 {
+		return ""
+	}
+	if a.Source == repositories.AssignmentReturn {
+		end = end.Add(p.RestAfterReturn)
+	}
+	if need.ReturnLeg {
+		slotEnd = slotEnd.Add(p.RestAfterReturn)
+	}
+	if overlaps(need.Start, slotEnd, start, end) {
+		return "belum cukup istirahat setelah pulang (" + describeAssignment(a) + ")"
+	}
+	return ""
+}
+
+// vehicleFit mengecek tipe, kapasitas dan denah kursi kendaraan untuk slot. Kapasitas 0 berarti
+// denah tidak diketahui (tabel seat_layouts belum ada).
+func vehicleFit(need slotNeed, v repositories.FleetVehicle, layouts []repositories.SeatLayout) (string, int, string) {
+	vtype := strings.ToLower(strings.TrimSpace(v.VehicleType))
+	var layout *repositories.SeatLayout
+	for i := range layouts {
+		l := &layouts[i]
+		if (vtype != "" && strings.EqualFold(l.VehicleType, vtype)) || (vtype == "" && l.IsDefault) {
+			layout = l
+			break
+		}
+	}
+	if vtype == "" && layout != nil {
+		vtype = strings.ToLower(layout.VehicleType)
+	}
+	if need.VehicleType != "" && vtype != "" && !strings.EqualFold(need.VehicleType, vtype) {
+		return vtype, 0, fmt.Sprintf("tipe %s, slot memakai %s", vtype, need.VehicleType)
+	}
+	if layout == nil {
+		return vtype, 0, ""
+	}
+
+	disabled := map[string]bool{}
+	for _, code := range layout.DisabledSeats {
+		disabled[strings.ToUpper(strings.TrimSpace(code))] = true
+	}
+	usable := map[string]bool{}
+	for _, cell := range layout.Seats {
+		if code := strings.ToUpper(strings.TrimSpace(cell.Code)); code != "" && !disabled[code] {
+			usable[code] = true
+		}
+	}
+	capacity := layout.Capacity
+	if capacity <= 0 || capacity > len(usable) {
+		capacity = len(usable)
+	}
+	if need.Passengers > capacity {
+		return vtype, capacity, fmt.Sprintf("kapasitas %d kursi, slot berisi %d penumpang", capacity, need.Passengers)
+	}
+	missing := []string{}
+	for _, seat := range need.Seats {
+		if !usable[strings.ToUpper(seat)] {
+			missing = append(missing, seat)
+		}
+	}
+	if len(missing) > 0 {
+		return vtype, capacity, "kursi " + strings.Join(missing, ", ") + " tidak ada di denah " + layout.Name
+	}
+	return vtype, capacity, ""
+}
+
+// rankAssignments menilai semua pasangan sopir + kendaraan yang layak untuk slot, urut skor
+// tertinggi lalu sisa kursi paling sedikit, beserta alasan kandidat yang ditolak.
+func rankAssignments(need slotNeed, fleet fleetSnapshot, p AssignmentPolicy) ([]AssignmentOption, []AssignmentRejection) {
+	p = p.normalized()
+	rejected := []AssignmentRejection{}
+
+	type fitVehicle struct {
+		repositories.FleetVehicle
+		vtype    string
+		capacity int
+	}
+	vehicles := []fitVehicle{}
+	for _, v := range fleet.Vehicles {
+		reason := ""
+		if v.ServiceStatus != "" && v.ServiceStatus != repositories.VehicleActive {
+			reason = "status " + v.ServiceStatus
+		}
+		vtype, capacity, fitReason := vehicleFit(need, v, fleet.Layouts)
+		if reason == "" {
+			reason = fitReason
+		}
+		for _, a := range fleet.Busy {
+			if reason != "" {
+				break
+			}
+			if strings.EqualFold(strings.TrimSpace(a.VehicleCode), v.VehicleCode) {
+				reason = busyReason(need, a, p, false, fleet.Estimate)
+			}
+		}
+		if reason != "" {
+			rejected = append(rejected, AssignmentRejection{Kind: "vehicle", Name: v.VehicleCode, Reason: reason})
+			continue
+		}
+		vehicles = append(vehicles, fitVehicle{FleetVehicle: v, vtype: vtype, capacity: capacity})
+	}
+
+	drivers := []repositories.FleetDriver{}
+	for _, d := range fleet.Drivers {
+		reason := ""
+		for _, a := range fleet.Busy {
+			if strings.EqualFold(strings.TrimSpace(a.DriverName), strings.TrimSpace(d.Name)) {
+				if reason = busyReason(need, a, p, true, fleet.Estimate); reason != "" {
+					break
+				}
+			}
+		}
+		if reason != "" {
+			rejected = append(rejected, AssignmentRejection{Kind: "driver", Name: d.Name, Reason: reason})
+			continue
+		}
+		drivers = append(drivers, d)
+	}
+
+	options := []AssignmentOption{}
+	for _, d := range drivers {
+		for _, v := range vehicles {
+			opt := AssignmentOption{
+				DriverName:  d.Name,
+				VehicleCode: v.VehicleCode,
+				VehicleType: v.vtype,
+				PlateNumber: v.PlateNumber,
+				Capacity:    v.capacity,
+				Reasons:     []string{},
+			}
+			add := func(points int, reason string) {
+				opt.Score += points
+				opt.Reasons = append(opt.Reasons, reason)
+			}
+			if strings.EqualFold(strings.TrimSpace(d.VehicleAssigned), v.VehicleCode) {
+				add(4, "kendaraan tetap sopir")
+			}
+			if sameBase(d.HomeBase, need.Origin) {
+				add(2, "sopir berbasis di "+need.Origin)
+			}
+			if sameBase(v.HomeBase, need.Origin) {
+				add(2, "kendaraan berada di "+need.Origin)
+			}
+			if d.VehicleType != "" && strings.EqualFold(d.VehicleType, v.vtype) {
+				add(1, "sopir biasa membawa tipe "+v.vtype)
+			}
+			if need.CurrentDriver != "" && strings.EqualFold(need.CurrentDriver, d.Name) &&
+				strings.EqualFold(need.CurrentVehicle, v.VehicleCode) {
+				add(1, "penugasan saat ini")
+			}
+			options = append(options, opt)
+		}
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		a, b := options[i], options[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Capacity != b.Capacity {
+			return a.Capacity < b.Capacity
+		}
+		if a.DriverName != b.DriverName {
+			return a.DriverName < b.DriverName
+		}
+		return a.VehicleCode < b.VehicleCode
+	})
+	sort.SliceStable(rejected, func(i, j int) bool {
+		if rejected[i].Kind != rejected[j].Kind {
+			return rejected[i].Kind < rejected[j].Kind
+		}
+		return rejected[i].Name < rejected[j].Name
+	})
+	return options, rejected
+}
+
+// soleKey mengembalikan satu-satunya key pada set; kosong bila set kosong atau berisi lebih dari satu.
+func soleKey(set map[string]bool) string {
+	if len(set) != 1 {
+		return ""
+	}
+	for k := range set {
+		return k
+	}
+	return ""
+}
+
+func (s AssignmentService) loadSlot(id int64) (repositories.TripSlot, error) {
+	if !s.Schedules.Available() {
+		return repositories.TripSlot{}, domain.InternalError{Msg: "tabel trip_slots belum tersedia, jalankan `migrate up`"}
+	}
+	ts, err := s.Schedules.GetSlot(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ts, domain.NotFoundError{Resource: "trip slot"}
+	}
+	if err != nil {
+		return ts, domain.InternalError{Msg: "gagal memuat trip slot", Err: err}
+	}
+	return ts, nil
+}
+
+// Suggest menghitung saran sopir/kendaraan untuk satu trip slot.
+func (s AssignmentService) Suggest(slotID int64) (SlotSuggestion, error) {
+	ts, err := s.loadSlot(slotID)
+	if err != nil {
+		return SlotSuggestion{}, err
+	}
+	rt, err := s.Routes.Get(ts.RouteID)
+	if err != nil {
+		return SlotSuggestion{}, err
+	}
+	stops, err := s.Routes.Fares.ListStops(false)
+	if err != nil {
+		return SlotSuggestion{}, err
+	}
+	display := map[string]string{}
+	for _, st := range stops {
+		display[st.Key] = st.DisplayName
+	}
+	seq := append([]string{}, rt.Stops...)
+	if ts.Direction == repositories.DirectionReverse {
+		for i, j := 0, len(seq)-1; i < j; i, j = i+1, j-1 {
+			seq[i], seq[j] = seq[j], seq[i]
+		}
+	}
+	out := SlotSuggestion{Slot: ts, Seats: []string{}, Departures: []repositories.SlotDeparture{}, Alternatives: []AssignmentOption{}}
+	if len(seq) > 0 {
+		out.RouteFrom = firstNonEmpty(display[seq[0]], seq[0])
+		out.RouteTo = firstNonEmpty(display[seq[len(seq)-1]], seq[len(seq)-1])
+	}
+
+	deps, err := s.Fleet.SlotDepartures(ts.Date, ts.Time)
+	if err != nil {
+		return out, domain.InternalError{Msg: "gagal memuat keberangkatan slot", Err: err}
+	}
+	own := map[int64]bool{}
+	seen := map[string]bool{}
+	drivers, vehicles := map[string]bool{}, map[string]bool{}
+	for _, d := range deps {
+		from, okFrom := findStop(stops, d.RouteFrom)
+		to, okTo := findStop(stops, d.RouteTo)
+		if !okFrom || !okTo {
+			continue
+		}
+		if dir, ok := routeDirection(rt.Stops, from.Key, to.Key); !ok || dir != ts.Direction {
+			continue
+		}
+		own[d.ID] = true
+		out.Departures = append(out.Departures, d)
+		seats := splitSeats(d.SeatNumbers)
+		out.Passengers += maxInt(d.PassengerCount, len(seats))
+		for _, seat := range seats {
+			if key := strings.ToUpper(seat); !seen[key] {
+				seen[key] = true
+				out.Seats = append(out.Seats, seat)
+			}
+		}
+		if v := strings.TrimSpace(d.DriverName); v != "" {
+			drivers[v] = true
+		}
+		if v := strings.TrimSpace(d.VehicleCode); v != "" {
+			vehicles[v] = true
+		}
+	}
+	out.CurrentDriver, out.CurrentVehicle = soleKey(drivers), soleKey(vehicles)
+
+	start, err := time.ParseInLocation("2006-01-02 15:04", ts.Date+" "+ts.Time, time.Local)
+	if err != nil {
+		return out, domain.InternalError{Msg: "jadwal trip slot tidak valid", Err: err}
+	}
+	p := s.Policy.normalized()
+	est, err := s.Routes.Estimator(p.TripDuration)
+	if err != nil {
+		return out, err
+	}
+	span := p.TripDuration + p.RestAfterReturn
+	busy, err := s.Fleet.Assignments(start.Add(-span).Format("2006-01-02"), start.Add(span).Format("2006-01-02"))
+	if err != nil {
+		return out, domain.InternalError{Msg: "gagal memuat jadwal armada", Err: err}
+	}
+	fleet := fleetSnapshot{Busy: []repositories.FleetAssignment{}, Estimate: est.Duration}
+	for _, a := range busy {
+		if (a.Source == repositories.AssignmentDeparture && own[a.ID]) || (a.Source == repositories.AssignmentTripRun && a.TripSlotID == ts.ID) {
+			continue
+		}
+		fleet.Busy = append(fleet.Busy, a)
+	}
+	if fleet.Drivers, err = s.Fleet.Drivers(); err != nil {
+		return out, domain.InternalError{Msg: "gagal memuat data sopir", Err: err}
+	}
+	if fleet.Vehicles, err = s.Fleet.Vehicles(); err != nil {
+		return out, domain.InternalError{Msg: "gagal memuat data kendaraan", Err: err}
+	}
+	if s.Layouts.Available() {
+		if fleet.Layouts, err = s.Layouts.List(); err != nil {
+			return out, domain.InternalError{Msg: "gagal memuat denah kursi", Err: err}
+		}
+	}
+
+	options, rejected := rankAssignments(slotNeed{
+		Origin:         out.RouteFrom,
+		Start:          start,
+		Duration:       est.Duration(out.RouteFrom, out.RouteTo),
+		ReturnLeg:      ts.Direction == repositories.DirectionReverse,
+		Passengers:     out.Passengers,
+		Seats:          out.Seats,
+		VehicleType:    strings.ToLower(strings.TrimSpace(ts.VehicleType)),
+		CurrentDriver:  out.CurrentDriver,
+		CurrentVehicle: out.CurrentVehicle,
+	}, fleet, p)
+	out.Rejected = rejected
+	if len(options) > 0 {
+		out.Suggestion = &options[0]
+		rest := options[1:]
+		if len(rest) > maxAssignmentAlternatives {
+			rest = rest[:maxAssignmentAlternatives]
+		}
+		out.Alternatives = rest
+	}
+	return out, nil
+}
+
+// Apply menerapkan saran ke slot terpilih, urut jam berangkat. Slot diproses satu per satu
+// sehingga sopir/kendaraan yang baru ditugaskan ikut dianggap sibuk untuk slot berikutnya.
+func (s AssignmentService) Apply(in AssignmentApplyInput) ([]AppliedAssignment, error) {
+	slots := []repositories.TripSlot{}
+	if len(in.SlotIDs) > 0 {
+		for _, id := range uniqueIDs(in.SlotIDs) {
+			ts, err := s.loadSlot(id)
+			if err != nil {
+				return nil, err
+			}
+			slots = append(slots, ts)
+		}
+	} else {
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(in.Date))
+		if err != nil {
+			return nil, domain.ValidationError{Field: "date", Msg: "isi date (YYYY-MM-DD) atau slotIds"}
+		}
+		if !s.Schedules.Available() {
+			return nil, domain.InternalError{Msg: "tabel trip_slots belum tersedia, jalankan `migrate up`"}
+		}
+		if slots, err = s.Schedules.ListSlots(date.Format("2006-01-02"), 0, "", false); err != nil {
+			return nil, domain.InternalError{Msg: "gagal memuat trip slots", Err: err}
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		if slots[i].Date != slots[j].Date {
+			return slots[i].Date < slots[j].Date
+		}
+		return slots[i].Time < slots[j].Time
+	})
+
+	out := []AppliedAssignment{}
+	for _, ts := range slots {
+		res, err := s.applySlot(ts, in.Overwrite)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+func (s AssignmentService) applySlot(ts repositories.TripSlot, overwrite bool) (AppliedAssignment, error) {
+	res := AppliedAssignment{SlotID: ts.ID, Date: ts.Date, Time: ts.Time}
+	sug, err := s.Suggest(ts.ID)
+	if err != nil {
+		return res, err
+	}
+	res.RouteFrom, res.RouteTo = sug.RouteFrom, sug.RouteTo
+	if len(sug.Departures) == 0 {
+		res.Skipped = "belum ada keberangkatan"
+		return res, nil
+	}
+
+	// tanpa overwrite, slot yang sebagian sudah ditugaskan dilengkapi dengan penugasan yang ada
+	driver, vehicle, vtype := "", "", ""
+	if !overwrite && sug.CurrentDriver != "" && sug.CurrentVehicle != "" {
+		driver, vehicle = sug.CurrentDriver, sug.CurrentVehicle
+	} else if sug.Suggestion != nil {
+		driver, vehicle, vtype = sug.Suggestion.DriverName, sug.Suggestion.VehicleCode, sug.Suggestion.VehicleType
+	} else {
+		res.Skipped = "tidak ada sopir/kendaraan yang tersedia"
+		return res, nil
+	}
+	res.DriverName, res.VehicleCode = driver, vehicle
+
+	fields := map[string]string{"driver_name": driver, "vehicle_code": vehicle}
+	if vtype != "" {
+		fields["vehicle_type"] = vtype
+	}
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return res, domain.InternalError{Msg: "gagal menyusun payload assignment", Err: err}
+	}
+	if res.Departures, err = s.assignDepartures(sug.Departures, payload, overwrite); err != nil {
+		return res, err
+	}
+
+	if s.Runs.Runs.Available() {
+		runs, err := s.Runs.Runs.List(repositories.TripRunFilter{Date: ts.Date, Status: repositories.TripRunPlanned})
+		if err != nil {
+			return res, domain.InternalError{Msg: "gagal memuat trip run", Err: err}
+		}
+		for _, run := range runs {
+			if run.TripSlotID != ts.ID || (!overwrite && run.DriverName != "" && run.VehicleCode != "") {
+				continue
+			}
+			a := TripRunAssignment{DriverName: &driver, VehicleCode: &vehicle}
+			if vtype != "" {
+				a.VehicleType = &vtype
+			}
+			if _, err := s.Runs.Assign(run.ID, a); err != nil {
+				return res, err
+			}
+			res.Runs++
+		}
+	}
+	if res.Departures == 0 && res.Runs == 0 {
+		res.Skipped = "sudah ditugaskan"
+		return res, nil
+	}
+	utils.LogEvent(s.RequestID, "assignment", "apply", fmt.Sprintf("slot_id=%d driver=%s vehicle=%s departures=%d runs=%d",
+		ts.ID, driver, vehicle, res.Departures, res.Runs))
+	return res, nil
+}
+
+// assignDepartures menulis sopir/kendaraan ke keberangkatan slot dalam satu transaksi. Setiap
+// baris dikunci lalu dicek AssignmentGuard sebelum ditulis, termasuk penugasan yang sudah ada
+// (overwrite=false); keberangkatan lain di slot yang sama adalah perjalanan yang sama sehingga
+// tidak dihitung bentrok.
+func (s AssignmentService) assignDepartures(deps []repositories.SlotDeparture, payload []byte, overwrite bool) (int, error) {
+	db := s.db()
+	if db == nil {
+		return 0, domain.InternalError{Msg: "db tidak tersedia"}
+	}
+	skip := []int64{}
+	for _, d := range deps {
+		if d.BookingID > 0 {
+			skip = append(skip, d.BookingID)
+		}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, domain.InternalError{Msg: "gagal memulai transaksi", Err: err}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	n := 0
+	for _, d := range deps {
+		id := int(d.ID)
+		current, err := s.Departures.GetForUpdateTx(tx, id)
+		if err != nil {
+			return 0, domain.InternalError{Msg: fmt.Sprintf("gagal membaca keberangkatan %d", d.ID), Err: err}
+		}
+		if !overwrite && (strings.TrimSpace(current.DriverName) != "" || strings.TrimSpace(current.VehicleCode) != "") {
+			continue
+		}
+		preview, changed, err := s.Departures.PreviewTx(tx, id, payload)
+		if err != nil {
+			return 0, domain.InternalError{Msg: fmt.Sprintf("gagal membaca keberangkatan %d", d.ID), Err: err}
+		}
+		if changed {
+			if err := s.Guard.CheckTx(tx, settingAssignment(repositories.AssignmentDeparture, preview), skip, false); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := s.Departures.UpdatePartialTx(tx, id, payload); err != nil {
+			return 0, domain.InternalError{Msg: fmt.Sprintf("gagal update keberangkatan %d", d.ID), Err: err}
+		}
+		n++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, domain.InternalError{Msg: "gagal menyimpan penugasan", Err: err}
+	}
+	return n, nil
+}
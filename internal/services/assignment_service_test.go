@@ -0,0 +1,150 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/repositories"
+)
+
+func assignmentFleet() fleetSnapshot {
+	return fleetSnapshot{
+		Drivers: []repositories.FleetDriver{
+			{Name: "Andi", VehicleAssigned: "LK-01", HomeBase: "Pasir Pengaraian"},
+			{Name: "Budi", HomeBase: "Pekanbaru"},
+		},
+		Vehicles: []repositories.FleetVehicle{
+			{VehicleCode: "LK-01", VehicleType: "reguler", ServiceStatus: repositories.VehicleActive, HomeBase: "Pasir Pengaraian"},
+			{VehicleCode: "LK-02", VehicleType: "reguler", ServiceStatus: repositories.VehicleActive},
+			{VehicleCode: "LK-03", VehicleType: "reguler", ServiceStatus: repositories.VehicleService},
+			{VehicleCode: "HI-01", VehicleType: "hiace", ServiceStatus: repositories.VehicleActive},
+		},
+		Layouts: []repositories.SeatLayout{
+			{VehicleType: "reguler", Name: "Reguler 6 kursi", Capacity: 6, IsDefault: true, Seats: []repositories.SeatCell{
+				{Code: "1A"}, {Code: "2A"}, {Code: "2B"}, {Code: "3A"}, {Code: "3B"}, {Code: "3C"},
+			}},
+			{VehicleType: "hiace", Name: "Hiace 10 kursi", Capacity: 10, Seats: []repositories.SeatCell{
+				{Code: "1A"}, {Code: "2A"}, {Code: "2B"}, {Code: "2C"}, {Code: "3A"}, {Code: "3B"}, {Code: "3C"}, {Code: "4A"}, {Code: "4B"}, {Code: "4C"},
+			}},
+		},
+	}
+}
+
+func rejection(list []AssignmentRejection, kind, name string) string {
+	for _, r := range list {
+		if r.Kind == kind && r.Name == name {
+			return r.Reason
+		}
+	}
+	return ""
+}
+
+func TestRankAssignmentsPrefersHomeBaseAndFixedVehicle(t *testing.T) {
+	start := time.Date(2026, 10, 20, 8, 0, 0, 0, time.Local)
+	need := slotNeed{Origin: "Pasir Pengaraian", Start: start, Passengers: 4, Seats: []string{"1A", "2B"}}
+	options, rejected := rankAssignments(need, assignmentFleet(), AssignmentPolicy{})
+
+	if len(options) == 0 {
+		t.Fatalf("expected options, rejected=%v", rejected)
+	}
+	best := options[0]
+	if best.DriverName != "Andi" || best.VehicleCode != "LK-01" || best.Capacity != 6 {
+		t.Fatalf("unexpected best option %+v", best)
+	}
+	if best.Score != 8 {
+		t.Fatalf("expected fixed vehicle + both home bases (8), got %d %v", best.Score, best.Reasons)
+	}
+	if !strings.Contains(rejection(rejected, "vehicle", "LK-03"), "service") {
+		t.Fatalf("vehicle in service must be rejected, got %v", rejected)
+	}
+	for _, opt := range options {
+		if opt.VehicleCode == "LK-03" {
+			t.Fatalf("vehicle in service must not be suggested")
+		}
+	}
+	// sisa kursi paling sedikit didahulukan bila skor sama
+	for i, opt := range options {
+		if opt.DriverName == "Budi" && opt.VehicleCode == "HI-01" {
+			for _, prev := range options[:i] {
+				if prev.DriverName == "Budi" && prev.VehicleCode == "LK-02" {
+					return
+				}
+			}
+			t.Fatalf("smaller vehicle should rank before larger one with the same score: %+v", options)
+		}
+	}
+}
+
+func TestRankAssignmentsCapacityAndSeatLayout(t *testing.T) {
+	start := time.Date(2026, 10, 20, 8, 0, 0, 0, time.Local)
+	need := slotNeed{Start: start, Passengers: 8}
+	options, rejected := rankAssignments(need, assignmentFleet(), AssignmentPolicy{})
+	for _, opt := range options {
+		if opt.VehicleCode != "HI-01" {
+			t.Fatalf("only the 10-seat vehicle fits 8 passengers, got %+v", opt)
+		}
+	}
+	if !strings.Contains(rejection(rejected, "vehicle", "LK-02"), "kapasitas 6") {
+		t.Fatalf("expected capacity rejection, got %v", rejected)
+	}
+
+	need = slotNeed{Start: start, Passengers: 1, Seats: []string{"4C"}}
+	_, rejected = rankAssignments(need, assignmentFleet(), AssignmentPolicy{})
+	if !strings.Contains(rejection(rejected, "vehicle", "LK-01"), "4C") {
+		t.Fatalf("seat missing from layout must be rejected, got %v", rejected)
+	}
+
+	need = slotNeed{Start: start, Passengers: 1, VehicleType: "hiace"}
+	options, _ = rankAssignments(need, assignmentFleet(), AssignmentPolicy{})
+	for _, opt := range options {
+		if opt.VehicleType != "hiace" {
+			t.Fatalf("slot vehicle type must be respected, got %+v", opt)
+		}
+	}
+}
+
+func TestRankAssignmentsDriverAvailability(t *testing.T) {
+	policy := AssignmentPolicy{TripDuration: 5 * time.Hour, RestAfterReturn: 8 * time.Hour}
+	start := time.Date(2026, 10, 20, 8, 0, 0, 0, time.Local)
+	fleet := assignmentFleet()
+	fleet.Busy = []repositories.FleetAssignment{
+		// Andi masih dalam perjalanan lain yang berangkat 06:00
+		{Source: repositories.AssignmentDeparture, ID: 7, DriverName: "Andi", VehicleCode: "LK-02", Date: "2026-10-20", Time: "06:00"},
+		// Budi pulang kemarin malam 21:00 -> tiba 02:00, istirahat sampai 10:00
+		{Source: repositories.AssignmentReturn, ID: 9, DriverName: "Budi", Date: "2026-10-19", Time: "21:00"},
+	}
+	options, rejected := rankAssignments(slotNeed{Start: start, Passengers: 1}, fleet, policy)
+	if len(options) != 0 {
+		t.Fatalf("no driver should be available, got %+v", options)
+	}
+	if !strings.Contains(rejection(rejected, "driver", "Andi"), "bentrok") {
+		t.Fatalf("expected overlap rejection, got %v", rejected)
+	}
+	if !strings.Contains(rejection(rejected, "driver", "Budi"), "istirahat") {
+		t.Fatalf("expected rest rejection, got %v", rejected)
+	}
+	if !strings.Contains(rejection(rejected, "vehicle", "LK-02"), "bentrok") {
+		t.Fatalf("vehicle on another run must be rejected, got %v", rejected)
+	}
+
+	// jam 11:00 Budi sudah cukup istirahat, Andi masih di jalan sampai 11:00 (tidak bentrok lagi)
+	later := slotNeed{Start: time.Date(2026, 10, 20, 11, 0, 0, 0, time.Local), Passengers: 1}
+	options, _ = rankAssignments(later, fleet, policy)
+	drivers := map[string]bool{}
+	for _, opt := range options {
+		drivers[opt.DriverName] = true
+	}
+	if !drivers["Andi"] || !drivers["Budi"] {
+		t.Fatalf("both drivers should be available at 11:00, got %v", drivers)
+	}
+}
+
+func TestSoleKey(t *testing.T) {
+	if soleKey(map[string]bool{"Andi": true}) != "Andi" {
+		t.Fatalf("expected single key")
+	}
+	if soleKey(map[string]bool{"Andi": true, "Budi": true}) != "" || soleKey(nil) != "" {
+		t.Fatalf("expected empty key for mixed/empty set")
+	}
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+)
+
+// Alasan pencabutan session yang disimpan di user_sessions.revoke_reason.
+const (
+	RevokeReasonLogout         = "logout"
+	RevokeReasonLogoutAll      = "logout_all"
+	RevokeReasonReuse          = "refresh_reuse"
+	RevokeReasonUserInactive   = "user_inactive"
+	RevokeReasonPasswordChange = "password_changed"
+	RevokeReasonUserDeleted    = "user_deleted"
+	RevokeReasonRoleChange     = "role_changed"
+)
+
+// DeviceInfo menjelaskan perangkat yang membuka session.
+type DeviceInfo struct {
+	Name      string
+	UserAgent string
+	IP        string
+}
+
+// SessionTokens adalah pasangan token yang dikembalikan ke client setelah login/refresh.
+type SessionTokens struct {
+	AccessToken      string    `json:"token"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// AuthService mengelola access token + refresh token berbasis tabel user_sessions.
+type AuthService struct {
+	DB         *sql.DB
+	Tokens     TokenService
+	Sessions   repositories.SessionRepository
+	RefreshTTL time.Duration
+	RequestID  string
+}
+
+func (s AuthService) db() *sql.DB {
+	if s.DB != nil {
+		return s.DB
+	}
+	if s.Sessions.DB != nil {
+		return s.Sessions.DB
+	}
+	return intconfig.DB
+}
+
+func (s AuthService) now() time.Time {
+	return s.Tokens.now()
+}
+
+func (s AuthService) refreshTTL() time.Duration {
+	if s.RefreshTTL > 0 {
+		return s.RefreshTTL
+	}
+	return 30 * 24 * time.Hour
+}
+
+// StartSession dipanggil setelah password terverifikasi; membuat family session baru.
+func (s AuthService) StartSession(userID int64, name, role string, device DeviceInfo) (SessionTokens, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return SessionTokens{}, domain.InternalError{Msg: "gagal membuat session", Err: err}
+	}
+	refresh, err := randomToken(32)
+	if err != nil {
+		return SessionTokens{}, domain.InternalError{Msg: "gagal membuat session", Err: err}
+	}
+
+	now := s.now()
+	sess := repositories.UserSession{
+		UserID:     userID,
+		FamilyID:   familyID,
+		TokenHash:  HashRefreshToken(refresh),
+		DeviceName: device.Name,
+		UserAgent:  device.UserAgent,
+		IPAddress:  device.IP,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(s.refreshTTL()),
+	}
+	if _, err := s.Sessions.Create(sess); err != nil {
+		return SessionTokens{}, domain.InternalError{Msg: "gagal menyimpan session", Err: err}
+	}
+
+	access, exp, err := s.Tokens.Issue(userID, name, role, familyID)
+	if err != nil {
+		return SessionTokens{}, err
+	}
+	utils.LogEvent(s.RequestID, "auth", "session_start", "user="+strconv.FormatInt(userID, 10)+" family="+familyID)
+
+	return SessionTokens{
+		AccessToken:      access,
+		ExpiresAt:        exp,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: sess.ExpiresAt,
+	}, nil
+}
+
+// Refresh menukar refresh token dengan pasangan token baru (rotasi).
+// Refresh token yang sudah pernah dirotasi dianggap dicuri: seluruh family dicabut.
+func (s AuthService) Refresh(refreshToken string, device DeviceInfo) (SessionTokens, error) {
+	refreshToken = strings.TrimSpace(refreshToken)
+	if refreshToken == "" {
+		return SessionTokens{}, domain.ValidationError{Field: "refreshToken", Msg: "wajib diisi"}
+	}
+
+	sess, err := s.Sessions.GetByTokenHash(HashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SessionTokens{}, domain.UnauthorizedError{Msg: "refresh token tidak valid"}
+		}
+		return SessionTokens{}, domain.InternalError{Msg: "gagal membaca session", Err: err}
+	}
+
+	now := s.now()
+	if sess.RevokedAt.Valid {
+		return SessionTokens{}, domain.UnauthorizedError{Msg: "session sudah dicabut"}
+	}
+	if sess.RotatedAt.Valid {
+		_ = s.Sessions.RevokeFamily(sess.FamilyID, RevokeReasonReuse)
+		utils.LogEvent(s.RequestID, "auth", "refresh_reuse", "user="+strconv.FormatInt(sess.UserID, 10)+" family="+sess.FamilyID)
+		return SessionTokens{}, domain.UnauthorizedError{Msg: "refresh token sudah dipakai, silakan login ulang"}
+	}
+	if !now.Before(sess.ExpiresAt) {
+		return SessionTokens{}, domain.UnauthorizedError{Msg: "refresh token kedaluwarsa"}
+	}
+
+	user, err := s.loadUser(sess.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			_ = s.Sessions.RevokeFamily(sess.FamilyID, RevokeReasonUserDeleted)
+			return SessionTokens{}, domain.UnauthorizedError{Msg: "user tidak ditemukan"}
+		}
+		return SessionTokens{}, domain.InternalError{Msg: "gagal membaca user", Err: err}
+	}
+	if !IsActiveUserStatus(user.status) {
+		_, _ = s.Sessions.RevokeAllForUser(sess.UserID, RevokeReasonUserInactive)
+		return SessionTokens{}, domain.UnauthorizedError{Msg: "akun tidak aktif"}
+	}
+
+	refresh, err := randomToken(32)
+	if err != nil {
+		return SessionTokens{}, domain.InternalError{Msg: "gagal membuat session", Err: err}
+	}
+	deviceName := strings.TrimSpace(device.Name)
+	if deviceName == "" {
+		deviceName = sess.DeviceName
+	}
+	next := repositories.UserSession{
+		UserID:     sess.UserID,
+		FamilyID:   sess.FamilyID,
+		TokenHash:  HashRefreshToken(refresh),
+		DeviceName: deviceName,
+		UserAgent:  device.UserAgent,
+		IPAddress:  device.IP,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(s.refreshTTL()),
+	}
+	if _, err := s.Sessions.Rotate(sess.ID, next, now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// refresh paralel dengan token yang sama: perlakukan sebagai reuse
+			_ = s.Sessions.RevokeFamily(sess.FamilyID, RevokeReasonReuse)
+			return SessionTokens{}, domain.UnauthorizedError{Msg: "refresh token sudah dipakai, silakan login ulang"}
+		}
+		return SessionTokens{}, domain.InternalError{Msg: "gagal merotasi session", Err: err}
+	}
+
+	access, exp, err := s.Tokens.Issue(sess.UserID, user.name, user.role, sess.FamilyID)
+	if err != nil {
+		return SessionTokens{}, err
+	}
+	return SessionTokens{
+		AccessToken:      access,
+		ExpiresAt:        exp,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: next.ExpiresAt,
+	}, nil
+}
+
+// Logout mencabut session milik caller. Bila all=true seluruh session user dicabut.
+// refreshToken opsional; bila diisi harus milik user yang sama.
+func (s AuthService) Logout(rc domain.RequestContext, refreshToken string, all bool) error {
+	userID := int64(rc.UserID)
+	if all {
+		if _, err := s.Sessions.RevokeAllForUser(userID, RevokeReasonLogoutAll); err != nil {
+			return domain.InternalError{Msg: "gagal logout", Err: err}
+		}
+		utils.LogEvent(s.RequestID, "auth", "logout_all", "user="+strconv.FormatInt(userID, 10))
+		return nil
+	}
+
+	familyID := rc.SessionID
+	if refreshToken = strings.TrimSpace(refreshToken); refreshToken != "" {
+		sess, err := s.Sessions.GetByTokenHash(HashRefreshToken(refreshToken))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return domain.InternalError{Msg: "gagal membaca session", Err: err}
+		}
+		if err == nil {
+			if sess.UserID != userID {
+				return domain.ForbiddenError{Msg: "session bukan milik anda"}
+			}
+			familyID = sess.FamilyID
+		}
+	}
+	if familyID == "" {
+		return domain.ValidationError{Field: "refreshToken", Msg: "session tidak diketahui"}
+	}
+	if err := s.Sessions.RevokeFamily(familyID, RevokeReasonLogout); err != nil {
+		return domain.InternalError{Msg: "gagal logout", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "auth", "logout", "user="+strconv.FormatInt(userID, 10)+" family="+familyID)
+	return nil
+}
+
+// RevokeUserSessions mencabut semua session user, dipakai saat status/password/role berubah.
+func (s AuthService) RevokeUserSessions(userID int64, reason string) error {
+	n, err := s.Sessions.RevokeAllForUser(userID, reason)
+	if err != nil {
+		return domain.InternalError{Msg: "gagal mencabut session user", Err: err}
+	}
+	if n > 0 {
+		utils.LogEvent(s.RequestID, "auth", "revoke_user_sessions", "user="+strconv.FormatInt(userID, 10)+" reason="+reason)
+	}
+	return nil
+}
+
+// Authenticate memverifikasi access token dan memastikan session-nya belum dicabut.
+// Token tanpa session (format lama) ditolak karena tidak bisa dicabut.
+func (s AuthService) Authenticate(tokenString string) (domain.RequestContext, error) {
+	rc, err := s.Tokens.Parse(tokenString)
+	if err != nil {
+		return rc, err
+	}
+	if rc.SessionID == "" {
+		return domain.RequestContext{}, domain.UnauthorizedError{Msg: "token tanpa session, silakan login ulang"}
+	}
+	active, err := s.Sessions.IsFamilyActive(rc.SessionID)
+	if err != nil {
+		return domain.RequestContext{}, domain.InternalError{Msg: "gagal memeriksa session", Err: err}
+	}
+	if !active {
+		return domain.RequestContext{}, domain.UnauthorizedError{Msg: "session sudah berakhir"}
+	}
+	return rc, nil
+}
+
+// IsActiveUserStatus menganggap status kosong (data lama) sebagai aktif.
+func IsActiveUserStatus(status string) bool {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "", "active", "aktif":
+		return true
+	default:
+		return false
+	}
+}
+
+// HashRefreshToken menghasilkan hash sha256 (hex) yang disimpan di user_sessions.token_hash.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type sessionUser struct {
+	name   string
+	role   string
+	status string
+}
+
+func (s AuthService) loadUser(userID int64) (sessionUser, error) {
+	var u sessionUser
+	db := s.db()
+	if db == nil {
+		return u, errors.New("db tidak tersedia")
+	}
+	err := db.QueryRow(`SELECT COALESCE(name,''), COALESCE(role,''), COALESCE(status,'') FROM users WHERE id=? LIMIT 1`, userID).
+		Scan(&u.name, &u.role, &u.status)
+	return u, err
+}
+
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAuthServiceRefreshReuseRevokesFamily(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	svc := AuthService{
+		Tokens:   TokenService{Secret: []byte("test-secret"), Now: func() time.Time { return now }},
+		Sessions: repositories.SessionRepository{DB: db},
+	}
+
+	cols := []string{"id", "user_id", "family_id", "token_hash", "device_name", "user_agent", "ip_address",
+		"created_at", "expires_at", "last_used_at", "rotated_at", "replaced_by", "revoked_at", "revoke_reason"}
+	mock.ExpectQuery("FROM user_sessions WHERE token_hash=").
+		WithArgs(HashRefreshToken("stolen")).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(
+			int64(3), int64(9), "fam-9", HashRefreshToken("stolen"), "HP Budi", "", "",
+			now.Add(-time.Hour), now.Add(time.Hour), nil, now.Add(-time.Minute), int64(4), nil, "",
+		))
+	mock.ExpectExec("UPDATE user_sessions SET revoked_at=\\?, revoke_reason=\\? WHERE family_id=\\?").
+		WithArgs(sqlmock.AnyArg(), RevokeReasonReuse, "fam-9").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	if _, err := svc.Refresh("stolen", DeviceInfo{}); !domain.IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized on refresh token reuse, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestIsActiveUserStatus(t *testing.T) {
+	for status, want := range map[string]bool{"": true, "active": true, "Aktif": true, "inactive": false, "suspended": false} {
+		if got := IsActiveUserStatus(status); got != want {
+			t.Fatalf("IsActiveUserStatus(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestAuthenticateRejectsTokenWithoutSession(t *testing.T) {
+	tokens := TokenService{Secret: []byte("test-secret"), TTL: time.Hour}
+	token, _, err := tokens.Issue(7, "Budi", "admin", "")
+	if err != nil {
+		t.Fatalf("issue error: %v", err)
+	}
+	svc := AuthService{Tokens: tokens}
+	if _, err := svc.Authenticate(token); !domain.IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized for token without session, got %v", err)
+	}
+}
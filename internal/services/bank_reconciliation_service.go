@@ -0,0 +1,327 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+)
+
+// DefaultBankMatchWindow adalah selisih hari maksimal antara tanggal mutasi dan kiriman bukti transfer.
+const DefaultBankMatchWindow = 3 * 24 * time.Hour
+
+// BankMatchCandidate adalah bukti transfer pending yang bisa dicocokkan dengan mutasi bank.
+type BankMatchCandidate struct {
+	ValidationID int64
+	BookingID    int64
+	Amount       int64 // nominal bukti / total booking, tanpa kode unik
+	UniqueCode   int64
+	SubmittedAt  time.Time // zero = tanpa batas tanggal
+}
+
+// Expected adalah nominal yang seharusnya masuk ke rekening (nominal + kode unik).
+func (c BankMatchCandidate) Expected() int64 {
+	return c.Amount + c.UniqueCode
+}
+
+// BankMatch adalah hasil pencocokan satu mutasi kredit.
+type BankMatch struct {
+	Status       string // repositories.MutationMatched / MutationReview / MutationUnmatched
+	ValidationID int64
+	CandidateIDs []int64
+	Reason       string
+}
+
+func withinWindow(posted, submitted time.Time, window time.Duration) bool {
+	if submitted.IsZero() {
+		return true
+	}
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+	diff := day(posted).Sub(day(submitted))
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= window
+}
+
+// MatchBankCredits mencocokkan mutasi kredit ke bukti transfer pending berdasarkan nominal (termasuk
+// kode unik) dan tanggal. Hanya pasangan satu-lawan-satu yang dianggap pasti (matched); nominal yang
+// cocok ke beberapa bukti, beberapa mutasi yang cocok ke bukti yang sama, atau transfer tanpa kode
+// unik masuk antrean review.
+func MatchBankCredits(credits []BankCredit, cands []BankMatchCandidate, window time.Duration) []BankMatch {
+	if window <= 0 {
+		window = DefaultBankMatchWindow
+	}
+	out := make([]BankMatch, len(credits))
+	claims := map[int64][]int{}
+	for i, cr := range credits {
+		exact, loose := []int64{}, []int64{}
+		for _, c := range cands {
+			if !withinWindow(cr.PostedAt, c.SubmittedAt, window) {
+				continue
+			}
+			switch {
+			case cr.Amount == c.Expected():
+				exact = append(exact, c.ValidationID)
+			case c.UniqueCode > 0 && cr.Amount == c.Amount:
+				loose = append(loose, c.ValidationID)
+			}
+		}
+		switch {
+		case len(exact) == 1:
+			out[i] = BankMatch{Status: repositories.MutationMatched, ValidationID: exact[0], CandidateIDs: exact}
+			claims[exact[0]] = append(claims[exact[0]], i)
+		case len(exact) > 1:
+			out[i] = BankMatch{Status: repositories.MutationReview, CandidateIDs: exact, Reason: "nominal cocok dengan beberapa bukti transfer"}
+		case len(loose) > 0:
+			out[i] = BankMatch{Status: repositories.MutationReview, CandidateIDs: loose, Reason: "nominal cocok tanpa kode unik"}
+		default:
+			out[i] = BankMatch{Status: repositories.MutationUnmatched, CandidateIDs: []int64{}}
+		}
+	}
+	for _, idx := range claims {
+		if len(idx) < 2 {
+			continue
+		}
+		for _, i := range idx {
+			out[i].Status = repositories.MutationReview
+			out[i].ValidationID = 0
+			out[i].Reason = "beberapa mutasi cocok dengan bukti transfer yang sama"
+		}
+	}
+	return out
+}
+
+var submittedAtLayouts = []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05", "2006-01-02"}
+
+func parseSubmittedAt(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, l := range submittedAtLayouts {
+		if t, err := time.ParseInLocation(l, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// BankImportResult merangkum satu impor rekening koran.
+type BankImportResult struct {
+	Format     string                      `json:"format"`
+	Credits    int                         `json:"credits"`
+	Duplicates int                         `json:"duplicates"`
+	Matched    int                         `json:"matched"`
+	Review     int                         `json:"review"`
+	Unmatched  int                         `json:"unmatched"`
+	Mutations  []repositories.BankMutation `json:"mutations"`
+}
+
+// BankReconciliationService mengimpor mutasi rekening dan menyetujui bukti transfer yang cocok.
+// Approve menjalankan alur yang sama dengan approve payment_validations oleh admin.
+type BankReconciliationService struct {
+	Mutations repositories.BankMutationRepository
+	Window    time.Duration
+	Approve   func(validationID int64) error
+	RequestID string
+	Now       func() time.Time
+}
+
+func (s BankReconciliationService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s BankReconciliationService) ready() error {
+	if !s.Mutations.Available() {
+		return domain.InternalError{Msg: "tabel bank_mutations belum tersedia, jalankan `migrate up`"}
+	}
+	if s.Approve == nil {
+		return domain.InternalError{Msg: "approve validasi belum dikonfigurasi"}
+	}
+	return nil
+}
+
+// Import membaca file CSV/MT940, menyimpan mutasi kredit baru (mutasi yang sudah pernah diimpor
+// dilewati), lalu mencocokkannya ke bukti transfer yang menunggu validasi.
+func (s BankReconciliationService) Import(filename string, data []byte, importedBy int64) (BankImportResult, error) {
+	res := BankImportResult{Mutations: []repositories.BankMutation{}}
+	if err := s.ready(); err != nil {
+		return res, err
+	}
+	if len(data) == 0 {
+		return res, domain.ValidationError{Field: "file", Msg: "file mutasi kosong"}
+	}
+	credits, format, err := ParseBankStatement(filename, data, s.now())
+	res.Format = format
+	if err != nil {
+		return res, err
+	}
+	res.Credits = len(credits)
+
+	fresh := []BankCredit{}
+	mutations := []repositories.BankMutation{}
+	for _, cr := range credits {
+		m := repositories.BankMutation{
+			Source:      format,
+			PostedAt:    cr.PostedAt,
+			Amount:      cr.Amount,
+			Description: cr.Description,
+			Reference:   cr.Reference,
+			Status:      repositories.MutationUnmatched,
+			ImportedBy:  importedBy,
+		}
+		id, dup, err := s.Mutations.Insert(m, cr.Fingerprint)
+		if err != nil {
+			return res, domain.InternalError{Msg: "gagal menyimpan mutasi", Err: err}
+		}
+		if dup {
+			res.Duplicates++
+			continue
+		}
+		m.ID = id
+		fresh = append(fresh, cr)
+		mutations = append(mutations, m)
+	}
+
+	pending, err := s.Mutations.PendingTransfers()
+	if err != nil {
+		return res, domain.InternalError{Msg: "gagal memuat bukti transfer", Err: err}
+	}
+	cands := make([]BankMatchCandidate, 0, len(pending))
+	for _, p := range pending {
+		cands = append(cands, BankMatchCandidate{
+			ValidationID: p.ValidationID,
+			BookingID:    p.BookingID,
+			Amount:       p.Amount,
+			UniqueCode:   p.UniqueCode,
+			SubmittedAt:  parseSubmittedAt(p.SubmittedAt),
+		})
+	}
+
+	for i, match := range MatchBankCredits(fresh, cands, s.Window) {
+		m, err := s.apply(mutations[i], match)
+		if err != nil {
+			return res, err
+		}
+		switch m.Status {
+		case repositories.MutationMatched:
+			res.Matched++
+		case repositories.MutationReview:
+			res.Review++
+		default:
+			res.Unmatched++
+		}
+		res.Mutations = append(res.Mutations, m)
+	}
+	utils.LogEvent(s.RequestID, "payment", "bank_import", fmt.Sprintf("format=%s credits=%d duplicates=%d matched=%d review=%d unmatched=%d",
+		format, res.Credits, res.Duplicates, res.Matched, res.Review, res.Unmatched))
+	return res, nil
+}
+
+// apply menyetujui validasi untuk match yang pasti; bila approve gagal (mis. role trip belum diisi)
+// mutasi dipindah ke antrean review beserta alasannya.
+func (s BankReconciliationService) apply(m repositories.BankMutation, match BankMatch) (repositories.BankMutation, error) {
+	m.Status, m.CandidateIDs, m.Note = match.Status, match.CandidateIDs, match.Reason
+	var resolved *time.Time
+	if match.Status == repositories.MutationMatched {
+		if err := s.Approve(match.ValidationID); err != nil {
+			m.Status = repositories.MutationReview
+			m.Note = "gagal approve otomatis: " + approveErrorMsg(err)
+		} else {
+			now := s.now()
+			m.ValidationID, resolved = match.ValidationID, &now
+		}
+	}
+	m.ResolvedAt = resolved
+	if err := s.Mutations.UpdateMatch(m.ID, m.Status, m.ValidationID, m.CandidateIDs, m.Note, resolved); err != nil {
+		return m, domain.InternalError{Msg: "gagal menyimpan hasil pencocokan", Err: err}
+	}
+	return m, nil
+}
+
+func approveErrorMsg(err error) string {
+	var (
+		ve domain.ValidationError
+		ce domain.ConflictError
+	)
+	switch {
+	case errors.As(err, &ve):
+		return ve.Msg
+	case errors.As(err, &ce):
+		return ce.Msg
+	}
+	return err.Error()
+}
+
+// List mengembalikan mutasi per status (review = antrean admin).
+func (s BankReconciliationService) List(status string) ([]repositories.BankMutation, error) {
+	if !s.Mutations.Available() {
+		return nil, domain.InternalError{Msg: "tabel bank_mutations belum tersedia, jalankan `migrate up`"}
+	}
+	list, err := s.Mutations.List(status, 0)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat mutasi", Err: err}
+	}
+	return list, nil
+}
+
+func (s BankReconciliationService) openMutation(id int64) (repositories.BankMutation, error) {
+	m, err := s.Mutations.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return m, domain.NotFoundError{Resource: "mutasi"}
+	}
+	if err != nil {
+		return m, domain.InternalError{Msg: "gagal memuat mutasi", Err: err}
+	}
+	if m.Status == repositories.MutationMatched || m.Status == repositories.MutationIgnored {
+		return m, domain.ConflictError{Resource: "mutasi", Msg: "Mutasi sudah diproses (" + m.Status + ")"}
+	}
+	return m, nil
+}
+
+// Resolve menyetujui validationID untuk mutasi di antrean review/unmatched.
+func (s BankReconciliationService) Resolve(mutationID, validationID int64) (repositories.BankMutation, error) {
+	if err := s.ready(); err != nil {
+		return repositories.BankMutation{}, err
+	}
+	if validationID <= 0 {
+		return repositories.BankMutation{}, domain.ValidationError{Field: "validationId", Msg: "validationId wajib diisi"}
+	}
+	m, err := s.openMutation(mutationID)
+	if err != nil {
+		return m, err
+	}
+	if err := s.Approve(validationID); err != nil {
+		return m, err
+	}
+	now := s.now()
+	m.Status, m.ValidationID, m.ResolvedAt, m.Note = repositories.MutationMatched, validationID, &now, "dicocokkan manual"
+	if err := s.Mutations.UpdateMatch(m.ID, m.Status, m.ValidationID, m.CandidateIDs, m.Note, &now); err != nil {
+		return m, domain.InternalError{Msg: "gagal menyimpan hasil pencocokan", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "payment", "bank_resolve", fmt.Sprintf("mutation_id=%d validation_id=%d", m.ID, validationID))
+	return m, nil
+}
+
+// Ignore menandai mutasi bukan pembayaran booking (mis. setoran lain).
+func (s BankReconciliationService) Ignore(mutationID int64, note string) (repositories.BankMutation, error) {
+	if !s.Mutations.Available() {
+		return repositories.BankMutation{}, domain.InternalError{Msg: "tabel bank_mutations belum tersedia, jalankan `migrate up`"}
+	}
+	m, err := s.openMutation(mutationID)
+	if err != nil {
+		return m, err
+	}
+	now := s.now()
+	m.Status, m.ResolvedAt, m.Note = repositories.MutationIgnored, &now, strings.TrimSpace(note)
+	if err := s.Mutations.UpdateMatch(m.ID, m.Status, 0, m.CandidateIDs, m.Note, &now); err != nil {
+		return m, domain.InternalError{Msg: "gagal menyimpan mutasi", Err: err}
+	}
+	return m, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/repositories"
+)
+
+func TestMatchBankCredits(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.Local) }
+	cands := []BankMatchCandidate{
+		{ValidationID: 1, BookingID: 10, Amount: 150000, UniqueCode: 123, SubmittedAt: day(5)},
+		{ValidationID: 2, BookingID: 11, Amount: 200000, SubmittedAt: day(5)},
+		{ValidationID: 3, BookingID: 12, Amount: 200000, SubmittedAt: day(6)},
+		{ValidationID: 4, BookingID: 13, Amount: 300000, UniqueCode: 45, SubmittedAt: day(5)},
+		{ValidationID: 5, BookingID: 14, Amount: 90000, SubmittedAt: day(1)},
+	}
+	credits := []BankCredit{
+		{PostedAt: day(5), Amount: 150123}, // cocok tepat dengan kode unik
+		{PostedAt: day(6), Amount: 200000}, // dua bukti dengan nominal sama
+		{PostedAt: day(5), Amount: 300000}, // kode unik tidak ikut ditransfer
+		{PostedAt: day(5), Amount: 999000}, // tidak ada bukti
+		{PostedAt: day(9), Amount: 90000},  // di luar jendela tanggal
+	}
+
+	got := MatchBankCredits(credits, cands, 0)
+	want := []string{
+		repositories.MutationMatched,
+		repositories.MutationReview,
+		repositories.MutationReview,
+		repositories.MutationUnmatched,
+		repositories.MutationUnmatched,
+	}
+	for i, w := range want {
+		if got[i].Status != w {
+			t.Fatalf("credit %d: got %s (%+v), want %s", i, got[i].Status, got[i], w)
+		}
+	}
+	if got[0].ValidationID != 1 {
+		t.Fatalf("expected validation 1, got %+v", got[0])
+	}
+	if len(got[1].CandidateIDs) != 2 || got[1].ValidationID != 0 {
+		t.Fatalf("ambiguous credit should list both candidates, got %+v", got[1])
+	}
+	if len(got[2].CandidateIDs) != 1 || got[2].CandidateIDs[0] != 4 {
+		t.Fatalf("expected candidate 4 for credit without unique code, got %+v", got[2])
+	}
+}
+
+func TestMatchBankCreditsContested(t *testing.T) {
+	cands := []BankMatchCandidate{{ValidationID: 7, Amount: 100000, UniqueCode: 7}}
+	credits := []BankCredit{{Amount: 100007}, {Amount: 100007}}
+	for i, m := range MatchBankCredits(credits, cands, time.Hour) {
+		if m.Status != repositories.MutationReview || m.ValidationID != 0 {
+			t.Fatalf("credit %d: contested match should go to review, got %+v", i, m)
+		}
+	}
+}
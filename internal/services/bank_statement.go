@@ -0,0 +1,372 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/domain"
+)
+
+// Format file mutasi rekening yang didukung.
+const (
+	StatementCSV   = "csv"
+	StatementMT940 = "mt940"
+)
+
+// BankCredit adalah satu mutasi kredit (uang masuk) dari file rekening koran.
+type BankCredit struct {
+	PostedAt    time.Time `json:"postedAt"`
+	Amount      int64     `json:"amount"` // rupiah
+	Description string    `json:"description"`
+	Reference   string    `json:"reference,omitempty"`
+	Fingerprint string    `json:"-"`
+}
+
+// ParseBankStatement mendeteksi format (dari nama file atau isinya) lalu mengembalikan mutasi kredit.
+// Mutasi debit diabaikan. now dipakai untuk tanggal tanpa tahun (mis. "05/03" di CSV BCA).
+func ParseBankStatement(filename string, data []byte, now time.Time) ([]BankCredit, string, error) {
+	format := StatementCSV
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".sta", ".mt940", ".940":
+		format = StatementMT940
+	case ".csv", ".txt":
+		if bytes.Contains(data, []byte(":61:")) {
+			format = StatementMT940
+		}
+	default:
+		if bytes.Contains(data, []byte(":61:")) {
+			format = StatementMT940
+		}
+	}
+
+	var (
+		credits []BankCredit
+		err     error
+	)
+	if format == StatementMT940 {
+		credits, err = ParseMT940(data)
+	} else {
+		credits, err = ParseBankCSV(data, now)
+	}
+	if err != nil {
+		return nil, format, err
+	}
+	fingerprintCredits(credits)
+	return credits, format, nil
+}
+
+// fingerprintCredits memberi sidik unik per mutasi supaya file yang sama aman diimpor ulang.
+// Mutasi identik dalam satu file dibedakan dengan urutan kemunculannya.
+func fingerprintCredits(credits []BankCredit) {
+	seen := map[string]int{}
+	for i := range credits {
+		c := &credits[i]
+		key := fmt.Sprintf("%s|%d|%s|%s", c.PostedAt.Format("2006-01-02"), c.Amount,
+			strings.Join(strings.Fields(strings.ToUpper(c.Description)), " "), strings.TrimSpace(c.Reference))
+		seen[key]++
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, seen[key])))
+		c.Fingerprint = hex.EncodeToString(sum[:])
+	}
+}
+
+// ===== CSV =====
+
+var (
+	csvDateCols   = []string{"tanggal", "tanggal transaksi", "tgl", "tgl transaksi", "tgl. transaksi", "date", "transaction date", "posting date", "tanggal posting"}
+	csvDescCols   = []string{"keterangan", "deskripsi", "description", "uraian", "uraian transaksi", "remark", "berita", "transaction description"}
+	csvAmountCols = []string{"jumlah", "nominal", "amount", "mutasi", "nilai"}
+	csvCreditCols = []string{"kredit", "credit", "mutasi kredit", "cr"}
+	csvTypeCols   = []string{"d/k", "db/cr", "cr/db", "d/c", "tipe", "type", "jenis"}
+	csvRefCols    = []string{"referensi", "reference", "no. referensi", "no referensi", "ref", "ref no"}
+)
+
+type csvLayout struct {
+	date, desc, amount, credit, kind, ref int
+}
+
+func csvColumn(header []string, names []string) int {
+	for i, h := range header {
+		h = strings.ToLower(strings.Trim(strings.TrimSpace(h), "\"'\ufeff"))
+		for _, n := range names {
+			if h == n {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func detectCSVLayout(header []string) (csvLayout, bool) {
+	l := csvLayout{
+		date:   csvColumn(header, csvDateCols),
+		desc:   csvColumn(header, csvDescCols),
+		amount: csvColumn(header, csvAmountCols),
+		credit: csvColumn(header, csvCreditCols),
+		kind:   csvColumn(header, csvTypeCols),
+		ref:    csvColumn(header, csvRefCols),
+	}
+	return l, l.date >= 0 && (l.amount >= 0 || l.credit >= 0)
+}
+
+func csvDelimiter(data []byte) rune {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	best, bestN := ',', 0
+	for _, d := range []rune{',', ';', '\t', '|'} {
+		if n := bytes.Count(line, []byte(string(d))); n > bestN {
+			best, bestN = d, n
+		}
+	}
+	return best
+}
+
+// ParseBankCSV membaca export mutasi CSV internet banking. Baris sebelum header (info rekening)
+// dan sesudah tabel (saldo akhir) dilewati. Kolom nominal boleh berupa satu kolom bertanda
+// (CR/DB, minus, atau kolom D/K terpisah) maupun kolom kredit & debit terpisah (debit diabaikan).
+func ParseBankCSV(data []byte, now time.Time) ([]BankCredit, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = csvDelimiter(data)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, domain.ValidationError{Field: "file", Msg: "CSV tidak valid", Err: err}
+	}
+
+	var (
+		layout csvLayout
+		found  bool
+		out    = []BankCredit{}
+	)
+	for _, row := range rows {
+		if !found {
+			layout, found = detectCSVLayout(row)
+			continue
+		}
+		at, ok := parseStatementDate(cell(row, layout.date), now)
+		if !ok {
+			continue
+		}
+
+		var amount int64
+		switch {
+		case layout.credit >= 0 && strings.TrimSpace(cell(row, layout.credit)) != "":
+			v, credit, err := parseStatementAmount(cell(row, layout.credit))
+			if err != nil || !credit || v == 0 {
+				continue
+			}
+			amount = v
+		case layout.amount >= 0:
+			v, credit, err := parseStatementAmount(cell(row, layout.amount))
+			if err != nil || v == 0 {
+				continue
+			}
+			if layout.kind >= 0 {
+				if k := strings.ToUpper(strings.TrimSpace(cell(row, layout.kind))); k != "" {
+					credit = k == "CR" || k == "K" || k == "C" || k == "KREDIT" || k == "CREDIT"
+				}
+			}
+			if !credit {
+				continue
+			}
+			amount = v
+		default:
+			continue
+		}
+
+		out = append(out, BankCredit{
+			PostedAt:    at,
+			Amount:      amount,
+			Description: strings.Join(strings.Fields(cell(row, layout.desc)), " "),
+			Reference:   strings.TrimSpace(cell(row, layout.ref)),
+		})
+	}
+	if !found {
+		return nil, domain.ValidationError{Field: "file", Msg: "header CSV tidak dikenali (butuh kolom tanggal dan nominal/kredit)"}
+	}
+	return out, nil
+}
+
+func cell(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(row[i]), "'")
+}
+
+var statementDateLayouts = []string{
+	"02/01/2006", "2/1/2006", "02-01-2006", "2006-01-02", "2006/01/02", "02.01.2006",
+	"02/01/06", "02-01-06", "02 Jan 2006", "02-Jan-2006", "02 Jan 06", "2006-01-02 15:04:05", "02/01/2006 15:04:05",
+}
+
+// parseStatementDate membaca tanggal mutasi (format Indonesia hari/bulan lebih dulu).
+// Tanggal tanpa tahun memakai tahun now, mundur setahun bila jatuh di masa depan.
+func parseStatementDate(s string, now time.Time) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, l := range statementDateLayouts {
+		if t, err := time.ParseInLocation(l, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	for _, l := range []string{"02/01", "02-01", "02.01"} {
+		if t, err := time.ParseInLocation(l, s, time.Local); err == nil {
+			t = time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+			if t.After(now.AddDate(0, 0, 1)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseStatementAmount membaca nominal seperti "1.500.000,00", "1,500,000.00 CR", "-150000",
+// "(150.000)" atau "150000 DB". credit=false untuk nominal negatif/debit. Sen dibulatkan ke rupiah.
+func parseStatementAmount(s string) (int64, bool, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	credit := true
+	for _, suf := range []string{"CR", "DB", "K", "D"} {
+		if strings.HasSuffix(v, suf) {
+			credit = suf == "CR" || suf == "K"
+			v = strings.TrimSpace(strings.TrimSuffix(v, suf))
+			break
+		}
+	}
+	v = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(v, "RP."), "RP"))
+	if strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")") {
+		credit = false
+		v = strings.Trim(v, "()")
+	}
+	if strings.HasPrefix(v, "-") {
+		credit = false
+		v = strings.TrimPrefix(v, "-")
+	}
+	v = strings.TrimPrefix(v, "+")
+	v = strings.ReplaceAll(v, " ", "")
+	if v == "" {
+		return 0, credit, fmt.Errorf("nominal kosong")
+	}
+
+	// pemisah desimal = pemisah terakhir bila kedua jenis pemisah dipakai ("1.500.000,00"),
+	// atau bila hanya muncul sekali dan diikuti 1-2 digit ("150000.00"); selain itu pemisah ribuan
+	intPart, frac := v, ""
+	if i := strings.LastIndexAny(v, ".,"); i >= 0 {
+		sep, tail := v[i:i+1], v[i+1:]
+		other := ","
+		if sep == "," {
+			other = "."
+		}
+		if strings.Contains(v[:i], other) || strings.Count(v, sep) == 1 && len(tail) <= 2 {
+			intPart, frac = v[:i], tail
+		}
+	}
+	intPart = strings.NewReplacer(".", "", ",", "").Replace(intPart)
+	n, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return 0, credit, fmt.Errorf("nominal %q tidak valid", s)
+	}
+	if frac != "" {
+		if len(frac) > 2 {
+			frac = frac[:2]
+		}
+		for len(frac) < 2 {
+			frac += "0"
+		}
+		cents, err := strconv.Atoi(frac)
+		if err != nil {
+			return 0, credit, fmt.Errorf("nominal %q tidak valid", s)
+		}
+		if cents >= 50 {
+			n++
+		}
+	}
+	return n, credit, nil
+}
+
+// ===== MT940 =====
+
+// :61:YYMMDD[MMDD](C|D|RC|RD)[funds code]amount N<type><reference>[//bank reference]
+var mt940Line61 = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d{0,2})([A-Z][A-Z0-9]{3})?(.*)$`)
+
+// ParseMT940 membaca rekening koran SWIFT MT940. Hanya mutasi kredit (C, dan RD = pembatalan debit)
+// yang dikembalikan; keterangan diambil dari field :86: setelahnya.
+func ParseMT940(data []byte) ([]BankCredit, error) {
+	type tag struct{ name, value string }
+	tags := []tag{}
+	for _, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		line := strings.TrimRight(raw, " \r")
+		if line == "" || line == "-" || strings.HasPrefix(line, "-}") || strings.HasPrefix(line, "{") {
+			continue
+		}
+		if len(line) > 3 && line[0] == ':' {
+			if j := strings.Index(line[1:], ":"); j > 0 {
+				tags = append(tags, tag{name: line[1 : j+1], value: line[j+2:]})
+				continue
+			}
+		}
+		if n := len(tags); n > 0 {
+			tags[n-1].value += "\n" + line
+		}
+	}
+
+	out := []BankCredit{}
+	found := false
+	for i, t := range tags {
+		if t.name != "61" {
+			continue
+		}
+		found = true
+		first := strings.SplitN(t.value, "\n", 2)
+		m := mt940Line61.FindStringSubmatch(strings.TrimSpace(first[0]))
+		if m == nil {
+			return nil, domain.ValidationError{Field: "file", Msg: "baris :61: MT940 tidak valid: " + first[0]}
+		}
+		if m[3] != "C" && m[3] != "RD" {
+			continue
+		}
+		at, err := time.ParseInLocation("060102", m[1], time.Local)
+		if err != nil {
+			return nil, domain.ValidationError{Field: "file", Msg: "tanggal MT940 tidak valid: " + m[1]}
+		}
+		amount, _, err := parseStatementAmount(m[5])
+		if err != nil {
+			return nil, domain.ValidationError{Field: "file", Msg: err.Error()}
+		}
+		ref := m[7]
+		if k := strings.Index(ref, "//"); k >= 0 {
+			ref = ref[:k]
+		}
+		desc := ""
+		if len(first) > 1 {
+			desc = first[1]
+		}
+		if i+1 < len(tags) && tags[i+1].name == "86" {
+			desc = strings.TrimSpace(desc + " " + tags[i+1].value)
+		}
+		out = append(out, BankCredit{
+			PostedAt:    at,
+			Amount:      amount,
+			Description: strings.Join(strings.Fields(desc), " "),
+			Reference:   strings.TrimSpace(ref),
+		})
+	}
+	if !found && !strings.Contains(string(data), ":20:") {
+		return nil, domain.ValidationError{Field: "file", Msg: "file bukan MT940"}
+	}
+	return out, nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStatementAmount(t *testing.T) {
+	cases := []struct {
+		in     string
+		amount int64
+		credit bool
+	}{
+		{"150.000,00", 150000, true},
+		{"1,500,000.00 CR", 1500000, true},
+		{"150000 DB", 150000, false},
+		{"-75.000", 75000, false},
+		{"(75.000)", 75000, false},
+		{"Rp 1.234.567", 1234567, true},
+		{"150123.50", 150124, true},
+		{"250000K", 250000, true},
+	}
+	for _, c := range cases {
+		amount, credit, err := parseStatementAmount(c.in)
+		if err != nil {
+			t.Fatalf("%q: unexpected error %v", c.in, err)
+		}
+		if amount != c.amount || credit != c.credit {
+			t.Fatalf("%q: got %d credit=%v, want %d credit=%v", c.in, amount, credit, c.amount, c.credit)
+		}
+	}
+	if _, _, err := parseStatementAmount("abc"); err == nil {
+		t.Fatalf("expected error for invalid amount")
+	}
+}
+
+func TestParseBankCSVSignedAmount(t *testing.T) {
+	now := time.Date(2025, 3, 20, 10, 0, 0, 0, time.Local)
+	data := []byte("\ufeffInformasi Rekening - Mutasi Rekening\n" +
+		"No. rekening : ,1234567890\n" +
+		"Tanggal Transaksi,Keterangan,Cabang,Jumlah,Saldo\n" +
+		"'05/03,TRSF E-BANKING CR 0503/FTSCY/WS95031 150123.00 BUDI,'0000,\"150,123.00 CR\",\"1,150,123.00\"\n" +
+		"'06/03,BIAYA ADM,'0000,\"10,000.00 DB\",\"1,140,123.00\"\n" +
+		"Saldo Akhir,,,,\"1,140,123.00\"\n")
+
+	credits, format, err := ParseBankStatement("mutasi.csv", data, now)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if format != StatementCSV || len(credits) != 1 {
+		t.Fatalf("expected 1 csv credit, got %s %+v", format, credits)
+	}
+	c := credits[0]
+	if c.Amount != 150123 || c.PostedAt.Format("2006-01-02") != "2025-03-05" || c.Fingerprint == "" {
+		t.Fatalf("unexpected credit %+v", c)
+	}
+}
+
+func TestParseBankCSVCreditColumn(t *testing.T) {
+	now := time.Date(2025, 3, 20, 10, 0, 0, 0, time.Local)
+	data := []byte("Tanggal;Uraian;Referensi;Debit;Kredit;Saldo\n" +
+		"01/03/2025;SETORAN TUNAI;REF1;;200.000,00;200.000,00\n" +
+		"02/03/2025;TARIK TUNAI;REF2;50.000,00;;150.000,00\n" +
+		"02/03/2025;SETORAN TUNAI;REF1;;200.000,00;350.000,00\n")
+
+	credits, err := ParseBankCSV(data, now)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(credits) != 2 || credits[0].Amount != 200000 || credits[0].Reference != "REF1" {
+		t.Fatalf("unexpected credits %+v", credits)
+	}
+
+	if _, err := ParseBankCSV([]byte("a,b,c\n1,2,3\n"), now); err == nil {
+		t.Fatalf("expected error for unknown header")
+	}
+}
+
+func TestParseBankStatementFingerprintsDuplicates(t *testing.T) {
+	now := time.Date(2025, 3, 20, 10, 0, 0, 0, time.Local)
+	data := []byte("Tanggal,Keterangan,Jumlah\n01/03/2025,SETORAN,100000\n01/03/2025,SETORAN,100000\n")
+	credits, _, err := ParseBankStatement("a.csv", data, now)
+	if err != nil || len(credits) != 2 {
+		t.Fatalf("unexpected result %+v %v", credits, err)
+	}
+	if credits[0].Fingerprint == credits[1].Fingerprint {
+		t.Fatalf("identical rows must get distinct fingerprints")
+	}
+	again, _, _ := ParseBankStatement("a.csv", data, now)
+	if again[0].Fingerprint != credits[0].Fingerprint {
+		t.Fatalf("fingerprint must be stable across imports")
+	}
+}
+
+func TestParseMT940(t *testing.T) {
+	data := []byte(":20:STMT250305\n" +
+		":25:1234567890\n" +
+		":28C:1/1\n" +
+		":60F:C250304IDR1000000,00\n" +
+		":61:2503050305C150123,00NTRFREF001//BNK1\n" +
+		":86:TRANSFER DARI BUDI\nBOOKING 42\n" +
+		":61:2503050305D25000,00NCHGADM\n" +
+		":86:BIAYA ADMIN\n" +
+		":62F:C250305IDR1125123,00\n-\n")
+
+	credits, format, err := ParseBankStatement("rekening.sta", data, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if format != StatementMT940 || len(credits) != 1 {
+		t.Fatalf("expected 1 mt940 credit, got %s %+v", format, credits)
+	}
+	c := credits[0]
+	if c.Amount != 150123 || c.Reference != "REF001" || c.Description != "TRANSFER DARI BUDI BOOKING 42" {
+		t.Fatalf("unexpected credit %+v", c)
+	}
+	if c.PostedAt.Format("2006-01-02") != "2025-03-05" {
+		t.Fatalf("unexpected date %s", c.PostedAt)
+	}
+}
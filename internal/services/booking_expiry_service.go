@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	intconfig "backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+)
+
+// BookingExpiryPolicy menentukan batas upload bukti bayar untuk booking yang belum lunas:
+// Window sejak booking dibuat, dan paling lambat CutoffHours sebelum jam berangkat.
+// Window <= 0 mematikan expiry.
+type BookingExpiryPolicy struct {
+	Window      time.Duration
+	CutoffHours int
+}
+
+// Enabled melaporkan apakah booking baru diberi batas pembayaran.
+func (p BookingExpiryPolicy) Enabled() bool {
+	return p.Window > 0
+}
+
+// Deadline menghitung batas pembayaran booking yang dibuat pada created untuk jadwal date+clock.
+// Booking yang dibuat sudah di dalam cutoff tetap mendapat Window, tapi tidak melewati jam berangkat.
+func (p BookingExpiryPolicy) Deadline(created time.Time, date, clock string) (time.Time, error) {
+	departure, err := departureTime(date, clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	deadline := created.Add(p.Window)
+	limit := departure.Add(-time.Duration(p.CutoffHours) * time.Hour)
+	if !limit.After(created) {
+		limit = departure
+	}
+	if limit.Before(deadline) {
+		deadline = limit
+	}
+	return deadline, nil
+}
+
+// BookingExpiryService meng-expire booking transfer/QRIS yang tidak mengirim bukti bayar
+// sampai payment_deadline, lalu melepas booking_seats-nya.
+type BookingExpiryService struct {
+	Bookings  repositories.BookingRepository
+	Seats     repositories.BookingSeatRepository
+	Batch     int
+	DB        *sql.DB
+	RequestID string
+	Now       func() time.Time
+}
+
+func (s BookingExpiryService) db() *sql.DB {
+	if s.DB != nil {
+		return s.DB
+	}
+	return intconfig.DB
+}
+
+func (s BookingExpiryService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// ExpireDue meng-expire semua booking yang lewat batas pembayaran dan mengembalikan jumlahnya.
+func (s BookingExpiryService) ExpireDue() (int, error) {
+	now := s.now()
+	ids, err := s.Bookings.ListPaymentOverdue(now, s.Batch)
+	if err != nil {
+		return 0, domain.InternalError{Msg: "gagal memuat booking lewat batas bayar", Err: err}
+	}
+	expired := 0
+	for _, id := range ids {
+		ok, err := s.expire(id)
+		if err != nil {
+			log.Printf("[BOOKING_EXPIRY] booking_id=%d error: %v", id, err)
+			continue
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// expire mengunci booking lalu memeriksa ulang bukti bayar sebelum meng-expire,
+// supaya upload bukti yang bersamaan tidak ikut dibatalkan.
+func (s BookingExpiryService) expire(bookingID int64) (bool, error) {
+	db := s.db()
+	if db == nil {
+		return false, fmt.Errorf("db tidak tersedia")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	b, err := s.Bookings.LockForUpdate(tx, bookingID)
+	if err != nil {
+		return false, err
+	}
+	// cash tidak pernah mengirim bukti transfer; batas lama yang terlanjur tersimpan tidak berlaku
+	if b.PaymentMethod == "cash" {
+		return false, nil
+	}
+	hasProof, err := s.Bookings.HasPaymentProofTx(tx, bookingID)
+	if err != nil || hasProof {
+		return false, err
+	}
+	actor := repositories.StatusActor{Role: "system", RequestID: s.RequestID}
+	if _, err := s.Bookings.TransitionTx(tx, bookingID, domain.BookingExpired, actor, "batas waktu pembayaran terlewati"); err != nil {
+		var ce domain.ConflictError
+		if errors.As(err, &ce) {
+			return false, nil
+		}
+		return false, err
+	}
+	released, err := s.Seats.DeleteByBookingTx(tx, bookingID)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	utils.LogEvent(s.RequestID, "booking", "expire", fmt.Sprintf("booking_id=%d seats=%d", bookingID, released))
+	return true, nil
+}
+
+// RunSweeper menjalankan ExpireDue berkala sampai ctx dibatalkan.
+func (s BookingExpiryService) RunSweeper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.ExpireDue()
+			if err != nil {
+				log.Printf("[BOOKING_EXPIRY] sweeper error: %v", err)
+				continue
+			}
+			if n > 0 {
+				utils.LogEvent("", "booking", "expire_sweep", fmt.Sprintf("expired=%d", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	intdb "backend/internal/db"
+	"backend/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBookingExpiryDeadline(t *testing.T) {
+	p := BookingExpiryPolicy{Window: 2 * time.Hour, CutoffHours: 3}
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	cases := []struct {
+		name    string
+		created string
+		want    string
+	}{
+		{"window", "2026-03-01 08:00", "2026-03-01 10:00"},
+		{"cutoff", "2026-03-02 04:00", "2026-03-02 05:00"},
+		{"inside cutoff", "2026-03-02 07:00", "2026-03-02 08:00"},
+	}
+	for _, tc := range cases {
+		got, err := p.Deadline(at(tc.created), "2026-03-02", "08:00")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !got.Equal(at(tc.want)) {
+			t.Errorf("%s: got %s want %s", tc.name, got.Format("2006-01-02 15:04"), tc.want)
+		}
+	}
+}
+
+func TestBookingExpirySkipsCashBooking(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	intdb.Schema.Invalidate()
+	schema := sqlmock.NewRows([]string{"table_name", "column_name"})
+	for _, col := range []string{"id", "payment_deadline", "payment_status", "payment_method"} {
+		schema.AddRow("bookings", col)
+	}
+	mock.ExpectQuery("FROM information_schema.columns").WillReturnRows(schema)
+
+	// booking cash lama yang terlanjur punya payment_deadline
+	mock.ExpectQuery(`SELECT b.id FROM bookings b WHERE .*COALESCE\(b.payment_method,''\) <> 'cash'`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM bookings WHERE id=\? LIMIT 1 FOR UPDATE`).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "category", "route_from", "route_to", "trip_date", "trip_time",
+			"passenger_count", "price_per_seat", "total", "payment_status", "payment_method"}).
+			AddRow(7, "Reguler", "A", "B", "2026-03-02", "08:00", 1, 100000, 100000, "Belum Lunas", "cash"))
+	mock.ExpectRollback()
+
+	svc := BookingExpiryService{
+		Bookings: repositories.BookingRepository{DB: db},
+		DB:       db,
+		Now:      func() time.Time { return time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local) },
+	}
+	n, err := svc.ExpireDue()
+	if err != nil {
+		t.Fatalf("ExpireDue: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("cash booking must not expire, expired=%d", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestBookingExpiryExpiresRejectedBooking(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	intdb.Schema.Invalidate()
+	schema := sqlmock.NewRows([]string{"table_name", "column_name"})
+	for _, col := range []string{"id", "payment_deadline", "payment_status", "payment_method", "booking_status"} {
+		schema.AddRow("bookings", col)
+	}
+	schema.AddRow("payment_validations", "booking_id")
+	schema.AddRow("payment_validations", "payment_status")
+	mock.ExpectQuery("FROM information_schema.columns").WillReturnRows(schema)
+
+	mock.ExpectQuery(`'Ditolak'\).*pv.booking_id = b.id AND COALESCE\(pv.payment_status,''\) <> 'Ditolak'`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM bookings WHERE id=\? LIMIT 1 FOR UPDATE`).
+		WithArgs(int64(8)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "category", "route_from", "route_to", "trip_date", "trip_time",
+			"passenger_count", "price_per_seat", "total", "payment_status", "payment_method"}).
+			AddRow(8, "Reguler", "A", "B", "2026-03-02", "08:00", 1, 100000, 100000, "Ditolak", "transfer"))
+	// bukti yang ditolak tidak menahan expiry
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM payment_validations pv WHERE pv.booking_id=\? AND COALESCE\(pv.payment_status,''\) <> 'Ditolak'`).
+		WithArgs(int64(8)).
+		WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(0))
+	mock.ExpectQuery(`FROM bookings WHERE id=\? LIMIT 1 FOR UPDATE`).
+		WithArgs(int64(8)).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_status", "booking_status"}).AddRow("Ditolak", "rejected"))
+	mock.ExpectExec(`UPDATE bookings SET booking_status=\?,payment_status=\? WHERE id=\?`).
+		WithArgs("expired", "Kedaluwarsa", int64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	svc := BookingExpiryService{
+		Bookings: repositories.BookingRepository{DB: db},
+		DB:       db,
+		Now:      func() time.Time { return time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local) },
+	}
+	n, err := svc.ExpireDue()
+	if err != nil {
+		t.Fatalf("ExpireDue: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("rejected booking should expire, expired=%d", n)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
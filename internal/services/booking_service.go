@@ -11,7 +11,6 @@ import (
 	"backend/internal/domain"
 	"backend/internal/domain/models"
 	"backend/internal/repositories"
-	"backend/internal/utils"
 )
 
 type BookingService struct {
@@ -88,57 +87,30 @@ func (s BookingService) SavePassengerInputs(bookingID int64, inputs []models.Pas
 		return domain.ValidationError{Field: "passengers", Msg: "data kosong"}
 	}
 
-	// pastikan table booking_passengers ada + kolom penting ada
-	if err := s.ensureBookingPassengerTable(); err != nil {
-		return domain.InternalError{Err: err}
-	}
-
+	// tabel & kolom paid_price dibuat migration (0001, 0024), bukan saat request
 	db := s.db()
-	withPaidPrice := intdb.HasColumn(db, "booking_passengers", "paid_price")
-
-	placeholder := "(?,?,?,?)"
-	if withPaidPrice {
-		placeholder = "(?,?,?,?,?)"
-	}
-
-	stmt := `INSERT INTO booking_passengers (booking_id, seat_code, passenger_name, passenger_phone`
-	if withPaidPrice {
-		stmt += ", paid_price"
+	if db == nil {
+		return domain.InternalError{Msg: "db tidak tersedia"}
 	}
-	stmt += `) VALUES ` + placeholder + `
-ON DUPLICATE KEY UPDATE passenger_name=VALUES(passenger_name), passenger_phone=VALUES(passenger_phone)`
-	if withPaidPrice {
-		stmt += `, paid_price=VALUES(paid_price)`
+	if !intdb.HasTable(db, "booking_passengers") {
+		return domain.InternalError{Msg: "tabel booking_passengers belum tersedia, jalankan `migrate up`"}
 	}
 
+	stmt := `INSERT INTO booking_passengers (booking_id, seat_code, passenger_name, passenger_phone, paid_price) VALUES (?,?,?,?,?)
+ON DUPLICATE KEY UPDATE passenger_name=VALUES(passenger_name), passenger_phone=VALUES(passenger_phone), paid_price=VALUES(paid_price)`
+
+	fares := FareService{Stops: repositories.StopRepository{DB: s.DB}, Fares: repositories.FareRepository{DB: s.DB}}
+	paid := fares.PricePerSeat(booking.RouteFrom, booking.RouteTo, booking.Category, booking.TripDate, booking.PricePerSeat)
+
 	tx, err := db.Begin()
 	if err != nil {
 		return domain.InternalError{Err: fmt.Errorf("begin tx: %w", err)}
 	}
 
 	for _, p := range clean {
-		if p.Name == "" {
-			p.Name = ""
-		}
-		if p.Phone == "" {
-			p.Phone = ""
-		}
-
-		var paid int64 = 0
-		if withPaidPrice {
-			paid = utils.ComputeFare(booking.RouteFrom, booking.RouteTo, booking.PricePerSeat)
-		}
-
-		if withPaidPrice {
-			if _, err := tx.Exec(stmt, bookingID, p.SeatCode, p.Name, p.Phone, paid); err != nil {
-				_ = tx.Rollback()
-				return domain.InternalError{Err: fmt.Errorf("insert booking_passengers booking=%d seat=%s: %w", bookingID, p.SeatCode, err)}
-			}
-		} else {
-			if _, err := tx.Exec(stmt, bookingID, p.SeatCode, p.Name, p.Phone); err != nil {
-				_ = tx.Rollback()
-				return domain.InternalError{Err: fmt.Errorf("insert booking_passengers booking=%d seat=%s: %w", bookingID, p.SeatCode, err)}
-			}
+		if _, err := tx.Exec(stmt, bookingID, p.SeatCode, p.Name, p.Phone, paid); err != nil {
+			_ = tx.Rollback()
+			return domain.InternalError{Err: fmt.Errorf("insert booking_passengers booking=%d seat=%s: %w", bookingID, p.SeatCode, err)}
 		}
 	}
 
@@ -191,35 +163,3 @@ ON DUPLICATE KEY UPDATE passenger_name=VALUES(passenger_name), passenger_phone=V
 
 	return nil
 }
-
-func (s BookingService) ensureBookingPassengerTable() error {
-	db := s.db()
-	if db == nil {
-		return fmt.Errorf("db tidak tersedia")
-	}
-
-	if intdb.HasTable(db, "booking_passengers") {
-		if !intdb.HasColumn(db, "booking_passengers", "paid_price") {
-			if _, err := db.Exec(`ALTER TABLE booking_passengers ADD COLUMN paid_price BIGINT NULL DEFAULT NULL`); err != nil {
-				return fmt.Errorf("alter booking_passengers add paid_price: %w", err)
-			}
-		}
-		return nil
-	}
-
-	ddl := `
-CREATE TABLE IF NOT EXISTS booking_passengers (
-	id BIGINT AUTO_INCREMENT PRIMARY KEY,
-	booking_id BIGINT NOT NULL,
-	seat_code VARCHAR(50) NOT NULL,
-	passenger_name VARCHAR(255) NOT NULL,
-	passenger_phone VARCHAR(100) NOT NULL,
-	paid_price BIGINT NULL DEFAULT NULL,
-	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	UNIQUE KEY uniq_booking_seat (booking_id, seat_code),
-	KEY idx_booking (booking_id)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
-`
-	_, err := db.Exec(ddl)
-	return err
-}
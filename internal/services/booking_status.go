@@ -0,0 +1,61 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+)
+
+// statusActor melengkapi actor perubahan status dengan request ID yang sedang berjalan.
+func statusActor(a repositories.StatusActor, requestID string) repositories.StatusActor {
+	if a.RequestID == "" {
+		a.RequestID = requestID
+	}
+	return a
+}
+
+// departureStatusAfter menebak departure_status setelah payload partial diterapkan
+// (key tidak ada = nilai lama tetap).
+func departureStatusAfter(raw []byte, current string) string {
+	var payload map[string]any
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return current
+	}
+	for _, k := range []string{"departure_status", "departureStatus"} {
+		if v, ok := payload[k]; ok {
+			s, _ := v.(string)
+			return strings.TrimSpace(s)
+		}
+	}
+	return current
+}
+
+func isBerangkat(status string) bool {
+	return strings.EqualFold(strings.TrimSpace(status), "Berangkat")
+}
+
+// pulangSteps mengembalikan transisi yang perlu dicatat saat kepulangan ditandai:
+// booking lunas dianggap sudah berangkat lalu selesai.
+func pulangSteps(repo repositories.BookingRepository, bookingID int64) ([]domain.BookingStatus, error) {
+	cur, err := repo.CurrentStatus(bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca status booking: %w", err)
+	}
+	return pulangStepsFrom(cur)
+}
+
+// pulangStepsFrom sama dengan pulangSteps untuk status cur yang sudah dibaca pemanggil.
+func pulangStepsFrom(cur domain.BookingStatus) ([]domain.BookingStatus, error) {
+	switch cur {
+	case domain.BookingCompleted:
+		return nil, nil
+	case domain.BookingDeparted:
+		return []domain.BookingStatus{domain.BookingCompleted}, nil
+	case domain.BookingPaid:
+		return []domain.BookingStatus{domain.BookingDeparted, domain.BookingCompleted}, nil
+	}
+	return nil, domain.ValidateBookingTransition(cur, domain.BookingCompleted)
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+)
+
+// Status pembayaran booking yang relevan untuk pembatalan.
+const (
+	bookingStatusPaid               = "Lunas"
+	bookingStatusAwaitingValidation = "Menunggu Validasi"
+	bookingStatusCancelled          = "Dibatalkan"
+)
+
+// CancellationPolicy menentukan biaya pembatalan: gratis bila dibatalkan minimal FreeHours
+// sebelum jam berangkat, setelah itu dipotong FeePercent dari jumlah yang sudah dibayar.
+// Booking tidak bisa dibatalkan setelah jam berangkat.
+type CancellationPolicy struct {
+	FreeHours  int
+	FeePercent int
+}
+
+// Fee menghitung potongan pembatalan untuk pembayaran paid.
+func (p CancellationPolicy) Fee(paid int64, departure, now time.Time) (int64, error) {
+	if !now.Before(departure) {
+		return 0, domain.ValidationError{Field: "booking", Msg: "Jadwal keberangkatan sudah lewat, booking tidak bisa dibatalkan"}
+	}
+	if departure.Sub(now) >= time.Duration(p.FreeHours)*time.Hour || paid <= 0 {
+		return 0, nil
+	}
+	pct := int64(p.FeePercent)
+	if pct < 0 {
+		pct = 0
+	}
+	if pct > 100 {
+		pct = 100
+	}
+	return paid * pct / 100, nil
+}
+
+// departureTime menggabungkan trip_date (YYYY-MM-DD) dan trip_time (HH:MM) di zona lokal.
+func departureTime(date, clock string) (time.Time, error) {
+	date = strings.TrimSpace(date)
+	if len(date) > 10 {
+		date = date[:10]
+	}
+	clock = strings.TrimSpace(clock)
+	if len(clock) > 5 {
+		clock = clock[:5]
+	}
+	if clock == "" {
+		clock = "00:00"
+	}
+	return time.ParseInLocation("2006-01-02 15:04", date+" "+clock, time.Local)
+}
+
+// bookingDeparted melaporkan apakah departure_settings booking sudah ditandai Berangkat.
+func bookingDeparted(repo repositories.DepartureRepository, bookingID int64) bool {
+	dep, err := repo.GetByBookingID(bookingID)
+	return err == nil && strings.EqualFold(strings.TrimSpace(dep.DepartureStatus), "Berangkat")
+}
+
+// cancelAmounts memisahkan uang yang sudah dikonfirmasi (dasar refund) dari bukti bayar yang
+// masih menunggu validasi. Booking lama tanpa ledger memakai payment_status: Lunas = total
+// terbayar, Menunggu Validasi = total masih menunggu.
+func cancelAmounts(total int64, paymentStatus string, entries []repositories.LedgerPayment) (paid, pending int64) {
+	if len(entries) > 0 {
+		// kelebihan bayar (kredit reschedule) dikembalikan lewat refund kredit, bukan refund pembatalan
+		bal := NewBookingBalance(0, total, entries, false)
+		return min(bal.Paid, total), bal.Pending
+	}
+	switch strings.TrimSpace(paymentStatus) {
+	case bookingStatusPaid:
+		return total, 0
+	case bookingStatusAwaitingValidation:
+		return 0, total
+	}
+	return 0, 0
+}
+
+// CancelResult adalah ringkasan pembatalan yang dikembalikan ke client.
+type CancelResult struct {
+	BookingID     int64                `json:"bookingId"`
+	PaymentStatus string               `json:"paymentStatus"`
+	SeatsReleased int64                `json:"seatsReleased"`
+	Fee           int64                `json:"fee"`
+	RefundAmount  int64                `json:"refundAmount"`
+	Refund        *repositories.Refund `json:"refund,omitempty"`
+	// PendingAmount adalah bukti bayar yang belum divalidasi; baru di-refund bila admin menyetujuinya.
+	PendingAmount int64 `json:"pendingAmount"`
+}
+
+// CancellationService membatalkan booking reguler: melepas seat, mencatat refund, dan
+// membersihkan manifest (departure/return settings, passengers, trip_information) dalam satu transaksi.
+type CancellationService struct {
+	Bookings   repositories.BookingRepository
+	Seats      repositories.BookingSeatRepository
+	Departures repositories.DepartureRepository
+	Returns    repositories.ReturnRepository
+	Passengers repositories.PassengerRepository
+	TripInfo   repositories.TripInformationRepository
+	Payments   repositories.PaymentRepository
+	Refunds    repositories.RefundRepository
+	Ledger     repositories.PaymentLedgerRepository
+	Policy     CancellationPolicy
+	Actor      repositories.StatusActor
+	DB         *sql.DB
+	RequestID  string
+	Now        func() time.Time
+}
+
+func (s CancellationService) db() *sql.DB {
+	if s.DB != nil {
+		return s.DB
+	}
+	return intconfig.DB
+}
+
+func (s CancellationService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Cancel membatalkan booking. Refund hanya dibuat untuk pembayaran yang sudah dikonfirmasi
+// (Lunas atau DP); refundMethod kosong = metode bayar booking. Bukti bayar yang masih menunggu
+// validasi dibiarkan pending dan diselesaikan lewat SettleLateProofTx saat admin memvalidasinya.
+func (s CancellationService) Cancel(bookingID, userID int64, reason, refundMethod string) (CancelResult, error) {
+	res := CancelResult{BookingID: bookingID}
+	if bookingID <= 0 {
+		return res, domain.ValidationError{Field: "id", Msg: "id tidak valid"}
+	}
+	if bookingDeparted(s.Departures, bookingID) {
+		return res, domain.ValidationError{Field: "booking", Msg: "Penumpang sudah berangkat, booking tidak bisa dibatalkan"}
+	}
+
+	db := s.db()
+	if db == nil {
+		return res, domain.InternalError{Msg: "db tidak tersedia"}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return res, domain.InternalError{Msg: "gagal mulai transaksi", Err: err}
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	b, err := s.Bookings.LockForUpdate(tx, bookingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return res, domain.NotFoundError{Resource: "booking"}
+	}
+	if err != nil {
+		return res, domain.InternalError{Msg: "gagal membaca booking", Err: err}
+	}
+	if strings.TrimSpace(b.PaymentStatus) == bookingStatusCancelled {
+		return res, domain.ConflictError{Resource: "booking", Msg: "Booking sudah dibatalkan"}
+	}
+
+	departure, err := departureTime(b.TripDate, b.TripTime)
+	if err != nil {
+		return res, domain.InternalError{Msg: "jadwal booking tidak valid", Err: err}
+	}
+	now := s.now()
+	entries, err := s.Ledger.ListTx(tx, bookingID)
+	if err != nil {
+		return res, domain.InternalError{Msg: "gagal membaca riwayat pembayaran", Err: err}
+	}
+	paid, pending := cancelAmounts(b.Total, b.PaymentStatus, entries)
+	fee, err := s.Policy.Fee(paid, departure, now)
+	if err != nil {
+		return res, err
+	}
+
+	released, err := s.Seats.DeleteByBookingTx(tx, bookingID)
+	if err != nil {
+		return res, domain.InternalError{Msg: "gagal melepas seat", Err: err}
+	}
+	actor := statusActor(s.Actor, s.RequestID)
+	if actor.UserID == 0 {
+		actor.UserID = userID
+	}
+	if _, err := s.Bookings.TransitionTx(tx, bookingID, domain.BookingCancelled, actor, reason); err != nil {
+		if domain.IsConflict(err) {
+			return res, err
+		}
+		return res, domain.InternalError{Msg: "gagal update status booking", Err: err}
+	}
+	if err := s.Bookings.MarkCancelledTx(tx, bookingID, bookingStatusCancelled, reason, now); err != nil {
+		return res, domain.InternalError{Msg: "gagal update booking", Err: err}
+	}
+	if err := s.Departures.CancelByBookingTx(tx, bookingID, bookingStatusCancelled); err != nil {
+		return res, domain.InternalError{Msg: "gagal update departure_settings", Err: err}
+	}
+	if err := s.Returns.CancelByBookingTx(tx, bookingID, bookingStatusCancelled); err != nil {
+		return res, domain.InternalError{Msg: "gagal update return_settings", Err: err}
+	}
+	if err := s.Passengers.DeleteByBookingTx(tx, bookingID); err != nil {
+		return res, domain.InternalError{Msg: "gagal hapus passengers", Err: err}
+	}
+	if err := s.TripInfo.DeleteByBookingTx(tx, bookingID); err != nil {
+		return res, domain.InternalError{Msg: "gagal hapus trip_information", Err: err}
+	}
+
+	if paid > 0 {
+		method := strings.TrimSpace(refundMethod)
+		if method == "" {
+			method = strings.TrimSpace(b.PaymentMethod)
+		}
+		rf := repositories.Refund{
+			BookingID:   bookingID,
+			PaidAmount:  paid,
+			Fee:         fee,
+			Amount:      paid - fee,
+			Method:      method,
+			Status:      repositories.RefundPending,
+			Reason:      strings.TrimSpace(reason),
+			RequestedBy: userID,
+			CreatedAt:   now,
+		}
+		id, err := s.Refunds.InsertTx(tx, rf)
+		if err != nil {
+			return res, domain.InternalError{Msg: "gagal mencatat refund", Err: err}
+		}
+		rf.ID = id
+		res.Refund = &rf
+	}
+
+	if err := tx.Commit(); err != nil {
+		return res, domain.InternalError{Msg: "gagal commit pembatalan", Err: err}
+	}
+	committed = true
+
+	res.PaymentStatus = bookingStatusCancelled
+	res.SeatsReleased = released
+	res.Fee = fee
+	res.RefundAmount = paid - fee
+	res.PendingAmount = pending
+	utils.LogEvent(s.RequestID, "booking", "cancel", fmt.Sprintf("booking_id=%d seats=%d paid=%d pending=%d fee=%d", bookingID, released, paid, pending, fee))
+	return res, nil
+}
+
+// SettleLateProofTx menerapkan validasi bukti bayar yang masih menunggu saat booking dibatalkan.
+// Status booking tetap dibatalkan; bukti yang disetujui dicatat di ledger lalu dibuatkan refund
+// tersendiri dengan potongan sesuai kebijakan pada waktu pembatalan. Mengembalikan nil bila
+// tidak ada uang yang perlu dikembalikan (bukti ditolak).
+func (s CancellationService) SettleLateProofTx(tx *sql.Tx, bookingID, validationID int64, approved bool, method string, actorID int64) (*repositories.Refund, error) {
+	b, err := s.Bookings.LockForUpdate(tx, bookingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NotFoundError{Resource: "booking"}
+	}
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal membaca booking", Err: err}
+	}
+
+	amount := int64(0)
+	ledger := PaymentLedgerService{Ledger: s.Ledger, Bookings: s.Bookings, RequestID: s.RequestID, Now: s.Now}
+	if ledger.Available() {
+		before, err := ledger.BalanceTx(tx, bookingID)
+		if err != nil {
+			return nil, err
+		}
+		after, err := ledger.SettleValidationTx(tx, bookingID, validationID, approved, method, actorID)
+		if err != nil {
+			return nil, err
+		}
+		amount = after.Paid - before.Paid
+	} else if approved {
+		amount = b.Total
+	}
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	// potongan mengikuti waktu pembatalan, bukan waktu validasi
+	fee := int64(0)
+	if departure, err := departureTime(b.TripDate, b.TripTime); err == nil {
+		at, ok, err := s.Bookings.CancelledAtTx(tx, bookingID)
+		if err != nil {
+			return nil, domain.InternalError{Msg: "gagal membaca waktu pembatalan", Err: err}
+		}
+		if ok {
+			fee, _ = s.Policy.Fee(amount, departure, at)
+		}
+	}
+	if strings.TrimSpace(method) == "" {
+		method = b.PaymentMethod
+	}
+	rf := repositories.Refund{
+		BookingID:   bookingID,
+		PaidAmount:  amount,
+		Fee:         fee,
+		Amount:      amount - fee,
+		Method:      strings.TrimSpace(method),
+		Status:      repositories.RefundPending,
+		Reason:      "bukti bayar disetujui setelah booking dibatalkan",
+		RequestedBy: actorID,
+		CreatedAt:   s.now(),
+	}
+	id, err := s.Refunds.InsertTx(tx, rf)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal mencatat refund", Err: err}
+	}
+	rf.ID = id
+	utils.LogEvent(s.RequestID, "booking", "late_proof_refund", fmt.Sprintf("booking_id=%d validation_id=%d amount=%d fee=%d", bookingID, validationID, amount, fee))
+	return &rf, nil
+}
+
+// ===== admin refund =====
+
+func (s CancellationService) ListRefunds(status string) ([]repositories.Refund, error) {
+	list, err := s.Refunds.List(status)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat refunds", Err: err}
+	}
+	return list, nil
+}
+
+// UpdateRefund menyelesaikan refund pending menjadi processed (dana sudah dikirim) atau rejected.
+func (s CancellationService) UpdateRefund(id int64, status, note string) (repositories.Refund, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if status != repositories.RefundProcessed && status != repositories.RefundRejected {
+		return repositories.Refund{}, domain.ValidationError{Field: "status", Msg: "harus processed atau rejected"}
+	}
+	rf, err := s.Refunds.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return rf, domain.NotFoundError{Resource: "refund"}
+	}
+	if err != nil {
+		return rf, domain.InternalError{Msg: "gagal memuat refund", Err: err}
+	}
+	if rf.Status != repositories.RefundPending {
+		return rf, domain.ConflictError{Resource: "refund", Msg: "refund sudah " + rf.Status}
+	}
+	now := s.now()
+	if err := s.Refunds.UpdateStatus(id, status, strings.TrimSpace(note), now); err != nil {
+		return rf, domain.InternalError{Msg: "gagal update refund", Err: err}
+	}
+	rf.Status = status
+	rf.Note = strings.TrimSpace(note)
+	rf.ProcessedAt = &now
+	utils.LogEvent(s.RequestID, "refund", "update", fmt.Sprintf("id=%d booking_id=%d status=%s", id, rf.BookingID, status))
+	return rf, nil
+}
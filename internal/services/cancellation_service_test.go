@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+)
+
+func TestCancellationPolicyFee(t *testing.T) {
+	p := CancellationPolicy{FreeHours: 24, FeePercent: 25}
+	departure, err := departureTime("2025-03-10", "08:00")
+	if err != nil {
+		t.Fatalf("departureTime: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		now  time.Time
+		paid int64
+		want int64
+	}{
+		{"jauh hari gratis", departure.Add(-48 * time.Hour), 200000, 0},
+		{"tepat batas gratis", departure.Add(-24 * time.Hour), 200000, 0},
+		{"kurang dari batas kena fee", departure.Add(-2 * time.Hour), 200000, 50000},
+		{"belum bayar tanpa fee", departure.Add(-2 * time.Hour), 0, 0},
+	}
+	for _, tc := range cases {
+		got, err := p.Fee(tc.paid, departure, tc.now)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%s: expected fee %d, got %d", tc.name, tc.want, got)
+		}
+	}
+
+	if _, err := p.Fee(200000, departure, departure); !domain.IsValidation(err) {
+		t.Fatalf("cancel at departure time should be rejected, got %v", err)
+	}
+}
+
+func TestDepartureTimeAcceptsDateTimeStrings(t *testing.T) {
+	got, err := departureTime("2025-03-10T00:00:00Z", "08:30:00")
+	if err != nil {
+		t.Fatalf("departureTime: %v", err)
+	}
+	if got.Format("2006-01-02 15:04") != "2025-03-10 08:30" {
+		t.Fatalf("unexpected departure %s", got)
+	}
+}
+
+func TestCancelAmountsRefundOnlyConfirmedPayments(t *testing.T) {
+	entries := []repositories.LedgerPayment{
+		{Amount: 50000, Status: repositories.LedgerApproved},
+		{Amount: 150000, Status: repositories.LedgerPending},
+		{Amount: 20000, Status: repositories.LedgerRejected},
+	}
+	if paid, pending := cancelAmounts(200000, bookingStatusAwaitingValidation, entries); paid != 50000 || pending != 150000 {
+		t.Fatalf("ledger: expected paid 50000 pending 150000, got %d %d", paid, pending)
+	}
+	if paid, pending := cancelAmounts(200000, bookingStatusPaid, nil); paid != 200000 || pending != 0 {
+		t.Fatalf("legacy lunas: expected paid 200000, got %d %d", paid, pending)
+	}
+	if paid, pending := cancelAmounts(200000, bookingStatusAwaitingValidation, nil); paid != 0 || pending != 200000 {
+		t.Fatalf("legacy menunggu validasi must not be refunded, got paid %d pending %d", paid, pending)
+	}
+	// reschedule ke tarif lebih murah: kelebihan bayar dikembalikan lewat refund kredit
+	overpaid := []repositories.LedgerPayment{{Amount: 200000, Status: repositories.LedgerApproved}}
+	if paid, _ := cancelAmounts(150000, bookingStatusPaid, overpaid); paid != 150000 {
+		t.Fatalf("overpaid: expected paid capped at 150000, got %d", paid)
+	}
+}
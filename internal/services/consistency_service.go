@@ -0,0 +1,294 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+)
+
+// Jenis inkonsistensi data turunan booking.
+const (
+	// IssuePaidWithoutSettings: booking lunas tanpa departure/return settings.
+	IssuePaidWithoutSettings = "paid_without_settings"
+	// IssuePaidWithoutPassengers: booking lunas dengan settings tetapi tanpa passenger_seats.
+	IssuePaidWithoutPassengers = "paid_without_passengers"
+	// IssueSeatsMissing: kursi di booking_seats tidak tercantum di seat_numbers settings.
+	IssueSeatsMissing = "seats_missing_from_settings"
+	// IssueDepartedWithoutTripInfo: departure_settings Berangkat tanpa trip_information.
+	IssueDepartedWithoutTripInfo = "departed_without_trip_information"
+	// IssueOrphanTripInfo: trip_information yang booking-nya sudah tidak ada.
+	IssueOrphanTripInfo = "orphan_trip_information"
+)
+
+// ConsistencyKinds adalah semua jenis inkonsistensi sesuai urutan perbaikan.
+var ConsistencyKinds = []string{
+	IssuePaidWithoutSettings,
+	IssuePaidWithoutPassengers,
+	IssueSeatsMissing,
+	IssueDepartedWithoutTripInfo,
+	IssueOrphanTripInfo,
+}
+
+// DefaultConsistencyLimit membatasi jumlah temuan per jenis dalam satu kali cek.
+const DefaultConsistencyLimit = 500
+
+// ConsistencyIssue adalah satu baris yang tidak sinkron beserta hasil perbaikannya (bila repair).
+type ConsistencyIssue struct {
+	Kind        string `json:"kind"`
+	Table       string `json:"table"`
+	RowID       int64  `json:"rowId"`
+	BookingID   int64  `json:"bookingId"`
+	Detail      string `json:"detail"`
+	Repaired    bool   `json:"repaired,omitempty"`
+	RepairError string `json:"repairError,omitempty"`
+}
+
+// ConsistencyReport adalah hasil cek (dan repair) konsistensi.
+type ConsistencyReport struct {
+	CheckedAt time.Time          `json:"checkedAt"`
+	DryRun    bool               `json:"dryRun"`
+	Counts    map[string]int     `json:"counts"`
+	Issues    []ConsistencyIssue `json:"issues"`
+	Repaired  int                `json:"repaired"`
+	Failed    int                `json:"failed"`
+}
+
+// ConsistencyService mendeteksi dan memperbaiki booking lunas, departure/return settings,
+// passenger_seats dan trip_information yang tidak sinkron. Perbaikan memakai jalur sinkron yang
+// sama dengan alur normal sehingga aman diulang.
+type ConsistencyService struct {
+	Repo      repositories.ConsistencyRepository
+	Limit     int
+	RequestID string
+	Now       func() time.Time
+	// Fix memperbaiki satu temuan; nil = perbaikan bawaan (fix).
+	Fix func(ConsistencyIssue) error
+}
+
+func (s ConsistencyService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// ParseConsistencyKinds memvalidasi daftar jenis (dipisah koma); kosong = semua jenis.
+func ParseConsistencyKinds(raw string) ([]string, error) {
+	out := []string{}
+	for _, k := range strings.Split(raw, ",") {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" {
+			continue
+		}
+		known := false
+		for _, c := range ConsistencyKinds {
+			if c == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, domain.ValidationError{Field: "kinds", Msg: "jenis " + k + " tidak dikenal; pilih " + strings.Join(ConsistencyKinds, ", ")}
+		}
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+// seatCodes memecah daftar kursi (koma/spasi) menjadi kode uppercase unik.
+func seatCodes(raw string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, part := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		code := strings.ToUpper(strings.TrimSpace(part))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		out = append(out, code)
+	}
+	return out
+}
+
+// missingSeats mengembalikan kursi booking yang tidak ada di seat_numbers settings.
+func missingSeats(booked, seatNumbers string) []string {
+	have := map[string]bool{}
+	for _, c := range seatCodes(seatNumbers) {
+		have[c] = true
+	}
+	out := []string{}
+	for _, c := range seatCodes(booked) {
+		if !have[c] {
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func wanted(kinds []string, kind string) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Check mendeteksi inkonsistensi jenis kinds (kosong = semua) tanpa mengubah data.
+func (s ConsistencyService) Check(kinds []string) (ConsistencyReport, error) {
+	limit := s.Limit
+	if limit <= 0 {
+		limit = DefaultConsistencyLimit
+	}
+	rep := ConsistencyReport{CheckedAt: s.now(), DryRun: true, Counts: map[string]int{}, Issues: []ConsistencyIssue{}}
+	add := func(i ConsistencyIssue) {
+		rep.Counts[i.Kind]++
+		rep.Issues = append(rep.Issues, i)
+	}
+	fail := func(err error) (ConsistencyReport, error) {
+		return rep, domain.InternalError{Msg: "gagal memeriksa konsistensi data", Err: err}
+	}
+
+	if wanted(kinds, IssuePaidWithoutSettings) {
+		ids, err := s.Repo.PaidWithoutSettings(limit)
+		if err != nil {
+			return fail(err)
+		}
+		for _, id := range ids {
+			add(ConsistencyIssue{Kind: IssuePaidWithoutSettings, Table: "bookings", RowID: id, BookingID: id,
+				Detail: "booking lunas belum punya departure/return settings"})
+		}
+	}
+	if wanted(kinds, IssuePaidWithoutPassengers) {
+		ids, err := s.Repo.PaidWithoutPassengers(limit)
+		if err != nil {
+			return fail(err)
+		}
+		for _, id := range ids {
+			add(ConsistencyIssue{Kind: IssuePaidWithoutPassengers, Table: "bookings", RowID: id, BookingID: id,
+				Detail: "booking lunas belum punya passenger_seats"})
+		}
+	}
+	if wanted(kinds, IssueSeatsMissing) {
+		rows, err := s.Repo.SettingSeats()
+		if err != nil {
+			return fail(err)
+		}
+		for _, r := range rows {
+			missing := missingSeats(r.BookedSeats, r.SeatNumbers)
+			if len(missing) == 0 || rep.Counts[IssueSeatsMissing] >= limit {
+				continue
+			}
+			add(ConsistencyIssue{Kind: IssueSeatsMissing, Table: r.Table, RowID: r.ID, BookingID: r.BookingID,
+				Detail: fmt.Sprintf("kursi %s tidak ada di seat_numbers %q", strings.Join(missing, ","), r.SeatNumbers)})
+		}
+	}
+	if wanted(kinds, IssueDepartedWithoutTripInfo) {
+		rows, err := s.Repo.DepartedWithoutTripInformation(limit)
+		if err != nil {
+			return fail(err)
+		}
+		for _, r := range rows {
+			add(ConsistencyIssue{Kind: IssueDepartedWithoutTripInfo, Table: "departure_settings", RowID: r.ID, BookingID: r.BookingID,
+				Detail: "keberangkatan berstatus Berangkat belum punya trip_information"})
+		}
+	}
+	if wanted(kinds, IssueOrphanTripInfo) {
+		rows, err := s.Repo.OrphanTripInformation(limit)
+		if err != nil {
+			return fail(err)
+		}
+		for _, r := range rows {
+			add(ConsistencyIssue{Kind: IssueOrphanTripInfo, Table: "trip_information", RowID: r.ID, BookingID: r.BookingID,
+				Detail: fmt.Sprintf("trip %s menunjuk booking yang sudah tidak ada", r.TripNumber)})
+		}
+	}
+	return rep, nil
+}
+
+// Repair menjalankan Check lalu memperbaiki setiap temuan. Kegagalan satu temuan dicatat di
+// report dan tidak menghentikan perbaikan lainnya.
+func (s ConsistencyService) Repair(kinds []string) (ConsistencyReport, error) {
+	rep, err := s.Check(kinds)
+	if err != nil {
+		return rep, err
+	}
+	rep.DryRun = false
+	fix := s.Fix
+	if fix == nil {
+		fix = s.fix
+	}
+	for i := range rep.Issues {
+		issue := &rep.Issues[i]
+		if err := fix(*issue); err != nil {
+			issue.RepairError = err.Error()
+			rep.Failed++
+			utils.LogEvent(s.RequestID, "consistency", "repair_failed",
+				fmt.Sprintf("kind=%s table=%s id=%d booking_id=%d: %v", issue.Kind, issue.Table, issue.RowID, issue.BookingID, err))
+			continue
+		}
+		issue.Repaired = true
+		rep.Repaired++
+	}
+	utils.LogEvent(s.RequestID, "consistency", "repair",
+		fmt.Sprintf("issues=%d repaired=%d failed=%d", len(rep.Issues), rep.Repaired, rep.Failed))
+	return rep, nil
+}
+
+// fix memperbaiki satu temuan lewat jalur sinkron yang sudah ada.
+func (s ConsistencyService) fix(issue ConsistencyIssue) error {
+	bookings := repositories.BookingRepository{}
+	seats := repositories.BookingSeatRepository{}
+	departures := DepartureService{Repo: repositories.DepartureRepository{}, BookingRepo: bookings, SeatRepo: seats, RequestID: s.RequestID}
+	returns := ReturnService{Repo: repositories.ReturnRepository{}, BookingRepo: bookings, SeatRepo: seats, RequestID: s.RequestID}
+	passengers := PassengerService{PassengerRepo: repositories.PassengerRepository{}, BookingRepo: bookings, BookingSeatRepo: seats, RequestID: s.RequestID}
+
+	switch issue.Kind {
+	case IssuePaidWithoutSettings:
+		payments := PaymentService{
+			PaymentRepo:     repositories.PaymentRepository{},
+			BookingRepo:     bookings,
+			BookingSeatRepo: seats,
+			DepartureSvc:    departures,
+			ReturnSvc:       returns,
+			PassengerSvc:    passengers,
+			RequestID:       s.RequestID,
+		}
+		return payments.SyncSettings(issue.BookingID, "")
+	case IssuePaidWithoutPassengers:
+		return passengers.SyncFromBooking(issue.BookingID)
+	case IssueSeatsMissing:
+		// upsert penuh dari booking akan mengosongkan sopir/status, jadi hanya kursi yang ditulis ulang
+		booked, err := seats.GetSeats(issue.BookingID)
+		if err != nil {
+			return err
+		}
+		codes := []string{}
+		for _, bs := range booked {
+			codes = append(codes, bs.SeatCode)
+		}
+		if err := s.Repo.UpdateSettingSeats(issue.Table, issue.RowID, seatCodes(strings.Join(codes, ","))); err != nil {
+			return err
+		}
+		return passengers.SyncFromBooking(issue.BookingID)
+	case IssueDepartedWithoutTripInfo:
+		dep, err := repositories.DepartureRepository{}.GetByID(int(issue.RowID))
+		if err != nil {
+			return err
+		}
+		return TripInfoService{Repo: repositories.TripInformationRepository{}, RequestID: s.RequestID}.UpsertFromDeparture(dep)
+	case IssueOrphanTripInfo:
+		_, err := s.Repo.DeleteTripInformation(issue.RowID)
+		return err
+	}
+	return fmt.Errorf("jenis %q tidak dikenal", issue.Kind)
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	intdb "backend/internal/db"
+	"backend/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestMissingSeats(t *testing.T) {
+	cases := []struct {
+		booked, seatNumbers string
+		want                []string
+	}{
+		{"1A,1B", "1A, 1B", []string{}},
+		{"1a,2B,1A", "1A", []string{"2B"}},
+		{"3C,2B", "", []string{"2B", "3C"}},
+		{"", "1A", []string{}},
+	}
+	for _, c := range cases {
+		if got := missingSeats(c.booked, c.seatNumbers); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("missingSeats(%q, %q) = %v, want %v", c.booked, c.seatNumbers, got, c.want)
+		}
+	}
+}
+
+func TestParseConsistencyKinds(t *testing.T) {
+	kinds, err := ParseConsistencyKinds(" Paid_Without_Settings, orphan_trip_information ,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(kinds, []string{IssuePaidWithoutSettings, IssueOrphanTripInfo}) {
+		t.Fatalf("unexpected kinds: %v", kinds)
+	}
+	if kinds, err := ParseConsistencyKinds(""); err != nil || len(kinds) != 0 {
+		t.Fatalf("empty kinds should mean all, got %v %v", kinds, err)
+	}
+	if _, err := ParseConsistencyKinds("unknown"); err == nil {
+		t.Fatal("expected validation error for unknown kind")
+	}
+}
+
+func TestConsistencyRepairReportsEachIssue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	intdb.Schema.Invalidate()
+	schema := sqlmock.NewRows([]string{"table_name", "column_name"})
+	for table, cols := range map[string][]string{
+		"bookings":           {"id", "booking_status"},
+		"departure_settings": {"id", "booking_id", "seat_numbers", "departure_status"},
+		"passenger_seats":    {"id", "booking_id"},
+		"booking_seats":      {"id", "booking_id", "seat_code"},
+		"trip_information":   {"id", "booking_id", "trip_number", "trip_role"},
+	} {
+		for _, col := range cols {
+			schema.AddRow(table, col)
+		}
+	}
+	mock.ExpectQuery("FROM information_schema.columns").WillReturnRows(schema)
+
+	mock.ExpectQuery(`WHERE \(b.booking_status IN .* AND NOT \(EXISTS`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectQuery(`NOT EXISTS \(SELECT 1 FROM passenger_seats`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	mock.ExpectQuery(`JOIN booking_seats bs`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "booking_id", "seat_numbers", "seats"}).
+			AddRow(5, 12, "1A", "1A,1B").
+			AddRow(6, 13, "2A,2B", "2A,2B"))
+	mock.ExpectQuery(`d.departure_status = 'Berangkat'.*t.trip_role`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "booking_id"}).AddRow(7, 14))
+	mock.ExpectQuery(`LEFT JOIN bookings b ON b.id = t.booking_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "booking_id", "trip_number"}).AddRow(99, 404, "TRIP-9"))
+
+	fixed := []string{}
+	svc := ConsistencyService{
+		Repo: repositories.ConsistencyRepository{DB: db},
+		Fix: func(i ConsistencyIssue) error {
+			if i.Kind == IssueDepartedWithoutTripInfo {
+				return errors.New("departure tidak ditemukan")
+			}
+			fixed = append(fixed, i.Kind)
+			return nil
+		},
+	}
+	rep, err := svc.Repair(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+
+	if rep.DryRun || len(rep.Issues) != 5 || rep.Repaired != 4 || rep.Failed != 1 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	want := []string{IssuePaidWithoutSettings, IssuePaidWithoutPassengers, IssueSeatsMissing, IssueOrphanTripInfo}
+	if !reflect.DeepEqual(fixed, want) {
+		t.Fatalf("fixed %v, want %v", fixed, want)
+	}
+	seat := rep.Issues[2]
+	if seat.Table != "departure_settings" || seat.RowID != 5 || seat.BookingID != 12 || rep.Counts[IssueSeatsMissing] != 1 {
+		t.Fatalf("unexpected seat issue: %+v", seat)
+	}
+	if failed := rep.Issues[3]; failed.Repaired || failed.RepairError == "" {
+		t.Fatalf("expected repair error on departed issue, got %+v", failed)
+	}
+}
@@ -2,11 +2,15 @@ package services
 
 import (
 	"bytes"
+	"database/sql"
 	"errors"
 	"log"
 	"strconv"
 	"strings"
 
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+	"backend/internal/domain"
 	"backend/internal/domain/models"
 	"backend/internal/repositories"
 	"backend/internal/utils"
@@ -17,7 +21,20 @@ type DepartureService struct {
 	Repo        repositories.DepartureRepository
 	BookingRepo repositories.BookingRepository
 	SeatRepo    repositories.BookingSeatRepository
+	Guard       AssignmentGuard
+	Outbox      repositories.OutboxRepository
 	RequestID   string
+	Actor       repositories.StatusActor
+}
+
+func (s DepartureService) db() *sql.DB {
+	if s.Repo.DB != nil {
+		return s.Repo.DB
+	}
+	if s.BookingRepo.DB != nil {
+		return s.BookingRepo.DB
+	}
+	return intconfig.DB
 }
 
 // CreateOrUpdateFromBooking ensures departure_settings exists from booking data.
@@ -59,18 +76,88 @@ func (s DepartureService) CreateOrUpdateFromBookingID(bookingID int64) (models.D
 }
 
 // MarkBerangkat updates departure_settings with key-presence semantics and triggers sync when status Berangkat.
+// Transisi booking ke departed divalidasi dan ditulis lebih dulu, lalu departure_settings dan event
+// sinkron outbox disimpan dalam transaksi yang sama.
 func (s DepartureService) MarkBerangkat(id int, rawPayload []byte) (models.DepartureSetting, error) {
 	if len(bytes.TrimSpace(rawPayload)) == 0 {
 		return models.DepartureSetting{}, errors.New("payload kosong")
 	}
 	utils.LogEvent(s.RequestID, "departure", "mark_berangkat", "start id="+strconv.Itoa(id))
 
-	updated, err := s.Repo.UpdatePartial(id, rawPayload)
+	db := s.db()
+	if db == nil {
+		return models.DepartureSetting{}, errors.New("db tidak tersedia")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return models.DepartureSetting{}, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	queued, err := s.markBerangkatTx(tx, id, rawPayload)
+	if err != nil {
+		return models.DepartureSetting{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.DepartureSetting{}, err
+	}
+	committed = true
+	return s.afterBerangkat(id, queued)
+}
+
+// markBerangkatTx adalah isi MarkBerangkat di dalam transaksi pemanggil (dipakai juga TripRunService
+// untuk seluruh booking run sekaligus). queued = event sinkron sudah tercatat di outbox.
+func (s DepartureService) markBerangkatTx(tx *sql.Tx, id int, rawPayload []byte) (bool, error) {
+	existing, err := s.Repo.GetForUpdateTx(tx, id)
+	if err != nil {
+		return false, err
+	}
+
+	// booking hanya boleh berangkat dari status paid; ditolak sebelum departure_settings diubah
+	var departedBooking int64
+	if existing.BookingID > 0 && !isBerangkat(existing.DepartureStatus) && isBerangkat(departureStatusAfter(rawPayload, existing.DepartureStatus)) {
+		if _, err := s.BookingRepo.TransitionTx(tx, existing.BookingID, domain.BookingDeparted, statusActor(s.Actor, s.RequestID), "departure_settings id="+strconv.Itoa(id)); err != nil {
+			utils.LogEvent(s.RequestID, "departure", "mark_berangkat_status_error", err.Error())
+			return false, err
+		}
+		departedBooking = existing.BookingID
+	}
+
+	// sopir/kendaraan/jadwal yang berubah dicek bentrok dengan penugasan lain di dalam tx ini;
+	// gagal membaca baris = gagal menyimpan
+	preview, changed, err := s.Repo.PreviewTx(tx, id, rawPayload)
 	if err != nil {
+		return false, err
+	}
+	if changed {
+		candidate := settingAssignment(repositories.AssignmentDeparture, preview)
+		if err := s.Guard.CheckTx(tx, candidate, nil, forceRequested(rawPayload)); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := s.Repo.UpdatePartialTx(tx, id, rawPayload); err != nil {
 		utils.LogEvent(s.RequestID, "departure", "mark_berangkat_error", err.Error())
-		return updated, err
+		return false, err
+	}
+
+	if departedBooking > 0 && s.Outbox.Available() {
+		if err := s.enqueueDepartureSync(tx, id, departedBooking); err != nil {
+			return false, err
+		}
+		return true, nil
 	}
+	return false, nil
+}
 
+// afterBerangkat memuat ulang departure setelah commit lalu menjalankan sinkron yang belum tercatat
+// di outbox.
+func (s DepartureService) afterBerangkat(id int, queued bool) (models.DepartureSetting, error) {
 	reloaded, err := s.Repo.GetByID(id)
 	if err != nil {
 		return reloaded, err
@@ -79,7 +166,7 @@ func (s DepartureService) MarkBerangkat(id int, rawPayload []byte) (models.Depar
 	log.Printf("[DEPARTURE] id=%d driverName=%s vehicleCode=%s vehicleType=%s", id, strings.TrimSpace(reloaded.DriverName), strings.TrimSpace(reloaded.VehicleCode), strings.TrimSpace(reloaded.VehicleType))
 
 	if strings.EqualFold(strings.TrimSpace(reloaded.DepartureStatus), "Berangkat") {
-		if err := s.syncAfterBerangkat(reloaded); err != nil {
+		if err := s.syncAfterBerangkat(reloaded, queued); err != nil {
 			utils.LogEvent(s.RequestID, "departure", "sync_after_berangkat_error", err.Error())
 			return reloaded, err
 		}
@@ -89,34 +176,51 @@ func (s DepartureService) MarkBerangkat(id int, rawPayload []byte) (models.Depar
 	return reloaded, nil
 }
 
-func (s DepartureService) syncAfterBerangkat(dep models.DepartureSetting) error {
+// enqueueDepartureSync mencatat event sinkron penumpang & trip_information untuk departure depID.
+func (s DepartureService) enqueueDepartureSync(q intdb.Queryer, depID int, bookingID int64) error {
+	payload := repositories.DepartureSyncPayload{DepartureID: depID}
+	for _, event := range []string{repositories.EventPassengerSync, repositories.EventTripInfoUpsert} {
+		if _, err := s.Outbox.EnqueueTx(q, event, bookingID, payload, s.RequestID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncAfterBerangkat menyinkron penumpang & trip_information setelah berangkat lewat outbox (worker
+// yang me-retry). queued=true berarti event sudah dicatat bersama transisi booking. Tanpa tabel
+// outbox sinkron dijalankan langsung seperti sebelumnya (gagal hanya dicatat di log).
+func (s DepartureService) syncAfterBerangkat(dep models.DepartureSetting, queued bool) error {
 	ref := dep
 	if ref.BookingID <= 0 {
 		if reloaded, err := s.Repo.GetByID(dep.ID); err == nil {
 			ref = reloaded
 		}
 	}
+	if queued || ref.BookingID <= 0 {
+		return nil
+	}
+	if db := s.db(); db != nil && s.Outbox.Available() {
+		return s.enqueueDepartureSync(db, ref.ID, ref.BookingID)
+	}
 
-	if ref.BookingID > 0 {
-		passengerSvc := PassengerService{
-			PassengerRepo:   repositories.PassengerRepository{},
-			BookingRepo:     repositories.BookingRepository{},
-			BookingSeatRepo: repositories.BookingSeatRepository{},
-			RequestID:       s.RequestID,
-		}
-		if err := passengerSvc.SyncFromDeparture(ref); err != nil {
-			log.Println("[BERANGKAT SYNC] warning passenger sync:", err)
-		}
-
-		tripSvc := TripInfoService{
-			Repo:      repositories.TripInformationRepository{},
-			RequestID: s.RequestID,
-		}
-		if err := tripSvc.UpsertFromDeparture(ref); err != nil {
-			log.Println("[BERANGKAT SYNC] warning trip info sync:", err)
-		}
+	passengerSvc := PassengerService{
+		PassengerRepo:   repositories.PassengerRepository{},
+		BookingRepo:     repositories.BookingRepository{},
+		BookingSeatRepo: repositories.BookingSeatRepository{},
+		RequestID:       s.RequestID,
+	}
+	if err := passengerSvc.SyncFromDeparture(ref); err != nil {
+		log.Println("[BERANGKAT SYNC] warning passenger sync:", err)
 	}
 
+	tripSvc := TripInfoService{
+		Repo:      repositories.TripInformationRepository{},
+		RequestID: s.RequestID,
+	}
+	if err := tripSvc.UpsertFromDeparture(ref); err != nil {
+		log.Println("[BERANGKAT SYNC] warning trip info sync:", err)
+	}
 	return nil
 }
 
@@ -17,6 +17,7 @@ type DocsService struct {
 	PassengerRepo repositories.PassengerRepository
 	SeatRepo      repositories.BookingSeatRepo
 	BookingRepo   repositories.BookingRepository
+	Ledger        repositories.PaymentLedgerRepository
 	RequestID     string
 	Loader        func(int64) (passengerDocData, error)
 }
@@ -37,6 +38,9 @@ type passengerDocData struct {
 	VehicleCode    string
 	DriverName     string
 	PricePerSeat   int64
+	Balance        *BookingBalance // riwayat pembayaran booking; nil bila belum ada ledger
+	TransferCode   int64           // kode unik transfer booking
+	TransferAmount int64           // sisa tagihan + kode unik; 0 bila sudah lunas
 }
 
 func (s DocsService) GenerateETicket(passengerID int64) ([]byte, string, error) {
@@ -137,7 +141,14 @@ func (s DocsService) loadPassengerDocData(passengerID int64) (passengerDocData,
 	}
 
 	// harga per seat by fare rules
-	out.PricePerSeat = utils.ComputeFare(out.RouteFrom, out.RouteTo, out.PricePerSeat)
+	out.PricePerSeat = FareService{RequestID: s.RequestID}.PricePerSeat(out.RouteFrom, out.RouteTo, out.ServiceType, out.TripDate, out.PricePerSeat)
+
+	if bal, err := (PaymentLedgerService{Ledger: s.Ledger, Bookings: s.bookingRepo(), RequestID: s.RequestID}).Balance(p.BookingID); err == nil {
+		if len(bal.Payments) > 0 {
+			out.Balance = &bal
+		}
+		out.TransferCode, out.TransferAmount = bal.TransferCode, bal.TransferAmount
+	}
 
 	return out, nil
 }
@@ -252,6 +263,41 @@ func buildInvoicePDF(d passengerDocData) ([]byte, string, error) {
 	pdf.Cell(0, 8, "Total: "+formatRupiah(price))
 	pdf.Ln(12)
 
+	if b := d.Balance; b != nil {
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.Cell(0, 7, fmt.Sprintf("Pembayaran Booking #%d:", d.BookingID))
+		pdf.Ln(8)
+
+		pdf.SetFont("Helvetica", "", 11)
+		for _, p := range b.Payments {
+			pdf.Cell(0, 6, fmt.Sprintf("%s  %-8s %-14s %s",
+				p.CreatedAt.Format("2006-01-02 15:04"), safe(p.Method, "-"), formatRupiah(p.Amount), paymentEntryLabel(p.Status)))
+			pdf.Ln(6)
+		}
+		pdf.Ln(2)
+		pdf.Cell(0, 6, "Total booking : "+formatRupiah(b.Total))
+		pdf.Ln(6)
+		pdf.Cell(0, 6, "Sudah dibayar : "+formatRupiah(b.Paid))
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.Cell(0, 7, "Sisa tagihan  : "+formatRupiah(b.Outstanding))
+		pdf.Ln(10)
+	}
+
+	if d.TransferCode > 0 {
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.Cell(0, 6, fmt.Sprintf("Kode unik transfer: %03d", d.TransferCode))
+		pdf.Ln(6)
+		if d.TransferAmount > 0 {
+			pdf.SetFont("Helvetica", "B", 11)
+			pdf.Cell(0, 7, "Transfer tepat sebesar: "+formatRupiah(d.TransferAmount))
+			pdf.Ln(7)
+			pdf.SetFont("Helvetica", "I", 10)
+			pdf.MultiCell(0, 5, "Kode unik memudahkan pencocokan transfer dan tidak termasuk tarif.", "", "", false)
+		}
+		pdf.Ln(4)
+	}
+
 	pdf.SetFont("Helvetica", "I", 10)
 	pdf.MultiCell(0, 6, "Invoice ini berlaku untuk 1 penumpang (1 seat).", "", "", false)
 
@@ -264,6 +310,20 @@ func buildInvoicePDF(d passengerDocData) ([]byte, string, error) {
 	return buf.Bytes(), filename, nil
 }
 
+func paymentEntryLabel(status string) string {
+	switch status {
+	case repositories.LedgerApproved:
+		return "Diterima"
+	case repositories.LedgerPending:
+		return "Menunggu Validasi"
+	case repositories.LedgerRejected:
+		return "Ditolak"
+	case repositories.LedgerCancelled:
+		return "Dibatalkan"
+	}
+	return status
+}
+
 func safe(v, fallback string) string {
 	v = strings.TrimSpace(v)
 	if v == "" {
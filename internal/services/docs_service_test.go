@@ -3,6 +3,8 @@ package services
 import (
 	"testing"
 	"time"
+
+	"backend/internal/repositories"
 )
 
 func TestDocsServiceGenerate(t *testing.T) {
@@ -21,6 +23,12 @@ func TestDocsServiceGenerate(t *testing.T) {
 			VehicleCode:    "B123",
 			DriverName:     "Driver",
 			PricePerSeat:   100000,
+			Balance: &BookingBalance{
+				Total: 200000, Paid: 50000, Outstanding: 150000,
+				Payments: []repositories.LedgerPayment{{Amount: 50000, Method: "transfer", Status: repositories.LedgerApproved, CreatedAt: time.Now()}},
+			},
+			TransferCode:   123,
+			TransferAmount: 150123,
 		}, nil
 	}
 
@@ -0,0 +1,403 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// FareService adalah satu-satunya sumber katalog halte dan tarif reguler.
+// Dipakai quote, pembuatan booking, sinkron penumpang dan PDF.
+type FareService struct {
+	Stops     repositories.StopRepository
+	Fares     repositories.FareRepository
+	RequestID string
+}
+
+// FareQuote adalah hasil resolusi rute + tarif per seat.
+type FareQuote struct {
+	From         repositories.Stop
+	To           repositories.Stop
+	PricePerSeat int64
+	FareID       int64
+}
+
+// NormalizeStopKey menyamakan input bebas ("Pasir Pengaraian", "pasir-pengaraian") ke bentuk key.
+func NormalizeStopKey(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.ReplaceAll(s, " ", "")
+	s = strings.ReplaceAll(s, "-", "")
+	s = strings.ReplaceAll(s, ".", "")
+	return s
+}
+
+// ListStops mengembalikan katalog halte urut sort_order.
+func (s FareService) ListStops(activeOnly bool) ([]repositories.Stop, error) {
+	stops, err := s.Stops.List(activeOnly)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat stops", Err: err}
+	}
+	return stops, nil
+}
+
+// ResolveStop mencari halte aktif berdasarkan key, nama tampilan atau alias.
+func (s FareService) ResolveStop(input string) (repositories.Stop, error) {
+	stops, err := s.ListStops(true)
+	if err != nil {
+		return repositories.Stop{}, err
+	}
+	stop, ok := findStop(stops, input)
+	if !ok {
+		return repositories.Stop{}, domain.ValidationError{Field: "stop", Msg: "Stop tidak didukung"}
+	}
+	return stop, nil
+}
+
+// Quote me-resolve from/to lalu memilih tarif paling spesifik yang berlaku pada tanggal trip.
+// date kosong/tidak valid dianggap hari ini.
+func (s FareService) Quote(from, to, category, date string) (FareQuote, error) {
+	stops, err := s.ListStops(true)
+	if err != nil {
+		return FareQuote{}, err
+	}
+	fromStop, ok := findStop(stops, from)
+	if !ok {
+		return FareQuote{}, domain.ValidationError{Field: "from", Msg: "Origin tidak didukung"}
+	}
+	toStop, ok := findStop(stops, to)
+	if !ok {
+		return FareQuote{}, domain.ValidationError{Field: "to", Msg: "Destination tidak didukung"}
+	}
+	if fromStop.Key == toStop.Key {
+		return FareQuote{}, domain.ValidationError{Field: "to", Msg: "Origin dan Destination tidak boleh sama"}
+	}
+
+	fares, err := s.Fares.List(true)
+	if err != nil {
+		return FareQuote{}, domain.InternalError{Msg: "gagal memuat fares", Err: err}
+	}
+	fare, ok := matchFare(fares, fromStop, toStop, category, tripDay(date))
+	if !ok {
+		return FareQuote{}, domain.NotFoundError{Resource: "tarif " + fromStop.DisplayName + " - " + toStop.DisplayName}
+	}
+	return FareQuote{From: fromStop, To: toStop, PricePerSeat: fare.Price, FareID: fare.ID}, nil
+}
+
+// PricePerSeat dipakai untuk data turunan (penumpang, PDF): bila rute/tarif tidak
+// ditemukan, fallback (biasanya price_per_seat booking) dikembalikan apa adanya.
+func (s FareService) PricePerSeat(from, to, category, date string, fallback int64) int64 {
+	q, err := s.Quote(from, to, category, date)
+	if err != nil {
+		if !domain.IsValidation(err) && !domain.IsNotFound(err) {
+			utils.LogEvent(s.RequestID, "fare", "price_per_seat", "fallback: "+err.Error())
+		}
+		return fallback
+	}
+	return q.PricePerSeat
+}
+
+// ===== admin CRUD =====
+
+func (s FareService) GetStop(id int64) (repositories.Stop, error) {
+	st, err := s.Stops.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return st, domain.NotFoundError{Resource: "stop"}
+	}
+	if err != nil {
+		return st, domain.InternalError{Msg: "gagal memuat stop", Err: err}
+	}
+	return st, nil
+}
+
+func (s FareService) CreateStop(in repositories.Stop) (repositories.Stop, error) {
+	in, err := cleanStop(in)
+	if err != nil {
+		return in, err
+	}
+	id, err := s.Stops.Create(in)
+	if err != nil {
+		return in, stopWriteError(err)
+	}
+	in.ID = id
+	utils.LogEvent(s.RequestID, "fare", "create_stop", fmt.Sprintf("id=%d key=%s", id, in.Key))
+	return in, nil
+}
+
+func (s FareService) UpdateStop(id int64, in repositories.Stop) (repositories.Stop, error) {
+	current, err := s.GetStop(id)
+	if err != nil {
+		return in, err
+	}
+	in, err = cleanStop(in)
+	if err != nil {
+		return in, err
+	}
+	if in.Key != current.Key {
+		if n, err := s.Fares.CountByStopKey(current.Key); err != nil {
+			return in, domain.InternalError{Msg: "gagal cek fares", Err: err}
+		} else if n > 0 {
+			return in, domain.ConflictError{Resource: "stop", Msg: "key stop masih dipakai di fares"}
+		}
+	}
+	in.ID = id
+	if err := s.Stops.Update(in); err != nil {
+		return in, stopWriteError(err)
+	}
+	utils.LogEvent(s.RequestID, "fare", "update_stop", fmt.Sprintf("id=%d key=%s", id, in.Key))
+	return in, nil
+}
+
+func (s FareService) DeleteStop(id int64) error {
+	current, err := s.GetStop(id)
+	if err != nil {
+		return err
+	}
+	n, err := s.Fares.CountByStopKey(current.Key)
+	if err != nil {
+		return domain.InternalError{Msg: "gagal cek fares", Err: err}
+	}
+	if n > 0 {
+		return domain.ConflictError{Resource: "stop", Msg: "stop masih dipakai di fares, nonaktifkan saja"}
+	}
+	if err := s.Stops.Delete(id); err != nil {
+		return domain.InternalError{Msg: "gagal hapus stop", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "fare", "delete_stop", fmt.Sprintf("id=%d key=%s", id, current.Key))
+	return nil
+}
+
+func (s FareService) ListFares() ([]repositories.Fare, error) {
+	fares, err := s.Fares.List(false)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat fares", Err: err}
+	}
+	return fares, nil
+}
+
+func (s FareService) CreateFare(in repositories.Fare) (repositories.Fare, error) {
+	in, err := s.cleanFare(in)
+	if err != nil {
+		return in, err
+	}
+	id, err := s.Fares.Create(in)
+	if err != nil {
+		return in, domain.InternalError{Msg: "gagal simpan fare", Err: err}
+	}
+	in.ID = id
+	utils.LogEvent(s.RequestID, "fare", "create_fare", fmt.Sprintf("id=%d price=%d", id, in.Price))
+	return in, nil
+}
+
+func (s FareService) UpdateFare(id int64, in repositories.Fare) (repositories.Fare, error) {
+	if _, err := s.Fares.GetByID(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return in, domain.NotFoundError{Resource: "fare"}
+		}
+		return in, domain.InternalError{Msg: "gagal memuat fare", Err: err}
+	}
+	in, err := s.cleanFare(in)
+	if err != nil {
+		return in, err
+	}
+	in.ID = id
+	if err := s.Fares.Update(in); err != nil {
+		return in, domain.InternalError{Msg: "gagal update fare", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "fare", "update_fare", fmt.Sprintf("id=%d price=%d", id, in.Price))
+	return in, nil
+}
+
+func (s FareService) DeleteFare(id int64) error {
+	err := s.Fares.Delete(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NotFoundError{Resource: "fare"}
+	}
+	if err != nil {
+		return domain.InternalError{Msg: "gagal hapus fare", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "fare", "delete_fare", fmt.Sprintf("id=%d", id))
+	return nil
+}
+
+// ===== helpers =====
+
+func cleanStop(in repositories.Stop) (repositories.Stop, error) {
+	in.Key = NormalizeStopKey(in.Key)
+	in.DisplayName = strings.TrimSpace(in.DisplayName)
+	in.Cluster = NormalizeStopKey(in.Cluster)
+	if in.Key == "" {
+		in.Key = NormalizeStopKey(in.DisplayName)
+	}
+	if in.Key == "" {
+		return in, domain.ValidationError{Field: "key", Msg: "wajib diisi"}
+	}
+	if in.DisplayName == "" {
+		return in, domain.ValidationError{Field: "displayName", Msg: "wajib diisi"}
+	}
+	aliases := make([]string, 0, len(in.Aliases))
+	for _, a := range in.Aliases {
+		if k := NormalizeStopKey(a); k != "" && k != in.Key {
+			if strings.Contains(k, ",") {
+				return in, domain.ValidationError{Field: "aliases", Msg: "alias tidak boleh mengandung koma"}
+			}
+			aliases = append(aliases, k)
+		}
+	}
+	in.Aliases = aliases
+	return in, nil
+}
+
+func stopWriteError(err error) error {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == 1062 {
+		return domain.ConflictError{Resource: "stop", Msg: "key stop sudah dipakai"}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NotFoundError{Resource: "stop"}
+	}
+	return domain.InternalError{Msg: "gagal simpan stop", Err: err}
+}
+
+func (s FareService) cleanFare(in repositories.Fare) (repositories.Fare, error) {
+	in.OriginKey = NormalizeStopKey(in.OriginKey)
+	in.OriginCluster = NormalizeStopKey(in.OriginCluster)
+	in.DestinationKey = NormalizeStopKey(in.DestinationKey)
+	in.DestinationCluster = NormalizeStopKey(in.DestinationCluster)
+	in.Category = strings.TrimSpace(in.Category)
+	in.ValidFrom = strings.TrimSpace(in.ValidFrom)
+	in.ValidTo = strings.TrimSpace(in.ValidTo)
+
+	if (in.OriginKey == "") == (in.OriginCluster == "") {
+		return in, domain.ValidationError{Field: "origin", Msg: "isi salah satu: originKey atau originCluster"}
+	}
+	if (in.DestinationKey == "") == (in.DestinationCluster == "") {
+		return in, domain.ValidationError{Field: "destination", Msg: "isi salah satu: destinationKey atau destinationCluster"}
+	}
+	if in.OriginKey != "" && in.OriginKey == in.DestinationKey {
+		return in, domain.ValidationError{Field: "destination", Msg: "origin dan destination tidak boleh sama"}
+	}
+	if in.Price <= 0 {
+		return in, domain.ValidationError{Field: "price", Msg: "harus > 0"}
+	}
+	for field, v := range map[string]string{"validFrom": in.ValidFrom, "validTo": in.ValidTo} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return in, domain.ValidationError{Field: field, Msg: "format YYYY-MM-DD"}
+		}
+	}
+	if in.ValidFrom != "" && in.ValidTo != "" && in.ValidTo < in.ValidFrom {
+		return in, domain.ValidationError{Field: "validTo", Msg: "tidak boleh sebelum validFrom"}
+	}
+
+	stops, err := s.ListStops(false)
+	if err != nil {
+		return in, err
+	}
+	keys, clusters := map[string]bool{}, map[string]bool{}
+	for _, st := range stops {
+		keys[st.Key] = true
+		if st.Cluster != "" {
+			clusters[st.Cluster] = true
+		}
+	}
+	for field, k := range map[string]string{"originKey": in.OriginKey, "destinationKey": in.DestinationKey} {
+		if k != "" && !keys[k] {
+			return in, domain.ValidationError{Field: field, Msg: "stop " + k + " tidak ada"}
+		}
+	}
+	for field, k := range map[string]string{"originCluster": in.OriginCluster, "destinationCluster": in.DestinationCluster} {
+		if k != "" && !clusters[k] {
+			return in, domain.ValidationError{Field: field, Msg: "cluster " + k + " tidak ada"}
+		}
+	}
+	return in, nil
+}
+
+func findStop(stops []repositories.Stop, input string) (repositories.Stop, bool) {
+	k := NormalizeStopKey(input)
+	if k == "" {
+		return repositories.Stop{}, false
+	}
+	for _, st := range stops {
+		if st.Key == k || NormalizeStopKey(st.DisplayName) == k {
+			return st, true
+		}
+		for _, a := range st.Aliases {
+			if NormalizeStopKey(a) == k {
+				return st, true
+			}
+		}
+	}
+	return repositories.Stop{}, false
+}
+
+func tripDay(date string) time.Time {
+	if d, err := time.Parse("2006-01-02", strings.TrimSpace(date)); err == nil {
+		return d
+	}
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// matchFare memilih tarif paling spesifik: halte-halte > halte-cluster > cluster-cluster,
+// tarif berkategori > umum, lalu valid_from terbaru. Tarif bidirectional juga cocok terbalik.
+func matchFare(fares []repositories.Fare, from, to repositories.Stop, category string, day time.Time) (repositories.Fare, bool) {
+	category = strings.ToLower(strings.TrimSpace(category))
+	dayStr := day.Format("2006-01-02")
+
+	var (
+		best      repositories.Fare
+		bestScore = -1
+	)
+	for _, f := range fares {
+		if !f.IsActive {
+			continue
+		}
+		if f.Category != "" && strings.ToLower(f.Category) != category {
+			continue
+		}
+		if (f.ValidFrom != "" && dayStr < f.ValidFrom) || (f.ValidTo != "" && dayStr > f.ValidTo) {
+			continue
+		}
+		score := fareSideScore(f.OriginKey, f.OriginCluster, from) * fareSideScore(f.DestinationKey, f.DestinationCluster, to)
+		if f.Bidirectional {
+			rev := fareSideScore(f.OriginKey, f.OriginCluster, to) * fareSideScore(f.DestinationKey, f.DestinationCluster, from)
+			if rev > score {
+				score = rev
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		score *= 2
+		if f.Category != "" {
+			score++
+		}
+		if score > bestScore || (score == bestScore && f.ValidFrom > best.ValidFrom) {
+			best, bestScore = f, score
+		}
+	}
+	return best, bestScore > 0
+}
+
+// fareSideScore: 2 bila cocok per halte, 1 bila cocok per cluster, 0 bila tidak cocok.
+func fareSideScore(key, cluster string, st repositories.Stop) int {
+	switch {
+	case key != "" && key == st.Key:
+		return 2
+	case cluster != "" && cluster == st.Cluster:
+		return 1
+	default:
+		return 0
+	}
+}
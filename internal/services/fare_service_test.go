@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/repositories"
+)
+
+func fareFixtures() ([]repositories.Stop, []repositories.Fare) {
+	stops := []repositories.Stop{
+		{ID: 1, Key: "skpd", DisplayName: "SKPD", Cluster: "rohul", IsActive: true},
+		{ID: 2, Key: "pasirpengaraian", DisplayName: "Pasir Pengaraian", Aliases: []string{"pasipengaraian"}, Cluster: "rohul", IsActive: true},
+		{ID: 3, Key: "bangkinang", DisplayName: "Bangkinang", IsActive: true},
+		{ID: 4, Key: "pekanbaru", DisplayName: "Pekanbaru", Aliases: []string{"pku"}, IsActive: true},
+	}
+	fares := []repositories.Fare{
+		{ID: 1, OriginKey: "bangkinang", DestinationKey: "pekanbaru", Price: 100000, Bidirectional: true, IsActive: true},
+		{ID: 2, OriginCluster: "rohul", DestinationKey: "pekanbaru", Price: 150000, Bidirectional: true, IsActive: true},
+		{ID: 3, OriginCluster: "rohul", DestinationKey: "bangkinang", Price: 130000, Bidirectional: true, IsActive: true},
+		// tarif lebaran khusus SKPD -> Pekanbaru (satu arah)
+		{ID: 4, OriginKey: "skpd", DestinationKey: "pekanbaru", Price: 175000, ValidFrom: "2025-03-25", ValidTo: "2025-04-10", IsActive: true},
+		// tarif kategori carter
+		{ID: 5, OriginCluster: "rohul", DestinationKey: "pekanbaru", Category: "Carter", Price: 900000, Bidirectional: true, IsActive: true},
+		{ID: 6, OriginKey: "bangkinang", DestinationKey: "pekanbaru", Price: 1, Bidirectional: true, IsActive: false},
+	}
+	return stops, fares
+}
+
+func TestFindStopMatchesKeyDisplayAndAlias(t *testing.T) {
+	stops, _ := fareFixtures()
+	cases := map[string]string{
+		"pekanbaru":        "pekanbaru",
+		"PKU":              "pekanbaru",
+		"Pasir Pengaraian": "pasirpengaraian",
+		"pasi-pengaraian":  "pasirpengaraian",
+		" s.k.p.d ":        "skpd",
+	}
+	for in, want := range cases {
+		st, ok := findStop(stops, in)
+		if !ok || st.Key != want {
+			t.Fatalf("findStop(%q) = %q,%v want %q", in, st.Key, ok, want)
+		}
+	}
+	if _, ok := findStop(stops, "jakarta"); ok {
+		t.Fatalf("unknown stop should not resolve")
+	}
+}
+
+func TestMatchFarePicksMostSpecificValidFare(t *testing.T) {
+	stops, fares := fareFixtures()
+	get := func(k string) repositories.Stop {
+		st, _ := findStop(stops, k)
+		return st
+	}
+	normal := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	lebaran := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		from, to string
+		category string
+		day      time.Time
+		want     int64
+	}{
+		{"cluster to stop", "skpd", "pekanbaru", "Reguler", normal, 150000},
+		{"bidirectional reverse", "pekanbaru", "pasirpengaraian", "Reguler", normal, 150000},
+		{"stop-stop beats cluster", "pekanbaru", "bangkinang", "", normal, 100000},
+		{"seasonal stop-stop within validity", "skpd", "pekanbaru", "Reguler", lebaran, 175000},
+		{"one-way seasonal fare not used in reverse", "pekanbaru", "skpd", "Reguler", lebaran, 150000},
+		{"category specific fare", "skpd", "pekanbaru", "carter", normal, 900000},
+	}
+	for _, tc := range cases {
+		f, ok := matchFare(fares, get(tc.from), get(tc.to), tc.category, tc.day)
+		if !ok || f.Price != tc.want {
+			t.Fatalf("%s: got %d,%v want %d", tc.name, f.Price, ok, tc.want)
+		}
+	}
+
+	if _, ok := matchFare(fares, get("skpd"), get("pasirpengaraian"), "", normal); ok {
+		t.Fatalf("route without fare should not match")
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+)
+
+// FileBlobTarget adalah kolom teks lama yang menyimpan file sebagai base64/data URL.
+type FileBlobTarget struct {
+	Table  string
+	Column string
+	Kind   string
+}
+
+// FileBlobTargets adalah kolom yang dipindahkan oleh `files migrate-base64`.
+var FileBlobTargets = []FileBlobTarget{
+	{Table: "payment_validations", Column: "proof_file", Kind: FileKindPaymentProof},
+	{Table: "departure_settings", Column: "surat_jalan_file", Kind: FileKindSuratJalan},
+	{Table: "return_settings", Column: "surat_jalan_file", Kind: FileKindSuratJalan},
+	{Table: "trip_information", Column: "e_surat_jalan", Kind: FileKindSuratJalan},
+}
+
+// FileBlobReport merangkum hasil migrasi per kolom.
+type FileBlobReport struct {
+	Table    string
+	Column   string
+	Scanned  int
+	Migrated int
+	Skipped  int
+	Failed   int
+}
+
+// FileBlobMigration mengekstrak base64 lama ke FileStore dan mengganti nilai kolom dengan referensi file.
+type FileBlobMigration struct {
+	Files  FileService
+	DB     *sql.DB
+	Batch  int
+	DryRun bool
+	Logf   func(format string, args ...any)
+}
+
+func (m FileBlobMigration) db() *sql.DB {
+	if m.DB != nil {
+		return m.DB
+	}
+	return intconfig.DB
+}
+
+func (m FileBlobMigration) logf(format string, args ...any) {
+	if m.Logf != nil {
+		m.Logf(format, args...)
+	}
+}
+
+// Run memproses semua FileBlobTargets yang tabel/kolomnya ada.
+func (m FileBlobMigration) Run() ([]FileBlobReport, error) {
+	db := m.db()
+	if db == nil {
+		return nil, fmt.Errorf("db tidak tersedia")
+	}
+	out := []FileBlobReport{}
+	for _, t := range FileBlobTargets {
+		if !intdb.HasColumn(db, t.Table, t.Column) {
+			continue
+		}
+		rep, err := m.migrate(db, t)
+		out = append(out, rep)
+		if err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// inlineFileValue mencari file inline di nilai kolom: data URL/base64 langsung,
+// atau JSON {"src": "data:..."} seperti e_surat_jalan lama.
+func inlineFileValue(v string) ([]byte, bool) {
+	if data, ok := DecodeInlineFile(v); ok {
+		return data, true
+	}
+	var obj map[string]any
+	if json.Unmarshal([]byte(v), &obj) != nil {
+		return nil, false
+	}
+	for _, k := range []string{"src", "eSuratJalan"} {
+		if s, ok := obj[k].(string); ok {
+			if data, ok := DecodeInlineFile(s); ok {
+				return data, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (m FileBlobMigration) migrate(db *sql.DB, t FileBlobTarget) (FileBlobReport, error) {
+	rep := FileBlobReport{Table: t.Table, Column: t.Column}
+	batch := m.Batch
+	if batch <= 0 {
+		batch = 50
+	}
+
+	var lastID int64
+	for {
+		rows, err := db.Query(`
+			SELECT id, `+t.Column+` FROM `+t.Table+`
+			WHERE id > ? AND `+t.Column+` IS NOT NULL AND `+t.Column+` NOT LIKE 'file:%'
+			  AND (`+t.Column+` LIKE 'data:%' OR LENGTH(`+t.Column+`) >= 80)
+			ORDER BY id LIMIT ?`, lastID, batch)
+		if err != nil {
+			return rep, err
+		}
+		type row struct {
+			id  int64
+			val string
+		}
+		list := []row{}
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.val); err != nil {
+				rows.Close()
+				return rep, err
+			}
+			list = append(list, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return rep, err
+		}
+		if len(list) == 0 {
+			return rep, nil
+		}
+
+		for _, r := range list {
+			lastID = r.id
+			rep.Scanned++
+			data, ok := inlineFileValue(strings.TrimSpace(r.val))
+			if !ok {
+				rep.Skipped++
+				continue
+			}
+			if m.DryRun {
+				rep.Migrated++
+				continue
+			}
+			f, err := m.Files.Save(t.Kind, bytes.NewReader(data))
+			if err != nil {
+				rep.Failed++
+				m.logf("%s.%s id=%d: %v", t.Table, t.Column, r.id, err)
+				continue
+			}
+			if _, err := db.Exec(`UPDATE `+t.Table+` SET `+t.Column+`=? WHERE id=?`, f.Ref, r.id); err != nil {
+				_ = m.Files.Store.Delete(f.Key)
+				return rep, err
+			}
+			rep.Migrated++
+		}
+	}
+}
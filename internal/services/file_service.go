@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/domain"
+)
+
+// Default batas upload & masa berlaku URL download.
+const (
+	DefaultFileMaxBytes = 5 << 20
+	DefaultFileURLTTL   = 15 * time.Minute
+)
+
+// Prefix key per jenis file.
+const (
+	FileKindPaymentProof = "payment-proofs"
+	FileKindSuratJalan   = "surat-jalan"
+)
+
+// allowedFileTypes adalah content-type (hasil sniffing) yang boleh diupload beserta ekstensinya.
+var allowedFileTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// StoredFile adalah hasil upload ke FileStore.
+type StoredFile struct {
+	Key         string `json:"key"`
+	Ref         string `json:"ref"` // nilai yang disimpan di kolom DB
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// FileService memvalidasi upload (ukuran & content-type hasil sniffing) lalu menyimpannya ke Store.
+type FileService struct {
+	Store    FileStore
+	MaxBytes int64
+	URLTTL   time.Duration
+	Now      func() time.Time
+}
+
+func (s FileService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s FileService) maxBytes() int64 {
+	if s.MaxBytes > 0 {
+		return s.MaxBytes
+	}
+	return DefaultFileMaxBytes
+}
+
+func (s FileService) urlTTL() time.Duration {
+	if s.URLTTL > 0 {
+		return s.URLTTL
+	}
+	return DefaultFileURLTTL
+}
+
+// Save membaca r (maksimal MaxBytes), mendeteksi content-type dari isinya, dan menyimpan
+// dengan key <kind>/<yyyy>/<mm>/<acak><ext>.
+func (s FileService) Save(kind string, r io.Reader) (StoredFile, error) {
+	if s.Store == nil {
+		return StoredFile{}, domain.InternalError{Msg: "file store belum dikonfigurasi"}
+	}
+	data, err := io.ReadAll(io.LimitReader(r, s.maxBytes()+1))
+	if err != nil {
+		return StoredFile{}, domain.ValidationError{Field: "file", Msg: "gagal membaca file", Err: err}
+	}
+	if len(data) == 0 {
+		return StoredFile{}, domain.ValidationError{Field: "file", Msg: "file kosong"}
+	}
+	if int64(len(data)) > s.maxBytes() {
+		return StoredFile{}, domain.ValidationError{Field: "file", Msg: "ukuran file melebihi batas"}
+	}
+
+	contentType := strings.SplitN(http.DetectContentType(data), ";", 2)[0]
+	ext, ok := allowedFileTypes[contentType]
+	if !ok {
+		return StoredFile{}, domain.ValidationError{Field: "file", Msg: "tipe file tidak didukung (jpg, png, webp, pdf)"}
+	}
+
+	var rnd [12]byte
+	if _, err := rand.Read(rnd[:]); err != nil {
+		return StoredFile{}, domain.InternalError{Msg: "gagal membuat nama file", Err: err}
+	}
+	key := kind + "/" + s.now().Format("2006/01") + "/" + hex.EncodeToString(rnd[:]) + ext
+
+	if err := s.Store.Put(key, contentType, bytes.NewReader(data), int64(len(data))); err != nil {
+		return StoredFile{}, domain.InternalError{Msg: "gagal menyimpan file", Err: err}
+	}
+	return StoredFile{Key: key, Ref: FileRef(key), ContentType: contentType, Size: int64(len(data))}, nil
+}
+
+// DecodeInlineFile mengembalikan isi file dari data URL ("data:image/png;base64,...")
+// atau base64 polos; ok=false bila s bukan file inline.
+func DecodeInlineFile(s string) ([]byte, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, false
+	}
+	if strings.HasPrefix(strings.ToLower(s), "data:") {
+		parts := strings.SplitN(s, ",", 2)
+		if len(parts) != 2 || !strings.Contains(strings.ToLower(parts[0]), ";base64") {
+			return nil, false
+		}
+		s = parts[1]
+	} else if len(s) < 80 || strings.ContainsAny(s, " \n\r\t{}:") {
+		return nil, false
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil && len(b) > 0 {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// StoreInline memindahkan nilai base64/data URL ke FileStore dan mengembalikan referensinya.
+// Nilai lain (sudah referensi, URL, kosong) dikembalikan apa adanya.
+func (s FileService) StoreInline(kind, value string) (string, error) {
+	if s.Store == nil {
+		return value, nil
+	}
+	data, ok := DecodeInlineFile(value)
+	if !ok {
+		return value, nil
+	}
+	f, err := s.Save(kind, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	return f.Ref, nil
+}
+
+// URL mengembalikan signed URL untuk referensi FileStore, atau "" bila value bukan referensi.
+func (s FileService) URL(value string) string {
+	key, ok := FileKeyFromRef(value)
+	if !ok || s.Store == nil {
+		return ""
+	}
+	u, err := s.Store.SignedURL(key, s.urlTTL())
+	if err != nil {
+		return ""
+	}
+	return u
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+)
+
+// FileRefPrefix menandai kolom teks yang berisi key FileStore (bukan base64/data URL).
+const FileRefPrefix = "file:"
+
+// ErrFileNotFound dikembalikan FileStore bila key tidak ada.
+var ErrFileNotFound = errors.New("file tidak ditemukan")
+
+// FileStore menyimpan file upload (bukti bayar, surat jalan) di luar database.
+type FileStore interface {
+	Put(key, contentType string, r io.Reader, size int64) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// SignedURL membuat URL download yang berlaku selama ttl.
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// FileRef membungkus key sebagai nilai kolom, mis. "file:payments/2025/01/ab12.jpg".
+func FileRef(key string) string {
+	return FileRefPrefix + key
+}
+
+// FileKeyFromRef mengembalikan key bila s adalah referensi FileStore.
+func FileKeyFromRef(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, FileRefPrefix) {
+		return "", false
+	}
+	key := strings.TrimPrefix(s, FileRefPrefix)
+	if !validFileKey(key) {
+		return "", false
+	}
+	return key, true
+}
+
+// validFileKey menolak key kosong, absolut, atau yang keluar dari root ("..").
+func validFileKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	if path.Clean(key) != key {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." || part == "." || part == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// NewFileStore membuat FileStore sesuai FILE_STORE (local | s3).
+func NewFileStore(env intconfig.Env) FileStore {
+	if env.FileStore == "s3" {
+		return S3FileStore{
+			Endpoint:  env.S3Endpoint,
+			Region:    env.S3Region,
+			Bucket:    env.S3Bucket,
+			AccessKey: env.S3AccessKey,
+			SecretKey: env.S3SecretKey,
+			PathStyle: env.S3PathStyle,
+		}
+	}
+	return LocalFileStore{Root: env.FileStoreDir, BaseURL: env.PublicBaseURL, Secret: []byte(env.FileURLSecret)}
+}
+
+// ===== local filesystem =====
+
+// LocalFilesPath adalah prefix route download file LocalFileStore.
+const LocalFilesPath = "/api/files/"
+
+// LocalFileStore menyimpan file di Root dan melayaninya lewat GET /api/files/<key>
+// dengan query expires & signature (HMAC-SHA256 dengan Secret).
+type LocalFileStore struct {
+	Root    string
+	BaseURL string // mis. https://api.example.com; kosong = URL relatif
+	Secret  []byte
+	Now     func() time.Time
+}
+
+func (s LocalFileStore) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s LocalFileStore) path(key string) (string, error) {
+	if !validFileKey(key) {
+		return "", fmt.Errorf("key file tidak valid: %q", key)
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(key)), nil
+}
+
+func (s LocalFileStore) Put(key, _ string, r io.Reader, _ int64) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s LocalFileStore) Open(key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	return f, err
+}
+
+func (s LocalFileStore) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s LocalFileStore) signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s LocalFileStore) SignedURL(key string, ttl time.Duration) (string, error) {
+	if !validFileKey(key) {
+		return "", fmt.Errorf("key file tidak valid: %q", key)
+	}
+	expires := s.now().Add(ttl).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", s.signature(key, expires))
+	return strings.TrimRight(s.BaseURL, "/") + LocalFilesPath + key + "?" + q.Encode(), nil
+}
+
+// VerifySignedURL memeriksa query expires & signature dari SignedURL.
+func (s LocalFileStore) VerifySignedURL(key, expires, signature string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || s.now().Unix() > exp || !validFileKey(key) {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.signature(key, exp)))
+}
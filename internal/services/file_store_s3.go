@@ -0,0 +1,249 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3FileStore menyimpan file di bucket S3-compatible (AWS S3, MinIO, R2, ...) memakai
+// Signature V4; download lewat presigned URL sehingga file tidak melewati server.
+type S3FileStore struct {
+	Endpoint   string // mis. https://s3.ap-southeast-1.amazonaws.com atau http://localhost:9000
+	Region     string
+	Bucket     string
+	AccessKey  string
+	SecretKey  string
+	PathStyle  bool // wajib untuk MinIO
+	HTTPClient *http.Client
+	Now        func() time.Time
+}
+
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+func (s S3FileStore) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+func (s S3FileStore) client() *http.Client {
+	if s.HTTPClient != nil {
+		return s.HTTPClient
+	}
+	return &http.Client{Timeout: 60 * time.Second}
+}
+
+func (s S3FileStore) region() string {
+	if s.Region != "" {
+		return s.Region
+	}
+	return "us-east-1"
+}
+
+// objectURL mengembalikan URL objek (virtual-host atau path style).
+func (s S3FileStore) objectURL(key string) (*url.URL, error) {
+	if !validFileKey(key) {
+		return nil, fmt.Errorf("key file tidak valid: %q", key)
+	}
+	u, err := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("S3 endpoint tidak valid: %q", s.Endpoint)
+	}
+	if s.PathStyle {
+		u.Path = "/" + s.Bucket + "/" + key
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return u, nil
+}
+
+// s3URIEncode meng-encode path/query sesuai aturan SigV4 (RFC 3986, '/' opsional).
+func s3URIEncode(s string, keepSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func s3CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, s3URIEncode(k, false)+"="+s3URIEncode(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func (s S3FileStore) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.region() + "/s3/aws4_request"
+}
+
+// signature menghitung signature SigV4 untuk canonical request yang sudah disusun.
+func (s S3FileStore) signature(t time.Time, canonicalRequest string) string {
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + t.Format("20060102T150405Z") + "\n" + s.scope(t) + "\n" + hex.EncodeToString(sum[:])
+
+	k := hmacSHA256([]byte("AWS4"+s.SecretKey), t.Format("20060102"))
+	k = hmacSHA256(k, s.region())
+	k = hmacSHA256(k, "s3")
+	k = hmacSHA256(k, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(k, stringToSign))
+}
+
+// do mengirim request bertanda tangan (header Authorization) ke objek key.
+func (s S3FileStore) do(method, key, contentType string, body io.Reader, size int64) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	t := s.now()
+	req.Header.Set("x-amz-date", t.Format("20060102T150405Z"))
+	req.Header.Set("x-amz-content-sha256", s3UnsignedPayload)
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	headers := map[string]string{
+		"host":                 u.Host,
+		"x-amz-content-sha256": s3UnsignedPayload,
+		"x-amz-date":           t.Format("20060102T150405Z"),
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+		headers["content-type"] = contentType
+		signed = append([]string{"content-type"}, signed...)
+	}
+
+	var canonHeaders strings.Builder
+	for _, h := range signed {
+		canonHeaders.WriteString(h + ":" + strings.TrimSpace(headers[h]) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+	canonical := strings.Join([]string{
+		method,
+		s3URIEncode(u.Path, true),
+		"",
+		canonHeaders.String(),
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, s.scope(t), signedHeaders, s.signature(t, canonical)))
+	return s.client().Do(req)
+}
+
+func s3Error(op string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s: http %d: %s", op, resp.StatusCode, strings.TrimSpace(string(msg)))
+}
+
+func (s S3FileStore) Put(key, contentType string, r io.Reader, size int64) error {
+	resp, err := s.do(http.MethodPut, key, contentType, r, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return s3Error("put", resp)
+	}
+	return nil
+}
+
+func (s S3FileStore) Open(key string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, key, "", nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrFileNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, s3Error("get", resp)
+	}
+	return resp.Body, nil
+}
+
+func (s S3FileStore) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, "", nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return s3Error("delete", resp)
+	}
+	return nil
+}
+
+// SignedURL membuat presigned GET URL (SigV4 query string), maksimal 7 hari.
+func (s S3FileStore) SignedURL(key string, ttl time.Duration) (string, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	expires := int64(ttl / time.Second)
+	if expires < 1 {
+		expires = 1
+	}
+	if expires > 7*24*3600 {
+		expires = 7 * 24 * 3600
+	}
+	t := s.now()
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.AccessKey+"/"+s.scope(t))
+	q.Set("X-Amz-Date", t.Format("20060102T150405Z"))
+	q.Set("X-Amz-Expires", strconv.FormatInt(expires, 10))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		s3URIEncode(u.Path, true),
+		s3CanonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+	u.RawQuery = s3CanonicalQuery(q) + "&X-Amz-Signature=" + s.signature(t, canonical)
+	return u.String(), nil
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DefaultIdempotencyTTL adalah lama respons disimpan untuk replay.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// DefaultIdempotencyLease adalah lama key boleh berstatus processing; lewat dari itu request
+// pertama dianggap mati (crash sebelum Complete/Release) dan key boleh dicadangkan ulang.
+const DefaultIdempotencyLease = 5 * time.Minute
+
+// IdempotencyService menyimpan hasil request per Idempotency-Key (dipakai middleware.Idempotency).
+// Sebelum migration idempotency_keys dijalankan, semua request diproses seperti biasa.
+type IdempotencyService struct {
+	Keys  repositories.IdempotencyRepository
+	TTL   time.Duration
+	Lease time.Duration
+	Now   func() time.Time
+}
+
+func (s IdempotencyService) ttl() time.Duration {
+	if s.TTL > 0 {
+		return s.TTL
+	}
+	return DefaultIdempotencyTTL
+}
+
+func (s IdempotencyService) lease() time.Duration {
+	if s.Lease > 0 {
+		return s.Lease
+	}
+	return DefaultIdempotencyLease
+}
+
+func (s IdempotencyService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Reserve mencadangkan key untuk request dengan hash tertentu. nil berarti request baru dan
+// handler boleh dijalankan; respons tersimpan dikembalikan untuk retry dengan body yang sama.
+// Key yang dipakai untuk body lain, atau yang masih diproses, menghasilkan ConflictError.
+func (s IdempotencyService) Reserve(scope, key, hash string) (*domain.IdempotentResponse, error) {
+	if !s.Keys.Available() {
+		return nil, nil
+	}
+	now := s.now()
+	for attempt := 0; attempt < 2; attempt++ {
+		err := s.Keys.Insert(repositories.IdempotencyKey{Scope: scope, Key: key, RequestHash: hash, LockedUntil: now.Add(s.lease()), ExpiresAt: now.Add(s.ttl())})
+		if err == nil {
+			return nil, nil
+		}
+		var me *mysql.MySQLError
+		if !errors.As(err, &me) || me.Number != 1062 {
+			return nil, domain.InternalError{Msg: "gagal menyimpan idempotency key", Err: err}
+		}
+
+		rec, err := s.Keys.Get(scope, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // baru saja dilepas request lain, coba cadangkan lagi
+		}
+		if err != nil {
+			return nil, domain.InternalError{Msg: "gagal membaca idempotency key", Err: err}
+		}
+		if !rec.ExpiresAt.After(now) {
+			if err := s.Keys.Delete(scope, key); err != nil {
+				return nil, domain.InternalError{Msg: "gagal menghapus idempotency key", Err: err}
+			}
+			continue
+		}
+		if rec.Status != repositories.IdempotencyDone && !rec.LockedUntil.IsZero() && !rec.LockedUntil.After(now) {
+			// lease habis: request pertama mati sebelum respons disimpan, key dianggap bebas
+			if _, err := s.Keys.DeleteStale(scope, key, now); err != nil {
+				return nil, domain.InternalError{Msg: "gagal melepas idempotency key", Err: err}
+			}
+			continue
+		}
+		if rec.RequestHash != hash {
+			return nil, domain.ConflictError{Resource: "idempotency_key", Msg: "Idempotency-Key sudah dipakai untuk request yang berbeda"}
+		}
+		if rec.Status != repositories.IdempotencyDone {
+			break
+		}
+		return &domain.IdempotentResponse{Status: rec.ResponseStatus, ContentType: rec.ContentType, Body: rec.ResponseBody}, nil
+	}
+	return nil, domain.ConflictError{Resource: "idempotency_key", Msg: "Request dengan Idempotency-Key ini masih diproses"}
+}
+
+// Complete menyimpan respons handler untuk replay.
+func (s IdempotencyService) Complete(scope, key string, resp domain.IdempotentResponse) error {
+	if !s.Keys.Available() {
+		return nil
+	}
+	return s.Keys.Complete(scope, key, resp.Status, resp.ContentType, resp.Body)
+}
+
+// Release melepas key yang gagal diproses supaya retry berikutnya dijalankan ulang.
+func (s IdempotencyService) Release(scope, key string) error {
+	if !s.Keys.Available() {
+		return nil
+	}
+	return s.Keys.Delete(scope, key)
+}
+
+// RunPurger menghapus key kedaluwarsa berkala sampai ctx dibatalkan.
+func (s IdempotencyService) RunPurger(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.Keys.Available() {
+				continue
+			}
+			n, err := s.Keys.DeleteExpired(s.now())
+			if err != nil {
+				log.Printf("[IDEMPOTENCY] purge error: %v", err)
+				continue
+			}
+			if n > 0 {
+				utils.LogEvent("", "idempotency", "purge", fmt.Sprintf("deleted=%d", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+var idempotencyCols = []string{"id", "scope", "idem_key", "request_hash", "status", "locked_until", "response_status", "content_type", "response_body", "created_at", "expires_at"}
+
+func TestIdempotencyReserveReplaysAndRejectsOtherBody(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	svc := IdempotencyService{Keys: repositories.IdempotencyRepository{DB: db}, Now: func() time.Time { return now }}
+
+	intdb.Schema.Invalidate()
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).AddRow("idempotency_keys", "id"))
+
+	stored := func() *sqlmock.Rows {
+		return sqlmock.NewRows(idempotencyCols).
+			AddRow(int64(1), "user:9", "k1", "hash-a", repositories.IdempotencyDone, nil, 201, "application/json", []byte(`{"bookingId":7}`), now, now.Add(time.Hour))
+	}
+	dup := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+
+	mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnError(dup)
+	mock.ExpectQuery("FROM idempotency_keys WHERE scope = \\?").WithArgs("user:9", "k1").WillReturnRows(stored())
+
+	replay, err := svc.Reserve("user:9", "k1", "hash-a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replay == nil || replay.Status != 201 || string(replay.Body) != `{"bookingId":7}` {
+		t.Fatalf("expected stored response replayed, got %+v", replay)
+	}
+
+	mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnError(dup)
+	mock.ExpectQuery("FROM idempotency_keys WHERE scope = \\?").WithArgs("user:9", "k1").WillReturnRows(stored())
+
+	if _, err := svc.Reserve("user:9", "k1", "hash-b"); !domain.IsConflict(err) {
+		t.Fatalf("expected conflict for different body, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestIdempotencyReserveTakesOverExpiredLease(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	svc := IdempotencyService{Keys: repositories.IdempotencyRepository{DB: db}, Lease: time.Minute, Now: func() time.Time { return now }}
+
+	intdb.Schema.Invalidate()
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).
+			AddRow("idempotency_keys", "id").AddRow("idempotency_keys", "locked_until"))
+
+	dup := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	processing := func(lockedUntil time.Time) *sqlmock.Rows {
+		return sqlmock.NewRows(idempotencyCols).
+			AddRow(int64(1), "user:9", "k1", "hash-a", repositories.IdempotencyProcessing, lockedUntil, 0, "", nil, now.Add(-time.Hour), now.Add(time.Hour))
+	}
+
+	// lease masih berlaku: request pertama dianggap masih diproses
+	mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnError(dup)
+	mock.ExpectQuery("FROM idempotency_keys WHERE scope = \\?").WithArgs("user:9", "k1").WillReturnRows(processing(now.Add(time.Second)))
+	if _, err := svc.Reserve("user:9", "k1", "hash-a"); !domain.IsConflict(err) {
+		t.Fatalf("expected conflict while lease is active, got %v", err)
+	}
+
+	// lease habis (request pertama crash): key dilepas lalu dicadangkan ulang
+	mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnError(dup)
+	mock.ExpectQuery("FROM idempotency_keys WHERE scope = \\?").WithArgs("user:9", "k1").WillReturnRows(processing(now.Add(-time.Second)))
+	mock.ExpectExec("DELETE FROM idempotency_keys").
+		WithArgs("user:9", "k1", repositories.IdempotencyProcessing, now).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO idempotency_keys").
+		WithArgs("user:9", "k1", "hash-a", repositories.IdempotencyProcessing, now.Add(time.Minute), now.Add(DefaultIdempotencyTTL)).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	replay, err := svc.Reserve("user:9", "k1", "hash-a")
+	if err != nil || replay != nil {
+		t.Fatalf("expected key reserved again, got replay=%+v err=%v", replay, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+)
+
+// Default retry outbox: 8 percobaan dengan backoff 30s, 1m, 2m, ... maksimal 1 jam.
+const (
+	DefaultOutboxMaxAttempts = 8
+	DefaultOutboxBackoff     = 30 * time.Second
+	DefaultOutboxMaxBackoff  = time.Hour
+	// outboxLease: event processing yang lock-nya lebih lama dari ini dianggap worker-nya mati.
+	outboxLease = 5 * time.Minute
+)
+
+// OutboxPolicy mengatur retry event outbox.
+type OutboxPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+func (p OutboxPolicy) normalized() OutboxPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultOutboxMaxAttempts
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = DefaultOutboxBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultOutboxMaxBackoff
+	}
+	return p
+}
+
+// Delay adalah jeda sebelum percobaan berikutnya setelah attempts kali gagal (eksponensial, dibatasi MaxBackoff).
+func (p OutboxPolicy) Delay(attempts int) time.Duration {
+	p = p.normalized()
+	d := p.Backoff
+	for i := 1; i < attempts && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// nextAfterFailure menentukan status & waktu tersedia event yang gagal pada percobaan ke-attempts.
+func (p OutboxPolicy) nextAfterFailure(attempts int, now time.Time) (string, time.Time) {
+	if attempts >= p.normalized().MaxAttempts {
+		return repositories.OutboxDead, now
+	}
+	return repositories.OutboxPending, now.Add(p.Delay(attempts))
+}
+
+// OutboxService memproses event outbox (sinkron settings, penumpang, trip_information) dengan
+// retry + backoff; event yang terus gagal menjadi dead dan bisa di-replay admin.
+type OutboxService struct {
+	Repo      repositories.OutboxRepository
+	Policy    OutboxPolicy
+	Batch     int
+	RequestID string
+	Now       func() time.Time
+	// Handle memproses satu event; nil = handler bawaan (dispatch).
+	Handle func(repositories.OutboxEvent) error
+}
+
+func (s OutboxService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// ProcessDue memproses event yang jatuh tempo dan mengembalikan jumlah yang berhasil.
+func (s OutboxService) ProcessDue() (int, error) {
+	if !s.Repo.Available() {
+		return 0, nil
+	}
+	events, err := s.Repo.Claim(s.now(), outboxLease, s.Batch)
+	if err != nil {
+		return 0, domain.InternalError{Msg: "gagal mengambil event outbox", Err: err}
+	}
+	done := 0
+	for _, e := range events {
+		ok, err := s.process(e)
+		if err != nil {
+			log.Printf("[OUTBOX] event_id=%d error: %v", e.ID, err)
+			continue
+		}
+		if ok {
+			done++
+		}
+	}
+	return done, nil
+}
+
+// process menjalankan handler event lalu mencatat hasilnya. ok=false bila handler gagal (dijadwalkan
+// ulang atau dead); err hanya untuk kegagalan menyimpan status.
+func (s OutboxService) process(e repositories.OutboxEvent) (bool, error) {
+	handle := s.Handle
+	if handle == nil {
+		handle = s.dispatch
+	}
+	herr := handle(e)
+	now := s.now()
+	if herr == nil {
+		utils.LogEvent(e.RequestID, "outbox", "done", fmt.Sprintf("id=%d type=%s attempts=%d", e.ID, e.EventType, e.Attempts))
+		return true, s.Repo.MarkDone(e.ID, now)
+	}
+	status, at := s.Policy.nextAfterFailure(e.Attempts, now)
+	utils.LogEvent(e.RequestID, "outbox", "failed", fmt.Sprintf("id=%d type=%s attempts=%d status=%s: %v", e.ID, e.EventType, e.Attempts, status, herr))
+	return false, s.Repo.MarkFailed(e.ID, status, herr.Error(), at)
+}
+
+// dispatch menjalankan sinkronisasi sesuai jenis event. Handler harus aman diulang.
+func (s OutboxService) dispatch(e repositories.OutboxEvent) error {
+	switch e.EventType {
+	case repositories.EventBookingPaid:
+		var p repositories.BookingPaidPayload
+		if err := json.Unmarshal(e.Payload, &p); err != nil || p.BookingID <= 0 {
+			return fmt.Errorf("payload %s tidak valid", e.EventType)
+		}
+		svc := PaymentService{
+			PaymentRepo:     repositories.PaymentRepository{},
+			BookingRepo:     repositories.BookingRepository{},
+			BookingSeatRepo: repositories.BookingSeatRepository{},
+			DepartureSvc:    DepartureService{Repo: repositories.DepartureRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}},
+			ReturnSvc:       ReturnService{Repo: repositories.ReturnRepository{}, BookingRepo: repositories.BookingRepository{}, SeatRepo: repositories.BookingSeatRepository{}},
+			PassengerSvc:    PassengerService{PassengerRepo: repositories.PassengerRepository{}, BookingRepo: repositories.BookingRepository{}, BookingSeatRepo: repositories.BookingSeatRepository{}},
+			RequestID:       e.RequestID,
+		}
+		return svc.SyncSettings(p.BookingID, p.TripRole)
+	case repositories.EventPassengerSync, repositories.EventTripInfoUpsert:
+		var p repositories.DepartureSyncPayload
+		if err := json.Unmarshal(e.Payload, &p); err != nil || p.DepartureID <= 0 {
+			return fmt.Errorf("payload %s tidak valid", e.EventType)
+		}
+		dep, err := repositories.DepartureRepository{}.GetByID(p.DepartureID)
+		if err != nil {
+			return err
+		}
+		if e.EventType == repositories.EventTripInfoUpsert {
+			return TripInfoService{Repo: repositories.TripInformationRepository{}, RequestID: e.RequestID}.UpsertFromDeparture(dep)
+		}
+		passengers := PassengerService{
+			PassengerRepo:   repositories.PassengerRepository{},
+			BookingRepo:     repositories.BookingRepository{},
+			BookingSeatRepo: repositories.BookingSeatRepository{},
+			RequestID:       e.RequestID,
+		}
+		return passengers.SyncFromDeparture(dep)
+	case repositories.EventReturnSync:
+		var p repositories.ReturnSyncPayload
+		if err := json.Unmarshal(e.Payload, &p); err != nil || p.ReturnID <= 0 {
+			return fmt.Errorf("payload %s tidak valid", e.EventType)
+		}
+		ret, err := repositories.ReturnRepository{}.GetByID(p.ReturnID)
+		if err != nil {
+			return err
+		}
+		return ReturnService{RequestID: e.RequestID}.syncAfterPulang(ret)
+	}
+	return fmt.Errorf("jenis event %q tidak dikenal", e.EventType)
+}
+
+// RunWorker menjalankan ProcessDue berkala sampai ctx dibatalkan.
+func (s OutboxService) RunWorker(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ProcessDue(); err != nil {
+				log.Printf("[OUTBOX] worker error: %v", err)
+			}
+		}
+	}
+}
+
+func (s OutboxService) ready() error {
+	if !s.Repo.Available() {
+		return domain.InternalError{Msg: "tabel outbox_events belum tersedia, jalankan `migrate up`"}
+	}
+	return nil
+}
+
+// List mengembalikan event outbox terbaru, opsional disaring status (pending/processing/done/dead).
+func (s OutboxService) List(status string) ([]repositories.OutboxEvent, error) {
+	if err := s.ready(); err != nil {
+		return nil, err
+	}
+	switch status {
+	case "", repositories.OutboxPending, repositories.OutboxProcessing, repositories.OutboxDone, repositories.OutboxDead:
+	default:
+		return nil, domain.ValidationError{Field: "status", Msg: "harus pending, processing, done atau dead"}
+	}
+	list, err := s.Repo.List(status, 0)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat event outbox", Err: err}
+	}
+	return list, nil
+}
+
+// Get mengembalikan satu event outbox.
+func (s OutboxService) Get(id int64) (repositories.OutboxEvent, error) {
+	if err := s.ready(); err != nil {
+		return repositories.OutboxEvent{}, err
+	}
+	e, err := s.Repo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return e, domain.NotFoundError{Resource: "event outbox"}
+	}
+	if err != nil {
+		return e, domain.InternalError{Msg: "gagal memuat event outbox", Err: err}
+	}
+	return e, nil
+}
+
+// Replay mengantrekan ulang event dead (atau pending yang menunggu backoff) untuk segera diproses.
+func (s OutboxService) Replay(id int64) (repositories.OutboxEvent, error) {
+	e, err := s.Get(id)
+	if err != nil {
+		return e, err
+	}
+	if e.Status != repositories.OutboxDead && e.Status != repositories.OutboxPending {
+		return e, domain.ConflictError{Resource: "event outbox", Msg: "Event berstatus " + e.Status + " tidak bisa di-replay"}
+	}
+	if err := s.Repo.Replay(id, s.now()); err != nil {
+		return e, domain.InternalError{Msg: "gagal me-replay event outbox", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "outbox", "replay", fmt.Sprintf("id=%d type=%s", id, e.EventType))
+	return s.Get(id)
+}
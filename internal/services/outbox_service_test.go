@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	intdb "backend/internal/db"
+	"backend/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestOutboxPolicyDelay(t *testing.T) {
+	p := OutboxPolicy{Backoff: 30 * time.Second, MaxBackoff: 5 * time.Minute}
+	for attempts, want := range map[int]time.Duration{
+		1: 30 * time.Second,
+		2: time.Minute,
+		3: 2 * time.Minute,
+		4: 4 * time.Minute,
+		5: 5 * time.Minute,
+		9: 5 * time.Minute,
+	} {
+		if got := p.Delay(attempts); got != want {
+			t.Errorf("Delay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+	if d := (OutboxPolicy{}).Delay(1); d != DefaultOutboxBackoff {
+		t.Fatalf("zero policy should use default backoff, got %v", d)
+	}
+}
+
+func TestOutboxNextAfterFailure(t *testing.T) {
+	now := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	p := OutboxPolicy{MaxAttempts: 3, Backoff: time.Minute}
+	if st, at := p.nextAfterFailure(2, now); st != repositories.OutboxPending || !at.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("expected retry in 2m, got %s %v", st, at)
+	}
+	if st, _ := p.nextAfterFailure(3, now); st != repositories.OutboxDead {
+		t.Fatalf("expected dead after max attempts, got %s", st)
+	}
+}
+
+var outboxCols = []string{"id", "event_type", "aggregate_id", "payload", "status", "attempts", "last_error", "request_id",
+	"available_at", "processed_at", "created_at"}
+
+func TestOutboxProcessDueRetriesAndDeadLetters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	now := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	svc := OutboxService{
+		Repo:   repositories.OutboxRepository{DB: db},
+		Policy: OutboxPolicy{MaxAttempts: 3, Backoff: time.Minute},
+		Now:    func() time.Time { return now },
+		Handle: func(e repositories.OutboxEvent) error {
+			if e.ID == 1 {
+				return nil
+			}
+			return errors.New("passenger_seats belum tersedia")
+		},
+	}
+
+	intdb.Schema.Invalidate()
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).AddRow("outbox_events", "id"))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM outbox_events").
+		WillReturnRows(sqlmock.NewRows(outboxCols).
+			AddRow(int64(1), repositories.EventPassengerSync, int64(7), `{"departureId":3}`, repositories.OutboxPending, 0, "", "req-1", now, nil, now).
+			AddRow(int64(2), repositories.EventTripInfoUpsert, int64(7), `{"departureId":3}`, repositories.OutboxPending, 0, "", "req-1", now, nil, now).
+			AddRow(int64(3), repositories.EventBookingPaid, int64(8), `{"bookingId":8}`, repositories.OutboxPending, 2, "timeout", "req-2", now, nil, now))
+	for _, id := range []int64{1, 2, 3} {
+		mock.ExpectExec("UPDATE outbox_events SET status=\\?, attempts=attempts\\+1").
+			WithArgs(repositories.OutboxProcessing, now, id).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	mock.ExpectExec("UPDATE outbox_events SET status=\\?, last_error=''").
+		WithArgs(repositories.OutboxDone, now, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	// percobaan pertama gagal: dijadwalkan ulang 1 menit lagi
+	mock.ExpectExec("UPDATE outbox_events SET status=\\?, last_error=\\?").
+		WithArgs(repositories.OutboxPending, "passenger_seats belum tersedia", now.Add(time.Minute), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	// percobaan ketiga gagal: dead
+	mock.ExpectExec("UPDATE outbox_events SET status=\\?, last_error=\\?").
+		WithArgs(repositories.OutboxDead, "passenger_seats belum tersedia", now, int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	done, err := svc.ProcessDue()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if done != 1 {
+		t.Fatalf("expected 1 processed event, got %d", done)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
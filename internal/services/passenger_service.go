@@ -205,6 +205,7 @@ func (s PassengerService) syncFromTrip(dep models.DepartureSetting, tripRole str
 	}
 
 	eticket := fmt.Sprintf("ETICKET_INVOICE_FROM_BOOKING:%d", dep.BookingID)
+	fares := FareService{Stops: repositories.StopRepository{DB: s.DB}, Fares: repositories.FareRepository{DB: s.DB}, RequestID: s.RequestID}
 
 	for _, code := range seatCodes {
 		seatMeta := seatByCode[code]
@@ -232,7 +233,7 @@ func (s PassengerService) syncFromTrip(dep models.DepartureSetting, tripRole str
 		}
 
 		// ✅ TotalAmount per seat ikut fare rules berdasar rute yang benar
-		amount := utils.ComputeFare(routeFrom, routeTo, pricePerSeat)
+		amount := fares.PricePerSeat(routeFrom, routeTo, serviceType, date, pricePerSeat)
 
 		payload := repositories.PassengerSeatData{
 			PassengerName:  name,
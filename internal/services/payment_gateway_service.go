@@ -0,0 +1,370 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+)
+
+// DefaultChargeTTL adalah masa berlaku tagihan QRIS/VA bila PAYMENT_CHARGE_TTL tidak diisi.
+const DefaultChargeTTL = time.Hour
+
+// chargeBanks adalah bank VA yang didukung gateway.
+var chargeBanks = map[string]bool{"bca": true, "bni": true, "bri": true, "permata": true, "cimb": true}
+
+// PaymentGatewayService membuat tagihan QRIS/VA di PaymentProvider dan memproses webhook-nya.
+// Webhook paid dicatat ke ledger payments; booking ditandai lunas lewat PaymentService.MarkPaidTx
+// di transaksi yang sama bila sisa tagihan sudah nol.
+type PaymentGatewayService struct {
+	Provider  PaymentProvider
+	Charges   repositories.PaymentChargeRepository
+	Bookings  repositories.BookingRepository
+	Payments  PaymentService
+	Ledger    PaymentLedgerService
+	Refunds   repositories.RefundRepository
+	ChargeTTL time.Duration
+	DB        *sql.DB
+	RequestID string
+	Now       func() time.Time
+}
+
+func (s PaymentGatewayService) db() *sql.DB {
+	if s.DB != nil {
+		return s.DB
+	}
+	return intconfig.DB
+}
+
+func (s PaymentGatewayService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s PaymentGatewayService) ttl() time.Duration {
+	if s.ChargeTTL > 0 {
+		return s.ChargeTTL
+	}
+	return DefaultChargeTTL
+}
+
+// normalizeChargeMethod menerima qris / va / transfer dan bank VA (default bca).
+func normalizeChargeMethod(method, bank string) (string, string, error) {
+	method = strings.ToLower(strings.TrimSpace(method))
+	bank = strings.ToLower(strings.TrimSpace(bank))
+	switch method {
+	case "", ChargeMethodQRIS:
+		return ChargeMethodQRIS, "", nil
+	case ChargeMethodVA, "transfer", "bank_transfer":
+		if bank == "" {
+			bank = "bca"
+		}
+		if !chargeBanks[bank] {
+			return "", "", domain.ValidationError{Field: "bank", Msg: "bank tidak didukung"}
+		}
+		return ChargeMethodVA, bank, nil
+	}
+	return "", "", domain.ValidationError{Field: "method", Msg: "metode harus qris atau va"}
+}
+
+// CreateCharge membuat (atau memakai ulang) tagihan aktif untuk booking yang belum lunas.
+// Nominal tagihan = sisa tagihan booking (total dikurangi DP yang sudah masuk).
+func (s PaymentGatewayService) CreateCharge(bookingID int64, method, bank string) (repositories.PaymentCharge, error) {
+	if s.Provider == nil {
+		return repositories.PaymentCharge{}, domain.ValidationError{Field: "provider", Msg: "payment gateway belum diaktifkan"}
+	}
+	if bookingID <= 0 {
+		return repositories.PaymentCharge{}, domain.ValidationError{Field: "booking_id", Msg: "id tidak valid"}
+	}
+	method, bank, err := normalizeChargeMethod(method, bank)
+	if err != nil {
+		return repositories.PaymentCharge{}, err
+	}
+	if !s.Charges.Available() {
+		return repositories.PaymentCharge{}, domain.InternalError{Msg: "tabel payment_charges belum tersedia, jalankan `migrate up`"}
+	}
+
+	status, err := s.Bookings.CurrentStatus(bookingID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repositories.PaymentCharge{}, domain.NotFoundError{Resource: "booking"}
+		}
+		return repositories.PaymentCharge{}, domain.InternalError{Msg: "gagal memuat status booking", Err: err}
+	}
+	switch status {
+	case domain.BookingDraft, domain.BookingAwaitingPayment, domain.BookingAwaitingValidation, domain.BookingPartiallyPaid, domain.BookingRejected:
+	default:
+		return repositories.PaymentCharge{}, domain.ConflictError{Resource: "booking", Msg: "Booking dengan status " + string(status) + " tidak bisa dibayar"}
+	}
+
+	now := s.now()
+	if active, err := s.Charges.ActiveForBooking(bookingID, now); err == nil {
+		if active.Provider == s.Provider.Name() && active.Method == method && active.Bank == bank {
+			return active, nil
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return repositories.PaymentCharge{}, domain.InternalError{Msg: "gagal memuat tagihan", Err: err}
+	}
+
+	booking, err := s.Bookings.GetByID(bookingID)
+	if err != nil {
+		return repositories.PaymentCharge{}, domain.InternalError{Msg: "gagal memuat booking", Err: err}
+	}
+	if booking.Total <= 0 {
+		return repositories.PaymentCharge{}, domain.ValidationError{Field: "total", Msg: "total booking belum diisi"}
+	}
+	amount := booking.Total
+	if s.Ledger.Available() {
+		bal, err := s.Ledger.Balance(bookingID)
+		if err != nil {
+			return repositories.PaymentCharge{}, err
+		}
+		if bal.Settled() {
+			return repositories.PaymentCharge{}, domain.ConflictError{Resource: "booking", Msg: "Booking sudah lunas"}
+		}
+		amount = bal.Outstanding
+	}
+
+	expires := now.Add(s.ttl())
+	deadline, err := s.Bookings.PaymentDeadline(bookingID)
+	if err != nil {
+		return repositories.PaymentCharge{}, domain.InternalError{Msg: "gagal memuat batas pembayaran", Err: err}
+	}
+	if deadline != nil {
+		if !deadline.After(now) {
+			return repositories.PaymentCharge{}, domain.ConflictError{Resource: "booking", Msg: "Batas pembayaran booking sudah lewat"}
+		}
+		if deadline.Before(expires) {
+			expires = *deadline
+		}
+	}
+
+	ref := fmt.Sprintf("BK%d-%d", bookingID, now.UnixMilli())
+	res, err := s.Provider.CreateCharge(ChargeRequest{Reference: ref, Amount: amount, Method: method, Bank: bank, ExpiresAt: expires})
+	if err != nil {
+		var ve domain.ValidationError
+		if errors.As(err, &ve) {
+			return repositories.PaymentCharge{}, err
+		}
+		return repositories.PaymentCharge{}, domain.InternalError{Msg: "gagal membuat tagihan di payment gateway", Err: err}
+	}
+	if !res.ExpiresAt.IsZero() {
+		expires = res.ExpiresAt
+	}
+
+	ch := repositories.PaymentCharge{
+		BookingID:   bookingID,
+		Provider:    s.Provider.Name(),
+		Reference:   ref,
+		ProviderRef: res.ProviderRef,
+		Method:      method,
+		Bank:        bank,
+		Amount:      amount,
+		QRString:    res.QRString,
+		VANumber:    res.VANumber,
+		Status:      repositories.ChargePending,
+		ExpiresAt:   &expires,
+		CreatedAt:   now,
+	}
+	id, err := s.Charges.Insert(ch)
+	if err != nil {
+		return repositories.PaymentCharge{}, domain.InternalError{Msg: "gagal menyimpan tagihan", Err: err}
+	}
+	ch.ID = id
+	utils.LogEvent(s.RequestID, "payment_gateway", "charge", "booking_id="+strconv.FormatInt(bookingID, 10)+" ref="+ref+" method="+method)
+	return ch, nil
+}
+
+// HandleWebhook memverifikasi notifikasi provider lalu menerapkannya ke tagihan & booking dalam
+// satu transaksi: tagihan dikunci, pembayaran dicatat, booking ditandai lunas dan status tagihan
+// diperbarui bersama, sehingga webhook yang dikirim ulang/bersamaan tidak tercatat dua kali.
+// Error internal dikembalikan supaya provider mengirim ulang webhook.
+func (s PaymentGatewayService) HandleWebhook(header http.Header, body []byte) (repositories.PaymentCharge, error) {
+	if s.Provider == nil {
+		return repositories.PaymentCharge{}, domain.ValidationError{Field: "provider", Msg: "payment gateway belum diaktifkan"}
+	}
+	n, err := s.Provider.VerifyWebhook(header, body)
+	if err != nil {
+		return repositories.PaymentCharge{}, err
+	}
+	if !s.Charges.Available() {
+		return repositories.PaymentCharge{}, domain.InternalError{Msg: "tabel payment_charges belum tersedia"}
+	}
+	db := s.db()
+	if db == nil {
+		return repositories.PaymentCharge{}, domain.InternalError{Msg: "db tidak tersedia"}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return repositories.PaymentCharge{}, domain.InternalError{Msg: "gagal mulai transaksi", Err: err}
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	ch, err := s.Charges.GetByReferenceForUpdateTx(tx, n.Reference)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repositories.PaymentCharge{}, domain.NotFoundError{Resource: "tagihan"}
+		}
+		return repositories.PaymentCharge{}, domain.InternalError{Msg: "gagal memuat tagihan", Err: err}
+	}
+	if ch.Status == repositories.ChargePaid {
+		return ch, nil
+	}
+
+	syncAfter := false
+	switch n.Status {
+	case repositories.ChargePaid:
+		if n.Amount < ch.Amount {
+			return ch, domain.ValidationError{Field: "amount", Msg: "nominal pembayaran kurang dari tagihan"}
+		}
+		if syncAfter, err = s.markBookingPaidTx(tx, ch); err != nil {
+			return ch, err
+		}
+		paidAt := n.PaidAt
+		if paidAt.IsZero() {
+			paidAt = s.now()
+		}
+		if err := s.Charges.UpdateStatusTx(tx, ch.ID, repositories.ChargePaid, &paidAt); err != nil {
+			return ch, domain.InternalError{Msg: "gagal memperbarui tagihan", Err: err}
+		}
+		ch.Status, ch.PaidAt = repositories.ChargePaid, &paidAt
+	case repositories.ChargeExpired, repositories.ChargeFailed:
+		if ch.Status != repositories.ChargePending {
+			return ch, nil
+		}
+		if err := s.Charges.UpdateStatusTx(tx, ch.ID, n.Status, nil); err != nil {
+			return ch, domain.InternalError{Msg: "gagal memperbarui tagihan", Err: err}
+		}
+		ch.Status = n.Status
+	}
+	if err := tx.Commit(); err != nil {
+		return ch, domain.InternalError{Msg: "gagal commit pembayaran", Err: err}
+	}
+	committed = true
+	utils.LogEvent(s.RequestID, "payment_gateway", "webhook", "ref="+ch.Reference+" status="+n.Status)
+	if syncAfter {
+		// tanpa tabel outbox (migration belum dijalankan) sinkron langsung seperti sebelumnya; tagihan
+		// sudah paid sehingga retry webhook tidak mengulang sinkron, kegagalan diperbaiki lewat cek konsistensi
+		svc := s.Payments
+		svc.RequestID = s.RequestID
+		if err := svc.SyncSettings(ch.BookingID, ""); err != nil {
+			utils.LogEvent(s.RequestID, "payment_gateway", "sync_failed", "booking_id="+strconv.FormatInt(ch.BookingID, 10)+": "+err.Error())
+		}
+	}
+	return ch, nil
+}
+
+// markBookingPaidTx mencatat pembayaran ke ledger dan menandai booking lunas (atau DP bila masih
+// ada sisa); event sinkronisasi booking_paid ikut tercatat di outbox. Booking yang sudah tidak bisa
+// dibayar (expired/cancelled) hanya dicatat supaya webhook tidak di-retry terus. syncAfter = booking
+// lunas tetapi event outbox tidak tercatat sehingga pemanggil harus sinkron sendiri setelah commit.
+func (s PaymentGatewayService) markBookingPaidTx(tx *sql.Tx, ch repositories.PaymentCharge) (bool, error) {
+	method := "qris"
+	if ch.Method == ChargeMethodVA {
+		method = "transfer"
+	}
+	actor := repositories.StatusActor{Role: "payment_gateway", RequestID: s.RequestID}
+	status, err := s.Bookings.StatusTx(tx, ch.BookingID)
+	if err != nil {
+		return false, domain.InternalError{Msg: "gagal membaca status booking", Err: err}
+	}
+	if status == domain.BookingCancelled || status == domain.BookingExpired {
+		return false, s.refundClosedBookingTx(tx, ch, method, status)
+	}
+	if s.Ledger.Available() {
+		ledger := s.Ledger
+		ledger.RequestID = s.RequestID
+		bal, err := ledger.RecordTx(tx, repositories.LedgerPayment{
+			BookingID: ch.BookingID,
+			Amount:    ch.Amount,
+			Method:    method,
+			Status:    repositories.LedgerApproved,
+			Reference: ch.Reference,
+			Note:      "payment gateway " + ch.Provider,
+		})
+		if err != nil {
+			return false, domain.InternalError{Msg: "gagal mencatat pembayaran", Err: err}
+		}
+		if !bal.Settled() {
+			if _, err := s.Bookings.TransitionTx(tx, ch.BookingID, domain.BookingPartiallyPaid, actor, "payment gateway "+ch.Reference); err != nil {
+				if domain.IsConflict(err) {
+					utils.LogEvent(s.RequestID, "payment_gateway", "webhook", "booking_id="+strconv.FormatInt(ch.BookingID, 10)+" DP tercatat tapi status tidak berubah: "+err.Error())
+					return false, nil
+				}
+				return false, domain.InternalError{Msg: "gagal memperbarui status booking", Err: err}
+			}
+			return false, nil
+		}
+	}
+	raw, _ := json.Marshal(map[string]any{
+		"payment_method": method,
+		"payment_status": "Sukses",
+		"notes":          "payment gateway " + ch.Provider + " ref " + ch.Reference,
+	})
+
+	svc := s.Payments
+	svc.RequestID = s.RequestID
+	if svc.Actor.Role == "" {
+		svc.Actor = actor
+	}
+	queued, err := svc.MarkPaidTx(tx, ch.BookingID, raw)
+	if err != nil {
+		var ce domain.ConflictError
+		if errors.As(err, &ce) {
+			utils.LogEvent(s.RequestID, "payment_gateway", "webhook", "booking_id="+strconv.FormatInt(ch.BookingID, 10)+" dibayar tapi tidak bisa dilunasi: "+ce.Msg)
+			return false, nil
+		}
+		return false, domain.InternalError{Msg: "gagal menandai booking lunas", Err: err}
+	}
+	return !queued, nil
+}
+
+// refundClosedBookingTx mencatat pembayaran gateway yang masuk setelah booking dibatalkan/expired:
+// uangnya tetap masuk ledger (approved) lalu dibuatkan refund pending penuh untuk diproses admin,
+// di transaksi yang sama dengan status tagihan. Status booking tidak berubah.
+func (s PaymentGatewayService) refundClosedBookingTx(tx *sql.Tx, ch repositories.PaymentCharge, method string, status domain.BookingStatus) error {
+	if s.Ledger.Available() {
+		ledger := s.Ledger
+		ledger.RequestID = s.RequestID
+		if _, err := ledger.RecordTx(tx, repositories.LedgerPayment{
+			BookingID: ch.BookingID,
+			Amount:    ch.Amount,
+			Method:    method,
+			Status:    repositories.LedgerApproved,
+			Reference: ch.Reference,
+			Note:      "payment gateway " + ch.Provider + " setelah booking " + string(status),
+		}); err != nil {
+			return domain.InternalError{Msg: "gagal mencatat pembayaran", Err: err}
+		}
+	}
+	rf := repositories.Refund{
+		BookingID:  ch.BookingID,
+		PaidAmount: ch.Amount,
+		Amount:     ch.Amount,
+		Method:     method,
+		Status:     repositories.RefundPending,
+		Reason:     "pembayaran gateway " + ch.Reference + " diterima setelah booking " + string(status),
+		CreatedAt:  s.now(),
+	}
+	if _, err := s.Refunds.InsertTx(tx, rf); err != nil {
+		return domain.InternalError{Msg: "gagal mencatat refund", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "payment_gateway", "late_payment_refund", fmt.Sprintf("booking_id=%d ref=%s amount=%d status=%s", ch.BookingID, ch.Reference, ch.Amount, status))
+	return nil
+}
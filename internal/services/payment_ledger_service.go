@@ -0,0 +1,296 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// BookingBalance adalah ringkasan pembayaran booking yang dihitung dari ledger payments.
+type BookingBalance struct {
+	BookingID   int64                        `json:"bookingId"`
+	Total       int64                        `json:"total"`
+	Paid        int64                        `json:"paid"`        // entri approved
+	Pending     int64                        `json:"pending"`     // bukti menunggu validasi
+	Outstanding int64                        `json:"outstanding"` // total - paid, minimal 0
+	Payments    []repositories.LedgerPayment `json:"payments"`
+
+	// TransferCode adalah kode unik transfer booking; TransferAmount = sisa tagihan + kode unik,
+	// nominal yang harus ditransfer customer (0 bila sudah lunas atau kode tidak lagi dipesan untuk
+	// sisa tagihan ini). Kode unik bukan bagian dari Total.
+	TransferCode   int64 `json:"transferCode"`
+	TransferAmount int64 `json:"transferAmount"`
+}
+
+// NewBookingBalance menjumlahkan ledger. Booking lama tanpa entri ledger yang sudah lunas
+// (legacyPaid) dianggap terbayar penuh.
+func NewBookingBalance(bookingID, total int64, entries []repositories.LedgerPayment, legacyPaid bool) BookingBalance {
+	b := BookingBalance{BookingID: bookingID, Total: total, Payments: entries}
+	if b.Payments == nil {
+		b.Payments = []repositories.LedgerPayment{}
+	}
+	for _, p := range entries {
+		switch p.Status {
+		case repositories.LedgerApproved:
+			b.Paid += p.Amount
+		case repositories.LedgerPending:
+			b.Pending += p.Amount
+		}
+	}
+	if len(entries) == 0 && legacyPaid {
+		b.Paid = total
+	}
+	if b.Outstanding = total - b.Paid; b.Outstanding < 0 {
+		b.Outstanding = 0
+	}
+	return b
+}
+
+// WithTransferCode mengisi kode unik transfer dan nominal transfer yang harus dibayar. reserved
+// adalah nominal yang dipesan di transfer_codes; nominal transfer hanya dikutip bila reservasi itu
+// memang untuk sisa tagihan saat ini, supaya customer tidak mentransfer nominal milik booking lain.
+func (b BookingBalance) WithTransferCode(code, reserved int64) BookingBalance {
+	b.TransferCode, b.TransferAmount = code, 0
+	if b.Outstanding > 0 && code > 0 && reserved == b.Outstanding+code {
+		b.TransferAmount = reserved
+	}
+	return b
+}
+
+// TransferPortion memisahkan kode unik dari nominal transfer: customer yang mentransfer tepat
+// sisa tagihan + kode unik dicatat sebesar sisa tagihannya saja.
+func (b BookingBalance) TransferPortion(amount int64) int64 {
+	if b.TransferCode > 0 && b.TransferAmount > 0 && amount == b.TransferAmount {
+		return b.Outstanding
+	}
+	return amount
+}
+
+// Settled melaporkan apakah sisa tagihan sudah nol.
+func (b BookingBalance) Settled() bool {
+	return b.Outstanding <= 0
+}
+
+// Status adalah status booking yang sesuai dengan pembayaran approved:
+// paid bila lunas, partially_paid bila baru sebagian, kosong bila belum ada yang masuk.
+func (b BookingBalance) Status() domain.BookingStatus {
+	switch {
+	case b.Settled():
+		return domain.BookingPaid
+	case b.Paid > 0:
+		return domain.BookingPartiallyPaid
+	}
+	return ""
+}
+
+// CheckAmount memvalidasi nominal pembayaran baru terhadap sisa tagihan.
+func (b BookingBalance) CheckAmount(amount int64) error {
+	if b.Settled() {
+		return domain.ConflictError{Resource: "booking", Msg: "Booking sudah lunas"}
+	}
+	if amount <= 0 {
+		return domain.ValidationError{Field: "amount", Msg: "nominal pembayaran harus lebih dari 0"}
+	}
+	if amount > b.Outstanding {
+		return domain.ValidationError{Field: "amount", Msg: "nominal melebihi sisa tagihan " + formatRupiah(b.Outstanding)}
+	}
+	return nil
+}
+
+// PaymentLedgerService mencatat pembayaran booking ke ledger payments dan menghitung sisa tagihannya.
+type PaymentLedgerService struct {
+	Ledger    repositories.PaymentLedgerRepository
+	Bookings  repositories.BookingRepository
+	DB        *sql.DB
+	RequestID string
+	Now       func() time.Time
+}
+
+func (s PaymentLedgerService) db() *sql.DB {
+	if s.DB != nil {
+		return s.DB
+	}
+	return intconfig.DB
+}
+
+func (s PaymentLedgerService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Available melaporkan apakah migration payments sudah dijalankan.
+func (s PaymentLedgerService) Available() bool {
+	return s.Ledger.Available()
+}
+
+func (s PaymentLedgerService) balance(q intdb.Queryer, bookingID int64) (BookingBalance, error) {
+	total, err := s.Ledger.BookingTotalTx(q, bookingID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return BookingBalance{}, domain.NotFoundError{Resource: "booking"}
+		}
+		return BookingBalance{}, domain.InternalError{Msg: "gagal membaca total booking", Err: err}
+	}
+	entries, err := s.Ledger.ListTx(q, bookingID)
+	if err != nil {
+		return BookingBalance{}, domain.InternalError{Msg: "gagal membaca riwayat pembayaran", Err: err}
+	}
+	legacyPaid := false
+	if len(entries) == 0 {
+		status, err := s.Bookings.StatusTx(q, bookingID)
+		if err != nil {
+			return BookingBalance{}, domain.InternalError{Msg: "gagal membaca status booking", Err: err}
+		}
+		switch status {
+		case domain.BookingPaid, domain.BookingDeparted, domain.BookingCompleted:
+			legacyPaid = true
+		}
+	}
+	code, err := s.Bookings.TransferCodeTx(q, bookingID)
+	if err != nil {
+		return BookingBalance{}, domain.InternalError{Msg: "gagal membaca kode unik transfer", Err: err}
+	}
+	reserved, _, err := s.Bookings.ReservedTransferCodeTx(q, bookingID)
+	if err != nil {
+		return BookingBalance{}, domain.InternalError{Msg: "gagal membaca kode unik transfer", Err: err}
+	}
+	return NewBookingBalance(bookingID, total, entries, legacyPaid).WithTransferCode(code, reserved), nil
+}
+
+// Balance menghitung pembayaran & sisa tagihan booking.
+func (s PaymentLedgerService) Balance(bookingID int64) (BookingBalance, error) {
+	db := s.db()
+	if db == nil {
+		return BookingBalance{}, domain.InternalError{Msg: "db tidak tersedia"}
+	}
+	return s.balance(db, bookingID)
+}
+
+// BalanceTx sama dengan Balance di dalam transaksi yang sedang berjalan.
+func (s PaymentLedgerService) BalanceTx(tx *sql.Tx, bookingID int64) (BookingBalance, error) {
+	return s.balance(tx, bookingID)
+}
+
+// RebaseTransferCodeTx menghitung saldo lalu memindahkan reservasi kode unik transfer ke sisa
+// tagihan terbaru. Dipanggil setiap kali total atau pembayaran approved booking berubah.
+func (s PaymentLedgerService) RebaseTransferCodeTx(tx *sql.Tx, bookingID int64) (BookingBalance, error) {
+	bal, err := s.balance(tx, bookingID)
+	if err != nil {
+		return bal, err
+	}
+	codes := TransferCodeService{Bookings: s.Bookings, RequestID: s.RequestID}
+	code, err := codes.RebaseTx(tx, bookingID, bal.Outstanding)
+	if err != nil {
+		return bal, err
+	}
+	if code == 0 {
+		return bal.WithTransferCode(bal.TransferCode, 0), nil
+	}
+	return bal.WithTransferCode(code, bal.Outstanding+code), nil
+}
+
+// RecordTx mencatat pembayaran lalu mengembalikan saldo terbaru. Entri dengan reference yang
+// sudah tercatat (mis. webhook yang dikirim ulang) tidak dicatat dua kali; unique key
+// (booking_id, reference) menahan dua transaksi yang mencatat reference yang sama bersamaan.
+func (s PaymentLedgerService) RecordTx(tx *sql.Tx, p repositories.LedgerPayment) (BookingBalance, error) {
+	if p.Amount <= 0 {
+		return BookingBalance{}, domain.ValidationError{Field: "amount", Msg: "nominal pembayaran harus lebih dari 0"}
+	}
+	if p.Status == "" {
+		p.Status = repositories.LedgerPending
+	}
+	if ref := strings.TrimSpace(p.Reference); ref != "" {
+		bal, err := s.balance(tx, p.BookingID)
+		if err != nil {
+			return bal, err
+		}
+		for _, e := range bal.Payments {
+			if e.Reference == ref {
+				return bal, nil
+			}
+		}
+	}
+
+	now := s.now()
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = now
+	}
+	if p.Status == repositories.LedgerApproved && p.ValidatedAt == nil {
+		p.ValidatedAt = &now
+	}
+	if _, err := s.Ledger.InsertTx(tx, p); err != nil {
+		var me *mysql.MySQLError
+		if strings.TrimSpace(p.Reference) != "" && errors.As(err, &me) && me.Number == 1062 {
+			return s.balance(tx, p.BookingID)
+		}
+		return BookingBalance{}, domain.InternalError{Msg: "gagal mencatat pembayaran", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "payment", "ledger", "booking_id="+strconv.FormatInt(p.BookingID, 10)+
+		" amount="+strconv.FormatInt(p.Amount, 10)+" method="+p.Method+" status="+p.Status)
+	if p.Status == repositories.LedgerApproved {
+		return s.RebaseTransferCodeTx(tx, p.BookingID)
+	}
+	return s.balance(tx, p.BookingID)
+}
+
+// Record menjalankan RecordTx dalam transaksi sendiri.
+func (s PaymentLedgerService) Record(p repositories.LedgerPayment) (BookingBalance, error) {
+	db := s.db()
+	if db == nil {
+		return BookingBalance{}, domain.InternalError{Msg: "db tidak tersedia"}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return BookingBalance{}, domain.InternalError{Msg: "gagal mulai transaksi", Err: err}
+	}
+	bal, err := s.RecordTx(tx, p)
+	if err != nil {
+		_ = tx.Rollback()
+		return bal, err
+	}
+	if err := tx.Commit(); err != nil {
+		return bal, domain.InternalError{Msg: "gagal commit pembayaran", Err: err}
+	}
+	return bal, nil
+}
+
+// SettleValidationTx menerapkan hasil validasi bukti bayar ke entri ledger-nya. Bukti lama yang
+// belum punya entri dicatat sebagai pelunasan sisa tagihan saat disetujui.
+func (s PaymentLedgerService) SettleValidationTx(tx *sql.Tx, bookingID, validationID int64, approved bool, method string, actorID int64) (BookingBalance, error) {
+	status := repositories.LedgerRejected
+	if approved {
+		status = repositories.LedgerApproved
+	}
+	n, err := s.Ledger.SetValidationStatusTx(tx, validationID, status, s.now())
+	if err != nil {
+		return BookingBalance{}, domain.InternalError{Msg: "gagal memperbarui pembayaran", Err: err}
+	}
+	if n > 0 && approved {
+		return s.RebaseTransferCodeTx(tx, bookingID)
+	}
+	bal, err := s.balance(tx, bookingID)
+	if err != nil || n > 0 || !approved || bal.Settled() {
+		return bal, err
+	}
+	return s.RecordTx(tx, repositories.LedgerPayment{
+		BookingID:    bookingID,
+		Amount:       bal.Outstanding,
+		Method:       method,
+		Status:       repositories.LedgerApproved,
+		ReceivedBy:   actorID,
+		ValidationID: validationID,
+		Note:         "validasi pembayaran",
+	})
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestBookingBalanceFromLedger(t *testing.T) {
+	entries := []repositories.LedgerPayment{
+		{Amount: 50000, Method: "transfer", Status: repositories.LedgerApproved},
+		{Amount: 30000, Method: "transfer", Status: repositories.LedgerRejected},
+		{Amount: 40000, Method: "transfer", Status: repositories.LedgerPending},
+	}
+	b := NewBookingBalance(1, 150000, entries, false)
+	if b.Paid != 50000 || b.Pending != 40000 || b.Outstanding != 100000 {
+		t.Fatalf("unexpected balance %+v", b)
+	}
+	if b.Settled() || b.Status() != domain.BookingPartiallyPaid {
+		t.Fatalf("expected partially paid, got %s", b.Status())
+	}
+	if err := b.CheckAmount(120000); !domain.IsValidation(err) {
+		t.Fatalf("expected validation error for overpayment, got %v", err)
+	}
+	if err := b.CheckAmount(100000); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	entries = append(entries, repositories.LedgerPayment{Amount: 100000, Method: "cash", Status: repositories.LedgerApproved})
+	b = NewBookingBalance(1, 150000, entries, false)
+	if !b.Settled() || b.Status() != domain.BookingPaid {
+		t.Fatalf("expected paid, got %+v", b)
+	}
+	if err := b.CheckAmount(1000); !domain.IsConflict(err) {
+		t.Fatalf("expected conflict for settled booking, got %v", err)
+	}
+}
+
+func TestBookingBalanceTransferCode(t *testing.T) {
+	b := NewBookingBalance(1, 150000, nil, false).WithTransferCode(123, 150123)
+	if b.TransferAmount != 150123 || b.Total != 150000 {
+		t.Fatalf("transfer code must not change the fare total, got %+v", b)
+	}
+	if got := b.TransferPortion(150123); got != 150000 {
+		t.Fatalf("expected unique code stripped from transfer, got %d", got)
+	}
+	if got := b.TransferPortion(50000); got != 50000 {
+		t.Fatalf("partial transfer must be kept as is, got %d", got)
+	}
+	if b := NewBookingBalance(1, 150000, nil, true).WithTransferCode(123, 150123); b.TransferAmount != 0 {
+		t.Fatalf("settled booking has nothing to transfer, got %+v", b)
+	}
+	// setelah DP reservasi lama (total + kode) bukan lagi nominal sisa tagihan
+	dp := []repositories.LedgerPayment{{Amount: 50000, Status: repositories.LedgerApproved}}
+	if b := NewBookingBalance(1, 150000, dp, false).WithTransferCode(123, 150123); b.TransferAmount != 0 {
+		t.Fatalf("stale reservation must not be quoted, got %+v", b)
+	}
+	if b := NewBookingBalance(1, 150000, dp, false).WithTransferCode(123, 100123); b.TransferAmount != 100123 {
+		t.Fatalf("expected outstanding + code, got %+v", b)
+	}
+}
+
+func TestBookingBalanceLegacyPaid(t *testing.T) {
+	if b := NewBookingBalance(1, 150000, nil, true); !b.Settled() || b.Paid != 150000 {
+		t.Fatalf("legacy paid booking should be settled, got %+v", b)
+	}
+	b := NewBookingBalance(1, 150000, nil, false)
+	if b.Outstanding != 150000 || b.Status() != "" || b.Payments == nil {
+		t.Fatalf("unpaid booking should have full outstanding, got %+v", b)
+	}
+}
+
+func TestLedgerRecordDuplicateReference(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	svc := PaymentLedgerService{Ledger: repositories.PaymentLedgerRepository{DB: db}, Bookings: repositories.BookingRepository{DB: db}, DB: db}
+	ledgerCols := []string{"id", "booking_id", "amount", "method", "status", "received_by", "validation_id", "reference", "note", "validated_at", "created_at"}
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	intdb.Schema.Invalidate()
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).
+			AddRow("payments", "id").
+			AddRow("bookings", "total"))
+	mock.ExpectQuery("FROM bookings").WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(int64(150000)))
+	mock.ExpectQuery("FROM payments WHERE booking_id").WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(ledgerCols))
+	mock.ExpectQuery("FROM bookings WHERE id").WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows([]string{"payment_status", "booking_status"}).AddRow("Belum Lunas", ""))
+	mock.ExpectQuery("SELECT COALESCE\\(transfer_code,0\\) FROM bookings").WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows([]string{"transfer_code"}).AddRow(int64(0)))
+	// webhook lain dengan reference yang sama commit lebih dulu
+	mock.ExpectExec("INSERT INTO payments").WillReturnError(&mysql.MySQLError{Number: 1062})
+	mock.ExpectQuery("FROM bookings").WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(int64(150000)))
+	mock.ExpectQuery("FROM payments WHERE booking_id").WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows(ledgerCols).AddRow(int64(1), int64(7), int64(150000), "qris", repositories.LedgerApproved, int64(0), int64(0), "BK7-1", "", now, now))
+	mock.ExpectQuery("SELECT COALESCE\\(transfer_code,0\\) FROM bookings").WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows([]string{"transfer_code"}).AddRow(int64(0)))
+	mock.ExpectCommit()
+
+	tx, _ := db.Begin()
+	bal, err := svc.RecordTx(tx, repositories.LedgerPayment{BookingID: 7, Amount: 150000, Method: "qris", Status: repositories.LedgerApproved, Reference: "BK7-1"})
+	if err != nil {
+		t.Fatalf("duplicate reference must not fail, got %v", err)
+	}
+	_ = tx.Commit()
+	if bal.Paid != 150000 || len(bal.Payments) != 1 {
+		t.Fatalf("expected the existing entry only, got %+v", bal)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
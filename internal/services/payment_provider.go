@@ -0,0 +1,141 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+)
+
+// Metode pembayaran gateway.
+const (
+	ChargeMethodQRIS = "qris"
+	ChargeMethodVA   = "va"
+)
+
+// ChargeRequest adalah tagihan yang dibuat di provider untuk satu booking.
+type ChargeRequest struct {
+	Reference string // order id unik, dikirim balik oleh webhook
+	Amount    int64
+	Method    string // qris | va
+	Bank      string // wajib untuk va
+	ExpiresAt time.Time
+}
+
+// ChargeResult adalah data pembayaran yang ditampilkan ke customer.
+type ChargeResult struct {
+	ProviderRef string
+	QRString    string
+	VANumber    string
+	ExpiresAt   time.Time
+}
+
+// PaymentNotification adalah isi webhook yang sudah diverifikasi signature-nya.
+// Status memakai konstanta repositories.Charge*.
+type PaymentNotification struct {
+	Reference   string
+	ProviderRef string
+	Status      string
+	Amount      int64
+	PaidAt      time.Time
+}
+
+// PaymentProvider membungkus payment gateway (QRIS / virtual account).
+type PaymentProvider interface {
+	Name() string
+	CreateCharge(req ChargeRequest) (ChargeResult, error)
+	// VerifyWebhook memeriksa signature notifikasi; signature salah = domain.UnauthorizedError.
+	VerifyWebhook(header http.Header, body []byte) (PaymentNotification, error)
+}
+
+// NewPaymentProvider membuat provider sesuai PAYMENT_PROVIDER; nil bila gateway tidak diaktifkan.
+func NewPaymentProvider(env intconfig.Env) PaymentProvider {
+	switch env.PaymentProvider {
+	case "midtrans":
+		return MidtransProvider{ServerKey: env.MidtransServerKey, BaseURL: env.MidtransBaseURL}
+	case "fake":
+		return FakePaymentProvider{Secret: env.FakePaymentSecret}
+	}
+	return nil
+}
+
+// ===== fake provider (offline) =====
+
+// FakePaymentSignatureHeader berisi HMAC-SHA256 hex dari body webhook fake.
+const FakePaymentSignatureHeader = "X-Fake-Signature"
+
+// FakePaymentProvider meniru gateway tanpa jaringan: QR string/VA dibuat lokal dan webhook
+// ditandatangani HMAC dengan Secret, sehingga alur bayar bisa diuji end-to-end secara offline.
+type FakePaymentProvider struct {
+	Secret string
+}
+
+type fakeNotification struct {
+	Reference string `json:"reference"`
+	Status    string `json:"status"`
+	Amount    int64  `json:"amount"`
+	PaidAt    string `json:"paidAt,omitempty"`
+}
+
+func (p FakePaymentProvider) Name() string { return "fake" }
+
+func (p FakePaymentProvider) CreateCharge(req ChargeRequest) (ChargeResult, error) {
+	res := ChargeResult{ProviderRef: "fake-" + req.Reference, ExpiresAt: req.ExpiresAt}
+	switch req.Method {
+	case ChargeMethodQRIS:
+		res.QRString = fmt.Sprintf("FAKEQRIS|%s|%d", req.Reference, req.Amount)
+	case ChargeMethodVA:
+		sum := sha256.Sum256([]byte(req.Reference))
+		digits := ""
+		for _, b := range sum[:6] {
+			digits += fmt.Sprintf("%02d", int(b)%100)
+		}
+		res.VANumber = "8808" + digits
+	default:
+		return res, domain.ValidationError{Field: "method", Msg: "metode tidak didukung"}
+	}
+	return res, nil
+}
+
+func (p FakePaymentProvider) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(p.Secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignedNotification membuat body + signature webhook fake untuk mensimulasikan pembayaran.
+func (p FakePaymentProvider) SignedNotification(reference, status string, amount int64, at time.Time) ([]byte, string, error) {
+	n := fakeNotification{Reference: reference, Status: status, Amount: amount}
+	if status == repositories.ChargePaid {
+		n.PaidAt = at.Format(time.RFC3339)
+	}
+	body, err := json.Marshal(n)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, p.sign(body), nil
+}
+
+func (p FakePaymentProvider) VerifyWebhook(header http.Header, body []byte) (PaymentNotification, error) {
+	sig := strings.TrimSpace(header.Get(FakePaymentSignatureHeader))
+	if sig == "" || !hmac.Equal([]byte(sig), []byte(p.sign(body))) {
+		return PaymentNotification{}, domain.UnauthorizedError{Msg: "signature webhook tidak valid"}
+	}
+	var n fakeNotification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return PaymentNotification{}, domain.ValidationError{Field: "body", Msg: "payload webhook tidak valid", Err: err}
+	}
+	out := PaymentNotification{Reference: n.Reference, ProviderRef: "fake-" + n.Reference, Status: n.Status, Amount: n.Amount}
+	if t, err := time.Parse(time.RFC3339, n.PaidAt); err == nil {
+		out.PaidAt = t
+	}
+	return out, nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+)
+
+// DefaultMidtransBaseURL adalah endpoint Core API sandbox.
+const DefaultMidtransBaseURL = "https://api.sandbox.midtrans.com"
+
+// midtransZone: expiry_time & settlement_time Midtrans dalam WIB.
+var midtransZone = time.FixedZone("WIB", 7*3600)
+
+// MidtransProvider memakai Midtrans Core API (/v2/charge) untuk QRIS dan bank transfer (VA).
+type MidtransProvider struct {
+	ServerKey  string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+func (p MidtransProvider) Name() string { return "midtrans" }
+
+func (p MidtransProvider) client() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return &http.Client{Timeout: 15 * time.Second}
+}
+
+func (p MidtransProvider) baseURL() string {
+	if p.BaseURL != "" {
+		return strings.TrimRight(p.BaseURL, "/")
+	}
+	return DefaultMidtransBaseURL
+}
+
+type midtransChargeResponse struct {
+	StatusCode    string `json:"status_code"`
+	StatusMessage string `json:"status_message"`
+	TransactionID string `json:"transaction_id"`
+	QRString      string `json:"qr_string"`
+	VANumbers     []struct {
+		Bank     string `json:"bank"`
+		VANumber string `json:"va_number"`
+	} `json:"va_numbers"`
+	PermataVANumber string `json:"permata_va_number"`
+	ExpiryTime      string `json:"expiry_time"`
+}
+
+func (p MidtransProvider) CreateCharge(req ChargeRequest) (ChargeResult, error) {
+	body := map[string]any{
+		"transaction_details": map[string]any{"order_id": req.Reference, "gross_amount": req.Amount},
+	}
+	switch req.Method {
+	case ChargeMethodQRIS:
+		body["payment_type"] = "qris"
+	case ChargeMethodVA:
+		body["payment_type"] = "bank_transfer"
+		body["bank_transfer"] = map[string]any{"bank": req.Bank}
+	default:
+		return ChargeResult{}, domain.ValidationError{Field: "method", Msg: "metode tidak didukung"}
+	}
+	if !req.ExpiresAt.IsZero() {
+		minutes := int(math.Ceil(time.Until(req.ExpiresAt).Minutes()))
+		if minutes < 1 {
+			minutes = 1
+		}
+		body["custom_expiry"] = map[string]any{"expiry_duration": minutes, "unit": "minute"}
+	}
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return ChargeResult{}, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, p.baseURL()+"/v2/charge", bytes.NewReader(raw))
+	if err != nil {
+		return ChargeResult{}, err
+	}
+	httpReq.SetBasicAuth(p.ServerKey, "")
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := p.client().Do(httpReq)
+	if err != nil {
+		return ChargeResult{}, fmt.Errorf("midtrans charge: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ChargeResult{}, fmt.Errorf("midtrans charge: %w", err)
+	}
+
+	var out midtransChargeResponse
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return ChargeResult{}, fmt.Errorf("midtrans charge: respons tidak valid (http %d)", resp.StatusCode)
+	}
+	if out.StatusCode != "201" && out.StatusCode != "200" {
+		return ChargeResult{}, fmt.Errorf("midtrans charge ditolak: %s %s", out.StatusCode, out.StatusMessage)
+	}
+
+	res := ChargeResult{ProviderRef: out.TransactionID, QRString: out.QRString, ExpiresAt: req.ExpiresAt}
+	if len(out.VANumbers) > 0 {
+		res.VANumber = out.VANumbers[0].VANumber
+	} else if out.PermataVANumber != "" {
+		res.VANumber = out.PermataVANumber
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", out.ExpiryTime, midtransZone); err == nil {
+		res.ExpiresAt = t
+	}
+	return res, nil
+}
+
+type midtransNotification struct {
+	OrderID           string `json:"order_id"`
+	TransactionID     string `json:"transaction_id"`
+	StatusCode        string `json:"status_code"`
+	GrossAmount       string `json:"gross_amount"`
+	SignatureKey      string `json:"signature_key"`
+	TransactionStatus string `json:"transaction_status"`
+	FraudStatus       string `json:"fraud_status"`
+	SettlementTime    string `json:"settlement_time"`
+}
+
+// midtransSignature = sha512(order_id + status_code + gross_amount + server_key).
+func midtransSignature(orderID, statusCode, grossAmount, serverKey string) string {
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + serverKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// midtransChargeStatus memetakan transaction_status Midtrans ke status tagihan.
+func midtransChargeStatus(transactionStatus, fraudStatus string) string {
+	switch strings.ToLower(transactionStatus) {
+	case "settlement":
+		return repositories.ChargePaid
+	case "capture":
+		if strings.EqualFold(fraudStatus, "challenge") {
+			return repositories.ChargePending
+		}
+		return repositories.ChargePaid
+	case "expire":
+		return repositories.ChargeExpired
+	case "cancel", "deny", "failure":
+		return repositories.ChargeFailed
+	}
+	return repositories.ChargePending
+}
+
+func (p MidtransProvider) VerifyWebhook(_ http.Header, body []byte) (PaymentNotification, error) {
+	var n midtransNotification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return PaymentNotification{}, domain.ValidationError{Field: "body", Msg: "payload webhook tidak valid", Err: err}
+	}
+	want := midtransSignature(n.OrderID, n.StatusCode, n.GrossAmount, p.ServerKey)
+	if n.SignatureKey == "" || subtle.ConstantTimeCompare([]byte(strings.ToLower(n.SignatureKey)), []byte(want)) != 1 {
+		return PaymentNotification{}, domain.UnauthorizedError{Msg: "signature webhook tidak valid"}
+	}
+	amount, err := strconv.ParseFloat(n.GrossAmount, 64)
+	if err != nil {
+		return PaymentNotification{}, domain.ValidationError{Field: "gross_amount", Msg: "nominal tidak valid", Err: err}
+	}
+	out := PaymentNotification{
+		Reference:   n.OrderID,
+		ProviderRef: n.TransactionID,
+		Status:      midtransChargeStatus(n.TransactionStatus, n.FraudStatus),
+		Amount:      int64(math.Round(amount)),
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", n.SettlementTime, midtransZone); err == nil {
+		out.PaidAt = t
+	}
+	return out, nil
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestFakeProviderWebhookRoundTrip(t *testing.T) {
+	p := FakePaymentProvider{Secret: "s3cret"}
+	at := time.Date(2025, 1, 1, 9, 30, 0, 0, time.UTC)
+
+	body, sig, err := p.SignedNotification("BK7-1", repositories.ChargePaid, 150000, at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	header := http.Header{}
+	header.Set(FakePaymentSignatureHeader, sig)
+
+	n, err := p.VerifyWebhook(header, body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n.Reference != "BK7-1" || n.Status != repositories.ChargePaid || n.Amount != 150000 || !n.PaidAt.Equal(at) {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+
+	tampered := []byte(strings.Replace(string(body), "150000", "1", 1))
+	if _, err := p.VerifyWebhook(header, tampered); !domain.IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized for tampered body, got %v", err)
+	}
+	if _, err := (FakePaymentProvider{Secret: "other"}).VerifyWebhook(header, body); !domain.IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized for other secret, got %v", err)
+	}
+}
+
+func TestFakeProviderCreateCharge(t *testing.T) {
+	p := FakePaymentProvider{Secret: "s3cret"}
+	qr, err := p.CreateCharge(ChargeRequest{Reference: "BK7-1", Amount: 150000, Method: ChargeMethodQRIS})
+	if err != nil || qr.QRString == "" || qr.VANumber != "" {
+		t.Fatalf("unexpected qris result: %+v err=%v", qr, err)
+	}
+	va, err := p.CreateCharge(ChargeRequest{Reference: "BK7-1", Amount: 150000, Method: ChargeMethodVA, Bank: "bca"})
+	if err != nil || va.VANumber == "" || va.QRString != "" {
+		t.Fatalf("unexpected va result: %+v err=%v", va, err)
+	}
+	again, _ := p.CreateCharge(ChargeRequest{Reference: "BK7-1", Amount: 150000, Method: ChargeMethodVA, Bank: "bca"})
+	if again.VANumber != va.VANumber {
+		t.Fatalf("expected deterministic va number, got %s vs %s", again.VANumber, va.VANumber)
+	}
+}
+
+func TestMidtransVerifyWebhook(t *testing.T) {
+	p := MidtransProvider{ServerKey: "SB-key"}
+	notif := func(status, sig string) []byte {
+		b, _ := json.Marshal(map[string]string{
+			"order_id":           "BK7-1",
+			"transaction_id":     "trx-1",
+			"status_code":        "200",
+			"gross_amount":       "150000.00",
+			"transaction_status": status,
+			"settlement_time":    "2025-01-01 16:30:00",
+			"signature_key":      sig,
+		})
+		return b
+	}
+	sig := midtransSignature("BK7-1", "200", "150000.00", "SB-key")
+
+	n, err := p.VerifyWebhook(nil, notif("settlement", sig))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Date(2025, 1, 1, 9, 30, 0, 0, time.UTC)
+	if n.Status != repositories.ChargePaid || n.Amount != 150000 || n.ProviderRef != "trx-1" || !n.PaidAt.Equal(want) {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+
+	if _, err := p.VerifyWebhook(nil, notif("settlement", strings.Repeat("0", 128))); !domain.IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized for bad signature, got %v", err)
+	}
+}
+
+func TestMidtransChargeStatus(t *testing.T) {
+	cases := map[string]string{
+		"settlement": repositories.ChargePaid,
+		"capture":    repositories.ChargePaid,
+		"pending":    repositories.ChargePending,
+		"expire":     repositories.ChargeExpired,
+		"cancel":     repositories.ChargeFailed,
+		"deny":       repositories.ChargeFailed,
+	}
+	for in, want := range cases {
+		if got := midtransChargeStatus(in, "accept"); got != want {
+			t.Fatalf("%s: expected %s, got %s", in, want, got)
+		}
+	}
+	if got := midtransChargeStatus("capture", "challenge"); got != repositories.ChargePending {
+		t.Fatalf("challenged capture must stay pending, got %s", got)
+	}
+}
+
+func TestMidtransCreateChargeVA(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/charge" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if user, _, ok := r.BasicAuth(); !ok || user != "SB-key" {
+			t.Errorf("expected basic auth with server key")
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"status_code":"201","transaction_id":"trx-1","va_numbers":[{"bank":"bni","va_number":"9881234"}],"expiry_time":"2025-01-01 17:00:00"}`)
+	}))
+	defer srv.Close()
+
+	p := MidtransProvider{ServerKey: "SB-key", BaseURL: srv.URL}
+	res, err := p.CreateCharge(ChargeRequest{Reference: "BK7-1", Amount: 150000, Method: ChargeMethodVA, Bank: "bni", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.VANumber != "9881234" || res.ProviderRef != "trx-1" || !res.ExpiresAt.Equal(time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if got["payment_type"] != "bank_transfer" {
+		t.Fatalf("expected bank_transfer payload, got %v", got)
+	}
+	if bt, _ := got["bank_transfer"].(map[string]any); bt["bank"] != "bni" {
+		t.Fatalf("expected bank bni, got %v", got["bank_transfer"])
+	}
+}
+
+func TestMidtransCreateChargeRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status_code":"401","status_message":"Access denied"}`)
+	}))
+	defer srv.Close()
+
+	_, err := MidtransProvider{ServerKey: "bad", BaseURL: srv.URL}.CreateCharge(ChargeRequest{Reference: "BK7-1", Amount: 1, Method: ChargeMethodQRIS})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected rejection error, got %v", err)
+	}
+}
+
+func TestGatewayWebhookIgnoresPaidCharge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	provider := FakePaymentProvider{Secret: "s3cret"}
+	svc := PaymentGatewayService{Provider: provider, Charges: repositories.PaymentChargeRepository{DB: db}, DB: db}
+
+	intdb.Schema.Invalidate()
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).AddRow("payment_charges", "id"))
+
+	paidAt := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM payment_charges WHERE reference = \\? LIMIT 1 FOR UPDATE").WithArgs("BK7-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "booking_id", "provider", "reference", "provider_ref", "method", "bank", "amount", "qr_string", "va_number", "status", "expires_at", "paid_at", "created_at"}).
+			AddRow(int64(3), int64(7), "fake", "BK7-1", "fake-BK7-1", ChargeMethodQRIS, "", int64(150000), "FAKEQRIS", "", repositories.ChargePaid, nil, paidAt, paidAt))
+	mock.ExpectRollback()
+
+	body, sig, _ := provider.SignedNotification("BK7-1", repositories.ChargePaid, 150000, paidAt)
+	header := http.Header{}
+	header.Set(FakePaymentSignatureHeader, sig)
+
+	ch, err := svc.HandleWebhook(header, body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ch.Status != repositories.ChargePaid || ch.BookingID != 7 {
+		t.Fatalf("unexpected charge: %+v", ch)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGatewayWebhookRefundsCancelledBooking(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	provider := FakePaymentProvider{Secret: "s3cret"}
+	svc := PaymentGatewayService{Provider: provider, Charges: repositories.PaymentChargeRepository{DB: db}, DB: db}
+
+	intdb.Schema.Invalidate()
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).
+			AddRow("payment_charges", "id").
+			AddRow("bookings", "payment_status").
+			AddRow("bookings", "booking_status").
+			AddRow("refunds", "id"))
+
+	paidAt := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM payment_charges WHERE reference = \\? LIMIT 1 FOR UPDATE").WithArgs("BK7-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "booking_id", "provider", "reference", "provider_ref", "method", "bank", "amount", "qr_string", "va_number", "status", "expires_at", "paid_at", "created_at"}).
+			AddRow(int64(3), int64(7), "fake", "BK7-1", "fake-BK7-1", ChargeMethodQRIS, "", int64(150000), "FAKEQRIS", "", repositories.ChargePending, nil, nil, paidAt))
+	mock.ExpectQuery("SELECT COALESCE\\(payment_status,''\\), COALESCE\\(booking_status,''\\) FROM bookings WHERE id=\\?").WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_status", "booking_status"}).AddRow("Dibatalkan", "cancelled"))
+	// booking tidak dilunasi; uang yang masuk menjadi refund pending penuh
+	mock.ExpectExec("INSERT INTO refunds").
+		WithArgs(int64(7), int64(150000), int64(0), int64(150000), "qris", repositories.RefundPending, sqlmock.AnyArg(), int64(0)).
+		WillReturnResult(sqlmock.NewResult(11, 1))
+	mock.ExpectExec("UPDATE payment_charges SET status = \\?").WithArgs(repositories.ChargePaid, sqlmock.AnyArg(), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	body, sig, _ := provider.SignedNotification("BK7-1", repositories.ChargePaid, 150000, paidAt)
+	header := http.Header{}
+	header.Set(FakePaymentSignatureHeader, sig)
+
+	ch, err := svc.HandleWebhook(header, body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ch.Status != repositories.ChargePaid {
+		t.Fatalf("charge should be marked paid, got %+v", ch)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -1,11 +1,13 @@
 package services
 
 import (
+    "database/sql"
     "encoding/json"
     "log"
     "strconv"
     "strings"
 
+    intconfig "backend/internal/config"
     "backend/internal/domain"
     "backend/internal/repositories"
     "backend/internal/utils"
@@ -20,38 +22,102 @@ type PaymentService struct {
     ReturnSvc       ReturnService
     RequestID       string
     PassengerSvc    PassengerService
+    Actor           repositories.StatusActor
+    Ledger          repositories.PaymentLedgerRepository
+    Outbox          repositories.OutboxRepository
+    DB              *sql.DB
 }
 
-// ValidatePayment menyimpan payment_validations, update status booking, dan sinkron ke departure/return sesuai trip_role.
+func (s PaymentService) db() *sql.DB {
+    if s.DB != nil {
+        return s.DB
+    }
+    return intconfig.DB
+}
+
+// ValidatePayment menandai booking lunas (MarkPaidTx) dalam transaksi sendiri, lalu sinkron ke
+// departure/return sesuai trip_role: lewat worker outbox, atau langsung bila tabel outbox belum ada.
 func (s PaymentService) ValidatePayment(bookingID int64, raw json.RawMessage) error {
     if bookingID <= 0 {
         return domain.ValidationError{Field: "booking_id", Msg: "id tidak valid"}
     }
+    db := s.db()
+    if db == nil {
+        return domain.InternalError{Msg: "db tidak tersedia"}
+    }
+    tx, err := db.Begin()
+    if err != nil {
+        return domain.InternalError{Msg: "gagal mulai transaksi", Err: err}
+    }
+    queued, err := s.MarkPaidTx(tx, bookingID, raw)
+    if err != nil {
+        _ = tx.Rollback()
+        return err
+    }
+    if err := tx.Commit(); err != nil {
+        return domain.InternalError{Msg: "gagal commit pembayaran", Err: err}
+    }
+    if queued {
+        return nil
+    }
+    return s.SyncSettings(bookingID, readTripRole(raw))
+}
+
+// MarkPaidTx menyimpan payment_validations, memindahkan booking ke Lunas (paid) dan mencatat event
+// booking_paid di transaksi pemanggil, sehingga status lunas tidak pernah tersimpan tanpa sinkron
+// settings-nya. queued=false bila tabel outbox belum ada; pemanggil lalu menjalankan SyncSettings
+// setelah commit.
+func (s PaymentService) MarkPaidTx(tx *sql.Tx, bookingID int64, raw json.RawMessage) (bool, error) {
+    if bookingID <= 0 {
+        return false, domain.ValidationError{Field: "booking_id", Msg: "id tidak valid"}
+    }
+
+    // booking yang punya ledger pembayaran baru boleh Lunas setelah sisa tagihan nol
+    ledger := PaymentLedgerService{Ledger: s.Ledger, Bookings: s.BookingRepo, RequestID: s.RequestID}
+    if ledger.Available() {
+        bal, err := ledger.BalanceTx(tx, bookingID)
+        if err != nil {
+            return false, err
+        }
+        if len(bal.Payments) > 0 && !bal.Settled() {
+            return false, domain.ConflictError{Resource: "booking", Msg: "Booking belum lunas, sisa tagihan " + formatRupiah(bal.Outstanding)}
+        }
+    }
+
+    // update status booking ke Lunas (paid); transisi yang tidak valid ditolak sebelum apa pun ditulis
+    if err := s.BookingRepo.UpdatePaymentStatusTx(tx, bookingID, "Lunas", readMethod(raw), statusActor(s.Actor, s.RequestID)); err != nil {
+        utils.LogEvent(s.RequestID, "payment", "validate", "update booking status failed: "+err.Error())
+        return false, err
+    }
 
     // simpan payment_validations jika ada payload
     if len(raw) > 0 {
-        if err := s.PaymentRepo.CreateOrUpdateValidation(bookingID, raw); err != nil {
+        if err := s.PaymentRepo.CreateOrUpdateValidationTx(tx, bookingID, raw); err != nil {
             utils.LogEvent(s.RequestID, "payment", "validate", "upsert warning: "+err.Error())
             // jangan return, karena sistem kamu sebelumnya memang "warning"
         }
     }
 
-    // update status booking ke Lunas
-    if err := s.BookingRepo.UpdatePaymentStatus(bookingID, "Lunas", readMethod(raw)); err != nil {
-        // sebelumnya hanya warning, tapi untuk flow "setelah lunas harus ada passengers"
-        // lebih aman: return error supaya jelas kalau status gagal diupdate
-        utils.LogEvent(s.RequestID, "payment", "validate", "update booking status failed: "+err.Error())
-        return err
+    if !s.Outbox.Available() {
+        return false, nil
+    }
+    payload := repositories.BookingPaidPayload{BookingID: bookingID, TripRole: readTripRole(raw)}
+    if _, err := s.Outbox.EnqueueTx(tx, repositories.EventBookingPaid, bookingID, payload, s.RequestID); err != nil {
+        return false, domain.InternalError{Msg: "gagal mencatat event sinkronisasi", Err: err}
     }
+    return true, nil
+}
 
-    // buat/rapikan departure/return settings dari booking
+// SyncSettings membuat/merapikan departure atau return settings dari booking lunas sesuai trip_role
+// (kosong = role di payment_validations) lalu sinkron penumpang. Aman diulang; dipakai juga oleh
+// worker outbox untuk event booking_paid.
+func (s PaymentService) SyncSettings(bookingID int64, tripRole string) error {
     booking, err := s.BookingRepo.GetByID(bookingID)
     if err != nil {
         return err
     }
     seats, _ := s.BookingSeatRepo.GetSeats(bookingID)
 
-    tripRole := readTripRole(raw)
     if tripRole == "" {
         if existing, _ := s.PaymentRepo.GetByBookingID(bookingID); existing.TripRole != "" {
             tripRole = existing.TripRole
@@ -0,0 +1,498 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// RescheduleInput adalah tujuan reschedule yang sudah divalidasi handler
+// (stop kanonik, legs jalur, trip slot terjadwal dan tarif hasil Quote).
+type RescheduleInput struct {
+	BookingID    int64
+	UserID       int64
+	Slot         repositories.SeatSlot
+	TripSlot     repositories.TripSlot
+	Seats        []string
+	PricePerSeat int64
+	HoldToken    string
+	Reason       string
+}
+
+// RescheduleService memindahkan booking reguler ke slot/seat lain dalam satu transaksi
+// dan menyinkronkan ulang departure/return settings serta data penumpang yang sudah ada.
+type RescheduleService struct {
+	Bookings    repositories.BookingRepository
+	Seats       repositories.BookingSeatRepository
+	Passengers  repositories.PassengerRepository
+	Reschedules repositories.RescheduleRepository
+	Ledger      repositories.PaymentLedgerRepository
+	Refunds     repositories.RefundRepository
+	Holds       SeatHoldService
+	Schedules   ScheduleService
+	DB          *sql.DB
+	Actor       repositories.StatusActor
+	RequestID   string
+	Now         func() time.Time
+}
+
+func (s RescheduleService) db() *sql.DB {
+	if s.DB != nil {
+		return s.DB
+	}
+	return intconfig.DB
+}
+
+func (s RescheduleService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s RescheduleService) ledger() PaymentLedgerService {
+	return PaymentLedgerService{Ledger: s.Ledger, Bookings: s.Bookings, DB: s.DB, RequestID: s.RequestID, Now: s.Now}
+}
+
+// carryLegacyPaymentTx mencatat pembayaran booking lama (lunas tanpa entri ledger) sebagai entri
+// approved sebesar total lama, supaya saldo tetap benar setelah total booking berubah.
+func (s RescheduleService) carryLegacyPaymentTx(tx *sql.Tx, b repositories.Booking) error {
+	bal, err := s.ledger().BalanceTx(tx, b.ID)
+	if err != nil || len(bal.Payments) > 0 || bal.Paid <= 0 {
+		return err
+	}
+	_, err = s.ledger().RecordTx(tx, repositories.LedgerPayment{
+		BookingID:  b.ID,
+		Amount:     bal.Paid,
+		Method:     strings.TrimSpace(b.PaymentMethod),
+		Status:     repositories.LedgerApproved,
+		ReceivedBy: s.Actor.UserID,
+		Note:       "saldo pembayaran sebelum reschedule",
+	})
+	return err
+}
+
+// reconcileStatusTx menyesuaikan status booking dengan saldo ledger setelah total berubah:
+// booking lunas yang tarifnya naik menjadi partially_paid, booking DP yang kini tertutup menjadi paid.
+// Booking yang belum ada uang masuk atau masih menunggu validasi dibiarkan.
+func (s RescheduleService) reconcileStatusTx(tx *sql.Tx, bookingID int64, status domain.BookingStatus, bal BookingBalance) error {
+	if status != domain.BookingPaid && status != domain.BookingPartiallyPaid {
+		return nil
+	}
+	target := bal.Status()
+	if target == "" || target == status {
+		return nil
+	}
+	_, err := s.Bookings.TransitionTx(tx, bookingID, target, s.Actor, "reschedule: total booking berubah")
+	return err
+}
+
+// fareAdjustment menentukan penyesuaian tarif: booking yang sudah dibayar dan tarifnya naik
+// menjadi tagihan tambahan (supplement), tarifnya turun menjadi kredit untuk customer.
+func fareAdjustment(paid bool, oldTotal, newTotal int64) (kind string, amount int64, status string) {
+	diff := newTotal - oldTotal
+	switch {
+	case !paid || diff == 0:
+		return repositories.AdjustmentNone, 0, repositories.AdjustmentNone
+	case diff > 0:
+		return repositories.AdjustmentSupplement, diff, repositories.AdjustmentPending
+	default:
+		return repositories.AdjustmentCredit, -diff, repositories.AdjustmentPending
+	}
+}
+
+// reschedulable menolak booking yang sudah di status akhir (batal, ditolak, kedaluwarsa) atau
+// sudah berangkat; hanya booking yang masih menunggu perjalanan boleh dipindah jadwalnya.
+func reschedulable(status domain.BookingStatus) error {
+	switch status {
+	case domain.BookingDraft, domain.BookingAwaitingPayment, domain.BookingAwaitingValidation,
+		domain.BookingPartiallyPaid, domain.BookingPaid:
+		return nil
+	case domain.BookingCancelled:
+		return domain.ConflictError{Resource: "booking", Msg: "Booking sudah dibatalkan"}
+	case domain.BookingRejected:
+		return domain.ConflictError{Resource: "booking", Msg: "Pembayaran booking ini ditolak"}
+	case domain.BookingExpired:
+		return domain.ConflictError{Resource: "booking", Msg: "Booking sudah kedaluwarsa"}
+	case domain.BookingDeparted, domain.BookingCompleted:
+		return domain.ConflictError{Resource: "booking", Msg: "Penumpang sudah berangkat, booking tidak bisa di-reschedule"}
+	}
+	return domain.ConflictError{Resource: "booking", Msg: fmt.Sprintf("Booking berstatus %s tidak bisa di-reschedule", status)}
+}
+
+// seatMoves memasangkan seat lama dan baru sesuai urutan (seat yang sama dilewati).
+func seatMoves(oldSeats, newSeats []string) map[string]string {
+	moves := map[string]string{}
+	for i := range oldSeats {
+		if i < len(newSeats) && oldSeats[i] != newSeats[i] {
+			moves[oldSeats[i]] = newSeats[i]
+		}
+	}
+	return moves
+}
+
+// Reschedule memindahkan booking_seats ke slot/seat baru, menghitung ulang total, mencatat
+// penyesuaian tarif di booking_reschedules, lalu menyinkronkan ulang manifest yang sudah ada.
+func (s RescheduleService) Reschedule(in RescheduleInput) (repositories.Reschedule, error) {
+	rs := repositories.Reschedule{BookingID: in.BookingID}
+	if in.BookingID <= 0 {
+		return rs, domain.ValidationError{Field: "id", Msg: "id tidak valid"}
+	}
+	if bookingDeparted(repositories.DepartureRepository{}, in.BookingID) {
+		return rs, domain.ValidationError{Field: "booking", Msg: "Penumpang sudah berangkat, booking tidak bisa di-reschedule"}
+	}
+	if !s.Reschedules.Available() {
+		return rs, domain.InternalError{Msg: "tabel booking_reschedules belum tersedia, jalankan `migrate up`"}
+	}
+	now := s.now()
+	departure, err := departureTime(in.Slot.Date, in.Slot.Time)
+	if err != nil || !departure.After(now) {
+		return rs, domain.ValidationError{Field: "date", Msg: "Jadwal baru harus setelah waktu sekarang"}
+	}
+
+	db := s.db()
+	if db == nil {
+		return rs, domain.InternalError{Msg: "db tidak tersedia"}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return rs, domain.InternalError{Msg: "gagal mulai transaksi", Err: err}
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	b, err := s.Bookings.LockForUpdate(tx, in.BookingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return rs, domain.NotFoundError{Resource: "booking"}
+	}
+	if err != nil {
+		return rs, domain.InternalError{Msg: "gagal membaca booking", Err: err}
+	}
+	status, err := s.Bookings.StatusTx(tx, in.BookingID)
+	if err != nil {
+		return rs, domain.InternalError{Msg: "gagal membaca status booking", Err: err}
+	}
+	if err := reschedulable(status); err != nil {
+		return rs, err
+	}
+	useLedger := s.ledger().Available()
+	if useLedger {
+		if err := s.carryLegacyPaymentTx(tx, b); err != nil {
+			return rs, domain.InternalError{Msg: "gagal mencatat pembayaran lama", Err: err}
+		}
+	}
+	if oldDeparture, err := departureTime(b.TripDate, b.TripTime); err == nil && !now.Before(oldDeparture) {
+		return rs, domain.ValidationError{Field: "booking", Msg: "Jadwal keberangkatan sudah lewat, booking tidak bisa di-reschedule"}
+	}
+
+	oldSeats, err := s.Seats.ListByBookingTx(tx, in.BookingID)
+	if err != nil {
+		return rs, domain.InternalError{Msg: "gagal membaca seat booking", Err: err}
+	}
+	if len(oldSeats) == 0 {
+		return rs, domain.ValidationError{Field: "booking", Msg: "Booking belum punya seat, tidak bisa di-reschedule"}
+	}
+	if len(in.Seats) != len(oldSeats) {
+		return rs, domain.ValidationError{Field: "selectedSeats", Msg: fmt.Sprintf("Jumlah seat harus sama dengan booking (%d)", len(oldSeats))}
+	}
+	sameSlot := b.RouteFrom == in.Slot.From && b.RouteTo == in.Slot.To && b.TripDate == in.Slot.Date && b.TripTime == in.Slot.Time
+	moves := seatMoves(oldSeats, in.Seats)
+	if sameSlot && len(moves) == 0 {
+		return rs, domain.ValidationError{Field: "booking", Msg: "Tidak ada perubahan jadwal atau seat"}
+	}
+
+	// seat lama dilepas dulu agar seat yang sama/overlap tetap bisa dipilih ulang
+	if _, err := s.Seats.DeleteByBookingTx(tx, in.BookingID); err != nil {
+		return rs, domain.InternalError{Msg: "gagal melepas seat lama", Err: err}
+	}
+	if err := s.Holds.EnsureSeatsFreeTx(tx, in.Slot, in.Seats); err != nil {
+		return rs, err
+	}
+	if in.TripSlot.ID > 0 {
+		if err := s.Schedules.CheckCapacityTx(tx, in.TripSlot, in.Slot, in.HoldToken, in.Seats); err != nil {
+			return rs, err
+		}
+	}
+	if err := s.Holds.ConsumeTx(tx, in.UserID, in.HoldToken, in.Slot, in.Seats); err != nil {
+		return rs, err
+	}
+	if err := s.Seats.InsertSeatsTx(tx, in.BookingID, in.Slot, in.Seats); err != nil {
+		var me *mysql.MySQLError
+		if errors.As(err, &me) && me.Number == 1062 {
+			return rs, domain.ConflictError{Resource: "seat", Msg: "Seat sudah dibooking orang lain, silakan pilih seat lain"}
+		}
+		return rs, domain.InternalError{Msg: "gagal menyimpan seat", Err: err}
+	}
+
+	newTotal := int64(len(in.Seats)) * in.PricePerSeat
+	if err := s.Bookings.RescheduleTx(tx, in.BookingID, in.Slot, in.TripSlot.ID, in.PricePerSeat, newTotal, now); err != nil {
+		return rs, domain.InternalError{Msg: "gagal update booking", Err: err}
+	}
+	if err := s.Passengers.RemapSeatsTx(tx, in.BookingID, moves); err != nil {
+		return rs, domain.InternalError{Msg: "gagal memindahkan data penumpang", Err: err}
+	}
+
+	st := strings.TrimSpace(b.PaymentStatus)
+	paid := st == bookingStatusPaid || st == bookingStatusAwaitingValidation
+	if useLedger {
+		// nominal transfer ikut sisa tagihan baru; kode unik dipindah agar tidak bentrok
+		bal, err := s.ledger().RebaseTransferCodeTx(tx, in.BookingID)
+		if err != nil {
+			return rs, err
+		}
+		paid = bal.Paid+bal.Pending > 0
+		if err := s.reconcileStatusTx(tx, in.BookingID, status, bal); err != nil {
+			return rs, domain.InternalError{Msg: "gagal menyesuaikan status booking", Err: err}
+		}
+	} else if !paid {
+		codes := TransferCodeService{Bookings: s.Bookings, RequestID: s.RequestID}
+		if _, err := codes.RebaseTx(tx, in.BookingID, newTotal); err != nil {
+			return rs, err
+		}
+	}
+	kind, amount, adjStatus := fareAdjustment(paid, b.Total, newTotal)
+	rs = repositories.Reschedule{
+		BookingID:        in.BookingID,
+		OldFrom:          b.RouteFrom,
+		OldTo:            b.RouteTo,
+		OldDate:          b.TripDate,
+		OldTime:          b.TripTime,
+		OldSeats:         oldSeats,
+		OldTotal:         b.Total,
+		NewFrom:          in.Slot.From,
+		NewTo:            in.Slot.To,
+		NewDate:          in.Slot.Date,
+		NewTime:          in.Slot.Time,
+		NewSeats:         in.Seats,
+		NewTotal:         newTotal,
+		Adjustment:       kind,
+		AdjustmentAmount: amount,
+		AdjustmentStatus: adjStatus,
+		Reason:           strings.TrimSpace(in.Reason),
+		RequestedBy:      in.UserID,
+		CreatedAt:        now,
+	}
+	id, err := s.Reschedules.InsertTx(tx, rs)
+	if err != nil {
+		return rs, domain.InternalError{Msg: "gagal mencatat reschedule", Err: err}
+	}
+	rs.ID = id
+
+	if err := tx.Commit(); err != nil {
+		return rs, domain.InternalError{Msg: "gagal commit reschedule", Err: err}
+	}
+	committed = true
+	utils.LogEvent(s.RequestID, "booking", "reschedule", fmt.Sprintf("booking_id=%d %s %s -> %s %s seats=%s adjustment=%s:%d",
+		in.BookingID, b.TripDate, b.TripTime, in.Slot.Date, in.Slot.Time, strings.Join(in.Seats, ","), kind, amount))
+
+	s.resyncManifest(in.BookingID)
+	return rs, nil
+}
+
+// resyncManifest memperbarui baris departure/return settings dan penumpang yang sudah ada
+// (upsert berdasarkan booking_id + seat) supaya tidak terduplikasi setelah reschedule.
+func (s RescheduleService) resyncManifest(bookingID int64) {
+	bookings := repositories.BookingRepository{}
+	seatRepo := repositories.BookingSeatRepository{}
+	passengers := PassengerService{PassengerRepo: s.Passengers, BookingRepo: bookings, BookingSeatRepo: seatRepo, RequestID: s.RequestID}
+
+	depSvc := DepartureService{Repo: repositories.DepartureRepository{}, BookingRepo: bookings, SeatRepo: seatRepo, RequestID: s.RequestID}
+	if _, err := depSvc.Repo.GetByBookingID(bookingID); err == nil {
+		dep, err := depSvc.CreateOrUpdateFromBookingID(bookingID)
+		if err == nil {
+			err = passengers.SyncFromDeparture(dep)
+		}
+		if err != nil {
+			log.Println("[RESCHEDULE] resync departure warning:", err)
+			utils.LogEvent(s.RequestID, "booking", "reschedule", "resync departure failed: "+err.Error())
+		}
+	}
+
+	retSvc := ReturnService{Repo: repositories.ReturnRepository{}, BookingRepo: bookings, SeatRepo: seatRepo, RequestID: s.RequestID}
+	if _, err := retSvc.Repo.GetByBookingID(bookingID); err == nil {
+		ret, err := retSvc.CreateOrUpdateFromBookingID(bookingID)
+		if err == nil {
+			err = passengers.SyncFromReturn(ret)
+		}
+		if err != nil {
+			log.Println("[RESCHEDULE] resync return warning:", err)
+			utils.LogEvent(s.RequestID, "booking", "reschedule", "resync return failed: "+err.Error())
+		}
+	}
+}
+
+// ===== admin: penyesuaian tarif =====
+
+func (s RescheduleService) List(adjustmentStatus string) ([]repositories.Reschedule, error) {
+	list, err := s.Reschedules.List(adjustmentStatus)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat reschedule", Err: err}
+	}
+	return list, nil
+}
+
+// RescheduleSettlement adalah reschedule yang penyesuaian tarifnya sudah ditutup beserta efeknya
+// ke pembayaran (saldo ledger terbaru, refund kredit).
+type RescheduleSettlement struct {
+	repositories.Reschedule
+	Balance *BookingBalance      `json:"balance,omitempty"`
+	Refund  *repositories.Refund `json:"refund,omitempty"`
+}
+
+// SettleAdjustment menutup tagihan tambahan / kredit reschedule yang masih pending dalam satu
+// transaksi bersama efeknya ke pembayaran:
+//   - supplement settled: sisa tagihan tambahan dicatat sebagai pembayaran approved di ledger
+//     (method, default cash);
+//   - supplement waived: total booking dikurangi tagihan yang dibebaskan;
+//   - credit settled: kelebihan bayar dicatat sebagai refund pending (method, default metode bayar booking);
+//   - credit waived: tidak ada dana yang dikembalikan.
+//
+// Status booking lalu disesuaikan dengan saldo ledger (partially_paid selama masih ada sisa tagihan).
+func (s RescheduleService) SettleAdjustment(id int64, status, method, note string) (RescheduleSettlement, error) {
+	out := RescheduleSettlement{}
+	status = strings.ToLower(strings.TrimSpace(status))
+	if status != repositories.AdjustmentSettled && status != repositories.AdjustmentWaived {
+		return out, domain.ValidationError{Field: "status", Msg: "harus settled atau waived"}
+	}
+	method = strings.ToLower(strings.TrimSpace(method))
+	db := s.db()
+	if db == nil {
+		return out, domain.InternalError{Msg: "db tidak tersedia"}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return out, domain.InternalError{Msg: "gagal mulai transaksi", Err: err}
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	rs, err := s.Reschedules.GetForUpdateTx(tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return out, domain.NotFoundError{Resource: "reschedule"}
+	}
+	if err != nil {
+		return out, domain.InternalError{Msg: "gagal memuat reschedule", Err: err}
+	}
+	out.Reschedule = rs
+	if rs.AdjustmentStatus != repositories.AdjustmentPending {
+		return out, domain.ConflictError{Resource: "reschedule", Msg: "tidak ada penyesuaian tarif yang pending"}
+	}
+	b, err := s.Bookings.LockForUpdate(tx, rs.BookingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return out, domain.NotFoundError{Resource: "booking"}
+	}
+	if err != nil {
+		return out, domain.InternalError{Msg: "gagal membaca booking", Err: err}
+	}
+	bookingStatus, err := s.Bookings.StatusTx(tx, rs.BookingID)
+	if err != nil {
+		return out, domain.InternalError{Msg: "gagal membaca status booking", Err: err}
+	}
+
+	now := s.now()
+	ledger := s.ledger()
+	useLedger := ledger.Available()
+	var bal BookingBalance
+	if useLedger {
+		if bal, err = ledger.BalanceTx(tx, rs.BookingID); err != nil {
+			return out, err
+		}
+	}
+	amount := rs.AdjustmentAmount
+	switch rs.Adjustment {
+	case repositories.AdjustmentSupplement:
+		if useLedger {
+			// bagian yang sudah dibayar customer lewat submit pembayaran tidak dicatat/dibebaskan lagi
+			amount = min(amount, bal.Outstanding)
+		}
+		if amount <= 0 {
+			break
+		}
+		if status == repositories.AdjustmentWaived {
+			if err := s.Bookings.ReduceTotalTx(tx, rs.BookingID, amount, now); err != nil {
+				return out, domain.InternalError{Msg: "gagal membebaskan tagihan tambahan", Err: err}
+			}
+		} else if useLedger {
+			if method == "" {
+				method = "cash"
+			}
+			if _, err := ledger.RecordTx(tx, repositories.LedgerPayment{
+				BookingID:  rs.BookingID,
+				Amount:     amount,
+				Method:     method,
+				Status:     repositories.LedgerApproved,
+				ReceivedBy: s.Actor.UserID,
+				Note:       fmt.Sprintf("tambahan tarif reschedule #%d", id),
+			}); err != nil {
+				return out, err
+			}
+		}
+	case repositories.AdjustmentCredit:
+		if useLedger {
+			amount = min(amount, max(bal.Paid-bal.Total, 0))
+		}
+		if status != repositories.AdjustmentSettled || amount <= 0 {
+			break
+		}
+		if method == "" {
+			method = strings.TrimSpace(b.PaymentMethod)
+		}
+		rf := repositories.Refund{
+			BookingID:   rs.BookingID,
+			PaidAmount:  amount,
+			Amount:      amount,
+			Method:      method,
+			Status:      repositories.RefundPending,
+			Reason:      fmt.Sprintf("kredit reschedule #%d", id),
+			RequestedBy: s.Actor.UserID,
+			CreatedAt:   now,
+		}
+		rfID, err := s.Refunds.InsertTx(tx, rf)
+		if err != nil {
+			return out, domain.InternalError{Msg: "gagal mencatat refund kredit", Err: err}
+		}
+		rf.ID = rfID
+		out.Refund = &rf
+	}
+	if useLedger {
+		if bal, err = ledger.RebaseTransferCodeTx(tx, rs.BookingID); err != nil {
+			return out, err
+		}
+		if err := s.reconcileStatusTx(tx, rs.BookingID, bookingStatus, bal); err != nil {
+			return out, domain.InternalError{Msg: "gagal menyesuaikan status booking", Err: err}
+		}
+		out.Balance = &bal
+	}
+
+	note = strings.TrimSpace(note)
+	if err := s.Reschedules.UpdateAdjustmentStatusTx(tx, id, status, note, now); err != nil {
+		return out, domain.InternalError{Msg: "gagal update reschedule", Err: err}
+	}
+	if err := tx.Commit(); err != nil {
+		return out, domain.InternalError{Msg: "gagal commit reschedule", Err: err}
+	}
+	committed = true
+	out.AdjustmentStatus = status
+	out.Note = note
+	out.SettledAt = &now
+	utils.LogEvent(s.RequestID, "reschedule", "settle", fmt.Sprintf("id=%d booking_id=%d %s:%d status=%s applied=%d", id, rs.BookingID, rs.Adjustment, rs.AdjustmentAmount, status, amount))
+	return out, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+)
+
+func TestFareAdjustment(t *testing.T) {
+	cases := []struct {
+		name       string
+		paid       bool
+		old, new   int64
+		wantKind   string
+		wantAmount int64
+		wantStatus string
+	}{
+		{"belum bayar", false, 150000, 200000, repositories.AdjustmentNone, 0, repositories.AdjustmentNone},
+		{"tarif sama", true, 150000, 150000, repositories.AdjustmentNone, 0, repositories.AdjustmentNone},
+		{"tarif naik", true, 150000, 200000, repositories.AdjustmentSupplement, 50000, repositories.AdjustmentPending},
+		{"tarif turun", true, 200000, 150000, repositories.AdjustmentCredit, 50000, repositories.AdjustmentPending},
+	}
+	for _, tc := range cases {
+		kind, amount, status := fareAdjustment(tc.paid, tc.old, tc.new)
+		if kind != tc.wantKind || amount != tc.wantAmount || status != tc.wantStatus {
+			t.Fatalf("%s: got %s/%d/%s", tc.name, kind, amount, status)
+		}
+	}
+}
+
+func TestSeatMovesPairsByPosition(t *testing.T) {
+	moves := seatMoves([]string{"1A", "2A", "3A"}, []string{"2A", "1A", "3A"})
+	if len(moves) != 2 || moves["1A"] != "2A" || moves["2A"] != "1A" {
+		t.Fatalf("unexpected moves %v", moves)
+	}
+}
+
+func TestReschedulableRejectsTerminalStatuses(t *testing.T) {
+	for _, st := range []domain.BookingStatus{domain.BookingAwaitingPayment, domain.BookingPartiallyPaid, domain.BookingPaid} {
+		if err := reschedulable(st); err != nil {
+			t.Fatalf("%s: unexpected error %v", st, err)
+		}
+	}
+	for _, st := range []domain.BookingStatus{domain.BookingExpired, domain.BookingCancelled, domain.BookingRejected, domain.BookingDeparted, domain.BookingCompleted} {
+		var ce domain.ConflictError
+		if err := reschedulable(st); !errors.As(err, &ce) {
+			t.Fatalf("%s: want ConflictError, got %v", st, err)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	intconfig "backend/internal/config"
 	intdb "backend/internal/db"
+	"backend/internal/domain"
 	"backend/internal/domain/models"
 	"backend/internal/repositories"
 	"backend/internal/utils"
@@ -17,7 +18,10 @@ type ReturnService struct {
 	Repo        repositories.ReturnRepository
 	BookingRepo repositories.BookingRepository
 	SeatRepo    repositories.BookingSeatRepository
+	Guard       AssignmentGuard
+	Outbox      repositories.OutboxRepository
 	RequestID   string
+	Actor       repositories.StatusActor
 }
 
 func (s ReturnService) db() *sql.DB {
@@ -178,16 +182,87 @@ func (s ReturnService) CreateOrUpdateFromBookingID(bookingID int64) (models.Retu
 }
 
 // MarkPulang updates return_settings with key-presence semantics + enrich fallback.
+// Transisi booking divalidasi dan ditulis lebih dulu, lalu return_settings beserta enrichment
+// disimpan dalam transaksi yang sama.
 func (s ReturnService) MarkPulang(id int, rawPayload []byte) (models.ReturnSetting, error) {
 	if s.Repo.DB == nil {
 		s.Repo.DB = s.db()
 	}
 
 	utils.LogEvent(s.RequestID, "return", "mark_pulang", "start id="+strconv.Itoa(id))
-	updated, err := s.Repo.UpdatePartial(id, rawPayload)
+
+	db := s.db()
+	if db == nil {
+		return models.ReturnSetting{}, domain.InternalError{Msg: "db tidak tersedia"}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return models.ReturnSetting{}, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	queued, err := s.markPulangTx(tx, id, rawPayload)
+	if err != nil {
+		return models.ReturnSetting{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.ReturnSetting{}, err
+	}
+	committed = true
+	return s.afterPulang(id, queued)
+}
+
+// markPulangTx adalah isi MarkPulang di dalam transaksi pemanggil (dipakai juga TripRunService
+// untuk seluruh booking run sekaligus). queued = event sinkron sudah tercatat di outbox.
+func (s ReturnService) markPulangTx(tx *sql.Tx, id int, rawPayload []byte) (bool, error) {
+	existing, err := s.Repo.GetForUpdateTx(tx, id)
+	if err != nil {
+		return false, err
+	}
+
+	// kepulangan pertama kali (status kosong default Berangkat) menyelesaikan booking; ditolak
+	// sebelum return_settings diubah
+	if existing.BookingID > 0 && !isBerangkat(existing.DepartureStatus) {
+		if next := departureStatusAfter(rawPayload, existing.DepartureStatus); next == "" || isBerangkat(next) {
+			cur, err := s.BookingRepo.StatusTx(tx, existing.BookingID)
+			if err != nil {
+				return false, err
+			}
+			steps, err := pulangStepsFrom(cur)
+			if err != nil {
+				return false, err
+			}
+			for _, st := range steps {
+				if _, err := s.BookingRepo.TransitionTx(tx, existing.BookingID, st, statusActor(s.Actor, s.RequestID), "return_settings id="+strconv.Itoa(id)); err != nil {
+					utils.LogEvent(s.RequestID, "return", "mark_pulang_status_error", err.Error())
+					return false, err
+				}
+			}
+		}
+	}
+
+	// sopir/kendaraan/jadwal yang berubah dicek bentrok dengan penugasan lain di dalam tx ini;
+	// gagal membaca baris = gagal menyimpan
+	preview, changed, err := s.Repo.PreviewTx(tx, id, rawPayload)
+	if err != nil {
+		return false, err
+	}
+	if changed {
+		candidate := settingAssignment(repositories.AssignmentReturn, preview)
+		if err := s.Guard.CheckTx(tx, candidate, nil, forceRequested(rawPayload)); err != nil {
+			return false, err
+		}
+	}
+
+	updated, err := s.Repo.UpdatePartialTx(tx, id, rawPayload)
 	if err != nil {
 		utils.LogEvent(s.RequestID, "return", "mark_pulang_error", err.Error())
-		return updated, err
+		return false, err
 	}
 
 	// ✅ ENRICH: kalau field masih kosong/self, isi dari booking_passengers & departure_settings
@@ -241,29 +316,31 @@ func (s ReturnService) MarkPulang(id int, rawPayload []byte) (models.ReturnSetti
 		}
 
 		if len(enrich) > 0 {
-			_ = s.Repo.UpdateEnrich(id, enrich)
-			// reload biar output konsisten dari DB
-			if fresh, e := s.Repo.GetByID(id); e == nil {
-				updated = fresh
-			}
+			_ = s.Repo.UpdateEnrichTx(tx, id, enrich)
 		}
+	}
 
-		// sync passenger seats & trip info
-		passengerSvc := PassengerService{
-			PassengerRepo:   repositories.PassengerRepository{},
-			BookingRepo:     repositories.BookingRepository{},
-			BookingSeatRepo: repositories.BookingSeatRepository{},
-			RequestID:       s.RequestID,
-		}
-		if err := passengerSvc.SyncFromReturn(updated); err != nil {
-			return updated, err
+	// sinkron penumpang & trip_information dicatat di outbox bersama perubahan di atas
+	if updated.BookingID > 0 && s.Outbox.Available() {
+		if _, err := s.Outbox.EnqueueTx(tx, repositories.EventReturnSync, updated.BookingID, repositories.ReturnSyncPayload{ReturnID: id}, s.RequestID); err != nil {
+			return false, err
 		}
+		return true, nil
+	}
+	return false, nil
+}
 
-		tripSvc := TripInfoService{
-			Repo:      repositories.TripInformationRepository{},
-			RequestID: s.RequestID,
-		}
-		if err := tripSvc.UpsertFromReturn(updated); err != nil {
+// afterPulang memuat ulang return_settings setelah commit lalu menjalankan sinkron yang belum
+// tercatat di outbox.
+func (s ReturnService) afterPulang(id int, queued bool) (models.ReturnSetting, error) {
+	updated, err := s.Repo.GetByID(id)
+	if err != nil {
+		return updated, err
+	}
+
+	// tanpa tabel outbox sinkron dijalankan langsung seperti sebelumnya
+	if updated.BookingID > 0 && !queued {
+		if err := s.syncAfterPulang(updated); err != nil {
 			return updated, err
 		}
 	}
@@ -271,3 +348,23 @@ func (s ReturnService) MarkPulang(id int, rawPayload []byte) (models.ReturnSetti
 	utils.LogEvent(s.RequestID, "return", "mark_pulang_done", "id="+strconv.Itoa(id))
 	return updated, nil
 }
+
+// syncAfterPulang menyinkron passenger seats & trip_information dari return_settings; dipakai
+// langsung oleh MarkPulang (tanpa outbox) dan oleh worker untuk EventReturnSync.
+func (s ReturnService) syncAfterPulang(ret models.ReturnSetting) error {
+	passengerSvc := PassengerService{
+		PassengerRepo:   repositories.PassengerRepository{},
+		BookingRepo:     repositories.BookingRepository{},
+		BookingSeatRepo: repositories.BookingSeatRepository{},
+		RequestID:       s.RequestID,
+	}
+	if err := passengerSvc.SyncFromReturn(ret); err != nil {
+		return err
+	}
+
+	tripSvc := TripInfoService{
+		Repo:      repositories.TripInformationRepository{},
+		RequestID: s.RequestID,
+	}
+	return tripSvc.UpsertFromReturn(ret)
+}
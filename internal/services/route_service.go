@@ -0,0 +1,356 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// RouteService mengelola urutan halte per jalur dan menentukan segmen mana saja
+// yang berbagi seat dalam satu mobil.
+type RouteService struct {
+	Routes    repositories.RouteRepository
+	Fares     FareService
+	RequestID string
+}
+
+// routeLeg adalah pasangan stop_key naik/turun searah perjalanan.
+type routeLeg struct {
+	From string
+	To   string
+}
+
+// stopPositions mengembalikan indeks from dan to pada jalur (-1 bila tidak dilewati).
+func stopPositions(stops []string, from, to string) (int, int) {
+	i, j := -1, -1
+	for idx, key := range stops {
+		if key == from {
+			i = idx
+		}
+		if key == to {
+			j = idx
+		}
+	}
+	return i, j
+}
+
+// overlappingLegs mengembalikan semua segmen pada jalur (urutan stop_key) yang overlap dengan
+// from->to, searah perjalanan. Jalur berlaku dua arah: bila from setelah to, urutan dibalik.
+// Segmen [a,b) dan [i,j) overlap bila a < j dan i < b; segmen yang hanya bersentuhan di satu
+// halte (turun di halte tempat penumpang lain naik) tidak overlap.
+func overlappingLegs(stops []string, from, to string) []routeLeg {
+	i, j := stopPositions(stops, from, to)
+	if i < 0 || j < 0 || i == j {
+		return nil
+	}
+	seq := stops
+	if i > j {
+		n := len(stops)
+		seq = make([]string, n)
+		for idx, key := range stops {
+			seq[n-1-idx] = key
+		}
+		i, j = n-1-i, n-1-j
+	}
+
+	out := []routeLeg{}
+	for a := 0; a < j; a++ {
+		for b := a + 1; b < len(seq); b++ {
+			if i < b {
+				out = append(out, routeLeg{From: seq[a], To: seq[b]})
+			}
+		}
+	}
+	return out
+}
+
+// routeDirection menentukan arah perjalanan from->to pada urutan halte jalur.
+func routeDirection(stops []string, from, to string) (string, bool) {
+	i, j := stopPositions(stops, from, to)
+	switch {
+	case i < 0 || j < 0 || i == j:
+		return "", false
+	case i < j:
+		return repositories.DirectionForward, true
+	default:
+		return repositories.DirectionReverse, true
+	}
+}
+
+// RouteMatch adalah jalur aktif yang melewati from lalu to, beserta arahnya.
+type RouteMatch struct {
+	Route     repositories.Route
+	Direction string
+}
+
+// Matching mengembalikan semua jalur aktif yang memuat perjalanan from->to.
+// Kosong bila tabel routes belum ada atau halte tidak dikenal.
+func (s RouteService) Matching(from, to string) ([]RouteMatch, error) {
+	if !s.Routes.Available() {
+		return nil, nil
+	}
+	routes, err := s.Routes.List(true)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat routes", Err: err}
+	}
+	stops, err := s.Fares.ListStops(false)
+	if err != nil {
+		return nil, err
+	}
+	fromStop, okFrom := findStop(stops, from)
+	toStop, okTo := findStop(stops, to)
+	if !okFrom || !okTo {
+		return nil, nil
+	}
+	out := []RouteMatch{}
+	for _, rt := range routes {
+		if dir, ok := routeDirection(rt.Stops, fromStop.Key, toStop.Key); ok {
+			out = append(out, RouteMatch{Route: rt, Direction: dir})
+		}
+	}
+	return out, nil
+}
+
+// SlotLegs melengkapi slot dengan semua segmen overlap di jalur yang memuat from/to.
+// Bila tabel routes belum ada atau tidak ada jalur yang cocok, slot dikembalikan apa adanya
+// (seat hanya dibandingkan dengan From/To persis seperti sebelumnya).
+func (s RouteService) SlotLegs(slot repositories.SeatSlot) (repositories.SeatSlot, error) {
+	if !s.Routes.Available() {
+		return slot, nil
+	}
+	routes, err := s.Routes.List(true)
+	if err != nil {
+		return slot, domain.InternalError{Msg: "gagal memuat routes", Err: err}
+	}
+	stops, err := s.Fares.ListStops(false)
+	if err != nil {
+		return slot, err
+	}
+	from, okFrom := findStop(stops, slot.From)
+	to, okTo := findStop(stops, slot.To)
+	if !okFrom || !okTo {
+		return slot, nil
+	}
+	display := map[string]string{}
+	for _, st := range stops {
+		display[st.Key] = st.DisplayName
+	}
+
+	legs := []repositories.SeatLeg{{From: slot.From, To: slot.To}}
+	seen := map[repositories.SeatLeg]bool{legs[0]: true}
+	for _, rt := range routes {
+		for _, leg := range overlappingLegs(rt.Stops, from.Key, to.Key) {
+			l := repositories.SeatLeg{From: display[leg.From], To: display[leg.To]}
+			if l.From == "" || l.To == "" || seen[l] {
+				continue
+			}
+			seen[l] = true
+			legs = append(legs, l)
+		}
+	}
+	if len(legs) > 1 {
+		slot.Legs = legs
+	}
+	return slot, nil
+}
+
+// RouteEstimator memperkirakan arah dan lama perjalanan antar halte dari jalur aktif.
+type RouteEstimator struct {
+	Routes []repositories.Route
+	Stops  []repositories.Stop
+	// Fallback adalah lama perjalanan ujung ke ujung bila menit per halte belum diisi.
+	Fallback time.Duration
+}
+
+// Estimator memuat jalur & halte sekali untuk dipakai berulang. Tanpa tabel routes semua
+// perjalanan dianggap Fallback.
+func (s RouteService) Estimator(fallback time.Duration) (RouteEstimator, error) {
+	est := RouteEstimator{Fallback: fallback}
+	if !s.Routes.Available() {
+		return est, nil
+	}
+	routes, err := s.Routes.List(true)
+	if err != nil {
+		return est, domain.InternalError{Msg: "gagal memuat routes", Err: err}
+	}
+	stops, err := s.Fares.ListStops(false)
+	if err != nil {
+		return est, err
+	}
+	est.Routes, est.Stops = routes, stops
+	return est, nil
+}
+
+// locate mencari jalur pertama yang memuat from & to, beserta indeks halte keduanya.
+func (e RouteEstimator) locate(from, to string) (repositories.Route, int, int, bool) {
+	f, okFrom := findStop(e.Stops, from)
+	t, okTo := findStop(e.Stops, to)
+	if !okFrom || !okTo {
+		return repositories.Route{}, -1, -1, false
+	}
+	for _, rt := range e.Routes {
+		if i, j := stopPositions(rt.Stops, f.Key, t.Key); i >= 0 && j >= 0 && i != j {
+			return rt, i, j, true
+		}
+	}
+	return repositories.Route{}, -1, -1, false
+}
+
+// Direction mengembalikan jalur & arah perjalanan from->to; ok=false bila tidak dikenal.
+func (e RouteEstimator) Direction(from, to string) (int64, string, bool) {
+	rt, i, j, ok := e.locate(from, to)
+	if !ok {
+		return 0, "", false
+	}
+	if i < j {
+		return rt.ID, repositories.DirectionForward, true
+	}
+	return rt.ID, repositories.DirectionReverse, true
+}
+
+// Duration memperkirakan lama perjalanan from->to: selisih menit halte bila diisi, selain itu
+// Fallback dibagi rata per segmen jalur. Halte di luar jalur = Fallback.
+func (e RouteEstimator) Duration(from, to string) time.Duration {
+	rt, i, j, ok := e.locate(from, to)
+	if !ok || len(rt.Stops) < 2 {
+		return e.Fallback
+	}
+	if len(rt.StopMinutes) == len(rt.Stops) {
+		if d := rt.StopMinutes[j] - rt.StopMinutes[i]; d != 0 {
+			if d < 0 {
+				d = -d
+			}
+			return time.Duration(d) * time.Minute
+		}
+	}
+	segments := j - i
+	if segments < 0 {
+		segments = -segments
+	}
+	return e.Fallback * time.Duration(segments) / time.Duration(len(rt.Stops)-1)
+}
+
+// ===== admin CRUD =====
+
+func (s RouteService) List() ([]repositories.Route, error) {
+	list, err := s.Routes.List(false)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat routes", Err: err}
+	}
+	return list, nil
+}
+
+func (s RouteService) Get(id int64) (repositories.Route, error) {
+	rt, err := s.Routes.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return rt, domain.NotFoundError{Resource: "route"}
+	}
+	if err != nil {
+		return rt, domain.InternalError{Msg: "gagal memuat route", Err: err}
+	}
+	return rt, nil
+}
+
+func (s RouteService) Create(in repositories.Route) (repositories.Route, error) {
+	in, err := s.cleanRoute(in)
+	if err != nil {
+		return in, err
+	}
+	id, err := s.Routes.Create(in)
+	if err != nil {
+		return in, routeWriteError(err)
+	}
+	in.ID = id
+	utils.LogEvent(s.RequestID, "route", "create", fmt.Sprintf("id=%d code=%s stops=%s", id, in.Code, strings.Join(in.Stops, ",")))
+	return in, nil
+}
+
+func (s RouteService) Update(id int64, in repositories.Route) (repositories.Route, error) {
+	if _, err := s.Get(id); err != nil {
+		return in, err
+	}
+	in, err := s.cleanRoute(in)
+	if err != nil {
+		return in, err
+	}
+	in.ID = id
+	if err := s.Routes.Update(in); err != nil {
+		return in, routeWriteError(err)
+	}
+	utils.LogEvent(s.RequestID, "route", "update", fmt.Sprintf("id=%d code=%s stops=%s", id, in.Code, strings.Join(in.Stops, ",")))
+	return in, nil
+}
+
+func (s RouteService) Delete(id int64) error {
+	current, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.Routes.Delete(id); err != nil {
+		return domain.InternalError{Msg: "gagal hapus route", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "route", "delete", fmt.Sprintf("id=%d code=%s", id, current.Code))
+	return nil
+}
+
+func routeWriteError(err error) error {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == 1062 {
+		return domain.ConflictError{Resource: "route", Msg: "code jalur sudah dipakai"}
+	}
+	return domain.InternalError{Msg: "gagal simpan route", Err: err}
+}
+
+// cleanRoute menormalkan code (lowercase) dan me-resolve setiap halte ke stop_key katalog.
+func (s RouteService) cleanRoute(in repositories.Route) (repositories.Route, error) {
+	in.Code = strings.ToLower(strings.TrimSpace(in.Code))
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Code == "" {
+		return in, domain.ValidationError{Field: "code", Msg: "wajib diisi"}
+	}
+	if in.Name == "" {
+		in.Name = in.Code
+	}
+	if len(in.Stops) < 2 {
+		return in, domain.ValidationError{Field: "stops", Msg: "minimal dua halte"}
+	}
+
+	catalog, err := s.Fares.ListStops(false)
+	if err != nil {
+		return in, err
+	}
+	keys := make([]string, 0, len(in.Stops))
+	seen := map[string]bool{}
+	for _, raw := range in.Stops {
+		st, ok := findStop(catalog, raw)
+		if !ok {
+			return in, domain.ValidationError{Field: "stops", Msg: "stop tidak dikenal: " + raw}
+		}
+		if seen[st.Key] {
+			return in, domain.ValidationError{Field: "stops", Msg: "stop duplikat: " + st.Key}
+		}
+		seen[st.Key] = true
+		keys = append(keys, st.Key)
+	}
+	in.Stops = keys
+
+	if len(in.StopMinutes) > 0 {
+		if len(in.StopMinutes) != len(in.Stops) {
+			return in, domain.ValidationError{Field: "stopMinutes", Msg: "jumlah harus sama dengan stops"}
+		}
+		for i, m := range in.StopMinutes {
+			if m < 0 || (i > 0 && m < in.StopMinutes[i-1]) {
+				return in, domain.ValidationError{Field: "stopMinutes", Msg: "menit harus >= 0 dan tidak menurun"}
+			}
+		}
+	}
+	return in, nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/repositories"
+)
+
+func legSet(legs []routeLeg) map[routeLeg]bool {
+	out := map[routeLeg]bool{}
+	for _, l := range legs {
+		out[l] = true
+	}
+	return out
+}
+
+func TestOverlappingLegsForward(t *testing.T) {
+	line := []string{"skpd", "ujungbatu", "bangkinang", "pekanbaru"}
+	got := legSet(overlappingLegs(line, "bangkinang", "pekanbaru"))
+
+	for _, want := range []routeLeg{
+		{"skpd", "pekanbaru"},
+		{"ujungbatu", "pekanbaru"},
+		{"bangkinang", "pekanbaru"},
+	} {
+		if !got[want] {
+			t.Fatalf("expected overlap %v, got %v", want, got)
+		}
+	}
+	// turun di Bangkinang lalu seat dijual lagi dari Bangkinang: tidak overlap
+	for _, free := range []routeLeg{{"skpd", "bangkinang"}, {"ujungbatu", "bangkinang"}, {"skpd", "ujungbatu"}} {
+		if got[free] {
+			t.Fatalf("leg %v should not overlap bangkinang->pekanbaru", free)
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 overlapping legs, got %d: %v", len(got), got)
+	}
+}
+
+func TestOverlappingLegsReverseDirection(t *testing.T) {
+	line := []string{"skpd", "ujungbatu", "bangkinang", "pekanbaru"}
+	got := legSet(overlappingLegs(line, "pekanbaru", "ujungbatu"))
+
+	for _, want := range []routeLeg{{"pekanbaru", "skpd"}, {"bangkinang", "ujungbatu"}, {"pekanbaru", "bangkinang"}} {
+		if !got[want] {
+			t.Fatalf("expected overlap %v, got %v", want, got)
+		}
+	}
+	if got[routeLeg{"ujungbatu", "skpd"}] {
+		t.Fatalf("ujungbatu->skpd should not overlap pekanbaru->ujungbatu")
+	}
+	// arah berangkat tidak dihitung untuk perjalanan pulang
+	if got[routeLeg{"skpd", "pekanbaru"}] {
+		t.Fatalf("opposite direction must not overlap")
+	}
+}
+
+func TestOverlappingLegsUnknownStop(t *testing.T) {
+	line := []string{"skpd", "bangkinang", "pekanbaru"}
+	if legs := overlappingLegs(line, "kuok", "pekanbaru"); legs != nil {
+		t.Fatalf("expected no legs for stop outside line, got %v", legs)
+	}
+	if legs := overlappingLegs(line, "skpd", "skpd"); legs != nil {
+		t.Fatalf("expected no legs for same stop, got %v", legs)
+	}
+}
+
+func testEstimator(minutes []int) RouteEstimator {
+	return RouteEstimator{
+		Routes: []repositories.Route{{ID: 1, Stops: []string{"skpd", "ujungbatu", "bangkinang", "pekanbaru"}, StopMinutes: minutes}},
+		Stops: []repositories.Stop{
+			{Key: "skpd", DisplayName: "SKPD"}, {Key: "ujungbatu", DisplayName: "Ujung Batu"},
+			{Key: "bangkinang", DisplayName: "Bangkinang"}, {Key: "pekanbaru", DisplayName: "Pekanbaru"},
+			{Key: "kuok", DisplayName: "Kuok"},
+		},
+		Fallback: 6 * time.Hour,
+	}
+}
+
+func TestRouteEstimatorDuration(t *testing.T) {
+	est := testEstimator([]int{0, 60, 180, 240})
+	if d := est.Duration("Ujung Batu", "Pekanbaru"); d != 3*time.Hour {
+		t.Fatalf("expected stop minutes difference (3h), got %v", d)
+	}
+	if d := est.Duration("Pekanbaru", "SKPD"); d != 4*time.Hour {
+		t.Fatalf("reverse direction uses the same minutes, got %v", d)
+	}
+
+	// tanpa menit per halte: fallback dibagi rata per segmen
+	est = testEstimator(nil)
+	if d := est.Duration("Bangkinang", "Pekanbaru"); d != 2*time.Hour {
+		t.Fatalf("expected 1/3 of fallback, got %v", d)
+	}
+	if d := est.Duration("Kuok", "Pekanbaru"); d != 6*time.Hour {
+		t.Fatalf("stop outside any route uses fallback, got %v", d)
+	}
+}
+
+func TestRouteEstimatorDirection(t *testing.T) {
+	est := testEstimator(nil)
+	if id, dir, ok := est.Direction("SKPD", "Bangkinang"); !ok || id != 1 || dir != repositories.DirectionForward {
+		t.Fatalf("expected forward on route 1, got %d %s %v", id, dir, ok)
+	}
+	if _, dir, ok := est.Direction("Pekanbaru", "Ujung Batu"); !ok || dir != repositories.DirectionReverse {
+		t.Fatalf("expected reverse, got %s %v", dir, ok)
+	}
+	if _, _, ok := est.Direction("Kuok", "Pekanbaru"); ok {
+		t.Fatalf("unknown stop must not resolve a direction")
+	}
+}
@@ -0,0 +1,552 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DefaultScheduleHorizonDays adalah jumlah hari ke depan yang trip slot-nya dibuat generator.
+const DefaultScheduleHorizonDays = 14
+
+// ScheduleService mengelola timetable, tanggal libur dan trip slot konkret yang bisa dibooking.
+type ScheduleService struct {
+	Schedules repositories.ScheduleRepository
+	Routes    RouteService
+	Holds     SeatHoldService
+	RequestID string
+	Now       func() time.Time
+}
+
+// SlotAvailability adalah trip slot beserta sisa kursi untuk perjalanan from->to.
+type SlotAvailability struct {
+	repositories.TripSlot
+	RouteCode string `json:"routeCode"`
+	RouteName string `json:"routeName"`
+	Available int    `json:"available"`
+}
+
+func (s ScheduleService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// isoWeekday mengubah time.Weekday ke ISO (1=Senin .. 7=Minggu).
+func isoWeekday(day time.Time) int {
+	if wd := int(day.Weekday()); wd != 0 {
+		return wd
+	}
+	return 7
+}
+
+// scheduleRunsOn menentukan apakah timetable berangkat pada tanggal tsb
+// (hari cocok, dalam masa berlaku, dan bukan tanggal libur jalur/semua jalur).
+func scheduleRunsOn(sc repositories.Schedule, day time.Time, blackouts []repositories.Blackout) bool {
+	date := day.Format("2006-01-02")
+	if sc.ValidFrom != "" && date < sc.ValidFrom {
+		return false
+	}
+	if sc.ValidTo != "" && date > sc.ValidTo {
+		return false
+	}
+	runs := false
+	wd := isoWeekday(day)
+	for _, d := range sc.DaysOfWeek {
+		if d == wd {
+			runs = true
+			break
+		}
+	}
+	if !runs {
+		return false
+	}
+	for _, b := range blackouts {
+		if b.Date == date && (b.RouteID == 0 || b.RouteID == sc.RouteID) {
+			return false
+		}
+	}
+	return true
+}
+
+// Generate membuat trip slot untuk days hari mulai from. Slot yang sudah ada tidak diubah.
+func (s ScheduleService) Generate(from time.Time, days int) (int, error) {
+	if days <= 0 {
+		days = DefaultScheduleHorizonDays
+	}
+	schedules, err := s.Schedules.ListSchedules(true)
+	if err != nil {
+		return 0, domain.InternalError{Msg: "gagal memuat schedules", Err: err}
+	}
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	blackouts, err := s.Schedules.ListBlackouts(start.Format("2006-01-02"))
+	if err != nil {
+		return 0, domain.InternalError{Msg: "gagal memuat blackouts", Err: err}
+	}
+
+	created := 0
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i)
+		for _, sc := range schedules {
+			if !scheduleRunsOn(sc, day, blackouts) {
+				continue
+			}
+			ok, err := s.Schedules.InsertSlot(repositories.TripSlot{
+				ScheduleID:  sc.ID,
+				RouteID:     sc.RouteID,
+				Direction:   sc.Direction,
+				Date:        day.Format("2006-01-02"),
+				Time:        sc.DepartureTime,
+				VehicleType: sc.VehicleType,
+				Capacity:    sc.Capacity,
+				Status:      repositories.TripSlotOpen,
+			})
+			if err != nil {
+				return created, domain.InternalError{Msg: "gagal membuat trip slot", Err: err}
+			}
+			if ok {
+				created++
+			}
+		}
+	}
+	if created > 0 {
+		utils.LogEvent(s.RequestID, "schedule", "generate", fmt.Sprintf("from=%s days=%d created=%d", start.Format("2006-01-02"), days, created))
+	}
+	return created, nil
+}
+
+// RunGenerator menjalankan Generate saat start lalu berkala sampai ctx dibatalkan.
+func (s ScheduleService) RunGenerator(ctx context.Context, interval time.Duration, days int) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	run := func() {
+		if !s.Schedules.Available() {
+			return
+		}
+		if _, err := s.Generate(s.now(), days); err != nil {
+			log.Printf("[SCHEDULE] generator error: %v", err)
+		}
+	}
+	run()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
+
+// scheduledMatches mengembalikan jalur from->to yang sudah punya timetable aktif.
+// Kosong = perjalanan belum terjadwal (booking memakai date/time bebas seperti sebelumnya).
+func (s ScheduleService) scheduledMatches(from, to string) ([]RouteMatch, error) {
+	if !s.Schedules.Available() {
+		return nil, nil
+	}
+	matches, err := s.Routes.Matching(from, to)
+	if err != nil {
+		return nil, err
+	}
+	out := []RouteMatch{}
+	for _, m := range matches {
+		n, err := s.Schedules.CountActiveSchedules(m.Route.ID)
+		if err != nil {
+			return nil, domain.InternalError{Msg: "gagal memuat schedules", Err: err}
+		}
+		if n > 0 {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// AvailableSlots mengembalikan slot open untuk perjalanan from->to pada tanggal, beserta sisa kursi.
+// scheduled=false bila perjalanan ini belum punya timetable.
+func (s ScheduleService) AvailableSlots(q *sql.DB, slot repositories.SeatSlot) ([]SlotAvailability, bool, error) {
+	matches, err := s.scheduledMatches(slot.From, slot.To)
+	if err != nil || len(matches) == 0 {
+		return []SlotAvailability{}, false, err
+	}
+
+	now := s.now()
+	today, nowHHMM := now.Format("2006-01-02"), now.Format("15:04")
+	out := []SlotAvailability{}
+	for _, m := range matches {
+		slots, err := s.Schedules.ListSlots(slot.Date, m.Route.ID, m.Direction, true)
+		if err != nil {
+			return nil, true, domain.InternalError{Msg: "gagal memuat trip slots", Err: err}
+		}
+		for _, ts := range slots {
+			// keberangkatan hari ini yang jamnya sudah lewat tidak ditawarkan lagi
+			if ts.Date == today && ts.Time <= nowHHMM {
+				continue
+			}
+			seatSlot := slot
+			seatSlot.Time = ts.Time
+			state, err := s.Holds.SlotSeats(q, seatSlot, "")
+			if err != nil {
+				return nil, true, err
+			}
+			out = append(out, SlotAvailability{
+				TripSlot:  ts,
+				RouteCode: m.Route.Code,
+				RouteName: m.Route.Name,
+				Available: remainingSeats(ts, occupiedSeats(state.Booked, state.Held)),
+			})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time < out[j].Time })
+	return out, true, nil
+}
+
+// ResolveSlot memastikan perjalanan memakai trip slot open yang terjadwal. slotID (opsional)
+// harus cocok dengan jalur/tanggal/jam; bila kosong slot dicari dari tanggal+jam.
+// ok=false bila perjalanan ini belum terjadwal (tanpa validasi slot).
+func (s ScheduleService) ResolveSlot(slot repositories.SeatSlot, slotID int64) (repositories.TripSlot, bool, error) {
+	matches, err := s.scheduledMatches(slot.From, slot.To)
+	if err != nil || len(matches) == 0 {
+		return repositories.TripSlot{}, false, err
+	}
+	notFound := domain.ValidationError{Field: "slotId", Msg: "Jadwal keberangkatan tidak tersedia untuk tanggal/jam ini"}
+
+	var ts repositories.TripSlot
+	if slotID > 0 {
+		ts, err = s.Schedules.GetSlot(slotID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ts, true, notFound
+		}
+		if err != nil {
+			return ts, true, domain.InternalError{Msg: "gagal memuat trip slot", Err: err}
+		}
+		if ts.Date != slot.Date || ts.Time != slot.Time || !slotOnMatches(ts, matches) {
+			return ts, true, domain.ValidationError{Field: "slotId", Msg: "Jadwal tidak sesuai rute/tanggal/jam yang dipilih"}
+		}
+	} else {
+		found := false
+		for _, m := range matches {
+			ts, err = s.Schedules.FindSlot(m.Route.ID, m.Direction, slot.Date, slot.Time)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return ts, true, domain.InternalError{Msg: "gagal memuat trip slot", Err: err}
+			}
+			found = true
+			break
+		}
+		if !found {
+			return ts, true, notFound
+		}
+	}
+	if ts.Status != repositories.TripSlotOpen {
+		return ts, true, domain.ValidationError{Field: "slotId", Msg: "Jadwal keberangkatan sudah ditutup"}
+	}
+	return ts, true, nil
+}
+
+func slotOnMatches(ts repositories.TripSlot, matches []RouteMatch) bool {
+	for _, m := range matches {
+		if m.Route.ID == ts.RouteID && m.Direction == ts.Direction {
+			return true
+		}
+	}
+	return false
+}
+
+// occupiedSeats menghitung seat unik yang sudah dibooking atau ditahan.
+func occupiedSeats(lists ...[]string) int {
+	seen := map[string]bool{}
+	for _, list := range lists {
+		for _, seat := range list {
+			seen[strings.ToUpper(strings.TrimSpace(seat))] = true
+		}
+	}
+	return len(seen)
+}
+
+func remainingSeats(ts repositories.TripSlot, occupied int) int {
+	if left := ts.Capacity - occupied; left > 0 {
+		return left
+	}
+	return 0
+}
+
+// CheckCapacity menolak permintaan seat yang melebihi sisa kapasitas slot.
+func CheckCapacity(ts repositories.TripSlot, state SlotSeatState, requested int) error {
+	left := remainingSeats(ts, occupiedSeats(state.Booked, state.Held))
+	if requested > left {
+		return domain.ConflictError{Resource: "trip slot", Msg: fmt.Sprintf("Kursi jadwal ini tinggal %d", left)}
+	}
+	return nil
+}
+
+// CheckCapacityTx dipanggil di transaksi create booking: seat yang sudah dibooking (dikunci)
+// ditambah hold aktif pihak lain, plus seat yang diminta, tidak boleh melebihi kapasitas.
+func (s ScheduleService) CheckCapacityTx(tx *sql.Tx, ts repositories.TripSlot, slot repositories.SeatSlot, holdToken string, seats []string) error {
+	booked, err := s.Holds.Holds.BookedSeats(tx, slot, nil, true)
+	if err != nil {
+		return domain.InternalError{Msg: "gagal cek kapasitas", Err: err}
+	}
+	state := SlotSeatState{Booked: booked}
+	if intdb.HasTable(tx, "seat_holds") {
+		holds, err := s.Holds.Holds.ListForSlot(tx, slot, nil, s.now(), false)
+		if err != nil {
+			return domain.InternalError{Msg: "gagal cek kapasitas", Err: err}
+		}
+		token := strings.TrimSpace(holdToken)
+		for _, h := range holds {
+			if token == "" || h.Token != token {
+				state.Held = append(state.Held, h.SeatCode)
+			}
+		}
+	}
+	return CheckCapacity(ts, state, len(seats))
+}
+
+// ===== admin: timetable =====
+
+func (s ScheduleService) ListSchedules() ([]repositories.Schedule, error) {
+	list, err := s.Schedules.ListSchedules(false)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat schedules", Err: err}
+	}
+	return list, nil
+}
+
+func (s ScheduleService) GetSchedule(id int64) (repositories.Schedule, error) {
+	sc, err := s.Schedules.GetSchedule(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sc, domain.NotFoundError{Resource: "schedule"}
+	}
+	if err != nil {
+		return sc, domain.InternalError{Msg: "gagal memuat schedule", Err: err}
+	}
+	return sc, nil
+}
+
+func (s ScheduleService) CreateSchedule(in repositories.Schedule) (repositories.Schedule, error) {
+	in, err := s.cleanSchedule(in)
+	if err != nil {
+		return in, err
+	}
+	id, err := s.Schedules.CreateSchedule(in)
+	if err != nil {
+		return in, scheduleWriteError(err)
+	}
+	in.ID = id
+	utils.LogEvent(s.RequestID, "schedule", "create", fmt.Sprintf("id=%d route=%d %s %s", id, in.RouteID, in.Direction, in.DepartureTime))
+	return in, nil
+}
+
+// UpdateSchedule hanya mengubah timetable; slot yang sudah dibuat diubah lewat trip slot.
+func (s ScheduleService) UpdateSchedule(id int64, in repositories.Schedule) (repositories.Schedule, error) {
+	if _, err := s.GetSchedule(id); err != nil {
+		return in, err
+	}
+	in, err := s.cleanSchedule(in)
+	if err != nil {
+		return in, err
+	}
+	in.ID = id
+	if err := s.Schedules.UpdateSchedule(in); err != nil {
+		return in, scheduleWriteError(err)
+	}
+	utils.LogEvent(s.RequestID, "schedule", "update", fmt.Sprintf("id=%d route=%d %s %s", id, in.RouteID, in.Direction, in.DepartureTime))
+	return in, nil
+}
+
+func (s ScheduleService) DeleteSchedule(id int64) error {
+	if _, err := s.GetSchedule(id); err != nil {
+		return err
+	}
+	if err := s.Schedules.DeleteSchedule(id); err != nil {
+		return domain.InternalError{Msg: "gagal hapus schedule", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "schedule", "delete", fmt.Sprintf("id=%d", id))
+	return nil
+}
+
+func scheduleWriteError(err error) error {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == 1062 {
+		return domain.ConflictError{Resource: "schedule", Msg: "jadwal dengan jalur/arah/jam yang sama sudah ada"}
+	}
+	return domain.InternalError{Msg: "gagal simpan schedule", Err: err}
+}
+
+func (s ScheduleService) cleanSchedule(in repositories.Schedule) (repositories.Schedule, error) {
+	in.Direction = strings.ToLower(strings.TrimSpace(in.Direction))
+	if in.Direction == "" {
+		in.Direction = repositories.DirectionForward
+	}
+	if in.Direction != repositories.DirectionForward && in.Direction != repositories.DirectionReverse {
+		return in, domain.ValidationError{Field: "direction", Msg: "harus forward atau reverse"}
+	}
+	if in.RouteID <= 0 {
+		return in, domain.ValidationError{Field: "routeId", Msg: "wajib diisi"}
+	}
+	if _, err := s.Routes.Get(in.RouteID); err != nil {
+		if domain.IsNotFound(err) {
+			return in, domain.ValidationError{Field: "routeId", Msg: "jalur tidak ditemukan"}
+		}
+		return in, err
+	}
+
+	t, err := time.Parse("15:04", strings.TrimSpace(in.DepartureTime))
+	if err != nil {
+		return in, domain.ValidationError{Field: "departureTime", Msg: "format HH:MM"}
+	}
+	in.DepartureTime = t.Format("15:04")
+
+	if len(in.DaysOfWeek) == 0 {
+		in.DaysOfWeek = []int{1, 2, 3, 4, 5, 6, 7}
+	}
+	seen := map[int]bool{}
+	days := make([]int, 0, len(in.DaysOfWeek))
+	for _, d := range in.DaysOfWeek {
+		if d < 1 || d > 7 {
+			return in, domain.ValidationError{Field: "daysOfWeek", Msg: "nilai 1 (Senin) sampai 7 (Minggu)"}
+		}
+		if !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+	sort.Ints(days)
+	in.DaysOfWeek = days
+
+	if in.Capacity <= 0 {
+		return in, domain.ValidationError{Field: "capacity", Msg: "harus > 0"}
+	}
+	in.VehicleType = strings.ToLower(strings.TrimSpace(in.VehicleType))
+	for field, v := range map[string]*string{"validFrom": &in.ValidFrom, "validTo": &in.ValidTo} {
+		*v = strings.TrimSpace(*v)
+		if *v == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", *v); err != nil {
+			return in, domain.ValidationError{Field: field, Msg: "format YYYY-MM-DD"}
+		}
+	}
+	if in.ValidFrom != "" && in.ValidTo != "" && in.ValidTo < in.ValidFrom {
+		return in, domain.ValidationError{Field: "validTo", Msg: "tidak boleh sebelum validFrom"}
+	}
+	return in, nil
+}
+
+// ===== admin: tanggal libur =====
+
+func (s ScheduleService) ListBlackouts(fromDate string) ([]repositories.Blackout, error) {
+	list, err := s.Schedules.ListBlackouts(strings.TrimSpace(fromDate))
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat blackouts", Err: err}
+	}
+	return list, nil
+}
+
+// CreateBlackout mencatat tanggal libur dan menutup slot open yang sudah terlanjur dibuat.
+func (s ScheduleService) CreateBlackout(in repositories.Blackout) (repositories.Blackout, error) {
+	in.Date = strings.TrimSpace(in.Date)
+	in.Reason = strings.TrimSpace(in.Reason)
+	if _, err := time.Parse("2006-01-02", in.Date); err != nil {
+		return in, domain.ValidationError{Field: "date", Msg: "format YYYY-MM-DD"}
+	}
+	if in.RouteID > 0 {
+		if _, err := s.Routes.Get(in.RouteID); err != nil {
+			if domain.IsNotFound(err) {
+				return in, domain.ValidationError{Field: "routeId", Msg: "jalur tidak ditemukan"}
+			}
+			return in, err
+		}
+	}
+	id, err := s.Schedules.CreateBlackout(in)
+	if err != nil {
+		var me *mysql.MySQLError
+		if errors.As(err, &me) && me.Number == 1062 {
+			return in, domain.ConflictError{Resource: "blackout", Msg: "tanggal libur sudah terdaftar"}
+		}
+		return in, domain.InternalError{Msg: "gagal simpan blackout", Err: err}
+	}
+	in.ID = id
+	closed, err := s.Schedules.CloseSlotsOn(in.Date, in.RouteID)
+	if err != nil {
+		return in, domain.InternalError{Msg: "gagal menutup trip slot", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "schedule", "blackout", fmt.Sprintf("id=%d date=%s route=%d closed_slots=%d", id, in.Date, in.RouteID, closed))
+	return in, nil
+}
+
+// DeleteBlackout menghapus tanggal libur; slot yang sudah ditutup dibuka manual lewat trip slot.
+func (s ScheduleService) DeleteBlackout(id int64) error {
+	err := s.Schedules.DeleteBlackout(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NotFoundError{Resource: "blackout"}
+	}
+	if err != nil {
+		return domain.InternalError{Msg: "gagal hapus blackout", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "schedule", "delete_blackout", fmt.Sprintf("id=%d", id))
+	return nil
+}
+
+// ===== admin: trip slot =====
+
+func (s ScheduleService) ListSlots(date string) ([]repositories.TripSlot, error) {
+	if _, err := time.Parse("2006-01-02", strings.TrimSpace(date)); err != nil {
+		return nil, domain.ValidationError{Field: "date", Msg: "format YYYY-MM-DD"}
+	}
+	list, err := s.Schedules.ListSlots(strings.TrimSpace(date), 0, "", false)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat trip slots", Err: err}
+	}
+	return list, nil
+}
+
+// UpdateSlot membuka/menutup slot atau mengganti kapasitas/tipe kendaraan.
+func (s ScheduleService) UpdateSlot(id int64, status string, capacity int, vehicleType *string) (repositories.TripSlot, error) {
+	ts, err := s.Schedules.GetSlot(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ts, domain.NotFoundError{Resource: "trip slot"}
+	}
+	if err != nil {
+		return ts, domain.InternalError{Msg: "gagal memuat trip slot", Err: err}
+	}
+	if status = strings.ToLower(strings.TrimSpace(status)); status != "" {
+		if status != repositories.TripSlotOpen && status != repositories.TripSlotClosed {
+			return ts, domain.ValidationError{Field: "status", Msg: "harus open atau closed"}
+		}
+		ts.Status = status
+	}
+	if capacity < 0 {
+		return ts, domain.ValidationError{Field: "capacity", Msg: "tidak boleh negatif"}
+	}
+	if capacity > 0 {
+		ts.Capacity = capacity
+	}
+	if vehicleType != nil {
+		ts.VehicleType = strings.ToLower(strings.TrimSpace(*vehicleType))
+	}
+	if err := s.Schedules.UpdateSlot(ts); err != nil {
+		return ts, domain.InternalError{Msg: "gagal update trip slot", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "schedule", "update_slot", fmt.Sprintf("id=%d status=%s capacity=%d", id, ts.Status, ts.Capacity))
+	return ts, nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+)
+
+func TestScheduleRunsOn(t *testing.T) {
+	sc := repositories.Schedule{
+		RouteID:    1,
+		DaysOfWeek: []int{1, 3, 5}, // Senin, Rabu, Jumat
+		ValidFrom:  "2025-01-01",
+		ValidTo:    "2025-01-31",
+	}
+	blackouts := []repositories.Blackout{
+		{Date: "2025-01-08", RouteID: 0}, // libur semua jalur
+		{Date: "2025-01-10", RouteID: 2}, // libur jalur lain
+	}
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+
+	cases := map[string]bool{
+		"2025-01-06": true,  // Senin
+		"2025-01-07": false, // Selasa
+		"2025-01-08": false, // Rabu, blackout global
+		"2025-01-10": true,  // Jumat, blackout jalur lain
+		"2025-01-12": false, // Minggu
+		"2025-02-03": false, // Senin, di luar masa berlaku
+	}
+	for date, want := range cases {
+		if got := scheduleRunsOn(sc, day(date), blackouts); got != want {
+			t.Fatalf("%s: expected %v, got %v", date, want, got)
+		}
+	}
+
+	sunday := repositories.Schedule{RouteID: 1, DaysOfWeek: []int{7}}
+	if !scheduleRunsOn(sunday, day("2025-01-12"), nil) {
+		t.Fatalf("ISO day 7 should match Sunday")
+	}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	ts := repositories.TripSlot{ID: 1, Capacity: 4}
+	state := SlotSeatState{
+		Booked: []string{"1A", "2A"},
+		Held:   []string{"2A", "3A"}, // 2A tercatat di dua segmen, dihitung sekali
+	}
+	if err := CheckCapacity(ts, state, 1); err != nil {
+		t.Fatalf("one seat left, expected ok, got %v", err)
+	}
+	if err := CheckCapacity(ts, state, 2); !domain.IsConflict(err) {
+		t.Fatalf("expected conflict when exceeding capacity, got %v", err)
+	}
+}
+
+func TestRouteDirection(t *testing.T) {
+	line := []string{"skpd", "bangkinang", "pekanbaru"}
+	if dir, ok := routeDirection(line, "skpd", "pekanbaru"); !ok || dir != repositories.DirectionForward {
+		t.Fatalf("expected forward, got %q %v", dir, ok)
+	}
+	if dir, ok := routeDirection(line, "pekanbaru", "bangkinang"); !ok || dir != repositories.DirectionReverse {
+		t.Fatalf("expected reverse, got %q %v", dir, ok)
+	}
+	if _, ok := routeDirection(line, "kuok", "pekanbaru"); ok {
+		t.Fatalf("stop outside line should not match")
+	}
+}
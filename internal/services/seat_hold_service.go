@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DefaultSeatHoldTTL dipakai bila SeatHoldService.TTL kosong.
+const DefaultSeatHoldTTL = 10 * time.Minute
+
+// SeatHoldService menahan seat selama checkout supaya dua customer tidak
+// bisa memilih seat yang sama sampai tahap submit booking.
+type SeatHoldService struct {
+	Holds     repositories.SeatHoldRepository
+	TTL       time.Duration
+	RequestID string
+	Now       func() time.Time
+}
+
+// SeatHoldResult dikembalikan ke client; HoldToken wajib dikirim saat create booking.
+type SeatHoldResult struct {
+	HoldToken string    `json:"holdToken"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Date      string    `json:"date"`
+	Time      string    `json:"time"`
+	Seats     []string  `json:"seats"`
+}
+
+// SlotSeatState memisahkan seat yang sudah dibooking, ditahan orang lain, dan ditahan oleh token sendiri.
+type SlotSeatState struct {
+	Booked  []string
+	Held    []string
+	OwnHeld []string
+}
+
+func (s SeatHoldService) ttl() time.Duration {
+	if s.TTL > 0 {
+		return s.TTL
+	}
+	return DefaultSeatHoldTTL
+}
+
+func (s SeatHoldService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Hold menahan seats di slot. Bila token diisi, hold lama dengan token itu diganti
+// (customer mengubah pilihan seat) dan token yang sama dipakai lagi.
+func (s SeatHoldService) Hold(userID int64, slot repositories.SeatSlot, seats []string, token string) (SeatHoldResult, error) {
+	if len(seats) == 0 {
+		return SeatHoldResult{}, domain.ValidationError{Field: "seats", Msg: "wajib pilih seat"}
+	}
+	now := s.now()
+	expiresAt := now.Add(s.ttl())
+
+	tx, err := s.Holds.Begin()
+	if err != nil {
+		return SeatHoldResult{}, domain.InternalError{Msg: "gagal membuka transaksi hold", Err: err}
+	}
+	defer tx.Rollback()
+
+	if err := s.Holds.DeleteExpiredForSlot(tx, slot, now); err != nil {
+		return SeatHoldResult{}, domain.InternalError{Msg: "gagal membersihkan hold", Err: err}
+	}
+
+	token = strings.TrimSpace(token)
+	if token != "" {
+		existing, err := s.Holds.ListByToken(tx, token, true)
+		if err != nil {
+			return SeatHoldResult{}, domain.InternalError{Msg: "gagal membaca hold", Err: err}
+		}
+		for _, h := range existing {
+			if h.UserID != 0 && h.UserID != userID {
+				return SeatHoldResult{}, domain.ForbiddenError{Msg: "hold bukan milik user ini"}
+			}
+		}
+		if _, err := s.Holds.DeleteByToken(tx, token); err != nil {
+			return SeatHoldResult{}, domain.InternalError{Msg: "gagal melepas hold lama", Err: err}
+		}
+	} else {
+		if token, err = randomToken(24); err != nil {
+			return SeatHoldResult{}, domain.InternalError{Msg: "gagal membuat hold token", Err: err}
+		}
+	}
+
+	if err := s.EnsureSeatsFreeTx(tx, slot, seats); err != nil {
+		return SeatHoldResult{}, err
+	}
+	// hold di segmen lain yang overlap tidak tertangkap unique key slot, cek eksplisit
+	others, err := s.Holds.ListForSlot(tx, slot, seats, now, true)
+	if err != nil {
+		return SeatHoldResult{}, domain.InternalError{Msg: "gagal membaca hold", Err: err}
+	}
+	if len(others) > 0 {
+		return SeatHoldResult{}, domain.ConflictError{Resource: "seat", Msg: "seat " + others[0].SeatCode + " sedang ditahan pelanggan lain"}
+	}
+
+	for _, seat := range seats {
+		err := s.Holds.Insert(tx, repositories.SeatHold{
+			Token: token, UserID: userID, Slot: slot, SeatCode: seat, ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			var me *mysql.MySQLError
+			if errors.As(err, &me) && me.Number == 1062 {
+				return SeatHoldResult{}, domain.ConflictError{Resource: "seat", Msg: "seat " + seat + " sedang ditahan pelanggan lain"}
+			}
+			return SeatHoldResult{}, domain.InternalError{Msg: "gagal menyimpan hold", Err: err}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return SeatHoldResult{}, domain.InternalError{Msg: "gagal commit hold", Err: err}
+	}
+
+	utils.LogEvent(s.RequestID, "seat_hold", "hold", fmt.Sprintf("user=%d slot=%s/%s/%s/%s seats=%s", userID, slot.From, slot.To, slot.Date, slot.Time, strings.Join(seats, ",")))
+	return SeatHoldResult{
+		HoldToken: token, ExpiresAt: expiresAt,
+		From: slot.From, To: slot.To, Date: slot.Date, Time: slot.Time,
+		Seats: seats,
+	}, nil
+}
+
+// Release melepas hold sebelum kedaluwarsa (customer batal checkout).
+func (s SeatHoldService) Release(userID int64, token string) error {
+	tx, err := s.Holds.Begin()
+	if err != nil {
+		return domain.InternalError{Msg: "gagal membuka transaksi hold", Err: err}
+	}
+	defer tx.Rollback()
+
+	holds, err := s.Holds.ListByToken(tx, token, true)
+	if err != nil {
+		return domain.InternalError{Msg: "gagal membaca hold", Err: err}
+	}
+	if len(holds) == 0 {
+		return domain.NotFoundError{Resource: "hold"}
+	}
+	for _, h := range holds {
+		if h.UserID != 0 && h.UserID != userID {
+			return domain.ForbiddenError{Msg: "hold bukan milik user ini"}
+		}
+	}
+	if _, err := s.Holds.DeleteByToken(tx, token); err != nil {
+		return domain.InternalError{Msg: "gagal melepas hold", Err: err}
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.InternalError{Msg: "gagal commit hold", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "seat_hold", "release", fmt.Sprintf("user=%d seats=%d", userID, len(holds)))
+	return nil
+}
+
+// SlotSeats mengembalikan status seat di slot. ownToken (opsional) memisahkan hold milik pemanggil.
+func (s SeatHoldService) SlotSeats(q *sql.DB, slot repositories.SeatSlot, ownToken string) (SlotSeatState, error) {
+	state := SlotSeatState{Booked: []string{}, Held: []string{}, OwnHeld: []string{}}
+
+	booked, err := s.Holds.BookedSeats(q, slot, nil, false)
+	if err != nil {
+		return state, domain.InternalError{Msg: "gagal mengambil seat", Err: err}
+	}
+	state.Booked = booked
+
+	// sebelum migration seat_holds dijalankan, semua seat dianggap tidak ditahan
+	if s.Holds.Ready(q) != nil {
+		return state, nil
+	}
+	holds, err := s.Holds.ListForSlot(q, slot, nil, s.now(), false)
+	if err != nil {
+		return state, domain.InternalError{Msg: "gagal mengambil hold", Err: err}
+	}
+	for _, h := range holds {
+		if ownToken != "" && h.Token == ownToken {
+			state.OwnHeld = append(state.OwnHeld, h.SeatCode)
+		} else {
+			state.Held = append(state.Held, h.SeatCode)
+		}
+	}
+	return state, nil
+}
+
+// EnsureSeatsFreeTx menolak seat yang sudah dibooking di slot atau segmen yang overlap.
+// Baris booking_seats dikunci sampai transaksi selesai.
+func (s SeatHoldService) EnsureSeatsFreeTx(tx *sql.Tx, slot repositories.SeatSlot, seats []string) error {
+	booked, err := s.Holds.BookedSeats(tx, slot, seats, true)
+	if err != nil {
+		return domain.InternalError{Msg: "gagal cek seat", Err: err}
+	}
+	if len(booked) > 0 {
+		return domain.ConflictError{Resource: "seat", Msg: "seat sudah dibooking: " + strings.Join(booked, ", ")}
+	}
+	return nil
+}
+
+// ConsumeTx dipanggil di dalam transaksi create booking: seat yang ditahan pihak lain
+// ditolak, dan hold milik token dilepas karena seat-nya kini tercatat di booking_seats.
+func (s SeatHoldService) ConsumeTx(tx *sql.Tx, userID int64, token string, slot repositories.SeatSlot, seats []string) error {
+	token = strings.TrimSpace(token)
+	if !intdb.HasTable(tx, "seat_holds") {
+		if token != "" {
+			return domain.ValidationError{Field: "holdToken", Msg: "fitur hold seat belum aktif"}
+		}
+		return nil
+	}
+	now := s.now()
+
+	holds, err := s.Holds.ListForSlot(tx, slot, seats, time.Time{}, true)
+	if err != nil {
+		return domain.InternalError{Msg: "gagal membaca hold", Err: err}
+	}
+	covered := map[string]bool{}
+	for _, h := range holds {
+		if !h.ExpiresAt.After(now) {
+			continue
+		}
+		if h.Token != token {
+			return domain.ConflictError{Resource: "seat", Msg: "seat " + h.SeatCode + " sedang ditahan pelanggan lain"}
+		}
+		if h.UserID != 0 && h.UserID != userID {
+			return domain.ForbiddenError{Msg: "hold bukan milik user ini"}
+		}
+		covered[h.SeatCode] = true
+	}
+	if token != "" {
+		for _, seat := range seats {
+			if !covered[seat] {
+				return domain.ConflictError{Resource: "hold", Msg: "hold seat " + seat + " sudah kedaluwarsa, silakan pilih ulang seat"}
+			}
+		}
+		if _, err := s.Holds.DeleteByToken(tx, token); err != nil {
+			return domain.InternalError{Msg: "gagal melepas hold", Err: err}
+		}
+	}
+	if err := s.Holds.DeleteExpiredForSlot(tx, slot, now); err != nil {
+		return domain.InternalError{Msg: "gagal membersihkan hold", Err: err}
+	}
+	return nil
+}
+
+// Sweep melepas semua hold yang sudah kedaluwarsa.
+func (s SeatHoldService) Sweep() (int64, error) {
+	return s.Holds.DeleteExpired(s.now())
+}
+
+// RunSweeper menjalankan Sweep berkala sampai ctx dibatalkan.
+func (s SeatHoldService) RunSweeper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Sweep()
+			if err != nil {
+				log.Printf("[SEAT_HOLD] sweeper error: %v", err)
+				continue
+			}
+			if n > 0 {
+				utils.LogEvent("", "seat_hold", "sweep", fmt.Sprintf("released=%d", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+	"backend/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var holdCols = []string{"id", "hold_token", "user_id", "route_from", "route_to", "trip_date", "trip_time", "seat_code", "expires_at"}
+
+func expectSeatHoldSchema(mock sqlmock.Sqlmock) {
+	intdb.Schema.Invalidate()
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).
+			AddRow("seat_holds", "id").
+			AddRow("booking_seats", "id"))
+}
+
+func TestSeatHoldConsumeRejectsSeatHeldByOthers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	slot := repositories.SeatSlot{From: "SKPD", To: "Pekanbaru", Date: "2025-01-02", Time: "08:00"}
+	svc := SeatHoldService{Now: func() time.Time { return now }}
+
+	mock.ExpectBegin()
+	expectSeatHoldSchema(mock)
+	mock.ExpectQuery("FROM seat_holds WHERE route_from = \\?").
+		WillReturnRows(sqlmock.NewRows(holdCols).
+			// hold kedaluwarsa milik orang lain tidak menghalangi
+			AddRow(int64(1), "tok-old", int64(5), slot.From, slot.To, slot.Date, slot.Time, "1A", now.Add(-time.Minute)).
+			AddRow(int64(2), "tok-other", int64(5), slot.From, slot.To, slot.Date, slot.Time, "2A", now.Add(5*time.Minute)))
+	mock.ExpectRollback()
+
+	tx, _ := db.Begin()
+	err = svc.ConsumeTx(tx, 9, "", slot, []string{"1A", "2A"})
+	_ = tx.Rollback()
+	if !domain.IsConflict(err) {
+		t.Fatalf("expected conflict for seat held by another customer, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSeatHoldConsumeReleasesOwnHold(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	slot := repositories.SeatSlot{From: "SKPD", To: "Pekanbaru", Date: "2025-01-02", Time: "08:00"}
+	svc := SeatHoldService{Now: func() time.Time { return now }}
+
+	mock.ExpectBegin()
+	expectSeatHoldSchema(mock)
+	mock.ExpectQuery("FROM seat_holds WHERE route_from = \\?").
+		WillReturnRows(sqlmock.NewRows(holdCols).
+			AddRow(int64(3), "tok-mine", int64(9), slot.From, slot.To, slot.Date, slot.Time, "2A", now.Add(5*time.Minute)))
+	mock.ExpectExec("DELETE FROM seat_holds WHERE hold_token = \\?").
+		WithArgs("tok-mine").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM seat_holds WHERE route_from = \\?").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	tx, _ := db.Begin()
+	if err := svc.ConsumeTx(tx, 9, "tok-mine", slot, []string{"2A"}); err != nil {
+		t.Fatalf("consume own hold: %v", err)
+	}
+	_ = tx.Commit()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
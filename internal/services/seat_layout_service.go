@@ -0,0 +1,285 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Status kursi pada seat map yang dikirim ke seat picker.
+const (
+	SeatAvailable = "available"
+	SeatBooked    = "booked"
+	SeatHeld      = "held"
+	SeatMine      = "mine"
+	SeatDisabled  = "disabled"
+)
+
+// SeatLayoutService mengelola denah kursi per tipe kendaraan dan validasi seat booking.
+type SeatLayoutService struct {
+	Layouts   repositories.SeatLayoutRepository
+	RequestID string
+}
+
+type SeatMapSeat struct {
+	Code   string `json:"code"`
+	Row    int    `json:"row"`
+	Col    int    `json:"col"`
+	Status string `json:"status"`
+}
+
+// SeatMap adalah denah lengkap + ketersediaan untuk satu slot.
+type SeatMap struct {
+	LayoutID    int64         `json:"layoutId"`
+	VehicleType string        `json:"vehicleType"`
+	Name        string        `json:"name"`
+	Rows        int           `json:"rows"`
+	Cols        int           `json:"cols"`
+	DriverRow   int           `json:"driverRow"`
+	DriverCol   int           `json:"driverCol"`
+	Capacity    int           `json:"capacity"`
+	Available   int           `json:"available"`
+	Seats       []SeatMapSeat `json:"seats"`
+}
+
+// LayoutForSlot memilih denah kendaraan slot (tipe dari trip slot, atau kendaraan yang
+// ditugaskan di departure_settings), atau denah default.
+// ok=false bila migration seat_layouts belum dijalankan / belum ada denah sama sekali.
+func (s SeatLayoutService) LayoutForSlot(slot repositories.SeatSlot) (repositories.SeatLayout, bool, error) {
+	if !s.Layouts.Available() {
+		return repositories.SeatLayout{}, false, nil
+	}
+	vehicleType := slot.VehicleType
+	if vehicleType == "" {
+		vt, err := s.Layouts.SlotVehicleType(slot)
+		if err != nil {
+			return repositories.SeatLayout{}, false, domain.InternalError{Msg: "gagal membaca kendaraan slot", Err: err}
+		}
+		vehicleType = vt
+	}
+	if vehicleType != "" {
+		layout, err := s.Layouts.GetByVehicleType(vehicleType)
+		if err == nil {
+			return layout, true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return repositories.SeatLayout{}, false, domain.InternalError{Msg: "gagal membaca seat layout", Err: err}
+		}
+		utils.LogEvent(s.RequestID, "seat_layout", "fallback_default", "vehicle_type tanpa denah: "+vehicleType)
+	}
+	layout, err := s.Layouts.GetByVehicleType("")
+	if errors.Is(err, sql.ErrNoRows) {
+		return repositories.SeatLayout{}, false, nil
+	}
+	if err != nil {
+		return repositories.SeatLayout{}, false, domain.InternalError{Msg: "gagal membaca seat layout", Err: err}
+	}
+	return layout, true, nil
+}
+
+// ValidateSeatCodes menolak seat yang tidak ada di denah atau sedang dinonaktifkan.
+func ValidateSeatCodes(layout repositories.SeatLayout, seats []string) error {
+	valid := map[string]bool{}
+	for _, cell := range layout.Seats {
+		valid[strings.ToUpper(cell.Code)] = true
+	}
+	for _, d := range layout.DisabledSeats {
+		delete(valid, strings.ToUpper(d))
+	}
+	for _, seat := range seats {
+		if !valid[strings.ToUpper(strings.TrimSpace(seat))] {
+			return domain.ValidationError{Field: "selectedSeats", Msg: fmt.Sprintf("Seat %s tidak tersedia di kendaraan ini", seat)}
+		}
+	}
+	return nil
+}
+
+// BuildSeatMap menggabungkan denah dengan status booked/held slot.
+func BuildSeatMap(layout repositories.SeatLayout, state SlotSeatState) SeatMap {
+	status := map[string]string{}
+	for _, d := range layout.DisabledSeats {
+		status[strings.ToUpper(d)] = SeatDisabled
+	}
+	mark := func(list []string, st string) {
+		for _, code := range list {
+			code = strings.ToUpper(code)
+			if status[code] == "" {
+				status[code] = st
+			}
+		}
+	}
+	mark(state.Booked, SeatBooked)
+	mark(state.Held, SeatHeld)
+	mark(state.OwnHeld, SeatMine)
+
+	out := SeatMap{
+		LayoutID: layout.ID, VehicleType: layout.VehicleType, Name: layout.Name,
+		Rows: layout.Rows, Cols: layout.Cols,
+		DriverRow: layout.DriverRow, DriverCol: layout.DriverCol,
+		Capacity: layout.Capacity,
+		Seats:    make([]SeatMapSeat, 0, len(layout.Seats)),
+	}
+	for _, cell := range layout.Seats {
+		st := status[strings.ToUpper(cell.Code)]
+		if st == "" {
+			st = SeatAvailable
+		}
+		if st == SeatAvailable || st == SeatMine {
+			out.Available++
+		}
+		out.Seats = append(out.Seats, SeatMapSeat{Code: cell.Code, Row: cell.Row, Col: cell.Col, Status: st})
+	}
+	sort.SliceStable(out.Seats, func(i, j int) bool {
+		if out.Seats[i].Row != out.Seats[j].Row {
+			return out.Seats[i].Row < out.Seats[j].Row
+		}
+		return out.Seats[i].Col < out.Seats[j].Col
+	})
+	return out
+}
+
+// ===== admin CRUD =====
+
+func (s SeatLayoutService) List() ([]repositories.SeatLayout, error) {
+	list, err := s.Layouts.List()
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat seat layouts", Err: err}
+	}
+	return list, nil
+}
+
+func (s SeatLayoutService) Get(id int64) (repositories.SeatLayout, error) {
+	l, err := s.Layouts.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return l, domain.NotFoundError{Resource: "seat layout"}
+	}
+	if err != nil {
+		return l, domain.InternalError{Msg: "gagal memuat seat layout", Err: err}
+	}
+	return l, nil
+}
+
+func (s SeatLayoutService) Create(in repositories.SeatLayout) (repositories.SeatLayout, error) {
+	in, err := cleanSeatLayout(in)
+	if err != nil {
+		return in, err
+	}
+	id, err := s.Layouts.Create(in)
+	if err != nil {
+		return in, seatLayoutWriteError(err)
+	}
+	in.ID = id
+	utils.LogEvent(s.RequestID, "seat_layout", "create", fmt.Sprintf("id=%d vehicle_type=%s", id, in.VehicleType))
+	return in, nil
+}
+
+func (s SeatLayoutService) Update(id int64, in repositories.SeatLayout) (repositories.SeatLayout, error) {
+	if _, err := s.Get(id); err != nil {
+		return in, err
+	}
+	in, err := cleanSeatLayout(in)
+	if err != nil {
+		return in, err
+	}
+	in.ID = id
+	if err := s.Layouts.Update(in); err != nil {
+		return in, seatLayoutWriteError(err)
+	}
+	utils.LogEvent(s.RequestID, "seat_layout", "update", fmt.Sprintf("id=%d vehicle_type=%s", id, in.VehicleType))
+	return in, nil
+}
+
+func (s SeatLayoutService) Delete(id int64) error {
+	current, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if current.IsDefault {
+		return domain.ConflictError{Resource: "seat layout", Msg: "denah default tidak bisa dihapus, jadikan denah lain default dulu"}
+	}
+	if err := s.Layouts.Delete(id); err != nil {
+		return domain.InternalError{Msg: "gagal hapus seat layout", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "seat_layout", "delete", fmt.Sprintf("id=%d vehicle_type=%s", id, current.VehicleType))
+	return nil
+}
+
+func seatLayoutWriteError(err error) error {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == 1062 {
+		return domain.ConflictError{Resource: "seat layout", Msg: "vehicleType sudah punya denah"}
+	}
+	return domain.InternalError{Msg: "gagal simpan seat layout", Err: err}
+}
+
+func cleanSeatLayout(in repositories.SeatLayout) (repositories.SeatLayout, error) {
+	in.VehicleType = strings.ToLower(strings.TrimSpace(in.VehicleType))
+	in.Name = strings.TrimSpace(in.Name)
+	if in.VehicleType == "" {
+		return in, domain.ValidationError{Field: "vehicleType", Msg: "wajib diisi"}
+	}
+	if in.Name == "" {
+		in.Name = in.VehicleType
+	}
+	if in.Rows <= 0 || in.Cols <= 0 {
+		return in, domain.ValidationError{Field: "rows", Msg: "rows dan cols harus > 0"}
+	}
+	if len(in.Seats) == 0 {
+		return in, domain.ValidationError{Field: "seats", Msg: "minimal satu kursi"}
+	}
+	if in.DriverRow < 1 || in.DriverRow > in.Rows || in.DriverCol < 1 || in.DriverCol > in.Cols {
+		return in, domain.ValidationError{Field: "driverRow", Msg: "posisi sopir di luar grid"}
+	}
+
+	codes := map[string]bool{}
+	cells := map[[2]int]bool{{in.DriverRow, in.DriverCol}: true}
+	for i, cell := range in.Seats {
+		code := strings.ToUpper(strings.TrimSpace(cell.Code))
+		if code == "" || strings.Contains(code, ",") {
+			return in, domain.ValidationError{Field: "seats", Msg: fmt.Sprintf("kode kursi ke-%d tidak valid", i+1)}
+		}
+		if codes[code] {
+			return in, domain.ValidationError{Field: "seats", Msg: "kode kursi duplikat: " + code}
+		}
+		if cell.Row < 1 || cell.Row > in.Rows || cell.Col < 1 || cell.Col > in.Cols {
+			return in, domain.ValidationError{Field: "seats", Msg: "kursi " + code + " di luar grid"}
+		}
+		pos := [2]int{cell.Row, cell.Col}
+		if cells[pos] {
+			return in, domain.ValidationError{Field: "seats", Msg: "kursi " + code + " menimpa posisi lain"}
+		}
+		codes[code] = true
+		cells[pos] = true
+		in.Seats[i].Code = code
+	}
+
+	disabled := make([]string, 0, len(in.DisabledSeats))
+	for _, d := range in.DisabledSeats {
+		d = strings.ToUpper(strings.TrimSpace(d))
+		if d == "" {
+			continue
+		}
+		if !codes[d] {
+			return in, domain.ValidationError{Field: "disabledSeats", Msg: "kursi " + d + " tidak ada di denah"}
+		}
+		disabled = append(disabled, d)
+	}
+	in.DisabledSeats = disabled
+
+	usable := len(in.Seats) - len(disabled)
+	if in.Capacity <= 0 {
+		in.Capacity = usable
+	}
+	if in.Capacity > usable {
+		return in, domain.ValidationError{Field: "capacity", Msg: fmt.Sprintf("maksimal %d (kursi aktif)", usable)}
+	}
+	return in, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+)
+
+func testSeatLayout() repositories.SeatLayout {
+	return repositories.SeatLayout{
+		ID: 1, VehicleType: "reguler", Name: "Reguler",
+		Rows: 3, Cols: 3, DriverRow: 1, DriverCol: 3,
+		Seats: []repositories.SeatCell{
+			{Code: "3C", Row: 3, Col: 3},
+			{Code: "1A", Row: 1, Col: 1},
+			{Code: "2A", Row: 2, Col: 1},
+			{Code: "2B", Row: 2, Col: 3},
+			{Code: "3A", Row: 3, Col: 1},
+			{Code: "3B", Row: 3, Col: 2},
+		},
+		DisabledSeats: []string{"3B"},
+		Capacity:      5,
+	}
+}
+
+func TestValidateSeatCodes(t *testing.T) {
+	layout := testSeatLayout()
+
+	if err := ValidateSeatCodes(layout, []string{"1a", "2B"}); err != nil {
+		t.Fatalf("seat ada di denah harus lolos, got %v", err)
+	}
+	for _, seats := range [][]string{{"4A"}, {"1A", "3B"}} {
+		if err := ValidateSeatCodes(layout, seats); !domain.IsValidation(err) {
+			t.Fatalf("seats %v: expected validation error, got %v", seats, err)
+		}
+	}
+}
+
+func TestBuildSeatMapStatuses(t *testing.T) {
+	m := BuildSeatMap(testSeatLayout(), SlotSeatState{
+		Booked:  []string{"1A", "3B"},
+		Held:    []string{"2A"},
+		OwnHeld: []string{"2B"},
+	})
+
+	want := map[string]string{
+		"1A": SeatBooked, "2A": SeatHeld, "2B": SeatMine,
+		"3A": SeatAvailable, "3B": SeatDisabled, "3C": SeatAvailable,
+	}
+	if len(m.Seats) != len(want) {
+		t.Fatalf("expected %d seats, got %d", len(want), len(m.Seats))
+	}
+	for _, s := range m.Seats {
+		if s.Status != want[s.Code] {
+			t.Fatalf("seat %s: expected %s, got %s", s.Code, want[s.Code], s.Status)
+		}
+	}
+	if m.Seats[0].Code != "1A" || m.Seats[len(m.Seats)-1].Code != "3C" {
+		t.Fatalf("seats harus urut row/col, got %+v", m.Seats)
+	}
+	// 3A, 3C bebas + 2B milik sendiri
+	if m.Available != 3 {
+		t.Fatalf("expected 3 available, got %d", m.Available)
+	}
+}
+
+func TestCleanSeatLayoutRejectsInvalidGrid(t *testing.T) {
+	cases := map[string]func(l *repositories.SeatLayout){
+		"seat outside grid":   func(l *repositories.SeatLayout) { l.Seats[0].Row = 4 },
+		"seat on driver":      func(l *repositories.SeatLayout) { l.Seats[0] = repositories.SeatCell{Code: "X", Row: 1, Col: 3} },
+		"duplicate code":      func(l *repositories.SeatLayout) { l.Seats[1].Code = "3c" },
+		"unknown disabled":    func(l *repositories.SeatLayout) { l.DisabledSeats = []string{"9Z"} },
+		"capacity over seats": func(l *repositories.SeatLayout) { l.Capacity = 6 },
+	}
+	for name, mutate := range cases {
+		l := testSeatLayout()
+		mutate(&l)
+		if _, err := cleanSeatLayout(l); !domain.IsValidation(err) {
+			t.Fatalf("%s: expected validation error, got %v", name, err)
+		}
+	}
+
+	l := testSeatLayout()
+	l.VehicleType, l.Capacity = " Reguler ", 0
+	got, err := cleanSeatLayout(l)
+	if err != nil {
+		t.Fatalf("valid layout: %v", err)
+	}
+	if got.VehicleType != "reguler" || got.Capacity != 5 {
+		t.Fatalf("expected normalized type and capacity 5, got %q %d", got.VehicleType, got.Capacity)
+	}
+}
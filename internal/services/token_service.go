@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenService menerbitkan dan memverifikasi access token HS256 untuk /api.
+type TokenService struct {
+	Secret []byte
+	TTL    time.Duration
+	Now    func() time.Time
+}
+
+type accessClaims struct {
+	UserID    int64  `json:"user_id"`
+	Name      string `json:"name,omitempty"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (s TokenService) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s TokenService) ttl() time.Duration {
+	if s.TTL > 0 {
+		return s.TTL
+	}
+	return 24 * time.Hour
+}
+
+// Issue membuat access token untuk user; role dinormalisasi ke admin/driver/customer.
+// sessionID mengikat token ke family refresh token di user_sessions (boleh kosong).
+func (s TokenService) Issue(userID int64, name, role, sessionID string) (string, time.Time, error) {
+	if len(s.Secret) == 0 {
+		return "", time.Time{}, domain.InternalError{Msg: "jwt secret belum diatur"}
+	}
+	if userID <= 0 {
+		return "", time.Time{}, domain.ValidationError{Field: "user_id", Msg: "id tidak valid"}
+	}
+
+	now := s.now()
+	exp := now.Add(s.ttl())
+	claims := accessClaims{
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Role:      domain.NormalizeRole(role),
+		SessionID: strings.TrimSpace(sessionID),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   fmt.Sprintf("%d", userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.Secret)
+	if err != nil {
+		return "", time.Time{}, domain.InternalError{Msg: "gagal membuat token", Err: err}
+	}
+	return signed, exp, nil
+}
+
+// Parse memverifikasi token dan mengembalikan identitas user.
+func (s TokenService) Parse(tokenString string) (domain.RequestContext, error) {
+	tokenString = strings.TrimSpace(tokenString)
+	if tokenString == "" {
+		return domain.RequestContext{}, domain.UnauthorizedError{Msg: "token tidak ditemukan"}
+	}
+	if len(s.Secret) == 0 {
+		return domain.RequestContext{}, domain.InternalError{Msg: "jwt secret belum diatur"}
+	}
+
+	claims := accessClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (any, error) {
+		return s.Secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(s.now),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return domain.RequestContext{}, domain.UnauthorizedError{Msg: "token kedaluwarsa", Err: err}
+		}
+		return domain.RequestContext{}, domain.UnauthorizedError{Msg: "token tidak valid", Err: err}
+	}
+	if claims.UserID <= 0 {
+		return domain.RequestContext{}, domain.UnauthorizedError{Msg: "token tidak valid"}
+	}
+
+	return domain.RequestContext{
+		UserID:    domain.ID(claims.UserID),
+		Name:      claims.Name,
+		Role:      domain.NormalizeRole(claims.Role),
+		SessionID: claims.SessionID,
+	}, nil
+}
+
+// Authenticate hanya memverifikasi tanda tangan dan masa berlaku token.
+func (s TokenService) Authenticate(tokenString string) (domain.RequestContext, error) {
+	return s.Parse(tokenString)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/domain"
+)
+
+func TestTokenServiceIssueAndParse(t *testing.T) {
+	svc := TokenService{Secret: []byte("test-secret"), TTL: time.Hour}
+
+	token, exp, err := svc.Issue(7, "Budi", "user", "fam-1")
+	if err != nil {
+		t.Fatalf("issue error: %v", err)
+	}
+	if time.Until(exp) <= 0 {
+		t.Fatalf("expiry should be in the future, got %v", exp)
+	}
+
+	rc, err := svc.Parse(token)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if rc.UserID != 7 || rc.Name != "Budi" {
+		t.Fatalf("unexpected identity: %+v", rc)
+	}
+	if rc.SessionID != "fam-1" {
+		t.Fatalf("session id not carried in token, got %q", rc.SessionID)
+	}
+	if rc.Role != domain.RoleCustomer {
+		t.Fatalf("legacy role 'user' should map to customer, got %q", rc.Role)
+	}
+}
+
+func TestTokenServiceRejectsExpiredAndForeignTokens(t *testing.T) {
+	issuedAt := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	svc := TokenService{
+		Secret: []byte("test-secret"),
+		TTL:    time.Minute,
+		Now:    func() time.Time { return issuedAt },
+	}
+	token, _, err := svc.Issue(1, "Admin", "admin", "")
+	if err != nil {
+		t.Fatalf("issue error: %v", err)
+	}
+
+	later := svc
+	later.Now = func() time.Time { return issuedAt.Add(2 * time.Minute) }
+	if _, err := later.Parse(token); !domain.IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized for expired token, got %v", err)
+	}
+
+	other := svc
+	other.Secret = []byte("other-secret")
+	if _, err := other.Parse(token); !domain.IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized for foreign secret, got %v", err)
+	}
+}
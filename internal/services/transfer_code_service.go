@@ -0,0 +1,114 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+)
+
+// MaxTransferCode adalah kode unik transfer terbesar (3 digit).
+const MaxTransferCode = 999
+
+// transferCodeAttempts membatasi percobaan ulang bila kode yang dipilih keduluan booking lain.
+const transferCodeAttempts = 3
+
+// PickTransferCode memilih kode 1..MaxTransferCode yang belum dipakai, mulai dari posisi acak
+// supaya booking berurutan tidak mendapat kode berurutan. ok=false bila semua kode terpakai.
+func PickTransferCode(used map[int64]bool, intn func(n int) int) (int64, bool) {
+	start := 0
+	if intn != nil {
+		start = intn(MaxTransferCode)
+	}
+	for i := 0; i < MaxTransferCode; i++ {
+		code := int64((start+i)%MaxTransferCode) + 1
+		if !used[code] {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// TransferCodeService memberi booking transfer kode unik 3 digit yang ditambahkan ke nominal
+// transfer, sehingga dua booking dengan total sama tetap punya nominal transfer berbeda.
+type TransferCodeService struct {
+	Bookings  repositories.BookingRepository
+	Intn      func(n int) int
+	RequestID string
+}
+
+func (s TransferCodeService) intn(n int) int {
+	if s.Intn != nil {
+		return s.Intn(n)
+	}
+	return rand.Intn(n)
+}
+
+// Available melaporkan apakah migration transfer_codes sudah dijalankan.
+func (s TransferCodeService) Available() bool {
+	return s.Bookings.TransferCodesAvailable()
+}
+
+// AssignTx memberi kode unik untuk booking dengan nominal dasar base. Mengembalikan 0 tanpa error
+// bila migration belum dijalankan atau semua kode untuk nominal itu sedang terpakai; booking tetap
+// dibuat dan transfernya dicocokkan manual.
+func (s TransferCodeService) AssignTx(tx *sql.Tx, bookingID, base int64) (int64, error) {
+	if !s.Available() || base <= 0 {
+		return 0, nil
+	}
+	for attempt := 0; attempt < transferCodeAttempts; attempt++ {
+		used, err := s.Bookings.UsedTransferCodesTx(tx, base)
+		if err != nil {
+			return 0, domain.InternalError{Msg: "gagal membaca kode unik transfer", Err: err}
+		}
+		code, ok := PickTransferCode(used, s.intn)
+		if !ok {
+			break
+		}
+		taken, err := s.Bookings.ReserveTransferCodeTx(tx, bookingID, base, code)
+		if err != nil {
+			return 0, domain.InternalError{Msg: "gagal menyimpan kode unik transfer", Err: err}
+		}
+		if !taken {
+			utils.LogEvent(s.RequestID, "payment", "transfer_code", fmt.Sprintf("booking_id=%d code=%d", bookingID, code))
+			return code, nil
+		}
+	}
+	utils.LogEvent(s.RequestID, "payment", "transfer_code", fmt.Sprintf("booking_id=%d kode unik habis untuk nominal %d", bookingID, base))
+	return 0, nil
+}
+
+// RebaseTx memindahkan reservasi kode unik booking ke nominal dasar base (sisa tagihan terbaru)
+// setelah DP, pelunasan sebagian atau reschedule, supaya nominal transfer base+kode tidak bentrok
+// dengan booking lain. Kode lama dipakai lagi bila nominal barunya masih bebas. Booking tanpa
+// reservasi aktif dibiarkan; base 0 hanya melepas reservasinya.
+func (s TransferCodeService) RebaseTx(tx *sql.Tx, bookingID, base int64) (int64, error) {
+	if !s.Available() {
+		return 0, nil
+	}
+	amount, code, err := s.Bookings.ReservedTransferCodeTx(tx, bookingID)
+	if err != nil {
+		return 0, domain.InternalError{Msg: "gagal membaca kode unik transfer", Err: err}
+	}
+	if code == 0 || amount == base+code {
+		return code, nil
+	}
+	if err := s.Bookings.ReleaseTransferCodeTx(tx, bookingID); err != nil {
+		return 0, domain.InternalError{Msg: "gagal melepas kode unik transfer", Err: err}
+	}
+	if base <= 0 {
+		return 0, nil
+	}
+	taken, err := s.Bookings.ReserveTransferCodeTx(tx, bookingID, base, code)
+	if err != nil {
+		return 0, domain.InternalError{Msg: "gagal menyimpan kode unik transfer", Err: err}
+	}
+	if !taken {
+		utils.LogEvent(s.RequestID, "payment", "transfer_code", fmt.Sprintf("booking_id=%d code=%d nominal %d -> %d", bookingID, code, amount, base+code))
+		return code, nil
+	}
+	return s.AssignTx(tx, bookingID, base)
+}
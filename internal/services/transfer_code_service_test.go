@@ -0,0 +1,116 @@
+package services
+
+import (
+	"testing"
+
+	intdb "backend/internal/db"
+	"backend/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestPickTransferCode(t *testing.T) {
+	used := map[int64]bool{5: true, 6: true}
+	if code, ok := PickTransferCode(used, func(int) int { return 4 }); !ok || code != 7 {
+		t.Fatalf("expected next free code 7, got %d %v", code, ok)
+	}
+	if code, ok := PickTransferCode(map[int64]bool{999: true}, func(int) int { return 998 }); !ok || code != 1 {
+		t.Fatalf("expected wrap around to 1, got %d %v", code, ok)
+	}
+	all := map[int64]bool{}
+	for i := int64(1); i <= MaxTransferCode; i++ {
+		all[i] = true
+	}
+	if _, ok := PickTransferCode(all, nil); ok {
+		t.Fatalf("expected no code when all codes are used")
+	}
+}
+
+func TestTransferCodeAssignRetriesTakenAmount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	svc := TransferCodeService{Bookings: repositories.BookingRepository{DB: db}, Intn: func(int) int { return 0 }}
+
+	mock.ExpectBegin()
+	intdb.Schema.Invalidate()
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).
+			AddRow("transfer_codes", "amount").
+			AddRow("bookings", "transfer_code"))
+	mock.ExpectQuery("SELECT amount FROM transfer_codes").WithArgs(int64(150001), int64(150999)).
+		WillReturnRows(sqlmock.NewRows([]string{"amount"}))
+	// kode 1 keduluan booking lain di antara SELECT dan INSERT
+	mock.ExpectExec("INSERT INTO transfer_codes").WithArgs(int64(150001), int64(10), int64(1)).
+		WillReturnError(&mysql.MySQLError{Number: 1062})
+	mock.ExpectQuery("SELECT amount FROM transfer_codes").
+		WillReturnRows(sqlmock.NewRows([]string{"amount"}).AddRow(int64(150001)))
+	mock.ExpectExec("INSERT INTO transfer_codes").WithArgs(int64(150002), int64(10), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE bookings SET transfer_code").WithArgs(int64(2), int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, _ := db.Begin()
+	code, err := svc.AssignTx(tx, 10, 150000)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_ = tx.Commit()
+	if code != 2 {
+		t.Fatalf("expected code 2, got %d", code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestTransferCodeRebaseAfterDownPayment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock init error: %v", err)
+	}
+	defer db.Close()
+	defer intdb.Schema.Invalidate()
+
+	svc := TransferCodeService{Bookings: repositories.BookingRepository{DB: db}, Intn: func(int) int { return 0 }}
+
+	mock.ExpectBegin()
+	intdb.Schema.Invalidate()
+	mock.ExpectQuery("FROM information_schema.columns").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name"}).
+			AddRow("transfer_codes", "amount").
+			AddRow("bookings", "transfer_code"))
+	mock.ExpectQuery("SELECT amount, code FROM transfer_codes WHERE booking_id").WithArgs(int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"amount", "code"}).AddRow(int64(150007), int64(7)))
+	mock.ExpectExec("DELETE FROM transfer_codes WHERE booking_id").WithArgs(int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// sisa tagihan + kode lama sudah dipakai booking lain, jadi kode baru dipilih
+	mock.ExpectExec("INSERT INTO transfer_codes").WithArgs(int64(100007), int64(10), int64(7)).
+		WillReturnError(&mysql.MySQLError{Number: 1062})
+	mock.ExpectQuery("SELECT amount FROM transfer_codes").WithArgs(int64(100001), int64(100999)).
+		WillReturnRows(sqlmock.NewRows([]string{"amount"}).AddRow(int64(100007)))
+	mock.ExpectExec("INSERT INTO transfer_codes").WithArgs(int64(100001), int64(10), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE bookings SET transfer_code").WithArgs(int64(1), int64(10)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, _ := db.Begin()
+	code, err := svc.RebaseTx(tx, 10, 100000)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_ = tx.Commit()
+	if code != 1 {
+		t.Fatalf("expected code 1, got %d", code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
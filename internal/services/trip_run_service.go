@@ -0,0 +1,634 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	intconfig "backend/internal/config"
+	intdb "backend/internal/db"
+	"backend/internal/domain"
+	"backend/internal/domain/models"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+)
+
+// TripRunInput adalah payload pembuatan run. TripSlotID opsional: tanggal, jam dan tipe
+// kendaraan yang kosong diisi dari trip slot.
+type TripRunInput struct {
+	TripSlotID  int64
+	RouteFrom   string
+	RouteTo     string
+	TripDate    string
+	TripTime    string
+	TripNumber  string
+	DriverName  string
+	VehicleCode string
+	VehicleType string
+	Note        string
+	BookingIDs  []int64
+}
+
+// TripRunAssignment mengubah sebagian data run; nil = tidak diubah.
+type TripRunAssignment struct {
+	TripDate    *string
+	TripTime    *string
+	TripNumber  *string
+	DriverName  *string
+	VehicleCode *string
+	VehicleType *string
+	Note        *string
+	// Force tetap menyimpan walau sopir/kendaraan bentrok dengan penugasan lain.
+	Force bool
+}
+
+// TripRunBooking adalah ringkasan booking di dalam run.
+type TripRunBooking struct {
+	BookingID      int64                `json:"bookingId"`
+	PassengerName  string               `json:"passengerName"`
+	PassengerPhone string               `json:"passengerPhone"`
+	PassengerCount int                  `json:"passengerCount"`
+	PickupLocation string               `json:"pickupLocation"`
+	Status         domain.BookingStatus `json:"status"`
+	PaymentStatus  string               `json:"paymentStatus"`
+}
+
+// TripRunDetail adalah run beserta booking-nya.
+type TripRunDetail struct {
+	repositories.TripRun
+	Bookings []TripRunBooking `json:"bookings"`
+}
+
+// TripRunService mengelompokkan booking ke satu perjalanan armada. Sopir & kendaraan di-assign
+// sekali di run lalu disalin ke departure_settings / return_settings tiap booking, dan
+// berangkat/pulang diterapkan ke seluruh booking run lewat DepartureService/ReturnService.
+type TripRunService struct {
+	Runs       repositories.TripRunRepository
+	Schedules  repositories.ScheduleRepository
+	Bookings   repositories.BookingRepository
+	Departures DepartureService
+	Returns    ReturnService
+	RequestID  string
+}
+
+func (s TripRunService) ready() error {
+	if !s.Runs.Available() {
+		return domain.InternalError{Msg: "tabel trip_runs belum tersedia, jalankan `migrate up`"}
+	}
+	return nil
+}
+
+func (s TripRunService) db() *sql.DB {
+	if s.Runs.DB != nil {
+		return s.Runs.DB
+	}
+	return intconfig.DB
+}
+
+func (s TripRunService) load(id int64) (repositories.TripRun, error) {
+	if err := s.ready(); err != nil {
+		return repositories.TripRun{}, err
+	}
+	run, err := s.Runs.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return run, domain.NotFoundError{Resource: "trip run"}
+	}
+	if err != nil {
+		return run, domain.InternalError{Msg: "gagal memuat trip run", Err: err}
+	}
+	return run, nil
+}
+
+func cleanRunDate(v string) (string, error) {
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(v))
+	if err != nil {
+		return "", domain.ValidationError{Field: "tripDate", Msg: "format YYYY-MM-DD"}
+	}
+	return t.Format("2006-01-02"), nil
+}
+
+func cleanRunTime(v string) (string, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(v))
+	if err != nil {
+		return "", domain.ValidationError{Field: "tripTime", Msg: "format HH:MM"}
+	}
+	return t.Format("15:04"), nil
+}
+
+// List mengembalikan run sesuai filter; Date (opsional) harus YYYY-MM-DD.
+func (s TripRunService) List(f repositories.TripRunFilter) ([]repositories.TripRun, error) {
+	if err := s.ready(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(f.Date) != "" {
+		d, err := cleanRunDate(f.Date)
+		if err != nil {
+			return nil, err
+		}
+		f.Date = d
+	}
+	list, err := s.Runs.List(f)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat trip run", Err: err}
+	}
+	return list, nil
+}
+
+// Get mengembalikan run beserta booking-nya.
+func (s TripRunService) Get(id int64) (TripRunDetail, error) {
+	run, err := s.load(id)
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	return s.detail(run)
+}
+
+func (s TripRunService) detail(run repositories.TripRun) (TripRunDetail, error) {
+	ids, err := s.Runs.BookingIDs(run.ID)
+	if err != nil {
+		return TripRunDetail{}, domain.InternalError{Msg: "gagal memuat booking trip run", Err: err}
+	}
+	out := TripRunDetail{TripRun: run, Bookings: []TripRunBooking{}}
+	for _, id := range ids {
+		b, err := s.Bookings.GetByID(id)
+		if err != nil {
+			return out, domain.InternalError{Msg: fmt.Sprintf("gagal memuat booking %d", id), Err: err}
+		}
+		st, _ := s.Bookings.CurrentStatus(id)
+		out.Bookings = append(out.Bookings, TripRunBooking{
+			BookingID:      id,
+			PassengerName:  firstNonEmpty(b.BookingFor, b.PassengerName),
+			PassengerPhone: b.PassengerPhone,
+			PassengerCount: b.PassengerCount,
+			PickupLocation: b.PickupLocation,
+			Status:         st,
+			PaymentStatus:  b.PaymentStatus,
+		})
+	}
+	out.BookingCount = len(out.Bookings)
+	return out, nil
+}
+
+// Create membuat run baru (status planned) dan opsional langsung memasukkan booking.
+func (s TripRunService) Create(in TripRunInput) (TripRunDetail, error) {
+	if err := s.ready(); err != nil {
+		return TripRunDetail{}, err
+	}
+	run := repositories.TripRun{
+		TripSlotID:  in.TripSlotID,
+		RouteFrom:   strings.TrimSpace(in.RouteFrom),
+		RouteTo:     strings.TrimSpace(in.RouteTo),
+		TripNumber:  strings.TrimSpace(in.TripNumber),
+		DriverName:  strings.TrimSpace(in.DriverName),
+		VehicleCode: strings.TrimSpace(in.VehicleCode),
+		VehicleType: strings.TrimSpace(in.VehicleType),
+		Note:        strings.TrimSpace(in.Note),
+		Status:      repositories.TripRunPlanned,
+	}
+	date, clock := in.TripDate, in.TripTime
+	if in.TripSlotID > 0 {
+		ts, err := s.Schedules.GetSlot(in.TripSlotID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return TripRunDetail{}, domain.ValidationError{Field: "tripSlotId", Msg: "trip slot tidak ditemukan"}
+		}
+		if err != nil {
+			return TripRunDetail{}, domain.InternalError{Msg: "gagal memuat trip slot", Err: err}
+		}
+		if strings.TrimSpace(date) == "" {
+			date = ts.Date
+		}
+		if strings.TrimSpace(clock) == "" {
+			clock = ts.Time
+		}
+		if run.VehicleType == "" {
+			run.VehicleType = ts.VehicleType
+		}
+	}
+	if run.RouteFrom == "" || run.RouteTo == "" {
+		return TripRunDetail{}, domain.ValidationError{Field: "routeFrom", Msg: "routeFrom dan routeTo wajib diisi"}
+	}
+	var err error
+	if run.TripDate, err = cleanRunDate(date); err != nil {
+		return TripRunDetail{}, err
+	}
+	if run.TripTime, err = cleanRunTime(clock); err != nil {
+		return TripRunDetail{}, err
+	}
+
+	id, err := s.Runs.Create(run)
+	if err != nil {
+		return TripRunDetail{}, domain.InternalError{Msg: "gagal membuat trip run", Err: err}
+	}
+	run.ID = id
+	utils.LogEvent(s.RequestID, "trip_run", "create", fmt.Sprintf("id=%d %s->%s %s %s", id, run.RouteFrom, run.RouteTo, run.TripDate, run.TripTime))
+	if len(in.BookingIDs) > 0 {
+		return s.AddBookings(id, in.BookingIDs)
+	}
+	return s.Get(id)
+}
+
+// Assign mengubah jadwal / sopir / kendaraan run sekali, lalu menyalinnya ke settings semua booking.
+func (s TripRunService) Assign(id int64, a TripRunAssignment) (TripRunDetail, error) {
+	run, err := s.load(id)
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	if run.Status == repositories.TripRunReturned || run.Status == repositories.TripRunCancelled {
+		return TripRunDetail{}, domain.ConflictError{Resource: "trip run", Msg: "Trip run sudah " + run.Status}
+	}
+	if a.TripDate != nil {
+		if run.TripDate, err = cleanRunDate(*a.TripDate); err != nil {
+			return TripRunDetail{}, err
+		}
+	}
+	if a.TripTime != nil {
+		if run.TripTime, err = cleanRunTime(*a.TripTime); err != nil {
+			return TripRunDetail{}, err
+		}
+	}
+	for dst, src := range map[*string]*string{
+		&run.TripNumber:  a.TripNumber,
+		&run.DriverName:  a.DriverName,
+		&run.VehicleCode: a.VehicleCode,
+		&run.VehicleType: a.VehicleType,
+		&run.Note:        a.Note,
+	} {
+		if src != nil {
+			*dst = strings.TrimSpace(*src)
+		}
+	}
+	ids, err := s.Runs.BookingIDs(id)
+	if err != nil {
+		return TripRunDetail{}, domain.InternalError{Msg: "gagal memuat booking trip run", Err: err}
+	}
+	// settings booking di run ini ikut disinkron, jadi tidak dihitung sebagai bentrok
+	candidate := repositories.FleetAssignment{
+		Source:      repositories.AssignmentTripRun,
+		ID:          run.ID,
+		TripNumber:  run.TripNumber,
+		DriverName:  run.DriverName,
+		VehicleCode: run.VehicleCode,
+		Date:        run.TripDate,
+		Time:        run.TripTime,
+		RouteFrom:   run.RouteFrom,
+		RouteTo:     run.RouteTo,
+	}
+	// cek bentrok, run dan settings booking disimpan dalam satu transaksi
+	db := s.db()
+	if db == nil {
+		return TripRunDetail{}, domain.InternalError{Msg: "db tidak tersedia"}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return TripRunDetail{}, domain.InternalError{Msg: "gagal memulai transaksi", Err: err}
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := s.Departures.Guard.CheckTx(tx, candidate, ids, a.Force); err != nil {
+		return TripRunDetail{}, err
+	}
+	if err := s.Runs.UpdateTx(tx, run); err != nil {
+		return TripRunDetail{}, domain.InternalError{Msg: "gagal menyimpan trip run", Err: err}
+	}
+	if err := s.syncSettings(tx, run, ids); err != nil {
+		return TripRunDetail{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return TripRunDetail{}, domain.InternalError{Msg: "gagal menyimpan trip run", Err: err}
+	}
+	utils.LogEvent(s.RequestID, "trip_run", "assign", fmt.Sprintf("id=%d driver=%s vehicle=%s bookings=%d", id, run.DriverName, run.VehicleCode, len(ids)))
+	return s.Get(id)
+}
+
+// bookingJoinable: booking yang sudah batal/kedaluwarsa/selesai tidak bisa dimasukkan ke run.
+func bookingJoinable(st domain.BookingStatus) bool {
+	switch st {
+	case domain.BookingCancelled, domain.BookingExpired, domain.BookingCompleted:
+		return false
+	}
+	return true
+}
+
+// AddBookings memindahkan booking ke run planned (dari run lain bila sudah punya) dan menyalin
+// assignment run ke departure_settings booking tsb.
+func (s TripRunService) AddBookings(id int64, bookingIDs []int64) (TripRunDetail, error) {
+	run, err := s.load(id)
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	if run.Status != repositories.TripRunPlanned {
+		return TripRunDetail{}, domain.ConflictError{Resource: "trip run", Msg: "Booking hanya bisa dipindah ke trip run yang belum berangkat"}
+	}
+	ids := uniqueIDs(bookingIDs)
+	if len(ids) == 0 {
+		return TripRunDetail{}, domain.ValidationError{Field: "bookingIds", Msg: "wajib diisi"}
+	}
+	for _, bid := range ids {
+		st, err := s.Bookings.CurrentStatus(bid)
+		if errors.Is(err, sql.ErrNoRows) {
+			return TripRunDetail{}, domain.ValidationError{Field: "bookingIds", Msg: fmt.Sprintf("booking %d tidak ditemukan", bid)}
+		}
+		if err != nil {
+			return TripRunDetail{}, domain.InternalError{Msg: "gagal membaca status booking", Err: err}
+		}
+		if !bookingJoinable(st) {
+			return TripRunDetail{}, domain.ConflictError{Resource: "booking", Msg: fmt.Sprintf("Booking %d berstatus %s", bid, st)}
+		}
+		if from, err := s.Runs.RunOfBooking(bid); err == nil && from > 0 && from != id {
+			if prev, err := s.Runs.GetByID(from); err == nil && prev.Status != repositories.TripRunPlanned && prev.Status != repositories.TripRunCancelled {
+				return TripRunDetail{}, domain.ConflictError{Resource: "booking", Msg: fmt.Sprintf("Booking %d sudah ada di trip run %d yang %s", bid, from, prev.Status)}
+			}
+		}
+	}
+	if err := s.Runs.AttachBookings(id, ids); err != nil {
+		return TripRunDetail{}, domain.InternalError{Msg: "gagal memindahkan booking", Err: err}
+	}
+	if err := s.syncSettings(s.db(), run, ids); err != nil {
+		return TripRunDetail{}, err
+	}
+	utils.LogEvent(s.RequestID, "trip_run", "add_bookings", fmt.Sprintf("id=%d bookings=%v", id, ids))
+	return s.Get(id)
+}
+
+// RemoveBooking mengeluarkan booking dari run yang belum berangkat.
+func (s TripRunService) RemoveBooking(id, bookingID int64) (TripRunDetail, error) {
+	run, err := s.load(id)
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	if run.Status != repositories.TripRunPlanned {
+		return TripRunDetail{}, domain.ConflictError{Resource: "trip run", Msg: "Trip run sudah " + run.Status}
+	}
+	ok, err := s.Runs.DetachBooking(id, bookingID)
+	if err != nil {
+		return TripRunDetail{}, domain.InternalError{Msg: "gagal mengeluarkan booking", Err: err}
+	}
+	if !ok {
+		return TripRunDetail{}, domain.NotFoundError{Resource: "booking di trip run"}
+	}
+	utils.LogEvent(s.RequestID, "trip_run", "remove_booking", fmt.Sprintf("id=%d booking_id=%d", id, bookingID))
+	return s.Get(id)
+}
+
+// MarkBerangkat menandai seluruh booking run Berangkat dengan alur DepartureService.MarkBerangkat,
+// semuanya dalam satu transaksi bersama status run. Semua booking dicek dulu (harus lunas) supaya
+// error lebih jelas; booking yang gagal di tengah me-rollback seluruh run. extra = payload
+// tambahan untuk departure_settings (mis. surat jalan), boleh kosong.
+func (s TripRunService) MarkBerangkat(id int64, extra []byte) (TripRunDetail, error) {
+	run, err := s.load(id)
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	if run.Status != repositories.TripRunPlanned && run.Status != repositories.TripRunDeparted {
+		return TripRunDetail{}, domain.ConflictError{Resource: "trip run", Msg: "Trip run sudah " + run.Status}
+	}
+	ids, err := s.runBookings(id)
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	blocked := []string{}
+	for _, bid := range ids {
+		st, err := s.Bookings.CurrentStatus(bid)
+		if err != nil {
+			return TripRunDetail{}, domain.InternalError{Msg: "gagal membaca status booking", Err: err}
+		}
+		if st == domain.BookingDeparted || st == domain.BookingCompleted {
+			continue
+		}
+		if domain.ValidateBookingTransition(st, domain.BookingDeparted) != nil {
+			blocked = append(blocked, fmt.Sprintf("#%d (%s)", bid, st))
+		}
+	}
+	if len(blocked) > 0 {
+		return TripRunDetail{}, domain.ConflictError{Resource: "trip run", Msg: "Booking belum bisa berangkat: " + strings.Join(blocked, ", ")}
+	}
+
+	payload, err := runSettingsPayload(run, extra, "Berangkat")
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	depIDs := make([]int, len(ids))
+	for i, bid := range ids {
+		dep, err := s.departureOf(bid)
+		if err != nil {
+			return TripRunDetail{}, err
+		}
+		depIDs[i] = dep.ID
+	}
+
+	// seluruh booking berangkat bersama status run dalam satu transaksi: satu booking gagal = tidak
+	// ada yang berubah, jadi run bisa diulang tanpa setengah booking sudah berangkat
+	queued := make([]bool, len(ids))
+	err = s.inTx(func(tx *sql.Tx) error {
+		for i, bid := range ids {
+			q, err := s.Departures.markBerangkatTx(tx, depIDs[i], payload)
+			if err != nil {
+				return runStepError(bid, "berangkat", err)
+			}
+			queued[i] = q
+		}
+		run.Status = repositories.TripRunDeparted
+		if err := s.Runs.UpdateTx(tx, run); err != nil {
+			return domain.InternalError{Msg: "gagal menyimpan trip run", Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	for i, bid := range ids {
+		if _, err := s.Departures.afterBerangkat(depIDs[i], queued[i]); err != nil {
+			utils.LogEvent(s.RequestID, "trip_run", "berangkat_sync_error", fmt.Sprintf("id=%d booking_id=%d: %v", id, bid, err))
+		}
+	}
+	utils.LogEvent(s.RequestID, "trip_run", "berangkat", fmt.Sprintf("id=%d bookings=%d", id, len(ids)))
+	return s.Get(id)
+}
+
+// MarkPulang menandai kepulangan seluruh booking run dengan alur ReturnService.MarkPulang
+// (booking menjadi completed) dalam satu transaksi bersama status run. Run harus sudah berangkat.
+func (s TripRunService) MarkPulang(id int64, extra []byte) (TripRunDetail, error) {
+	run, err := s.load(id)
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	if run.Status != repositories.TripRunDeparted && run.Status != repositories.TripRunReturned {
+		return TripRunDetail{}, domain.ConflictError{Resource: "trip run", Msg: "Trip run belum berangkat"}
+	}
+	ids, err := s.runBookings(id)
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	for _, bid := range ids {
+		if _, err := pulangSteps(s.Bookings, bid); err != nil {
+			return TripRunDetail{}, runStepError(bid, "pulang", err)
+		}
+	}
+
+	payload, err := runSettingsPayload(run, extra, "Berangkat")
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	retIDs := make([]int, len(ids))
+	for i, bid := range ids {
+		ret, err := s.returnOf(bid)
+		if err != nil {
+			return TripRunDetail{}, err
+		}
+		retIDs[i] = ret.ID
+	}
+
+	queued := make([]bool, len(ids))
+	err = s.inTx(func(tx *sql.Tx) error {
+		for i, bid := range ids {
+			q, err := s.Returns.markPulangTx(tx, retIDs[i], payload)
+			if err != nil {
+				return runStepError(bid, "pulang", err)
+			}
+			queued[i] = q
+		}
+		run.Status = repositories.TripRunReturned
+		if err := s.Runs.UpdateTx(tx, run); err != nil {
+			return domain.InternalError{Msg: "gagal menyimpan trip run", Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return TripRunDetail{}, err
+	}
+	for i, bid := range ids {
+		if _, err := s.Returns.afterPulang(retIDs[i], queued[i]); err != nil {
+			utils.LogEvent(s.RequestID, "trip_run", "pulang_sync_error", fmt.Sprintf("id=%d booking_id=%d: %v", id, bid, err))
+		}
+	}
+	utils.LogEvent(s.RequestID, "trip_run", "pulang", fmt.Sprintf("id=%d bookings=%d", id, len(ids)))
+	return s.Get(id)
+}
+
+// inTx menjalankan fn dalam satu transaksi; rollback bila fn gagal.
+func (s TripRunService) inTx(fn func(tx *sql.Tx) error) error {
+	db := s.db()
+	if db == nil {
+		return domain.InternalError{Msg: "db tidak tersedia"}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return domain.InternalError{Msg: "gagal mulai transaksi", Err: err}
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.InternalError{Msg: "gagal commit trip run", Err: err}
+	}
+	return nil
+}
+
+func (s TripRunService) runBookings(id int64) ([]int64, error) {
+	ids, err := s.Runs.BookingIDs(id)
+	if err != nil {
+		return nil, domain.InternalError{Msg: "gagal memuat booking trip run", Err: err}
+	}
+	if len(ids) == 0 {
+		return nil, domain.ValidationError{Field: "bookings", Msg: "trip run belum berisi booking"}
+	}
+	return ids, nil
+}
+
+// departureOf mengembalikan departure_settings booking, dibuat dari data booking bila belum ada.
+func (s TripRunService) departureOf(bookingID int64) (models.DepartureSetting, error) {
+	dep, err := s.Departures.Repo.GetByBookingID(bookingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		dep, err = s.Departures.CreateOrUpdateFromBookingID(bookingID)
+	}
+	if err != nil {
+		return dep, domain.InternalError{Msg: fmt.Sprintf("gagal menyiapkan keberangkatan booking %d", bookingID), Err: err}
+	}
+	return dep, nil
+}
+
+// returnOf mengembalikan return_settings booking, dibuat dari data booking bila belum ada.
+func (s TripRunService) returnOf(bookingID int64) (models.ReturnSetting, error) {
+	ret, err := s.Returns.Repo.GetByBookingID(bookingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		ret, err = s.Returns.CreateOrUpdateFromBookingID(bookingID)
+	}
+	if err != nil {
+		return ret, domain.InternalError{Msg: fmt.Sprintf("gagal menyiapkan kepulangan booking %d", bookingID), Err: err}
+	}
+	return ret, nil
+}
+
+// syncSettings menyalin sopir/kendaraan/no. trip run ke departure_settings (dibuat bila belum ada)
+// dan return_settings (bila sudah ada) milik booking, lewat q (db atau transaksi pemanggil).
+func (s TripRunService) syncSettings(q intdb.Queryer, run repositories.TripRun, bookingIDs []int64) error {
+	payload, err := runSettingsPayload(run, nil, "")
+	if err != nil || bytes.Equal(payload, []byte("{}")) {
+		return err
+	}
+	for _, bid := range bookingIDs {
+		dep, err := s.departureOf(bid)
+		if err != nil {
+			return err
+		}
+		if _, err := s.Departures.Repo.UpdatePartialTx(q, dep.ID, payload); err != nil {
+			return domain.InternalError{Msg: fmt.Sprintf("gagal update keberangkatan booking %d", bid), Err: err}
+		}
+		if ret, err := s.Returns.Repo.GetByBookingID(bid); err == nil {
+			if _, err := s.Returns.Repo.UpdatePartialTx(q, ret.ID, payload); err != nil {
+				return domain.InternalError{Msg: fmt.Sprintf("gagal update kepulangan booking %d", bid), Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// runSettingsPayload membuat payload partial departure/return_settings dari run: extra (JSON
+// object, opsional) ditimpa assignment run yang terisi dan departure_status bila status tidak kosong.
+func runSettingsPayload(run repositories.TripRun, extra []byte, status string) ([]byte, error) {
+	payload := map[string]any{}
+	if len(bytes.TrimSpace(extra)) > 0 {
+		if err := json.Unmarshal(extra, &payload); err != nil {
+			return nil, domain.ValidationError{Field: "payload", Msg: "payload harus JSON object", Err: err}
+		}
+	}
+	for key, val := range map[string]string{
+		"driver_name":  run.DriverName,
+		"vehicle_code": run.VehicleCode,
+		"vehicle_type": run.VehicleType,
+		"trip_number":  run.TripNumber,
+	} {
+		if strings.TrimSpace(val) != "" {
+			payload[key] = val
+		}
+	}
+	if status != "" {
+		payload["departure_status"] = status
+	}
+	return json.Marshal(payload)
+}
+
+func runStepError(bookingID int64, step string, err error) error {
+	if domain.IsConflict(err) || domain.IsValidation(err) {
+		return err
+	}
+	return domain.InternalError{Msg: fmt.Sprintf("gagal memproses %s booking %d", step, bookingID), Err: err}
+}
+
+func uniqueIDs(ids []int64) []int64 {
+	seen := map[int64]bool{}
+	out := []int64{}
+	for _, id := range ids {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
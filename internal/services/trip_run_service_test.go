@@ -0,0 +1,59 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"backend/internal/domain"
+	"backend/internal/repositories"
+)
+
+func TestRunSettingsPayload(t *testing.T) {
+	run := repositories.TripRun{DriverName: "Budi", VehicleCode: "BM 1234 XX", TripNumber: ""}
+	raw, err := runSettingsPayload(run, []byte(`{"surat_jalan_file":"file:abc","driver_name":"Lama"}`), "Berangkat")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("invalid payload %s", raw)
+	}
+	if got["driver_name"] != "Budi" || got["vehicle_code"] != "BM 1234 XX" || got["departure_status"] != "Berangkat" {
+		t.Fatalf("run assignment must override payload, got %v", got)
+	}
+	if got["surat_jalan_file"] != "file:abc" {
+		t.Fatalf("extra payload must be kept, got %v", got)
+	}
+	if _, ok := got["trip_number"]; ok {
+		t.Fatalf("empty run fields must not clear settings, got %v", got)
+	}
+
+	if raw, _ := runSettingsPayload(repositories.TripRun{}, nil, ""); string(raw) != "{}" {
+		t.Fatalf("expected empty payload, got %s", raw)
+	}
+	if _, err := runSettingsPayload(run, []byte(`[1,2]`), ""); !domain.IsValidation(err) {
+		t.Fatalf("expected validation error for non-object payload, got %v", err)
+	}
+}
+
+func TestTripRunBookingRules(t *testing.T) {
+	if got := uniqueIDs([]int64{5, 3, 5, 0, -1, 3}); len(got) != 2 || got[0] != 3 || got[1] != 5 {
+		t.Fatalf("unexpected ids %v", got)
+	}
+	for _, st := range []domain.BookingStatus{domain.BookingPaid, domain.BookingAwaitingValidation, domain.BookingPartiallyPaid} {
+		if !bookingJoinable(st) {
+			t.Fatalf("%s booking should be joinable", st)
+		}
+	}
+	for _, st := range []domain.BookingStatus{domain.BookingCancelled, domain.BookingExpired, domain.BookingCompleted} {
+		if bookingJoinable(st) {
+			t.Fatalf("%s booking should not be joinable", st)
+		}
+	}
+	if _, err := cleanRunTime("25:00"); !domain.IsValidation(err) {
+		t.Fatalf("expected HH:MM validation, got %v", err)
+	}
+	if v, err := cleanRunTime("8:05"); err != nil || v != "08:05" {
+		t.Fatalf("unexpected time %q %v", v, err)
+	}
+}
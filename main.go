@@ -11,22 +11,40 @@ import (
 
 	intconfig "backend/internal/config"
 	router "backend/internal/http"
+	"backend/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
 	env := intconfig.LoadEnv()
 	if env.GinMode != "" {
 		gin.SetMode(env.GinMode)
 	}
 
-	intconfig.ConnectDB()
+	intconfig.ConnectDB(env.DB)
 	defer intconfig.CloseDB()
 
 	// Router (Gin engine)
 	r := router.NewRouter(env)
 
+	// background job: lepas hold seat yang kedaluwarsa, buat trip slot dari timetable,
+	// expire booking yang tidak dibayar sampai batas waktu, hapus Idempotency-Key kedaluwarsa,
+	// proses event outbox (sinkron settings/penumpang/trip_information)
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go services.SeatHoldService{TTL: env.SeatHoldTTL}.RunSweeper(jobsCtx, env.SeatHoldSweepInterval)
+	go services.ScheduleService{}.RunGenerator(jobsCtx, env.ScheduleGenerateInterval, env.ScheduleHorizonDays)
+	if env.PaymentDeadline > 0 {
+		go services.BookingExpiryService{}.RunSweeper(jobsCtx, env.BookingExpiryInterval)
+	}
+	go services.IdempotencyService{}.RunPurger(jobsCtx, time.Hour)
+	go services.OutboxService{Policy: services.OutboxPolicy{MaxAttempts: env.OutboxMaxAttempts, Backoff: env.OutboxBackoff}}.RunWorker(jobsCtx, env.OutboxInterval)
+
 	srv := &http.Server{
 		Addr:              env.AppAddr,
 		Handler:           r,
@@ -48,6 +66,7 @@ func main() {
 	<-quit
 
 	log.Println("Mematikan server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()